@@ -67,6 +67,21 @@ func (c *GroupCache) Refresh(id string) (model.Group, error) {
 	return res, nil
 }
 
+// RefreshMembership refreshes the group along with the groups containing it, as
+// members are resolved through subgroups when caching a group.
+func (c *GroupCache) RefreshMembership(id string) error {
+	ancestorIDs, err := c.groupRepo.FindAncestorIDs(id)
+	if err != nil {
+		return err
+	}
+	for _, groupID := range append([]string{id}, ancestorIDs...) {
+		if _, err := c.Refresh(groupID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *GroupCache) Delete(id string) error {
 	if err := c.redis.Delete(c.keyPrefix + id); err != nil {
 		return err
@@ -0,0 +1,75 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package cache
+
+import (
+	"encoding/json"
+
+	"github.com/kouprlabs/voltaserve/api/infra"
+	"github.com/kouprlabs/voltaserve/api/model"
+	"github.com/kouprlabs/voltaserve/api/repo"
+)
+
+type RoleCache struct {
+	redis     *infra.RedisManager
+	roleRepo  *repo.RoleRepo
+	keyPrefix string
+}
+
+func NewRoleCache() *RoleCache {
+	return &RoleCache{
+		redis:     infra.NewRedisManager(),
+		roleRepo:  repo.NewRoleRepo(),
+		keyPrefix: "role:",
+	}
+}
+
+func (c *RoleCache) Set(role model.Role) error {
+	b, err := json.Marshal(role)
+	if err != nil {
+		return err
+	}
+	err = c.redis.Set(c.keyPrefix+role.GetID(), string(b))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *RoleCache) Get(id string) (model.Role, error) {
+	value, err := c.redis.Get(c.keyPrefix + id)
+	if err != nil {
+		return c.Refresh(id)
+	}
+	res := repo.NewRole()
+	if err = json.Unmarshal([]byte(value), &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *RoleCache) Refresh(id string) (model.Role, error) {
+	res, err := c.roleRepo.Find(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.Set(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *RoleCache) Delete(id string) error {
+	if err := c.redis.Delete(c.keyPrefix + id); err != nil {
+		return err
+	}
+	return nil
+}
@@ -44,6 +44,16 @@ func newPipelineClient() *pipelineClient {
 const (
 	PipelineInsights = "insights"
 	PipelineMosaic   = "mosaic"
+	PipelineRedact   = "redact"
+	PipelinePDFPages = "pdf_pages"
+)
+
+const (
+	PayloadLanguage                  = "language"
+	PayloadMosaicThresholdMegapixels = "mosaicThresholdMegapixels"
+	PayloadRedactions                = "redactions"
+	PayloadFirstPage                 = "firstPage"
+	PayloadExtractAttachments        = "extractAttachments"
 )
 
 type PipelineRunOptions struct {
@@ -30,6 +30,10 @@ type Config struct {
 	Security      SecurityConfig
 	SMTP          SMTPConfig
 	Defaults      DefaultsConfig
+	Permissions   PermissionsConfig
+	Invitations   InvitationsConfig
+	Storage       StorageConfig
+	Archive       ArchiveConfig
 	Environment   EnvironmentConfig
 }
 
@@ -42,6 +46,30 @@ type DefaultsConfig struct {
 	WorkspaceStorageCapacityMB int
 }
 
+type PermissionsConfig struct {
+	SweepIntervalSeconds int
+	ExpiryReminderHours  int
+}
+
+type InvitationsConfig struct {
+	SweepIntervalSeconds int
+	ExpiryHours          int
+}
+
+type StorageConfig struct {
+	// WarningThresholds are the percentages of a quota at which a warning email is sent.
+	WarningThresholds        []int
+	ReconcileIntervalSeconds int
+}
+
+type ArchiveConfig struct {
+	// Bucket receives the objects of archived workspaces, archival is disabled when empty.
+	Bucket string
+	// StorageClass is applied to the archived objects, it must allow reading them without
+	// a thaw request, like STANDARD_IA or GLACIER_IR on AWS, as archived files stay downloadable.
+	StorageClass string
+}
+
 type TokenConfig struct {
 	AccessTokenLifetime  int
 	RefreshTokenLifetime int
@@ -95,6 +123,8 @@ const (
 	FileTypeVideo          = "video"
 	FileTypeAudio          = "audio"
 	FileTypeGLB            = "glb"
+	FileTypeModel          = "model"
+	FileTypeEmail          = "email"
 	FileTypeZIP            = "zip"
 	FileTypeGLTF           = "gltf"
 	FileTypeEverythingElse = "*"
@@ -111,6 +141,10 @@ func GetConfig() *Config {
 	readSMTP(config)
 	readLimits(config)
 	readDefaults(config)
+	readPermissions(config)
+	readInvitations(config)
+	readStorage(config)
+	readArchive(config)
 	readEnvironment(config)
 	return config
 }
@@ -234,6 +268,64 @@ func readDefaults(config *Config) {
 	}
 }
 
+func readPermissions(config *Config) {
+	if len(os.Getenv("PERMISSIONS_SWEEP_INTERVAL_SECONDS")) > 0 {
+		v, err := strconv.ParseInt(os.Getenv("PERMISSIONS_SWEEP_INTERVAL_SECONDS"), 10, 32)
+		if err != nil {
+			panic(err)
+		}
+		config.Permissions.SweepIntervalSeconds = int(v)
+	}
+	if len(os.Getenv("PERMISSIONS_EXPIRY_REMINDER_HOURS")) > 0 {
+		v, err := strconv.ParseInt(os.Getenv("PERMISSIONS_EXPIRY_REMINDER_HOURS"), 10, 32)
+		if err != nil {
+			panic(err)
+		}
+		config.Permissions.ExpiryReminderHours = int(v)
+	}
+}
+
+func readInvitations(config *Config) {
+	if len(os.Getenv("INVITATIONS_SWEEP_INTERVAL_SECONDS")) > 0 {
+		v, err := strconv.ParseInt(os.Getenv("INVITATIONS_SWEEP_INTERVAL_SECONDS"), 10, 32)
+		if err != nil {
+			panic(err)
+		}
+		config.Invitations.SweepIntervalSeconds = int(v)
+	}
+	if len(os.Getenv("INVITATIONS_EXPIRY_HOURS")) > 0 {
+		v, err := strconv.ParseInt(os.Getenv("INVITATIONS_EXPIRY_HOURS"), 10, 32)
+		if err != nil {
+			panic(err)
+		}
+		config.Invitations.ExpiryHours = int(v)
+	}
+}
+
+func readStorage(config *Config) {
+	if len(os.Getenv("STORAGE_WARNING_THRESHOLDS")) > 0 {
+		for _, part := range strings.Split(os.Getenv("STORAGE_WARNING_THRESHOLDS"), ",") {
+			v, err := strconv.ParseInt(strings.TrimSpace(part), 10, 32)
+			if err != nil {
+				panic(err)
+			}
+			config.Storage.WarningThresholds = append(config.Storage.WarningThresholds, int(v))
+		}
+	}
+	if len(os.Getenv("STORAGE_RECONCILE_INTERVAL_SECONDS")) > 0 {
+		v, err := strconv.ParseInt(os.Getenv("STORAGE_RECONCILE_INTERVAL_SECONDS"), 10, 32)
+		if err != nil {
+			panic(err)
+		}
+		config.Storage.ReconcileIntervalSeconds = int(v)
+	}
+}
+
+func readArchive(config *Config) {
+	config.Archive.Bucket = os.Getenv("ARCHIVE_BUCKET")
+	config.Archive.StorageClass = os.Getenv("ARCHIVE_STORAGE_CLASS")
+}
+
 func readEnvironment(config *Config) {
 	if os.Getenv("TEST") == "true" {
 		config.Environment.IsTest = true
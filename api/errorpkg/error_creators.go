@@ -140,6 +140,246 @@ func NewInsightsNotFoundError(err error) *ErrorResponse {
 	)
 }
 
+func NewSummaryNotFoundError(err error) *ErrorResponse {
+	return NewErrorResponse(
+		"summary_not_found",
+		http.StatusNotFound,
+		"Summary not found.",
+		"Summary not found.",
+		err,
+	)
+}
+
+func NewMetadataNotFoundError(err error) *ErrorResponse {
+	return NewErrorResponse(
+		"metadata_not_found",
+		http.StatusNotFound,
+		"Metadata not found.",
+		"Metadata not found.",
+		err,
+	)
+}
+
+func NewSheetNotFoundError(err error) *ErrorResponse {
+	return NewErrorResponse(
+		"sheet_not_found",
+		http.StatusNotFound,
+		"Sheet not found.",
+		"Sheet not found.",
+		err,
+	)
+}
+
+func NewWaveformNotFoundError(err error) *ErrorResponse {
+	return NewErrorResponse(
+		"waveform_not_found",
+		http.StatusNotFound,
+		"Waveform not found.",
+		"Waveform not found.",
+		err,
+	)
+}
+
+func NewTranscriptNotFoundError(err error) *ErrorResponse {
+	return NewErrorResponse(
+		"transcript_not_found",
+		http.StatusNotFound,
+		"Transcript not found.",
+		"Transcript not found.",
+		err,
+	)
+}
+
+func NewSlideNotFoundError(err error) *ErrorResponse {
+	return NewErrorResponse(
+		"slide_not_found",
+		http.StatusNotFound,
+		"Slide not found.",
+		"Slide not found.",
+		err,
+	)
+}
+
+func NewPageNotFoundError(err error) *ErrorResponse {
+	return NewErrorResponse(
+		"page_not_found",
+		http.StatusNotFound,
+		"Page not found.",
+		"Page not found.",
+		err,
+	)
+}
+
+func NewPageNotReadyError() *ErrorResponse {
+	return NewErrorResponse(
+		"page_not_ready",
+		http.StatusServiceUnavailable,
+		"Page is being rendered.",
+		"The page is being rendered, please try again shortly.",
+		nil,
+	)
+}
+
+func NewLayoutNotFoundError(err error) *ErrorResponse {
+	return NewErrorResponse(
+		"layout_not_found",
+		http.StatusNotFound,
+		"Layout not found.",
+		"Text layout is not available for this file.",
+		err,
+	)
+}
+
+func NewNoRedactionsSelectedError() *ErrorResponse {
+	return NewErrorResponse(
+		"no_redactions_selected",
+		http.StatusBadRequest,
+		"No redactions selected.",
+		"Select at least one finding to redact.",
+		nil,
+	)
+}
+
+func NewRedactionSourceNotFoundError() *ErrorResponse {
+	return NewErrorResponse(
+		"redaction_source_not_found",
+		http.StatusBadRequest,
+		"Redaction source not found.",
+		"Only files with a PDF rendition can be redacted.",
+		nil,
+	)
+}
+
+func NewCannotDenyOwnPermissionError() *ErrorResponse {
+	return NewErrorResponse(
+		"cannot_deny_own_permission",
+		http.StatusBadRequest,
+		"Cannot deny own permission.",
+		"You cannot deny your own permission.",
+		nil,
+	)
+}
+
+func NewCannotGrantAboveOwnPermissionError() *ErrorResponse {
+	return NewErrorResponse(
+		"cannot_grant_above_own_permission",
+		http.StatusForbidden,
+		"Cannot grant or change a permission above own permission.",
+		"You cannot grant or change a permission above your own.",
+		nil,
+	)
+}
+
+func NewInvalidPermissionScheduleError() *ErrorResponse {
+	return NewErrorResponse(
+		"invalid_permission_schedule",
+		http.StatusBadRequest,
+		"Permission must expire in the future and after it starts.",
+		"The permission must expire in the future and after it starts.",
+		nil,
+	)
+}
+
+func NewRoleNotFoundError(err error) *ErrorResponse {
+	return NewErrorResponse(
+		"role_not_found",
+		http.StatusNotFound,
+		"Role not found.",
+		"Role not found.",
+		err,
+	)
+}
+
+func NewWorkspaceTemplateNotFoundError(err error) *ErrorResponse {
+	return NewErrorResponse(
+		"workspace_template_not_found",
+		http.StatusNotFound,
+		"Workspace template not found.",
+		"Workspace template not found.",
+		err,
+	)
+}
+
+func NewWorkspaceTemplateOrganizationMismatchError(template model.WorkspaceTemplate) *ErrorResponse {
+	return NewErrorResponse(
+		"workspace_template_organization_mismatch",
+		http.StatusBadRequest,
+		fmt.Sprintf("Workspace template '%s' belongs to another organization.", template.GetID()),
+		fmt.Sprintf("Workspace template '%s' belongs to another organization.", template.GetName()),
+		nil,
+	)
+}
+
+func NewWorkspaceArchivedError(workspace model.Workspace) *ErrorResponse {
+	return NewErrorResponse(
+		"workspace_archived",
+		http.StatusForbidden,
+		fmt.Sprintf("Workspace '%s' is archived.", workspace.GetID()),
+		fmt.Sprintf("Workspace '%s' is archived, restore it to make changes.", workspace.GetName()),
+		nil,
+	)
+}
+
+func NewWorkspaceNotArchivedError(workspace model.Workspace) *ErrorResponse {
+	return NewErrorResponse(
+		"workspace_not_archived",
+		http.StatusBadRequest,
+		fmt.Sprintf("Workspace '%s' is not archived.", workspace.GetID()),
+		fmt.Sprintf("Workspace '%s' is not archived.", workspace.GetName()),
+		nil,
+	)
+}
+
+func NewArchivalNotConfiguredError() *ErrorResponse {
+	return NewErrorResponse(
+		"archival_not_configured",
+		http.StatusServiceUnavailable,
+		"Archival bucket is not configured.",
+		"Archiving workspaces is not available.",
+		nil,
+	)
+}
+
+func NewRoleInUseError(role model.Role) *ErrorResponse {
+	return NewErrorResponse(
+		"role_in_use",
+		http.StatusBadRequest,
+		fmt.Sprintf("Role '%s' is still granted.", role.GetID()),
+		fmt.Sprintf("Role '%s' is still granted, revoke it before deleting it.", role.GetName()),
+		nil,
+	)
+}
+
+func NewInvalidCapabilityError(capability string) *ErrorResponse {
+	return NewErrorResponse(
+		"invalid_capability",
+		http.StatusBadRequest,
+		fmt.Sprintf("Capability '%s' is invalid.", capability),
+		fmt.Sprintf("Capability '%s' is invalid.", capability),
+		nil,
+	)
+}
+
+func NewRoleMissingListCapabilityError() *ErrorResponse {
+	return NewErrorResponse(
+		"role_missing_list_capability",
+		http.StatusBadRequest,
+		"Role must include the list capability.",
+		"A role must at least allow listing items.",
+		nil,
+	)
+}
+
+func NewRoleNotInOrganizationError(role model.Role) *ErrorResponse {
+	return NewErrorResponse(
+		"role_not_in_organization",
+		http.StatusBadRequest,
+		fmt.Sprintf("Role '%s' belongs to another organization.", role.GetID()),
+		fmt.Sprintf("Role '%s' belongs to another organization.", role.GetName()),
+		nil,
+	)
+}
+
 func NewMosaicNotFoundError(err error) *ErrorResponse {
 	return NewErrorResponse(
 		"mosaic_not_found",
@@ -243,6 +483,56 @@ func NewCannotRemoveSoleOwnerOfGroupError(group model.Group) *ErrorResponse {
 	)
 }
 
+func NewGroupCycleError(group model.Group, subgroup model.Group) *ErrorResponse {
+	return NewErrorResponse(
+		"group_cycle",
+		http.StatusBadRequest,
+		fmt.Sprintf("Group '%s' already contains group '%s'.", subgroup.GetID(), group.GetID()),
+		fmt.Sprintf("Group '%s' cannot be added to '%s', as it already contains it.", subgroup.GetName(), group.GetName()),
+		nil,
+	)
+}
+
+func NewGroupNotInSameOrganizationError(group model.Group, subgroup model.Group) *ErrorResponse {
+	return NewErrorResponse(
+		"group_not_in_same_organization",
+		http.StatusBadRequest,
+		fmt.Sprintf("Group '%s' is not in the organization of group '%s'.", subgroup.GetID(), group.GetID()),
+		fmt.Sprintf("Group '%s' belongs to another organization.", subgroup.GetName()),
+		nil,
+	)
+}
+
+func NewMemberStillOwnsResourcesError(org model.Organization, count int) *ErrorResponse {
+	return NewErrorResponse(
+		"member_still_owns_resources",
+		http.StatusForbidden,
+		fmt.Sprintf("Member is the sole owner of %d resources in organization '%s'.", count, org.GetID()),
+		fmt.Sprintf("This member is the sole owner of %d items, transfer their ownership first.", count),
+		nil,
+	)
+}
+
+func NewInvalidOwnershipTransferTargetError() *ErrorResponse {
+	return NewErrorResponse(
+		"invalid_ownership_transfer_target",
+		http.StatusBadRequest,
+		"Ownership cannot be transferred to the same member.",
+		"Ownership must be transferred to another member.",
+		nil,
+	)
+}
+
+func NewCannotDemoteSoleAdminOfOrganizationError(org model.Organization) *ErrorResponse {
+	return NewErrorResponse(
+		"cannot_demote_sole_admin_of_organization",
+		http.StatusBadRequest,
+		fmt.Sprintf("Cannot demote sole admin of organization '%s'.", org.GetID()),
+		fmt.Sprintf("Cannot demote sole admin of organization '%s'.", org.GetName()),
+		nil,
+	)
+}
+
 func NewGroupPermissionError(userID string, org model.Group, permission string) *ErrorResponse {
 	return NewErrorResponse(
 		"missing_group_permission",
@@ -282,6 +572,19 @@ func NewFilePermissionError(userID string, file model.File, permission string) *
 	)
 }
 
+func NewFileCapabilityError(userID string, file model.File, capability string) *ErrorResponse {
+	return NewErrorResponse(
+		"missing_file_capability",
+		http.StatusForbidden,
+		fmt.Sprintf(
+			"User '%s' is missing capability '%s' for file '%s'.",
+			userID, capability, file.GetID(),
+		),
+		fmt.Sprintf("Sorry, you are not allowed to do this on item '%s'.", file.GetName()),
+		nil,
+	)
+}
+
 func NewS3Error(message string) *ErrorResponse {
 	return NewErrorResponse(
 		"s3_error",
@@ -322,6 +625,26 @@ func NewStorageLimitExceededError() *ErrorResponse {
 	)
 }
 
+func NewOrganizationStorageLimitExceededError() *ErrorResponse {
+	return NewErrorResponse(
+		"organization_storage_limit_exceeded",
+		http.StatusForbidden,
+		"Organization storage limit exceeded.",
+		"Storage limit of your organization has been reached, please increase it and try again.",
+		nil,
+	)
+}
+
+func NewMemberStorageLimitExceededError() *ErrorResponse {
+	return NewErrorResponse(
+		"member_storage_limit_exceeded",
+		http.StatusForbidden,
+		"Member storage limit exceeded.",
+		"You have reached your upload quota in this organization, please free some space and try again.",
+		nil,
+	)
+}
+
 func NewInsufficientStorageCapacityError() *ErrorResponse {
 	return NewErrorResponse(
 		"insufficient_storage_capacity",
@@ -333,6 +656,26 @@ func NewInsufficientStorageCapacityError() *ErrorResponse {
 	)
 }
 
+func NewUnsupportedLanguageError(language string) *ErrorResponse {
+	return NewErrorResponse(
+		"unsupported_language",
+		http.StatusBadRequest,
+		fmt.Sprintf("Language '%s' is not supported.", language),
+		"The selected language is not supported.",
+		nil,
+	)
+}
+
+func NewInvalidProcessingPolicyError(field string) *ErrorResponse {
+	return NewErrorResponse(
+		"invalid_processing_policy",
+		http.StatusBadRequest,
+		fmt.Sprintf("Processing policy field '%s' is invalid.", field),
+		"The processing policy is invalid.",
+		nil,
+	)
+}
+
 func NewRequestBodyValidationError(err error) *ErrorResponse {
 	var fields []string
 	for _, e := range err.(validator.ValidationErrors) {
@@ -527,6 +870,56 @@ func NewUserNotAllowedToDeleteInvitationError(user model.User, invitation model.
 	)
 }
 
+func NewInvitationExpiredError(invitation model.Invitation) *ErrorResponse {
+	return NewErrorResponse(
+		"invitation_expired",
+		http.StatusForbidden,
+		fmt.Sprintf("Invitation '%s' expired on '%s'.", invitation.GetID(), *invitation.GetExpiresAt()),
+		"This invitation has expired.",
+		nil,
+	)
+}
+
+func NewInvalidInvitationExpiryError() *ErrorResponse {
+	return NewErrorResponse(
+		"invalid_invitation_expiry",
+		http.StatusBadRequest,
+		"Invitation must expire in the future.",
+		"The invitation must expire in the future.",
+		nil,
+	)
+}
+
+func NewInvitationImportTooLargeError(maxRows int) *ErrorResponse {
+	return NewErrorResponse(
+		"invitation_import_too_large",
+		http.StatusBadRequest,
+		fmt.Sprintf("Invitation import exceeds the maximum of %d rows.", maxRows),
+		fmt.Sprintf("You can invite at most %d people at once.", maxRows),
+		nil,
+	)
+}
+
+func NewInvalidInvitationImportError(err error) *ErrorResponse {
+	return NewErrorResponse(
+		"invalid_invitation_import",
+		http.StatusBadRequest,
+		"Invitation import is not a valid CSV file.",
+		"The file is not a valid CSV file.",
+		err,
+	)
+}
+
+func NewGroupNotInOrganizationError(group model.Group, org model.Organization) *ErrorResponse {
+	return NewErrorResponse(
+		"group_not_in_organization",
+		http.StatusBadRequest,
+		fmt.Sprintf("Group '%s' is not in organization '%s'.", group.GetID(), org.GetID()),
+		fmt.Sprintf("Group '%s' belongs to another organization.", group.GetName()),
+		nil,
+	)
+}
+
 func NewUserAlreadyMemberOfOrganizationError(user model.User, org model.Organization) *ErrorResponse {
 	return NewErrorResponse(
 		"user_already_member_of_organization",
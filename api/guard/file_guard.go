@@ -11,6 +11,8 @@
 package guard
 
 import (
+	"slices"
+
 	"github.com/kouprlabs/voltaserve/api/cache"
 	"github.com/kouprlabs/voltaserve/api/errorpkg"
 	"github.com/kouprlabs/voltaserve/api/log"
@@ -18,40 +20,84 @@ import (
 )
 
 type FileGuard struct {
-	groupCache *cache.GroupCache
+	fileCache      *cache.FileCache
+	groupCache     *cache.GroupCache
+	roleCache      *cache.RoleCache
+	workspaceCache *cache.WorkspaceCache
+	orgGuard       *OrganizationGuard
 }
 
 func NewFileGuard() *FileGuard {
 	return &FileGuard{
-		groupCache: cache.NewGroupCache(),
+		fileCache:      cache.NewFileCache(),
+		groupCache:     cache.NewGroupCache(),
+		roleCache:      cache.NewRoleCache(),
+		workspaceCache: cache.NewWorkspaceCache(),
+		orgGuard:       NewOrganizationGuard(),
 	}
 }
 
 func (g *FileGuard) IsAuthorized(userID string, file model.File, permission string) bool {
-	for _, p := range file.GetUserPermissions() {
-		if p.GetUserID() == userID && model.IsEquivalentPermission(p.GetValue(), permission) {
-			return true
-		}
-	}
-	for _, p := range file.GetGroupPermissions() {
-		g, err := g.groupCache.Get(p.GetGroupID())
-		if err != nil {
-			log.GetLogger().Error(err)
-			return false
-		}
-		for _, u := range g.GetMembers() {
-			if u == userID && model.IsEquivalentPermission(p.GetValue(), permission) {
-				return true
-			}
-		}
-	}
-	return false
+	return model.IsEquivalentPermission(g.GetPermission(userID, file), permission)
 }
 
 func (g *FileGuard) Authorize(userID string, file model.File, permission string) error {
 	if !g.IsAuthorized(userID, file, permission) {
 		err := errorpkg.NewFilePermissionError(userID, file, permission)
 		if g.IsAuthorized(userID, file, model.PermissionViewer) {
+			if workspace := g.findArchivedWorkspace(file); workspace != nil {
+				return errorpkg.NewWorkspaceArchivedError(workspace)
+			}
+			return err
+		} else {
+			return errorpkg.NewFileNotFoundError(err)
+		}
+	}
+	return nil
+}
+
+// Filter returns the files on which the user has the permission, the permissions
+// inherited from the common ancestors are resolved only once.
+func (g *FileGuard) Filter(userID string, files []model.File, permission string) []model.File {
+	resolver := g.newResolver(userID)
+	var res []model.File
+	for _, f := range files {
+		if model.IsEquivalentPermission(resolver.resolve(f).permission, permission) {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// GetPermission resolves the effective permission of the user on the file.
+func (g *FileGuard) GetPermission(userID string, file model.File) string {
+	return g.newResolver(userID).resolve(file).permission
+}
+
+// Resolve returns both the effective permission and capabilities of the user on the file.
+func (g *FileGuard) Resolve(userID string, file model.File) (string, []string) {
+	res := g.newResolver(userID).resolve(file)
+	model.SortCapabilities(res.capabilities)
+	return res.permission, res.capabilities
+}
+
+func (g *FileGuard) GetCapabilities(userID string, file model.File) []string {
+	return g.newResolver(userID).resolve(file).capabilities
+}
+
+func (g *FileGuard) HasCapability(userID string, file model.File, capability string) bool {
+	return slices.Contains(g.GetCapabilities(userID, file), capability)
+}
+
+// AuthorizeCapability hides the file from users who cannot even list it, like Authorize.
+func (g *FileGuard) AuthorizeCapability(userID string, file model.File, capability string) error {
+	capabilities := g.GetCapabilities(userID, file)
+	if !slices.Contains(capabilities, capability) {
+		err := errorpkg.NewFileCapabilityError(userID, file, capability)
+		if slices.Contains(capabilities, model.CapabilityList) {
+			if workspace := g.findArchivedWorkspace(file); workspace != nil {
+				return errorpkg.NewWorkspaceArchivedError(workspace)
+			}
 			return err
 		} else {
 			return errorpkg.NewFileNotFoundError(err)
@@ -59,3 +105,233 @@ func (g *FileGuard) Authorize(userID string, file model.File, permission string)
 	}
 	return nil
 }
+
+// FilterCapability returns the files on which the user has the capability.
+func (g *FileGuard) FilterCapability(userID string, files []model.File, capability string) []model.File {
+	resolver := g.newResolver(userID)
+	var res []model.File
+	for _, f := range files {
+		if slices.Contains(resolver.resolve(f).capabilities, capability) {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// findArchivedWorkspace returns the workspace of the file if it's not active, so
+// denied writes can be told apart from missing permissions.
+func (g *FileGuard) findArchivedWorkspace(file model.File) model.Workspace {
+	workspace, err := g.workspaceCache.Get(file.GetWorkspaceID())
+	if err != nil {
+		log.GetLogger().Error(err)
+		return nil
+	}
+	if workspace.GetArchiveStatus() == nil {
+		return nil
+	}
+	return workspace
+}
+
+// IsOrganizationAdmin tells whether the user gets owner access to the file as an admin
+// of the organization the workspace belongs to, like WorkspaceGuard and GroupGuard do.
+func (g *FileGuard) IsOrganizationAdmin(userID string, file model.File) bool {
+	return g.newResolver(userID).isAdmin(file.GetWorkspaceID())
+}
+
+// FindInheritanceChain returns the file followed by the ancestors it inherits
+// permissions from, up to the root or the first ancestor breaking inheritance.
+func (g *FileGuard) FindInheritanceChain(file model.File) ([]model.File, error) {
+	res := []model.File{file}
+	for current := file; !current.GetBreaksInheritance() && current.GetParentID() != nil; {
+		parent, err := g.fileCache.Get(*current.GetParentID())
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, parent)
+		current = parent
+	}
+	return res, nil
+}
+
+// fileResolver resolves permissions through the ancestors of a file. Entries of
+// each node are applied from the root down: allow entries raise the permission,
+// then deny entries cap it, so a deny wins over an allow of the same node but
+// is overridden by an allow further down the tree. Capabilities follow the same
+// rules, allow entries add those of their role or permission, and deny entries
+// keep only those of the capped permission.
+type fileResolver struct {
+	guard     *FileGuard
+	userID    string
+	inherited map[string]fileAccess
+	groups    map[string]bool
+	roles     map[string][]string
+	archived  map[string]bool
+	admins    map[string]bool
+}
+
+type fileAccess struct {
+	permission   string
+	capabilities []string
+}
+
+func (g *FileGuard) newResolver(userID string) *fileResolver {
+	return &fileResolver{
+		guard:     g,
+		userID:    userID,
+		inherited: make(map[string]fileAccess),
+		groups:    make(map[string]bool),
+		roles:     make(map[string][]string),
+		archived:  make(map[string]bool),
+		admins:    make(map[string]bool),
+	}
+}
+
+// resolve gives owner access to the admins of the organization regardless of the grants and denies,
+// then caps the access to the files of workspaces that are not active to what a viewer can do,
+// as they are read-only until restored.
+func (r *fileResolver) resolve(file model.File) fileAccess {
+	var res fileAccess
+	if r.isAdmin(file.GetWorkspaceID()) {
+		res = fileAccess{
+			permission:   model.PermissionOwner,
+			capabilities: model.GetPermissionCapabilities(model.PermissionOwner),
+		}
+	} else {
+		res = r.apply(file, r.base(file), true)
+	}
+	if r.isArchived(file.GetWorkspaceID()) {
+		res.permission = model.CapPermission(res.permission, model.PermissionViewer)
+		kept := model.GetPermissionCapabilities(model.PermissionViewer)
+		res.capabilities = slices.DeleteFunc(res.capabilities, func(capability string) bool {
+			return !slices.Contains(kept, capability)
+		})
+	}
+	return res
+}
+
+// inheritedFrom returns the access the file passes down to its children.
+func (r *fileResolver) inheritedFrom(file model.File) fileAccess {
+	if res, ok := r.inherited[file.GetID()]; ok {
+		return res
+	}
+	res := r.apply(file, r.base(file), false)
+	r.inherited[file.GetID()] = res
+	return res
+}
+
+func (r *fileResolver) base(file model.File) fileAccess {
+	none := fileAccess{permission: model.PermissionNone, capabilities: []string{}}
+	if file.GetBreaksInheritance() || file.GetParentID() == nil {
+		return none
+	}
+	parent, err := r.guard.fileCache.Get(*file.GetParentID())
+	if err != nil {
+		log.GetLogger().Error(err)
+		return none
+	}
+	return r.inheritedFrom(parent)
+}
+
+func (r *fileResolver) apply(file model.File, base fileAccess, isTarget bool) fileAccess {
+	allowed, denied := model.PermissionNone, ""
+	capabilities := slices.Clone(base.capabilities)
+	accumulate := func(value string, effect string, scope string, startsAt *string, expiresAt *string, roleID *string) {
+		if (!isTarget && scope == model.PermissionScopeNode) || !model.IsActivePermission(startsAt, expiresAt) {
+			return
+		}
+		if effect == model.PermissionEffectDeny {
+			if denied == "" || model.GetPermissionWeight(value) < model.GetPermissionWeight(denied) {
+				denied = value
+			}
+		} else {
+			allowed = model.MaxPermission(allowed, value)
+			for _, capability := range r.capabilities(value, roleID) {
+				if !slices.Contains(capabilities, capability) {
+					capabilities = append(capabilities, capability)
+				}
+			}
+		}
+	}
+	for _, p := range file.GetUserPermissions() {
+		if p.GetUserID() == r.userID {
+			accumulate(p.GetValue(), p.GetEffect(), p.GetScope(), p.GetStartsAt(), p.GetExpiresAt(), p.GetRoleID())
+		}
+	}
+	for _, p := range file.GetGroupPermissions() {
+		if r.isMember(p.GetGroupID()) {
+			accumulate(p.GetValue(), p.GetEffect(), p.GetScope(), p.GetStartsAt(), p.GetExpiresAt(), p.GetRoleID())
+		}
+	}
+	res := fileAccess{
+		permission:   model.MaxPermission(base.permission, allowed),
+		capabilities: capabilities,
+	}
+	if denied != "" {
+		res.permission = model.CapPermission(res.permission, denied)
+		kept := model.GetPermissionCapabilities(model.CapPermission(model.PermissionOwner, denied))
+		res.capabilities = slices.DeleteFunc(res.capabilities, func(capability string) bool {
+			return !slices.Contains(kept, capability)
+		})
+	}
+	return res
+}
+
+// capabilities returns those of the entry's role, or of its permission if it has none.
+func (r *fileResolver) capabilities(permission string, roleID *string) []string {
+	if roleID == nil {
+		return model.GetPermissionCapabilities(permission)
+	}
+	if res, ok := r.roles[*roleID]; ok {
+		return res
+	}
+	role, err := r.guard.roleCache.Get(*roleID)
+	if err != nil {
+		log.GetLogger().Error(err)
+		return model.GetPermissionCapabilities(permission)
+	}
+	res := role.GetCapabilities()
+	r.roles[*roleID] = res
+	return res
+}
+
+func (r *fileResolver) isArchived(workspaceID string) bool {
+	if res, ok := r.archived[workspaceID]; ok {
+		return res
+	}
+	workspace, err := r.guard.workspaceCache.Get(workspaceID)
+	if err != nil {
+		log.GetLogger().Error(err)
+		return false
+	}
+	res := workspace.GetArchiveStatus() != nil
+	r.archived[workspaceID] = res
+	return res
+}
+
+func (r *fileResolver) isAdmin(workspaceID string) bool {
+	if res, ok := r.admins[workspaceID]; ok {
+		return res
+	}
+	workspace, err := r.guard.workspaceCache.Get(workspaceID)
+	if err != nil {
+		log.GetLogger().Error(err)
+		return false
+	}
+	res := r.guard.orgGuard.IsAdmin(r.userID, workspace.GetOrganizationID())
+	r.admins[workspaceID] = res
+	return res
+}
+
+func (r *fileResolver) isMember(groupID string) bool {
+	if res, ok := r.groups[groupID]; ok {
+		return res
+	}
+	group, err := r.guard.groupCache.Get(groupID)
+	if err != nil {
+		log.GetLogger().Error(err)
+		return false
+	}
+	res := slices.Contains(group.GetMembers(), r.userID)
+	r.groups[groupID] = res
+	return res
+}
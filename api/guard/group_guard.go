@@ -19,17 +19,23 @@ import (
 
 type GroupGuard struct {
 	groupCache *cache.GroupCache
+	orgGuard   *OrganizationGuard
 }
 
 func NewGroupGuard() *GroupGuard {
 	return &GroupGuard{
 		groupCache: cache.NewGroupCache(),
+		orgGuard:   NewOrganizationGuard(),
 	}
 }
 
 func (g *GroupGuard) IsAuthorized(userID string, group model.Group, permission string) bool {
+	if g.orgGuard.IsAdmin(userID, group.GetOrganizationID()) {
+		return true
+	}
 	for _, p := range group.GetUserPermissions() {
-		if p.GetUserID() == userID && model.IsEquivalentPermission(p.GetValue(), permission) {
+		if p.GetUserID() == userID && model.IsEquivalentPermission(p.GetValue(), permission) &&
+			model.IsActivePermission(p.GetStartsAt(), p.GetExpiresAt()) {
 			return true
 		}
 	}
@@ -40,7 +46,8 @@ func (g *GroupGuard) IsAuthorized(userID string, group model.Group, permission s
 			return false
 		}
 		for _, u := range g.GetMembers() {
-			if u == userID && model.IsEquivalentPermission(p.GetValue(), permission) {
+			if u == userID && model.IsEquivalentPermission(p.GetValue(), permission) &&
+				model.IsActivePermission(p.GetStartsAt(), p.GetExpiresAt()) {
 				return true
 			}
 		}
@@ -18,11 +18,13 @@ import (
 )
 
 type OrganizationGuard struct {
+	orgCache   *cache.OrganizationCache
 	groupCache *cache.GroupCache
 }
 
 func NewOrganizationGuard() *OrganizationGuard {
 	return &OrganizationGuard{
+		orgCache:   cache.NewOrganizationCache(),
 		groupCache: cache.NewGroupCache(),
 	}
 }
@@ -48,6 +50,17 @@ func (g *OrganizationGuard) IsAuthorized(userID string, org model.Organization,
 	return false
 }
 
+// IsAdmin tells whether the user has the admin role in the organization, which
+// grants ownership of all its workspaces and groups.
+func (g *OrganizationGuard) IsAdmin(userID string, orgID string) bool {
+	org, err := g.orgCache.Get(orgID)
+	if err != nil {
+		log.GetLogger().Error(err)
+		return false
+	}
+	return g.IsAuthorized(userID, org, model.GetOrganizationRolePermission(model.OrganizationRoleAdmin))
+}
+
 func (g *OrganizationGuard) Authorize(userID string, org model.Organization, permission string) error {
 	if !g.IsAuthorized(userID, org, permission) {
 		err := errorpkg.NewOrganizationPermissionError(userID, org, permission)
@@ -19,17 +19,23 @@ import (
 
 type WorkspaceGuard struct {
 	groupCache *cache.GroupCache
+	orgGuard   *OrganizationGuard
 }
 
 func NewWorkspaceGuard() *WorkspaceGuard {
 	return &WorkspaceGuard{
 		groupCache: cache.NewGroupCache(),
+		orgGuard:   NewOrganizationGuard(),
 	}
 }
 
 func (g *WorkspaceGuard) IsAuthorized(userID string, workspace model.Workspace, permission string) bool {
+	if g.orgGuard.IsAdmin(userID, workspace.GetOrganizationID()) {
+		return true
+	}
 	for _, p := range workspace.GetUserPermissions() {
-		if p.GetUserID() == userID && model.IsEquivalentPermission(p.GetValue(), permission) {
+		if p.GetUserID() == userID && model.IsEquivalentPermission(p.GetValue(), permission) &&
+			model.IsActivePermission(p.GetStartsAt(), p.GetExpiresAt()) {
 			return true
 		}
 	}
@@ -40,7 +46,8 @@ func (g *WorkspaceGuard) IsAuthorized(userID string, workspace model.Workspace,
 			return false
 		}
 		for _, u := range g.GetMembers() {
-			if u == userID && model.IsEquivalentPermission(p.GetValue(), permission) {
+			if u == userID && model.IsEquivalentPermission(p.GetValue(), permission) &&
+				model.IsActivePermission(p.GetStartsAt(), p.GetExpiresAt()) {
 				return true
 			}
 		}
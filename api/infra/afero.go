@@ -15,6 +15,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/spf13/afero"
@@ -124,6 +125,44 @@ func (am *aferoManager) RemoveFolder(objectName string, bucketName string, opts
 	return am.fs.RemoveAll(am.getObjectPath(objectName, bucketName))
 }
 
+func (am *aferoManager) CopyFolder(sourceName string, sourceBucketName string, targetName string, targetBucketName string) error {
+	sourcePath := am.getObjectPath(sourceName, sourceBucketName)
+	targetPath := am.getObjectPath(targetName, targetBucketName)
+	return afero.Walk(am.fs, sourcePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := afero.ReadFile(am.fs, path)
+		if err != nil {
+			return err
+		}
+		return afero.WriteFile(am.fs, filepath.Join(targetPath, strings.TrimPrefix(path, sourcePath)), data, 0o644)
+	})
+}
+
+func (am *aferoManager) ListFolder(objectName string, bucketName string) ([]string, error) {
+	bucketPath := am.getBucketPath(bucketName)
+	var res []string
+	if err := afero.Walk(am.fs, am.getObjectPath(objectName, bucketName), func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		res = append(res, strings.TrimPrefix(strings.TrimPrefix(path, bucketPath), "/"))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (am *aferoManager) MoveObject(objectName string, sourceBucketName string, targetBucketName string, _ string) error {
+	targetPath := am.getObjectPath(objectName, targetBucketName)
+	if err := am.fs.MkdirAll(filepath.Dir(targetPath), 0o755); err != nil {
+		return err
+	}
+	return am.fs.Rename(am.getObjectPath(objectName, sourceBucketName), targetPath)
+}
+
 func (am *aferoManager) CreateBucket(bucketName string) error {
 	return am.fs.MkdirAll(am.getBucketPath(bucketName), 0o755)
 }
@@ -30,6 +30,7 @@ func newBleveSearchManager() SearchManager {
 		indexes: make(map[string]bleve.Index),
 	}
 	manager.createIndex(FileSearchIndex)
+	manager.createIndex(FilePageSearchIndex)
 	manager.createIndex(GroupSearchIndex)
 	manager.createIndex(WorkspaceSearchIndex)
 	manager.createIndex(OrganizationSearchIndex)
@@ -103,6 +104,30 @@ func (mgr *bleveSearchManager) Delete(indexName string, ids []string) error {
 	return index.Batch(batch)
 }
 
+func (mgr *bleveSearchManager) DeleteByFilter(indexName string, filter interface{}) error {
+	index, ok := mgr.indexes[indexName]
+	if !ok {
+		return errors.New("index not found")
+	}
+	count, err := index.DocCount()
+	if err != nil {
+		return err
+	}
+	conjunctionQuery := bleve.NewConjunctionQuery(bleve.NewMatchAllQuery())
+	for _, v := range mgr.buildFilter(filter) {
+		conjunctionQuery.AddQuery(v)
+	}
+	searchResult, err := index.Search(bleve.NewSearchRequestOptions(conjunctionQuery, int(count), 0, false))
+	if err != nil {
+		return err
+	}
+	batch := index.NewBatch()
+	for _, hit := range searchResult.Hits {
+		batch.Delete(hit.ID)
+	}
+	return index.Batch(batch)
+}
+
 func (mgr *bleveSearchManager) createIndex(indexName string) {
 	index, err := bleve.NewMemOnly(bleve.NewIndexMapping())
 	if err != nil {
@@ -69,7 +69,7 @@ func (fi *FileIdentifier) IsPlainText(path string) bool {
 		".txt",
 		".html",
 		".js",
-		"jsx",
+		".jsx",
 		".ts",
 		".tsx",
 		".css",
@@ -88,6 +88,7 @@ func (fi *FileIdentifier) IsPlainText(path string) bool {
 		".yaml",
 		".toml",
 		".md",
+		".markdown",
 	}
 	extension := filepath.Ext(path)
 	for _, v := range extensions {
@@ -111,9 +112,18 @@ func (fi *FileIdentifier) IsImage(path string) bool {
 		".tif",
 		".bmp",
 		".ico",
+		".heic",
 		".heif",
 		".xcf",
 		".svg",
+		".cr2",
+		".cr3",
+		".nef",
+		".arw",
+		".dng",
+		".raf",
+		".psd",
+		".psb",
 	}
 	extension := filepath.Ext(path)
 	for _, v := range extensions {
@@ -189,6 +199,45 @@ func (fi *FileIdentifier) IsGLB(path string) bool {
 	return false
 }
 
+// IsModel matches the 3D formats that the conversion converts to GLB,
+// .glb files and zipped glTF excepted.
+func (fi *FileIdentifier) IsModel(path string) bool {
+	extensions := []string{
+		".obj",
+		".fbx",
+		".stl",
+		".ply",
+		".usd",
+		".usda",
+		".usdc",
+		".usdz",
+		".step",
+		".stp",
+	}
+	extension := filepath.Ext(path)
+	for _, v := range extensions {
+		if strings.ToLower(extension) == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (fi *FileIdentifier) IsEmail(path string) bool {
+	extensions := []string{
+		".eml",
+		".msg",
+		".mbox",
+	}
+	extension := filepath.Ext(path)
+	for _, v := range extensions {
+		if strings.ToLower(extension) == v {
+			return true
+		}
+	}
+	return false
+}
+
 func (fi *FileIdentifier) IsZIP(path string) bool {
 	extensions := []string{
 		".zip",
@@ -273,6 +322,10 @@ func (fi *FileIdentifier) GetProcessingLimitMB(path string) int {
 		res = fi.config.Limits.GetFileProcessingMB(config.FileTypeVideo)
 	} else if fi.IsGLB(path) {
 		res = fi.config.Limits.GetFileProcessingMB(config.FileTypeGLB)
+	} else if fi.IsModel(path) {
+		res = fi.config.Limits.GetFileProcessingMB(config.FileTypeModel)
+	} else if fi.IsEmail(path) {
+		res = fi.config.Limits.GetFileProcessingMB(config.FileTypeEmail)
 	} else if fi.IsZIP(path) {
 		res = fi.config.Limits.GetFileProcessingMB(config.FileTypeZIP)
 	} else if ok, err := fi.IsGLTF(path); ok && err != nil {
@@ -32,6 +32,9 @@ func newMeilisearchManager() *meilisearchManager {
 	if err := mgr.createFileIndex(); err != nil {
 		panic(err)
 	}
+	if err := mgr.createFilePageIndex(); err != nil {
+		panic(err)
+	}
 	if err := mgr.createGroupIndex(); err != nil {
 		panic(err)
 	}
@@ -85,6 +88,14 @@ func (mgr *meilisearchManager) Delete(index string, ids []string) error {
 	return nil
 }
 
+func (mgr *meilisearchManager) DeleteByFilter(index string, filter interface{}) error {
+	_, err := meilisearchClient.Index(index).DeleteDocumentsByFilter(filter)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func (mgr *meilisearchManager) createFileIndex() error {
 	if _, err := meilisearchClient.CreateIndex(&meilisearch.IndexConfig{
 		Uid:        FileSearchIndex,
@@ -93,7 +104,7 @@ func (mgr *meilisearchManager) createFileIndex() error {
 		return err
 	}
 	if _, err := meilisearchClient.Index(FileSearchIndex).UpdateSettings(&meilisearch.Settings{
-		SearchableAttributes: []string{"name", "text"},
+		SearchableAttributes: []string{"name", "keywords", "camera", "lens", "caption", "sender", "recipients", "summary", "text"},
 		FilterableAttributes: []string{
 			"id",
 			"workspaceId",
@@ -102,6 +113,31 @@ func (mgr *meilisearchManager) createFileIndex() error {
 			"snapshotId",
 			"createTime",
 			"updateTime",
+			"camera",
+			"captureTime",
+			"captureMonth",
+			"rating",
+		},
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (mgr *meilisearchManager) createFilePageIndex() error {
+	if _, err := meilisearchClient.CreateIndex(&meilisearch.IndexConfig{
+		Uid:        FilePageSearchIndex,
+		PrimaryKey: "id",
+	}); err != nil {
+		return err
+	}
+	if _, err := meilisearchClient.Index(FilePageSearchIndex).UpdateSettings(&meilisearch.Settings{
+		SearchableAttributes: []string{"text"},
+		FilterableAttributes: []string{
+			"id",
+			"fileId",
+			"workspaceId",
+			"page",
 		},
 	}); err != nil {
 		return err
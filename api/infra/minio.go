@@ -176,6 +176,80 @@ func (mgr *minioManager) RemoveFolder(objectName string, bucketName string, opts
 	return nil
 }
 
+// CopyFolder copies the objects under the source prefix server-side, keeping their path relative to it.
+func (mgr *minioManager) CopyFolder(sourceName string, sourceBucketName string, targetName string, targetBucketName string) error {
+	if err := mgr.Connect(); err != nil {
+		return err
+	}
+	objectCh := mgr.client.ListObjects(context.Background(), sourceBucketName, minio.ListObjectsOptions{
+		Prefix:    sourceName,
+		Recursive: true,
+	})
+	for object := range objectCh {
+		if object.Err != nil {
+			return object.Err
+		}
+		if _, err := mgr.client.CopyObject(context.Background(), minio.CopyDestOptions{
+			Bucket: targetBucketName,
+			Object: targetName + strings.TrimPrefix(object.Key, sourceName),
+		}, minio.CopySrcOptions{
+			Bucket: sourceBucketName,
+			Object: object.Key,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mgr *minioManager) ListFolder(objectName string, bucketName string) ([]string, error) {
+	if err := mgr.Connect(); err != nil {
+		return nil, err
+	}
+	objectCh := mgr.client.ListObjects(context.Background(), bucketName, minio.ListObjectsOptions{
+		Prefix:    objectName,
+		Recursive: true,
+	})
+	var res []string
+	for object := range objectCh {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		res = append(res, object.Key)
+	}
+	return res, nil
+}
+
+// MoveObject copies the object server-side then removes the source, the copy gets the storage
+// class when given, which requires replacing the metadata so we carry the content type over.
+func (mgr *minioManager) MoveObject(objectName string, sourceBucketName string, targetBucketName string, storageClass string) error {
+	if err := mgr.Connect(); err != nil {
+		return err
+	}
+	dst := minio.CopyDestOptions{
+		Bucket: targetBucketName,
+		Object: objectName,
+	}
+	if storageClass != "" {
+		info, err := mgr.client.StatObject(context.Background(), sourceBucketName, objectName, minio.StatObjectOptions{})
+		if err != nil {
+			return err
+		}
+		dst.ReplaceMetadata = true
+		dst.UserMetadata = map[string]string{
+			"Content-Type":        info.ContentType,
+			"X-Amz-Storage-Class": storageClass,
+		}
+	}
+	if _, err := mgr.client.CopyObject(context.Background(), dst, minio.CopySrcOptions{
+		Bucket: sourceBucketName,
+		Object: objectName,
+	}); err != nil {
+		return err
+	}
+	return mgr.client.RemoveObject(context.Background(), sourceBucketName, objectName, minio.RemoveObjectOptions{})
+}
+
 func (mgr *minioManager) CreateBucket(bucketName string) error {
 	if err := mgr.Connect(); err != nil {
 		return err
@@ -13,6 +13,7 @@ package infra
 import (
 	"context"
 	"strings"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 
@@ -47,6 +48,19 @@ func (mgr *RedisManager) Set(key string, value interface{}) error {
 	return nil
 }
 
+// SetNX sets the key only if it doesn't exist, it expires after the given duration,
+// so it can be used as a lock that is released even if its holder goes away.
+func (mgr *RedisManager) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	if err := mgr.Connect(); err != nil {
+		return false, err
+	}
+	if mgr.clusterClient != nil {
+		return mgr.clusterClient.SetNX(context.Background(), key, value, expiration).Result()
+	} else {
+		return mgr.client.SetNX(context.Background(), key, value, expiration).Result()
+	}
+}
+
 func (mgr *RedisManager) Get(key string) (string, error) {
 	if err := mgr.Connect(); err != nil {
 		return "", err
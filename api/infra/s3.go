@@ -12,6 +12,8 @@ package infra
 
 import (
 	"bytes"
+	"errors"
+	"io/fs"
 
 	"github.com/minio/minio-go/v7"
 
@@ -29,10 +31,22 @@ type S3Manager interface {
 	GetText(objectName string, bucketName string, opts minio.GetObjectOptions) (string, error)
 	RemoveObject(objectName string, bucketName string, opts minio.RemoveObjectOptions) error
 	RemoveFolder(objectName string, bucketName string, opts minio.RemoveObjectOptions) error
+	CopyFolder(sourceName string, sourceBucketName string, targetName string, targetBucketName string) error
+	ListFolder(objectName string, bucketName string) ([]string, error)
+	MoveObject(objectName string, sourceBucketName string, targetBucketName string, storageClass string) error
 	CreateBucket(bucketName string) error
 	RemoveBucket(bucketName string) error
 }
 
+// IsS3ObjectNotFound tells whether the error means the object doesn't exist,
+// as opposed to the storage failing.
+func IsS3ObjectNotFound(err error) bool {
+	if errors.Is(err, fs.ErrNotExist) {
+		return true
+	}
+	return minio.ToErrorResponse(err).Code == "NoSuchKey"
+}
+
 func NewS3Manager() S3Manager {
 	if config.GetConfig().Environment.IsTest {
 		return newAferoManager()
@@ -17,6 +17,7 @@ type SearchManager interface {
 	Index(index string, models []SearchModel) error
 	Update(index string, models []SearchModel) error
 	Delete(index string, ids []string) error
+	DeleteByFilter(index string, filter interface{}) error
 }
 
 func NewSearchManager() SearchManager {
@@ -38,6 +39,7 @@ type QueryOptions struct {
 
 const (
 	FileSearchIndex         = "file"
+	FilePageSearchIndex     = "file_page"
 	GroupSearchIndex        = "group"
 	WorkspaceSearchIndex    = "workspace"
 	OrganizationSearchIndex = "organization"
@@ -24,6 +24,7 @@ import (
 	"github.com/kouprlabs/voltaserve/api/errorpkg"
 	"github.com/kouprlabs/voltaserve/api/helper"
 	"github.com/kouprlabs/voltaserve/api/router"
+	"github.com/kouprlabs/voltaserve/api/service"
 )
 
 // @title		Voltaserve API
@@ -113,6 +114,16 @@ func main() {
 	groups := router.NewGroupRouter()
 	groups.AppendRoutes(v3.Group("groups"))
 
+	roles := router.NewRoleRouter()
+	roles.AppendRoutes(v3.Group("roles"))
+
+	workspaceTemplates := router.NewWorkspaceTemplateRouter()
+	workspaceTemplates.AppendRoutes(v3.Group("workspace_templates"))
+
+	go service.NewPermissionService().Start()
+	go service.NewInvitationService().Start()
+	go service.NewStorageService().Start()
+
 	if err := app.Listen(fmt.Sprintf(":%d", cfg.Port)); err != nil {
 		panic(err)
 	}
@@ -24,7 +24,11 @@ type File interface {
 	GetUserPermissions() []CoreUserPermission
 	GetGroupPermissions() []CoreGroupPermission
 	GetText() *string
+	GetSummary() *string
+	GetKeywords() []string
+	GetMediaMetadata() *MediaMetadata
 	GetSnapshotID() *string
+	GetBreaksInheritance() bool
 	GetCreateTime() string
 	GetUpdateTime() *string
 	SetID(string)
@@ -33,7 +37,11 @@ type File interface {
 	SetType(string)
 	SetName(string)
 	SetText(*string)
+	SetSummary(*string)
+	SetKeywords([]string)
+	SetMediaMetadata(*MediaMetadata)
 	SetSnapshotID(*string)
+	SetBreaksInheritance(bool)
 	SetUserPermissions([]CoreUserPermission)
 	SetGroupPermissions([]CoreGroupPermission)
 	SetCreateTime(string)
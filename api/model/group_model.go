@@ -17,6 +17,7 @@ type Group interface {
 	GetUserPermissions() []CoreUserPermission
 	GetGroupPermissions() []CoreGroupPermission
 	GetMembers() []string
+	GetSubgroups() []string
 	GetCreateTime() string
 	GetUpdateTime() *string
 	SetName(string)
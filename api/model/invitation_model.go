@@ -14,6 +14,7 @@ const (
 	InvitationStatusPending  = "pending"
 	InvitationStatusAccepted = "accepted"
 	InvitationStatusDeclined = "declined"
+	InvitationStatusExpired  = "expired"
 )
 
 type Invitation interface {
@@ -22,6 +23,9 @@ type Invitation interface {
 	GetOwnerID() string
 	GetEmail() string
 	GetStatus() string
+	GetRole() string
+	GetGroupIDs() []string
+	GetExpiresAt() *string
 	GetCreateTime() string
 	GetUpdateTime() *string
 	SetStatus(string)
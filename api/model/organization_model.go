@@ -10,18 +10,57 @@
 
 package model
 
+// Organization roles are stored as permissions on the organization, admins can
+// manage every workspace and group of the organization.
+const (
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+	OrganizationRoleGuest  = "guest"
+)
+
+var organizationRolePermissions = map[string]string{
+	OrganizationRoleAdmin:  PermissionOwner,
+	OrganizationRoleMember: PermissionEditor,
+	OrganizationRoleGuest:  PermissionViewer,
+}
+
 type Organization interface {
 	GetID() string
 	GetName() string
 	GetUserPermissions() []CoreUserPermission
 	GetGroupPermissions() []CoreGroupPermission
 	GetMembers() []string
+	// GetStorageCapacity caps the usage of all the workspaces, and GetMemberStorageCapacity
+	// the uploads of each member, both are unlimited when nil.
+	GetStorageCapacity() *int64
+	GetMemberStorageCapacity() *int64
 	GetCreateTime() string
 	GetUpdateTime() *string
 	SetID(string)
 	SetName(string)
 	SetUserPermissions([]CoreUserPermission)
 	SetGroupPermissions([]CoreGroupPermission)
+	SetStorageCapacity(*int64)
+	SetMemberStorageCapacity(*int64)
 	SetCreateTime(string)
 	SetUpdateTime(*string)
 }
+
+func IsValidOrganizationRole(role string) bool {
+	_, ok := organizationRolePermissions[role]
+	return ok
+}
+
+func GetOrganizationRolePermission(role string) string {
+	return organizationRolePermissions[role]
+}
+
+// GetOrganizationRole returns the role matching the permission, or an empty string for 'none'.
+func GetOrganizationRole(permission string) string {
+	for role, p := range organizationRolePermissions {
+		if p == permission {
+			return role
+		}
+	}
+	return ""
+}
@@ -10,6 +10,11 @@
 
 package model
 
+import (
+	"slices"
+	"time"
+)
+
 const (
 	PermissionNone   = "none"
 	PermissionViewer = "viewer"
@@ -17,16 +22,91 @@ const (
 	PermissionOwner  = "owner"
 )
 
+const (
+	PermissionEffectAllow = "allow"
+	PermissionEffectDeny  = "deny"
+)
+
+const (
+	// PermissionScopeTree entries are inherited by the descendants of the resource.
+	PermissionScopeTree = "tree"
+	// PermissionScopeNode entries apply to the resource only, they let users reach
+	// a shared file through its ancestors without seeing their other children.
+	PermissionScopeNode = "node"
+)
+
+const (
+	CapabilityList             = "list"
+	CapabilityPreview          = "preview"
+	CapabilityDownloadOriginal = "download_original"
+	CapabilityUpload           = "upload"
+	CapabilityRename           = "rename"
+	CapabilityMove             = "move"
+	CapabilityDelete           = "delete"
+	CapabilityShare            = "share"
+	CapabilityManageVersions   = "manage_versions"
+	CapabilityRunInsights      = "run_insights"
+	CapabilityCreateMosaic     = "create_mosaic"
+)
+
+// permissionCapabilities are the capabilities of the built-in permissions, they match
+// what each permission allowed before capabilities existed.
+var permissionCapabilities = map[string][]string{
+	PermissionViewer: {
+		CapabilityList,
+		CapabilityPreview,
+		CapabilityDownloadOriginal,
+	},
+	PermissionEditor: {
+		CapabilityList,
+		CapabilityPreview,
+		CapabilityDownloadOriginal,
+		CapabilityUpload,
+		CapabilityRename,
+		CapabilityMove,
+		CapabilityManageVersions,
+		CapabilityRunInsights,
+		CapabilityCreateMosaic,
+	},
+	PermissionOwner: {
+		CapabilityList,
+		CapabilityPreview,
+		CapabilityDownloadOriginal,
+		CapabilityUpload,
+		CapabilityRename,
+		CapabilityMove,
+		CapabilityDelete,
+		CapabilityShare,
+		CapabilityManageVersions,
+		CapabilityRunInsights,
+		CapabilityCreateMosaic,
+	},
+}
+
 type UserPermission interface {
 	GetID() string
 	GetUserID() string
 	GetResourceID() string
 	GetPermission() string
+	GetEffect() string
+	GetScope() string
+	GetStartsAt() *string
+	GetExpiresAt() *string
+	GetGrantedBy() *string
+	GetReminderTime() *string
+	GetRoleID() *string
 	GetCreateTime() string
 	SetID(string)
 	SetUserID(string)
 	SetResourceID(string)
 	SetPermission(string)
+	SetEffect(string)
+	SetScope(string)
+	SetStartsAt(*string)
+	SetExpiresAt(*string)
+	SetGrantedBy(*string)
+	SetReminderTime(*string)
+	SetRoleID(*string)
 	SetCreateTime(string)
 }
 
@@ -35,22 +115,46 @@ type GroupPermission interface {
 	GetGroupID() string
 	GetResourceID() string
 	GetPermission() string
+	GetEffect() string
+	GetScope() string
+	GetStartsAt() *string
+	GetExpiresAt() *string
+	GetGrantedBy() *string
+	GetReminderTime() *string
+	GetRoleID() *string
 	GetCreateTime() string
 	SetID(string)
 	SetGroupID(string)
 	SetResourceID(string)
 	SetPermission(string)
+	SetEffect(string)
+	SetScope(string)
+	SetStartsAt(*string)
+	SetExpiresAt(*string)
+	SetGrantedBy(*string)
+	SetReminderTime(*string)
+	SetRoleID(*string)
 	SetCreateTime(string)
 }
 
 type CoreUserPermission interface {
 	GetUserID() string
 	GetValue() string
+	GetEffect() string
+	GetScope() string
+	GetStartsAt() *string
+	GetExpiresAt() *string
+	GetRoleID() *string
 }
 
 type CoreGroupPermission interface {
 	GetGroupID() string
 	GetValue() string
+	GetEffect() string
+	GetScope() string
+	GetStartsAt() *string
+	GetExpiresAt() *string
+	GetRoleID() *string
 }
 
 func GteViewerPermission(permission string) bool {
@@ -93,3 +197,83 @@ func GetPermissionWeight(permission string) int {
 	}
 	return 0
 }
+
+func GetPermissionFromWeight(weight int) string {
+	if weight >= 3 {
+		return PermissionOwner
+	}
+	if weight == 2 {
+		return PermissionEditor
+	}
+	if weight == 1 {
+		return PermissionViewer
+	}
+	return PermissionNone
+}
+
+func MaxPermission(permission string, otherPermission string) string {
+	if GetPermissionWeight(otherPermission) > GetPermissionWeight(permission) {
+		return otherPermission
+	}
+	return permission
+}
+
+// CapPermission lowers the permission below the denied one, denying 'viewer' leaves no access.
+func CapPermission(permission string, denied string) string {
+	return GetPermissionFromWeight(min(GetPermissionWeight(permission), GetPermissionWeight(denied)-1))
+}
+
+// IsActivePermission tells whether a grant limited in time applies right now.
+func IsActivePermission(startsAt *string, expiresAt *string) bool {
+	now := time.Now()
+	if startsAt != nil {
+		if t, err := time.Parse(time.RFC3339, *startsAt); err == nil && now.Before(t) {
+			return false
+		}
+	}
+	if expiresAt != nil {
+		if t, err := time.Parse(time.RFC3339, *expiresAt); err == nil && !now.Before(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func IsValidCapability(capability string) bool {
+	return slices.Contains(permissionCapabilities[PermissionOwner], capability)
+}
+
+// GetPermissionCapabilities returns the capabilities of a built-in permission.
+func GetPermissionCapabilities(permission string) []string {
+	return slices.Clone(permissionCapabilities[permission])
+}
+
+// SortCapabilities puts the capabilities in the order they are declared in.
+func SortCapabilities(capabilities []string) {
+	slices.SortFunc(capabilities, func(a string, b string) int {
+		return slices.Index(permissionCapabilities[PermissionOwner], a) -
+			slices.Index(permissionCapabilities[PermissionOwner], b)
+	})
+}
+
+// GetPermissionFromCapabilities returns the highest built-in permission whose capabilities
+// are all included, a set without 'list' doesn't give access at all. Grants of custom roles
+// store it so checks that don't need a specific capability keep working.
+func GetPermissionFromCapabilities(capabilities []string) string {
+	if !slices.Contains(capabilities, CapabilityList) {
+		return PermissionNone
+	}
+	for _, permission := range []string{PermissionOwner, PermissionEditor} {
+		covered := true
+		for _, capability := range permissionCapabilities[permission] {
+			if !slices.Contains(capabilities, capability) {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return permission
+		}
+	}
+	return PermissionViewer
+}
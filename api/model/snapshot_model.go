@@ -24,18 +24,29 @@ type Snapshot interface {
 	GetText() *S3Object
 	GetOCR() *S3Object
 	GetEntities() *S3Object
+	GetLayout() *S3Object
+	GetSummary() *S3Object
+	GetMetadata() *S3Object
+	GetSheets() *S3Object
 	GetMosaic() *S3Object
 	GetThumbnail() *S3Object
 	GetTaskID() *string
+	// GetUserID returns the uploader, it's nil for snapshots created before uploads were attributed.
+	GetUserID() *string
 	HasOriginal() bool
 	HasPreview() bool
 	HasText() bool
 	HasOCR() bool
 	HasEntities() bool
+	HasLayout() bool
+	HasSummary() bool
+	HasMetadata() bool
+	HasSheets() bool
 	HasMosaic() bool
 	HasThumbnail() bool
 	GetStatus() string
 	GetLanguage() *string
+	GetLanguageConfidence() *float64
 	GetCreateTime() string
 	GetUpdateTime() *string
 	SetID(string)
@@ -45,11 +56,17 @@ type Snapshot interface {
 	SetText(*S3Object)
 	SetOCR(*S3Object)
 	SetEntities(*S3Object)
+	SetLayout(*S3Object)
+	SetSummary(*S3Object)
+	SetMetadata(*S3Object)
+	SetSheets(*S3Object)
 	SetMosaic(*S3Object)
 	SetThumbnail(*S3Object)
 	SetStatus(string)
 	SetLanguage(string)
+	SetLanguageConfidence(*float64)
 	SetTaskID(*string)
+	SetUserID(*string)
 }
 
 type S3Object struct {
@@ -58,19 +75,51 @@ type S3Object struct {
 	Size     *int64         `json:"size,omitempty"`
 	Image    *ImageProps    `json:"image,omitempty"`
 	Document *DocumentProps `json:"document,omitempty"`
+	Model    *ModelProps    `json:"model,omitempty"`
+	Workbook *WorkbookProps `json:"workbook,omitempty"`
+	Audio    *AudioProps    `json:"audio,omitempty"`
+}
+
+// AudioProps describes the renditions of the audio track of audio and video
+// files, which are stored next to the preview.
+type AudioProps struct {
+	Waveform   *WaveformProps   `json:"waveform,omitempty"`
+	Transcript *TranscriptProps `json:"transcript,omitempty"`
+}
+
+type WaveformProps struct {
+	Peaks     int    `json:"peaks"`
+	Extension string `json:"extension"`
+}
+
+type TranscriptProps struct {
+	Extension string `json:"extension"`
 }
 
 type ImageProps struct {
 	Width      int         `json:"width"`
 	Height     int         `json:"height"`
+	ColorSpace *string     `json:"colorSpace,omitempty"`
 	ZoomLevels []ZoomLevel `json:"zoomLevels,omitempty"`
 }
 
 type DocumentProps struct {
 	Pages      *PagesProps      `json:"pages,omitempty"`
 	Thumbnails *ThumbnailsProps `json:"thumbnails,omitempty"`
+	Slides     *SlidesProps     `json:"slides,omitempty"`
+	// Cache is the conversion the preview was copied from, it's shared by the
+	// snapshots of the same content in the bucket.
+	Cache *S3Object `json:"cache,omitempty"`
 }
 
+const (
+	PageResolutionStandard = "standard"
+	PageResolutionHigh     = "high"
+)
+
+// PageExtension is the format of the page images and page thumbnails of documents.
+const PageExtension = ".webp"
+
 type PagesProps struct {
 	Count     int    `json:"count"`
 	Extension string `json:"extension"`
@@ -80,6 +129,42 @@ type ThumbnailsProps struct {
 	Extension string `json:"extension"`
 }
 
+// SlidesProps describes the images of the slides of presentations, which are
+// the first pages of the PDF preview, so Count can be less than the pages count.
+type SlidesProps struct {
+	Count     int    `json:"count"`
+	Extension string `json:"extension"`
+}
+
+// ModelProps describes the scene of a 3D model, the bounding box is in meters
+// as in glTF, and takes the transforms of the nodes into account.
+type ModelProps struct {
+	Vertices      int          `json:"vertices"`
+	Triangles     int          `json:"triangles"`
+	Meshes        int          `json:"meshes"`
+	Materials     int          `json:"materials"`
+	HasAnimations bool         `json:"hasAnimations"`
+	BoundingBox   *BoundingBox `json:"boundingBox,omitempty"`
+}
+
+type BoundingBox struct {
+	Min [3]float64 `json:"min"`
+	Max [3]float64 `json:"max"`
+}
+
+// WorkbookProps lists the sheets of the structured rendition of a spreadsheet,
+// the rows and columns are the ones kept in the rendition.
+type WorkbookProps struct {
+	Sheets []SheetProps `json:"sheets"`
+}
+
+type SheetProps struct {
+	Name      string `json:"name"`
+	Rows      int    `json:"rows"`
+	Columns   int    `json:"columns"`
+	Truncated bool   `json:"truncated"`
+}
+
 type ZoomLevel struct {
 	Index               int     `json:"index"`
 	Width               int     `json:"width"`
@@ -97,6 +182,96 @@ type Tile struct {
 	LastRowHeight int `json:"lastRowHeight"`
 }
 
+type Layout struct {
+	Pages []LayoutPage `json:"pages"`
+}
+
+type LayoutPage struct {
+	Number int          `json:"number"`
+	Width  float64      `json:"width"`
+	Height float64      `json:"height"`
+	Words  []LayoutWord `json:"words"`
+}
+
+type LayoutWord struct {
+	Text string  `json:"text"`
+	XMin float64 `json:"xMin"`
+	YMin float64 `json:"yMin"`
+	XMax float64 `json:"xMax"`
+	YMax float64 `json:"yMax"`
+}
+
+const (
+	SheetColumnTypeString  = "string"
+	SheetColumnTypeNumber  = "number"
+	SheetColumnTypeBoolean = "boolean"
+	SheetColumnTypeDate    = "date"
+)
+
+// Workbook is the structured rendition of a spreadsheet or a CSV file.
+type Workbook struct {
+	Sheets []Sheet `json:"sheets"`
+}
+
+// Sheet holds cells that are strings, numbers, booleans, or null when empty,
+// dates are strings in RFC 3339. Truncated tells if the sheet has more rows or
+// columns than the rendition kept.
+type Sheet struct {
+	Name      string        `json:"name"`
+	Columns   []SheetColumn `json:"columns"`
+	Rows      [][]any       `json:"rows"`
+	Truncated bool          `json:"truncated"`
+}
+
+// SheetColumn is named after its letter like in spreadsheets, the type is the
+// one shared by all of its cells, or a string when they differ.
+type SheetColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// MediaMetadata holds the EXIF, IPTC and XMP tags of a file normalized across
+// formats, together with the properties of its streams for audio and video,
+// and the headers of emails.
+type MediaMetadata struct {
+	CaptureTime *string        `json:"captureTime,omitempty"`
+	CameraMake  *string        `json:"cameraMake,omitempty"`
+	CameraModel *string        `json:"cameraModel,omitempty"`
+	Lens        *string        `json:"lens,omitempty"`
+	Location    *GeoLocation   `json:"location,omitempty"`
+	Duration    *float64       `json:"duration,omitempty"`
+	VideoCodec  *string        `json:"videoCodec,omitempty"`
+	AudioCodec  *string        `json:"audioCodec,omitempty"`
+	SampleRate  *int           `json:"sampleRate,omitempty"`
+	Channels    *int           `json:"channels,omitempty"`
+	Bitrate     *int64         `json:"bitrate,omitempty"`
+	Keywords    []string       `json:"keywords,omitempty"`
+	Caption     *string        `json:"caption,omitempty"`
+	Creator     *string        `json:"creator,omitempty"`
+	Copyright   *string        `json:"copyright,omitempty"`
+	Rating      *int           `json:"rating,omitempty"`
+	Email       *EmailMetadata `json:"email,omitempty"`
+}
+
+// EmailMetadata holds the headers of an email, for mailboxes they are the
+// ones of the first message.
+type EmailMetadata struct {
+	From        *string  `json:"from,omitempty"`
+	To          []string `json:"to,omitempty"`
+	Cc          []string `json:"cc,omitempty"`
+	Subject     *string  `json:"subject,omitempty"`
+	Date        *string  `json:"date,omitempty"`
+	MessageID   *string  `json:"messageId,omitempty"`
+	Attachments []string `json:"attachments,omitempty"`
+	Messages    int      `json:"messages"`
+}
+
+type GeoLocation struct {
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Altitude  *float64 `json:"altitude,omitempty"`
+}
+
 type S3Reference struct {
 	Bucket      string `json:"bucket"`
 	Key         string `json:"key"`
@@ -19,6 +19,10 @@ type Workspace interface {
 	GetUserPermissions() []CoreUserPermission
 	GetGroupPermissions() []CoreGroupPermission
 	GetBucket() string
+	GetProcessingPolicy() *ProcessingPolicy
+	// GetArchiveStatus returns nil when the workspace is active.
+	GetArchiveStatus() *string
+	GetArchiveTime() *string
 	GetCreateTime() string
 	GetUpdateTime() *string
 	SetID(string)
@@ -29,6 +33,47 @@ type Workspace interface {
 	SetUserPermissions([]CoreUserPermission)
 	SetGroupPermissions([]CoreGroupPermission)
 	SetBucket(string)
+	SetProcessingPolicy(*ProcessingPolicy)
+	SetArchiveStatus(*string)
+	SetArchiveTime(*string)
 	SetCreateTime(string)
 	SetUpdateTime(*string)
 }
+
+const (
+	WorkspaceArchiveStatusArchiving = "archiving"
+	WorkspaceArchiveStatusArchived  = "archived"
+	WorkspaceArchiveStatusRestoring = "restoring"
+)
+
+const (
+	ProcessingFileTypePDF    = "pdf"
+	ProcessingFileTypeOffice = "office"
+	ProcessingFileTypeText   = "text"
+	ProcessingFileTypeImage  = "image"
+	ProcessingFileTypeVideo  = "video"
+	ProcessingFileTypeAudio  = "audio"
+	ProcessingFileTypeGLB    = "glb"
+	ProcessingFileTypeModel  = "model"
+	ProcessingFileTypeEmail  = "email"
+	ProcessingFileTypeZIP    = "zip"
+)
+
+// ProcessingPolicy describes what the conversion pipeline does automatically
+// when a file of the workspace is uploaded, gets a new version, or is reprocessed.
+// The attachments of emails are extracted next to the message on upload only.
+type ProcessingPolicy struct {
+	Insights                *InsightsProcessingPolicy `json:"insights,omitempty"                validate:"omitempty"`
+	Mosaic                  *MosaicProcessingPolicy   `json:"mosaic,omitempty"                  validate:"omitempty"`
+	SkipPreview             []string                  `json:"skipPreview,omitempty"`
+	ExtractEmailAttachments bool                      `json:"extractEmailAttachments,omitempty"`
+}
+
+type InsightsProcessingPolicy struct {
+	LanguageID string   `json:"languageId" validate:"required"`
+	FileTypes  []string `json:"fileTypes"`
+}
+
+type MosaicProcessingPolicy struct {
+	ThresholdMegapixels float64 `json:"thresholdMegapixels" validate:"required,gt=0"`
+}
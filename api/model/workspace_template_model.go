@@ -0,0 +1,66 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package model
+
+type WorkspaceTemplate interface {
+	GetID() string
+	GetOrganizationID() string
+	GetName() string
+	GetBucket() *string
+	GetNodes() []WorkspaceTemplateNode
+	GetGrants() []WorkspaceTemplateGrant
+	GetCreateTime() string
+	GetUpdateTime() *string
+	SetName(string)
+	SetUpdateTime(*string)
+}
+
+// WorkspaceTemplateNode is a file or folder of the template, the node without
+// a parent stands for the root of the workspace.
+type WorkspaceTemplateNode struct {
+	ID                string                     `json:"id"`
+	ParentID          *string                    `json:"parentId,omitempty"`
+	Name              string                     `json:"name"`
+	Type              string                     `json:"type"`
+	BreaksInheritance bool                       `json:"breaksInheritance,omitempty"`
+	Snapshot          *WorkspaceTemplateSnapshot `json:"snapshot,omitempty"`
+}
+
+// WorkspaceTemplateSnapshot points to the objects of a snapshot, which are
+// stored in the bucket under the snapshot's ID.
+type WorkspaceTemplateSnapshot struct {
+	ID                 string    `json:"id"`
+	Bucket             string    `json:"bucket"`
+	Original           *S3Object `json:"original,omitempty"`
+	Preview            *S3Object `json:"preview,omitempty"`
+	Text               *S3Object `json:"text,omitempty"`
+	OCR                *S3Object `json:"ocr,omitempty"`
+	Entities           *S3Object `json:"entities,omitempty"`
+	Layout             *S3Object `json:"layout,omitempty"`
+	Summary            *S3Object `json:"summary,omitempty"`
+	Metadata           *S3Object `json:"metadata,omitempty"`
+	Sheets             *S3Object `json:"sheets,omitempty"`
+	Mosaic             *S3Object `json:"mosaic,omitempty"`
+	Thumbnail          *S3Object `json:"thumbnail,omitempty"`
+	Language           *string   `json:"language,omitempty"`
+	LanguageConfidence *float64  `json:"languageConfidence,omitempty"`
+}
+
+type WorkspaceTemplateGrant struct {
+	NodeID     string  `json:"nodeId"`
+	UserID     *string `json:"userId,omitempty"`
+	GroupID    *string `json:"groupId,omitempty"`
+	Permission string  `json:"permission"`
+	Effect     string  `json:"effect"`
+	RoleID     *string `json:"roleId,omitempty"`
+	StartsAt   *string `json:"startsAt,omitempty"`
+	ExpiresAt  *string `json:"expiresAt,omitempty"`
+}
@@ -12,6 +12,7 @@ package repo
 
 import (
 	"errors"
+	"fmt"
 
 	"gorm.io/gorm"
 
@@ -22,17 +23,21 @@ import (
 )
 
 type fileEntity struct {
-	ID               string                  `gorm:"column:id"           json:"id"`
-	WorkspaceID      string                  `gorm:"column:workspace_id" json:"workspaceId"`
-	Name             string                  `gorm:"column:name"         json:"name"`
-	Type             string                  `gorm:"column:type"         json:"type"`
-	ParentID         *string                 `gorm:"column:parent_id"    json:"parentId,omitempty"`
-	UserPermissions  []*UserPermissionValue  `gorm:"-"                   json:"userPermissions"`
-	GroupPermissions []*GroupPermissionValue `gorm:"-"                   json:"groupPermissions"`
-	Text             *string                 `gorm:"-"                   json:"text,omitempty"`
-	SnapshotID       *string                 `gorm:"column:snapshot_id"  json:"snapshotId,omitempty"`
-	CreateTime       string                  `gorm:"column:create_time"  json:"createTime"`
-	UpdateTime       *string                 `gorm:"column:update_time"  json:"updateTime,omitempty"`
+	ID                string                  `gorm:"column:id"                 json:"id"`
+	WorkspaceID       string                  `gorm:"column:workspace_id"       json:"workspaceId"`
+	Name              string                  `gorm:"column:name"               json:"name"`
+	Type              string                  `gorm:"column:type"               json:"type"`
+	ParentID          *string                 `gorm:"column:parent_id"          json:"parentId,omitempty"`
+	UserPermissions   []*UserPermissionValue  `gorm:"-"                         json:"userPermissions"`
+	GroupPermissions  []*GroupPermissionValue `gorm:"-"                         json:"groupPermissions"`
+	Text              *string                 `gorm:"-"                         json:"text,omitempty"`
+	Summary           *string                 `gorm:"-"                         json:"summary,omitempty"`
+	Keywords          []string                `gorm:"-"                         json:"keywords,omitempty"`
+	MediaMetadata     *model.MediaMetadata    `gorm:"-"                         json:"mediaMetadata,omitempty"`
+	SnapshotID        *string                 `gorm:"column:snapshot_id"        json:"snapshotId,omitempty"`
+	BreaksInheritance bool                    `gorm:"column:breaks_inheritance" json:"breaksInheritance,omitempty"`
+	CreateTime        string                  `gorm:"column:create_time"        json:"createTime"`
+	UpdateTime        *string                 `gorm:"column:update_time"        json:"updateTime,omitempty"`
 }
 
 func (*fileEntity) TableName() string {
@@ -90,10 +95,26 @@ func (f *fileEntity) GetText() *string {
 	return f.Text
 }
 
+func (f *fileEntity) GetSummary() *string {
+	return f.Summary
+}
+
+func (f *fileEntity) GetKeywords() []string {
+	return f.Keywords
+}
+
+func (f *fileEntity) GetMediaMetadata() *model.MediaMetadata {
+	return f.MediaMetadata
+}
+
 func (f *fileEntity) GetSnapshotID() *string {
 	return f.SnapshotID
 }
 
+func (f *fileEntity) GetBreaksInheritance() bool {
+	return f.BreaksInheritance
+}
+
 func (f *fileEntity) GetCreateTime() string {
 	return f.CreateTime
 }
@@ -126,10 +147,26 @@ func (f *fileEntity) SetText(text *string) {
 	f.Text = text
 }
 
+func (f *fileEntity) SetSummary(summary *string) {
+	f.Summary = summary
+}
+
+func (f *fileEntity) SetKeywords(keywords []string) {
+	f.Keywords = keywords
+}
+
+func (f *fileEntity) SetMediaMetadata(metadata *model.MediaMetadata) {
+	f.MediaMetadata = metadata
+}
+
 func (f *fileEntity) SetSnapshotID(snapshotID *string) {
 	f.SnapshotID = snapshotID
 }
 
+func (f *fileEntity) SetBreaksInheritance(breaksInheritance bool) {
+	f.BreaksInheritance = breaksInheritance
+}
+
 func (f *fileEntity) SetUserPermissions(permissions []model.CoreUserPermission) {
 	f.UserPermissions = make([]*UserPermissionValue, len(permissions))
 	for i, p := range permissions {
@@ -282,9 +319,9 @@ func (repo *FileRepo) FindChildren(id string) ([]model.File, error) {
 func (repo *FileRepo) FindPath(id string) ([]model.File, error) {
 	var entities []*fileEntity
 	if db := repo.db.
-		Raw(`WITH RECURSIVE rec (id, name, type, parent_id, workspace_id, create_time, update_time) AS
-             (SELECT f.id, f.name, f.type, f.parent_id, f.workspace_id, f.create_time, f.update_time FROM file f WHERE f.id = ?
-             UNION SELECT f.id, f.name, f.type, f.parent_id, f.workspace_id, f.create_time, f.update_time FROM rec, file f WHERE f.id = rec.parent_id)
+		Raw(`WITH RECURSIVE rec (id, name, type, parent_id, workspace_id, breaks_inheritance, create_time, update_time) AS
+             (SELECT f.id, f.name, f.type, f.parent_id, f.workspace_id, f.breaks_inheritance, f.create_time, f.update_time FROM file f WHERE f.id = ?
+             UNION SELECT f.id, f.name, f.type, f.parent_id, f.workspace_id, f.breaks_inheritance, f.create_time, f.update_time FROM rec, file f WHERE f.id = rec.parent_id)
              SELECT * FROM rec`,
 			id).
 		Scan(&entities); db.Error != nil {
@@ -303,9 +340,9 @@ func (repo *FileRepo) FindPath(id string) ([]model.File, error) {
 func (repo *FileRepo) FindTree(id string) ([]model.File, error) {
 	var entities []*fileEntity
 	db := repo.db.
-		Raw(`WITH RECURSIVE rec (id, name, type, parent_id, workspace_id, snapshot_id, create_time, update_time) AS
-             (SELECT f.id, f.name, f.type, f.parent_id, f.workspace_id, f.snapshot_id, f.create_time, f.update_time FROM file f WHERE f.id = ?
-             UNION SELECT f.id, f.name, f.type, f.parent_id, f.workspace_id, f.snapshot_id, f.create_time, f.update_time FROM rec, file f WHERE f.parent_id = rec.id)
+		Raw(`WITH RECURSIVE rec (id, name, type, parent_id, workspace_id, snapshot_id, breaks_inheritance, create_time, update_time) AS
+             (SELECT f.id, f.name, f.type, f.parent_id, f.workspace_id, f.snapshot_id, f.breaks_inheritance, f.create_time, f.update_time FROM file f WHERE f.id = ?
+             UNION SELECT f.id, f.name, f.type, f.parent_id, f.workspace_id, f.snapshot_id, f.breaks_inheritance, f.create_time, f.update_time FROM rec, file f WHERE f.parent_id = rec.id)
              SELECT rec.* FROM rec ORDER BY create_time ASC`,
 			id).
 		Scan(&entities)
@@ -541,118 +578,197 @@ func (repo *FileRepo) ClearSnapshotID(id string) error {
 	return nil
 }
 
-func (repo *FileRepo) GrantUserPermission(id string, userID string, permission string) error {
-	// Grant 'viewer' permission to workspace
+// GrantUserPermission stores the permission on the file only, its descendants inherit it.
+// The ancestors get a 'viewer' permission scoped to themselves, so the file can be reached
+// without exposing its siblings.
+func (repo *FileRepo) GrantUserPermission(id string, userID string, permission string, opts PermissionGrantOptions) error {
+	return repo.grantPermission("userpermission", "user_id", id, userID, permission, model.PermissionEffectAllow, opts)
+}
+
+// DenyUserPermission caps the permission inherited by the file and its descendants.
+func (repo *FileRepo) DenyUserPermission(id string, userID string, permission string) error {
+	return repo.grantPermission("userpermission", "user_id", id, userID, permission, model.PermissionEffectDeny, PermissionGrantOptions{})
+}
+
+// InsertUserPermission stores the permission on the file only, without touching its ancestors.
+func (repo *FileRepo) InsertUserPermission(id string, userID string, permission string, opts PermissionGrantOptions) error {
+	return repo.upsertPermission("userpermission", "user_id", id, userID, permission, model.PermissionEffectAllow, opts)
+}
+
+// RevokeUserPermission deletes the permissions of the user on the file and its descendants,
+// then the 'viewer' permissions of the ancestors that no longer lead to a file the user has
+// access to, and returns the IDs of the files that had one.
+func (repo *FileRepo) RevokeUserPermission(id string, userID string) ([]string, error) {
+	return repo.revokePermission("userpermission", "user_id", id, userID)
+}
+
+func (repo *FileRepo) GrantGroupPermission(id string, groupID string, permission string, opts PermissionGrantOptions) error {
+	return repo.grantPermission("grouppermission", "group_id", id, groupID, permission, model.PermissionEffectAllow, opts)
+}
+
+func (repo *FileRepo) DenyGroupPermission(id string, groupID string, permission string) error {
+	return repo.grantPermission("grouppermission", "group_id", id, groupID, permission, model.PermissionEffectDeny, PermissionGrantOptions{})
+}
+
+func (repo *FileRepo) InsertGroupPermission(id string, groupID string, permission string, opts PermissionGrantOptions) error {
+	return repo.upsertPermission("grouppermission", "group_id", id, groupID, permission, model.PermissionEffectAllow, opts)
+}
+
+func (repo *FileRepo) RevokeGroupPermission(id string, groupID string) ([]string, error) {
+	return repo.revokePermission("grouppermission", "group_id", id, groupID)
+}
+
+func (repo *FileRepo) grantPermission(
+	table string,
+	principal string,
+	id string,
+	principalID string,
+	permission string,
+	effect string,
+	opts PermissionGrantOptions,
+) error {
+	// A deny only caps the file and its descendants, it must not make the workspace or ancestors reachable
+	if effect == model.PermissionEffectDeny {
+		return repo.upsertPermission(table, principal, id, principalID, permission, effect, opts)
+	}
+
+	// Grant 'viewer' permission to workspace, a permission granted on the workspace itself is left as is
 	db := repo.db.
-		Exec(`INSERT INTO userpermission (id, user_id, resource_id, permission, create_time)
-              (SELECT ?, ?, w.id, 'viewer', ? FROM file f
+		Exec(fmt.Sprintf(`INSERT INTO %s (id, %s, resource_id, permission, effect, scope, starts_at, expires_at, create_time)
+              (SELECT ?, ?, w.id, 'viewer', 'allow', 'node', ?, ?, ? FROM file f
               INNER JOIN workspace w ON w.id = f.workspace_id AND f.id = ?)
-              ON CONFLICT DO NOTHING`,
-			helper.NewID(), userID, helper.NewTimestamp(), id)
+              ON CONFLICT (%s, resource_id) DO UPDATE SET %s WHERE %s.scope = 'node'`,
+			table, principal, principal, permissionScheduleMerge(table), table),
+			helper.NewID(), principalID, opts.StartsAt, opts.ExpiresAt, helper.NewTimestamp(), id)
 	if db.Error != nil {
 		return db.Error
 	}
 
-	// Grant 'viewer' permission to path files
-	path, err := repo.FindPath(id)
-	if err != nil {
-		return err
-	}
-	for _, f := range path {
-		db := repo.db.
-			Exec(`INSERT INTO userpermission (id, user_id, resource_id, permission, create_time)
-                  VALUES (?, ?, ?, 'viewer', ?) ON CONFLICT DO NOTHING`,
-				helper.NewID(), userID, f.GetID(), helper.NewTimestamp())
-		if db.Error != nil {
-			return db.Error
-		}
-	}
-
-	// Grant the requested permission to tree files
-	tree, err := repo.FindTree(id)
-	if err != nil {
-		return err
-	}
-	for _, leaf := range tree {
-		db := repo.db.
-			Exec(`INSERT INTO userpermission (id, user_id, resource_id, permission, create_time)
-                  VALUES (?, ?, ?, ?, ?) ON CONFLICT (user_id, resource_id) DO UPDATE SET permission = ?`,
-				helper.NewID(), userID, leaf.GetID(), permission, helper.NewTimestamp(), permission)
-		if db.Error != nil {
-			return db.Error
-		}
+	// Grant 'viewer' permission to ancestors, without inheritance
+	db = repo.db.
+		Exec(fmt.Sprintf(`WITH RECURSIVE rec (id, parent_id) AS
+              (SELECT f.id, f.parent_id FROM file f WHERE f.id = ?
+              UNION SELECT f.id, f.parent_id FROM rec, file f WHERE f.id = rec.parent_id)
+              INSERT INTO %s (id, %s, resource_id, permission, effect, scope, starts_at, expires_at, create_time)
+              SELECT md5(random()::text || rec.id), ?, rec.id, 'viewer', 'allow', 'node', ?, ?, ? FROM rec WHERE rec.id != ?
+              ON CONFLICT (%s, resource_id) DO UPDATE SET %s WHERE %s.scope = 'node'`,
+			table, principal, principal, permissionScheduleMerge(table), table),
+			id, principalID, opts.StartsAt, opts.ExpiresAt, helper.NewTimestamp(), id)
+	if db.Error != nil {
+		return db.Error
 	}
 
-	return nil
+	return repo.upsertPermission(table, principal, id, principalID, permission, effect, opts)
 }
 
-func (repo *FileRepo) RevokeUserPermission(tree []model.File, userID string) error {
-	for _, leaf := range tree {
-		db := repo.db.
-			Exec("DELETE FROM userpermission WHERE user_id = ? AND resource_id = ?",
-				userID, leaf.GetID())
-		if db.Error != nil {
-			return db.Error
-		}
+func (repo *FileRepo) upsertPermission(
+	table string,
+	principal string,
+	id string,
+	principalID string,
+	permission string,
+	effect string,
+	opts PermissionGrantOptions,
+) error {
+	db := repo.db.
+		Exec(fmt.Sprintf(`INSERT INTO %s (id, %s, resource_id, permission, effect, scope, starts_at, expires_at, granted_by, role_id, create_time)
+              VALUES (?, ?, ?, ?, ?, 'tree', ?, ?, ?, ?, ?)
+              ON CONFLICT (%s, resource_id) DO UPDATE SET permission = ?, effect = ?, scope = 'tree',
+              starts_at = EXCLUDED.starts_at, expires_at = EXCLUDED.expires_at, granted_by = EXCLUDED.granted_by,
+              role_id = EXCLUDED.role_id, reminder_time = NULL`, table, principal, principal),
+			helper.NewID(), principalID, id, permission, effect, opts.StartsAt, opts.ExpiresAt, opts.GrantedBy, opts.RoleID,
+			helper.NewTimestamp(), permission, effect)
+	if db.Error != nil {
+		return db.Error
 	}
 	return nil
 }
 
-func (repo *FileRepo) GrantGroupPermission(id string, groupID string, permission string) error {
-	// Grant permission to workspace
+// permissionScheduleMerge widens the schedule of an existing row so it covers both grants,
+// a permanent grant makes it permanent.
+func permissionScheduleMerge(table string) string {
+	return fmt.Sprintf(`starts_at = CASE WHEN %[1]s.starts_at IS NULL OR EXCLUDED.starts_at IS NULL THEN NULL
+                        ELSE LEAST(%[1]s.starts_at, EXCLUDED.starts_at) END,
+                        expires_at = CASE WHEN %[1]s.expires_at IS NULL OR EXCLUDED.expires_at IS NULL THEN NULL
+                        ELSE GREATEST(%[1]s.expires_at, EXCLUDED.expires_at) END`, table)
+}
+
+func (repo *FileRepo) revokePermission(table string, principal string, id string, principalID string) ([]string, error) {
+	type Value struct {
+		Result string
+	}
+	var values []Value
 	db := repo.db.
-		Exec(`INSERT INTO grouppermission (id, group_id, resource_id, permission, create_time)
-              (SELECT ?, ?, w.id, 'viewer', ? FROM file f
-              INNER JOIN workspace w ON w.id = f.workspace_id AND f.id = ?)
-              ON CONFLICT DO NOTHING`,
-			helper.NewID(), groupID, helper.NewTimestamp(), id)
+		Raw(fmt.Sprintf(`WITH RECURSIVE rec (id) AS
+             (SELECT f.id FROM file f WHERE f.id = ?
+             UNION SELECT f.id FROM rec, file f WHERE f.parent_id = rec.id)
+             DELETE FROM %s p USING rec WHERE p.resource_id = rec.id AND p.%s = ?
+             RETURNING p.resource_id result`, table, principal),
+			id, principalID).
+		Scan(&values)
 	if db.Error != nil {
-		return db.Error
+		return nil, db.Error
 	}
-
-	// Grant 'viewer' permission to path files
-	path, err := repo.FindPath(id)
-	if err != nil {
-		return err
-	}
-	for _, f := range path {
-		db := repo.db.
-			Exec(`INSERT INTO grouppermission (id, group_id, resource_id, permission, create_time)
-                  VALUES (?, ?, ?, 'viewer', ?) ON CONFLICT DO NOTHING`,
-				helper.NewID(), groupID, f.GetID(), helper.NewTimestamp())
-		if db.Error != nil {
-			return db.Error
-		}
+	res := make([]string, 0)
+	for _, v := range values {
+		res = append(res, v.Result)
 	}
-
-	// Grant the requested permission to tree files
-	tree, err := repo.FindTree(id)
+	prunedIDs, err := repo.pruneAncestorPermissions(table, principal, id, principalID)
 	if err != nil {
-		return err
-	}
-	for _, leaf := range tree {
-		db := repo.db.
-			Exec(`INSERT INTO grouppermission (id, group_id, resource_id, permission, create_time)
-                  VALUES (?, ?, ?, ?, ?) ON CONFLICT (group_id, resource_id) DO UPDATE SET permission = ?`,
-				helper.NewID(), groupID, leaf.GetID(), permission, helper.NewTimestamp(), permission)
-		if db.Error != nil {
-			return db.Error
-		}
+		return nil, err
 	}
+	return append(res, prunedIDs...), nil
+}
 
-	return nil
+// PruneUserAncestorPermissions deletes the 'viewer' permissions of the user on the ancestors
+// of the file and on its workspace that no longer lead to a file the user has access to, and
+// returns the IDs of the ancestors.
+func (repo *FileRepo) PruneUserAncestorPermissions(id string, userID string) ([]string, error) {
+	return repo.pruneAncestorPermissions("userpermission", "user_id", id, userID)
 }
 
-func (repo *FileRepo) RevokeGroupPermission(tree []model.File, groupID string) error {
-	for _, leaf := range tree {
-		db := repo.db.
-			Exec("DELETE FROM grouppermission WHERE group_id = ? AND resource_id = ?",
-				groupID, leaf.GetID())
-		if db.Error != nil {
-			return db.Error
-		}
+func (repo *FileRepo) PruneGroupAncestorPermissions(id string, groupID string) ([]string, error) {
+	return repo.pruneAncestorPermissions("grouppermission", "group_id", id, groupID)
+}
+
+func (repo *FileRepo) pruneAncestorPermissions(table string, principal string, id string, principalID string) ([]string, error) {
+	type Value struct {
+		Result string
 	}
-	return nil
+	var values []Value
+	db := repo.db.
+		Raw(fmt.Sprintf(`WITH RECURSIVE kept (id, parent_id) AS
+             (SELECT f.id, f.parent_id FROM file f
+             INNER JOIN %[1]s p ON p.resource_id = f.id AND p.%[2]s = ? AND p.scope = 'tree' AND p.effect = 'allow'
+             WHERE f.workspace_id = (SELECT workspace_id FROM file WHERE id = ?)
+             UNION SELECT f.id, f.parent_id FROM kept, file f WHERE f.id = kept.parent_id),
+             rec (id, parent_id) AS
+             (SELECT f.id, f.parent_id FROM file f WHERE f.id = ?
+             UNION SELECT f.id, f.parent_id FROM rec, file f WHERE f.id = rec.parent_id)
+             DELETE FROM %[1]s p USING rec WHERE p.resource_id = rec.id AND p.%[2]s = ? AND p.scope = 'node'
+             AND rec.id NOT IN (SELECT kept.id FROM kept)
+             RETURNING p.resource_id result`, table, principal),
+			principalID, id, id, principalID).
+		Scan(&values)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	// The workspace goes last, once no file of the workspace is granted anymore
+	db = repo.db.
+		Exec(fmt.Sprintf(`DELETE FROM %[1]s p WHERE p.%[2]s = ? AND p.scope = 'node'
+             AND p.resource_id = (SELECT workspace_id FROM file WHERE id = ?)
+             AND NOT EXISTS (SELECT 1 FROM %[1]s fp INNER JOIN file f ON f.id = fp.resource_id
+             WHERE fp.%[2]s = ? AND fp.scope = 'tree' AND fp.effect = 'allow' AND f.workspace_id = p.resource_id)`,
+			table, principal),
+			principalID, id, principalID)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	res := make([]string, 0)
+	for _, v := range values {
+		res = append(res, v.Result)
+	}
+	return res, nil
 }
 
 func (repo *FileRepo) PopulateModelFieldsForUser(files []model.File, userID string) error {
@@ -677,8 +793,13 @@ func (repo *FileRepo) populateModelFields(entities []*fileEntity) error {
 		}
 		for _, p := range userPermissions {
 			f.UserPermissions = append(f.UserPermissions, &UserPermissionValue{
-				UserID: p.GetUserID(),
-				Value:  p.GetPermission(),
+				UserID:    p.GetUserID(),
+				Value:     p.GetPermission(),
+				Effect:    p.GetEffect(),
+				Scope:     p.GetScope(),
+				StartsAt:  p.GetStartsAt(),
+				ExpiresAt: p.GetExpiresAt(),
+				RoleID:    p.GetRoleID(),
 			})
 		}
 		f.GroupPermissions = make([]*GroupPermissionValue, 0)
@@ -688,8 +809,13 @@ func (repo *FileRepo) populateModelFields(entities []*fileEntity) error {
 		}
 		for _, p := range groupPermissions {
 			f.GroupPermissions = append(f.GroupPermissions, &GroupPermissionValue{
-				GroupID: p.GetGroupID(),
-				Value:   p.GetPermission(),
+				GroupID:   p.GetGroupID(),
+				Value:     p.GetPermission(),
+				Effect:    p.GetEffect(),
+				Scope:     p.GetScope(),
+				StartsAt:  p.GetStartsAt(),
+				ExpiresAt: p.GetExpiresAt(),
+				RoleID:    p.GetRoleID(),
 			})
 		}
 	}
@@ -12,6 +12,7 @@ package repo
 
 import (
 	"errors"
+	"fmt"
 
 	"gorm.io/gorm"
 
@@ -28,6 +29,7 @@ type groupEntity struct {
 	UserPermissions  []*UserPermissionValue  `gorm:"-"                      json:"userPermissions"`
 	GroupPermissions []*GroupPermissionValue `gorm:"-"                      json:"groupPermissions"`
 	Members          []string                `gorm:"-"                      json:"members"`
+	Subgroups        []string                `gorm:"-"                      json:"subgroups"`
 	CreateTime       string                  `gorm:"column:create_time"     json:"createTime"`
 	UpdateTime       *string                 `gorm:"column:update_time"     json:"updateTime"`
 }
@@ -79,6 +81,10 @@ func (g *groupEntity) GetMembers() []string {
 	return g.Members
 }
 
+func (g *groupEntity) GetSubgroups() []string {
+	return g.Subgroups
+}
+
 func (g *groupEntity) GetCreateTime() string {
 	return g.CreateTime
 }
@@ -244,11 +250,22 @@ func (repo *GroupRepo) FindIDs() ([]string, error) {
 	return res, nil
 }
 
+// FindMembers returns the users whose membership is active, scheduled and expired ones are left out.
+// Members of subgroups are members too, UNION stops the recursion if the groups ever form a cycle.
 func (repo *GroupRepo) FindMembers(id string) ([]model.User, error) {
 	var entities []*userEntity
+	now := helper.NewTimestamp()
 	db := repo.db.
-		Raw(`SELECT u.* FROM "user" u INNER JOIN userpermission up on
-             u.id = up.user_id AND up.resource_id = ?`, id).
+		Raw(`WITH RECURSIVE subgroup (id) AS (
+               SELECT CAST(? AS text)
+               UNION
+               SELECT gp.group_id FROM grouppermission gp INNER JOIN subgroup s ON gp.resource_id = s.id
+               WHERE (gp.starts_at IS NULL OR gp.starts_at <= ?) AND (gp.expires_at IS NULL OR gp.expires_at > ?)
+             )
+             SELECT DISTINCT u.* FROM "user" u INNER JOIN userpermission up on
+             u.id = up.user_id AND up.resource_id IN (SELECT id FROM subgroup)
+             AND (up.starts_at IS NULL OR up.starts_at <= ?)
+             AND (up.expires_at IS NULL OR up.expires_at > ?)`, id, now, now, now, now).
 		Scan(&entities)
 	if db.Error != nil {
 		return nil, db.Error
@@ -260,6 +277,59 @@ func (repo *GroupRepo) FindMembers(id string) ([]model.User, error) {
 	return res, nil
 }
 
+// FindSubgroupIDs returns the groups directly contained in the group.
+func (repo *GroupRepo) FindSubgroupIDs(id string) ([]string, error) {
+	type Value struct {
+		Result string
+	}
+	var values []Value
+	db := repo.db.
+		Raw(`SELECT gp.group_id result FROM grouppermission gp
+             INNER JOIN "group" g ON g.id = gp.resource_id WHERE gp.resource_id = ?`, id).
+		Scan(&values)
+	if db.Error != nil {
+		return []string{}, db.Error
+	}
+	res := make([]string, 0)
+	for _, v := range values {
+		res = append(res, v.Result)
+	}
+	return res, nil
+}
+
+// FindDescendantIDs returns the groups contained in the group, directly or not.
+func (repo *GroupRepo) FindDescendantIDs(id string) ([]string, error) {
+	return repo.findRelatedIDs(id, "gp.resource_id = r.id", "gp.group_id")
+}
+
+// FindAncestorIDs returns the groups containing the group, directly or not.
+func (repo *GroupRepo) FindAncestorIDs(id string) ([]string, error) {
+	return repo.findRelatedIDs(id, "gp.group_id = r.id AND gp.resource_id IN (SELECT id FROM \"group\")", "gp.resource_id")
+}
+
+func (repo *GroupRepo) findRelatedIDs(id string, join string, column string) ([]string, error) {
+	type Value struct {
+		Result string
+	}
+	var values []Value
+	db := repo.db.
+		Raw(fmt.Sprintf(`WITH RECURSIVE related (id) AS (
+               SELECT CAST(? AS text)
+               UNION
+               SELECT %s FROM grouppermission gp INNER JOIN related r ON %s
+             )
+             SELECT id result FROM related WHERE id <> ?`, column, join), id, id).
+		Scan(&values)
+	if db.Error != nil {
+		return []string{}, db.Error
+	}
+	res := make([]string, 0)
+	for _, v := range values {
+		res = append(res, v.Result)
+	}
+	return res, nil
+}
+
 func (repo *GroupRepo) CountOwners(id string) (int64, error) {
 	var count int64
 	db := repo.db.Model(&userPermissionEntity{}).
@@ -272,11 +342,14 @@ func (repo *GroupRepo) CountOwners(id string) (int64, error) {
 	return count, nil
 }
 
-func (repo *GroupRepo) GrantUserPermission(id string, userID string, permission string) error {
+func (repo *GroupRepo) GrantUserPermission(id string, userID string, permission string, opts PermissionGrantOptions) error {
 	db := repo.db.
-		Exec(`INSERT INTO userpermission (id, user_id, resource_id, permission, create_time)
-              VALUES (?, ?, ?, ?, ?) ON CONFLICT (user_id, resource_id) DO UPDATE SET permission = ?`,
-			helper.NewID(), userID, id, permission, helper.NewTimestamp(), permission)
+		Exec(`INSERT INTO userpermission (id, user_id, resource_id, permission, starts_at, expires_at, granted_by, create_time)
+              VALUES (?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (user_id, resource_id) DO UPDATE SET permission = ?,
+              starts_at = EXCLUDED.starts_at, expires_at = EXCLUDED.expires_at, granted_by = EXCLUDED.granted_by,
+              reminder_time = NULL`,
+			helper.NewID(), userID, id, permission, opts.StartsAt, opts.ExpiresAt, opts.GrantedBy,
+			helper.NewTimestamp(), permission)
 	if db.Error != nil {
 		return db.Error
 	}
@@ -291,6 +364,28 @@ func (repo *GroupRepo) RevokeUserPermission(id string, userID string) error {
 	return nil
 }
 
+func (repo *GroupRepo) GrantGroupPermission(id string, groupID string, permission string, opts PermissionGrantOptions) error {
+	db := repo.db.
+		Exec(`INSERT INTO grouppermission (id, group_id, resource_id, permission, starts_at, expires_at, granted_by, create_time)
+              VALUES (?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (group_id, resource_id) DO UPDATE SET permission = ?,
+              starts_at = EXCLUDED.starts_at, expires_at = EXCLUDED.expires_at, granted_by = EXCLUDED.granted_by,
+              reminder_time = NULL`,
+			helper.NewID(), groupID, id, permission, opts.StartsAt, opts.ExpiresAt, opts.GrantedBy,
+			helper.NewTimestamp(), permission)
+	if db.Error != nil {
+		return db.Error
+	}
+	return nil
+}
+
+func (repo *GroupRepo) RevokeGroupPermission(id string, groupID string) error {
+	db := repo.db.Exec("DELETE FROM grouppermission WHERE group_id = ? AND resource_id = ?", groupID, id)
+	if db.Error != nil {
+		return db.Error
+	}
+	return nil
+}
+
 func (repo *GroupRepo) populateModelFields(groups []*groupEntity) error {
 	for _, g := range groups {
 		g.UserPermissions = make([]*UserPermissionValue, 0)
@@ -300,8 +395,11 @@ func (repo *GroupRepo) populateModelFields(groups []*groupEntity) error {
 		}
 		for _, p := range userPermissions {
 			g.UserPermissions = append(g.UserPermissions, &UserPermissionValue{
-				UserID: p.GetUserID(),
-				Value:  p.GetPermission(),
+				UserID:    p.GetUserID(),
+				Value:     p.GetPermission(),
+				StartsAt:  p.GetStartsAt(),
+				ExpiresAt: p.GetExpiresAt(),
+				RoleID:    p.GetRoleID(),
 			})
 		}
 		g.GroupPermissions = make([]*GroupPermissionValue, 0)
@@ -311,8 +409,11 @@ func (repo *GroupRepo) populateModelFields(groups []*groupEntity) error {
 		}
 		for _, p := range groupPermissions {
 			g.GroupPermissions = append(g.GroupPermissions, &GroupPermissionValue{
-				GroupID: p.GetGroupID(),
-				Value:   p.GetPermission(),
+				GroupID:   p.GetGroupID(),
+				Value:     p.GetPermission(),
+				StartsAt:  p.GetStartsAt(),
+				ExpiresAt: p.GetExpiresAt(),
+				RoleID:    p.GetRoleID(),
 			})
 		}
 		members, err := repo.FindMembers(g.ID)
@@ -323,6 +424,10 @@ func (repo *GroupRepo) populateModelFields(groups []*groupEntity) error {
 		for _, u := range members {
 			g.Members = append(g.Members, u.GetID())
 		}
+		g.Subgroups, err = repo.FindSubgroupIDs(g.ID)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -11,24 +11,30 @@
 package repo
 
 import (
+	"encoding/json"
 	"errors"
 
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 
 	"github.com/kouprlabs/voltaserve/api/errorpkg"
 	"github.com/kouprlabs/voltaserve/api/helper"
 	"github.com/kouprlabs/voltaserve/api/infra"
+	"github.com/kouprlabs/voltaserve/api/log"
 	"github.com/kouprlabs/voltaserve/api/model"
 )
 
 type invitationEntity struct {
-	ID             string  `gorm:"column:id"              json:"id"`
-	OrganizationID string  `gorm:"column:organization_id" json:"organizationId"`
-	OwnerID        string  `gorm:"column:owner_id"        json:"ownerId"`
-	Email          string  `gorm:"column:email"           json:"email"`
-	Status         string  `gorm:"column:status"          json:"status"`
-	CreateTime     string  `gorm:"column:create_time"     json:"createTime"`
-	UpdateTime     *string `gorm:"column:update_time"     json:"updateTime"`
+	ID             string         `gorm:"column:id"              json:"id"`
+	OrganizationID string         `gorm:"column:organization_id" json:"organizationId"`
+	OwnerID        string         `gorm:"column:owner_id"        json:"ownerId"`
+	Email          string         `gorm:"column:email"           json:"email"`
+	Status         string         `gorm:"column:status"          json:"status"`
+	Role           string         `gorm:"column:role"            json:"role"`
+	GroupIDs       datatypes.JSON `gorm:"column:group_ids"       json:"groupIds,omitempty"`
+	ExpiresAt      *string        `gorm:"column:expires_at"      json:"expiresAt,omitempty"`
+	CreateTime     string         `gorm:"column:create_time"     json:"createTime"`
+	UpdateTime     *string        `gorm:"column:update_time"     json:"updateTime"`
 }
 
 func (*invitationEntity) TableName() string {
@@ -66,6 +72,26 @@ func (i *invitationEntity) GetStatus() string {
 	return i.Status
 }
 
+func (i *invitationEntity) GetRole() string {
+	return i.Role
+}
+
+func (i *invitationEntity) GetGroupIDs() []string {
+	res := make([]string, 0)
+	if i.GroupIDs.String() == "" {
+		return res
+	}
+	if err := json.Unmarshal([]byte(i.GroupIDs.String()), &res); err != nil {
+		log.GetLogger().Error(err)
+		return []string{}
+	}
+	return res
+}
+
+func (i *invitationEntity) GetExpiresAt() *string {
+	return i.ExpiresAt
+}
+
 func (i *invitationEntity) GetCreateTime() string {
 	return i.CreateTime
 }
@@ -94,9 +120,20 @@ type InvitationInsertOptions struct {
 	UserID         string
 	OrganizationID string
 	Emails         []string
+	Role           string
+	GroupIDs       []string
+	ExpiresAt      *string
 }
 
 func (repo *InvitationRepo) Insert(opts InvitationInsertOptions) ([]model.Invitation, error) {
+	var groupIDs datatypes.JSON
+	if len(opts.GroupIDs) > 0 {
+		b, err := json.Marshal(opts.GroupIDs)
+		if err != nil {
+			return nil, err
+		}
+		groupIDs = b
+	}
 	var res []model.Invitation
 	for _, e := range opts.Emails {
 		invitation := invitationEntity{
@@ -105,6 +142,9 @@ func (repo *InvitationRepo) Insert(opts InvitationInsertOptions) ([]model.Invita
 			OwnerID:        opts.UserID,
 			Email:          e,
 			Status:         model.InvitationStatusPending,
+			Role:           opts.Role,
+			GroupIDs:       groupIDs,
+			ExpiresAt:      opts.ExpiresAt,
 		}
 		if db := repo.db.Create(&invitation); db.Error != nil {
 			return nil, db.Error
@@ -134,7 +174,7 @@ func (repo *InvitationRepo) Find(id string) (model.Invitation, error) {
 func (repo *InvitationRepo) FindIncoming(email string) ([]model.Invitation, error) {
 	var invitations []*invitationEntity
 	db := repo.db.
-		Raw("SELECT * FROM invitation WHERE email = ? and status = 'pending' ORDER BY create_time DESC", email).
+		Raw("SELECT * FROM invitation WHERE email = ? and status = 'pending' AND (expires_at IS NULL OR expires_at > ?) ORDER BY create_time DESC", email, helper.NewTimestamp()).
 		Scan(&invitations)
 	if db.Error != nil {
 		return nil, db.Error
@@ -152,6 +192,7 @@ func (repo *InvitationRepo) CountIncoming(email string) (int64, error) {
 		Model(&invitationEntity{}).
 		Where("email = ?", email).
 		Where("status = 'pending'").
+		Where("expires_at IS NULL OR expires_at > ?", helper.NewTimestamp()).
 		Count(&count)
 	if db.Error != nil {
 		return -1, db.Error
@@ -174,6 +215,45 @@ func (repo *InvitationRepo) FindOutgoing(orgID string, userID string) ([]model.I
 	return res, nil
 }
 
+// Expire marks the pending invitations whose expiry date has passed as expired,
+// and returns how many were affected.
+func (repo *InvitationRepo) Expire() (int64, error) {
+	db := repo.db.Exec(
+		"UPDATE invitation SET status = 'expired', update_time = ? WHERE status = 'pending' AND expires_at IS NOT NULL AND expires_at <= ?",
+		helper.NewTimestamp(), helper.NewTimestamp(),
+	)
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+func (repo *InvitationRepo) CountPendingOutgoing(orgID string, userID string) (int64, error) {
+	var count int64
+	db := repo.db.
+		Model(&invitationEntity{}).
+		Where("organization_id = ?", orgID).
+		Where("owner_id = ?", userID).
+		Where("status = 'pending'").
+		Count(&count)
+	if db.Error != nil {
+		return -1, db.Error
+	}
+	return count, nil
+}
+
+// TransferPending hands the pending invitations the user sent in the organization over to the target.
+func (repo *InvitationRepo) TransferPending(orgID string, userID string, targetID string) error {
+	db := repo.db.Exec(
+		"UPDATE invitation SET owner_id = ?, update_time = ? WHERE organization_id = ? AND owner_id = ? AND status = 'pending'",
+		targetID, helper.NewTimestamp(), orgID, userID,
+	)
+	if db.Error != nil {
+		return db.Error
+	}
+	return nil
+}
+
 func (repo *InvitationRepo) Save(org model.Invitation) error {
 	db := repo.db.Save(org)
 	if db.Error != nil {
@@ -189,3 +269,11 @@ func (repo *InvitationRepo) Delete(id string) error {
 	}
 	return nil
 }
+
+func (repo *InvitationRepo) DeletePending(orgID string) (int64, error) {
+	db := repo.db.Exec("DELETE FROM invitation WHERE organization_id = ? AND status = 'pending'", orgID)
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
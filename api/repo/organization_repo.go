@@ -22,13 +22,15 @@ import (
 )
 
 type organizationEntity struct {
-	ID               string                  `gorm:"column:id"          json:"id"`
-	Name             string                  `gorm:"column:name"        json:"name"`
-	UserPermissions  []*UserPermissionValue  `gorm:"-"                  json:"userPermissions"`
-	GroupPermissions []*GroupPermissionValue `gorm:"-"                  json:"groupPermissions"`
-	Members          []string                `gorm:"-"                  json:"members"`
-	CreateTime       string                  `gorm:"column:create_time" json:"createTime"`
-	UpdateTime       *string                 `gorm:"column:update_time" json:"updateTime,omitempty"`
+	ID                    string                  `gorm:"column:id"                      json:"id"`
+	Name                  string                  `gorm:"column:name"                    json:"name"`
+	UserPermissions       []*UserPermissionValue  `gorm:"-"                              json:"userPermissions"`
+	GroupPermissions      []*GroupPermissionValue `gorm:"-"                              json:"groupPermissions"`
+	Members               []string                `gorm:"-"                              json:"members"`
+	StorageCapacity       *int64                  `gorm:"column:storage_capacity"        json:"storageCapacity,omitempty"`
+	MemberStorageCapacity *int64                  `gorm:"column:member_storage_capacity" json:"memberStorageCapacity,omitempty"`
+	CreateTime            string                  `gorm:"column:create_time"             json:"createTime"`
+	UpdateTime            *string                 `gorm:"column:update_time"             json:"updateTime,omitempty"`
 }
 
 func (*organizationEntity) TableName() string {
@@ -74,6 +76,14 @@ func (o *organizationEntity) GetMembers() []string {
 	return o.Members
 }
 
+func (o *organizationEntity) GetStorageCapacity() *int64 {
+	return o.StorageCapacity
+}
+
+func (o *organizationEntity) GetMemberStorageCapacity() *int64 {
+	return o.MemberStorageCapacity
+}
+
 func (o *organizationEntity) GetCreateTime() string {
 	return o.CreateTime
 }
@@ -104,6 +114,14 @@ func (o *organizationEntity) SetGroupPermissions(permissions []model.CoreGroupPe
 	}
 }
 
+func (o *organizationEntity) SetStorageCapacity(storageCapacity *int64) {
+	o.StorageCapacity = storageCapacity
+}
+
+func (o *organizationEntity) SetMemberStorageCapacity(memberStorageCapacity *int64) {
+	o.MemberStorageCapacity = memberStorageCapacity
+}
+
 func (o *organizationEntity) SetCreateTime(createTime string) {
 	o.CreateTime = createTime
 }
@@ -321,8 +339,11 @@ func (repo *OrganizationRepo) populateModelFields(organizations []*organizationE
 		}
 		for _, p := range userPermissions {
 			o.UserPermissions = append(o.UserPermissions, &UserPermissionValue{
-				UserID: p.GetUserID(),
-				Value:  p.GetPermission(),
+				UserID:    p.GetUserID(),
+				Value:     p.GetPermission(),
+				StartsAt:  p.GetStartsAt(),
+				ExpiresAt: p.GetExpiresAt(),
+				RoleID:    p.GetRoleID(),
 			})
 		}
 		o.GroupPermissions = make([]*GroupPermissionValue, 0)
@@ -332,8 +353,11 @@ func (repo *OrganizationRepo) populateModelFields(organizations []*organizationE
 		}
 		for _, p := range groupPermissions {
 			o.GroupPermissions = append(o.GroupPermissions, &GroupPermissionValue{
-				GroupID: p.GetGroupID(),
-				Value:   p.GetPermission(),
+				GroupID:   p.GetGroupID(),
+				Value:     p.GetPermission(),
+				StartsAt:  p.GetStartsAt(),
+				ExpiresAt: p.GetExpiresAt(),
+				RoleID:    p.GetRoleID(),
 			})
 		}
 		members, err := repo.FindMembers(o.ID)
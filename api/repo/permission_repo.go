@@ -11,6 +11,8 @@
 package repo
 
 import (
+	"fmt"
+
 	"gorm.io/gorm"
 
 	"github.com/kouprlabs/voltaserve/api/helper"
@@ -19,11 +21,18 @@ import (
 )
 
 type userPermissionEntity struct {
-	ID         string `gorm:"column:id"          json:"id"`
-	UserID     string `gorm:"column:user_id"     json:"userId"`
-	ResourceID string `gorm:"column:resource_id" json:"resourceId"`
-	Permission string `gorm:"column:permission"  json:"permission"`
-	CreateTime string `gorm:"column:create_time" json:"createTime"`
+	ID           string  `gorm:"column:id"            json:"id"`
+	UserID       string  `gorm:"column:user_id"       json:"userId"`
+	ResourceID   string  `gorm:"column:resource_id"   json:"resourceId"`
+	Permission   string  `gorm:"column:permission"    json:"permission"`
+	Effect       string  `gorm:"column:effect"        json:"effect"`
+	Scope        string  `gorm:"column:scope"         json:"scope"`
+	StartsAt     *string `gorm:"column:starts_at"     json:"startsAt,omitempty"`
+	ExpiresAt    *string `gorm:"column:expires_at"    json:"expiresAt,omitempty"`
+	GrantedBy    *string `gorm:"column:granted_by"    json:"grantedBy,omitempty"`
+	ReminderTime *string `gorm:"column:reminder_time" json:"reminderTime,omitempty"`
+	RoleID       *string `gorm:"column:role_id"       json:"roleId,omitempty"`
+	CreateTime   string  `gorm:"column:create_time"   json:"createTime"`
 }
 
 func (*userPermissionEntity) TableName() string {
@@ -32,6 +41,12 @@ func (*userPermissionEntity) TableName() string {
 
 func (u *userPermissionEntity) BeforeCreate(*gorm.DB) (err error) {
 	u.CreateTime = helper.NewTimestamp()
+	if u.Effect == "" {
+		u.Effect = model.PermissionEffectAllow
+	}
+	if u.Scope == "" {
+		u.Scope = model.PermissionScopeTree
+	}
 	return nil
 }
 
@@ -51,6 +66,34 @@ func (u *userPermissionEntity) GetPermission() string {
 	return u.Permission
 }
 
+func (u *userPermissionEntity) GetEffect() string {
+	return u.Effect
+}
+
+func (u *userPermissionEntity) GetScope() string {
+	return u.Scope
+}
+
+func (u *userPermissionEntity) GetStartsAt() *string {
+	return u.StartsAt
+}
+
+func (u *userPermissionEntity) GetExpiresAt() *string {
+	return u.ExpiresAt
+}
+
+func (u *userPermissionEntity) GetGrantedBy() *string {
+	return u.GrantedBy
+}
+
+func (u *userPermissionEntity) GetReminderTime() *string {
+	return u.ReminderTime
+}
+
+func (u *userPermissionEntity) GetRoleID() *string {
+	return u.RoleID
+}
+
 func (u *userPermissionEntity) GetCreateTime() string {
 	return u.CreateTime
 }
@@ -71,16 +114,51 @@ func (u *userPermissionEntity) SetPermission(permission string) {
 	u.Permission = permission
 }
 
+func (u *userPermissionEntity) SetEffect(effect string) {
+	u.Effect = effect
+}
+
+func (u *userPermissionEntity) SetScope(scope string) {
+	u.Scope = scope
+}
+
+func (u *userPermissionEntity) SetStartsAt(startsAt *string) {
+	u.StartsAt = startsAt
+}
+
+func (u *userPermissionEntity) SetExpiresAt(expiresAt *string) {
+	u.ExpiresAt = expiresAt
+}
+
+func (u *userPermissionEntity) SetGrantedBy(grantedBy *string) {
+	u.GrantedBy = grantedBy
+}
+
+func (u *userPermissionEntity) SetReminderTime(reminderTime *string) {
+	u.ReminderTime = reminderTime
+}
+
+func (u *userPermissionEntity) SetRoleID(roleID *string) {
+	u.RoleID = roleID
+}
+
 func (u *userPermissionEntity) SetCreateTime(createTime string) {
 	u.CreateTime = createTime
 }
 
 type groupPermissionEntity struct {
-	ID         string `gorm:"column:id"          json:"id"`
-	GroupID    string `gorm:"column:group_id"    json:"groupId"`
-	ResourceID string `gorm:"column:resource_id" json:"resourceId"`
-	Permission string `gorm:"column:permission"  json:"permission"`
-	CreateTime string `gorm:"column:create_time" json:"createTime"`
+	ID           string  `gorm:"column:id"            json:"id"`
+	GroupID      string  `gorm:"column:group_id"      json:"groupId"`
+	ResourceID   string  `gorm:"column:resource_id"   json:"resourceId"`
+	Permission   string  `gorm:"column:permission"    json:"permission"`
+	Effect       string  `gorm:"column:effect"        json:"effect"`
+	Scope        string  `gorm:"column:scope"         json:"scope"`
+	StartsAt     *string `gorm:"column:starts_at"     json:"startsAt,omitempty"`
+	ExpiresAt    *string `gorm:"column:expires_at"    json:"expiresAt,omitempty"`
+	GrantedBy    *string `gorm:"column:granted_by"    json:"grantedBy,omitempty"`
+	ReminderTime *string `gorm:"column:reminder_time" json:"reminderTime,omitempty"`
+	RoleID       *string `gorm:"column:role_id"       json:"roleId,omitempty"`
+	CreateTime   string  `gorm:"column:create_time"   json:"createTime"`
 }
 
 func (*groupPermissionEntity) TableName() string {
@@ -89,6 +167,12 @@ func (*groupPermissionEntity) TableName() string {
 
 func (g *groupPermissionEntity) BeforeCreate(*gorm.DB) (err error) {
 	g.CreateTime = helper.NewTimestamp()
+	if g.Effect == "" {
+		g.Effect = model.PermissionEffectAllow
+	}
+	if g.Scope == "" {
+		g.Scope = model.PermissionScopeTree
+	}
 	return nil
 }
 
@@ -108,6 +192,34 @@ func (g *groupPermissionEntity) GetPermission() string {
 	return g.Permission
 }
 
+func (g *groupPermissionEntity) GetEffect() string {
+	return g.Effect
+}
+
+func (g *groupPermissionEntity) GetScope() string {
+	return g.Scope
+}
+
+func (g *groupPermissionEntity) GetStartsAt() *string {
+	return g.StartsAt
+}
+
+func (g *groupPermissionEntity) GetExpiresAt() *string {
+	return g.ExpiresAt
+}
+
+func (g *groupPermissionEntity) GetGrantedBy() *string {
+	return g.GrantedBy
+}
+
+func (g *groupPermissionEntity) GetReminderTime() *string {
+	return g.ReminderTime
+}
+
+func (g *groupPermissionEntity) GetRoleID() *string {
+	return g.RoleID
+}
+
 func (g *groupPermissionEntity) GetCreateTime() string {
 	return g.CreateTime
 }
@@ -128,13 +240,46 @@ func (g *groupPermissionEntity) SetPermission(permission string) {
 	g.Permission = permission
 }
 
+func (g *groupPermissionEntity) SetEffect(effect string) {
+	g.Effect = effect
+}
+
+func (g *groupPermissionEntity) SetScope(scope string) {
+	g.Scope = scope
+}
+
+func (g *groupPermissionEntity) SetStartsAt(startsAt *string) {
+	g.StartsAt = startsAt
+}
+
+func (g *groupPermissionEntity) SetExpiresAt(expiresAt *string) {
+	g.ExpiresAt = expiresAt
+}
+
+func (g *groupPermissionEntity) SetGrantedBy(grantedBy *string) {
+	g.GrantedBy = grantedBy
+}
+
+func (g *groupPermissionEntity) SetReminderTime(reminderTime *string) {
+	g.ReminderTime = reminderTime
+}
+
+func (g *groupPermissionEntity) SetRoleID(roleID *string) {
+	g.RoleID = roleID
+}
+
 func (g *groupPermissionEntity) SetCreateTime(createTime string) {
 	g.CreateTime = createTime
 }
 
 type UserPermissionValue struct {
-	UserID string `json:"userId,omitempty"`
-	Value  string `json:"value,omitempty"`
+	UserID    string  `json:"userId,omitempty"`
+	Value     string  `json:"value,omitempty"`
+	Effect    string  `json:"effect,omitempty"`
+	Scope     string  `json:"scope,omitempty"`
+	StartsAt  *string `json:"startsAt,omitempty"`
+	ExpiresAt *string `json:"expiresAt,omitempty"`
+	RoleID    *string `json:"roleId,omitempty"`
 }
 
 func (p UserPermissionValue) GetUserID() string {
@@ -145,9 +290,42 @@ func (p UserPermissionValue) GetValue() string {
 	return p.Value
 }
 
+// GetEffect defaults to allow, as only file permissions carry an effect.
+func (p UserPermissionValue) GetEffect() string {
+	if p.Effect == "" {
+		return model.PermissionEffectAllow
+	}
+	return p.Effect
+}
+
+// GetScope defaults to tree, as only file permissions carry a scope.
+func (p UserPermissionValue) GetScope() string {
+	if p.Scope == "" {
+		return model.PermissionScopeTree
+	}
+	return p.Scope
+}
+
+func (p UserPermissionValue) GetStartsAt() *string {
+	return p.StartsAt
+}
+
+func (p UserPermissionValue) GetExpiresAt() *string {
+	return p.ExpiresAt
+}
+
+func (p UserPermissionValue) GetRoleID() *string {
+	return p.RoleID
+}
+
 type GroupPermissionValue struct {
-	GroupID string `json:"groupId,omitempty"`
-	Value   string `json:"value,omitempty"`
+	GroupID   string  `json:"groupId,omitempty"`
+	Value     string  `json:"value,omitempty"`
+	Effect    string  `json:"effect,omitempty"`
+	Scope     string  `json:"scope,omitempty"`
+	StartsAt  *string `json:"startsAt,omitempty"`
+	ExpiresAt *string `json:"expiresAt,omitempty"`
+	RoleID    *string `json:"roleId,omitempty"`
 }
 
 func (p GroupPermissionValue) GetGroupID() string {
@@ -158,10 +336,47 @@ func (p GroupPermissionValue) GetValue() string {
 	return p.Value
 }
 
+// GetEffect defaults to allow, as only file permissions carry an effect.
+func (p GroupPermissionValue) GetEffect() string {
+	if p.Effect == "" {
+		return model.PermissionEffectAllow
+	}
+	return p.Effect
+}
+
+// GetScope defaults to tree, as only file permissions carry a scope.
+func (p GroupPermissionValue) GetScope() string {
+	if p.Scope == "" {
+		return model.PermissionScopeTree
+	}
+	return p.Scope
+}
+
+func (p GroupPermissionValue) GetStartsAt() *string {
+	return p.StartsAt
+}
+
+func (p GroupPermissionValue) GetExpiresAt() *string {
+	return p.ExpiresAt
+}
+
+func (p GroupPermissionValue) GetRoleID() *string {
+	return p.RoleID
+}
+
 func NewUserPermission() model.UserPermission {
 	return &userPermissionEntity{}
 }
 
+// PermissionGrantOptions limits a grant in time, both bounds are optional.
+// RoleID is set when the grant gives an organization role rather than a built-in permission.
+type PermissionGrantOptions struct {
+	StartsAt  *string
+	ExpiresAt *string
+	GrantedBy *string
+	RoleID    *string
+}
+
 type PermissionRepo struct {
 	db *gorm.DB
 }
@@ -207,3 +422,288 @@ func (repo *PermissionRepo) FindGroupPermissions(id string) ([]model.GroupPermis
 		return nil, nil
 	}
 }
+
+func (repo *PermissionRepo) FindExpiredUserPermissions() ([]model.UserPermission, error) {
+	var entities []*userPermissionEntity
+	if db := repo.db.
+		Raw("SELECT * FROM userpermission WHERE expires_at IS NOT NULL AND expires_at <= ?", helper.NewTimestamp()).
+		Scan(&entities); db.Error != nil {
+		return nil, db.Error
+	}
+	var res []model.UserPermission
+	for _, entity := range entities {
+		res = append(res, entity)
+	}
+	return res, nil
+}
+
+func (repo *PermissionRepo) FindExpiredGroupPermissions() ([]model.GroupPermission, error) {
+	var entities []*groupPermissionEntity
+	if db := repo.db.
+		Raw("SELECT * FROM grouppermission WHERE expires_at IS NOT NULL AND expires_at <= ?", helper.NewTimestamp()).
+		Scan(&entities); db.Error != nil {
+		return nil, db.Error
+	}
+	var res []model.GroupPermission
+	for _, entity := range entities {
+		res = append(res, entity)
+	}
+	return res, nil
+}
+
+// FindStartedResourceIDs returns the resources having a grant that started between the given times.
+func (repo *PermissionRepo) FindStartedResourceIDs(after string, before string) ([]string, error) {
+	type Value struct {
+		Result string
+	}
+	var values []Value
+	if db := repo.db.
+		Raw(`SELECT resource_id result FROM userpermission WHERE starts_at > ? AND starts_at <= ?
+             UNION SELECT resource_id result FROM grouppermission WHERE starts_at > ? AND starts_at <= ?`,
+			after, before, after, before).
+		Scan(&values); db.Error != nil {
+		return nil, db.Error
+	}
+	res := make([]string, 0)
+	for _, v := range values {
+		res = append(res, v.Result)
+	}
+	return res, nil
+}
+
+// FindUserPermissionsToRemind returns the grants expiring before the given time, whose
+// granter wasn't reminded yet. Grants on ancestors that only let users reach a shared
+// file are left out, as they expire along with the grant itself.
+func (repo *PermissionRepo) FindUserPermissionsToRemind(before string) ([]model.UserPermission, error) {
+	var entities []*userPermissionEntity
+	if db := repo.db.
+		Raw(`SELECT * FROM userpermission WHERE expires_at IS NOT NULL AND expires_at <= ?
+             AND granted_by IS NOT NULL AND reminder_time IS NULL AND scope = ?`,
+			before, model.PermissionScopeTree).
+		Scan(&entities); db.Error != nil {
+		return nil, db.Error
+	}
+	var res []model.UserPermission
+	for _, entity := range entities {
+		res = append(res, entity)
+	}
+	return res, nil
+}
+
+func (repo *PermissionRepo) FindGroupPermissionsToRemind(before string) ([]model.GroupPermission, error) {
+	var entities []*groupPermissionEntity
+	if db := repo.db.
+		Raw(`SELECT * FROM grouppermission WHERE expires_at IS NOT NULL AND expires_at <= ?
+             AND granted_by IS NOT NULL AND reminder_time IS NULL AND scope = ?`,
+			before, model.PermissionScopeTree).
+		Scan(&entities); db.Error != nil {
+		return nil, db.Error
+	}
+	var res []model.GroupPermission
+	for _, entity := range entities {
+		res = append(res, entity)
+	}
+	return res, nil
+}
+
+// ClaimReminder marks the reminder of the grant as sent, it returns false if another
+// instance claimed it first.
+func (repo *PermissionRepo) ClaimReminder(table string, id string) (bool, error) {
+	db := repo.db.
+		Exec(fmt.Sprintf("UPDATE %s SET reminder_time = ? WHERE id = ? AND reminder_time IS NULL", table),
+			helper.NewTimestamp(), id)
+	if db.Error != nil {
+		return false, db.Error
+	}
+	return db.RowsAffected > 0, nil
+}
+
+func (repo *PermissionRepo) DeleteUserPermission(id string) error {
+	if db := repo.db.Exec("DELETE FROM userpermission WHERE id = ?", id); db.Error != nil {
+		return db.Error
+	}
+	return nil
+}
+
+func (repo *PermissionRepo) DeleteGroupPermission(id string) error {
+	if db := repo.db.Exec("DELETE FROM grouppermission WHERE id = ?", id); db.Error != nil {
+		return db.Error
+	}
+	return nil
+}
+
+// FindResourceType tells which table the resource of a grant lives in, as the same
+// permission tables are shared by files, workspaces, groups and organizations.
+func (repo *PermissionRepo) FindResourceType(resourceID string) (string, error) {
+	type Value struct {
+		Result string
+	}
+	var value Value
+	if db := repo.db.
+		Raw(`SELECT CASE
+             WHEN EXISTS (SELECT 1 FROM file WHERE id = ?) THEN 'file'
+             WHEN EXISTS (SELECT 1 FROM workspace WHERE id = ?) THEN 'workspace'
+             WHEN EXISTS (SELECT 1 FROM "group" WHERE id = ?) THEN 'group'
+             WHEN EXISTS (SELECT 1 FROM organization WHERE id = ?) THEN 'organization'
+             ELSE '' END result`, resourceID, resourceID, resourceID, resourceID).
+		Scan(&value); db.Error != nil {
+		return "", db.Error
+	}
+	return value.Result, nil
+}
+
+func (repo *PermissionRepo) CountRoleGrants(roleID string) (int64, error) {
+	type Value struct {
+		Result int64
+	}
+	var value Value
+	if db := repo.db.
+		Raw(`SELECT (SELECT count(*) FROM userpermission WHERE role_id = ?) +
+             (SELECT count(*) FROM grouppermission WHERE role_id = ?) result`, roleID, roleID).
+		Scan(&value); db.Error != nil {
+		return -1, db.Error
+	}
+	return value.Result, nil
+}
+
+func (repo *PermissionRepo) FindRoleResourceIDs(roleID string) ([]string, error) {
+	type Value struct {
+		Result string
+	}
+	var values []Value
+	if db := repo.db.
+		Raw(`SELECT resource_id result FROM userpermission WHERE role_id = ?
+             UNION
+             SELECT resource_id result FROM grouppermission WHERE role_id = ?`, roleID, roleID).
+		Scan(&values); db.Error != nil {
+		return nil, db.Error
+	}
+	res := make([]string, 0)
+	for _, v := range values {
+		res = append(res, v.Result)
+	}
+	return res, nil
+}
+
+// UpdateRolePermission keeps the permission stored along the grants of the role
+// in line with its capabilities.
+func (repo *PermissionRepo) UpdateRolePermission(roleID string, permission string) error {
+	if db := repo.db.Exec("UPDATE userpermission SET permission = ? WHERE role_id = ?", permission, roleID); db.Error != nil {
+		return db.Error
+	}
+	if db := repo.db.Exec("UPDATE grouppermission SET permission = ? WHERE role_id = ?", permission, roleID); db.Error != nil {
+		return db.Error
+	}
+	return nil
+}
+
+// organizationResources lists the workspaces, groups and files of the organization given
+// as the first three arguments, along with their type.
+const organizationResources = `SELECT w.id, 'workspace' type FROM workspace w WHERE w.organization_id = ?
+                               UNION ALL SELECT g.id, 'group' type FROM "group" g WHERE g.organization_id = ?
+                               UNION ALL SELECT f.id, 'file' type FROM file f
+                               INNER JOIN workspace w ON w.id = f.workspace_id AND w.organization_id = ?`
+
+type OwnedResourceValue struct {
+	ResourceID   string `json:"resourceId"`
+	ResourceType string `json:"resourceType"`
+	IsSoleOwner  bool   `json:"isSoleOwner"`
+}
+
+// FindOwnedResources returns the resources of the organization the user is an owner of. The user is
+// the sole owner when no other user or group owns the resource, or a file it inherits from.
+func (repo *PermissionRepo) FindOwnedResources(orgID string, userID string) ([]OwnedResourceValue, error) {
+	var res []OwnedResourceValue
+	if db := repo.db.
+		Raw(fmt.Sprintf(`WITH RECURSIVE res (id, type) AS (%s),
+             owned AS (SELECT res.id, res.type FROM res
+             INNER JOIN userpermission p ON p.resource_id = res.id AND p.user_id = ?
+             AND p.permission = 'owner' AND p.effect = 'allow'),
+             anc (resource_id, id, parent_id, breaks_inheritance) AS
+             (SELECT owned.id, owned.id, NULL::text, true FROM owned WHERE owned.type != 'file'
+             UNION ALL SELECT f.id, f.id, f.parent_id, f.breaks_inheritance FROM file f INNER JOIN owned ON owned.id = f.id
+             UNION SELECT anc.resource_id, f.id, f.parent_id, f.breaks_inheritance FROM anc, file f
+             WHERE f.id = anc.parent_id AND NOT anc.breaks_inheritance)
+             SELECT owned.id resource_id, owned.type resource_type, NOT EXISTS (
+             SELECT 1 FROM anc WHERE anc.resource_id = owned.id AND (
+             EXISTS (SELECT 1 FROM userpermission o WHERE o.resource_id = anc.id AND o.user_id != ?
+             AND o.permission = 'owner' AND o.effect = 'allow' AND (o.scope = 'tree' OR anc.id = owned.id))
+             OR EXISTS (SELECT 1 FROM grouppermission o WHERE o.resource_id = anc.id
+             AND o.permission = 'owner' AND o.effect = 'allow' AND (o.scope = 'tree' OR anc.id = owned.id)))
+             ) is_sole_owner FROM owned`, organizationResources),
+			orgID, orgID, orgID, userID, userID).
+		Scan(&res); db.Error != nil {
+		return nil, db.Error
+	}
+	return res, nil
+}
+
+// RevokeUserFilePermissions deletes the permissions of the user on the files of the organization,
+// and returns the IDs of the files.
+func (repo *PermissionRepo) RevokeUserFilePermissions(orgID string, userID string) ([]string, error) {
+	type Value struct {
+		Result string
+	}
+	var values []Value
+	if db := repo.db.
+		Raw(`DELETE FROM userpermission p USING file f, workspace w
+             WHERE p.resource_id = f.id AND w.id = f.workspace_id AND w.organization_id = ? AND p.user_id = ?
+             RETURNING p.resource_id result`,
+			orgID, userID).
+		Scan(&values); db.Error != nil {
+		return nil, db.Error
+	}
+	res := make([]string, 0)
+	for _, v := range values {
+		res = append(res, v.Result)
+	}
+	return res, nil
+}
+
+// TransferOwnership makes the target an owner of everything the user owns in the organization,
+// and demotes the user to editor if asked to. It returns the resources which changed hands.
+func (repo *PermissionRepo) TransferOwnership(orgID string, userID string, targetID string, demote bool) ([]OwnedResourceValue, error) {
+	var res []OwnedResourceValue
+	if db := repo.db.
+		Raw(fmt.Sprintf(`WITH res (id, type) AS (%s)
+             SELECT res.id resource_id, res.type resource_type FROM res
+             INNER JOIN userpermission p ON p.resource_id = res.id AND p.user_id = ?
+             AND p.permission = 'owner' AND p.effect = 'allow'`, organizationResources),
+			orgID, orgID, orgID, userID).
+		Scan(&res); db.Error != nil {
+		return nil, db.Error
+	}
+	if len(res) == 0 {
+		return res, nil
+	}
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if db := tx.
+			Exec(fmt.Sprintf(`WITH res (id, type) AS (%s)
+                 INSERT INTO userpermission (id, user_id, resource_id, permission, effect, scope, granted_by, create_time)
+                 SELECT md5(random()::text || p.id), ?, p.resource_id, 'owner', 'allow', p.scope, ?, ? FROM userpermission p
+                 INNER JOIN res ON res.id = p.resource_id
+                 WHERE p.user_id = ? AND p.permission = 'owner' AND p.effect = 'allow'
+                 ON CONFLICT (user_id, resource_id) DO UPDATE SET permission = 'owner', effect = 'allow',
+                 scope = EXCLUDED.scope, starts_at = NULL, expires_at = NULL, role_id = NULL, reminder_time = NULL`,
+				organizationResources),
+				orgID, orgID, orgID, targetID, userID, helper.NewTimestamp(), userID); db.Error != nil {
+			return db.Error
+		}
+		if !demote {
+			return nil
+		}
+		if db := tx.
+			Exec(fmt.Sprintf(`WITH res (id, type) AS (%s)
+                 UPDATE userpermission p SET permission = 'editor', role_id = NULL FROM res
+                 WHERE res.id = p.resource_id AND p.user_id = ? AND p.permission = 'owner' AND p.effect = 'allow'`,
+				organizationResources),
+				orgID, orgID, orgID, userID); db.Error != nil {
+			return db.Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/kouprlabs/voltaserve/api/errorpkg"
+	"github.com/kouprlabs/voltaserve/api/helper"
+	"github.com/kouprlabs/voltaserve/api/infra"
+	"github.com/kouprlabs/voltaserve/api/log"
+	"github.com/kouprlabs/voltaserve/api/model"
+)
+
+type roleEntity struct {
+	ID             string         `gorm:"column:id"              json:"id"`
+	OrganizationID string         `gorm:"column:organization_id" json:"organizationId"`
+	Name           string         `gorm:"column:name"            json:"name"`
+	Capabilities   datatypes.JSON `gorm:"column:capabilities"    json:"capabilities"`
+	CreateTime     string         `gorm:"column:create_time"     json:"createTime"`
+	UpdateTime     *string        `gorm:"column:update_time"     json:"updateTime"`
+}
+
+func (*roleEntity) TableName() string {
+	return "role"
+}
+
+func (r *roleEntity) BeforeCreate(*gorm.DB) (err error) {
+	r.CreateTime = helper.NewTimestamp()
+	return nil
+}
+
+func (r *roleEntity) BeforeSave(*gorm.DB) (err error) {
+	timeNow := helper.NewTimestamp()
+	r.UpdateTime = &timeNow
+	return nil
+}
+
+func (r *roleEntity) GetID() string {
+	return r.ID
+}
+
+func (r *roleEntity) GetOrganizationID() string {
+	return r.OrganizationID
+}
+
+func (r *roleEntity) GetName() string {
+	return r.Name
+}
+
+func (r *roleEntity) GetCapabilities() []string {
+	res := make([]string, 0)
+	if r.Capabilities.String() == "" {
+		return res
+	}
+	if err := json.Unmarshal([]byte(r.Capabilities.String()), &res); err != nil {
+		log.GetLogger().Error(err)
+		return []string{}
+	}
+	return res
+}
+
+func (r *roleEntity) GetCreateTime() string {
+	return r.CreateTime
+}
+
+func (r *roleEntity) GetUpdateTime() *string {
+	return r.UpdateTime
+}
+
+func (r *roleEntity) SetName(name string) {
+	r.Name = name
+}
+
+func (r *roleEntity) SetCapabilities(capabilities []string) {
+	b, err := json.Marshal(capabilities)
+	if err != nil {
+		log.GetLogger().Error(err)
+		return
+	}
+	r.Capabilities = b
+}
+
+func (r *roleEntity) SetUpdateTime(updateTime *string) {
+	r.UpdateTime = updateTime
+}
+
+func NewRole() model.Role {
+	return &roleEntity{}
+}
+
+type RoleRepo struct {
+	db *gorm.DB
+}
+
+func NewRoleRepo() *RoleRepo {
+	return &RoleRepo{
+		db: infra.NewPostgresManager().GetDBOrPanic(),
+	}
+}
+
+type RoleInsertOptions struct {
+	ID             string
+	OrganizationID string
+	Name           string
+	Capabilities   []string
+}
+
+func (repo *RoleRepo) Insert(opts RoleInsertOptions) (model.Role, error) {
+	role := roleEntity{
+		ID:             opts.ID,
+		OrganizationID: opts.OrganizationID,
+		Name:           opts.Name,
+	}
+	role.SetCapabilities(opts.Capabilities)
+	if db := repo.db.Create(&role); db.Error != nil {
+		return nil, db.Error
+	}
+	res, err := repo.Find(opts.ID)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (repo *RoleRepo) find(id string) (*roleEntity, error) {
+	res := roleEntity{}
+	db := repo.db.Where("id = ?", id).First(&res)
+	if db.Error != nil {
+		if errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			return nil, errorpkg.NewRoleNotFoundError(db.Error)
+		} else {
+			return nil, errorpkg.NewInternalServerError(db.Error)
+		}
+	}
+	return &res, nil
+}
+
+func (repo *RoleRepo) Find(id string) (model.Role, error) {
+	res, err := repo.find(id)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (repo *RoleRepo) FindIDsByOrganization(id string) ([]string, error) {
+	type Value struct {
+		Result string
+	}
+	var values []Value
+	db := repo.db.Raw(`SELECT id as result FROM role WHERE organization_id = ? ORDER BY name`, id).Scan(&values)
+	if db.Error != nil {
+		return []string{}, db.Error
+	}
+	res := make([]string, 0)
+	for _, v := range values {
+		res = append(res, v.Result)
+	}
+	return res, nil
+}
+
+func (repo *RoleRepo) Save(role model.Role) error {
+	db := repo.db.Save(role)
+	if db.Error != nil {
+		return db.Error
+	}
+	return nil
+}
+
+func (repo *RoleRepo) Delete(id string) error {
+	db := repo.db.Exec("DELETE FROM role WHERE id = ?", id)
+	if db.Error != nil {
+		return db.Error
+	}
+	return nil
+}
@@ -26,20 +26,26 @@ import (
 )
 
 type snapshotEntity struct {
-	ID         string         `gorm:"column:id;size:36"  json:"id"`
-	Version    int64          `gorm:"column:version"     json:"version"`
-	Original   datatypes.JSON `gorm:"column:original"    json:"original,omitempty"`
-	Preview    datatypes.JSON `gorm:"column:preview"     json:"preview,omitempty"`
-	Text       datatypes.JSON `gorm:"column:text"        json:"text,omitempty"`
-	OCR        datatypes.JSON `gorm:"column:ocr"         json:"ocr,omitempty"`
-	Entities   datatypes.JSON `gorm:"column:entities"    json:"entities,omitempty"`
-	Mosaic     datatypes.JSON `gorm:"column:mosaic"      json:"mosaic,omitempty"`
-	Thumbnail  datatypes.JSON `gorm:"column:thumbnail"   json:"thumbnail,omitempty"`
-	Status     string         `gorm:"column,status"      json:"status,omitempty"`
-	Language   *string        `gorm:"column:language"    json:"language,omitempty"`
-	TaskID     *string        `gorm:"column:task_id"     json:"taskId,omitempty"`
-	CreateTime string         `gorm:"column:create_time" json:"createTime"`
-	UpdateTime *string        `gorm:"column:update_time" json:"updateTime,omitempty"`
+	ID                 string         `gorm:"column:id;size:36"  json:"id"`
+	Version            int64          `gorm:"column:version"     json:"version"`
+	Original           datatypes.JSON `gorm:"column:original"    json:"original,omitempty"`
+	Preview            datatypes.JSON `gorm:"column:preview"     json:"preview,omitempty"`
+	Text               datatypes.JSON `gorm:"column:text"        json:"text,omitempty"`
+	OCR                datatypes.JSON `gorm:"column:ocr"         json:"ocr,omitempty"`
+	Entities           datatypes.JSON `gorm:"column:entities"    json:"entities,omitempty"`
+	Layout             datatypes.JSON `gorm:"column:layout"      json:"layout,omitempty"`
+	Summary            datatypes.JSON `gorm:"column:summary" json:"summary,omitempty"`
+	Metadata           datatypes.JSON `gorm:"column:metadata" json:"metadata,omitempty"`
+	Sheets             datatypes.JSON `gorm:"column:sheets" json:"sheets,omitempty"`
+	Mosaic             datatypes.JSON `gorm:"column:mosaic"      json:"mosaic,omitempty"`
+	Thumbnail          datatypes.JSON `gorm:"column:thumbnail"   json:"thumbnail,omitempty"`
+	Status             string         `gorm:"column,status"              json:"status,omitempty"`
+	Language           *string        `gorm:"column:language"            json:"language,omitempty"`
+	LanguageConfidence *float64       `gorm:"column:language_confidence" json:"languageConfidence,omitempty"`
+	TaskID             *string        `gorm:"column:task_id"             json:"taskId,omitempty"`
+	UserID             *string        `gorm:"column:user_id"             json:"userId,omitempty"`
+	CreateTime         string         `gorm:"column:create_time"         json:"createTime"`
+	UpdateTime         *string        `gorm:"column:update_time"         json:"updateTime,omitempty"`
 }
 
 func (*snapshotEntity) TableName() string {
@@ -125,6 +131,54 @@ func (s *snapshotEntity) GetEntities() *model.S3Object {
 	return &res
 }
 
+func (s *snapshotEntity) GetLayout() *model.S3Object {
+	if s.Layout.String() == "" {
+		return nil
+	}
+	res := model.S3Object{}
+	if err := json.Unmarshal([]byte(s.Layout.String()), &res); err != nil {
+		log.GetLogger().Fatal(err)
+		return nil
+	}
+	return &res
+}
+
+func (s *snapshotEntity) GetSummary() *model.S3Object {
+	if s.Summary.String() == "" {
+		return nil
+	}
+	res := model.S3Object{}
+	if err := json.Unmarshal([]byte(s.Summary.String()), &res); err != nil {
+		log.GetLogger().Fatal(err)
+		return nil
+	}
+	return &res
+}
+
+func (s *snapshotEntity) GetMetadata() *model.S3Object {
+	if s.Metadata.String() == "" {
+		return nil
+	}
+	res := model.S3Object{}
+	if err := json.Unmarshal([]byte(s.Metadata.String()), &res); err != nil {
+		log.GetLogger().Fatal(err)
+		return nil
+	}
+	return &res
+}
+
+func (s *snapshotEntity) GetSheets() *model.S3Object {
+	if s.Sheets.String() == "" {
+		return nil
+	}
+	res := model.S3Object{}
+	if err := json.Unmarshal([]byte(s.Sheets.String()), &res); err != nil {
+		log.GetLogger().Fatal(err)
+		return nil
+	}
+	return &res
+}
+
 func (s *snapshotEntity) GetMosaic() *model.S3Object {
 	if s.Mosaic.String() == "" {
 		return nil
@@ -157,10 +211,18 @@ func (s *snapshotEntity) GetLanguage() *string {
 	return s.Language
 }
 
+func (s *snapshotEntity) GetLanguageConfidence() *float64 {
+	return s.LanguageConfidence
+}
+
 func (s *snapshotEntity) GetTaskID() *string {
 	return s.TaskID
 }
 
+func (s *snapshotEntity) GetUserID() *string {
+	return s.UserID
+}
+
 func (s *snapshotEntity) SetID(id string) {
 	s.ID = id
 }
@@ -244,6 +306,66 @@ func (s *snapshotEntity) SetEntities(m *model.S3Object) {
 	}
 }
 
+func (s *snapshotEntity) SetLayout(m *model.S3Object) {
+	if m == nil {
+		s.Layout = nil
+	} else {
+		b, err := json.Marshal(m)
+		if err != nil {
+			log.GetLogger().Fatal(err)
+			return
+		}
+		if err := s.Layout.UnmarshalJSON(b); err != nil {
+			log.GetLogger().Fatal(err)
+		}
+	}
+}
+
+func (s *snapshotEntity) SetSummary(m *model.S3Object) {
+	if m == nil {
+		s.Summary = nil
+	} else {
+		b, err := json.Marshal(m)
+		if err != nil {
+			log.GetLogger().Fatal(err)
+			return
+		}
+		if err := s.Summary.UnmarshalJSON(b); err != nil {
+			log.GetLogger().Fatal(err)
+		}
+	}
+}
+
+func (s *snapshotEntity) SetMetadata(m *model.S3Object) {
+	if m == nil {
+		s.Metadata = nil
+	} else {
+		b, err := json.Marshal(m)
+		if err != nil {
+			log.GetLogger().Fatal(err)
+			return
+		}
+		if err := s.Metadata.UnmarshalJSON(b); err != nil {
+			log.GetLogger().Fatal(err)
+		}
+	}
+}
+
+func (s *snapshotEntity) SetSheets(m *model.S3Object) {
+	if m == nil {
+		s.Sheets = nil
+	} else {
+		b, err := json.Marshal(m)
+		if err != nil {
+			log.GetLogger().Fatal(err)
+			return
+		}
+		if err := s.Sheets.UnmarshalJSON(b); err != nil {
+			log.GetLogger().Fatal(err)
+		}
+	}
+}
+
 func (s *snapshotEntity) SetMosaic(m *model.S3Object) {
 	if m == nil {
 		s.Mosaic = nil
@@ -282,10 +404,18 @@ func (s *snapshotEntity) SetLanguage(language string) {
 	s.Language = &language
 }
 
+func (s *snapshotEntity) SetLanguageConfidence(confidence *float64) {
+	s.LanguageConfidence = confidence
+}
+
 func (s *snapshotEntity) SetTaskID(taskID *string) {
 	s.TaskID = taskID
 }
 
+func (s *snapshotEntity) SetUserID(userID *string) {
+	s.UserID = userID
+}
+
 func (s *snapshotEntity) HasOriginal() bool {
 	return s.Original != nil
 }
@@ -306,6 +436,22 @@ func (s *snapshotEntity) HasEntities() bool {
 	return s.Entities != nil
 }
 
+func (s *snapshotEntity) HasLayout() bool {
+	return s.Layout != nil
+}
+
+func (s *snapshotEntity) HasSummary() bool {
+	return s.Summary != nil
+}
+
+func (s *snapshotEntity) HasMetadata() bool {
+	return s.Metadata != nil
+}
+
+func (s *snapshotEntity) HasSheets() bool {
+	return s.Sheets != nil
+}
+
 func (s *snapshotEntity) HasMosaic() bool {
 	return s.Mosaic != nil
 }
@@ -410,30 +556,40 @@ func (repo *SnapshotRepo) Delete(id string) error {
 }
 
 type SnapshotUpdateOptions struct {
-	Fields    []string `json:"fields"`
-	Original  *model.S3Object
-	Preview   *model.S3Object
-	Text      *model.S3Object
-	OCR       *model.S3Object
-	Entities  *model.S3Object
-	Mosaic    *model.S3Object
-	Thumbnail *model.S3Object
-	Status    *string
-	Language  *string
-	TaskID    *string
+	Fields             []string `json:"fields"`
+	Original           *model.S3Object
+	Preview            *model.S3Object
+	Text               *model.S3Object
+	OCR                *model.S3Object
+	Entities           *model.S3Object
+	Layout             *model.S3Object
+	Summary            *model.S3Object
+	Metadata           *model.S3Object
+	Sheets             *model.S3Object
+	Mosaic             *model.S3Object
+	Thumbnail          *model.S3Object
+	Status             *string
+	Language           *string
+	LanguageConfidence *float64
+	TaskID             *string
 }
 
 const (
-	SnapshotFieldOriginal  = "original"
-	SnapshotFieldPreview   = "preview"
-	SnapshotFieldText      = "text"
-	SnapshotFieldOCR       = "ocr"
-	SnapshotFieldEntities  = "entities"
-	SnapshotFieldMosaic    = "mosaic"
-	SnapshotFieldThumbnail = "thumbnail"
-	SnapshotFieldStatus    = "status"
-	SnapshotFieldLanguage  = "language"
-	SnapshotFieldTaskID    = "taskId"
+	SnapshotFieldOriginal           = "original"
+	SnapshotFieldPreview            = "preview"
+	SnapshotFieldText               = "text"
+	SnapshotFieldOCR                = "ocr"
+	SnapshotFieldEntities           = "entities"
+	SnapshotFieldLayout             = "layout"
+	SnapshotFieldSummary            = "summary"
+	SnapshotFieldMetadata           = "metadata"
+	SnapshotFieldSheets             = "sheets"
+	SnapshotFieldMosaic             = "mosaic"
+	SnapshotFieldThumbnail          = "thumbnail"
+	SnapshotFieldStatus             = "status"
+	SnapshotFieldLanguage           = "language"
+	SnapshotFieldLanguageConfidence = "languageConfidence"
+	SnapshotFieldTaskID             = "taskId"
 )
 
 func (repo *SnapshotRepo) Update(id string, opts SnapshotUpdateOptions) error {
@@ -456,6 +612,18 @@ func (repo *SnapshotRepo) Update(id string, opts SnapshotUpdateOptions) error {
 	if slices.Contains(opts.Fields, SnapshotFieldEntities) {
 		snapshot.SetEntities(opts.Entities)
 	}
+	if slices.Contains(opts.Fields, SnapshotFieldLayout) {
+		snapshot.SetLayout(opts.Layout)
+	}
+	if slices.Contains(opts.Fields, SnapshotFieldSummary) {
+		snapshot.SetSummary(opts.Summary)
+	}
+	if slices.Contains(opts.Fields, SnapshotFieldMetadata) {
+		snapshot.SetMetadata(opts.Metadata)
+	}
+	if slices.Contains(opts.Fields, SnapshotFieldSheets) {
+		snapshot.SetSheets(opts.Sheets)
+	}
 	if slices.Contains(opts.Fields, SnapshotFieldMosaic) {
 		snapshot.SetMosaic(opts.Mosaic)
 	}
@@ -468,6 +636,9 @@ func (repo *SnapshotRepo) Update(id string, opts SnapshotUpdateOptions) error {
 	if slices.Contains(opts.Fields, SnapshotFieldLanguage) {
 		snapshot.SetLanguage(*opts.Language)
 	}
+	if slices.Contains(opts.Fields, SnapshotFieldLanguageConfidence) {
+		snapshot.SetLanguageConfidence(opts.LanguageConfidence)
+	}
 	if slices.Contains(opts.Fields, SnapshotFieldTaskID) {
 		snapshot.SetTaskID(opts.TaskID)
 	}
@@ -549,6 +720,55 @@ func (repo *SnapshotRepo) FindExclusiveForFile(fileID string) ([]model.Snapshot,
 	return res, nil
 }
 
+func (repo *SnapshotRepo) FindAllForWorkspace(workspaceID string) ([]model.Snapshot, error) {
+	var entities []*snapshotEntity
+	db := repo.db.
+		Raw(`SELECT * FROM snapshot s
+             WHERE s.id IN (
+                 SELECT sf.snapshot_id FROM snapshot_file sf
+                 JOIN file f ON f.id = sf.file_id
+                 WHERE f.workspace_id = ?
+             )`,
+			workspaceID).
+		Scan(&entities)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	var res []model.Snapshot
+	for _, s := range entities {
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+// FindExclusiveForWorkspace returns the snapshots mapped only to files of the workspace,
+// those shared with other workspaces through copies are left out.
+func (repo *SnapshotRepo) FindExclusiveForWorkspace(workspaceID string) ([]model.Snapshot, error) {
+	var entities []*snapshotEntity
+	db := repo.db.
+		Raw(`SELECT * FROM snapshot s
+             WHERE s.id IN (
+                 SELECT sf.snapshot_id FROM snapshot_file sf
+                 JOIN file f ON f.id = sf.file_id
+                 WHERE f.workspace_id = ?
+             )
+             AND NOT EXISTS (
+                 SELECT 1 FROM snapshot_file sf2
+                 JOIN file f2 ON f2.id = sf2.file_id
+                 WHERE sf2.snapshot_id = s.id AND f2.workspace_id != ?
+             )`,
+			workspaceID, workspaceID).
+		Scan(&entities)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	var res []model.Snapshot
+	for _, s := range entities {
+		res = append(res, s)
+	}
+	return res, nil
+}
+
 func (repo *SnapshotRepo) FindAllForTask(taskID string) ([]model.Snapshot, error) {
 	var entities []*snapshotEntity
 	db := repo.db.
@@ -667,6 +887,22 @@ func (repo *SnapshotRepo) CountAssociations(id string) (int64, error) {
 	return count, nil
 }
 
+// CountCacheReferences counts the snapshots, other than the excluded ones, whose preview
+// was copied from the cache entry. At least one snapshot must be excluded.
+func (repo *SnapshotRepo) CountCacheReferences(bucket string, key string, excludedIDs []string) (int64, error) {
+	var count int64
+	db := repo.db.
+		Raw(`SELECT count(*) FROM snapshot
+             WHERE preview->'document'->'cache'->>'bucket' = ? AND preview->'document'->'cache'->>'key' = ?
+             AND id NOT IN ?`,
+			bucket, key, excludedIDs).
+		Scan(&count)
+	if db.Error != nil {
+		return -1, db.Error
+	}
+	return count, nil
+}
+
 func (repo *SnapshotRepo) Attach(sourceFileID string, targetFileID string) error {
 	if db := repo.db.
 		Exec(`INSERT INTO snapshot_file (snapshot_id, file_id, create_time) SELECT s.id, ?, ?
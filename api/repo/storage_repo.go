@@ -0,0 +1,218 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package repo
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/kouprlabs/voltaserve/api/helper"
+	"github.com/kouprlabs/voltaserve/api/infra"
+)
+
+// StorageUsageEntity is an entry of the usage ledger, it holds the bytes of the originals
+// uploaded by a user in a workspace for a file type, either of latest versions or of the history.
+type StorageUsageEntity struct {
+	WorkspaceID string `gorm:"column:workspace_id"`
+	UserID      string `gorm:"column:user_id"`
+	Category    string `gorm:"column:category"`
+	History     bool   `gorm:"column:history"`
+	Bytes       int64  `gorm:"column:bytes"`
+}
+
+func (*StorageUsageEntity) TableName() string {
+	return "storage_usage"
+}
+
+// StorageSnapshotSize is either the original of a snapshot, or the cached conversion its
+// preview was copied from.
+type StorageSnapshotSize struct {
+	UserID  string `gorm:"column:user_id"`
+	Key     string `gorm:"column:key"`
+	Size    int64  `gorm:"column:size"`
+	History bool   `gorm:"column:history"`
+	Cache   bool   `gorm:"column:cache"`
+}
+
+type StorageRepo struct {
+	db *gorm.DB
+}
+
+func NewStorageRepo() *StorageRepo {
+	return &StorageRepo{
+		db: infra.NewPostgresManager().GetDBOrPanic(),
+	}
+}
+
+// FindSnapshotSizes returns the originals of the tree and the cached conversions of their
+// previews, a snapshot is counted once for every file it's mapped to, the same way ComputeSize does.
+func (repo *StorageRepo) FindSnapshotSizes(rootID string) ([]*StorageSnapshotSize, error) {
+	var res []*StorageSnapshotSize
+	db := repo.db.
+		Raw(`WITH RECURSIVE rec (id, parent_id, snapshot_id) AS
+             (SELECT f.id, f.parent_id, f.snapshot_id FROM file f WHERE f.id = ?
+             UNION SELECT f.id, f.parent_id, f.snapshot_id FROM rec, file f WHERE f.parent_id = rec.id),
+             sizes AS (SELECT coalesce(s.user_id, '') user_id, s.original, s.preview->'document'->'cache' cache,
+             s.id IS DISTINCT FROM rec.snapshot_id history
+             FROM rec INNER JOIN snapshot_file map ON map.file_id = rec.id
+             INNER JOIN snapshot s ON s.id = map.snapshot_id)
+             SELECT user_id, original->>'key' key, coalesce((original->>'size')::bigint, 0) size, history, false cache
+             FROM sizes WHERE original IS NOT NULL
+             UNION ALL
+             SELECT user_id, cache->>'key' key, coalesce((cache->>'size')::bigint, 0) size, history, true cache
+             FROM sizes WHERE cache IS NOT NULL`,
+			rootID).
+		Scan(&res)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	return res, nil
+}
+
+func (repo *StorageRepo) ReplaceWorkspaceUsage(workspaceID string, entries []*StorageUsageEntity) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		if db := tx.Exec("DELETE FROM storage_usage WHERE workspace_id = ?", workspaceID); db.Error != nil {
+			return db.Error
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		if db := tx.Create(&entries); db.Error != nil {
+			return db.Error
+		}
+		return nil
+	})
+}
+
+// AddWorkspaceUsage adds the deltas to the entries in place, so concurrent changes of the same
+// workspace add up instead of overwriting each other. Entries that drop to zero are removed.
+func (repo *StorageRepo) AddWorkspaceUsage(workspaceID string, deltas []*StorageUsageEntity) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		for _, d := range deltas {
+			if db := tx.Exec(`INSERT INTO storage_usage (workspace_id, user_id, category, history, bytes)
+                              VALUES (?, ?, ?, ?, ?)
+                              ON CONFLICT (workspace_id, user_id, category, history)
+                              DO UPDATE SET bytes = storage_usage.bytes + EXCLUDED.bytes`,
+				workspaceID, d.UserID, d.Category, d.History, d.Bytes); db.Error != nil {
+				return db.Error
+			}
+		}
+		if db := tx.Exec("DELETE FROM storage_usage WHERE workspace_id = ? AND bytes <= 0", workspaceID); db.Error != nil {
+			return db.Error
+		}
+		return nil
+	})
+}
+
+func (repo *StorageRepo) FindWorkspaceUsage(workspaceID string) ([]*StorageUsageEntity, error) {
+	var res []*StorageUsageEntity
+	db := repo.db.
+		Raw(`SELECT ? workspace_id, '' user_id, category, history, sum(bytes) bytes FROM storage_usage
+             WHERE workspace_id = ? GROUP BY category, history ORDER BY category, history`,
+			workspaceID, workspaceID).
+		Scan(&res)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	return res, nil
+}
+
+func (repo *StorageRepo) FindOrganizationUsage(orgID string) ([]*StorageUsageEntity, error) {
+	var res []*StorageUsageEntity
+	db := repo.db.
+		Raw(`SELECT '' workspace_id, '' user_id, u.category, u.history, sum(u.bytes) bytes FROM storage_usage u
+             INNER JOIN workspace w ON w.id = u.workspace_id AND w.organization_id = ?
+             GROUP BY u.category, u.history ORDER BY u.category, u.history`,
+			orgID).
+		Scan(&res)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	return res, nil
+}
+
+func (repo *StorageRepo) SumWorkspaceUsage(workspaceID string) (int64, error) {
+	type Result struct {
+		Result int64
+	}
+	var res Result
+	db := repo.db.
+		Raw(`SELECT coalesce(sum(bytes), 0) result FROM storage_usage WHERE workspace_id = ?`, workspaceID).
+		Scan(&res)
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return res.Result, nil
+}
+
+func (repo *StorageRepo) SumOrganizationUsage(orgID string) (int64, error) {
+	type Result struct {
+		Result int64
+	}
+	var res Result
+	db := repo.db.
+		Raw(`SELECT coalesce(sum(u.bytes), 0) result FROM storage_usage u
+             INNER JOIN workspace w ON w.id = u.workspace_id AND w.organization_id = ?`,
+			orgID).
+		Scan(&res)
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return res.Result, nil
+}
+
+func (repo *StorageRepo) SumMemberUsage(orgID string, userID string) (int64, error) {
+	type Result struct {
+		Result int64
+	}
+	var res Result
+	db := repo.db.
+		Raw(`SELECT coalesce(sum(u.bytes), 0) result FROM storage_usage u
+             INNER JOIN workspace w ON w.id = u.workspace_id AND w.organization_id = ?
+             WHERE u.user_id = ?`,
+			orgID, userID).
+		Scan(&res)
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return res.Result, nil
+}
+
+// FindWarningThreshold returns the highest threshold a warning was sent for, the user
+// is empty for the warnings about the organization itself.
+func (repo *StorageRepo) FindWarningThreshold(orgID string, userID string) (int, error) {
+	type Result struct {
+		Result int
+	}
+	var res Result
+	db := repo.db.
+		Raw(`SELECT coalesce(max(threshold), 0) result FROM storage_warning WHERE organization_id = ? AND user_id = ?`,
+			orgID, userID).
+		Scan(&res)
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return res.Result, nil
+}
+
+func (repo *StorageRepo) SaveWarningThreshold(orgID string, userID string, threshold int) error {
+	db := repo.db.
+		Exec(`INSERT INTO storage_warning (organization_id, user_id, threshold, update_time) VALUES (?, ?, ?, ?)
+              ON CONFLICT (organization_id, user_id) DO UPDATE SET threshold = EXCLUDED.threshold,
+              update_time = EXCLUDED.update_time`,
+			orgID, userID, threshold, helper.NewTimestamp())
+	if db.Error != nil {
+		return db.Error
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ package repo
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
@@ -294,6 +295,52 @@ func (repo *TaskRepo) CountByEmail(userID string) (int64, error) {
 	return count, nil
 }
 
+// organizationTasks selects the tasks processing the snapshots of the files of an organization.
+const organizationTasks = `SELECT s.task_id FROM snapshot s
+                           INNER JOIN snapshot_file sf ON sf.snapshot_id = s.id
+                           INNER JOIN file f ON f.id = sf.file_id
+                           INNER JOIN workspace w ON w.id = f.workspace_id AND w.organization_id = ?
+                           WHERE s.task_id IS NOT NULL`
+
+func (repo *TaskRepo) CountPendingInOrganization(orgID string, userID string) (int64, error) {
+	type Result struct {
+		Result int64
+	}
+	var res Result
+	db := repo.db.
+		Raw(fmt.Sprintf(`SELECT count(t.id) result FROM task t WHERE t.user_id = ?
+             AND t.status IN ('waiting', 'running') AND t.id IN (%s)`, organizationTasks),
+			userID, orgID).
+		Scan(&res)
+	if db.Error != nil {
+		return -1, db.Error
+	}
+	return res.Result, nil
+}
+
+// TransferPendingInOrganization hands the pending tasks of the organization over to
+// another user, and returns their IDs.
+func (repo *TaskRepo) TransferPendingInOrganization(orgID string, userID string, targetID string) ([]string, error) {
+	type Value struct {
+		Result string
+	}
+	var values []Value
+	db := repo.db.
+		Raw(fmt.Sprintf(`UPDATE task t SET user_id = ?, update_time = ? WHERE t.user_id = ?
+             AND t.status IN ('waiting', 'running') AND t.id IN (%s)
+             RETURNING t.id result`, organizationTasks),
+			targetID, helper.NewTimestamp(), userID, orgID).
+		Scan(&values)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	res := make([]string, 0)
+	for _, v := range values {
+		res = append(res, v.Result)
+	}
+	return res, nil
+}
+
 func (repo *TaskRepo) Save(task model.Task) error {
 	db := repo.db.Save(task)
 	if db.Error != nil {
@@ -11,13 +11,16 @@
 package repo
 
 import (
+	"encoding/json"
 	"errors"
 
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 
 	"github.com/kouprlabs/voltaserve/api/errorpkg"
 	"github.com/kouprlabs/voltaserve/api/helper"
 	"github.com/kouprlabs/voltaserve/api/infra"
+	"github.com/kouprlabs/voltaserve/api/log"
 	"github.com/kouprlabs/voltaserve/api/model"
 )
 
@@ -30,6 +33,9 @@ type workspaceEntity struct {
 	UserPermissions  []*UserPermissionValue  `gorm:"-"                              json:"userPermissions"`
 	GroupPermissions []*GroupPermissionValue `gorm:"-"                              json:"groupPermissions"`
 	Bucket           string                  `gorm:"column:bucket;size:255"         json:"bucket"`
+	ProcessingPolicy datatypes.JSON          `gorm:"column:processing_policy"       json:"processingPolicy,omitempty"`
+	ArchiveStatus    *string                 `gorm:"column:archive_status"          json:"archiveStatus,omitempty"`
+	ArchiveTime      *string                 `gorm:"column:archive_time"            json:"archiveTime,omitempty"`
 	CreateTime       string                  `gorm:"column:create_time"             json:"createTime"`
 	UpdateTime       *string                 `gorm:"column:update_time"             json:"updateTime,omitempty"`
 }
@@ -93,6 +99,26 @@ func (w *workspaceEntity) GetBucket() string {
 	return w.Bucket
 }
 
+func (w *workspaceEntity) GetProcessingPolicy() *model.ProcessingPolicy {
+	if w.ProcessingPolicy.String() == "" {
+		return nil
+	}
+	res := model.ProcessingPolicy{}
+	if err := json.Unmarshal([]byte(w.ProcessingPolicy.String()), &res); err != nil {
+		log.GetLogger().Fatal(err)
+		return nil
+	}
+	return &res
+}
+
+func (w *workspaceEntity) GetArchiveStatus() *string {
+	return w.ArchiveStatus
+}
+
+func (w *workspaceEntity) GetArchiveTime() *string {
+	return w.ArchiveTime
+}
+
 func (w *workspaceEntity) GetCreateTime() string {
 	return w.CreateTime
 }
@@ -139,6 +165,29 @@ func (w *workspaceEntity) SetBucket(bucket string) {
 	w.Bucket = bucket
 }
 
+func (w *workspaceEntity) SetProcessingPolicy(policy *model.ProcessingPolicy) {
+	if policy == nil {
+		w.ProcessingPolicy = nil
+	} else {
+		b, err := json.Marshal(policy)
+		if err != nil {
+			log.GetLogger().Fatal(err)
+			return
+		}
+		if err := w.ProcessingPolicy.UnmarshalJSON(b); err != nil {
+			log.GetLogger().Fatal(err)
+		}
+	}
+}
+
+func (w *workspaceEntity) SetArchiveStatus(archiveStatus *string) {
+	w.ArchiveStatus = archiveStatus
+}
+
+func (w *workspaceEntity) SetArchiveTime(archiveTime *string) {
+	w.ArchiveTime = archiveTime
+}
+
 func (w *workspaceEntity) SetCreateTime(createTime string) {
 	w.CreateTime = createTime
 }
@@ -297,6 +346,39 @@ func (repo *WorkspaceRepo) UpdateStorageCapacity(id string, storageCapacity int6
 	return res, nil
 }
 
+func (repo *WorkspaceRepo) UpdateProcessingPolicy(id string, policy *model.ProcessingPolicy) (model.Workspace, error) {
+	workspace, err := repo.find(id)
+	if err != nil {
+		return &workspaceEntity{}, err
+	}
+	workspace.SetProcessingPolicy(policy)
+	if db := repo.db.Save(&workspace); db.Error != nil {
+		return nil, db.Error
+	}
+	res, err := repo.Find(id)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (repo *WorkspaceRepo) UpdateArchiveStatus(id string, archiveStatus *string, archiveTime *string) (model.Workspace, error) {
+	workspace, err := repo.find(id)
+	if err != nil {
+		return &workspaceEntity{}, err
+	}
+	workspace.ArchiveStatus = archiveStatus
+	workspace.ArchiveTime = archiveTime
+	if db := repo.db.Save(&workspace); db.Error != nil {
+		return nil, db.Error
+	}
+	res, err := repo.Find(id)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func (repo *WorkspaceRepo) UpdateRootID(id string, rootNodeID string) error {
 	db := repo.db.Exec("UPDATE workspace SET root_id = ? WHERE id = ?", rootNodeID, id)
 	if db.Error != nil {
@@ -384,8 +466,11 @@ func (repo *WorkspaceRepo) populateModelFields(workspaces []*workspaceEntity) er
 		}
 		for _, p := range userPermissions {
 			w.UserPermissions = append(w.UserPermissions, &UserPermissionValue{
-				UserID: p.GetUserID(),
-				Value:  p.GetPermission(),
+				UserID:    p.GetUserID(),
+				Value:     p.GetPermission(),
+				StartsAt:  p.GetStartsAt(),
+				ExpiresAt: p.GetExpiresAt(),
+				RoleID:    p.GetRoleID(),
 			})
 		}
 		w.GroupPermissions = make([]*GroupPermissionValue, 0)
@@ -395,8 +480,11 @@ func (repo *WorkspaceRepo) populateModelFields(workspaces []*workspaceEntity) er
 		}
 		for _, p := range groupPermissions {
 			w.GroupPermissions = append(w.GroupPermissions, &GroupPermissionValue{
-				GroupID: p.GetGroupID(),
-				Value:   p.GetPermission(),
+				GroupID:   p.GetGroupID(),
+				Value:     p.GetPermission(),
+				StartsAt:  p.GetStartsAt(),
+				ExpiresAt: p.GetExpiresAt(),
+				RoleID:    p.GetRoleID(),
 			})
 		}
 	}
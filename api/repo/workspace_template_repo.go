@@ -0,0 +1,207 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+
+	"github.com/kouprlabs/voltaserve/api/errorpkg"
+	"github.com/kouprlabs/voltaserve/api/helper"
+	"github.com/kouprlabs/voltaserve/api/infra"
+	"github.com/kouprlabs/voltaserve/api/log"
+	"github.com/kouprlabs/voltaserve/api/model"
+)
+
+type workspaceTemplateEntity struct {
+	ID             string         `gorm:"column:id"              json:"id"`
+	OrganizationID string         `gorm:"column:organization_id" json:"organizationId"`
+	Name           string         `gorm:"column:name"            json:"name"`
+	Bucket         *string        `gorm:"column:bucket"          json:"bucket,omitempty"`
+	Nodes          datatypes.JSON `gorm:"column:nodes"           json:"nodes"`
+	Grants         datatypes.JSON `gorm:"column:grants"          json:"grants"`
+	CreateTime     string         `gorm:"column:create_time"     json:"createTime"`
+	UpdateTime     *string        `gorm:"column:update_time"     json:"updateTime"`
+}
+
+func (*workspaceTemplateEntity) TableName() string {
+	return "workspace_template"
+}
+
+func (t *workspaceTemplateEntity) BeforeCreate(*gorm.DB) (err error) {
+	t.CreateTime = helper.NewTimestamp()
+	return nil
+}
+
+func (t *workspaceTemplateEntity) BeforeSave(*gorm.DB) (err error) {
+	timeNow := helper.NewTimestamp()
+	t.UpdateTime = &timeNow
+	return nil
+}
+
+func (t *workspaceTemplateEntity) GetID() string {
+	return t.ID
+}
+
+func (t *workspaceTemplateEntity) GetOrganizationID() string {
+	return t.OrganizationID
+}
+
+func (t *workspaceTemplateEntity) GetName() string {
+	return t.Name
+}
+
+func (t *workspaceTemplateEntity) GetBucket() *string {
+	return t.Bucket
+}
+
+func (t *workspaceTemplateEntity) GetNodes() []model.WorkspaceTemplateNode {
+	res := make([]model.WorkspaceTemplateNode, 0)
+	if t.Nodes.String() == "" {
+		return res
+	}
+	if err := json.Unmarshal([]byte(t.Nodes.String()), &res); err != nil {
+		log.GetLogger().Error(err)
+		return []model.WorkspaceTemplateNode{}
+	}
+	return res
+}
+
+func (t *workspaceTemplateEntity) GetGrants() []model.WorkspaceTemplateGrant {
+	res := make([]model.WorkspaceTemplateGrant, 0)
+	if t.Grants.String() == "" {
+		return res
+	}
+	if err := json.Unmarshal([]byte(t.Grants.String()), &res); err != nil {
+		log.GetLogger().Error(err)
+		return []model.WorkspaceTemplateGrant{}
+	}
+	return res
+}
+
+func (t *workspaceTemplateEntity) GetCreateTime() string {
+	return t.CreateTime
+}
+
+func (t *workspaceTemplateEntity) GetUpdateTime() *string {
+	return t.UpdateTime
+}
+
+func (t *workspaceTemplateEntity) SetName(name string) {
+	t.Name = name
+}
+
+func (t *workspaceTemplateEntity) SetUpdateTime(updateTime *string) {
+	t.UpdateTime = updateTime
+}
+
+func NewWorkspaceTemplate() model.WorkspaceTemplate {
+	return &workspaceTemplateEntity{}
+}
+
+type WorkspaceTemplateRepo struct {
+	db *gorm.DB
+}
+
+func NewWorkspaceTemplateRepo() *WorkspaceTemplateRepo {
+	return &WorkspaceTemplateRepo{
+		db: infra.NewPostgresManager().GetDBOrPanic(),
+	}
+}
+
+type WorkspaceTemplateInsertOptions struct {
+	ID             string
+	OrganizationID string
+	Name           string
+	Bucket         *string
+	Nodes          []model.WorkspaceTemplateNode
+	Grants         []model.WorkspaceTemplateGrant
+}
+
+func (repo *WorkspaceTemplateRepo) Insert(opts WorkspaceTemplateInsertOptions) (model.WorkspaceTemplate, error) {
+	nodes, err := json.Marshal(opts.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	grants, err := json.Marshal(opts.Grants)
+	if err != nil {
+		return nil, err
+	}
+	template := workspaceTemplateEntity{
+		ID:             opts.ID,
+		OrganizationID: opts.OrganizationID,
+		Name:           opts.Name,
+		Bucket:         opts.Bucket,
+		Nodes:          nodes,
+		Grants:         grants,
+	}
+	if db := repo.db.Create(&template); db.Error != nil {
+		return nil, db.Error
+	}
+	res, err := repo.Find(opts.ID)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (repo *WorkspaceTemplateRepo) find(id string) (*workspaceTemplateEntity, error) {
+	res := workspaceTemplateEntity{}
+	db := repo.db.Where("id = ?", id).First(&res)
+	if db.Error != nil {
+		if errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			return nil, errorpkg.NewWorkspaceTemplateNotFoundError(db.Error)
+		} else {
+			return nil, errorpkg.NewInternalServerError(db.Error)
+		}
+	}
+	return &res, nil
+}
+
+func (repo *WorkspaceTemplateRepo) Find(id string) (model.WorkspaceTemplate, error) {
+	res, err := repo.find(id)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (repo *WorkspaceTemplateRepo) FindByOrganization(id string) ([]model.WorkspaceTemplate, error) {
+	var entities []*workspaceTemplateEntity
+	db := repo.db.Raw(`SELECT * FROM workspace_template WHERE organization_id = ? ORDER BY name`, id).Scan(&entities)
+	if db.Error != nil {
+		return nil, db.Error
+	}
+	res := make([]model.WorkspaceTemplate, 0)
+	for _, t := range entities {
+		res = append(res, t)
+	}
+	return res, nil
+}
+
+func (repo *WorkspaceTemplateRepo) Save(template model.WorkspaceTemplate) error {
+	db := repo.db.Save(template)
+	if db.Error != nil {
+		return db.Error
+	}
+	return nil
+}
+
+func (repo *WorkspaceTemplateRepo) Delete(id string) error {
+	db := repo.db.Exec("DELETE FROM workspace_template WHERE id = ?", id)
+	if db.Error != nil {
+		return db.Error
+	}
+	return nil
+}
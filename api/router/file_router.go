@@ -60,7 +60,9 @@ func NewFileRouter() *FileRouter {
 }
 
 const (
-	FileDefaultPageSize = 100
+	FileDefaultPageSize   = 100
+	FileSheetDefaultLimit = 100
+	FileSheetMaxLimit     = 1000
 )
 
 func (r *FileRouter) AppendRoutes(g fiber.Router) {
@@ -82,20 +84,34 @@ func (r *FileRouter) AppendRoutes(g fiber.Router) {
 	g.Patch("/:id/name", r.PatchName)
 	g.Post("/:id/reprocess", r.Reprocess)
 	g.Get("/:id/size", r.ComputeSize)
+	g.Get("/:id/search", r.SearchText)
+	g.Get("/:id/metadata", r.ReadMetadata)
+	g.Get("/:id/sheets/:sheet", r.ReadSheet)
 	g.Post("/grant_user_permission", r.GrantUserPermission)
 	g.Post("/revoke_user_permission", r.RevokeUserPermission)
 	g.Post("/grant_group_permission", r.GrantGroupPermission)
 	g.Post("/revoke_group_permission", r.RevokeGroupPermission)
+	g.Post("/deny_user_permission", r.DenyUserPermission)
+	g.Post("/deny_group_permission", r.DenyGroupPermission)
+	g.Post("/break_permission_inheritance", r.BreakPermissionInheritance)
+	g.Post("/restore_permission_inheritance", r.RestorePermissionInheritance)
 	g.Get("/:id/user_permissions", r.FindUserPermissions)
 	g.Get("/:id/group_permissions", r.FindGroupPermissions)
+	g.Get("/:id/access", r.FindAccess)
 }
 
 func (r *FileRouter) AppendNonJWTRoutes(g fiber.Router) {
 	g.Get("/:id/original.:extension", r.DownloadOriginal)
 	g.Get("/:id/preview.:extension", r.DownloadPreview)
 	g.Get("/:id/thumbnail.:extension", r.DownloadThumbnail)
+	g.Get("/:id/pages/:page.:extension", r.DownloadPage)
+	g.Get("/:id/page_thumbnails/:page.:extension", r.DownloadPageThumbnail)
+	g.Get("/:id/slides/:slide.:extension", r.DownloadSlide)
+	g.Get("/:id/waveform.json", r.DownloadWaveform)
+	g.Get("/:id/transcript.vtt", r.DownloadTranscript)
 	g.Post("/create_from_s3", r.CreateFromS3)
 	g.Patch("/:id/patch_from_s3", r.PatchFromS3)
+	g.Post("/create_from_attachments", r.CreateFromAttachments)
 }
 
 // Create godoc
@@ -139,13 +155,9 @@ func (r *FileRouter) Create(c *fiber.Ctx) error {
 		if err != nil {
 			return err
 		}
-		ok, err := r.workspaceSvc.HasEnoughSpaceForByteSize(workspaceID, fh.Size)
-		if err != nil {
+		if err := r.workspaceSvc.CheckStorageQuota(workspaceID, userID, fh.Size); err != nil {
 			return err
 		}
-		if !*ok {
-			return errorpkg.NewStorageLimitExceededError()
-		}
 		if name == "" {
 			name = fh.Filename
 		}
@@ -217,13 +229,9 @@ func (r *FileRouter) Patch(c *fiber.Ctx) error {
 	if err != nil {
 		return err
 	}
-	ok, err := r.workspaceSvc.HasEnoughSpaceForByteSize(file.WorkspaceID, fh.Size)
-	if err != nil {
+	if err := r.workspaceSvc.CheckStorageQuota(file.WorkspaceID, userID, fh.Size); err != nil {
 		return err
 	}
-	if !*ok {
-		return errorpkg.NewStorageLimitExceededError()
-	}
 	tmpPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + filepath.Ext(fh.Filename))
 	if err := c.SaveFile(fh, tmpPath); err != nil {
 		return err
@@ -712,10 +720,100 @@ func (r *FileRouter) Count(c *fiber.Ctx) error {
 	return c.JSON(res)
 }
 
+// SearchText godoc
+//
+//	@Summary		Search Text
+//	@Description	Search Text
+//	@Tags			Files
+//	@Id				files_search_text
+//	@Produce		json
+//	@Param			id	path		string	true	"ID"
+//	@Param			q	query		string	true	"Query"
+//	@Success		200	{object}	service.FileTextSearchResult
+//	@Failure		404	{object}	errorpkg.ErrorResponse
+//	@Failure		500	{object}	errorpkg.ErrorResponse
+//	@Router			/files/{id}/search [get]
+func (r *FileRouter) SearchText(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	query := c.Query("q")
+	if query == "" {
+		return errorpkg.NewMissingQueryParamError("q")
+	}
+	res, err := r.fileSvc.SearchText(c.Params("id"), query, userID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+// ReadMetadata godoc
+//
+//	@Summary		Read Metadata
+//	@Description	Read Metadata
+//	@Tags			Files
+//	@Id				files_read_metadata
+//	@Produce		json
+//	@Param			id	path		string	true	"ID"
+//	@Success		200	{object}	model.MediaMetadata
+//	@Failure		404	{object}	errorpkg.ErrorResponse
+//	@Failure		500	{object}	errorpkg.ErrorResponse
+//	@Router			/files/{id}/metadata [get]
+func (r *FileRouter) ReadMetadata(c *fiber.Ctx) error {
+	res, err := r.fileSvc.ReadMetadata(c.Params("id"), helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+// ReadSheet godoc
+//
+//	@Summary		Read Sheet
+//	@Description	Read Sheet
+//	@Tags			Files
+//	@Id				files_read_sheet
+//	@Produce		json
+//	@Param			id		path		string	true	"ID"
+//	@Param			sheet	path		string	true	"Sheet"
+//	@Param			offset	query		string	false	"Offset"
+//	@Param			limit	query		string	false	"Limit"
+//	@Success		200		{object}	service.FileSheet
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/files/{id}/sheets/{sheet} [get]
+func (r *FileRouter) ReadSheet(c *fiber.Ctx) error {
+	sheet, err := strconv.Atoi(c.Params("sheet"))
+	if err != nil {
+		return errorpkg.NewInvalidQueryParamError("sheet")
+	}
+	opts := service.FileReadSheetOptions{Limit: FileSheetDefaultLimit}
+	if c.Query("offset") != "" {
+		opts.Offset, err = strconv.Atoi(c.Query("offset"))
+		if err != nil || opts.Offset < 0 {
+			return errorpkg.NewInvalidQueryParamError("offset")
+		}
+	}
+	if c.Query("limit") != "" {
+		opts.Limit, err = strconv.Atoi(c.Query("limit"))
+		if err != nil || opts.Limit < 1 || opts.Limit > FileSheetMaxLimit {
+			return errorpkg.NewInvalidQueryParamError("limit")
+		}
+	}
+	res, err := r.fileSvc.ReadSheet(c.Params("id"), sheet, opts, helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
 type FileGrantUserPermissionOptions struct {
-	UserID     string   `json:"userId"     validate:"required"`
-	IDs        []string `json:"ids"        validate:"required"`
-	Permission string   `json:"permission" validate:"required,oneof=viewer editor owner"`
+	UserID     string   `json:"userId"              validate:"required"`
+	IDs        []string `json:"ids"                 validate:"required"`
+	Permission string   `json:"permission"          validate:"required_without=RoleID,omitempty,oneof=viewer editor owner"`
+	RoleID     *string  `json:"roleId,omitempty"`
+	StartsAt   *string  `json:"startsAt,omitempty"`
+	ExpiresAt  *string  `json:"expiresAt,omitempty"`
 }
 
 // GrantUserPermission godoc
@@ -739,7 +837,14 @@ func (r *FileRouter) GrantUserPermission(c *fiber.Ctx) error {
 	if err := validator.New().Struct(opts); err != nil {
 		return errorpkg.NewRequestBodyValidationError(err)
 	}
-	if err := r.fileSvc.GrantUserPermission(opts.IDs, opts.UserID, opts.Permission, userID); err != nil {
+	if err := r.fileSvc.GrantUserPermissionWithOptions(opts.IDs, opts.UserID, service.PermissionGrantOptions{
+		Permission: opts.Permission,
+		RoleID:     opts.RoleID,
+		Schedule: service.PermissionSchedule{
+			StartsAt:  opts.StartsAt,
+			ExpiresAt: opts.ExpiresAt,
+		},
+	}, userID); err != nil {
 		return err
 	}
 	return c.SendStatus(http.StatusNoContent)
@@ -778,9 +883,12 @@ func (r *FileRouter) RevokeUserPermission(c *fiber.Ctx) error {
 }
 
 type FileGrantGroupPermissionOptions struct {
-	GroupID    string   `json:"groupId"    validate:"required"`
-	IDs        []string `json:"ids"        validate:"required"`
-	Permission string   `json:"permission" validate:"required,oneof=viewer editor owner"`
+	GroupID    string   `json:"groupId"             validate:"required"`
+	IDs        []string `json:"ids"                 validate:"required"`
+	Permission string   `json:"permission"          validate:"required_without=RoleID,omitempty,oneof=viewer editor owner"`
+	RoleID     *string  `json:"roleId,omitempty"`
+	StartsAt   *string  `json:"startsAt,omitempty"`
+	ExpiresAt  *string  `json:"expiresAt,omitempty"`
 }
 
 // GrantGroupPermission godoc
@@ -804,7 +912,14 @@ func (r *FileRouter) GrantGroupPermission(c *fiber.Ctx) error {
 	if err := validator.New().Struct(opts); err != nil {
 		return errorpkg.NewRequestBodyValidationError(err)
 	}
-	if err := r.fileSvc.GrantGroupPermission(opts.IDs, opts.GroupID, opts.Permission, userID); err != nil {
+	if err := r.fileSvc.GrantGroupPermissionWithOptions(opts.IDs, opts.GroupID, service.PermissionGrantOptions{
+		Permission: opts.Permission,
+		RoleID:     opts.RoleID,
+		Schedule: service.PermissionSchedule{
+			StartsAt:  opts.StartsAt,
+			ExpiresAt: opts.ExpiresAt,
+		},
+	}, userID); err != nil {
 		return err
 	}
 	return c.SendStatus(http.StatusNoContent)
@@ -842,6 +957,135 @@ func (r *FileRouter) RevokeGroupPermission(c *fiber.Ctx) error {
 	return c.SendStatus(http.StatusNoContent)
 }
 
+type FileDenyUserPermissionOptions struct {
+	UserID string   `json:"userId" validate:"required"`
+	IDs    []string `json:"ids"    validate:"required"`
+	// Permission is the lowest denied permission, denying 'viewer' removes all access.
+	Permission string `json:"permission" validate:"required,oneof=viewer editor owner"`
+}
+
+// DenyUserPermission godoc
+//
+//	@Summary		Deny User Permission
+//	@Description	Deny User Permission
+//	@Tags			Files
+//	@Id				files_deny_user_permission
+//	@Produce		json
+//	@Param			body	body		FileDenyUserPermissionOptions	true	"Body"
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/files/deny_user_permission [post]
+func (r *FileRouter) DenyUserPermission(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	opts := new(FileDenyUserPermissionOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	if err := r.fileSvc.DenyUserPermission(opts.IDs, opts.UserID, opts.Permission, userID); err != nil {
+		return err
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+type FileDenyGroupPermissionOptions struct {
+	GroupID string   `json:"groupId" validate:"required"`
+	IDs     []string `json:"ids"     validate:"required"`
+	// Permission is the lowest denied permission, denying 'viewer' removes all access.
+	Permission string `json:"permission" validate:"required,oneof=viewer editor owner"`
+}
+
+// DenyGroupPermission godoc
+//
+//	@Summary		Deny Group Permission
+//	@Description	Deny Group Permission
+//	@Tags			Files
+//	@Id				files_deny_group_permission
+//	@Produce		json
+//	@Param			body	body		FileDenyGroupPermissionOptions	true	"Body"
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/files/deny_group_permission [post]
+func (r *FileRouter) DenyGroupPermission(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	opts := new(FileDenyGroupPermissionOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	if err := r.fileSvc.DenyGroupPermission(opts.IDs, opts.GroupID, opts.Permission, userID); err != nil {
+		return err
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+type FileBreakPermissionInheritanceOptions struct {
+	IDs []string `json:"ids" validate:"required"`
+	// CopyPermissions keeps the inherited permissions as permissions of the file.
+	CopyPermissions bool `json:"copyPermissions"`
+}
+
+// BreakPermissionInheritance godoc
+//
+//	@Summary		Break Permission Inheritance
+//	@Description	Break Permission Inheritance
+//	@Tags			Files
+//	@Id				files_break_permission_inheritance
+//	@Produce		json
+//	@Param			body	body		FileBreakPermissionInheritanceOptions	true	"Body"
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/files/break_permission_inheritance [post]
+func (r *FileRouter) BreakPermissionInheritance(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	opts := new(FileBreakPermissionInheritanceOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	if err := r.fileSvc.BreakPermissionInheritance(opts.IDs, opts.CopyPermissions, userID); err != nil {
+		return err
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+type FileRestorePermissionInheritanceOptions struct {
+	IDs []string `json:"ids" validate:"required"`
+}
+
+// RestorePermissionInheritance godoc
+//
+//	@Summary		Restore Permission Inheritance
+//	@Description	Restore Permission Inheritance
+//	@Tags			Files
+//	@Id				files_restore_permission_inheritance
+//	@Produce		json
+//	@Param			body	body		FileRestorePermissionInheritanceOptions	true	"Body"
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/files/restore_permission_inheritance [post]
+func (r *FileRouter) RestorePermissionInheritance(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	opts := new(FileRestorePermissionInheritanceOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	if err := r.fileSvc.RestorePermissionInheritance(opts.IDs, userID); err != nil {
+		return err
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
 // FindUserPermissions godoc
 //
 //	@Summary		Read User Permissions
@@ -863,6 +1107,32 @@ func (r *FileRouter) FindUserPermissions(c *fiber.Ctx) error {
 	return c.JSON(res)
 }
 
+// FindAccess godoc
+//
+//	@Summary		Read Access
+//	@Description	Read the effective permission of a user and the grants producing it
+//	@Tags			Files
+//	@Id				files_find_access
+//	@Produce		json
+//	@Param			id		path		string	true	"ID"
+//	@Param			user_id	query		string	true	"User ID"
+//	@Success		200		{object}	service.FileAccess
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/files/{id}/access [get]
+func (r *FileRouter) FindAccess(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	if c.Query("user_id") == "" {
+		return errorpkg.NewMissingQueryParamError("user_id")
+	}
+	res, err := r.fileSvc.FindAccess(c.Params("id"), c.Query("user_id"), userID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
 // FindGroupPermissions godoc
 //
 //	@Summary		Read Group Permissions
@@ -982,7 +1252,13 @@ func (r *FileRouter) DownloadPreview(c *fiber.Ctx) error {
 	if strings.TrimPrefix(filepath.Ext(res.Snapshot.GetPreview().Key), ".") != extension {
 		return errorpkg.NewS3ObjectNotFoundError(nil)
 	}
-	c.Set("Content-Type", infra.DetectMIMEFromBytes(buf.Bytes()))
+	if extension == "html" {
+		// Rendered text previews are served from our origin, so they must not run scripts
+		c.Set("Content-Type", "text/html; charset=utf-8")
+		c.Set("Content-Security-Policy", "sandbox; default-src 'none'; style-src 'unsafe-inline'; img-src data:")
+	} else {
+		c.Set("Content-Type", infra.DetectMIMEFromBytes(buf.Bytes()))
+	}
 	c.Set("Content-Disposition", fmt.Sprintf("filename=\"%s\"", filepath.Base(res.File.GetName())))
 	if res.RangeInterval != nil {
 		res.RangeInterval.ApplyToFiberContext(c)
@@ -1042,6 +1318,212 @@ func (r *FileRouter) DownloadThumbnail(c *fiber.Ctx) error {
 	return c.Send(buf.Bytes())
 }
 
+// DownloadPage godoc
+//
+//	@Summary		Download Page
+//	@Description	Download Page
+//	@Tags			Files
+//	@Id				files_download_page
+//	@Produce		json
+//	@Param			id				path		string	true	"ID"
+//	@Param			page			path		string	true	"Page"
+//	@Param			ext				path		string	true	"Extension"
+//	@Param			resolution		query		string	false	"Resolution"	Enums(standard, high)
+//	@Param			access_token	query		string	true	"Access Token"
+//	@Failure		404				{object}	errorpkg.ErrorResponse
+//	@Failure		500				{object}	errorpkg.ErrorResponse
+//	@Failure		503				{object}	errorpkg.ErrorResponse
+//	@Router			/files/{id}/pages/{page}.{ext} [get]
+func (r *FileRouter) DownloadPage(c *fiber.Ctx) error {
+	userID, page, err := r.readPageParams(c)
+	if err != nil {
+		return err
+	}
+	resolution := c.Query("resolution", model.PageResolutionStandard)
+	buf := r.bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer r.bufferPool.Put(buf)
+	if err := r.fileSvc.DownloadPageBuffer(c.Params("id"), page, resolution, buf, userID); err != nil {
+		return err
+	}
+	c.Set("Content-Type", infra.DetectMIMEFromBytes(buf.Bytes()))
+	c.Set("Content-Disposition", fmt.Sprintf("filename=\"%d%s\"", page, model.PageExtension))
+	return c.Send(buf.Bytes())
+}
+
+// DownloadPageThumbnail godoc
+//
+//	@Summary		Download Page Thumbnail
+//	@Description	Download Page Thumbnail
+//	@Tags			Files
+//	@Id				files_download_page_thumbnail
+//	@Produce		json
+//	@Param			id				path		string	true	"ID"
+//	@Param			page			path		string	true	"Page"
+//	@Param			ext				path		string	true	"Extension"
+//	@Param			access_token	query		string	true	"Access Token"
+//	@Failure		404				{object}	errorpkg.ErrorResponse
+//	@Failure		500				{object}	errorpkg.ErrorResponse
+//	@Failure		503				{object}	errorpkg.ErrorResponse
+//	@Router			/files/{id}/page_thumbnails/{page}.{ext} [get]
+func (r *FileRouter) DownloadPageThumbnail(c *fiber.Ctx) error {
+	userID, page, err := r.readPageParams(c)
+	if err != nil {
+		return err
+	}
+	buf := r.bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer r.bufferPool.Put(buf)
+	if err := r.fileSvc.DownloadPageThumbnailBuffer(c.Params("id"), page, buf, userID); err != nil {
+		return err
+	}
+	c.Set("Content-Type", infra.DetectMIMEFromBytes(buf.Bytes()))
+	c.Set("Content-Disposition", fmt.Sprintf("filename=\"%d%s\"", page, model.PageExtension))
+	return c.Send(buf.Bytes())
+}
+
+// DownloadSlide godoc
+//
+//	@Summary		Download Slide
+//	@Description	Download Slide
+//	@Tags			Files
+//	@Id				files_download_slide
+//	@Produce		json
+//	@Param			id				path		string	true	"ID"
+//	@Param			slide			path		string	true	"Slide"
+//	@Param			ext				path		string	true	"Extension"
+//	@Param			access_token	query		string	true	"Access Token"
+//	@Failure		404				{object}	errorpkg.ErrorResponse
+//	@Failure		500				{object}	errorpkg.ErrorResponse
+//	@Router			/files/{id}/slides/{slide}.{ext} [get]
+func (r *FileRouter) DownloadSlide(c *fiber.Ctx) error {
+	accessToken := c.Cookies(r.accessTokenCookieName)
+	if accessToken == "" {
+		accessToken = c.Query("access_token")
+		if accessToken == "" {
+			return errorpkg.NewFileNotFoundError(nil)
+		}
+	}
+	userID, err := r.getUserIDFromAccessToken(accessToken)
+	if err != nil {
+		return errorpkg.NewFileNotFoundError(nil)
+	}
+	if c.Params("id") == "" {
+		return errorpkg.NewMissingQueryParamError("id")
+	}
+	slide, err := strconv.Atoi(c.Params("slide"))
+	if err != nil {
+		return errorpkg.NewInvalidQueryParamError("slide")
+	}
+	extension := "." + c.Params("extension")
+	buf := r.bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer r.bufferPool.Put(buf)
+	if err := r.fileSvc.DownloadSlideBuffer(c.Params("id"), slide, extension, buf, userID); err != nil {
+		return err
+	}
+	c.Set("Content-Type", infra.DetectMIMEFromBytes(buf.Bytes()))
+	c.Set("Content-Disposition", fmt.Sprintf("filename=\"%d%s\"", slide, extension))
+	return c.Send(buf.Bytes())
+}
+
+// DownloadWaveform godoc
+//
+//	@Summary		Download Waveform
+//	@Description	Download Waveform
+//	@Tags			Files
+//	@Id				files_download_waveform
+//	@Produce		json
+//	@Param			id				path		string	true	"ID"
+//	@Param			access_token	query		string	true	"Access Token"
+//	@Failure		404				{object}	errorpkg.ErrorResponse
+//	@Failure		500				{object}	errorpkg.ErrorResponse
+//	@Router			/files/{id}/waveform.json [get]
+func (r *FileRouter) DownloadWaveform(c *fiber.Ctx) error {
+	userID, err := r.readAudioParams(c)
+	if err != nil {
+		return err
+	}
+	buf := r.bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer r.bufferPool.Put(buf)
+	if err := r.fileSvc.DownloadWaveformBuffer(c.Params("id"), buf, userID); err != nil {
+		return err
+	}
+	c.Set("Content-Type", "application/json")
+	return c.Send(buf.Bytes())
+}
+
+// DownloadTranscript godoc
+//
+//	@Summary		Download Transcript
+//	@Description	Download Transcript
+//	@Tags			Files
+//	@Id				files_download_transcript
+//	@Produce		plain
+//	@Param			id				path		string	true	"ID"
+//	@Param			access_token	query		string	true	"Access Token"
+//	@Failure		404				{object}	errorpkg.ErrorResponse
+//	@Failure		500				{object}	errorpkg.ErrorResponse
+//	@Router			/files/{id}/transcript.vtt [get]
+func (r *FileRouter) DownloadTranscript(c *fiber.Ctx) error {
+	userID, err := r.readAudioParams(c)
+	if err != nil {
+		return err
+	}
+	buf := r.bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer r.bufferPool.Put(buf)
+	if err := r.fileSvc.DownloadTranscriptBuffer(c.Params("id"), buf, userID); err != nil {
+		return err
+	}
+	c.Set("Content-Type", "text/vtt; charset=utf-8")
+	return c.Send(buf.Bytes())
+}
+
+func (r *FileRouter) readAudioParams(c *fiber.Ctx) (string, error) {
+	accessToken := c.Cookies(r.accessTokenCookieName)
+	if accessToken == "" {
+		accessToken = c.Query("access_token")
+		if accessToken == "" {
+			return "", errorpkg.NewFileNotFoundError(nil)
+		}
+	}
+	userID, err := r.getUserIDFromAccessToken(accessToken)
+	if err != nil {
+		return "", errorpkg.NewFileNotFoundError(nil)
+	}
+	if c.Params("id") == "" {
+		return "", errorpkg.NewMissingQueryParamError("id")
+	}
+	return userID, nil
+}
+
+func (r *FileRouter) readPageParams(c *fiber.Ctx) (string, int, error) {
+	accessToken := c.Cookies(r.accessTokenCookieName)
+	if accessToken == "" {
+		accessToken = c.Query("access_token")
+		if accessToken == "" {
+			return "", 0, errorpkg.NewFileNotFoundError(nil)
+		}
+	}
+	userID, err := r.getUserIDFromAccessToken(accessToken)
+	if err != nil {
+		return "", 0, errorpkg.NewFileNotFoundError(nil)
+	}
+	if c.Params("id") == "" {
+		return "", 0, errorpkg.NewMissingQueryParamError("id")
+	}
+	page, err := strconv.Atoi(c.Params("page"))
+	if err != nil {
+		return "", 0, errorpkg.NewInvalidQueryParamError("page")
+	}
+	if "."+c.Params("extension") != model.PageExtension {
+		return "", 0, errorpkg.NewS3ObjectNotFoundError(nil)
+	}
+	return userID, page, nil
+}
+
 // CreateFromS3 godoc
 //
 //	@Summary		Create from S3
@@ -1119,13 +1601,9 @@ func (r *FileRouter) CreateFromS3(c *fiber.Ctx) error {
 	if err != nil {
 		return err
 	}
-	ok, err := r.workspaceSvc.HasEnoughSpaceForByteSize(workspaceID, size)
-	if err != nil {
+	if err := r.workspaceSvc.CheckStorageQuota(workspaceID, userID, size); err != nil {
 		return err
 	}
-	if !*ok {
-		return errorpkg.NewStorageLimitExceededError()
-	}
 	file, err := r.fileSvc.Create(service.FileCreateOptions{
 		Name:        name,
 		Type:        model.FileTypeFile,
@@ -1214,13 +1692,9 @@ func (r *FileRouter) PatchFromS3(c *fiber.Ctx) error {
 	if contentType == "" {
 		return errorpkg.NewMissingQueryParamError("content_type")
 	}
-	ok, err := r.workspaceSvc.HasEnoughSpaceForByteSize(file.WorkspaceID, size)
-	if err != nil {
+	if err := r.workspaceSvc.CheckStorageQuota(file.WorkspaceID, userID, size); err != nil {
 		return err
 	}
-	if !*ok {
-		return errorpkg.NewStorageLimitExceededError()
-	}
 	file, err = r.fileSvc.Store(file.ID, service.FileStoreOptions{
 		S3Reference: &model.S3Reference{
 			Key:         s3Key,
@@ -1236,6 +1710,43 @@ func (r *FileRouter) PatchFromS3(c *fiber.Ctx) error {
 	return c.JSON(file)
 }
 
+// CreateFromAttachments godoc
+//
+//	@Summary		Create from Attachments
+//	@Description	Create from Attachments
+//	@Tags			Files
+//	@Id				files_create_from_attachments
+//	@Accept			json
+//	@Produce		json
+//	@Param			api_key	query		string										true	"API Key"
+//	@Param			body	body		service.FileCreateFromAttachmentsOptions	true	"Body"
+//	@Success		201		{array}		service.File
+//	@Failure		401		{object}	errorpkg.ErrorResponse
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/files/create_from_attachments [post]
+func (r *FileRouter) CreateFromAttachments(c *fiber.Ctx) error {
+	apiKey := c.Query("api_key")
+	if apiKey == "" {
+		return errorpkg.NewMissingQueryParamError("api_key")
+	}
+	if apiKey != r.config.Security.APIKey {
+		return errorpkg.NewInvalidAPIKeyError()
+	}
+	opts := new(service.FileCreateFromAttachmentsOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	res, err := r.fileSvc.CreateFromAttachments(*opts)
+	if err != nil {
+		return err
+	}
+	return c.Status(http.StatusCreated).JSON(res)
+}
+
 func (r *FileRouter) getUserIDFromAccessToken(accessToken string) (string, error) {
 	token, err := jwt.Parse(accessToken, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
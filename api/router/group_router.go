@@ -46,6 +46,9 @@ func (r *GroupRouter) AppendRoutes(g fiber.Router) {
 	g.Patch("/:id/name", r.PatchName)
 	g.Post("/:id/members", r.AddMember)
 	g.Delete("/:id/members", r.RemoveMember)
+	g.Get("/:id/subgroups", r.ListSubgroups)
+	g.Post("/:id/subgroups", r.AddSubgroup)
+	g.Delete("/:id/subgroups", r.RemoveSubgroup)
 }
 
 // Create godoc
@@ -253,7 +256,9 @@ func (r *GroupRouter) Delete(c *fiber.Ctx) error {
 }
 
 type GroupAddMemberOptions struct {
-	UserID string `json:"userId" validate:"required"`
+	UserID    string  `json:"userId"              validate:"required"`
+	StartsAt  *string `json:"startsAt,omitempty"`
+	ExpiresAt *string `json:"expiresAt,omitempty"`
 }
 
 // AddMember godoc
@@ -278,7 +283,10 @@ func (r *GroupRouter) AddMember(c *fiber.Ctx) error {
 	if err := validator.New().Struct(opts); err != nil {
 		return errorpkg.NewRequestBodyValidationError(err)
 	}
-	if err := r.groupSvc.AddMember(c.Params("id"), opts.UserID, userID); err != nil {
+	if err := r.groupSvc.AddScheduledMember(c.Params("id"), opts.UserID, service.PermissionSchedule{
+		StartsAt:  opts.StartsAt,
+		ExpiresAt: opts.ExpiresAt,
+	}, userID); err != nil {
 		return err
 	}
 	return c.SendStatus(http.StatusNoContent)
@@ -316,3 +324,89 @@ func (r *GroupRouter) RemoveMember(c *fiber.Ctx) error {
 	}
 	return c.SendStatus(http.StatusNoContent)
 }
+
+// ListSubgroups godoc
+//
+//	@Summary		List Subgroups
+//	@Description	List Subgroups
+//	@Tags			Groups
+//	@Id				groups_list_subgroups
+//	@Produce		json
+//	@Param			id	path		string	true	"ID"
+//	@Success		200	{array}		service.Group
+//	@Failure		404	{object}	errorpkg.ErrorResponse
+//	@Failure		500	{object}	errorpkg.ErrorResponse
+//	@Router			/groups/{id}/subgroups [get]
+func (r *GroupRouter) ListSubgroups(c *fiber.Ctx) error {
+	res, err := r.groupSvc.ListSubgroups(c.Params("id"), helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+type GroupAddSubgroupOptions struct {
+	GroupID string `json:"groupId" validate:"required"`
+}
+
+// AddSubgroup godoc
+//
+//	@Summary		Add Subgroup
+//	@Description	Add Subgroup
+//	@Tags			Groups
+//	@Id				groups_add_subgroup
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string					true	"ID"
+//	@Param			body	body		GroupAddSubgroupOptions	true	"Body"
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/groups/{id}/subgroups [post]
+func (r *GroupRouter) AddSubgroup(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	opts := new(GroupAddSubgroupOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	if err := r.groupSvc.AddSubgroup(c.Params("id"), opts.GroupID, userID); err != nil {
+		return err
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+type GroupRemoveSubgroupOptions struct {
+	GroupID string `json:"groupId" validate:"required"`
+}
+
+// RemoveSubgroup godoc
+//
+//	@Summary		Remove Subgroup
+//	@Description	Remove Subgroup
+//	@Tags			Groups
+//	@Id				groups_remove_subgroup
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string						true	"ID"
+//	@Param			body	body		GroupRemoveSubgroupOptions	true	"Body"
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/groups/{id}/subgroups [delete]
+func (r *GroupRouter) RemoveSubgroup(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	opts := new(GroupRemoveSubgroupOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	if err := r.groupSvc.RemoveSubgroup(c.Params("id"), opts.GroupID, userID); err != nil {
+		return err
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
@@ -53,6 +53,9 @@ func (r *InsightsRouter) AppendRoutes(g fiber.Router) {
 	g.Get("/:id/info", r.ReadInfo)
 	g.Get("/:id/entities", r.ListEntities)
 	g.Get("/:id/entities/probe", r.ProbeEntities)
+	g.Get("/:id/summary", r.ReadSummary)
+	g.Get("/:id/pii", r.ListPII)
+	g.Post("/:id/redact", r.Redact)
 }
 
 func (r *InsightsRouter) AppendNonJWTRoutes(g fiber.Router) {
@@ -152,6 +155,76 @@ func (r *InsightsRouter) Delete(c *fiber.Ctx) error {
 	return c.JSON(res)
 }
 
+// ReadSummary godoc
+//
+//	@Summary		Read Summary
+//	@Description	Read Summary
+//	@Tags			Insights
+//	@Id				insights_read_summary
+//	@Produce		json
+//	@Param			id	path		string	true	"ID"
+//	@Success		200	{object}	service.InsightsSummary
+//	@Failure		404	{object}	errorpkg.ErrorResponse
+//	@Failure		500	{object}	errorpkg.ErrorResponse
+//	@Router			/insights/{id}/summary [get]
+func (r *InsightsRouter) ReadSummary(c *fiber.Ctx) error {
+	res, err := r.insightsSvc.ReadSummary(c.Params("id"), helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+// ListPII godoc
+//
+//	@Summary		List PII
+//	@Description	List PII
+//	@Tags			Insights
+//	@Id				insights_list_pii
+//	@Produce		json
+//	@Param			id	path		string	true	"ID"
+//	@Success		200	{object}	service.InsightsPIIList
+//	@Failure		404	{object}	errorpkg.ErrorResponse
+//	@Failure		500	{object}	errorpkg.ErrorResponse
+//	@Router			/insights/{id}/pii [get]
+func (r *InsightsRouter) ListPII(c *fiber.Ctx) error {
+	res, err := r.insightsSvc.ListPII(c.Params("id"), helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+// Redact godoc
+//
+//	@Summary		Redact
+//	@Description	Redact
+//	@Tags			Insights
+//	@Id				insights_redact
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string							true	"ID"
+//	@Param			body	body		service.InsightsRedactOptions	true	"Body"
+//	@Success		200		{object}	service.File
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/insights/{id}/redact [post]
+func (r *InsightsRouter) Redact(c *fiber.Ctx) error {
+	opts := new(service.InsightsRedactOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	res, err := r.insightsSvc.Redact(c.Params("id"), *opts, helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
 // ListEntities godoc
 //
 //	@Summary		List Entities
@@ -13,12 +13,14 @@ package router
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 
 	"github.com/kouprlabs/voltaserve/api/errorpkg"
 	"github.com/kouprlabs/voltaserve/api/helper"
+	"github.com/kouprlabs/voltaserve/api/log"
 	"github.com/kouprlabs/voltaserve/api/service"
 )
 
@@ -43,6 +45,8 @@ func (r *InvitationRouter) AppendRoutes(g fiber.Router) {
 	g.Get("/incoming/count", r.CountIncoming)
 	g.Get("/outgoing", r.ListOutgoing)
 	g.Get("/outgoing/probe", r.ProbeOutgoing)
+	g.Post("/outgoing/revoke", r.RevokePending)
+	g.Post("/import", r.Import)
 	g.Post("/:id/accept", r.Accept)
 	g.Post("/:id/resend", r.Resend)
 	g.Post("/:id/decline", r.Decline)
@@ -80,6 +84,83 @@ func (r *InvitationRouter) Create(c *fiber.Ctx) error {
 	return c.JSON(res)
 }
 
+// Import godoc
+//
+//	@Summary		Import
+//	@Description	Import
+//	@Tags			Invitations
+//	@Id				invitations_import
+//	@Accept			mpfd
+//	@Produce		json
+//	@Param			organization_id	query		string	true	"Organization ID"
+//	@Param			role			query		string	false	"Role"
+//	@Param			group_ids		query		string	false	"Comma separated group IDs"
+//	@Param			expires_at		query		string	false	"Expires At"
+//	@Param			file			formData	file	true	"CSV file"
+//	@Success		200				{array}		service.InvitationImportResult
+//	@Failure		404				{object}	errorpkg.ErrorResponse
+//	@Failure		400				{object}	errorpkg.ErrorResponse
+//	@Failure		500				{object}	errorpkg.ErrorResponse
+//	@Router			/invitations/import [post]
+func (r *InvitationRouter) Import(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	orgID := c.Query("organization_id")
+	if orgID == "" {
+		return errorpkg.NewMissingQueryParamError("organization_id")
+	}
+	opts := service.InvitationOptions{Role: c.Query("role")}
+	if c.Query("group_ids") != "" {
+		opts.GroupIDs = strings.Split(c.Query("group_ids"), ",")
+	}
+	if c.Query("expires_at") != "" {
+		opts.ExpiresAt = helper.ToPtr(c.Query("expires_at"))
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewInvalidQueryParamError("role")
+	}
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return err
+	}
+	file, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.GetLogger().Error(err)
+		}
+	}()
+	res, err := r.invitationSvc.Import(orgID, file, opts, userID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+// RevokePending godoc
+//
+//	@Summary		Revoke Pending
+//	@Description	Revoke Pending
+//	@Tags			Invitations
+//	@Id				invitations_revoke_pending
+//	@Produce		json
+//	@Param			organization_id	query		string	true	"Organization ID"
+//	@Failure		404				{object}	errorpkg.ErrorResponse
+//	@Failure		400				{object}	errorpkg.ErrorResponse
+//	@Failure		500				{object}	errorpkg.ErrorResponse
+//	@Router			/invitations/outgoing/revoke [post]
+func (r *InvitationRouter) RevokePending(c *fiber.Ctx) error {
+	orgID := c.Query("organization_id")
+	if orgID == "" {
+		return errorpkg.NewMissingQueryParamError("organization_id")
+	}
+	if err := r.invitationSvc.RevokePending(orgID, helper.GetUserID(c)); err != nil {
+		return err
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
 // ListIncoming godoc
 //
 //	@Summary		List Incoming
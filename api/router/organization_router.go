@@ -44,8 +44,13 @@ func (r *OrganizationRouter) AppendRoutes(g fiber.Router) {
 	g.Get("/:id", r.Find)
 	g.Delete("/:id", r.Delete)
 	g.Patch("/:id/name", r.PatchName)
+	g.Patch("/:id/storage_quota", r.PatchStorageQuota)
 	g.Post("/:id/leave", r.Leave)
 	g.Delete("/:id/members", r.RemoveMember)
+	g.Patch("/:id/members/role", r.PatchMemberRole)
+	g.Get("/:id/members/ownership", r.FindOwnership)
+	g.Post("/:id/members/transfer", r.TransferOwnership)
+	g.Get("/:id/access", r.FindWorkspaceAccess)
 }
 
 // Create godoc
@@ -101,6 +106,28 @@ func (r *OrganizationRouter) Find(c *fiber.Ctx) error {
 	return c.JSON(res)
 }
 
+// FindWorkspaceAccess godoc
+//
+//	@Summary		Read Workspace Access
+//	@Description	Read who has access to the workspaces of the organization
+//	@Tags			Organizations
+//	@Id				organizations_find_workspace_access
+//	@Produce		json
+//	@Param			id				path		string	true	"ID"
+//	@Param			workspace_id	query		string	false	"Workspace ID"
+//	@Success		200				{array}		service.WorkspaceAccess
+//	@Failure		404				{object}	errorpkg.ErrorResponse
+//	@Failure		500				{object}	errorpkg.ErrorResponse
+//	@Router			/organizations/{id}/access [get]
+func (r *OrganizationRouter) FindWorkspaceAccess(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	res, err := r.orgSvc.FindWorkspaceAccess(c.Params("id"), c.Query("workspace_id"), userID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
 // Delete godoc
 //
 //	@Summary		Delete
@@ -157,6 +184,37 @@ func (r *OrganizationRouter) PatchName(c *fiber.Ctx) error {
 	return c.JSON(res)
 }
 
+// PatchStorageQuota godoc
+//
+//	@Summary		Patch Storage Quota
+//	@Description	Patch Storage Quota
+//	@Tags			Organizations
+//	@Id				organizations_patch_storage_quota
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string									true	"ID"
+//	@Param			body	body		service.OrganizationStorageQuotaOptions	true	"Body"
+//	@Success		200		{object}	service.Organization
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/organizations/{id}/storage_quota [patch]
+func (r *OrganizationRouter) PatchStorageQuota(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	opts := new(service.OrganizationStorageQuotaOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	res, err := r.orgSvc.PatchStorageQuota(c.Params("id"), *opts, userID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
 // List godoc
 //
 //	@Summary		List
@@ -274,6 +332,96 @@ func (r *OrganizationRouter) Leave(c *fiber.Ctx) error {
 	return c.SendStatus(http.StatusNoContent)
 }
 
+type OrganizationPatchMemberRoleOptions struct {
+	UserID string `json:"userId" validate:"required"`
+	Role   string `json:"role"   validate:"required,oneof=admin member guest"`
+}
+
+// PatchMemberRole godoc
+//
+//	@Summary		Patch Member Role
+//	@Description	Patch Member Role
+//	@Tags			Organizations
+//	@Id				organizations_patch_member_role
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string								true	"ID"
+//	@Param			body	body		OrganizationPatchMemberRoleOptions	true	"Body"
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/organizations/{id}/members/role [patch]
+func (r *OrganizationRouter) PatchMemberRole(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	opts := new(OrganizationPatchMemberRoleOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	if err := r.orgSvc.PatchMemberRole(c.Params("id"), opts.UserID, opts.Role, userID); err != nil {
+		return err
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// FindOwnership godoc
+//
+//	@Summary		Find Ownership
+//	@Description	Find Ownership
+//	@Tags			Organizations
+//	@Id				organizations_find_ownership
+//	@Produce		json
+//	@Param			id		path		string	true	"ID"
+//	@Param			user_id	query		string	true	"User ID"
+//	@Success		200		{object}	service.OrganizationOwnership
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/organizations/{id}/members/ownership [get]
+func (r *OrganizationRouter) FindOwnership(c *fiber.Ctx) error {
+	memberID := c.Query("user_id")
+	if memberID == "" {
+		return errorpkg.NewMissingQueryParamError("user_id")
+	}
+	res, err := r.orgSvc.FindOwnership(c.Params("id"), memberID, helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+// TransferOwnership godoc
+//
+//	@Summary		Transfer Ownership
+//	@Description	Transfer Ownership
+//	@Tags			Organizations
+//	@Id				organizations_transfer_ownership
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string										true	"ID"
+//	@Param			body	body		service.OrganizationTransferOwnershipOptions	true	"Body"
+//	@Success		200		{object}	service.Task
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/organizations/{id}/members/transfer [post]
+func (r *OrganizationRouter) TransferOwnership(c *fiber.Ctx) error {
+	opts := new(service.OrganizationTransferOwnershipOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	res, err := r.orgSvc.TransferOwnership(c.Params("id"), *opts, helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
 type OrganizationRemoveMemberOptions struct {
 	UserID string `json:"userId" validate:"required"`
 }
@@ -0,0 +1,205 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package router
+
+import (
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kouprlabs/voltaserve/api/errorpkg"
+	"github.com/kouprlabs/voltaserve/api/helper"
+	"github.com/kouprlabs/voltaserve/api/service"
+)
+
+type RoleRouter struct {
+	roleSvc *service.RoleService
+}
+
+func NewRoleRouter() *RoleRouter {
+	return &RoleRouter{
+		roleSvc: service.NewRoleService(),
+	}
+}
+
+func (r *RoleRouter) AppendRoutes(g fiber.Router) {
+	g.Get("/", r.List)
+	g.Post("/", r.Create)
+	g.Get("/:id", r.Find)
+	g.Delete("/:id", r.Delete)
+	g.Patch("/:id/name", r.PatchName)
+	g.Patch("/:id/capabilities", r.PatchCapabilities)
+}
+
+// Create godoc
+//
+//	@Summary		Create
+//	@Description	Create
+//	@Tags			Roles
+//	@Id				roles_create
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		service.RoleCreateOptions	true	"Body"
+//	@Success		201		{object}	service.Role
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/roles [post]
+func (r *RoleRouter) Create(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	opts := new(service.RoleCreateOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	res, err := r.roleSvc.Create(*opts, userID)
+	if err != nil {
+		return err
+	}
+	return c.Status(http.StatusCreated).JSON(res)
+}
+
+// Find godoc
+//
+//	@Summary		Read
+//	@Description	Read
+//	@Tags			Roles
+//	@Id				roles_find
+//	@Produce		json
+//	@Param			id	path		string	true	"ID"
+//	@Success		200	{object}	service.Role
+//	@Failure		404	{object}	errorpkg.ErrorResponse
+//	@Failure		500	{object}	errorpkg.ErrorResponse
+//	@Router			/roles/{id} [get]
+func (r *RoleRouter) Find(c *fiber.Ctx) error {
+	res, err := r.roleSvc.Find(c.Params("id"), helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+// List godoc
+//
+//	@Summary		List
+//	@Description	List
+//	@Tags			Roles
+//	@Id				roles_list
+//	@Produce		json
+//	@Param			organization_id	query		string	true	"Organization ID"
+//	@Success		200				{array}		service.Role
+//	@Failure		400				{object}	errorpkg.ErrorResponse
+//	@Failure		404				{object}	errorpkg.ErrorResponse
+//	@Failure		500				{object}	errorpkg.ErrorResponse
+//	@Router			/roles [get]
+func (r *RoleRouter) List(c *fiber.Ctx) error {
+	organizationID := c.Query("organization_id")
+	if organizationID == "" {
+		return errorpkg.NewMissingQueryParamError("organization_id")
+	}
+	res, err := r.roleSvc.List(organizationID, helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+type RolePatchNameOptions struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+// PatchName godoc
+//
+//	@Summary		Patch Name
+//	@Description	Patch Name
+//	@Tags			Roles
+//	@Id				roles_patch_name
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string					true	"ID"
+//	@Param			body	body		RolePatchNameOptions	true	"Body"
+//	@Success		200		{object}	service.Role
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/roles/{id}/name [patch]
+func (r *RoleRouter) PatchName(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	opts := new(RolePatchNameOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	res, err := r.roleSvc.PatchName(c.Params("id"), opts.Name, userID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+type RolePatchCapabilitiesOptions struct {
+	Capabilities []string `json:"capabilities" validate:"required"`
+}
+
+// PatchCapabilities godoc
+//
+//	@Summary		Patch Capabilities
+//	@Description	Patch Capabilities
+//	@Tags			Roles
+//	@Id				roles_patch_capabilities
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string							true	"ID"
+//	@Param			body	body		RolePatchCapabilitiesOptions	true	"Body"
+//	@Success		200		{object}	service.Role
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/roles/{id}/capabilities [patch]
+func (r *RoleRouter) PatchCapabilities(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	opts := new(RolePatchCapabilitiesOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	res, err := r.roleSvc.PatchCapabilities(c.Params("id"), opts.Capabilities, userID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+// Delete godoc
+//
+//	@Summary		Delete
+//	@Description	Delete
+//	@Tags			Roles
+//	@Id				roles_delete
+//	@Produce		json
+//	@Param			id	path	string	true	"ID"
+//	@Success		204
+//	@Failure		400	{object}	errorpkg.ErrorResponse
+//	@Failure		404	{object}	errorpkg.ErrorResponse
+//	@Failure		500	{object}	errorpkg.ErrorResponse
+//	@Router			/roles/{id} [delete]
+func (r *RoleRouter) Delete(c *fiber.Ctx) error {
+	if err := r.roleSvc.Delete(c.Params("id"), helper.GetUserID(c)); err != nil {
+		return err
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
@@ -31,6 +31,10 @@ func NewStorageRouter() *StorageRouter {
 func (r *StorageRouter) AppendRoutes(g fiber.Router) {
 	g.Get("/account_usage", r.ComputeAccountUsage)
 	g.Get("/workspace_usage", r.ComputeWorkspaceUsage)
+	g.Get("/workspace_usage_breakdown", r.ComputeWorkspaceBreakdown)
+	g.Get("/organization_usage", r.ComputeOrganizationUsage)
+	g.Get("/organization_usage_breakdown", r.ComputeOrganizationBreakdown)
+	g.Get("/member_usage", r.ComputeMemberUsage)
 	g.Get("/file_usage", r.ComputeFileUsage)
 }
 
@@ -75,6 +79,104 @@ func (r *StorageRouter) ComputeWorkspaceUsage(c *fiber.Ctx) error {
 	return c.JSON(res)
 }
 
+// ComputeWorkspaceBreakdown godoc
+//
+//	@Summary		Compute Workspace Breakdown
+//	@Description	Compute Workspace Breakdown
+//	@Tags			Storage
+//	@Id				storage_compute_workspace_breakdown
+//	@Produce		json
+//	@Param			id	query		string	true	"Workspace ID"
+//	@Success		200	{object}	service.StorageBreakdown
+//	@Failure		500
+//	@Router			/storage/workspace_usage_breakdown [get]
+func (r *StorageRouter) ComputeWorkspaceBreakdown(c *fiber.Ctx) error {
+	id := c.Query("id")
+	if id == "" {
+		return errorpkg.NewMissingQueryParamError("id")
+	}
+	res, err := r.storageSvc.ComputeWorkspaceBreakdown(id, helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+// ComputeOrganizationUsage godoc
+//
+//	@Summary		Compute Organization Usage
+//	@Description	Compute Organization Usage
+//	@Tags			Storage
+//	@Id				storage_compute_organization_usage
+//	@Produce		json
+//	@Param			id	query		string	true	"Organization ID"
+//	@Success		200	{object}	service.StorageUsage
+//	@Failure		500
+//	@Router			/storage/organization_usage [get]
+func (r *StorageRouter) ComputeOrganizationUsage(c *fiber.Ctx) error {
+	id := c.Query("id")
+	if id == "" {
+		return errorpkg.NewMissingQueryParamError("id")
+	}
+	res, err := r.storageSvc.ComputeOrganizationUsage(id, helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+// ComputeOrganizationBreakdown godoc
+//
+//	@Summary		Compute Organization Breakdown
+//	@Description	Compute Organization Breakdown
+//	@Tags			Storage
+//	@Id				storage_compute_organization_breakdown
+//	@Produce		json
+//	@Param			id	query		string	true	"Organization ID"
+//	@Success		200	{object}	service.StorageBreakdown
+//	@Failure		500
+//	@Router			/storage/organization_usage_breakdown [get]
+func (r *StorageRouter) ComputeOrganizationBreakdown(c *fiber.Ctx) error {
+	id := c.Query("id")
+	if id == "" {
+		return errorpkg.NewMissingQueryParamError("id")
+	}
+	res, err := r.storageSvc.ComputeOrganizationBreakdown(id, helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+// ComputeMemberUsage godoc
+//
+//	@Summary		Compute Member Usage
+//	@Description	Compute Member Usage
+//	@Tags			Storage
+//	@Id				storage_compute_member_usage
+//	@Produce		json
+//	@Param			organization_id	query		string	true	"Organization ID"
+//	@Param			user_id			query		string	false	"User ID, defaults to the current user"
+//	@Success		200				{object}	service.StorageUsage
+//	@Failure		500
+//	@Router			/storage/member_usage [get]
+func (r *StorageRouter) ComputeMemberUsage(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	orgID := c.Query("organization_id")
+	if orgID == "" {
+		return errorpkg.NewMissingQueryParamError("organization_id")
+	}
+	memberID := c.Query("user_id")
+	if memberID == "" {
+		memberID = userID
+	}
+	res, err := r.storageSvc.ComputeMemberUsage(orgID, memberID, userID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
 // ComputeFileUsage godoc
 //
 //	@Summary		Compute File Usage
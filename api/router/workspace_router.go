@@ -20,6 +20,7 @@ import (
 
 	"github.com/kouprlabs/voltaserve/api/errorpkg"
 	"github.com/kouprlabs/voltaserve/api/helper"
+	"github.com/kouprlabs/voltaserve/api/model"
 	"github.com/kouprlabs/voltaserve/api/service"
 )
 
@@ -45,6 +46,10 @@ func (r *WorkspaceRouter) AppendRoutes(g fiber.Router) {
 	g.Delete("/:id", r.Delete)
 	g.Patch("/:id/name", r.PatchName)
 	g.Patch("/:id/storage_capacity", r.PatchStorageCapacity)
+	g.Patch("/:id/processing_policy", r.PatchProcessingPolicy)
+	g.Post("/:id/clone", r.Clone)
+	g.Post("/:id/archive", r.Archive)
+	g.Post("/:id/restore", r.Restore)
 }
 
 // Create godoc
@@ -222,6 +227,80 @@ func (r *WorkspaceRouter) PatchName(c *fiber.Ctx) error {
 	return c.JSON(res)
 }
 
+// Clone godoc
+//
+//	@Summary		Clone
+//	@Description	Clone
+//	@Tags			Workspaces
+//	@Id				workspaces_clone
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string							true	"ID"
+//	@Param			body	body		service.WorkspaceCloneOptions	true	"Body"
+//	@Success		201		{object}	service.WorkspaceCloneResult
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/workspaces/{id}/clone [post]
+func (r *WorkspaceRouter) Clone(c *fiber.Ctx) error {
+	opts := new(service.WorkspaceCloneOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	res, err := r.workspaceSvc.Clone(c.Params("id"), *opts, helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.Status(http.StatusCreated).JSON(res)
+}
+
+// Archive godoc
+//
+//	@Summary		Archive
+//	@Description	Archive
+//	@Tags			Workspaces
+//	@Id				workspaces_archive
+//	@Produce		json
+//	@Param			id	path		string	true	"ID"
+//	@Success		202	{object}	service.WorkspaceArchiveResult
+//	@Failure		400	{object}	errorpkg.ErrorResponse
+//	@Failure		403	{object}	errorpkg.ErrorResponse
+//	@Failure		404	{object}	errorpkg.ErrorResponse
+//	@Failure		500	{object}	errorpkg.ErrorResponse
+//	@Router			/workspaces/{id}/archive [post]
+func (r *WorkspaceRouter) Archive(c *fiber.Ctx) error {
+	res, err := r.workspaceSvc.Archive(c.Params("id"), helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.Status(http.StatusAccepted).JSON(res)
+}
+
+// Restore godoc
+//
+//	@Summary		Restore
+//	@Description	Restore
+//	@Tags			Workspaces
+//	@Id				workspaces_restore
+//	@Produce		json
+//	@Param			id	path		string	true	"ID"
+//	@Success		202	{object}	service.WorkspaceArchiveResult
+//	@Failure		400	{object}	errorpkg.ErrorResponse
+//	@Failure		403	{object}	errorpkg.ErrorResponse
+//	@Failure		404	{object}	errorpkg.ErrorResponse
+//	@Failure		500	{object}	errorpkg.ErrorResponse
+//	@Router			/workspaces/{id}/restore [post]
+func (r *WorkspaceRouter) Restore(c *fiber.Ctx) error {
+	res, err := r.workspaceSvc.Restore(c.Params("id"), helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.Status(http.StatusAccepted).JSON(res)
+}
+
 type WorkspacePatchStorageCapacityOptions struct {
 	StorageCapacity int64 `json:"storageCapacity" validate:"required,min=1"`
 }
@@ -252,6 +331,39 @@ func (r *WorkspaceRouter) PatchStorageCapacity(c *fiber.Ctx) error {
 	return c.JSON(res)
 }
 
+type WorkspacePatchProcessingPolicyOptions struct {
+	Policy *model.ProcessingPolicy `json:"policy"`
+}
+
+// PatchProcessingPolicy godoc
+//
+//	@Summary		Patch Processing Policy
+//	@Description	Patch Processing Policy
+//	@Tags			Workspaces
+//	@Id				workspaces_patch_processing_policy
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string									true	"Id"
+//	@Param			body	body		WorkspacePatchProcessingPolicyOptions	true	"Body"
+//	@Success		200		{object}	service.Workspace
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/workspaces/{id}/processing_policy [patch]
+func (r *WorkspaceRouter) PatchProcessingPolicy(c *fiber.Ctx) error {
+	opts := new(WorkspacePatchProcessingPolicyOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	res, err := r.workspaceSvc.PatchProcessingPolicy(c.Params("id"), opts.Policy, helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
 // Delete godoc
 //
 //	@Summary		Delete
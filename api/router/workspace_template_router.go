@@ -0,0 +1,169 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package router
+
+import (
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kouprlabs/voltaserve/api/errorpkg"
+	"github.com/kouprlabs/voltaserve/api/helper"
+	"github.com/kouprlabs/voltaserve/api/service"
+)
+
+type WorkspaceTemplateRouter struct {
+	templateSvc *service.WorkspaceTemplateService
+}
+
+func NewWorkspaceTemplateRouter() *WorkspaceTemplateRouter {
+	return &WorkspaceTemplateRouter{
+		templateSvc: service.NewWorkspaceTemplateService(),
+	}
+}
+
+func (r *WorkspaceTemplateRouter) AppendRoutes(g fiber.Router) {
+	g.Get("/", r.List)
+	g.Post("/", r.Create)
+	g.Get("/:id", r.Find)
+	g.Delete("/:id", r.Delete)
+	g.Patch("/:id/name", r.PatchName)
+}
+
+// Create godoc
+//
+//	@Summary		Create
+//	@Description	Create
+//	@Tags			WorkspaceTemplates
+//	@Id				workspace_templates_create
+//	@Accept			json
+//	@Produce		json
+//	@Param			body	body		service.WorkspaceTemplateCreateOptions	true	"Body"
+//	@Success		200		{object}	service.Task
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/workspace_templates [post]
+func (r *WorkspaceTemplateRouter) Create(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	opts := new(service.WorkspaceTemplateCreateOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	res, err := r.templateSvc.Create(*opts, userID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+// Find godoc
+//
+//	@Summary		Read
+//	@Description	Read
+//	@Tags			WorkspaceTemplates
+//	@Id				workspace_templates_find
+//	@Produce		json
+//	@Param			id	path		string	true	"ID"
+//	@Success		200	{object}	service.WorkspaceTemplate
+//	@Failure		404	{object}	errorpkg.ErrorResponse
+//	@Failure		500	{object}	errorpkg.ErrorResponse
+//	@Router			/workspace_templates/{id} [get]
+func (r *WorkspaceTemplateRouter) Find(c *fiber.Ctx) error {
+	res, err := r.templateSvc.Find(c.Params("id"), helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+// List godoc
+//
+//	@Summary		List
+//	@Description	List
+//	@Tags			WorkspaceTemplates
+//	@Id				workspace_templates_list
+//	@Produce		json
+//	@Param			organization_id	query		string	true	"Organization ID"
+//	@Success		200				{array}		service.WorkspaceTemplate
+//	@Failure		400				{object}	errorpkg.ErrorResponse
+//	@Failure		404				{object}	errorpkg.ErrorResponse
+//	@Failure		500				{object}	errorpkg.ErrorResponse
+//	@Router			/workspace_templates [get]
+func (r *WorkspaceTemplateRouter) List(c *fiber.Ctx) error {
+	organizationID := c.Query("organization_id")
+	if organizationID == "" {
+		return errorpkg.NewMissingQueryParamError("organization_id")
+	}
+	res, err := r.templateSvc.List(organizationID, helper.GetUserID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+type WorkspaceTemplatePatchNameOptions struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+// PatchName godoc
+//
+//	@Summary		Patch Name
+//	@Description	Patch Name
+//	@Tags			WorkspaceTemplates
+//	@Id				workspace_templates_patch_name
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		string								true	"ID"
+//	@Param			body	body		WorkspaceTemplatePatchNameOptions	true	"Body"
+//	@Success		200		{object}	service.WorkspaceTemplate
+//	@Failure		404		{object}	errorpkg.ErrorResponse
+//	@Failure		400		{object}	errorpkg.ErrorResponse
+//	@Failure		500		{object}	errorpkg.ErrorResponse
+//	@Router			/workspace_templates/{id}/name [patch]
+func (r *WorkspaceTemplateRouter) PatchName(c *fiber.Ctx) error {
+	userID := helper.GetUserID(c)
+	opts := new(WorkspaceTemplatePatchNameOptions)
+	if err := c.BodyParser(opts); err != nil {
+		return err
+	}
+	if err := validator.New().Struct(opts); err != nil {
+		return errorpkg.NewRequestBodyValidationError(err)
+	}
+	res, err := r.templateSvc.PatchName(c.Params("id"), opts.Name, userID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(res)
+}
+
+// Delete godoc
+//
+//	@Summary		Delete
+//	@Description	Delete
+//	@Tags			WorkspaceTemplates
+//	@Id				workspace_templates_delete
+//	@Produce		json
+//	@Param			id	path	string	true	"ID"
+//	@Success		204
+//	@Failure		400	{object}	errorpkg.ErrorResponse
+//	@Failure		404	{object}	errorpkg.ErrorResponse
+//	@Failure		500	{object}	errorpkg.ErrorResponse
+//	@Router			/workspace_templates/{id} [delete]
+func (r *WorkspaceTemplateRouter) Delete(c *fiber.Ctx) error {
+	if err := r.templateSvc.Delete(c.Params("id"), helper.GetUserID(c)); err != nil {
+		return err
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
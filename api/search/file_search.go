@@ -12,9 +12,14 @@ package search
 
 import (
 	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 
+	"github.com/kouprlabs/voltaserve/api/helper"
 	"github.com/kouprlabs/voltaserve/api/infra"
 	"github.com/kouprlabs/voltaserve/api/model"
 	"github.com/kouprlabs/voltaserve/api/repo"
@@ -28,21 +33,45 @@ type FileSearch struct {
 }
 
 type fileEntity struct {
-	ID          string  `json:"id"`
-	WorkspaceID string  `json:"workspaceId"`
-	Name        string  `json:"name"`
-	Type        string  `json:"type"`
-	ParentID    *string `json:"parentId,omitempty"`
-	Text        *string `json:"text,omitempty"`
-	SnapshotID  *string `json:"snapshotId,omitempty"`
-	CreateTime  string  `json:"createTime"`
-	UpdateTime  *string `json:"updateTime,omitempty"`
+	ID           string   `json:"id"`
+	WorkspaceID  string   `json:"workspaceId"`
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	ParentID     *string  `json:"parentId,omitempty"`
+	Text         *string  `json:"text,omitempty"`
+	Summary      *string  `json:"summary,omitempty"`
+	Keywords     []string `json:"keywords,omitempty"`
+	Camera       *string  `json:"camera,omitempty"`
+	Lens         *string  `json:"lens,omitempty"`
+	Caption      *string  `json:"caption,omitempty"`
+	CaptureTime  *int64   `json:"captureTime,omitempty"`
+	CaptureMonth *int     `json:"captureMonth,omitempty"`
+	Rating       *int     `json:"rating,omitempty"`
+	Sender       *string  `json:"sender,omitempty"`
+	Recipients   []string `json:"recipients,omitempty"`
+	SnapshotID   *string  `json:"snapshotId,omitempty"`
+	CreateTime   string   `json:"createTime"`
+	UpdateTime   *string  `json:"updateTime,omitempty"`
 }
 
 func (f fileEntity) GetID() string {
 	return f.ID
 }
 
+// filePageEntity holds the text of a page of a document, so that hits can be
+// narrowed down to pages.
+type filePageEntity struct {
+	ID          string `json:"id"`
+	FileID      string `json:"fileId"`
+	WorkspaceID string `json:"workspaceId"`
+	Page        int    `json:"page"`
+	Text        string `json:"text"`
+}
+
+func (f filePageEntity) GetID() string {
+	return f.ID
+}
+
 func NewFileSearch() *FileSearch {
 	return &FileSearch{
 		index:        infra.FileSearchIndex,
@@ -63,10 +92,7 @@ func (s *FileSearch) Index(files []model.File) (err error) {
 	for _, f := range files {
 		res = append(res, s.mapEntity(f))
 	}
-	if err := s.search.Index(s.index, res); err != nil {
-		return err
-	}
-	return nil
+	return s.search.Index(s.index, res)
 }
 
 func (s *FileSearch) Update(files []model.File) (err error) {
@@ -80,10 +106,7 @@ func (s *FileSearch) Update(files []model.File) (err error) {
 	for _, f := range files {
 		res = append(res, s.mapEntity(f))
 	}
-	if err := s.search.Update(s.index, res); err != nil {
-		return err
-	}
-	return nil
+	return s.search.Update(s.index, res)
 }
 
 func (s *FileSearch) Delete(ids []string) error {
@@ -93,9 +116,68 @@ func (s *FileSearch) Delete(ids []string) error {
 	if err := s.search.Delete(s.index, ids); err != nil {
 		return err
 	}
+	for _, id := range ids {
+		if err := s.deletePages(id); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// IndexPages replaces the pages of the files with the ones of the layout of their snapshot,
+// files without layout are left without pages. Unlike Index and Update, it downloads the
+// layout, so it is only called when the snapshot of the files or its layout changes.
+func (s *FileSearch) IndexPages(files []model.File) error {
+	var res []infra.SearchModel
+	for _, f := range files {
+		if f.GetType() != model.FileTypeFile {
+			continue
+		}
+		if err := s.deletePages(f.GetID()); err != nil {
+			return err
+		}
+		if f.GetSnapshotID() == nil {
+			continue
+		}
+		snapshot, err := s.snapshotRepo.Find(*f.GetSnapshotID())
+		if err != nil {
+			return err
+		}
+		if !snapshot.HasLayout() {
+			continue
+		}
+		text, err := s.s3.GetText(snapshot.GetLayout().Key, snapshot.GetLayout().Bucket, minio.GetObjectOptions{})
+		if err != nil {
+			return err
+		}
+		var layout model.Layout
+		if err := json.Unmarshal([]byte(text), &layout); err != nil {
+			return err
+		}
+		for _, page := range layout.Pages {
+			words := make([]string, 0, len(page.Words))
+			for _, word := range page.Words {
+				words = append(words, word.Text)
+			}
+			res = append(res, &filePageEntity{
+				ID:          fmt.Sprintf("%s_%d", f.GetID(), page.Number),
+				FileID:      f.GetID(),
+				WorkspaceID: f.GetWorkspaceID(),
+				Page:        page.Number,
+				Text:        strings.Join(words, " "),
+			})
+		}
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return s.search.Index(infra.FilePageSearchIndex, res)
+}
+
+func (s *FileSearch) deletePages(fileID string) error {
+	return s.search.DeleteByFilter(infra.FilePageSearchIndex, fmt.Sprintf("fileId=\"%s\"", fileID))
+}
+
 func (s *FileSearch) Query(query string, opts infra.QueryOptions) ([]model.File, error) {
 	hits, err := s.search.Query(s.index, query, opts)
 	if err != nil {
@@ -131,21 +213,106 @@ func (s *FileSearch) populateTextField(files []model.File) error {
 				}
 				f.SetText(&text)
 			}
+			if snapshot.HasSummary() {
+				if err := s.populateSummaryFields(f, snapshot); err != nil {
+					return err
+				}
+			}
+			if snapshot.HasMetadata() {
+				if err := s.populateMetadataFields(f, snapshot); err != nil {
+					return err
+				}
+			}
 		}
 	}
 	return nil
 }
 
+func (s *FileSearch) populateSummaryFields(file model.File, snapshot model.Snapshot) error {
+	text, err := s.s3.GetText(snapshot.GetSummary().Key, snapshot.GetSummary().Bucket, minio.GetObjectOptions{})
+	if err != nil {
+		return err
+	}
+	summary := struct {
+		Sentences  []string `json:"sentences"`
+		Keyphrases []struct {
+			Text string `json:"text"`
+		} `json:"keyphrases"`
+	}{}
+	if err := json.Unmarshal([]byte(text), &summary); err != nil {
+		return err
+	}
+	file.SetSummary(helper.ToPtr(strings.Join(summary.Sentences, " ")))
+	keywords := make([]string, 0, len(summary.Keyphrases))
+	for _, keyphrase := range summary.Keyphrases {
+		keywords = append(keywords, keyphrase.Text)
+	}
+	file.SetKeywords(keywords)
+	return nil
+}
+
+// populateMetadataFields merges the IPTC keywords with the ones of the summary.
+func (s *FileSearch) populateMetadataFields(file model.File, snapshot model.Snapshot) error {
+	text, err := s.s3.GetText(snapshot.GetMetadata().Key, snapshot.GetMetadata().Bucket, minio.GetObjectOptions{})
+	if err != nil {
+		return err
+	}
+	metadata := &model.MediaMetadata{}
+	if err := json.Unmarshal([]byte(text), metadata); err != nil {
+		return err
+	}
+	file.SetMediaMetadata(metadata)
+	keywords := file.GetKeywords()
+	for _, keyword := range metadata.Keywords {
+		if !slices.Contains(keywords, keyword) {
+			keywords = append(keywords, keyword)
+		}
+	}
+	file.SetKeywords(keywords)
+	return nil
+}
+
 func (s *FileSearch) mapEntity(file model.File) *fileEntity {
-	return &fileEntity{
+	res := &fileEntity{
 		ID:          file.GetID(),
 		WorkspaceID: file.GetWorkspaceID(),
 		Name:        file.GetName(),
 		Type:        file.GetType(),
 		ParentID:    file.GetParentID(),
 		Text:        file.GetText(),
+		Summary:     file.GetSummary(),
+		Keywords:    file.GetKeywords(),
+		Camera:      s.camera(file.GetMediaMetadata()),
 		SnapshotID:  file.GetSnapshotID(),
 		CreateTime:  file.GetCreateTime(),
 		UpdateTime:  file.GetUpdateTime(),
 	}
+	if metadata := file.GetMediaMetadata(); metadata != nil {
+		res.Lens = metadata.Lens
+		res.Caption = metadata.Caption
+		res.Rating = metadata.Rating
+		if metadata.Email != nil {
+			res.Sender = metadata.Email.From
+			res.Recipients = slices.Concat(metadata.Email.To, metadata.Email.Cc)
+		}
+		if metadata.CaptureTime != nil {
+			if t, err := time.Parse(time.RFC3339, *metadata.CaptureTime); err == nil {
+				res.CaptureTime = helper.ToPtr(t.UnixMilli())
+				res.CaptureMonth = helper.ToPtr(int(t.Month()))
+			}
+		}
+	}
+	return res
+}
+
+// camera joins the make and the model, unless the model already starts with the make.
+func (s *FileSearch) camera(metadata *model.MediaMetadata) *string {
+	if metadata == nil || metadata.CameraModel == nil {
+		return nil
+	}
+	if metadata.CameraMake == nil ||
+		strings.HasPrefix(strings.ToLower(*metadata.CameraModel), strings.ToLower(*metadata.CameraMake)) {
+		return metadata.CameraModel
+	}
+	return helper.ToPtr(*metadata.CameraMake + " " + *metadata.CameraModel)
 }
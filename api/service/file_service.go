@@ -12,14 +12,17 @@ package service
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gosimple/slug"
 	"github.com/minio/minio-go/v7"
@@ -38,48 +41,64 @@ import (
 )
 
 type FileService struct {
-	fileCreate     *fileCreate
-	fileStore      *fileStore
-	fileDelete     *fileDelete
-	fileMove       *fileMove
-	fileCopy       *fileCopy
-	fileDownload   *fileDownload
-	fileFetch      *fileFetch
-	fileList       *fileList
-	fileReprocess  *fileReprocess
-	filePermission *filePermission
-	fileCompute    *fileCompute
-	filePatch      *filePatch
+	fileCreate      *fileCreate
+	fileStore       *fileStore
+	fileDelete      *fileDelete
+	fileMove        *fileMove
+	fileCopy        *fileCopy
+	fileDownload    *fileDownload
+	fileFetch       *fileFetch
+	fileList        *fileList
+	fileReprocess   *fileReprocess
+	fileTextSearch  *fileTextSearch
+	fileMetadata    *fileMetadata
+	fileSheets      *fileSheets
+	fileAudio       *fileAudio
+	filePages       *filePages
+	fileAttachments *fileAttachments
+	filePermission  *filePermission
+	fileAccess      *fileAccess
+	fileCompute     *fileCompute
+	filePatch       *filePatch
 }
 
 func NewFileService() *FileService {
 	return &FileService{
-		fileCreate:     newFileCreate(),
-		fileStore:      newFileStore(),
-		fileDelete:     newFileDelete(),
-		fileMove:       newFileMove(),
-		fileCopy:       newFileCopy(),
-		fileDownload:   newFileDownload(),
-		fileFetch:      newFileFetch(),
-		fileList:       newFileList(),
-		fileReprocess:  newFileReprocess(),
-		filePermission: newFilePermission(),
-		fileCompute:    newFileCompute(),
-		filePatch:      newFilePatch(),
+		fileCreate:      newFileCreate(),
+		fileStore:       newFileStore(),
+		fileDelete:      newFileDelete(),
+		fileMove:        newFileMove(),
+		fileCopy:        newFileCopy(),
+		fileDownload:    newFileDownload(),
+		fileFetch:       newFileFetch(),
+		fileList:        newFileList(),
+		fileReprocess:   newFileReprocess(),
+		fileTextSearch:  newFileTextSearch(),
+		fileMetadata:    newFileMetadata(),
+		fileSheets:      newFileSheets(),
+		fileAudio:       newFileAudio(),
+		filePages:       newFilePages(),
+		fileAttachments: newFileAttachments(),
+		filePermission:  newFilePermission(),
+		fileAccess:      newFileAccess(),
+		fileCompute:     newFileCompute(),
+		filePatch:       newFilePatch(),
 	}
 }
 
 type File struct {
-	ID          string    `json:"id"`
-	WorkspaceID string    `json:"workspaceId"`
-	Name        string    `json:"name"`
-	Type        string    `json:"type"`
-	ParentID    *string   `json:"parentId,omitempty"`
-	Permission  string    `json:"permission"`
-	IsShared    *bool     `json:"isShared,omitempty"`
-	Snapshot    *Snapshot `json:"snapshot,omitempty"`
-	CreateTime  string    `json:"createTime"`
-	UpdateTime  *string   `json:"updateTime,omitempty"`
+	ID                string    `json:"id"`
+	WorkspaceID       string    `json:"workspaceId"`
+	Name              string    `json:"name"`
+	Type              string    `json:"type"`
+	ParentID          *string   `json:"parentId,omitempty"`
+	Permission        string    `json:"permission"`
+	Capabilities      []string  `json:"capabilities"`
+	IsShared          *bool     `json:"isShared,omitempty"`
+	BreaksInheritance bool      `json:"breaksInheritance"`
+	Snapshot          *Snapshot `json:"snapshot,omitempty"`
+	CreateTime        string    `json:"createTime"`
+	UpdateTime        *string   `json:"updateTime,omitempty"`
 }
 
 const (
@@ -172,6 +191,18 @@ func (svc *FileService) DownloadThumbnailBuffer(id string, buf *bytes.Buffer, us
 	return svc.fileDownload.downloadThumbnailBuffer(id, buf, userID)
 }
 
+func (svc *FileService) DownloadPageBuffer(id string, page int, resolution string, buf *bytes.Buffer, userID string) error {
+	return svc.filePages.downloadPageBuffer(id, page, resolution, buf, userID)
+}
+
+func (svc *FileService) DownloadPageThumbnailBuffer(id string, page int, buf *bytes.Buffer, userID string) error {
+	return svc.filePages.downloadPageThumbnailBuffer(id, page, buf, userID)
+}
+
+func (svc *FileService) DownloadSlideBuffer(id string, slide int, extension string, buf *bytes.Buffer, userID string) error {
+	return svc.filePages.downloadSlideBuffer(id, slide, extension, buf, userID)
+}
+
 func (svc *FileService) Move(sourceID string, targetID string, userID string) (*File, error) {
 	return svc.fileMove.move(sourceID, targetID, userID)
 }
@@ -185,7 +216,16 @@ func (svc *FileService) PatchName(id string, name string, userID string) (*File,
 }
 
 func (svc *FileService) GrantUserPermission(ids []string, assigneeID string, permission string, userID string) error {
-	return svc.filePermission.grantUserPermission(ids, assigneeID, permission, userID)
+	return svc.filePermission.grantUserPermission(ids, assigneeID, PermissionGrantOptions{Permission: permission}, userID)
+}
+
+func (svc *FileService) GrantUserPermissionWithOptions(
+	ids []string,
+	assigneeID string,
+	opts PermissionGrantOptions,
+	userID string,
+) error {
+	return svc.filePermission.grantUserPermission(ids, assigneeID, opts, userID)
 }
 
 func (svc *FileService) RevokeUserPermission(ids []string, assigneeID string, userID string) error {
@@ -193,13 +233,38 @@ func (svc *FileService) RevokeUserPermission(ids []string, assigneeID string, us
 }
 
 func (svc *FileService) GrantGroupPermission(ids []string, groupID string, permission string, userID string) error {
-	return svc.filePermission.grantGroupPermission(ids, groupID, permission, userID)
+	return svc.filePermission.grantGroupPermission(ids, groupID, PermissionGrantOptions{Permission: permission}, userID)
+}
+
+func (svc *FileService) GrantGroupPermissionWithOptions(
+	ids []string,
+	groupID string,
+	opts PermissionGrantOptions,
+	userID string,
+) error {
+	return svc.filePermission.grantGroupPermission(ids, groupID, opts, userID)
 }
 
 func (svc *FileService) RevokeGroupPermission(ids []string, groupID string, userID string) error {
 	return svc.filePermission.revokeGroupPermission(ids, groupID, userID)
 }
 
+func (svc *FileService) DenyUserPermission(ids []string, assigneeID string, permission string, userID string) error {
+	return svc.filePermission.denyUserPermission(ids, assigneeID, permission, userID)
+}
+
+func (svc *FileService) DenyGroupPermission(ids []string, groupID string, permission string, userID string) error {
+	return svc.filePermission.denyGroupPermission(ids, groupID, permission, userID)
+}
+
+func (svc *FileService) BreakPermissionInheritance(ids []string, copyPermissions bool, userID string) error {
+	return svc.filePermission.breakInheritance(ids, copyPermissions, userID)
+}
+
+func (svc *FileService) RestorePermissionInheritance(ids []string, userID string) error {
+	return svc.filePermission.restoreInheritance(ids, userID)
+}
+
 func (svc *FileService) FindUserPermissions(id string, userID string) ([]*UserPermission, error) {
 	return svc.filePermission.findUserPermissions(id, userID)
 }
@@ -208,14 +273,43 @@ func (svc *FileService) FindGroupPermissions(id string, userID string) ([]*Group
 	return svc.filePermission.findGroupPermissions(id, userID)
 }
 
+// FindAccess explains the effective permission of the assignee on the file.
+func (svc *FileService) FindAccess(id string, assigneeID string, userID string) (*FileAccess, error) {
+	return svc.fileAccess.find(id, assigneeID, userID)
+}
+
 func (svc *FileService) Reprocess(id string, userID string) (*FileReprocessResult, error) {
 	return svc.fileReprocess.reprocess(id, userID)
 }
 
+func (svc *FileService) SearchText(id string, query string, userID string) (*FileTextSearchResult, error) {
+	return svc.fileTextSearch.search(id, query, userID)
+}
+
+func (svc *FileService) ReadMetadata(id string, userID string) (*model.MediaMetadata, error) {
+	return svc.fileMetadata.read(id, userID)
+}
+
+func (svc *FileService) ReadSheet(id string, sheet int, opts FileReadSheetOptions, userID string) (*FileSheet, error) {
+	return svc.fileSheets.read(id, sheet, opts, userID)
+}
+
+func (svc *FileService) DownloadWaveformBuffer(id string, buf *bytes.Buffer, userID string) error {
+	return svc.fileAudio.downloadWaveformBuffer(id, buf, userID)
+}
+
+func (svc *FileService) DownloadTranscriptBuffer(id string, buf *bytes.Buffer, userID string) error {
+	return svc.fileAudio.downloadTranscriptBuffer(id, buf, userID)
+}
+
 func (svc *FileService) Store(id string, opts FileStoreOptions, userID string) (*File, error) {
 	return svc.fileStore.store(id, opts, userID)
 }
 
+func (svc *FileService) CreateFromAttachments(opts FileCreateFromAttachmentsOptions) ([]*File, error) {
+	return svc.fileAttachments.createFromAttachments(opts)
+}
+
 type fileCreate struct {
 	fileRepo    *repo.FileRepo
 	fileSearch  *search.FileSearch
@@ -315,7 +409,7 @@ func (svc *fileCreate) performCreate(opts FileCreateOptions, failOnDuplicateName
 	if err != nil {
 		return nil, err
 	}
-	if err := svc.fileRepo.GrantUserPermission(file.GetID(), userID, model.PermissionOwner); err != nil {
+	if err := svc.fileRepo.InsertUserPermission(file.GetID(), userID, model.PermissionOwner, repo.PermissionGrantOptions{}); err != nil {
 		return nil, err
 	}
 	file, err = svc.fileCache.Refresh(file.GetID())
@@ -337,7 +431,7 @@ func (svc *fileCreate) validateParent(id string, userID string) error {
 	if err != nil {
 		return err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionEditor); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityUpload); err != nil {
 		return err
 	}
 	if file.GetType() != model.FileTypeFolder {
@@ -383,7 +477,7 @@ func (svc *fileFetch) find(ids []string, userID string) ([]*File, error) {
 		if err != nil {
 			continue
 		}
-		if err = svc.fileGuard.Authorize(userID, file, model.PermissionViewer); err != nil {
+		if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityList); err != nil {
 			return nil, err
 		}
 		mapped, err := svc.fileMapper.mapOne(file, userID)
@@ -476,7 +570,7 @@ func (svc *fileFetch) findPath(id string, userID string) ([]*File, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionViewer); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityList); err != nil {
 		return nil, err
 	}
 	path, err := svc.fileRepo.FindPath(id)
@@ -577,7 +671,7 @@ func (svc *fileFetch) getAuthorizedChildren(id string, userID string) ([]model.F
 	if err != nil {
 		return nil, err
 	}
-	authorized, err := svc.fileCoreSvc.authorizeIDs(userID, childrenIDs, model.PermissionViewer)
+	authorized, err := svc.fileCoreSvc.authorizeIDs(userID, childrenIDs, model.CapabilityList)
 	if err != nil {
 		return nil, err
 	}
@@ -649,12 +743,17 @@ func newFileList() *fileList {
 }
 
 type FileQuery struct {
-	Text             *string `json:"text"                       validate:"required"`
-	Type             *string `json:"type,omitempty"             validate:"omitempty,oneof=file folder"`
-	CreateTimeAfter  *int64  `json:"createTimeAfter,omitempty"`
-	CreateTimeBefore *int64  `json:"createTimeBefore,omitempty"`
-	UpdateTimeAfter  *int64  `json:"updateTimeAfter,omitempty"`
-	UpdateTimeBefore *int64  `json:"updateTimeBefore,omitempty"`
+	Text              *string `json:"text"                        validate:"required"`
+	Type              *string `json:"type,omitempty"              validate:"omitempty,oneof=file folder"`
+	CreateTimeAfter   *int64  `json:"createTimeAfter,omitempty"`
+	CreateTimeBefore  *int64  `json:"createTimeBefore,omitempty"`
+	UpdateTimeAfter   *int64  `json:"updateTimeAfter,omitempty"`
+	UpdateTimeBefore  *int64  `json:"updateTimeBefore,omitempty"`
+	Camera            *string `json:"camera,omitempty"`
+	CaptureTimeAfter  *int64  `json:"captureTimeAfter,omitempty"`
+	CaptureTimeBefore *int64  `json:"captureTimeBefore,omitempty"`
+	CaptureMonth      *int    `json:"captureMonth,omitempty"      validate:"omitempty,min=1,max=12"`
+	MinRating         *int    `json:"minRating,omitempty"         validate:"omitempty,min=0,max=5"`
 }
 
 type FileList struct {
@@ -684,7 +783,7 @@ func (svc *fileList) probe(id string, opts FileListOptions, userID string) (*Fil
 	if err != nil {
 		return nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionViewer); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityList); err != nil {
 		return nil, err
 	}
 	if file.GetType() != model.FileTypeFolder {
@@ -705,7 +804,7 @@ func (svc *fileList) list(id string, opts FileListOptions, userID string) (*File
 	if err != nil {
 		return nil, err
 	}
-	if err := svc.fileGuard.Authorize(userID, file, model.PermissionViewer); err != nil {
+	if err := svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityList); err != nil {
 		return nil, err
 	}
 	if file.GetType() != model.FileTypeFolder {
@@ -733,12 +832,31 @@ func (svc *fileList) list(id string, opts FileListOptions, userID string) (*File
 	return svc.createList(data, file, opts, userID)
 }
 
+// filterValueEscaper escapes the backslashes and quotes of a value put in a quoted filter string,
+// in a single pass so the backslashes escaping quotes are not escaped again.
+var filterValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
 func (svc *fileList) search(query *FileQuery, workspace model.Workspace) ([]model.File, error) {
 	var res []model.File
 	filter := fmt.Sprintf("workspaceId=\"%s\"", workspace.GetID())
 	if query.Type != nil {
 		filter += fmt.Sprintf(" AND type=\"%s\"", *query.Type)
 	}
+	if query.Camera != nil {
+		filter += fmt.Sprintf(" AND camera=\"%s\"", filterValueEscaper.Replace(*query.Camera))
+	}
+	if query.CaptureTimeAfter != nil {
+		filter += fmt.Sprintf(" AND captureTime >= %d", *query.CaptureTimeAfter)
+	}
+	if query.CaptureTimeBefore != nil {
+		filter += fmt.Sprintf(" AND captureTime <= %d", *query.CaptureTimeBefore)
+	}
+	if query.CaptureMonth != nil {
+		filter += fmt.Sprintf(" AND captureMonth = %d", *query.CaptureMonth)
+	}
+	if query.MinRating != nil {
+		filter += fmt.Sprintf(" AND rating >= %d", *query.MinRating)
+	}
 	hits, err := svc.fileSearch.Query(*query.Text, infra.QueryOptions{Filter: filter})
 	if err != nil {
 		return nil, err
@@ -788,7 +906,7 @@ func (svc *fileList) createList(data []model.File, parent model.File, opts FileL
 	} else {
 		filtered = data
 	}
-	authorized, err := svc.fileCoreSvc.authorize(userID, filtered, model.PermissionViewer)
+	authorized, err := svc.fileCoreSvc.authorize(userID, filtered, model.CapabilityList)
 	if err != nil {
 		return nil, err
 	}
@@ -842,7 +960,7 @@ func (svc *fileCompute) computeSize(id string, userID string) (*int64, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := svc.fileGuard.Authorize(userID, file, model.PermissionViewer); err != nil {
+	if err := svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityList); err != nil {
 		return nil, err
 	}
 	res, err := svc.fileRepo.ComputeSize(id)
@@ -857,7 +975,7 @@ func (svc *fileCompute) count(id string, userID string) (*int64, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := svc.fileGuard.Authorize(userID, file, model.PermissionViewer); err != nil {
+	if err := svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityList); err != nil {
 		return nil, err
 	}
 	res, err := svc.fileRepo.CountItems(id)
@@ -868,26 +986,28 @@ func (svc *fileCompute) count(id string, userID string) (*int64, error) {
 }
 
 type fileCopy struct {
-	fileRepo     *repo.FileRepo
-	fileSearch   *search.FileSearch
-	fileCache    *cache.FileCache
-	fileGuard    *guard.FileGuard
-	fileMapper   *fileMapper
-	fileCoreSvc  *fileCoreService
-	taskSvc      *TaskService
-	snapshotRepo *repo.SnapshotRepo
+	fileRepo      *repo.FileRepo
+	fileSearch    *search.FileSearch
+	fileCache     *cache.FileCache
+	fileGuard     *guard.FileGuard
+	fileMapper    *fileMapper
+	fileCoreSvc   *fileCoreService
+	taskSvc       *TaskService
+	snapshotRepo  *repo.SnapshotRepo
+	storageLedger *storageLedger
 }
 
 func newFileCopy() *fileCopy {
 	return &fileCopy{
-		fileRepo:     repo.NewFileRepo(),
-		fileSearch:   search.NewFileSearch(),
-		fileCache:    cache.NewFileCache(),
-		fileGuard:    guard.NewFileGuard(),
-		fileMapper:   newFileMapper(),
-		fileCoreSvc:  newFileCoreService(),
-		taskSvc:      NewTaskService(),
-		snapshotRepo: repo.NewSnapshotRepo(),
+		fileRepo:      repo.NewFileRepo(),
+		fileSearch:    search.NewFileSearch(),
+		fileCache:     cache.NewFileCache(),
+		fileGuard:     guard.NewFileGuard(),
+		fileMapper:    newFileMapper(),
+		fileCoreSvc:   newFileCoreService(),
+		taskSvc:       NewTaskService(),
+		snapshotRepo:  repo.NewSnapshotRepo(),
+		storageLedger: newStorageLedger(),
 	}
 }
 
@@ -959,7 +1079,12 @@ func (svc *fileCopy) performCopy(source model.File, target model.File, userID st
 	if err := svc.attachSnapshots(cloneResult.Clones, tree); err != nil {
 		return nil, err
 	}
-	svc.cache(cloneResult.Clones, userID)
+	if usage, err := svc.storageLedger.measure(cloneResult.Root.GetID()); err != nil {
+		log.GetLogger().Error(err)
+	} else if err := svc.storageLedger.apply(target.GetWorkspaceID(), nil, usage); err != nil {
+		log.GetLogger().Error(err)
+	}
+	svc.cache(cloneResult.Clones, cloneResult.Root, userID)
 	go svc.index(cloneResult.Clones)
 	if err := svc.refreshUpdateTime(target); err != nil {
 		return nil, err
@@ -987,10 +1112,10 @@ func (svc *fileCopy) createTask(file model.File, userID string) (model.Task, err
 }
 
 func (svc *fileCopy) check(source model.File, target model.File, userID string) error {
-	if err := svc.fileGuard.Authorize(userID, target, model.PermissionEditor); err != nil {
+	if err := svc.fileGuard.AuthorizeCapability(userID, target, model.CapabilityUpload); err != nil {
 		return err
 	}
-	if err := svc.fileGuard.Authorize(userID, source, model.PermissionEditor); err != nil {
+	if err := svc.fileGuard.AuthorizeCapability(userID, source, model.CapabilityDownloadOriginal); err != nil {
 		return err
 	}
 	if source.GetID() == target.GetID() {
@@ -1036,7 +1161,6 @@ func (svc *fileCopy) cloneTree(source model.File, target model.File, tree []mode
 	var rootIndex int
 	ids := make(map[string]string)
 	var clones []model.File
-	var permissions []model.UserPermission
 	for index, leaf := range tree {
 		clone := svc.newClone(leaf)
 		if leaf.GetID() == source.GetID() {
@@ -1044,9 +1168,10 @@ func (svc *fileCopy) cloneTree(source model.File, target model.File, tree []mode
 		}
 		ids[leaf.GetID()] = clone.GetID()
 		clones = append(clones, clone)
-		permissions = append(permissions, svc.newUserPermission(clone, userID))
 	}
 	root := clones[rootIndex]
+	// The descendants of the copy inherit the owner permission from its root
+	permissions := []model.UserPermission{svc.newUserPermission(root, userID)}
 	for index, clone := range clones {
 		id := ids[*clone.GetParentID()]
 		clones[index].SetParentID(&id)
@@ -1117,9 +1242,17 @@ func (svc *fileCopy) attachSnapshots(clones []model.File, tree []model.File) err
 	return nil
 }
 
-func (svc *fileCopy) cache(clones []model.File, userID string) {
+func (svc *fileCopy) cache(clones []model.File, root model.File, userID string) {
 	for _, clone := range clones {
-		if _, err := svc.fileCache.RefreshWithExisting(clone, userID); err != nil {
+		if clone.GetID() == root.GetID() {
+			if _, err := svc.fileCache.RefreshWithExisting(clone, userID); err != nil {
+				log.GetLogger().Error(err)
+			}
+			continue
+		}
+		clone.SetUserPermissions(make([]model.CoreUserPermission, 0))
+		clone.SetGroupPermissions(make([]model.CoreGroupPermission, 0))
+		if err := svc.fileCache.Set(clone); err != nil {
 			log.GetLogger().Error(err)
 		}
 	}
@@ -1129,6 +1262,9 @@ func (svc *fileCopy) index(clones []model.File) {
 	if err := svc.fileSearch.Index(clones); err != nil {
 		log.GetLogger().Error(err)
 	}
+	if err := svc.fileSearch.IndexPages(clones); err != nil {
+		log.GetLogger().Error(err)
+	}
 }
 
 func (svc *fileCopy) refreshUpdateTime(target model.File) error {
@@ -1152,6 +1288,7 @@ type fileDelete struct {
 	taskSvc        *TaskService
 	snapshotRepo   *repo.SnapshotRepo
 	snapshotSvc    *SnapshotService
+	storageLedger  *storageLedger
 }
 
 func newFileDelete() *fileDelete {
@@ -1164,6 +1301,7 @@ func newFileDelete() *fileDelete {
 		taskSvc:        NewTaskService(),
 		snapshotRepo:   repo.NewSnapshotRepo(),
 		snapshotSvc:    NewSnapshotService(),
+		storageLedger:  newStorageLedger(),
 	}
 }
 
@@ -1172,7 +1310,7 @@ func (svc *fileDelete) delete(id string, userID string) error {
 	if err != nil {
 		return err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionOwner); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityDelete); err != nil {
 		return err
 	}
 	task, err := svc.createTask(file, userID)
@@ -1187,7 +1325,17 @@ func (svc *fileDelete) delete(id string, userID string) error {
 	if err := svc.check(file); err != nil {
 		return err
 	}
-	return svc.performDelete(file)
+	usage, err := svc.storageLedger.measure(file.GetID())
+	if err != nil {
+		return err
+	}
+	if err := svc.performDelete(file); err != nil {
+		return err
+	}
+	if err := svc.storageLedger.apply(file.GetWorkspaceID(), usage, nil); err != nil {
+		log.GetLogger().Error(err)
+	}
+	return nil
 }
 
 type FileDeleteManyOptions struct {
@@ -1352,7 +1500,7 @@ func (svc *fileDownload) downloadOriginalBuffer(id string, rangeHeader string, b
 	if err != nil {
 		return nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionViewer); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityDownloadOriginal); err != nil {
 		return nil, err
 	}
 	if err = svc.check(file); err != nil {
@@ -1382,7 +1530,7 @@ func (svc *fileDownload) downloadPreviewBuffer(id string, rangeHeader string, bu
 	if err != nil {
 		return nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionViewer); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityPreview); err != nil {
 		return nil, err
 	}
 	if err = svc.check(file); err != nil {
@@ -1392,6 +1540,12 @@ func (svc *fileDownload) downloadPreviewBuffer(id string, rangeHeader string, bu
 	if err != nil {
 		return nil, err
 	}
+	// The preview of PDFs and images is the original itself
+	if svc.isPreviewOriginal(snapshot) {
+		if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityDownloadOriginal); err != nil {
+			return nil, err
+		}
+	}
 	if snapshot.HasPreview() {
 		rangeInterval, err := svc.downloadS3Object(snapshot.GetPreview(), rangeHeader, buf)
 		if err != nil {
@@ -1415,7 +1569,7 @@ func (svc *fileDownload) downloadThumbnailBuffer(id string, buf *bytes.Buffer, u
 	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
 		return nil, errorpkg.NewFileIsNotAFileError(file)
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionViewer); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityPreview); err != nil {
 		return nil, err
 	}
 	snapshot, err := svc.snapshotCache.Get(*file.GetSnapshotID())
@@ -1432,6 +1586,12 @@ func (svc *fileDownload) downloadThumbnailBuffer(id string, buf *bytes.Buffer, u
 	}
 }
 
+func (svc *fileDownload) isPreviewOriginal(snapshot model.Snapshot) bool {
+	return snapshot.HasPreview() && snapshot.HasOriginal() &&
+		snapshot.GetPreview().Bucket == snapshot.GetOriginal().Bucket &&
+		snapshot.GetPreview().Key == snapshot.GetOriginal().Key
+}
+
 func (svc *fileDownload) check(file model.File) error {
 	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
 		return errorpkg.NewFileIsNotAFileError(file)
@@ -1459,24 +1619,26 @@ func (svc *fileDownload) downloadS3Object(s3Object *model.S3Object, rangeHeader
 }
 
 type fileMove struct {
-	fileRepo    *repo.FileRepo
-	fileSearch  *search.FileSearch
-	fileCache   *cache.FileCache
-	fileGuard   *guard.FileGuard
-	fileMapper  *fileMapper
-	fileCoreSvc *fileCoreService
-	taskSvc     *TaskService
+	fileRepo      *repo.FileRepo
+	fileSearch    *search.FileSearch
+	fileCache     *cache.FileCache
+	fileGuard     *guard.FileGuard
+	fileMapper    *fileMapper
+	fileCoreSvc   *fileCoreService
+	taskSvc       *TaskService
+	storageLedger *storageLedger
 }
 
 func newFileMove() *fileMove {
 	return &fileMove{
-		fileRepo:    repo.NewFileRepo(),
-		fileSearch:  search.NewFileSearch(),
-		fileCache:   cache.NewFileCache(),
-		fileGuard:   guard.NewFileGuard(),
-		fileMapper:  newFileMapper(),
-		fileCoreSvc: newFileCoreService(),
-		taskSvc:     NewTaskService(),
+		fileRepo:      repo.NewFileRepo(),
+		fileSearch:    search.NewFileSearch(),
+		fileCache:     cache.NewFileCache(),
+		fileGuard:     guard.NewFileGuard(),
+		fileMapper:    newFileMapper(),
+		fileCoreSvc:   newFileCoreService(),
+		taskSvc:       NewTaskService(),
+		storageLedger: newStorageLedger(),
 	}
 }
 
@@ -1530,10 +1692,25 @@ func (svc *fileMove) moveMany(opts FileMoveManyOptions, userID string) (*FileMov
 }
 
 func (svc *fileMove) performMove(source model.File, target model.File, userID string) (*File, error) {
+	var usage []*repo.StorageUsageEntity
+	var err error
+	if source.GetWorkspaceID() != target.GetWorkspaceID() {
+		usage, err = svc.storageLedger.measure(source.GetID())
+		if err != nil {
+			return nil, err
+		}
+	}
 	if err := svc.fileRepo.MoveSourceIntoTarget(target.GetID(), source.GetID()); err != nil {
 		return nil, err
 	}
-	var err error
+	if source.GetWorkspaceID() != target.GetWorkspaceID() {
+		if err := svc.storageLedger.apply(source.GetWorkspaceID(), usage, nil); err != nil {
+			log.GetLogger().Error(err)
+		}
+		if err := svc.storageLedger.apply(target.GetWorkspaceID(), nil, usage); err != nil {
+			log.GetLogger().Error(err)
+		}
+	}
 	source, err = svc.fileRepo.Find(source.GetID())
 	if err != nil {
 		return nil, err
@@ -1573,10 +1750,10 @@ func (svc *fileMove) check(source model.File, target model.File, userID string)
 			return errorpkg.NewFileWithSimilarNameExistsError()
 		}
 	}
-	if err := svc.fileGuard.Authorize(userID, target, model.PermissionEditor); err != nil {
+	if err := svc.fileGuard.AuthorizeCapability(userID, target, model.CapabilityUpload); err != nil {
 		return err
 	}
-	if err := svc.fileGuard.Authorize(userID, source, model.PermissionEditor); err != nil {
+	if err := svc.fileGuard.AuthorizeCapability(userID, source, model.CapabilityMove); err != nil {
 		return err
 	}
 	if source.GetParentID() != nil && *source.GetParentID() == target.GetID() {
@@ -1649,7 +1826,7 @@ func (svc *filePatch) patchName(id string, name string, userID string) (*File, e
 			return nil, errorpkg.NewFileWithSimilarNameExistsError()
 		}
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionEditor); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityRename); err != nil {
 		return nil, err
 	}
 	file.SetName(name)
@@ -1678,6 +1855,7 @@ type filePermission struct {
 	groupCache     *cache.GroupCache
 	groupGuard     *guard.GroupGuard
 	groupMapper    *groupMapper
+	roleCache      *cache.RoleCache
 	permissionRepo *repo.PermissionRepo
 }
 
@@ -1694,86 +1872,207 @@ func newFilePermission() *filePermission {
 		groupCache:     cache.NewGroupCache(),
 		groupGuard:     guard.NewGroupGuard(),
 		groupMapper:    newGroupMapper(),
+		roleCache:      cache.NewRoleCache(),
 		permissionRepo: repo.NewPermissionRepo(),
 	}
 }
 
-func (svc *filePermission) grantUserPermission(ids []string, assigneeID string, permission string, userID string) error {
+func (svc *filePermission) grantUserPermission(
+	ids []string,
+	assigneeID string,
+	opts PermissionGrantOptions,
+	userID string,
+) error {
+	grantOpts, err := opts.Schedule.toGrantOptions(userID)
+	if err != nil {
+		return err
+	}
+	grantOpts.RoleID = opts.RoleID
 	for _, id := range ids {
-		if err := svc.grantOneUserPermission(id, assigneeID, permission, userID); err != nil {
+		if err := svc.grantOneUserPermission(id, assigneeID, opts.Permission, grantOpts, userID); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (svc *filePermission) grantOneUserPermission(id string, assigneeID string, permission string, userID string) error {
+func (svc *filePermission) grantOneUserPermission(
+	id string,
+	assigneeID string,
+	permission string,
+	opts repo.PermissionGrantOptions,
+	userID string,
+) error {
 	file, err := svc.authorizeUserPermission(id, assigneeID, userID)
 	if err != nil {
 		return err
 	}
-	if err = svc.fileRepo.GrantUserPermission(id, assigneeID, permission); err != nil {
+	if permission, err = svc.authorizeGrant(file, permission, opts.RoleID, userID); err != nil {
+		return err
+	}
+	if err = svc.fileRepo.GrantUserPermission(id, assigneeID, permission, opts); err != nil {
 		return err
 	}
 	if _, err := svc.workspaceCache.Refresh(file.GetWorkspaceID()); err != nil {
 		return err
 	}
-	if err := svc.refreshPathAndTree(id); err != nil {
-		return nil
+	if err := svc.refreshPath(id); err != nil {
+		return err
 	}
 	return nil
 }
 
-func (svc *filePermission) revokeUserPermission(ids []string, assigneeID string, userID string) error {
+// authorizeGrant returns the permission stored along the grant, the user can't grant more
+// than they have themselves, neither in permission nor in capabilities.
+func (svc *filePermission) authorizeGrant(file model.File, permission string, roleID *string, userID string) (string, error) {
+	permission, capabilities, err := svc.resolveRole(file, permission, roleID)
+	if err != nil {
+		return "", err
+	}
+	granterPermission, granterCapabilities := svc.fileGuard.Resolve(userID, file)
+	if !model.IsEquivalentPermission(granterPermission, permission) {
+		return "", errorpkg.NewCannotGrantAboveOwnPermissionError()
+	}
+	for _, capability := range capabilities {
+		if !slices.Contains(granterCapabilities, capability) {
+			return "", errorpkg.NewCannotGrantAboveOwnPermissionError()
+		}
+	}
+	return permission, nil
+}
+
+// resolveRole returns the permission stored along a grant of the role and the capabilities
+// it gives, the role must belong to the organization of the file's workspace.
+func (svc *filePermission) resolveRole(file model.File, permission string, roleID *string) (string, []string, error) {
+	if roleID == nil {
+		return permission, model.GetPermissionCapabilities(permission), nil
+	}
+	role, err := svc.roleCache.Get(*roleID)
+	if err != nil {
+		return "", nil, err
+	}
+	workspace, err := svc.workspaceCache.Get(file.GetWorkspaceID())
+	if err != nil {
+		return "", nil, err
+	}
+	if role.GetOrganizationID() != workspace.GetOrganizationID() {
+		return "", nil, errorpkg.NewRoleNotInOrganizationError(role)
+	}
+	return model.GetPermissionFromCapabilities(role.GetCapabilities()), role.GetCapabilities(), nil
+}
+
+func (svc *filePermission) denyUserPermission(ids []string, assigneeID string, permission string, userID string) error {
 	for _, id := range ids {
-		if err := svc.revokeOneUserPermission(id, assigneeID, userID); err != nil {
+		if err := svc.denyOneUserPermission(id, assigneeID, permission, userID); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (svc *filePermission) revokeOneUserPermission(id string, assigneeID string, userID string) error {
+func (svc *filePermission) denyOneUserPermission(id string, assigneeID string, permission string, userID string) error {
 	if _, err := svc.authorizeUserPermission(id, assigneeID, userID); err != nil {
 		return err
 	}
-	tree, err := svc.fileRepo.FindTree(id)
-	if err != nil {
+	if assigneeID == userID {
+		return errorpkg.NewCannotDenyOwnPermissionError()
+	}
+	if err := svc.fileRepo.DenyUserPermission(id, assigneeID, permission); err != nil {
 		return err
 	}
-	if err := svc.fileRepo.RevokeUserPermission(tree, assigneeID); err != nil {
+	if _, err := svc.fileCache.Refresh(id); err != nil {
 		return err
 	}
-	for _, leaf := range tree {
-		if _, err := svc.fileCache.Refresh(leaf.GetID()); err != nil {
+	return nil
+}
+
+func (svc *filePermission) revokeUserPermission(ids []string, assigneeID string, userID string) error {
+	for _, id := range ids {
+		if err := svc.revokeOneUserPermission(id, assigneeID, userID); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (svc *filePermission) grantGroupPermission(ids []string, groupID string, permission string, userID string) error {
+func (svc *filePermission) revokeOneUserPermission(id string, assigneeID string, userID string) error {
+	file, err := svc.authorizeUserPermission(id, assigneeID, userID)
+	if err != nil {
+		return err
+	}
+	revokedIDs, err := svc.fileRepo.RevokeUserPermission(id, assigneeID)
+	if err != nil {
+		return err
+	}
+	if _, err := svc.workspaceCache.Refresh(file.GetWorkspaceID()); err != nil {
+		return err
+	}
+	return svc.refresh(revokedIDs)
+}
+
+func (svc *filePermission) grantGroupPermission(
+	ids []string,
+	groupID string,
+	opts PermissionGrantOptions,
+	userID string,
+) error {
+	grantOpts, err := opts.Schedule.toGrantOptions(userID)
+	if err != nil {
+		return err
+	}
+	grantOpts.RoleID = opts.RoleID
 	for _, id := range ids {
-		if err := svc.grantOneGroupPermission(id, groupID, permission, userID); err != nil {
+		if err := svc.grantOneGroupPermission(id, groupID, opts.Permission, grantOpts, userID); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (svc *filePermission) grantOneGroupPermission(id string, groupID string, permission string, userID string) error {
+func (svc *filePermission) grantOneGroupPermission(
+	id string,
+	groupID string,
+	permission string,
+	opts repo.PermissionGrantOptions,
+	userID string,
+) error {
 	file, _, err := svc.authorizeGroupPermission(id, groupID, userID)
 	if err != nil {
 		return err
 	}
-	if err := svc.fileRepo.GrantGroupPermission(id, groupID, permission); err != nil {
+	if permission, err = svc.authorizeGrant(file, permission, opts.RoleID, userID); err != nil {
+		return err
+	}
+	if err := svc.fileRepo.GrantGroupPermission(id, groupID, permission, opts); err != nil {
 		return err
 	}
 	if _, err := svc.workspaceCache.Refresh(file.GetWorkspaceID()); err != nil {
 		return err
 	}
-	if err := svc.refreshPathAndTree(id); err != nil {
-		return nil
+	if err := svc.refreshPath(id); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (svc *filePermission) denyGroupPermission(ids []string, groupID string, permission string, userID string) error {
+	for _, id := range ids {
+		if err := svc.denyOneGroupPermission(id, groupID, permission, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (svc *filePermission) denyOneGroupPermission(id string, groupID string, permission string, userID string) error {
+	if _, _, err := svc.authorizeGroupPermission(id, groupID, userID); err != nil {
+		return err
+	}
+	if err := svc.fileRepo.DenyGroupPermission(id, groupID, permission); err != nil {
+		return err
+	}
+	if _, err := svc.fileCache.Refresh(id); err != nil {
+		return err
 	}
 	return nil
 }
@@ -1788,18 +2087,156 @@ func (svc *filePermission) revokeGroupPermission(ids []string, groupID string, u
 }
 
 func (svc *filePermission) revokeOneGroupPermission(id string, groupID string, userID string) error {
-	if _, _, err := svc.authorizeGroupPermission(id, groupID, userID); err != nil {
+	file, _, err := svc.authorizeGroupPermission(id, groupID, userID)
+	if err != nil {
 		return err
 	}
-	tree, err := svc.fileRepo.FindTree(id)
+	revokedIDs, err := svc.fileRepo.RevokeGroupPermission(id, groupID)
 	if err != nil {
 		return err
 	}
-	if err := svc.fileRepo.RevokeGroupPermission(tree, groupID); err != nil {
+	if _, err := svc.workspaceCache.Refresh(file.GetWorkspaceID()); err != nil {
 		return err
 	}
-	for _, leaf := range tree {
-		if _, err := svc.fileCache.Refresh(leaf.GetID()); err != nil {
+	return svc.refresh(revokedIDs)
+}
+
+func (svc *filePermission) breakInheritance(ids []string, copyPermissions bool, userID string) error {
+	for _, id := range ids {
+		if err := svc.breakOneInheritance(id, copyPermissions, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// breakOneInheritance stops the file from inheriting the permissions of its parent,
+// the inherited permissions are either copied onto the file or dropped. Only owners
+// can do it, as they keep their ownership on the file.
+func (svc *filePermission) breakOneInheritance(id string, copyPermissions bool, userID string) error {
+	file, err := svc.fileCache.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := svc.fileGuard.Authorize(userID, file, model.PermissionOwner); err != nil {
+		return err
+	}
+	if file.GetParentID() == nil || file.GetBreaksInheritance() {
+		return nil
+	}
+	if copyPermissions {
+		if err := svc.copyInheritedPermissions(file); err != nil {
+			return err
+		}
+	}
+	// The user keeps ownership, otherwise they would lock themselves out
+	if err := svc.fileRepo.InsertUserPermission(id, userID, model.PermissionOwner, repo.PermissionGrantOptions{}); err != nil {
+		return err
+	}
+	file.SetBreaksInheritance(true)
+	if err := svc.fileRepo.Save(file); err != nil {
+		return err
+	}
+	if _, err := svc.fileCache.Refresh(id); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (svc *filePermission) copyInheritedPermissions(file model.File) error {
+	parent, err := svc.fileCache.Get(*file.GetParentID())
+	if err != nil {
+		return err
+	}
+	chain, err := svc.fileGuard.FindInheritanceChain(parent)
+	if err != nil {
+		return err
+	}
+	users, groups := svc.collectInherited(chain, file)
+	for userID, p := range users {
+		if p.GetEffect() == model.PermissionEffectDeny {
+			err = svc.fileRepo.DenyUserPermission(file.GetID(), userID, p.GetValue())
+		} else {
+			err = svc.fileRepo.InsertUserPermission(file.GetID(), userID, p.GetValue(), repo.PermissionGrantOptions{
+				StartsAt:  p.GetStartsAt(),
+				ExpiresAt: p.GetExpiresAt(),
+				RoleID:    p.GetRoleID(),
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for groupID, p := range groups {
+		if p.GetEffect() == model.PermissionEffectDeny {
+			err = svc.fileRepo.DenyGroupPermission(file.GetID(), groupID, p.GetValue())
+		} else {
+			err = svc.fileRepo.InsertGroupPermission(file.GetID(), groupID, p.GetValue(), repo.PermissionGrantOptions{
+				StartsAt:  p.GetStartsAt(),
+				ExpiresAt: p.GetExpiresAt(),
+				RoleID:    p.GetRoleID(),
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectInherited returns the inherited entries closest to the file for each
+// user and group, entries the file defines itself take precedence.
+func (svc *filePermission) collectInherited(chain []model.File, file model.File) (map[string]model.CoreUserPermission, map[string]model.CoreGroupPermission) {
+	users := make(map[string]model.CoreUserPermission)
+	groups := make(map[string]model.CoreGroupPermission)
+	for _, p := range file.GetUserPermissions() {
+		users[p.GetUserID()] = nil
+	}
+	for _, p := range file.GetGroupPermissions() {
+		groups[p.GetGroupID()] = nil
+	}
+	for _, f := range chain {
+		for _, p := range f.GetUserPermissions() {
+			if _, ok := users[p.GetUserID()]; !ok && p.GetScope() == model.PermissionScopeTree {
+				users[p.GetUserID()] = p
+			}
+		}
+		for _, p := range f.GetGroupPermissions() {
+			if _, ok := groups[p.GetGroupID()]; !ok && p.GetScope() == model.PermissionScopeTree {
+				groups[p.GetGroupID()] = p
+			}
+		}
+	}
+	for k, v := range users {
+		if v == nil {
+			delete(users, k)
+		}
+	}
+	for k, v := range groups {
+		if v == nil {
+			delete(groups, k)
+		}
+	}
+	return users, groups
+}
+
+func (svc *filePermission) restoreInheritance(ids []string, userID string) error {
+	for _, id := range ids {
+		file, err := svc.fileCache.Get(id)
+		if err != nil {
+			return err
+		}
+		if err := svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityShare); err != nil {
+			return err
+		}
+		if !file.GetBreaksInheritance() {
+			continue
+		}
+		file.SetBreaksInheritance(false)
+		if err := svc.fileRepo.Save(file); err != nil {
+			return err
+		}
+		if _, err := svc.fileCache.Refresh(id); err != nil {
 			return err
 		}
 	}
@@ -1811,12 +2248,16 @@ func (svc *filePermission) authorizeUserPermission(id string, assigneeID string,
 	if err != nil {
 		return nil, err
 	}
-	if err := svc.fileGuard.Authorize(userID, file, model.PermissionOwner); err != nil {
+	if err := svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityShare); err != nil {
 		return nil, err
 	}
 	if _, err := svc.userRepo.Find(assigneeID); err != nil {
 		return nil, err
 	}
+	// The access of users above the user is out of their reach
+	if !model.IsEquivalentPermission(svc.fileGuard.GetPermission(userID, file), svc.fileGuard.GetPermission(assigneeID, file)) {
+		return nil, errorpkg.NewCannotGrantAboveOwnPermissionError()
+	}
 	return file, nil
 }
 
@@ -1825,7 +2266,7 @@ func (svc *filePermission) authorizeGroupPermission(id string, groupID string, u
 	if err != nil {
 		return nil, nil, err
 	}
-	if err := svc.fileGuard.Authorize(userID, file, model.PermissionOwner); err != nil {
+	if err := svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityShare); err != nil {
 		return nil, nil, err
 	}
 	group, err := svc.groupCache.Get(groupID)
@@ -1838,7 +2279,9 @@ func (svc *filePermission) authorizeGroupPermission(id string, groupID string, u
 	return file, group, nil
 }
 
-func (svc *filePermission) refreshPathAndTree(id string) error {
+// refreshPath refreshes the file and its ancestors, the descendants don't need to
+// be refreshed as they resolve inherited permissions through their ancestors.
+func (svc *filePermission) refreshPath(id string) error {
 	path, err := svc.fileRepo.FindPath(id)
 	if err != nil {
 		return err
@@ -1848,12 +2291,50 @@ func (svc *filePermission) refreshPathAndTree(id string) error {
 			return err
 		}
 	}
-	tree, err := svc.fileRepo.FindTree(id)
-	if err != nil {
+	return nil
+}
+
+// expireUserPermission deletes a single entry, unlike a revoke the descendants keep their own
+// entries, then prunes the ancestor entries that only led to the file.
+func (svc *filePermission) expireUserPermission(id string, fileID string, userID string) error {
+	if err := svc.permissionRepo.DeleteUserPermission(id); err != nil {
 		return err
 	}
-	for _, leaf := range tree {
-		if _, err := svc.fileCache.Refresh(leaf.GetID()); err != nil {
+	prunedIDs, err := svc.fileRepo.PruneUserAncestorPermissions(fileID, userID)
+	if err != nil {
+		return err
+	}
+	file, err := svc.fileCache.Get(fileID)
+	if err != nil {
+		return err
+	}
+	if _, err := svc.workspaceCache.Refresh(file.GetWorkspaceID()); err != nil {
+		return err
+	}
+	return svc.refresh(append([]string{fileID}, prunedIDs...))
+}
+
+func (svc *filePermission) expireGroupPermission(id string, fileID string, groupID string) error {
+	if err := svc.permissionRepo.DeleteGroupPermission(id); err != nil {
+		return err
+	}
+	prunedIDs, err := svc.fileRepo.PruneGroupAncestorPermissions(fileID, groupID)
+	if err != nil {
+		return err
+	}
+	file, err := svc.fileCache.Get(fileID)
+	if err != nil {
+		return err
+	}
+	if _, err := svc.workspaceCache.Refresh(file.GetWorkspaceID()); err != nil {
+		return err
+	}
+	return svc.refresh(append([]string{fileID}, prunedIDs...))
+}
+
+func (svc *filePermission) refresh(ids []string) error {
+	for _, id := range ids {
+		if _, err := svc.fileCache.Refresh(id); err != nil {
 			return err
 		}
 	}
@@ -1861,9 +2342,15 @@ func (svc *filePermission) refreshPathAndTree(id string) error {
 }
 
 type UserPermission struct {
-	ID         string `json:"id"`
-	User       *User  `json:"user"`
-	Permission string `json:"permission"`
+	ID         string  `json:"id"`
+	User       *User   `json:"user"`
+	Permission string  `json:"permission"`
+	Effect     string  `json:"effect"`
+	StartsAt   *string `json:"startsAt,omitempty"`
+	ExpiresAt  *string `json:"expiresAt,omitempty"`
+	RoleID     *string `json:"roleId,omitempty"`
+	// InheritedFrom is the ID of the ancestor the permission is inherited from.
+	InheritedFrom *string `json:"inheritedFrom,omitempty"`
 }
 
 func (svc *filePermission) findUserPermissions(id string, userID string) ([]*UserPermission, error) {
@@ -1871,35 +2358,55 @@ func (svc *filePermission) findUserPermissions(id string, userID string) ([]*Use
 	if err != nil {
 		return nil, err
 	}
-	if err := svc.fileGuard.Authorize(userID, file, model.PermissionOwner); err != nil {
+	if err := svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityShare); err != nil {
 		return nil, err
 	}
-	permissions, err := svc.permissionRepo.FindUserPermissions(id)
+	chain, err := svc.fileGuard.FindInheritanceChain(file)
 	if err != nil {
 		return nil, err
 	}
 	res := make([]*UserPermission, 0)
-	for _, p := range permissions {
-		if p.GetUserID() == userID {
-			continue
-		}
-		u, err := svc.userRepo.Find(p.GetUserID())
+	for i, f := range chain {
+		permissions, err := svc.permissionRepo.FindUserPermissions(f.GetID())
 		if err != nil {
 			return nil, err
 		}
-		res = append(res, &UserPermission{
-			ID:         p.GetID(),
-			User:       svc.userMapper.mapOne(u),
-			Permission: p.GetPermission(),
-		})
+		for _, p := range permissions {
+			if p.GetUserID() == userID || (i > 0 && p.GetScope() == model.PermissionScopeNode) {
+				continue
+			}
+			u, err := svc.userRepo.Find(p.GetUserID())
+			if err != nil {
+				return nil, err
+			}
+			permission := &UserPermission{
+				ID:         p.GetID(),
+				User:       svc.userMapper.mapOne(u),
+				Permission: p.GetPermission(),
+				Effect:     p.GetEffect(),
+				StartsAt:   p.GetStartsAt(),
+				ExpiresAt:  p.GetExpiresAt(),
+				RoleID:     p.GetRoleID(),
+			}
+			if i > 0 {
+				permission.InheritedFrom = helper.ToPtr(f.GetID())
+			}
+			res = append(res, permission)
+		}
 	}
 	return res, nil
 }
 
 type GroupPermission struct {
-	ID         string `json:"id"`
-	Group      *Group `json:"group"`
-	Permission string `json:"permission"`
+	ID         string  `json:"id"`
+	Group      *Group  `json:"group"`
+	Permission string  `json:"permission"`
+	Effect     string  `json:"effect"`
+	StartsAt   *string `json:"startsAt,omitempty"`
+	ExpiresAt  *string `json:"expiresAt,omitempty"`
+	RoleID     *string `json:"roleId,omitempty"`
+	// InheritedFrom is the ID of the ancestor the permission is inherited from.
+	InheritedFrom *string `json:"inheritedFrom,omitempty"`
 }
 
 func (svc *filePermission) findGroupPermissions(id string, userID string) ([]*GroupPermission, error) {
@@ -1907,32 +2414,201 @@ func (svc *filePermission) findGroupPermissions(id string, userID string) ([]*Gr
 	if err != nil {
 		return nil, err
 	}
-	if err := svc.fileGuard.Authorize(userID, file, model.PermissionOwner); err != nil {
+	if err := svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityShare); err != nil {
 		return nil, err
 	}
-	permissions, err := svc.permissionRepo.FindGroupPermissions(id)
+	chain, err := svc.fileGuard.FindInheritanceChain(file)
 	if err != nil {
 		return nil, err
 	}
 	res := make([]*GroupPermission, 0)
-	for _, p := range permissions {
-		m, err := svc.groupCache.Get(p.GetGroupID())
+	for i, f := range chain {
+		permissions, err := svc.permissionRepo.FindGroupPermissions(f.GetID())
 		if err != nil {
 			return nil, err
 		}
-		g, err := svc.groupMapper.mapOne(m, userID)
-		if err != nil {
-			return nil, err
+		for _, p := range permissions {
+			if i > 0 && p.GetScope() == model.PermissionScopeNode {
+				continue
+			}
+			m, err := svc.groupCache.Get(p.GetGroupID())
+			if err != nil {
+				return nil, err
+			}
+			g, err := svc.groupMapper.mapOne(m, userID)
+			if err != nil {
+				return nil, err
+			}
+			permission := &GroupPermission{
+				ID:         p.GetID(),
+				Group:      g,
+				Permission: p.GetPermission(),
+				Effect:     p.GetEffect(),
+				StartsAt:   p.GetStartsAt(),
+				ExpiresAt:  p.GetExpiresAt(),
+				RoleID:     p.GetRoleID(),
+			}
+			if i > 0 {
+				permission.InheritedFrom = helper.ToPtr(f.GetID())
+			}
+			res = append(res, permission)
+		}
+	}
+	return res, nil
+}
+
+const (
+	FileAccessOriginFile      = "file"
+	FileAccessOriginFolder    = "folder"
+	FileAccessOriginWorkspace = "workspace"
+)
+
+const (
+	FileAccessReasonNotStarted   = "not_started"
+	FileAccessReasonExpired      = "expired"
+	FileAccessReasonNotInherited = "not_inherited"
+)
+
+type FileAccess struct {
+	User         *User              `json:"user"`
+	Permission   string             `json:"permission"`
+	Capabilities []string           `json:"capabilities"`
+	Grants       []*FileAccessGrant `json:"grants"`
+	// InheritanceBrokenAt is the ID of the file that stops inheriting permissions from its parent.
+	InheritanceBrokenAt *string `json:"inheritanceBrokenAt,omitempty"`
+	// IsOrganizationAdmin is set when the user gets owner access as an admin of the organization,
+	// the grants are then listed but don't change the effective permission.
+	IsOrganizationAdmin bool `json:"isOrganizationAdmin"`
+}
+
+// FileAccessGrant is a grant taking part in the effective permission, Group is
+// set when the user gets the grant through one of their groups.
+type FileAccessGrant struct {
+	Permission string  `json:"permission"`
+	Effect     string  `json:"effect"`
+	RoleID     *string `json:"roleId,omitempty"`
+	Group      *Group  `json:"group,omitempty"`
+	FileID     string  `json:"fileId"`
+	FileName   string  `json:"fileName"`
+	Origin     string  `json:"origin"`
+	StartsAt   *string `json:"startsAt,omitempty"`
+	ExpiresAt  *string `json:"expiresAt,omitempty"`
+	IsActive   bool    `json:"isActive"`
+	Reason     *string `json:"reason,omitempty"`
+}
+
+type fileAccess struct {
+	fileCache   *cache.FileCache
+	fileGuard   *guard.FileGuard
+	userRepo    *repo.UserRepo
+	userMapper  *userMapper
+	groupCache  *cache.GroupCache
+	groupMapper *groupMapper
+}
+
+func newFileAccess() *fileAccess {
+	return &fileAccess{
+		fileCache:   cache.NewFileCache(),
+		fileGuard:   guard.NewFileGuard(),
+		userRepo:    repo.NewUserRepo(),
+		userMapper:  newUserMapper(),
+		groupCache:  cache.NewGroupCache(),
+		groupMapper: newGroupMapper(),
+	}
+}
+
+func (svc *fileAccess) find(id string, assigneeID string, userID string) (*FileAccess, error) {
+	file, err := svc.fileCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityShare); err != nil {
+		return nil, err
+	}
+	return svc.explain(file, assigneeID, userID)
+}
+
+// explain lists the grants of the assignee on the file and the ancestors it inherits from,
+// including the ones that don't apply, so it's clear why they don't.
+func (svc *fileAccess) explain(file model.File, assigneeID string, userID string) (*FileAccess, error) {
+	assignee, err := svc.userRepo.Find(assigneeID)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := svc.fileGuard.FindInheritanceChain(file)
+	if err != nil {
+		return nil, err
+	}
+	res := &FileAccess{
+		User:   svc.userMapper.mapOne(assignee),
+		Grants: make([]*FileAccessGrant, 0),
+	}
+	res.Permission, res.Capabilities = svc.fileGuard.Resolve(assigneeID, file)
+	res.IsOrganizationAdmin = svc.fileGuard.IsOrganizationAdmin(assigneeID, file)
+	if last := chain[len(chain)-1]; last.GetBreaksInheritance() {
+		res.InheritanceBrokenAt = helper.ToPtr(last.GetID())
+	}
+	for i, f := range chain {
+		for _, p := range f.GetUserPermissions() {
+			if p.GetUserID() == assigneeID {
+				res.Grants = append(res.Grants, svc.newGrant(f, i == 0, p.GetValue(), p.GetEffect(), p.GetScope(), p.GetStartsAt(), p.GetExpiresAt(), p.GetRoleID()))
+			}
+		}
+		for _, p := range f.GetGroupPermissions() {
+			group, err := svc.groupCache.Get(p.GetGroupID())
+			if err != nil {
+				return nil, err
+			}
+			if !slices.Contains(group.GetMembers(), assigneeID) {
+				continue
+			}
+			grant := svc.newGrant(f, i == 0, p.GetValue(), p.GetEffect(), p.GetScope(), p.GetStartsAt(), p.GetExpiresAt(), p.GetRoleID())
+			if grant.Group, err = svc.groupMapper.mapOne(group, userID); err != nil {
+				return nil, err
+			}
+			res.Grants = append(res.Grants, grant)
 		}
-		res = append(res, &GroupPermission{
-			ID:         p.GetID(),
-			Group:      g,
-			Permission: p.GetPermission(),
-		})
 	}
 	return res, nil
 }
 
+func (svc *fileAccess) newGrant(
+	file model.File,
+	isTarget bool,
+	permission string,
+	effect string,
+	scope string,
+	startsAt *string,
+	expiresAt *string,
+	roleID *string,
+) *FileAccessGrant {
+	res := &FileAccessGrant{
+		Permission: permission,
+		Effect:     effect,
+		RoleID:     roleID,
+		FileID:     file.GetID(),
+		FileName:   file.GetName(),
+		Origin:     FileAccessOriginFolder,
+		StartsAt:   startsAt,
+		ExpiresAt:  expiresAt,
+		IsActive:   true,
+	}
+	if isTarget {
+		res.Origin = FileAccessOriginFile
+	} else if file.GetParentID() == nil {
+		res.Origin = FileAccessOriginWorkspace
+	}
+	now := helper.NewTimestamp()
+	if startsAt != nil && *startsAt > now {
+		res.IsActive, res.Reason = false, helper.ToPtr(FileAccessReasonNotStarted)
+	} else if expiresAt != nil && *expiresAt <= now {
+		res.IsActive, res.Reason = false, helper.ToPtr(FileAccessReasonExpired)
+	} else if !isTarget && scope == model.PermissionScopeNode {
+		res.IsActive, res.Reason = false, helper.ToPtr(FileAccessReasonNotInherited)
+	}
+	return res
+}
+
 type fileReprocess struct {
 	fileCache      *cache.FileCache
 	fileRepo       *repo.FileRepo
@@ -1942,6 +2618,7 @@ type fileReprocess struct {
 	taskCache      *cache.TaskCache
 	taskSvc        *TaskService
 	fileIdent      *infra.FileIdentifier
+	filePolicy     *fileProcessingPolicy
 	pipelineClient conversion_client.PipelineClient
 }
 
@@ -1955,6 +2632,7 @@ func newFileReprocess() *fileReprocess {
 		taskCache:      cache.NewTaskCache(),
 		taskSvc:        NewTaskService(),
 		fileIdent:      infra.NewFileIdentifier(),
+		filePolicy:     newFileProcessingPolicy(),
 		pipelineClient: conversion_client.NewPipelineClient(),
 	}
 }
@@ -2010,7 +2688,7 @@ func (svc *fileReprocess) performReprocess(leaf model.File, userID string) bool
 	if leaf.GetType() != model.FileTypeFile {
 		return false
 	}
-	if err := svc.fileGuard.Authorize(userID, leaf, model.PermissionEditor); err != nil {
+	if err := svc.fileGuard.AuthorizeCapability(userID, leaf, model.CapabilityManageVersions); err != nil {
 		log.GetLogger().Error(err)
 		return false
 	}
@@ -2022,7 +2700,18 @@ func (svc *fileReprocess) performReprocess(leaf model.File, userID string) bool
 	if !svc.check(leaf, snapshot) {
 		return false
 	}
-	if err := svc.runPipeline(leaf, snapshot, userID); err != nil {
+	decision, err := svc.filePolicy.evaluate(leaf, snapshot.GetOriginal().Key)
+	if err != nil {
+		log.GetLogger().Error(err)
+		return false
+	}
+	if decision.SkipPreview {
+		// We don't reprocess if the workspace's processing policy skips this file type
+		return false
+	}
+	// The attachments were extracted when the message was stored
+	delete(decision.Payload, conversion_client.PayloadExtractAttachments)
+	if err := svc.runPipeline(leaf, snapshot, decision, userID); err != nil {
 		log.GetLogger().Error(err)
 		return false
 	}
@@ -2056,7 +2745,7 @@ func (svc *fileReprocess) getTree(file model.File, userID string) ([]model.File,
 	var tree []model.File
 	var err error
 	if file.GetType() == model.FileTypeFolder {
-		if err = svc.fileGuard.Authorize(userID, file, model.PermissionViewer); err != nil {
+		if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityList); err != nil {
 			return nil, err
 		}
 		tree, err = svc.fileRepo.FindTree(file.GetID())
@@ -2084,12 +2773,15 @@ func (svc *fileReprocess) createTask(file model.File, userID string) (model.Task
 	return res, nil
 }
 
-func (svc *fileReprocess) runPipeline(file model.File, snapshot model.Snapshot, userID string) error {
+func (svc *fileReprocess) runPipeline(file model.File, snapshot model.Snapshot, decision *fileProcessingDecision, userID string) error {
 	task, err := svc.createTask(file, userID)
 	if err != nil {
 		return err
 	}
 	snapshot.SetTaskID(helper.ToPtr(task.GetID()))
+	if decision.Language != nil {
+		snapshot.SetLanguage(*decision.Language)
+	}
 	if err := svc.snapshotSvc.saveAndSync(snapshot); err != nil {
 		return err
 	}
@@ -2098,14 +2790,731 @@ func (svc *fileReprocess) runPipeline(file model.File, snapshot model.Snapshot,
 		SnapshotID: snapshot.GetID(),
 		Bucket:     snapshot.GetOriginal().Bucket,
 		Key:        snapshot.GetOriginal().Key,
+		Payload:    decision.Payload,
 	}); err != nil {
 		return err
 	}
 	return nil
 }
 
+type fileProcessingPolicy struct {
+	workspaceCache *cache.WorkspaceCache
+	fileIdent      *infra.FileIdentifier
+}
+
+func newFileProcessingPolicy() *fileProcessingPolicy {
+	return &fileProcessingPolicy{
+		workspaceCache: cache.NewWorkspaceCache(),
+		fileIdent:      infra.NewFileIdentifier(),
+	}
+}
+
+type fileProcessingDecision struct {
+	SkipPreview bool
+	Language    *string
+	Payload     map[string]string
+}
+
+func (svc *fileProcessingPolicy) evaluate(file model.File, key string) (*fileProcessingDecision, error) {
+	res := &fileProcessingDecision{}
+	workspace, err := svc.workspaceCache.Get(file.GetWorkspaceID())
+	if err != nil {
+		return nil, err
+	}
+	policy := workspace.GetProcessingPolicy()
+	if policy == nil {
+		return res, nil
+	}
+	for _, value := range policy.SkipPreview {
+		if svc.matches(key, value) {
+			res.SkipPreview = true
+			return res, nil
+		}
+	}
+	if policy.Insights != nil && svc.isInsightsEligible(key, policy.Insights.FileTypes) {
+		res.Language = helper.ToPtr(policy.Insights.LanguageID)
+		res.setPayload(conversion_client.PayloadLanguage, policy.Insights.LanguageID)
+	}
+	if policy.Mosaic != nil && svc.fileIdent.IsImage(key) {
+		res.setPayload(conversion_client.PayloadMosaicThresholdMegapixels, strconv.FormatFloat(policy.Mosaic.ThresholdMegapixels, 'f', -1, 64))
+	}
+	if policy.ExtractEmailAttachments && svc.fileIdent.IsEmail(key) {
+		res.setPayload(conversion_client.PayloadExtractAttachments, "true")
+	}
+	return res, nil
+}
+
+func (svc *fileProcessingPolicy) isInsightsEligible(key string, fileTypes []string) bool {
+	if len(fileTypes) == 0 {
+		fileTypes = []string{
+			model.ProcessingFileTypePDF,
+			model.ProcessingFileTypeOffice,
+			model.ProcessingFileTypeText,
+			model.ProcessingFileTypeImage,
+			model.ProcessingFileTypeEmail,
+		}
+	}
+	for _, fileType := range fileTypes {
+		if svc.matches(key, fileType) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches accepts either a file type like "image", or an extension like ".psd".
+func (svc *fileProcessingPolicy) matches(key string, value string) bool {
+	if strings.HasPrefix(value, ".") {
+		return strings.EqualFold(filepath.Ext(key), value)
+	}
+	switch value {
+	case model.ProcessingFileTypePDF:
+		return svc.fileIdent.IsPDF(key)
+	case model.ProcessingFileTypeOffice:
+		return svc.fileIdent.IsOffice(key)
+	case model.ProcessingFileTypeText:
+		return svc.fileIdent.IsPlainText(key)
+	case model.ProcessingFileTypeImage:
+		return svc.fileIdent.IsImage(key)
+	case model.ProcessingFileTypeVideo:
+		return svc.fileIdent.IsVideo(key)
+	case model.ProcessingFileTypeAudio:
+		return svc.fileIdent.IsAudio(key)
+	case model.ProcessingFileTypeGLB:
+		return svc.fileIdent.IsGLB(key)
+	case model.ProcessingFileTypeModel:
+		return svc.fileIdent.IsModel(key)
+	case model.ProcessingFileTypeEmail:
+		return svc.fileIdent.IsEmail(key)
+	case model.ProcessingFileTypeZIP:
+		return svc.fileIdent.IsZIP(key)
+	}
+	return false
+}
+
+func (d *fileProcessingDecision) setPayload(key string, value string) {
+	if d.Payload == nil {
+		d.Payload = make(map[string]string)
+	}
+	d.Payload[key] = value
+}
+
+type fileTextSearch struct {
+	fileCache     *cache.FileCache
+	fileGuard     *guard.FileGuard
+	snapshotCache *cache.SnapshotCache
+	s3            infra.S3Manager
+}
+
+func newFileTextSearch() *fileTextSearch {
+	return &fileTextSearch{
+		fileCache:     cache.NewFileCache(),
+		fileGuard:     guard.NewFileGuard(),
+		snapshotCache: cache.NewSnapshotCache(),
+		s3:            infra.NewS3Manager(),
+	}
+}
+
+const (
+	FileTextSearchMaxHits      = 1000
+	FileTextSearchSnippetWords = 8
+)
+
+type FileTextSearchResult struct {
+	Query        string               `json:"query"`
+	Hits         []*FileTextSearchHit `json:"hits"`
+	TotalHits    int                  `json:"totalHits"`
+	IsTruncated  bool                 `json:"isTruncated"`
+	TotalPages   int                  `json:"totalPages"`
+	MatchedPages []int                `json:"matchedPages"`
+}
+
+type FileTextSearchHit struct {
+	Page       int                  `json:"page"`
+	PageWidth  float64              `json:"pageWidth"`
+	PageHeight float64              `json:"pageHeight"`
+	Snippet    string               `json:"snippet"`
+	Boxes      []*FileTextSearchBox `json:"boxes"`
+}
+
+type FileTextSearchBox struct {
+	XMin float64 `json:"xMin"`
+	YMin float64 `json:"yMin"`
+	XMax float64 `json:"xMax"`
+	YMax float64 `json:"yMax"`
+}
+
+func (svc *fileTextSearch) search(id string, query string, userID string) (*FileTextSearchResult, error) {
+	file, err := svc.fileCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityPreview); err != nil {
+		return nil, err
+	}
+	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
+		return nil, errorpkg.NewFileIsNotAFileError(file)
+	}
+	snapshot, err := svc.snapshotCache.Get(*file.GetSnapshotID())
+	if err != nil {
+		return nil, err
+	}
+	if !snapshot.HasLayout() {
+		return nil, errorpkg.NewLayoutNotFoundError(nil)
+	}
+	text, err := svc.s3.GetText(snapshot.GetLayout().Key, snapshot.GetLayout().Bucket, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var layout model.Layout
+	if err := json.Unmarshal([]byte(text), &layout); err != nil {
+		return nil, err
+	}
+	return svc.findHits(layout, query), nil
+}
+
+// findHits looks for the query in every page of the layout, the page index is not used
+// to narrow the pages down, as its tokenized matching misses terms found inside words.
+func (svc *fileTextSearch) findHits(layout model.Layout, query string) *FileTextSearchResult {
+	res := &FileTextSearchResult{
+		Query:        query,
+		Hits:         make([]*FileTextSearchHit, 0),
+		TotalPages:   len(layout.Pages),
+		MatchedPages: make([]int, 0),
+	}
+	terms := make([]string, 0)
+	for _, field := range strings.Fields(query) {
+		if term := svc.normalize(field); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	if len(terms) == 0 {
+		return res
+	}
+	for _, page := range layout.Pages {
+		words := make([]string, len(page.Words))
+		for i, word := range page.Words {
+			words[i] = svc.normalize(word.Text)
+		}
+		for i := 0; i+len(terms) <= len(words); i++ {
+			if !svc.matches(words[i:i+len(terms)], terms) {
+				continue
+			}
+			res.TotalHits++
+			if !slices.Contains(res.MatchedPages, page.Number) {
+				res.MatchedPages = append(res.MatchedPages, page.Number)
+			}
+			if len(res.Hits) >= FileTextSearchMaxHits {
+				res.IsTruncated = true
+				continue
+			}
+			hit := &FileTextSearchHit{
+				Page:       page.Number,
+				PageWidth:  page.Width,
+				PageHeight: page.Height,
+				Snippet:    svc.snippet(page.Words, i, i+len(terms)),
+				Boxes:      make([]*FileTextSearchBox, 0),
+			}
+			for _, word := range page.Words[i : i+len(terms)] {
+				hit.Boxes = append(hit.Boxes, &FileTextSearchBox{
+					XMin: word.XMin,
+					YMin: word.YMin,
+					XMax: word.XMax,
+					YMax: word.YMax,
+				})
+			}
+			res.Hits = append(res.Hits, hit)
+		}
+	}
+	return res
+}
+
+func (svc *fileTextSearch) matches(words []string, terms []string) bool {
+	for i, term := range terms {
+		if len(terms) == 1 {
+			if !strings.Contains(words[i], term) {
+				return false
+			}
+		} else if i == 0 {
+			// The first term of a phrase can be the end of a word
+			if !strings.HasSuffix(words[i], term) {
+				return false
+			}
+		} else if i == len(terms)-1 {
+			// The last term of a phrase can be the beginning of a word
+			if !strings.HasPrefix(words[i], term) {
+				return false
+			}
+		} else if words[i] != term {
+			return false
+		}
+	}
+	return true
+}
+
+func (svc *fileTextSearch) snippet(words []model.LayoutWord, start int, end int) string {
+	from := max(0, start-FileTextSearchSnippetWords)
+	to := min(len(words), end+FileTextSearchSnippetWords)
+	var parts []string
+	for _, word := range words[from:to] {
+		parts = append(parts, word.Text)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (svc *fileTextSearch) normalize(value string) string {
+	return strings.ToLower(strings.TrimFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}))
+}
+
+type fileMetadata struct {
+	fileCache     *cache.FileCache
+	fileGuard     *guard.FileGuard
+	snapshotCache *cache.SnapshotCache
+	s3            infra.S3Manager
+}
+
+func newFileMetadata() *fileMetadata {
+	return &fileMetadata{
+		fileCache:     cache.NewFileCache(),
+		fileGuard:     guard.NewFileGuard(),
+		snapshotCache: cache.NewSnapshotCache(),
+		s3:            infra.NewS3Manager(),
+	}
+}
+
+func (svc *fileMetadata) read(id string, userID string) (*model.MediaMetadata, error) {
+	file, err := svc.fileCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityPreview); err != nil {
+		return nil, err
+	}
+	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
+		return nil, errorpkg.NewFileIsNotAFileError(file)
+	}
+	snapshot, err := svc.snapshotCache.Get(*file.GetSnapshotID())
+	if err != nil {
+		return nil, err
+	}
+	if !snapshot.HasMetadata() {
+		return nil, errorpkg.NewMetadataNotFoundError(nil)
+	}
+	text, err := svc.s3.GetText(snapshot.GetMetadata().Key, snapshot.GetMetadata().Bucket, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	res := &model.MediaMetadata{}
+	if err := json.Unmarshal([]byte(text), res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// fileSheets serves the rows of the structured rendition of spreadsheets, one
+// window of rows at a time since sheets can hold thousands of them.
+type fileSheets struct {
+	fileCache     *cache.FileCache
+	fileGuard     *guard.FileGuard
+	snapshotCache *cache.SnapshotCache
+	s3            infra.S3Manager
+}
+
+func newFileSheets() *fileSheets {
+	return &fileSheets{
+		fileCache:     cache.NewFileCache(),
+		fileGuard:     guard.NewFileGuard(),
+		snapshotCache: cache.NewSnapshotCache(),
+		s3:            infra.NewS3Manager(),
+	}
+}
+
+type FileReadSheetOptions struct {
+	Offset int
+	Limit  int
+}
+
+type FileSheet struct {
+	Name      string              `json:"name"`
+	Columns   []model.SheetColumn `json:"columns"`
+	Rows      [][]any             `json:"rows"`
+	Offset    int                 `json:"offset"`
+	Limit     int                 `json:"limit"`
+	TotalRows int                 `json:"totalRows"`
+	Truncated bool                `json:"truncated"`
+}
+
+// read takes the sheet by its number, starting at 1.
+func (svc *fileSheets) read(id string, sheet int, opts FileReadSheetOptions, userID string) (*FileSheet, error) {
+	file, err := svc.fileCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
+		return nil, errorpkg.NewFileIsNotAFileError(file)
+	}
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityPreview); err != nil {
+		return nil, err
+	}
+	snapshot, err := svc.snapshotCache.Get(*file.GetSnapshotID())
+	if err != nil {
+		return nil, err
+	}
+	sheets := snapshot.GetSheets()
+	if sheets == nil || sheets.Workbook == nil || sheet < 1 || sheet > len(sheets.Workbook.Sheets) {
+		return nil, errorpkg.NewSheetNotFoundError(nil)
+	}
+	text, err := svc.s3.GetText(sheets.Key, sheets.Bucket, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	workbook := &model.Workbook{}
+	if err := json.Unmarshal([]byte(text), workbook); err != nil {
+		return nil, err
+	}
+	if sheet > len(workbook.Sheets) {
+		return nil, errorpkg.NewSheetNotFoundError(nil)
+	}
+	s := workbook.Sheets[sheet-1]
+	start := min(opts.Offset, len(s.Rows))
+	end := min(start+opts.Limit, len(s.Rows))
+	return &FileSheet{
+		Name:      s.Name,
+		Columns:   s.Columns,
+		Rows:      s.Rows[start:end],
+		Offset:    opts.Offset,
+		Limit:     opts.Limit,
+		TotalRows: len(s.Rows),
+		Truncated: s.Truncated,
+	}, nil
+}
+
+// fileAudio serves the waveform and the transcript of the audio track of audio
+// and video files, which the conversion stores next to the preview.
+type fileAudio struct {
+	fileCache     *cache.FileCache
+	fileGuard     *guard.FileGuard
+	snapshotCache *cache.SnapshotCache
+	s3            infra.S3Manager
+}
+
+func newFileAudio() *fileAudio {
+	return &fileAudio{
+		fileCache:     cache.NewFileCache(),
+		fileGuard:     guard.NewFileGuard(),
+		snapshotCache: cache.NewSnapshotCache(),
+		s3:            infra.NewS3Manager(),
+	}
+}
+
+func (svc *fileAudio) downloadWaveformBuffer(id string, buf *bytes.Buffer, userID string) error {
+	snapshot, err := svc.getSnapshot(id, userID)
+	if err != nil {
+		return err
+	}
+	preview := snapshot.GetPreview()
+	if preview == nil || preview.Audio == nil || preview.Audio.Waveform == nil {
+		return errorpkg.NewWaveformNotFoundError(nil)
+	}
+	objectKey := snapshot.GetID() + "/waveform" + preview.Audio.Waveform.Extension
+	if _, err := svc.s3.GetObjectWithBuffer(objectKey, preview.Bucket, buf, minio.GetObjectOptions{}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (svc *fileAudio) downloadTranscriptBuffer(id string, buf *bytes.Buffer, userID string) error {
+	snapshot, err := svc.getSnapshot(id, userID)
+	if err != nil {
+		return err
+	}
+	preview := snapshot.GetPreview()
+	if preview == nil || preview.Audio == nil || preview.Audio.Transcript == nil {
+		return errorpkg.NewTranscriptNotFoundError(nil)
+	}
+	objectKey := snapshot.GetID() + "/transcript" + preview.Audio.Transcript.Extension
+	if _, err := svc.s3.GetObjectWithBuffer(objectKey, preview.Bucket, buf, minio.GetObjectOptions{}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (svc *fileAudio) getSnapshot(id string, userID string) (model.Snapshot, error) {
+	file, err := svc.fileCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
+		return nil, errorpkg.NewFileIsNotAFileError(file)
+	}
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityPreview); err != nil {
+		return nil, err
+	}
+	return svc.snapshotCache.Get(*file.GetSnapshotID())
+}
+
+// filePages serves the page images of documents, which the conversion renders
+// in batches: the first batch when the document is processed, and the next ones
+// lazily when a page that isn't rendered yet is requested.
+type filePages struct {
+	fileCache      *cache.FileCache
+	fileGuard      *guard.FileGuard
+	snapshotCache  *cache.SnapshotCache
+	workspaceCache *cache.WorkspaceCache
+	redis          *infra.RedisManager
+	s3             infra.S3Manager
+	pipelineClient conversion_client.PipelineClient
+}
+
+// pageRenderLockExpiration is how long a batch of pages is given to render before
+// another one can be requested for the same snapshot.
+const pageRenderLockExpiration = time.Minute
+
+func newFilePages() *filePages {
+	return &filePages{
+		fileCache:      cache.NewFileCache(),
+		fileGuard:      guard.NewFileGuard(),
+		snapshotCache:  cache.NewSnapshotCache(),
+		workspaceCache: cache.NewWorkspaceCache(),
+		redis:          infra.NewRedisManager(),
+		s3:             infra.NewS3Manager(),
+		pipelineClient: conversion_client.NewPipelineClient(),
+	}
+}
+
+func (svc *filePages) downloadPageBuffer(id string, page int, resolution string, buf *bytes.Buffer, userID string) error {
+	if resolution != model.PageResolutionStandard && resolution != model.PageResolutionHigh {
+		return errorpkg.NewInvalidQueryParamError("resolution")
+	}
+	return svc.download(id, page, func(snapshotID string) string {
+		return snapshotID + "/pages/" + resolution + "/" + strconv.Itoa(page) + model.PageExtension
+	}, buf, userID)
+}
+
+func (svc *filePages) downloadPageThumbnailBuffer(id string, page int, buf *bytes.Buffer, userID string) error {
+	return svc.download(id, page, func(snapshotID string) string {
+		return snapshotID + "/page_thumbnails/" + strconv.Itoa(page) + model.PageExtension
+	}, buf, userID)
+}
+
+// downloadSlideBuffer serves the images of the slides of presentations, which are
+// rendered along with the preview, so unlike pages they are not rendered on demand.
+func (svc *filePages) downloadSlideBuffer(id string, slide int, extension string, buf *bytes.Buffer, userID string) error {
+	_, snapshot, err := svc.getFileAndSnapshot(id, userID)
+	if err != nil {
+		return err
+	}
+	preview := snapshot.GetPreview()
+	if preview == nil || preview.Document == nil || preview.Document.Slides == nil {
+		return errorpkg.NewSlideNotFoundError(nil)
+	}
+	if slide < 1 || slide > preview.Document.Slides.Count || extension != preview.Document.Slides.Extension {
+		return errorpkg.NewSlideNotFoundError(nil)
+	}
+	objectKey := snapshot.GetID() + "/slides/" + strconv.Itoa(slide) + extension
+	if _, err := svc.s3.GetObjectWithBuffer(objectKey, preview.Bucket, buf, minio.GetObjectOptions{}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (svc *filePages) download(id string, page int, key func(snapshotID string) string, buf *bytes.Buffer, userID string) error {
+	file, snapshot, err := svc.getFileAndSnapshot(id, userID)
+	if err != nil {
+		return err
+	}
+	preview := snapshot.GetPreview()
+	if preview == nil || preview.Document == nil || preview.Document.Pages == nil {
+		return errorpkg.NewPageNotFoundError(nil)
+	}
+	if page < 1 || page > preview.Document.Pages.Count {
+		return errorpkg.NewPageNotFoundError(nil)
+	}
+	objectKey := key(snapshot.GetID())
+	if _, err := svc.s3.StatObject(objectKey, preview.Bucket, minio.StatObjectOptions{}); err != nil {
+		if infra.IsS3ObjectNotFound(err) {
+			return svc.render(file, snapshot, page)
+		}
+		return err
+	}
+	if _, err := svc.s3.GetObjectWithBuffer(objectKey, preview.Bucket, buf, minio.GetObjectOptions{}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (svc *filePages) getFileAndSnapshot(id string, userID string) (model.File, model.Snapshot, error) {
+	file, err := svc.fileCache.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
+		return nil, nil, errorpkg.NewFileIsNotAFileError(file)
+	}
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityPreview); err != nil {
+		return nil, nil, err
+	}
+	snapshot, err := svc.snapshotCache.Get(*file.GetSnapshotID())
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, snapshot, nil
+}
+
+// render asks the conversion for the batch of pages starting at the given page,
+// unless a batch of the snapshot was requested less than pageRenderLockExpiration
+// ago. Either way the caller is told to try again. The batch runs without a task,
+// so it doesn't mark the snapshot as processing and block the file.
+func (svc *filePages) render(file model.File, snapshot model.Snapshot, page int) error {
+	workspace, err := svc.workspaceCache.Get(file.GetWorkspaceID())
+	if err != nil {
+		return err
+	}
+	if workspace.GetArchiveStatus() != nil {
+		return errorpkg.NewPageNotFoundError(nil)
+	}
+	lockKey := "page_render:" + snapshot.GetID()
+	acquired, err := svc.redis.SetNX(lockKey, strconv.Itoa(page), pageRenderLockExpiration)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		return errorpkg.NewPageNotReadyError()
+	}
+	if err := svc.pipelineClient.Run(&conversion_client.PipelineRunOptions{
+		PipelineID: helper.ToPtr(conversion_client.PipelinePDFPages),
+		SnapshotID: snapshot.GetID(),
+		Bucket:     snapshot.GetPreview().Bucket,
+		Key:        snapshot.GetPreview().Key,
+		Payload:    map[string]string{conversion_client.PayloadFirstPage: strconv.Itoa(page)},
+	}); err != nil {
+		if err := svc.redis.Delete(lockKey); err != nil {
+			log.GetLogger().Error(err)
+		}
+		return err
+	}
+	return errorpkg.NewPageNotReadyError()
+}
+
+// fileAttachments creates the attachments that the conversion extracts from
+// an email as files next to the message, on behalf of the uploader of the message.
+type fileAttachments struct {
+	fileCache     *cache.FileCache
+	fileRepo      *repo.FileRepo
+	fileCreate    *fileCreate
+	fileStore     *fileStore
+	fileCoreSvc   *fileCoreService
+	snapshotCache *cache.SnapshotCache
+	workspaceSvc  *WorkspaceService
+}
+
+func newFileAttachments() *fileAttachments {
+	return &fileAttachments{
+		fileCache:     cache.NewFileCache(),
+		fileRepo:      repo.NewFileRepo(),
+		fileCreate:    newFileCreate(),
+		fileStore:     newFileStore(),
+		fileCoreSvc:   newFileCoreService(),
+		snapshotCache: cache.NewSnapshotCache(),
+		workspaceSvc:  NewWorkspaceService(),
+	}
+}
+
+type FileCreateFromAttachmentsOptions struct {
+	SnapshotID  string           `json:"snapshotId"  validate:"required"`
+	Attachments []FileAttachment `json:"attachments" validate:"required,dive"`
+}
+
+type FileAttachment struct {
+	Name        string `json:"name"        validate:"required,max=255"`
+	SnapshotID  string `json:"snapshotId"  validate:"required"`
+	Bucket      string `json:"bucket"      validate:"required"`
+	Key         string `json:"key"         validate:"required"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"contentType" validate:"required"`
+}
+
+func (svc *fileAttachments) createFromAttachments(opts FileCreateFromAttachmentsOptions) ([]*File, error) {
+	snapshot, err := svc.snapshotCache.Get(opts.SnapshotID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.GetUserID() == nil {
+		return nil, errorpkg.NewInternalServerError(errors.New("the snapshot of the message has no uploader"))
+	}
+	userID := *snapshot.GetUserID()
+	fileIDs, err := svc.fileRepo.FindIDsBySnapshot(snapshot.GetID())
+	if err != nil {
+		return nil, err
+	}
+	if len(fileIDs) == 0 {
+		return nil, errorpkg.NewFileNotFoundError(nil)
+	}
+	// Messages are processed on upload, before they can be copied, so there is one file
+	message, err := svc.fileCache.Get(fileIDs[0])
+	if err != nil {
+		return nil, err
+	}
+	if message.GetParentID() == nil {
+		return nil, errorpkg.NewFileIsNotAFileError(message)
+	}
+	var res []*File
+	for _, attachment := range opts.Attachments {
+		if err := svc.workspaceSvc.CheckStorageQuota(message.GetWorkspaceID(), userID, attachment.Size); err != nil {
+			return nil, err
+		}
+		name, err := svc.getAvailableName(*message.GetParentID(), attachment.Name)
+		if err != nil {
+			return nil, err
+		}
+		file, err := svc.fileCreate.performCreate(FileCreateOptions{
+			WorkspaceID: message.GetWorkspaceID(),
+			Name:        name,
+			Type:        model.FileTypeFile,
+			ParentID:    *message.GetParentID(),
+		}, true, userID)
+		if err != nil {
+			return nil, err
+		}
+		file, err = svc.fileStore.store(file.ID, FileStoreOptions{
+			S3Reference: &model.S3Reference{
+				Key:         attachment.Key,
+				Bucket:      attachment.Bucket,
+				SnapshotID:  attachment.SnapshotID,
+				Size:        attachment.Size,
+				ContentType: attachment.ContentType,
+			},
+		}, userID)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, file)
+	}
+	return res, nil
+}
+
+// getAvailableName numbers the name like "invoice (2).pdf" when a file with the same name exists.
+func (svc *fileAttachments) getAvailableName(parentID string, name string) (string, error) {
+	base := helper.FilenameWithoutExtension(name)
+	extension := filepath.Ext(name)
+	res := name
+	for i := 2; ; i++ {
+		existing, err := svc.fileCoreSvc.getChildWithName(parentID, res)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return res, nil
+		}
+		res = fmt.Sprintf("%s (%d)%s", base, i, extension)
+	}
+}
+
 type fileStore struct {
 	fileCache      *cache.FileCache
+	fileGuard      *guard.FileGuard
 	fileCoreSvc    *fileCoreService
 	fileMapper     *fileMapper
 	workspaceCache *cache.WorkspaceCache
@@ -2114,13 +3523,16 @@ type fileStore struct {
 	snapshotSvc    *SnapshotService
 	taskSvc        *TaskService
 	fileIdent      *infra.FileIdentifier
+	filePolicy     *fileProcessingPolicy
 	s3             infra.S3Manager
 	pipelineClient conversion_client.PipelineClient
+	storageLedger  *storageLedger
 }
 
 func newFileStore() *fileStore {
 	return &fileStore{
 		fileCache:      cache.NewFileCache(),
+		fileGuard:      guard.NewFileGuard(),
 		fileCoreSvc:    newFileCoreService(),
 		fileMapper:     newFileMapper(),
 		workspaceCache: cache.NewWorkspaceCache(),
@@ -2129,8 +3541,10 @@ func newFileStore() *fileStore {
 		snapshotSvc:    NewSnapshotService(),
 		taskSvc:        NewTaskService(),
 		fileIdent:      infra.NewFileIdentifier(),
+		filePolicy:     newFileProcessingPolicy(),
 		s3:             infra.NewS3Manager(),
 		pipelineClient: conversion_client.NewPipelineClient(),
+		storageLedger:  newStorageLedger(),
 	}
 }
 
@@ -2144,24 +3558,41 @@ func (svc *fileStore) store(id string, opts FileStoreOptions, userID string) (*F
 	if err != nil {
 		return nil, err
 	}
+	if err := svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityUpload); err != nil {
+		return nil, err
+	}
 	props, err := svc.getProperties(file, opts)
 	if err != nil {
 		return nil, err
 	}
+	decision, err := svc.filePolicy.evaluate(file, props.Original.Key)
+	if err != nil {
+		return nil, err
+	}
+	props.SkipsProcessing = props.ExceedsProcessingLimit || decision.SkipPreview
 	if opts.S3Reference == nil {
 		if err := svc.performStore(props); err != nil {
 			return nil, err
 		}
 	}
-	snapshot, err := svc.createSnapshot(file, props)
+	before, err := svc.storageLedger.measure(file.GetID())
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := svc.createSnapshot(file, props, userID)
 	if err != nil {
 		return nil, err
 	}
 	if err := svc.assignSnapshotToFile(file, snapshot); err != nil {
 		return nil, err
 	}
-	if !props.ExceedsProcessingLimit {
-		if err := svc.runPipeline(file, snapshot, props, userID); err != nil {
+	if after, err := svc.storageLedger.measure(file.GetID()); err != nil {
+		log.GetLogger().Error(err)
+	} else if err := svc.storageLedger.apply(file.GetWorkspaceID(), before, after); err != nil {
+		log.GetLogger().Error(err)
+	}
+	if !props.SkipsProcessing {
+		if err := svc.runPipeline(file, snapshot, props, decision, userID); err != nil {
 			return nil, err
 		}
 	}
@@ -2180,6 +3611,7 @@ type fileStoreProperties struct {
 	Bucket                 string
 	ContentType            string
 	ExceedsProcessingLimit bool
+	SkipsProcessing        bool
 }
 
 func (svc *fileStore) getProperties(file model.File, opts FileStoreOptions) (fileStoreProperties, error) {
@@ -2243,10 +3675,11 @@ func (svc *fileStore) performStore(props fileStoreProperties) error {
 	return nil
 }
 
-func (svc *fileStore) createSnapshot(file model.File, props fileStoreProperties) (model.Snapshot, error) {
+func (svc *fileStore) createSnapshot(file model.File, props fileStoreProperties, userID string) (model.Snapshot, error) {
 	res := repo.NewSnapshot()
 	res.SetID(props.SnapshotID)
-	if props.ExceedsProcessingLimit {
+	res.SetUserID(helper.ToPtr(userID))
+	if props.SkipsProcessing {
 		res.SetStatus(model.SnapshotStatusReady)
 	} else {
 		res.SetStatus(model.SnapshotStatusWaiting)
@@ -2289,12 +3722,15 @@ func (svc *fileStore) createTask(file model.File, userID string) (model.Task, er
 	return res, nil
 }
 
-func (svc *fileStore) runPipeline(file model.File, snapshot model.Snapshot, props fileStoreProperties, userID string) error {
+func (svc *fileStore) runPipeline(file model.File, snapshot model.Snapshot, props fileStoreProperties, decision *fileProcessingDecision, userID string) error {
 	task, err := svc.createTask(file, userID)
 	if err != nil {
 		return err
 	}
 	snapshot.SetTaskID(helper.ToPtr(task.GetID()))
+	if decision.Language != nil {
+		snapshot.SetLanguage(*decision.Language)
+	}
 	if err := svc.snapshotSvc.saveAndSync(snapshot); err != nil {
 		return err
 	}
@@ -2303,6 +3739,7 @@ func (svc *fileStore) runPipeline(file model.File, snapshot model.Snapshot, prop
 		SnapshotID: snapshot.GetID(),
 		Bucket:     props.Original.Bucket,
 		Key:        props.Original.Key,
+		Payload:    decision.Payload,
 	}); err != nil {
 		return err
 	}
@@ -2358,18 +3795,12 @@ func (svc *fileCoreService) saveAndSync(file model.File) error {
 	return nil
 }
 
-func (svc *fileCoreService) authorize(userID string, files []model.File, permission string) ([]model.File, error) {
-	var res []model.File
-	for _, f := range files {
-		if svc.fileGuard.IsAuthorized(userID, f, permission) {
-			res = append(res, f)
-		}
-	}
-	return res, nil
+func (svc *fileCoreService) authorize(userID string, files []model.File, capability string) ([]model.File, error) {
+	return svc.fileGuard.FilterCapability(userID, files, capability), nil
 }
 
-func (svc *fileCoreService) authorizeIDs(userID string, ids []string, permission string) ([]model.File, error) {
-	var res []model.File
+func (svc *fileCoreService) authorizeIDs(userID string, ids []string, capability string) ([]model.File, error) {
+	var files []model.File
 	for _, id := range ids {
 		var f model.File
 		f, err := svc.fileCache.Get(id)
@@ -2381,11 +3812,9 @@ func (svc *fileCoreService) authorizeIDs(userID string, ids []string, permission
 				return nil, err
 			}
 		}
-		if svc.fileGuard.IsAuthorized(userID, f, permission) {
-			res = append(res, f)
-		}
+		files = append(files, f)
 	}
-	return res, nil
+	return svc.fileGuard.FilterCapability(userID, files, capability), nil
 }
 
 type fileFilterService struct {
@@ -2746,7 +4175,7 @@ func (svc *fileSortService) sortByKind(data []model.File, userID string) []model
 }
 
 type fileMapper struct {
-	groupCache     *cache.GroupCache
+	fileGuard      *guard.FileGuard
 	snapshotMapper *snapshotMapper
 	snapshotCache  *cache.SnapshotCache
 	snapshotRepo   *repo.SnapshotRepo
@@ -2754,7 +4183,7 @@ type fileMapper struct {
 
 func newFileMapper() *fileMapper {
 	return &fileMapper{
-		groupCache:     cache.NewGroupCache(),
+		fileGuard:      guard.NewFileGuard(),
 		snapshotMapper: newSnapshotMapper(),
 		snapshotCache:  cache.NewSnapshotCache(),
 		snapshotRepo:   repo.NewSnapshotRepo(),
@@ -2771,6 +4200,7 @@ func (mp *fileMapper) mapOne(m model.File, userID string) (*File, error) {
 		CreateTime:  m.GetCreateTime(),
 		UpdateTime:  m.GetUpdateTime(),
 	}
+	res.BreaksInheritance = m.GetBreaksInheritance()
 	if m.GetSnapshotID() != nil {
 		snapshot, err := mp.snapshotCache.Get(*m.GetSnapshotID())
 		if err != nil {
@@ -2779,39 +4209,40 @@ func (mp *fileMapper) mapOne(m model.File, userID string) (*File, error) {
 		res.Snapshot = mp.snapshotMapper.mapOne(snapshot)
 		res.Snapshot.IsActive = true
 	}
-	res.Permission = model.PermissionNone
-	for _, p := range m.GetUserPermissions() {
-		if p.GetUserID() == userID && model.GetPermissionWeight(p.GetValue()) > model.GetPermissionWeight(res.Permission) {
-			res.Permission = p.GetValue()
-		}
-	}
-	for _, p := range m.GetGroupPermissions() {
-		g, err := mp.groupCache.Get(p.GetGroupID())
+	res.Permission, res.Capabilities = mp.fileGuard.Resolve(userID, m)
+	if slices.Contains(res.Capabilities, model.CapabilityShare) {
+		isShared, err := mp.isShared(m, userID)
 		if err != nil {
 			return nil, err
 		}
-		for _, u := range g.GetMembers() {
-			if u == userID && model.GetPermissionWeight(p.GetValue()) > model.GetPermissionWeight(res.Permission) {
-				res.Permission = p.GetValue()
-			}
-		}
+		res.IsShared = &isShared
 	}
-	shareCount := 0
-	for _, p := range m.GetUserPermissions() {
-		if p.GetUserID() != userID {
-			shareCount++
+	return res, nil
+}
+
+// isShared tells whether others have access to the file, through its own
+// permissions or the ones it inherits.
+func (mp *fileMapper) isShared(m model.File, userID string) (bool, error) {
+	chain, err := mp.fileGuard.FindInheritanceChain(m)
+	if err != nil {
+		return false, err
+	}
+	for i, f := range chain {
+		for _, p := range f.GetUserPermissions() {
+			if p.GetUserID() != userID && p.GetEffect() == model.PermissionEffectAllow &&
+				(i == 0 || p.GetScope() == model.PermissionScopeTree) &&
+				model.IsActivePermission(p.GetStartsAt(), p.GetExpiresAt()) {
+				return true, nil
+			}
 		}
-	}
-	if res.Permission == model.PermissionOwner {
-		shareCount += len(m.GetGroupPermissions())
-		res.IsShared = new(bool)
-		if shareCount > 0 {
-			*res.IsShared = true
-		} else {
-			*res.IsShared = false
+		for _, p := range f.GetGroupPermissions() {
+			if p.GetEffect() == model.PermissionEffectAllow && (i == 0 || p.GetScope() == model.PermissionScopeTree) &&
+				model.IsActivePermission(p.GetStartsAt(), p.GetExpiresAt()) {
+				return true, nil
+			}
 		}
 	}
-	return res, nil
+	return false, nil
 }
 
 func (mp *fileMapper) mapMany(data []model.File, userID string) ([]*File, error) {
@@ -113,7 +113,7 @@ func (svc *GroupService) Create(opts GroupCreateOptions, userID string) (*Group,
 	if err != nil {
 		return nil, err
 	}
-	if err := svc.groupRepo.GrantUserPermission(group.GetID(), userID, model.PermissionOwner); err != nil {
+	if err := svc.groupRepo.GrantUserPermission(group.GetID(), userID, model.PermissionOwner, repo.PermissionGrantOptions{}); err != nil {
 		return nil, err
 	}
 	group, err = svc.groupCache.Refresh(group.GetID())
@@ -235,9 +235,19 @@ func (svc *GroupService) Delete(id string, userID string) error {
 	if err := svc.groupGuard.Authorize(userID, group, model.PermissionOwner); err != nil {
 		return err
 	}
+	ancestorIDs, err := svc.groupRepo.FindAncestorIDs(id)
+	if err != nil {
+		return err
+	}
 	if err := svc.groupRepo.Delete(id); err != nil {
 		return err
 	}
+	// The groups containing this one lose its members
+	for _, ancestorID := range ancestorIDs {
+		if _, err := svc.groupCache.Refresh(ancestorID); err != nil {
+			return err
+		}
+	}
 	if err := svc.groupSearch.Delete([]string{group.GetID()}); err != nil {
 		return err
 	}
@@ -248,6 +258,15 @@ func (svc *GroupService) Delete(id string, userID string) error {
 }
 
 func (svc *GroupService) AddMember(id string, memberID string, userID string) error {
+	return svc.AddScheduledMember(id, memberID, PermissionSchedule{}, userID)
+}
+
+// AddScheduledMember adds a member whose membership starts and ends at the given times.
+func (svc *GroupService) AddScheduledMember(id string, memberID string, schedule PermissionSchedule, userID string) error {
+	opts, err := schedule.toGrantOptions(userID)
+	if err != nil {
+		return err
+	}
 	group, err := svc.groupCache.Get(id)
 	if err != nil {
 		return nil
@@ -269,12 +288,11 @@ func (svc *GroupService) AddMember(id string, memberID string, userID string) er
 	}
 	// Ensure that the member doesn't already have a higher permission on the group,
 	// if we don't check that, we risk downgrading the existing permission
-	if !svc.groupGuard.IsAuthorized(memberID, group, model.PermissionViewer) &&
-		!svc.groupGuard.IsAuthorized(memberID, group, model.PermissionEditor) {
-		if err := svc.groupRepo.GrantUserPermission(group.GetID(), memberID, model.PermissionViewer); err != nil {
+	if svc.findUserPermission(group, memberID) == model.PermissionNone {
+		if err := svc.groupRepo.GrantUserPermission(group.GetID(), memberID, model.PermissionViewer, opts); err != nil {
 			return err
 		}
-		if _, err := svc.groupCache.Refresh(group.GetID()); err != nil {
+		if err := svc.groupCache.RefreshMembership(group.GetID()); err != nil {
 			return err
 		}
 	}
@@ -306,18 +324,98 @@ func (svc *GroupService) RemoveMember(id string, memberID string, userID string)
 	if err != nil {
 		return err
 	}
-	if svc.groupGuard.IsAuthorized(memberID, group, model.PermissionOwner) && ownerCount == 1 {
+	if svc.findUserPermission(group, memberID) == model.PermissionOwner && ownerCount == 1 {
 		return errorpkg.NewCannotRemoveSoleOwnerOfGroupError(group)
 	}
 	if err := svc.groupRepo.RevokeUserPermission(id, memberID); err != nil {
 		return err
 	}
-	if _, err := svc.groupCache.Refresh(group.GetID()); err != nil {
+	if err := svc.groupCache.RefreshMembership(group.GetID()); err != nil {
 		return err
 	}
 	return nil
 }
 
+// AddSubgroup makes the members of the subgroup members of the group, a group
+// cannot contain itself, directly or through its subgroups.
+func (svc *GroupService) AddSubgroup(id string, subgroupID string, userID string) error {
+	group, subgroup, err := svc.authorizeSubgroup(id, subgroupID, userID)
+	if err != nil {
+		return err
+	}
+	if group.GetOrganizationID() != subgroup.GetOrganizationID() {
+		return errorpkg.NewGroupNotInSameOrganizationError(group, subgroup)
+	}
+	descendantIDs, err := svc.groupRepo.FindDescendantIDs(subgroupID)
+	if err != nil {
+		return err
+	}
+	if subgroupID == id || slices.Contains(descendantIDs, id) {
+		return errorpkg.NewGroupCycleError(group, subgroup)
+	}
+	if err := svc.groupRepo.GrantGroupPermission(id, subgroupID, model.PermissionViewer, repo.PermissionGrantOptions{
+		GrantedBy: helper.ToPtr(userID),
+	}); err != nil {
+		return err
+	}
+	return svc.groupCache.RefreshMembership(id)
+}
+
+func (svc *GroupService) RemoveSubgroup(id string, subgroupID string, userID string) error {
+	if _, _, err := svc.authorizeSubgroup(id, subgroupID, userID); err != nil {
+		return err
+	}
+	if err := svc.groupRepo.RevokeGroupPermission(id, subgroupID); err != nil {
+		return err
+	}
+	return svc.groupCache.RefreshMembership(id)
+}
+
+func (svc *GroupService) ListSubgroups(id string, userID string) ([]*Group, error) {
+	group, err := svc.groupCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.groupGuard.Authorize(userID, group, model.PermissionViewer); err != nil {
+		return nil, err
+	}
+	subgroups, err := svc.authorizeIDs(group.GetSubgroups(), userID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.groupMapper.mapMany(subgroups, userID)
+}
+
+func (svc *GroupService) authorizeSubgroup(id string, subgroupID string, userID string) (model.Group, model.Group, error) {
+	group, err := svc.groupCache.Get(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := svc.groupGuard.Authorize(userID, group, model.PermissionOwner); err != nil {
+		return nil, nil, err
+	}
+	subgroup, err := svc.groupCache.Get(subgroupID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := svc.groupGuard.Authorize(userID, subgroup, model.PermissionViewer); err != nil {
+		return nil, nil, err
+	}
+	return group, subgroup, nil
+}
+
+// findUserPermission returns the permission granted to the user on the group itself,
+// ignoring the ones coming from subgroups or from the organization.
+func (svc *GroupService) findUserPermission(group model.Group, userID string) string {
+	res := model.PermissionNone
+	for _, p := range group.GetUserPermissions() {
+		if p.GetUserID() == userID && model.IsActivePermission(p.GetStartsAt(), p.GetExpiresAt()) {
+			res = model.MaxPermission(res, p.GetValue())
+		}
+	}
+	return res
+}
+
 func (svc *GroupService) IsValidSortBy(value string) bool {
 	return value == "" ||
 		value == GroupSortByName ||
@@ -533,7 +631,8 @@ func (mp *groupMapper) mapOne(m model.Group, userID string) (*Group, error) {
 	}
 	res.Permission = model.PermissionNone
 	for _, p := range m.GetUserPermissions() {
-		if p.GetUserID() == userID && model.GetPermissionWeight(p.GetValue()) > model.GetPermissionWeight(res.Permission) {
+		if p.GetUserID() == userID && model.GetPermissionWeight(p.GetValue()) > model.GetPermissionWeight(res.Permission) &&
+			model.IsActivePermission(p.GetStartsAt(), p.GetExpiresAt()) {
 			res.Permission = p.GetValue()
 		}
 	}
@@ -543,11 +642,16 @@ func (mp *groupMapper) mapOne(m model.Group, userID string) (*Group, error) {
 			return nil, err
 		}
 		for _, u := range g.GetMembers() {
-			if u == userID && model.GetPermissionWeight(p.GetValue()) > model.GetPermissionWeight(res.Permission) {
+			if u == userID && model.GetPermissionWeight(p.GetValue()) > model.GetPermissionWeight(res.Permission) &&
+				model.IsActivePermission(p.GetStartsAt(), p.GetExpiresAt()) {
 				res.Permission = p.GetValue()
 			}
 		}
 	}
+	// Organization admins own all workspaces and groups of the organization
+	if o.Role == model.OrganizationRoleAdmin {
+		res.Permission = model.PermissionOwner
+	}
 	return res, nil
 }
 
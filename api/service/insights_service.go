@@ -14,8 +14,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math/big"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
+	"unicode"
 
 	"github.com/minio/minio-go/v7"
 
@@ -44,6 +49,10 @@ type InsightsService struct {
 	languageClient *language_client.LanguageClient
 	pipelineClient conversion_client.PipelineClient
 	fileIdent      *infra.FileIdentifier
+	fileCreate     *fileCreate
+	fileStore      *fileStore
+	fileMapper     *fileMapper
+	workspaceCache *cache.WorkspaceCache
 }
 
 func NewInsightsService() *InsightsService {
@@ -78,6 +87,10 @@ func NewInsightsService() *InsightsService {
 		languageClient: language_client.NewLanguageClient(),
 		pipelineClient: conversion_client.NewPipelineClient(),
 		fileIdent:      infra.NewFileIdentifier(),
+		fileCreate:     newFileCreate(),
+		fileStore:      newFileStore(),
+		fileMapper:     newFileMapper(),
+		workspaceCache: cache.NewWorkspaceCache(),
 	}
 }
 
@@ -101,8 +114,30 @@ func (svc *InsightsService) FindLanguages() ([]*InsightsLanguage, error) {
 	return svc.languages, nil
 }
 
+// IsValidLanguage accepts a single language ID, or a combination
+// of language IDs joined with "+" (e.g. "eng+deu").
+func (svc *InsightsService) IsValidLanguage(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, id := range strings.Split(value, "+") {
+		found := false
+		for _, language := range svc.languages {
+			if language.ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 type InsightsCreateOptions struct {
-	LanguageID string `json:"languageId" validate:"required"`
+	// LanguageID is detected automatically when left empty.
+	LanguageID string `json:"languageId"`
 }
 
 func (svc *InsightsService) Create(id string, opts InsightsCreateOptions, userID string) (*Task, error) {
@@ -110,7 +145,7 @@ func (svc *InsightsService) Create(id string, opts InsightsCreateOptions, userID
 	if err != nil {
 		return nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionEditor); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityRunInsights); err != nil {
 		return nil, err
 	}
 	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
@@ -127,17 +162,25 @@ func (svc *InsightsService) Create(id string, opts InsightsCreateOptions, userID
 	if isTaskPending {
 		return nil, errorpkg.NewSnapshotHasPendingTaskError(nil)
 	}
+	if opts.LanguageID != "" && !svc.IsValidLanguage(opts.LanguageID) {
+		return nil, errorpkg.NewUnsupportedLanguageError(opts.LanguageID)
+	}
 	task, err := svc.createWaitingTask(file, userID)
 	if err != nil {
 		return nil, err
 	}
-	snapshot.SetLanguage(opts.LanguageID)
+	var language *string
+	if opts.LanguageID != "" {
+		language = helper.ToPtr(opts.LanguageID)
+		snapshot.SetLanguage(opts.LanguageID)
+		snapshot.SetLanguageConfidence(nil)
+	}
 	snapshot.SetStatus(model.SnapshotStatusWaiting)
 	snapshot.SetTaskID(helper.ToPtr(task.GetID()))
 	if err := svc.snapshotSvc.saveAndSync(snapshot); err != nil {
 		return nil, err
 	}
-	if err := svc.runPipeline(snapshot, task); err != nil {
+	if err := svc.runPipeline(snapshot, task, language); err != nil {
 		return nil, err
 	}
 	res, err := svc.taskMapper.mapOne(task)
@@ -152,7 +195,7 @@ func (svc *InsightsService) Patch(id string, userID string) (*Task, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionEditor); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityRunInsights); err != nil {
 		return nil, err
 	}
 	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
@@ -182,11 +225,12 @@ func (svc *InsightsService) Patch(id string, userID string) (*Task, error) {
 	}
 	snapshot.SetStatus(model.SnapshotStatusWaiting)
 	snapshot.SetLanguage(*previous.GetLanguage())
+	snapshot.SetLanguageConfidence(previous.GetLanguageConfidence())
 	snapshot.SetTaskID(helper.ToPtr(task.GetID()))
 	if err := svc.snapshotSvc.saveAndSync(snapshot); err != nil {
 		return nil, err
 	}
-	if err := svc.runPipeline(snapshot, task); err != nil {
+	if err := svc.runPipeline(snapshot, task, previous.GetLanguage()); err != nil {
 		return nil, err
 	}
 	res, err := svc.taskMapper.mapOne(task)
@@ -201,7 +245,10 @@ func (svc *InsightsService) Delete(id string, userID string) (*Task, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionOwner); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityRunInsights); err != nil {
+		return nil, err
+	}
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityDelete); err != nil {
 		return nil, err
 	}
 	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
@@ -248,11 +295,19 @@ func (svc *InsightsService) Delete(id string, userID string) (*Task, error) {
 				combinedErrMsg = err.Error()
 				failed = true
 			}
+			if err := svc.deleteLayout(snapshot); err != nil {
+				combinedErrMsg = fmt.Sprintf("%s\n%s", combinedErrMsg, err.Error())
+				failed = true
+			}
 		}
 		if err := svc.deleteEntities(snapshot); err != nil {
 			combinedErrMsg = fmt.Sprintf("%s\n%s", combinedErrMsg, err.Error())
 			failed = true
 		}
+		if err := svc.deleteSummary(snapshot); err != nil {
+			combinedErrMsg = fmt.Sprintf("%s\n%s", combinedErrMsg, err.Error())
+			failed = true
+		}
 		if failed {
 			task.SetError(&combinedErrMsg)
 			if err := svc.taskSvc.saveAndSync(repo.NewTask()); err != nil {
@@ -331,12 +386,60 @@ func (svc *InsightsService) ProbeEntities(id string, opts InsightsListEntitiesOp
 	}, nil
 }
 
+type InsightsSummary struct {
+	Sentences  []string            `json:"sentences"`
+	Keyphrases []InsightsKeyphrase `json:"keyphrases"`
+	Provider   string              `json:"provider"`
+}
+
+type InsightsKeyphrase struct {
+	Text  string  `json:"text"`
+	Score float64 `json:"score"`
+}
+
+func (svc *InsightsService) ReadSummary(id string, userID string) (*InsightsSummary, error) {
+	file, err := svc.fileCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityPreview); err != nil {
+		return nil, err
+	}
+	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
+		return nil, errorpkg.NewFileIsNotAFileError(file)
+	}
+	snapshot, err := svc.snapshotCache.Get(*file.GetSnapshotID())
+	if err != nil {
+		return nil, err
+	}
+	if !snapshot.HasSummary() {
+		previous, err := svc.getPreviousSnapshot(file.GetID(), snapshot.GetVersion())
+		if err != nil {
+			return nil, err
+		}
+		if previous == nil || !previous.HasSummary() {
+			return nil, errorpkg.NewSummaryNotFoundError(nil)
+		} else {
+			snapshot = previous
+		}
+	}
+	text, err := svc.s3.GetText(snapshot.GetSummary().Key, snapshot.GetSummary().Bucket, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	res := &InsightsSummary{}
+	if err := json.Unmarshal([]byte(text), res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func (svc *InsightsService) ReadInfo(id string, userID string) (*InsightsInfo, error) {
 	file, err := svc.fileCache.Get(id)
 	if err != nil {
 		return nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionViewer); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityPreview); err != nil {
 		return nil, err
 	}
 	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
@@ -371,7 +474,7 @@ func (svc *InsightsService) DownloadTextBuffer(id string, userID string) (*bytes
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionViewer); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityPreview); err != nil {
 		return nil, nil, nil, err
 	}
 	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
@@ -408,7 +511,7 @@ func (svc *InsightsService) DownloadOCRBuffer(id string, userID string) (*bytes.
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionViewer); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityPreview); err != nil {
 		return nil, nil, nil, err
 	}
 	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
@@ -450,10 +553,21 @@ func (svc *InsightsService) IsValidSortOrder(value string) bool {
 	return value == "" || value == InsightsEntitiesSortOrderAsc || value == InsightsEntitiesSortOrderDesc
 }
 
-func (svc *InsightsService) runPipeline(snapshot model.Snapshot, task model.Task) error {
+// runPipeline lets the conversion service detect the language when none is provided.
+func (svc *InsightsService) runPipeline(snapshot model.Snapshot, task model.Task, language *string) error {
 	key := snapshot.GetOriginal().Key
+	// Text files previewed as HTML are read from the original, and office files
+	// that LibreOffice failed to convert from their extracted text
 	if svc.fileIdent.IsOffice(key) || svc.fileIdent.IsPlainText(key) {
-		key = snapshot.GetPreview().Key
+		if svc.fileIdent.IsPDF(snapshot.GetPreview().Key) {
+			key = snapshot.GetPreview().Key
+		} else if svc.fileIdent.IsOffice(key) && snapshot.HasText() {
+			key = snapshot.GetText().Key
+		}
+	}
+	var payload map[string]string
+	if language != nil {
+		payload = map[string]string{conversion_client.PayloadLanguage: *language}
 	}
 	if err := svc.pipelineClient.Run(&conversion_client.PipelineRunOptions{
 		PipelineID: helper.ToPtr(conversion_client.PipelineInsights),
@@ -461,7 +575,7 @@ func (svc *InsightsService) runPipeline(snapshot model.Snapshot, task model.Task
 		SnapshotID: snapshot.GetID(),
 		Bucket:     snapshot.GetPreview().Bucket,
 		Key:        key,
-		Payload:    map[string]string{"language": *snapshot.GetLanguage()},
+		Payload:    payload,
 	}); err != nil {
 		return err
 	}
@@ -498,6 +612,36 @@ func (svc *InsightsService) deleteText(snapshot model.Snapshot) error {
 	return nil
 }
 
+func (svc *InsightsService) deleteLayout(snapshot model.Snapshot) error {
+	if !snapshot.HasLayout() {
+		return nil
+	}
+	s3Object := snapshot.GetLayout()
+	if err := svc.s3.RemoveObject(s3Object.Key, s3Object.Bucket, minio.RemoveObjectOptions{}); err != nil {
+		return err
+	}
+	snapshot.SetLayout(nil)
+	if err := svc.snapshotSvc.saveAndSync(snapshot); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (svc *InsightsService) deleteSummary(snapshot model.Snapshot) error {
+	if !snapshot.HasSummary() {
+		return nil
+	}
+	s3Object := snapshot.GetSummary()
+	if err := svc.s3.RemoveObject(s3Object.Key, s3Object.Bucket, minio.RemoveObjectOptions{}); err != nil {
+		return err
+	}
+	snapshot.SetSummary(nil)
+	if err := svc.snapshotSvc.saveAndSync(snapshot); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (svc *InsightsService) deleteEntities(snapshot model.Snapshot) error {
 	if !snapshot.HasEntities() {
 		return nil
@@ -518,7 +662,7 @@ func (svc *InsightsService) findEntities(id string, opts InsightsListEntitiesOpt
 	if err != nil {
 		return nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionViewer); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityPreview); err != nil {
 		return nil, err
 	}
 	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
@@ -613,3 +757,411 @@ func (svc *InsightsService) getPreviousSnapshot(fileID string, version int64) (m
 	}
 	return nil, nil
 }
+
+const (
+	InsightsPIITypeEmail        = "email"
+	InsightsPIITypePhone        = "phone"
+	InsightsPIITypeIBAN         = "iban"
+	InsightsPIITypeNationalID   = "national_id"
+	InsightsPIITypePerson       = "person"
+	InsightsPIITypeOrganization = "organization"
+	InsightsPIITypeLocation     = "location"
+)
+
+var insightsPIIPatterns = []struct {
+	Type  string
+	Regex *regexp.Regexp
+}{
+	{Type: InsightsPIITypeEmail, Regex: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{Type: InsightsPIITypeIBAN, Regex: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`)},
+	// US social security numbers and UK national insurance numbers
+	{Type: InsightsPIITypeNationalID, Regex: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{Type: InsightsPIITypeNationalID, Regex: regexp.MustCompile(`\b[A-CEGHJ-PR-TW-Z]{2} ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`)},
+	{Type: InsightsPIITypePhone, Regex: regexp.MustCompile(`\+?\(?\d[\d ().-]{6,}\d`)},
+}
+
+// insightsPIIEntityLabels maps the NER labels of the language service to PII types,
+// the labels differ between the spaCy models of each language.
+var insightsPIIEntityLabels = map[string]string{
+	"PERSON": InsightsPIITypePerson,
+	"PER":    InsightsPIITypePerson,
+	"ORG":    InsightsPIITypeOrganization,
+	"GPE":    InsightsPIITypeLocation,
+	"LOC":    InsightsPIITypeLocation,
+}
+
+type InsightsPIIFinding struct {
+	ID         string               `json:"id"`
+	Type       string               `json:"type"`
+	Text       string               `json:"text"`
+	Page       int                  `json:"page"`
+	PageWidth  float64              `json:"pageWidth"`
+	PageHeight float64              `json:"pageHeight"`
+	Boxes      []*FileTextSearchBox `json:"boxes"`
+}
+
+type InsightsPIIList struct {
+	Data          []*InsightsPIIFinding `json:"data"`
+	TotalElements int                   `json:"totalElements"`
+}
+
+func (svc *InsightsService) ListPII(id string, userID string) (*InsightsPIIList, error) {
+	file, err := svc.fileCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityPreview); err != nil {
+		return nil, err
+	}
+	findings, _, err := svc.findPII(file)
+	if err != nil {
+		return nil, err
+	}
+	return &InsightsPIIList{
+		Data:          findings,
+		TotalElements: len(findings),
+	}, nil
+}
+
+type InsightsRedactOptions struct {
+	// FindingIDs and Types select the findings to redact, at least one of them is required.
+	FindingIDs []string `json:"findingIds"`
+	Types      []string `json:"types"`
+}
+
+// Redact creates a sibling PDF file in which the selected findings are burned out.
+func (svc *InsightsService) Redact(id string, opts InsightsRedactOptions, userID string) (*File, error) {
+	file, err := svc.fileCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityRunInsights); err != nil {
+		return nil, err
+	}
+	if len(opts.FindingIDs) == 0 && len(opts.Types) == 0 {
+		return nil, errorpkg.NewNoRedactionsSelectedError()
+	}
+	findings, snapshot, err := svc.findPII(file)
+	if err != nil {
+		return nil, err
+	}
+	isTaskPending, err := svc.snapshotSvc.isTaskPending(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if isTaskPending {
+		return nil, errorpkg.NewSnapshotHasPendingTaskError(nil)
+	}
+	source := svc.getRedactionSource(snapshot)
+	if source == nil {
+		return nil, errorpkg.NewRedactionSourceNotFoundError()
+	}
+	redactions := make([]*InsightsRedaction, 0)
+	for _, finding := range findings {
+		if !slices.Contains(opts.FindingIDs, finding.ID) && !slices.Contains(opts.Types, finding.Type) {
+			continue
+		}
+		for _, box := range finding.Boxes {
+			redactions = append(redactions, &InsightsRedaction{
+				Page:       finding.Page,
+				PageWidth:  finding.PageWidth,
+				PageHeight: finding.PageHeight,
+				XMin:       box.XMin,
+				YMin:       box.YMin,
+				XMax:       box.XMax,
+				YMax:       box.YMax,
+			})
+		}
+	}
+	if len(redactions) == 0 {
+		return nil, errorpkg.NewNoRedactionsSelectedError()
+	}
+	b, err := json.Marshal(redactions)
+	if err != nil {
+		return nil, err
+	}
+	sibling, err := svc.createRedactedFile(file, userID)
+	if err != nil {
+		return nil, err
+	}
+	payload := map[string]string{conversion_client.PayloadRedactions: string(b)}
+	if snapshot.GetLanguage() != nil {
+		payload[conversion_client.PayloadLanguage] = *snapshot.GetLanguage()
+	}
+	if err := svc.runRedactPipeline(sibling, *source, snapshot.GetLanguage(), payload, userID); err != nil {
+		return nil, err
+	}
+	res, err := svc.fileMapper.mapOne(sibling, userID)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+type InsightsRedaction struct {
+	Page       int     `json:"page"`
+	PageWidth  float64 `json:"pageWidth"`
+	PageHeight float64 `json:"pageHeight"`
+	XMin       float64 `json:"xMin"`
+	YMin       float64 `json:"yMin"`
+	XMax       float64 `json:"xMax"`
+	YMax       float64 `json:"yMax"`
+}
+
+func (svc *InsightsService) findPII(file model.File) ([]*InsightsPIIFinding, model.Snapshot, error) {
+	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
+		return nil, nil, errorpkg.NewFileIsNotAFileError(file)
+	}
+	snapshot, err := svc.snapshotCache.Get(*file.GetSnapshotID())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !snapshot.HasLayout() {
+		return nil, nil, errorpkg.NewLayoutNotFoundError(nil)
+	}
+	text, err := svc.s3.GetText(snapshot.GetLayout().Key, snapshot.GetLayout().Bucket, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	var layout model.Layout
+	if err := json.Unmarshal([]byte(text), &layout); err != nil {
+		return nil, nil, err
+	}
+	var entities []*language_client.InsightsEntity
+	if snapshot.HasEntities() {
+		text, err := svc.s3.GetText(snapshot.GetEntities().Key, snapshot.GetEntities().Bucket, minio.GetObjectOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := json.Unmarshal([]byte(text), &entities); err != nil {
+			return nil, nil, err
+		}
+	}
+	res := make([]*InsightsPIIFinding, 0)
+	for _, page := range layout.Pages {
+		res = append(res, svc.findPatternPII(page)...)
+		res = append(res, svc.findEntityPII(page, entities)...)
+	}
+	return res, snapshot, nil
+}
+
+// findPatternPII runs the patterns on the page's text, and maps the matches back to the words.
+func (svc *InsightsService) findPatternPII(page model.LayoutPage) []*InsightsPIIFinding {
+	var builder strings.Builder
+	offsets := make([]int, len(page.Words))
+	for i, word := range page.Words {
+		if i > 0 {
+			builder.WriteString(" ")
+		}
+		offsets[i] = builder.Len()
+		builder.WriteString(word.Text)
+	}
+	text := builder.String()
+	res := make([]*InsightsPIIFinding, 0)
+	taken := make(map[int]bool)
+	for _, pattern := range insightsPIIPatterns {
+		for _, match := range pattern.Regex.FindAllStringIndex(text, -1) {
+			value := text[match[0]:match[1]]
+			if !svc.isValidPII(pattern.Type, value) {
+				continue
+			}
+			start, end := -1, -1
+			for i, offset := range offsets {
+				if offset < match[1] && offset+len(page.Words[i].Text) > match[0] {
+					if start == -1 {
+						start = i
+					}
+					end = i + 1
+				}
+			}
+			// A phone number pattern can match inside an IBAN, the first pattern wins
+			if start == -1 || taken[start] {
+				continue
+			}
+			for i := start; i < end; i++ {
+				taken[i] = true
+			}
+			res = append(res, svc.newPIIFinding(pattern.Type, value, page, start, end))
+		}
+	}
+	return res
+}
+
+func (svc *InsightsService) findEntityPII(page model.LayoutPage, entities []*language_client.InsightsEntity) []*InsightsPIIFinding {
+	res := make([]*InsightsPIIFinding, 0)
+	words := make([]string, len(page.Words))
+	for i, word := range page.Words {
+		words[i] = svc.normalizePII(word.Text)
+	}
+	for _, entity := range entities {
+		piiType, ok := insightsPIIEntityLabels[entity.Label]
+		if !ok {
+			continue
+		}
+		terms := make([]string, 0)
+		for _, field := range strings.Fields(entity.Text) {
+			if term := svc.normalizePII(field); term != "" {
+				terms = append(terms, term)
+			}
+		}
+		if len(terms) == 0 {
+			continue
+		}
+		for i := 0; i+len(terms) <= len(words); i++ {
+			if slices.Equal(words[i:i+len(terms)], terms) {
+				res = append(res, svc.newPIIFinding(piiType, entity.Text, page, i, i+len(terms)))
+			}
+		}
+	}
+	return res
+}
+
+func (svc *InsightsService) newPIIFinding(piiType string, text string, page model.LayoutPage, start int, end int) *InsightsPIIFinding {
+	res := &InsightsPIIFinding{
+		// The ID is stable as long as the layout doesn't change, so it can be used to select findings
+		ID:         fmt.Sprintf("%s-%d-%d-%d", piiType, page.Number, start, end),
+		Type:       piiType,
+		Text:       text,
+		Page:       page.Number,
+		PageWidth:  page.Width,
+		PageHeight: page.Height,
+		Boxes:      make([]*FileTextSearchBox, 0, end-start),
+	}
+	for _, word := range page.Words[start:end] {
+		res.Boxes = append(res.Boxes, &FileTextSearchBox{
+			XMin: word.XMin,
+			YMin: word.YMin,
+			XMax: word.XMax,
+			YMax: word.YMax,
+		})
+	}
+	return res
+}
+
+func (svc *InsightsService) isValidPII(piiType string, value string) bool {
+	switch piiType {
+	case InsightsPIITypePhone:
+		digits := 0
+		for _, r := range value {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		return digits >= 8 && digits <= 15
+	case InsightsPIITypeIBAN:
+		return svc.isValidIBAN(strings.ReplaceAll(value, " ", ""))
+	}
+	return true
+}
+
+// isValidIBAN verifies the ISO 13616 mod 97 checksum.
+func (svc *InsightsService) isValidIBAN(value string) bool {
+	if len(value) < 15 || len(value) > 34 {
+		return false
+	}
+	var digits strings.Builder
+	for _, r := range value[4:] + value[:4] {
+		if r >= 'A' && r <= 'Z' {
+			digits.WriteString(fmt.Sprintf("%d", r-'A'+10))
+		} else {
+			digits.WriteRune(r)
+		}
+	}
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+func (svc *InsightsService) normalizePII(value string) string {
+	return strings.ToLower(strings.TrimFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}))
+}
+
+// getRedactionSource returns the PDF the layout was extracted from.
+func (svc *InsightsService) getRedactionSource(snapshot model.Snapshot) *model.S3Object {
+	if snapshot.HasOCR() {
+		return snapshot.GetOCR()
+	}
+	if snapshot.HasPreview() && svc.fileIdent.IsPDF(snapshot.GetPreview().Key) {
+		return snapshot.GetPreview()
+	}
+	if snapshot.HasOriginal() && svc.fileIdent.IsPDF(snapshot.GetOriginal().Key) {
+		return snapshot.GetOriginal()
+	}
+	return nil
+}
+
+func (svc *InsightsService) createRedactedFile(file model.File, userID string) (model.File, error) {
+	if file.GetParentID() == nil {
+		return nil, errorpkg.NewFileIsNotAFileError(file)
+	}
+	base := strings.TrimSuffix(file.GetName(), filepath.Ext(file.GetName()))
+	name := base + " (redacted).pdf"
+	for i := 2; ; i++ {
+		existing, err := svc.fileCreate.fileCoreSvc.getChildWithName(*file.GetParentID(), name)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			break
+		}
+		name = fmt.Sprintf("%s (redacted %d).pdf", base, i)
+	}
+	created, err := svc.fileCreate.performCreate(FileCreateOptions{
+		WorkspaceID: file.GetWorkspaceID(),
+		Name:        name,
+		Type:        model.FileTypeFile,
+		ParentID:    *file.GetParentID(),
+	}, true, userID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.fileCache.Get(created.ID)
+}
+
+func (svc *InsightsService) runRedactPipeline(file model.File, source model.S3Object, language *string, payload map[string]string, userID string) error {
+	workspace, err := svc.workspaceCache.Get(file.GetWorkspaceID())
+	if err != nil {
+		return err
+	}
+	snapshotID := helper.NewID()
+	snapshot, err := svc.fileStore.createSnapshot(file, fileStoreProperties{
+		SnapshotID: snapshotID,
+		Original: model.S3Object{
+			Bucket: workspace.GetBucket(),
+			Key:    snapshotID + "/original.pdf",
+		},
+		Bucket: workspace.GetBucket(),
+	}, userID)
+	if err != nil {
+		return err
+	}
+	if err := svc.fileStore.assignSnapshotToFile(file, snapshot); err != nil {
+		return err
+	}
+	task, err := svc.fileStore.createTask(file, userID)
+	if err != nil {
+		return err
+	}
+	snapshot.SetTaskID(helper.ToPtr(task.GetID()))
+	if language != nil {
+		snapshot.SetLanguage(*language)
+	}
+	if err := svc.snapshotSvc.saveAndSync(snapshot); err != nil {
+		return err
+	}
+	if err := svc.pipelineClient.Run(&conversion_client.PipelineRunOptions{
+		PipelineID: helper.ToPtr(conversion_client.PipelineRedact),
+		TaskID:     task.GetID(),
+		SnapshotID: snapshot.GetID(),
+		Bucket:     source.Bucket,
+		Key:        source.Key,
+		Payload:    payload,
+	}); err != nil {
+		return err
+	}
+	return nil
+}
@@ -11,15 +11,23 @@
 package service
 
 import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/go-playground/validator/v10"
+
 	"github.com/kouprlabs/voltaserve/api/cache"
 	"github.com/kouprlabs/voltaserve/api/config"
 	"github.com/kouprlabs/voltaserve/api/errorpkg"
 	"github.com/kouprlabs/voltaserve/api/guard"
+	"github.com/kouprlabs/voltaserve/api/helper"
 	"github.com/kouprlabs/voltaserve/api/infra"
+	"github.com/kouprlabs/voltaserve/api/log"
 	"github.com/kouprlabs/voltaserve/api/model"
 	"github.com/kouprlabs/voltaserve/api/repo"
 )
@@ -31,6 +39,8 @@ type InvitationService struct {
 	invitationMapper *invitationMapper
 	orgCache         *cache.OrganizationCache
 	orgGuard         *guard.OrganizationGuard
+	groupRepo        *repo.GroupRepo
+	groupCache       *cache.GroupCache
 	userRepo         *repo.UserRepo
 	mailTmpl         infra.MailTemplate
 	config           *config.Config
@@ -43,6 +53,8 @@ func NewInvitationService() *InvitationService {
 		orgGuard:         guard.NewOrganizationGuard(),
 		invitationRepo:   repo.NewInvitationRepo(),
 		invitationMapper: newInvitationMapper(),
+		groupRepo:        repo.NewGroupRepo(),
+		groupCache:       cache.NewGroupCache(),
 		userRepo:         repo.NewUserRepo(),
 		mailTmpl:         infra.NewMailTemplate(config.GetConfig().SMTP),
 		orgMapper:        newOrganizationMapper(),
@@ -56,6 +68,9 @@ type Invitation struct {
 	Email        string        `json:"email"`
 	Organization *Organization `json:"organization,omitempty"`
 	Status       string        `json:"status"`
+	Role         string        `json:"role"`
+	GroupIDs     []string      `json:"groupIds,omitempty"`
+	ExpiresAt    *string       `json:"expiresAt,omitempty"`
 	CreateTime   string        `json:"createTime"`
 	UpdateTime   *string       `json:"updateTime"`
 }
@@ -71,7 +86,25 @@ const (
 	InvitationSortOrderDesc = "desc"
 )
 
+// invitationImportMaxRows keeps a bulk import within what we can email in a single request.
+const invitationImportMaxRows = 1000
+
+const (
+	InvitationImportStatusInvited  = "invited"
+	InvitationImportStatusInvalid  = "invalid"
+	InvitationImportStatusExisting = "existing"
+)
+
+// InvitationOptions are applied when the invitation is accepted, the role defaults
+// to guest and the expiry to the configured amount of hours.
+type InvitationOptions struct {
+	Role      string   `json:"role,omitempty"      validate:"omitempty,oneof=admin member guest"`
+	GroupIDs  []string `json:"groupIds,omitempty"`
+	ExpiresAt *string  `json:"expiresAt,omitempty"`
+}
+
 type InvitationCreateOptions struct {
+	InvitationOptions
 	OrganizationID string   `json:"organizationId" validate:"required"`
 	Emails         []string `json:"emails"         validate:"required,dive,email"`
 }
@@ -87,6 +120,10 @@ func (svc *InvitationService) Create(opts InvitationCreateOptions, userID string
 	if err := svc.orgGuard.Authorize(userID, org, model.PermissionOwner); err != nil {
 		return nil, err
 	}
+	insertOpts, err := svc.newInsertOptions(org, opts.InvitationOptions, userID)
+	if err != nil {
+		return nil, err
+	}
 	orgMembers, err := svc.orgRepo.FindMembers(opts.OrganizationID)
 	if err != nil {
 		return nil, err
@@ -95,12 +132,8 @@ func (svc *InvitationService) Create(opts InvitationCreateOptions, userID string
 	if err != nil {
 		return nil, err
 	}
-	emails := svc.getEmailsFromNonMembersAndOutgoing(opts.Emails, orgMembers, outgoing)
-	invitations, err := svc.invitationRepo.Insert(repo.InvitationInsertOptions{
-		UserID:         userID,
-		OrganizationID: opts.OrganizationID,
-		Emails:         emails,
-	})
+	insertOpts.Emails = svc.getEmailsFromNonMembersAndOutgoing(opts.Emails, orgMembers, outgoing)
+	invitations, err := svc.invitationRepo.Insert(*insertOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -114,6 +147,135 @@ func (svc *InvitationService) Create(opts InvitationCreateOptions, userID string
 	return res, nil
 }
 
+type InvitationImportResult struct {
+	Row        int     `json:"row"`
+	Email      string  `json:"email"`
+	Status     string  `json:"status"`
+	Invitation *string `json:"invitationId,omitempty"`
+}
+
+// Import invites the addresses found in the first column of a CSV, an optional second column
+// overrides the role. Rows are reported one by one rather than failing the whole import.
+func (svc *InvitationService) Import(orgID string, r io.Reader, opts InvitationOptions, userID string) ([]*InvitationImportResult, error) {
+	org, err := svc.orgCache.Get(orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.orgGuard.Authorize(userID, org, model.PermissionOwner); err != nil {
+		return nil, err
+	}
+	insertOpts, err := svc.newInsertOptions(org, opts, userID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := svc.readImportRows(r)
+	if err != nil {
+		return nil, err
+	}
+	orgMembers, err := svc.orgRepo.FindMembers(orgID)
+	if err != nil {
+		return nil, err
+	}
+	outgoing, err := svc.invitationRepo.FindOutgoing(orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*InvitationImportResult, 0, len(rows))
+	var invitations []model.Invitation
+	seen := make(map[string]bool)
+	validate := validator.New()
+	for i, row := range rows {
+		result := &InvitationImportResult{
+			Row:   i + 1,
+			Email: strings.ToLower(strings.TrimSpace(row[0])),
+		}
+		res = append(res, result)
+		role := insertOpts.Role
+		if len(row) > 1 && strings.TrimSpace(row[1]) != "" {
+			role = strings.ToLower(strings.TrimSpace(row[1]))
+		}
+		if validate.Var(result.Email, "required,email") != nil || !model.IsValidOrganizationRole(role) {
+			result.Status = InvitationImportStatusInvalid
+			continue
+		}
+		if seen[result.Email] || len(svc.getEmailsFromNonMembersAndOutgoing([]string{result.Email}, orgMembers, outgoing)) == 0 {
+			result.Status = InvitationImportStatusExisting
+			continue
+		}
+		seen[result.Email] = true
+		rowOpts := *insertOpts
+		rowOpts.Emails = []string{result.Email}
+		rowOpts.Role = role
+		inserted, err := svc.invitationRepo.Insert(rowOpts)
+		if err != nil {
+			return nil, err
+		}
+		result.Status = InvitationImportStatusInvited
+		result.Invitation = helper.ToPtr(inserted[0].GetID())
+		invitations = append(invitations, inserted...)
+	}
+	if err := svc.sendEmails(invitations, org, userID); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// readImportRows skips empty lines and a leading header row.
+func (svc *InvitationService) readImportRows(r io.Reader) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	var res [][]string
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, errorpkg.NewInvalidInvitationImportError(err)
+		}
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		if len(res) == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "email") {
+			continue
+		}
+		if len(res) == invitationImportMaxRows {
+			return nil, errorpkg.NewInvitationImportTooLargeError(invitationImportMaxRows)
+		}
+		res = append(res, record)
+	}
+	return res, nil
+}
+
+// RevokePending deletes all pending invitations of the organization, regardless of who sent them.
+func (svc *InvitationService) RevokePending(orgID string, userID string) error {
+	org, err := svc.orgCache.Get(orgID)
+	if err != nil {
+		return err
+	}
+	if err := svc.orgGuard.Authorize(userID, org, model.PermissionOwner); err != nil {
+		return err
+	}
+	if _, err := svc.invitationRepo.DeletePending(orgID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Start expires invitations periodically, it blocks so it should run in its own goroutine.
+func (svc *InvitationService) Start() {
+	if svc.config.Invitations.SweepIntervalSeconds <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(svc.config.Invitations.SweepIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := svc.invitationRepo.Expire(); err != nil {
+			log.GetLogger().Error(err)
+		}
+	}
+}
+
 type InvitationList struct {
 	Data          []*Invitation `json:"data"`
 	TotalPages    uint64        `json:"totalPages"`
@@ -244,6 +406,9 @@ func (svc *InvitationService) Accept(id string, userID string) error {
 	if !strings.EqualFold(user.GetEmail(), invitation.GetEmail()) {
 		return errorpkg.NewUserNotAllowedToAcceptInvitationError(user, invitation)
 	}
+	if svc.isExpired(invitation) {
+		return errorpkg.NewInvitationExpiredError(invitation)
+	}
 	org, err := svc.orgCache.Get(invitation.GetOrganizationID())
 	if err != nil {
 		return err
@@ -257,12 +422,39 @@ func (svc *InvitationService) Accept(id string, userID string) error {
 	if err := svc.invitationRepo.Save(invitation); err != nil {
 		return err
 	}
-	if err := svc.orgRepo.GrantUserPermission(invitation.GetOrganizationID(), userID, model.PermissionViewer); err != nil {
+	permission := model.GetOrganizationRolePermission(invitation.GetRole())
+	if permission == "" {
+		permission = model.PermissionViewer
+	}
+	if err := svc.orgRepo.GrantUserPermission(invitation.GetOrganizationID(), userID, permission); err != nil {
 		return err
 	}
 	if _, err := svc.orgCache.Refresh(invitation.GetOrganizationID()); err != nil {
 		return err
 	}
+	return svc.joinGroups(invitation, userID)
+}
+
+// joinGroups skips the groups which were deleted or moved since the invitation was sent.
+func (svc *InvitationService) joinGroups(invitation model.Invitation, userID string) error {
+	for _, groupID := range invitation.GetGroupIDs() {
+		group, err := svc.groupCache.Get(groupID)
+		if err != nil {
+			log.GetLogger().Error(err)
+			continue
+		}
+		if group.GetOrganizationID() != invitation.GetOrganizationID() {
+			continue
+		}
+		if err := svc.groupRepo.GrantUserPermission(group.GetID(), userID, model.PermissionViewer, repo.PermissionGrantOptions{
+			GrantedBy: helper.ToPtr(invitation.GetOwnerID()),
+		}); err != nil {
+			return err
+		}
+		if err := svc.groupCache.RefreshMembership(group.GetID()); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -300,6 +492,9 @@ func (svc *InvitationService) Resend(id string, userID string) error {
 	if invitation.GetStatus() != model.InvitationStatusPending {
 		return errorpkg.NewCannotResendNonPendingInvitationError(invitation)
 	}
+	if svc.isExpired(invitation) {
+		return errorpkg.NewInvitationExpiredError(invitation)
+	}
 	org, err := svc.orgCache.Get(invitation.GetOrganizationID())
 	if err != nil {
 		return err
@@ -351,6 +546,54 @@ func (svc *InvitationService) IsValidSortOrder(value string) bool {
 	return value == "" || value == InvitationSortOrderAsc || value == InvitationSortOrderDesc
 }
 
+func (svc *InvitationService) newInsertOptions(org model.Organization, opts InvitationOptions, userID string) (*repo.InvitationInsertOptions, error) {
+	res := &repo.InvitationInsertOptions{
+		UserID:         userID,
+		OrganizationID: org.GetID(),
+		Role:           opts.Role,
+	}
+	if res.Role == "" {
+		res.Role = model.OrganizationRoleGuest
+	}
+	for _, groupID := range opts.GroupIDs {
+		group, err := svc.groupCache.Get(groupID)
+		if err != nil {
+			return nil, err
+		}
+		if group.GetOrganizationID() != org.GetID() {
+			return nil, errorpkg.NewGroupNotInOrganizationError(group, org)
+		}
+		if !slices.Contains(res.GroupIDs, groupID) {
+			res.GroupIDs = append(res.GroupIDs, groupID)
+		}
+	}
+	if opts.ExpiresAt != nil {
+		t, err := time.Parse(time.RFC3339, *opts.ExpiresAt)
+		if err != nil || !t.After(time.Now()) {
+			return nil, errorpkg.NewInvalidInvitationExpiryError()
+		}
+		res.ExpiresAt = helper.ToPtr(t.UTC().Format(time.RFC3339))
+	} else if svc.config.Invitations.ExpiryHours > 0 {
+		res.ExpiresAt = helper.ToPtr(time.Now().
+			Add(time.Duration(svc.config.Invitations.ExpiryHours) * time.Hour).
+			UTC().
+			Format(time.RFC3339))
+	}
+	return res, nil
+}
+
+// isExpired doesn't wait for the sweeper, and marks the invitation as expired on the spot.
+func (svc *InvitationService) isExpired(invitation model.Invitation) bool {
+	if invitation.GetExpiresAt() == nil || *invitation.GetExpiresAt() > helper.NewTimestamp() {
+		return false
+	}
+	invitation.SetStatus(model.InvitationStatusExpired)
+	if err := svc.invitationRepo.Save(invitation); err != nil {
+		log.GetLogger().Error(err)
+	}
+	return true
+}
+
 func (svc *InvitationService) getEmailsFromNonMembersAndOutgoing(emails []string, orgMembers []model.User, outgoing []model.Invitation) []string {
 	var res []string
 	for _, email := range emails {
@@ -488,6 +731,9 @@ func (mp *invitationMapper) mapOne(m model.Invitation, userID string) (*Invitati
 		Email:        m.GetEmail(),
 		Organization: o,
 		Status:       m.GetStatus(),
+		Role:         m.GetRole(),
+		GroupIDs:     m.GetGroupIDs(),
+		ExpiresAt:    m.GetExpiresAt(),
 		CreateTime:   m.GetCreateTime(),
 		UpdateTime:   m.GetUpdateTime(),
 	}, nil
@@ -61,7 +61,7 @@ func (svc *MosaicService) Create(id string, userID string) (*Task, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionEditor); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityCreateMosaic); err != nil {
 		return nil, err
 	}
 	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
@@ -102,7 +102,10 @@ func (svc *MosaicService) Delete(id string, userID string) (*Task, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionOwner); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityCreateMosaic); err != nil {
+		return nil, err
+	}
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityDelete); err != nil {
 		return nil, err
 	}
 	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
@@ -152,7 +155,7 @@ func (svc *MosaicService) ReadInfo(id string, userID string) (*MosaicInfo, error
 	if err != nil {
 		return nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionViewer); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityPreview); err != nil {
 		return nil, err
 	}
 	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
@@ -202,7 +205,7 @@ func (svc *MosaicService) DownloadTileBuffer(id string, opts MosaicDownloadTileO
 	if err != nil {
 		return nil, nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionViewer); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityPreview); err != nil {
 		return nil, nil, err
 	}
 	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
@@ -12,6 +12,7 @@ package service
 
 import (
 	"errors"
+	"slices"
 	"sort"
 	"time"
 
@@ -42,6 +43,15 @@ type OrganizationService struct {
 	groupMapper    *groupMapper
 	workspaceCache *cache.WorkspaceCache
 	workspaceRepo  *repo.WorkspaceRepo
+	workspaceGuard *guard.WorkspaceGuard
+	fileCache      *cache.FileCache
+	fileAccess     *fileAccess
+	permissionRepo *repo.PermissionRepo
+	invitationRepo *repo.InvitationRepo
+	taskRepo       *repo.TaskRepo
+	taskSvc        *TaskService
+	taskMapper     *taskMapper
+	storageRepo    *repo.StorageRepo
 	config         *config.Config
 }
 
@@ -61,17 +71,29 @@ func NewOrganizationService() *OrganizationService {
 		userMapper:     newUserMapper(),
 		workspaceCache: cache.NewWorkspaceCache(),
 		workspaceRepo:  repo.NewWorkspaceRepo(),
+		workspaceGuard: guard.NewWorkspaceGuard(),
+		fileCache:      cache.NewFileCache(),
+		fileAccess:     newFileAccess(),
+		permissionRepo: repo.NewPermissionRepo(),
+		invitationRepo: repo.NewInvitationRepo(),
+		taskRepo:       repo.NewTaskRepo(),
+		taskSvc:        NewTaskService(),
+		taskMapper:     newTaskMapper(),
+		storageRepo:    repo.NewStorageRepo(),
 		config:         config.GetConfig(),
 	}
 }
 
 type Organization struct {
-	ID         string  `json:"id"`
-	Name       string  `json:"name"`
-	Image      *string `json:"image,omitempty"`
-	Permission string  `json:"permission"`
-	CreateTime string  `json:"createTime"`
-	UpdateTime *string `json:"updateTime,omitempty"`
+	ID                    string  `json:"id"`
+	Name                  string  `json:"name"`
+	Image                 *string `json:"image,omitempty"`
+	Permission            string  `json:"permission"`
+	Role                  string  `json:"role,omitempty"`
+	StorageCapacity       *int64  `json:"storageCapacity,omitempty"`
+	MemberStorageCapacity *int64  `json:"memberStorageCapacity,omitempty"`
+	CreateTime            string  `json:"createTime"`
+	UpdateTime            *string `json:"updateTime,omitempty"`
 }
 
 const (
@@ -143,6 +165,93 @@ func (svc *OrganizationService) Find(id string, userID string) (*Organization, e
 	return res, nil
 }
 
+type WorkspaceAccess struct {
+	WorkspaceID   string                 `json:"workspaceId"`
+	WorkspaceName string                 `json:"workspaceName"`
+	Users         []*WorkspaceAccessUser `json:"users"`
+}
+
+// WorkspaceAccessUser is a user having access to the workspace, the permission and grants
+// are the ones on its root folder. IsPartial means the user only reaches the files that
+// were shared with them.
+type WorkspaceAccessUser struct {
+	User       *User              `json:"user"`
+	Permission string             `json:"permission"`
+	IsPartial  bool               `json:"isPartial"`
+	Grants     []*FileAccessGrant `json:"grants"`
+}
+
+// FindWorkspaceAccess reports who has access to the workspaces of the organization,
+// or only to the given workspace if workspaceID isn't empty.
+func (svc *OrganizationService) FindWorkspaceAccess(id string, workspaceID string, userID string) ([]*WorkspaceAccess, error) {
+	org, err := svc.orgCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.orgGuard.Authorize(userID, org, model.PermissionOwner); err != nil {
+		return nil, err
+	}
+	workspaceIDs, err := svc.workspaceRepo.FindIDsByOrganization(org.GetID())
+	if err != nil {
+		return nil, err
+	}
+	if workspaceID != "" {
+		if !slices.Contains(workspaceIDs, workspaceID) {
+			return nil, errorpkg.NewWorkspaceNotFoundError(nil)
+		}
+		workspaceIDs = []string{workspaceID}
+	}
+	res := make([]*WorkspaceAccess, 0)
+	for i := range workspaceIDs {
+		workspace, err := svc.workspaceCache.Get(workspaceIDs[i])
+		if err != nil {
+			return nil, err
+		}
+		access, err := svc.findWorkspaceAccess(workspace, org, userID)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, access)
+	}
+	return res, nil
+}
+
+func (svc *OrganizationService) findWorkspaceAccess(workspace model.Workspace, org model.Organization, userID string) (*WorkspaceAccess, error) {
+	root, err := svc.fileCache.Get(workspace.GetRootID())
+	if err != nil {
+		return nil, err
+	}
+	candidates := slices.Clone(org.GetMembers())
+	for _, p := range workspace.GetUserPermissions() {
+		if !slices.Contains(candidates, p.GetUserID()) {
+			candidates = append(candidates, p.GetUserID())
+		}
+	}
+	res := &WorkspaceAccess{
+		WorkspaceID:   workspace.GetID(),
+		WorkspaceName: workspace.GetName(),
+		Users:         make([]*WorkspaceAccessUser, 0),
+	}
+	for _, candidate := range candidates {
+		access, err := svc.fileAccess.explain(root, candidate, userID)
+		if err != nil {
+			return nil, err
+		}
+		isPartial := access.Permission == model.PermissionNone &&
+			svc.workspaceGuard.IsAuthorized(candidate, workspace, model.PermissionViewer)
+		if access.Permission == model.PermissionNone && !isPartial {
+			continue
+		}
+		res.Users = append(res.Users, &WorkspaceAccessUser{
+			User:       access.User,
+			Permission: access.Permission,
+			IsPartial:  isPartial,
+			Grants:     access.Grants,
+		})
+	}
+	return res, nil
+}
+
 type OrganizationListOptions struct {
 	Query     string
 	Page      uint64
@@ -211,6 +320,44 @@ func (svc *OrganizationService) PatchName(id string, name string, userID string)
 	return res, nil
 }
 
+// OrganizationStorageQuotaOptions replaces both quotas, a nil value lifts the quota.
+type OrganizationStorageQuotaOptions struct {
+	StorageCapacity       *int64 `json:"storageCapacity"       validate:"omitempty,min=1"`
+	MemberStorageCapacity *int64 `json:"memberStorageCapacity" validate:"omitempty,min=1"`
+}
+
+func (svc *OrganizationService) PatchStorageQuota(id string, opts OrganizationStorageQuotaOptions, userID string) (*Organization, error) {
+	org, err := svc.orgCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.orgGuard.Authorize(userID, org, model.PermissionOwner); err != nil {
+		return nil, err
+	}
+	if opts.StorageCapacity != nil {
+		usage, err := svc.storageRepo.SumOrganizationUsage(org.GetID())
+		if err != nil {
+			return nil, err
+		}
+		if *opts.StorageCapacity < usage {
+			return nil, errorpkg.NewInsufficientStorageCapacityError()
+		}
+	}
+	org.SetStorageCapacity(opts.StorageCapacity)
+	org.SetMemberStorageCapacity(opts.MemberStorageCapacity)
+	if err := svc.orgRepo.Save(org); err != nil {
+		return nil, err
+	}
+	if err := svc.sync(org); err != nil {
+		return nil, err
+	}
+	res, err := svc.orgMapper.mapOne(org, userID)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func (svc *OrganizationService) Delete(id string, userID string) error {
 	org, err := svc.orgCache.Get(id)
 	if err != nil {
@@ -239,9 +386,15 @@ func (svc *OrganizationService) RemoveMember(id string, memberID string, userID
 	if err := svc.checkUserCanRemoveMember(memberID, org, userID); err != nil {
 		return err
 	}
+	if err := svc.checkMemberOwnsNothingAlone(memberID, org); err != nil {
+		return err
+	}
 	if err := svc.revokeGroupPermissions(memberID, org); err != nil {
 		return err
 	}
+	if err := svc.revokeFilePermissions(memberID, org); err != nil {
+		return err
+	}
 	if err := svc.revokeWorkspacePermissions(memberID, org); err != nil {
 		return err
 	}
@@ -254,6 +407,202 @@ func (svc *OrganizationService) RemoveMember(id string, memberID string, userID
 	return nil
 }
 
+type OwnedResource struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	IsSoleOwner bool   `json:"isSoleOwner"`
+}
+
+type OrganizationOwnership struct {
+	Workspaces     []*OwnedResource `json:"workspaces"`
+	Groups         []*OwnedResource `json:"groups"`
+	Files          []*OwnedResource `json:"files"`
+	Invitations    int64            `json:"invitations"`
+	Tasks          int64            `json:"tasks"`
+	SoleOwnerCount int              `json:"soleOwnerCount"`
+}
+
+// FindOwnership previews what a member owns across the organization, so it can be
+// transferred before the member is removed. Only the tasks processing the files of
+// the organization are counted, as they are the ones transferred.
+func (svc *OrganizationService) FindOwnership(id string, memberID string, userID string) (*OrganizationOwnership, error) {
+	org, err := svc.orgCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if memberID != userID {
+		if err := svc.orgGuard.Authorize(userID, org, model.PermissionOwner); err != nil {
+			return nil, err
+		}
+	}
+	if !slices.Contains(org.GetMembers(), memberID) {
+		return nil, errorpkg.NewUserNotMemberOfOrganizationError()
+	}
+	owned, err := svc.permissionRepo.FindOwnedResources(org.GetID(), memberID)
+	if err != nil {
+		return nil, err
+	}
+	res := &OrganizationOwnership{
+		Workspaces: make([]*OwnedResource, 0),
+		Groups:     make([]*OwnedResource, 0),
+		Files:      make([]*OwnedResource, 0),
+	}
+	for _, o := range owned {
+		r := &OwnedResource{ID: o.ResourceID, IsSoleOwner: o.IsSoleOwner}
+		switch o.ResourceType {
+		case permissionResourceWorkspace:
+			if workspace, err := svc.workspaceCache.Get(o.ResourceID); err == nil {
+				r.Name = workspace.GetName()
+			}
+			res.Workspaces = append(res.Workspaces, r)
+		case permissionResourceGroup:
+			if group, err := svc.groupCache.Get(o.ResourceID); err == nil {
+				r.Name = group.GetName()
+			}
+			res.Groups = append(res.Groups, r)
+		case permissionResourceFile:
+			if file, err := svc.fileCache.Get(o.ResourceID); err == nil {
+				r.Name = file.GetName()
+			}
+			res.Files = append(res.Files, r)
+		}
+		if o.IsSoleOwner {
+			res.SoleOwnerCount++
+		}
+	}
+	if res.Invitations, err = svc.invitationRepo.CountPendingOutgoing(org.GetID(), memberID); err != nil {
+		return nil, err
+	}
+	if res.Tasks, err = svc.taskRepo.CountPendingInOrganization(org.GetID(), memberID); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// OrganizationTransferOwnershipOptions describes a transfer, the member stays an owner
+// alongside the target unless DemoteMember is set, then they become an editor.
+type OrganizationTransferOwnershipOptions struct {
+	UserID       string `json:"userId"       validate:"required"`
+	TargetUserID string `json:"targetUserId" validate:"required"`
+	DemoteMember bool   `json:"demoteMember"`
+}
+
+// TransferOwnership hands the workspaces, groups, files, pending invitations and pending tasks of a
+// member over to another member. It runs in the background, and the returned task tracks its progress.
+func (svc *OrganizationService) TransferOwnership(id string, opts OrganizationTransferOwnershipOptions, userID string) (*Task, error) {
+	org, err := svc.orgCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.orgGuard.Authorize(userID, org, model.PermissionOwner); err != nil {
+		return nil, err
+	}
+	if opts.UserID == opts.TargetUserID {
+		return nil, errorpkg.NewInvalidOwnershipTransferTargetError()
+	}
+	if !slices.Contains(org.GetMembers(), opts.UserID) || !slices.Contains(org.GetMembers(), opts.TargetUserID) {
+		return nil, errorpkg.NewUserNotMemberOfOrganizationError()
+	}
+	member, err := svc.userRepo.Find(opts.UserID)
+	if err != nil {
+		return nil, err
+	}
+	task, err := svc.taskSvc.insertAndSync(repo.TaskInsertOptions{
+		ID:              helper.NewID(),
+		Name:            "Transferring ownership.",
+		UserID:          userID,
+		IsIndeterminate: true,
+		Status:          model.TaskStatusRunning,
+		Payload:         map[string]string{repo.TaskPayloadObjectKey: member.GetFullName()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	go func(task model.Task) {
+		if err := svc.transferOwnership(org, opts); err != nil {
+			value := err.Error()
+			task.SetError(&value)
+			task.SetStatus(model.TaskStatusError)
+			if err := svc.taskSvc.saveAndSync(task); err != nil {
+				log.GetLogger().Error(err)
+			}
+		} else {
+			if err := svc.taskSvc.deleteAndSync(task.GetID()); err != nil {
+				log.GetLogger().Error(err)
+			}
+		}
+	}(task)
+	return svc.taskMapper.mapOne(task)
+}
+
+func (svc *OrganizationService) transferOwnership(org model.Organization, opts OrganizationTransferOwnershipOptions) error {
+	transferred, err := svc.permissionRepo.TransferOwnership(org.GetID(), opts.UserID, opts.TargetUserID, opts.DemoteMember)
+	if err != nil {
+		return err
+	}
+	for _, t := range transferred {
+		switch t.ResourceType {
+		case permissionResourceWorkspace:
+			_, err = svc.workspaceCache.Refresh(t.ResourceID)
+		case permissionResourceGroup:
+			err = svc.groupCache.RefreshMembership(t.ResourceID)
+		case permissionResourceFile:
+			_, err = svc.fileCache.Refresh(t.ResourceID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := svc.invitationRepo.TransferPending(org.GetID(), opts.UserID, opts.TargetUserID); err != nil {
+		return err
+	}
+	taskIDs, err := svc.taskRepo.TransferPendingInOrganization(org.GetID(), opts.UserID, opts.TargetUserID)
+	if err != nil {
+		return err
+	}
+	for _, taskID := range taskIDs {
+		task, err := svc.taskRepo.Find(taskID)
+		if err != nil {
+			return err
+		}
+		if err := svc.taskSvc.saveAndSync(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PatchMemberRole changes the role of a member, only admins can do it and the
+// organization must keep at least one admin.
+func (svc *OrganizationService) PatchMemberRole(id string, memberID string, role string, userID string) error {
+	org, err := svc.orgCache.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := svc.orgGuard.Authorize(userID, org, model.GetOrganizationRolePermission(model.OrganizationRoleAdmin)); err != nil {
+		return err
+	}
+	if !slices.Contains(org.GetMembers(), memberID) {
+		return errorpkg.NewUserNotMemberOfOrganizationError()
+	}
+	if role != model.OrganizationRoleAdmin && svc.orgGuard.IsAdmin(memberID, org.GetID()) {
+		ownerCount, err := svc.orgRepo.CountOwners(org.GetID())
+		if err != nil {
+			return err
+		}
+		if ownerCount == 1 {
+			return errorpkg.NewCannotDemoteSoleAdminOfOrganizationError(org)
+		}
+	}
+	if err := svc.orgRepo.GrantUserPermission(org.GetID(), memberID, model.GetOrganizationRolePermission(role)); err != nil {
+		return err
+	}
+	if _, err := svc.orgCache.Refresh(org.GetID()); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (svc *OrganizationService) IsValidSortBy(value string) bool {
 	return value == "" ||
 		value == OrganizationSortByName ||
@@ -325,6 +674,25 @@ func (svc *OrganizationService) checkUserCanRemoveMember(memberID string, org mo
 	return nil
 }
 
+// checkMemberOwnsNothingAlone prevents leaving resources without an owner, their ownership
+// has to be transferred before the member can be removed.
+func (svc *OrganizationService) checkMemberOwnsNothingAlone(memberID string, org model.Organization) error {
+	owned, err := svc.permissionRepo.FindOwnedResources(org.GetID(), memberID)
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, o := range owned {
+		if o.IsSoleOwner {
+			count++
+		}
+	}
+	if count > 0 {
+		return errorpkg.NewMemberStillOwnsResourcesError(org, count)
+	}
+	return nil
+}
+
 func (svc *OrganizationService) revokeGroupPermissions(memberID string, org model.Organization) error {
 	groupsIDs, err := svc.groupRepo.FindIDsByOrganization(org.GetID())
 	if err != nil {
@@ -334,7 +702,20 @@ func (svc *OrganizationService) revokeGroupPermissions(memberID string, org mode
 		if err := svc.groupRepo.RevokeUserPermission(groupID, memberID); err != nil {
 			log.GetLogger().Error(err)
 		}
-		if _, err := svc.groupCache.Refresh(groupID); err != nil {
+		if err := svc.groupCache.RefreshMembership(groupID); err != nil {
+			log.GetLogger().Error(err)
+		}
+	}
+	return nil
+}
+
+func (svc *OrganizationService) revokeFilePermissions(memberID string, org model.Organization) error {
+	fileIDs, err := svc.permissionRepo.RevokeUserFilePermissions(org.GetID(), memberID)
+	if err != nil {
+		return err
+	}
+	for _, fileID := range fileIDs {
+		if _, err := svc.fileCache.Refresh(fileID); err != nil {
 			log.GetLogger().Error(err)
 		}
 	}
@@ -463,10 +844,12 @@ func newOrganizationMapper() *organizationMapper {
 
 func (mp *organizationMapper) mapOne(m model.Organization, userID string) (*Organization, error) {
 	res := &Organization{
-		ID:         m.GetID(),
-		Name:       m.GetName(),
-		CreateTime: m.GetCreateTime(),
-		UpdateTime: m.GetUpdateTime(),
+		ID:                    m.GetID(),
+		Name:                  m.GetName(),
+		StorageCapacity:       m.GetStorageCapacity(),
+		MemberStorageCapacity: m.GetMemberStorageCapacity(),
+		CreateTime:            m.GetCreateTime(),
+		UpdateTime:            m.GetUpdateTime(),
 	}
 	res.Permission = model.PermissionNone
 	for _, p := range m.GetUserPermissions() {
@@ -485,6 +868,7 @@ func (mp *organizationMapper) mapOne(m model.Organization, userID string) (*Orga
 			}
 		}
 	}
+	res.Role = model.GetOrganizationRole(res.Permission)
 	return res, nil
 }
 
@@ -0,0 +1,321 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kouprlabs/voltaserve/api/cache"
+	"github.com/kouprlabs/voltaserve/api/config"
+	"github.com/kouprlabs/voltaserve/api/errorpkg"
+	"github.com/kouprlabs/voltaserve/api/helper"
+	"github.com/kouprlabs/voltaserve/api/infra"
+	"github.com/kouprlabs/voltaserve/api/log"
+	"github.com/kouprlabs/voltaserve/api/model"
+	"github.com/kouprlabs/voltaserve/api/repo"
+)
+
+// PermissionSchedule limits a grant in time, both bounds are optional
+// and given in RFC 3339 format.
+type PermissionSchedule struct {
+	StartsAt  *string `json:"startsAt,omitempty"`
+	ExpiresAt *string `json:"expiresAt,omitempty"`
+}
+
+// toGrantOptions validates the schedule and normalizes its bounds to UTC,
+// so they can be compared as text in the database.
+func (s PermissionSchedule) toGrantOptions(userID string) (repo.PermissionGrantOptions, error) {
+	res := repo.PermissionGrantOptions{GrantedBy: helper.ToPtr(userID)}
+	var startsAt time.Time
+	if s.StartsAt != nil {
+		t, err := time.Parse(time.RFC3339, *s.StartsAt)
+		if err != nil {
+			return res, errorpkg.NewInvalidPermissionScheduleError()
+		}
+		startsAt = t
+		res.StartsAt = helper.ToPtr(t.UTC().Format(time.RFC3339))
+	}
+	if s.ExpiresAt != nil {
+		t, err := time.Parse(time.RFC3339, *s.ExpiresAt)
+		if err != nil || !t.After(time.Now()) || (s.StartsAt != nil && !t.After(startsAt)) {
+			return res, errorpkg.NewInvalidPermissionScheduleError()
+		}
+		res.ExpiresAt = helper.ToPtr(t.UTC().Format(time.RFC3339))
+	}
+	return res, nil
+}
+
+// PermissionGrantOptions describes a grant, RoleID takes precedence over Permission
+// when given, as the permission is derived from the role's capabilities.
+type PermissionGrantOptions struct {
+	Permission string
+	RoleID     *string
+	Schedule   PermissionSchedule
+}
+
+const (
+	permissionResourceFile      = "file"
+	permissionResourceWorkspace = "workspace"
+	permissionResourceGroup     = "group"
+)
+
+// PermissionService revokes expired grants and reminds granters before their grants expire.
+type PermissionService struct {
+	permissionRepo *repo.PermissionRepo
+	fileCache      *cache.FileCache
+	filePermission *filePermission
+	workspaceCache *cache.WorkspaceCache
+	groupCache     *cache.GroupCache
+	userRepo       *repo.UserRepo
+	redis          *infra.RedisManager
+	mailTmpl       infra.MailTemplate
+	config         *config.Config
+}
+
+const (
+	permissionSweepLockKey = "permission_sweep_lock"
+	permissionSweepTimeKey = "permission_sweep_time"
+)
+
+func NewPermissionService() *PermissionService {
+	return &PermissionService{
+		permissionRepo: repo.NewPermissionRepo(),
+		fileCache:      cache.NewFileCache(),
+		filePermission: newFilePermission(),
+		workspaceCache: cache.NewWorkspaceCache(),
+		groupCache:     cache.NewGroupCache(),
+		userRepo:       repo.NewUserRepo(),
+		redis:          infra.NewRedisManager(),
+		mailTmpl:       infra.NewMailTemplate(config.GetConfig().SMTP),
+		config:         config.GetConfig(),
+	}
+}
+
+// Start sweeps periodically, it blocks so it should run in its own goroutine. Only one
+// replica sweeps per interval, the one that takes the lock first.
+func (svc *PermissionService) Start() {
+	if svc.config.Permissions.SweepIntervalSeconds <= 0 {
+		return
+	}
+	interval := time.Duration(svc.config.Permissions.SweepIntervalSeconds) * time.Second
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		acquired, err := svc.redis.SetNX(permissionSweepLockKey, helper.NewID(), interval)
+		if err != nil {
+			log.GetLogger().Error(err)
+			continue
+		}
+		if !acquired {
+			continue
+		}
+		if err := svc.Sweep(); err != nil {
+			log.GetLogger().Error(err)
+		}
+	}
+}
+
+func (svc *PermissionService) Sweep() error {
+	now := helper.NewTimestamp()
+	if err := svc.revokeExpired(); err != nil {
+		return err
+	}
+	if err := svc.refreshStarted(svc.findLastSweepTime(now), now); err != nil {
+		return err
+	}
+	svc.remind()
+	return svc.redis.Set(permissionSweepTimeKey, now)
+}
+
+// findLastSweepTime returns the time of the last sweep of any replica, the first sweep
+// has nothing to catch up on as the caches are filled with the grants that already started.
+func (svc *PermissionService) findLastSweepTime(now string) string {
+	value, err := svc.redis.Get(permissionSweepTimeKey)
+	if err != nil || value == "" {
+		return now
+	}
+	return value
+}
+
+// revokeExpired deletes the expired rows one by one rather than cascading like a revoke
+// does, as the descendants may hold grants of their own that didn't expire.
+func (svc *PermissionService) revokeExpired() error {
+	userPermissions, err := svc.permissionRepo.FindExpiredUserPermissions()
+	if err != nil {
+		return err
+	}
+	for _, p := range userPermissions {
+		resourceType, err := svc.permissionRepo.FindResourceType(p.GetResourceID())
+		if err != nil {
+			return err
+		}
+		if resourceType == permissionResourceFile {
+			err = svc.filePermission.expireUserPermission(p.GetID(), p.GetResourceID(), p.GetUserID())
+		} else if err = svc.permissionRepo.DeleteUserPermission(p.GetID()); err == nil {
+			err = svc.refresh(p.GetResourceID())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	groupPermissions, err := svc.permissionRepo.FindExpiredGroupPermissions()
+	if err != nil {
+		return err
+	}
+	for _, p := range groupPermissions {
+		resourceType, err := svc.permissionRepo.FindResourceType(p.GetResourceID())
+		if err != nil {
+			return err
+		}
+		if resourceType == permissionResourceFile {
+			err = svc.filePermission.expireGroupPermission(p.GetID(), p.GetResourceID(), p.GetGroupID())
+		} else if err = svc.permissionRepo.DeleteGroupPermission(p.GetID()); err == nil {
+			err = svc.refresh(p.GetResourceID())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshStarted refreshes the resources whose grants started since the last sweep. Guards
+// check the schedule on their own, but group members are resolved when caching the group.
+func (svc *PermissionService) refreshStarted(since string, now string) error {
+	ids, err := svc.permissionRepo.FindStartedResourceIDs(since, now)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := svc.refresh(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (svc *PermissionService) refresh(resourceID string) error {
+	resourceType, err := svc.permissionRepo.FindResourceType(resourceID)
+	if err != nil {
+		return err
+	}
+	switch resourceType {
+	case permissionResourceFile:
+		return svc.filePermission.refresh([]string{resourceID})
+	case permissionResourceWorkspace:
+		_, err = svc.workspaceCache.Refresh(resourceID)
+	case permissionResourceGroup:
+		err = svc.groupCache.RefreshMembership(resourceID)
+	}
+	return err
+}
+
+// remind doesn't stop on errors, so a single failing email doesn't hold back the others.
+func (svc *PermissionService) remind() {
+	before := time.Now().
+		Add(time.Duration(svc.config.Permissions.ExpiryReminderHours) * time.Hour).
+		UTC().
+		Format(time.RFC3339)
+	userPermissions, err := svc.permissionRepo.FindUserPermissionsToRemind(before)
+	if err != nil {
+		log.GetLogger().Error(err)
+		return
+	}
+	for _, p := range userPermissions {
+		assignee, err := svc.userRepo.Find(p.GetUserID())
+		if err != nil {
+			log.GetLogger().Error(err)
+			continue
+		}
+		if err := svc.sendReminder("userpermission", p, assignee.GetFullName()); err != nil {
+			log.GetLogger().Error(err)
+		}
+	}
+	groupPermissions, err := svc.permissionRepo.FindGroupPermissionsToRemind(before)
+	if err != nil {
+		log.GetLogger().Error(err)
+		return
+	}
+	for _, p := range groupPermissions {
+		group, err := svc.groupCache.Get(p.GetGroupID())
+		if err != nil {
+			log.GetLogger().Error(err)
+			continue
+		}
+		if err := svc.sendReminder("grouppermission", p, group.GetName()); err != nil {
+			log.GetLogger().Error(err)
+		}
+	}
+}
+
+type permissionReminder interface {
+	GetID() string
+	GetResourceID() string
+	GetPermission() string
+	GetExpiresAt() *string
+	GetGrantedBy() *string
+}
+
+func (svc *PermissionService) sendReminder(table string, p permissionReminder, assigneeName string) error {
+	resourceName, resourcePath, err := svc.describeResource(p.GetResourceID())
+	if err != nil {
+		return err
+	}
+	granter, err := svc.userRepo.Find(*p.GetGrantedBy())
+	if err != nil {
+		return err
+	}
+	// Claiming first means an email can get lost, but never be sent twice
+	claimed, err := svc.permissionRepo.ClaimReminder(table, p.GetID())
+	if err != nil || !claimed {
+		return err
+	}
+	return svc.mailTmpl.Send("permission-expiry-reminder", granter.GetEmail(), map[string]string{
+		"PERMISSION":    p.GetPermission(),
+		"ASSIGNEE_NAME": assigneeName,
+		"RESOURCE_NAME": resourceName,
+		"RESOURCE_PATH": resourcePath,
+		"EXPIRES_AT":    *p.GetExpiresAt(),
+		"UI_URL":        svc.config.PublicUIURL,
+	})
+}
+
+func (svc *PermissionService) describeResource(resourceID string) (string, string, error) {
+	resourceType, err := svc.permissionRepo.FindResourceType(resourceID)
+	if err != nil {
+		return "", "", err
+	}
+	switch resourceType {
+	case permissionResourceFile:
+		file, err := svc.fileCache.Get(resourceID)
+		if err != nil {
+			return "", "", err
+		}
+		if file.GetType() == model.FileTypeFolder {
+			return file.GetName(), fmt.Sprintf("/workspace/%s/file/%s", file.GetWorkspaceID(), file.GetID()), nil
+		}
+		return file.GetName(), fmt.Sprintf("/file/%s", file.GetID()), nil
+	case permissionResourceWorkspace:
+		workspace, err := svc.workspaceCache.Get(resourceID)
+		if err != nil {
+			return "", "", err
+		}
+		return workspace.GetName(), fmt.Sprintf("/workspace/%s/file/%s", workspace.GetID(), workspace.GetRootID()), nil
+	case permissionResourceGroup:
+		group, err := svc.groupCache.Get(resourceID)
+		if err != nil {
+			return "", "", err
+		}
+		return group.GetName(), fmt.Sprintf("/group/%s/member", group.GetID()), nil
+	}
+	return "", "", fmt.Errorf("unknown resource %s", resourceID)
+}
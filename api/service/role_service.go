@@ -0,0 +1,248 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package service
+
+import (
+	"slices"
+
+	"github.com/kouprlabs/voltaserve/api/cache"
+	"github.com/kouprlabs/voltaserve/api/errorpkg"
+	"github.com/kouprlabs/voltaserve/api/guard"
+	"github.com/kouprlabs/voltaserve/api/helper"
+	"github.com/kouprlabs/voltaserve/api/model"
+	"github.com/kouprlabs/voltaserve/api/repo"
+)
+
+// RoleService manages the roles an organization defines on top of the built-in
+// permissions, each role being a set of file capabilities.
+type RoleService struct {
+	roleRepo       *repo.RoleRepo
+	roleCache      *cache.RoleCache
+	roleMapper     *roleMapper
+	orgCache       *cache.OrganizationCache
+	orgGuard       *guard.OrganizationGuard
+	permissionRepo *repo.PermissionRepo
+	filePermission *filePermission
+}
+
+func NewRoleService() *RoleService {
+	return &RoleService{
+		roleRepo:       repo.NewRoleRepo(),
+		roleCache:      cache.NewRoleCache(),
+		roleMapper:     newRoleMapper(),
+		orgCache:       cache.NewOrganizationCache(),
+		orgGuard:       guard.NewOrganizationGuard(),
+		permissionRepo: repo.NewPermissionRepo(),
+		filePermission: newFilePermission(),
+	}
+}
+
+type Role struct {
+	ID             string   `json:"id"`
+	OrganizationID string   `json:"organizationId"`
+	Name           string   `json:"name"`
+	Capabilities   []string `json:"capabilities"`
+	// Permission is the built-in permission the role is the closest to.
+	Permission string  `json:"permission"`
+	CreateTime string  `json:"createTime"`
+	UpdateTime *string `json:"updateTime,omitempty"`
+}
+
+type RoleCreateOptions struct {
+	OrganizationID string   `json:"organizationId" validate:"required"`
+	Name           string   `json:"name"           validate:"required,max=255"`
+	Capabilities   []string `json:"capabilities"   validate:"required"`
+}
+
+func (svc *RoleService) Create(opts RoleCreateOptions, userID string) (*Role, error) {
+	org, err := svc.orgCache.Get(opts.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.orgGuard.Authorize(userID, org, model.PermissionOwner); err != nil {
+		return nil, err
+	}
+	capabilities, err := svc.validateCapabilities(opts.Capabilities)
+	if err != nil {
+		return nil, err
+	}
+	role, err := svc.roleRepo.Insert(repo.RoleInsertOptions{
+		ID:             helper.NewID(),
+		OrganizationID: opts.OrganizationID,
+		Name:           opts.Name,
+		Capabilities:   capabilities,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.roleCache.Set(role); err != nil {
+		return nil, err
+	}
+	return svc.roleMapper.mapOne(role), nil
+}
+
+func (svc *RoleService) Find(id string, userID string) (*Role, error) {
+	role, err := svc.roleCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.authorize(role, model.PermissionViewer, userID); err != nil {
+		return nil, err
+	}
+	return svc.roleMapper.mapOne(role), nil
+}
+
+func (svc *RoleService) List(organizationID string, userID string) ([]*Role, error) {
+	org, err := svc.orgCache.Get(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.orgGuard.Authorize(userID, org, model.PermissionViewer); err != nil {
+		return nil, err
+	}
+	ids, err := svc.roleRepo.FindIDsByOrganization(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*Role, 0)
+	for _, id := range ids {
+		role, err := svc.roleCache.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, svc.roleMapper.mapOne(role))
+	}
+	return res, nil
+}
+
+func (svc *RoleService) PatchName(id string, name string, userID string) (*Role, error) {
+	role, err := svc.roleCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.authorize(role, model.PermissionOwner, userID); err != nil {
+		return nil, err
+	}
+	role.SetName(name)
+	if err := svc.roleRepo.Save(role); err != nil {
+		return nil, err
+	}
+	role, err = svc.roleCache.Refresh(id)
+	if err != nil {
+		return nil, err
+	}
+	return svc.roleMapper.mapOne(role), nil
+}
+
+// PatchCapabilities applies to the existing grants of the role right away, the files
+// they are on are refreshed so their cached permission follows the new capabilities.
+func (svc *RoleService) PatchCapabilities(id string, capabilities []string, userID string) (*Role, error) {
+	role, err := svc.roleCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.authorize(role, model.PermissionOwner, userID); err != nil {
+		return nil, err
+	}
+	capabilities, err = svc.validateCapabilities(capabilities)
+	if err != nil {
+		return nil, err
+	}
+	role.SetCapabilities(capabilities)
+	if err := svc.roleRepo.Save(role); err != nil {
+		return nil, err
+	}
+	role, err = svc.roleCache.Refresh(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.permissionRepo.UpdateRolePermission(id, model.GetPermissionFromCapabilities(capabilities)); err != nil {
+		return nil, err
+	}
+	resourceIDs, err := svc.permissionRepo.FindRoleResourceIDs(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.filePermission.refresh(resourceIDs); err != nil {
+		return nil, err
+	}
+	return svc.roleMapper.mapOne(role), nil
+}
+
+// Delete refuses to delete a role which is still granted, as revoking the grants
+// silently would take access away from users.
+func (svc *RoleService) Delete(id string, userID string) error {
+	role, err := svc.roleCache.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := svc.authorize(role, model.PermissionOwner, userID); err != nil {
+		return err
+	}
+	count, err := svc.permissionRepo.CountRoleGrants(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errorpkg.NewRoleInUseError(role)
+	}
+	if err := svc.roleRepo.Delete(id); err != nil {
+		return err
+	}
+	if err := svc.roleCache.Delete(id); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (svc *RoleService) authorize(role model.Role, permission string, userID string) error {
+	org, err := svc.orgCache.Get(role.GetOrganizationID())
+	if err != nil {
+		return err
+	}
+	return svc.orgGuard.Authorize(userID, org, permission)
+}
+
+// validateCapabilities returns the capabilities deduplicated and sorted.
+func (svc *RoleService) validateCapabilities(capabilities []string) ([]string, error) {
+	var res []string
+	for _, capability := range capabilities {
+		if !model.IsValidCapability(capability) {
+			return nil, errorpkg.NewInvalidCapabilityError(capability)
+		}
+		if !slices.Contains(res, capability) {
+			res = append(res, capability)
+		}
+	}
+	if !slices.Contains(res, model.CapabilityList) {
+		return nil, errorpkg.NewRoleMissingListCapabilityError()
+	}
+	model.SortCapabilities(res)
+	return res, nil
+}
+
+type roleMapper struct{}
+
+func newRoleMapper() *roleMapper {
+	return &roleMapper{}
+}
+
+func (mp *roleMapper) mapOne(m model.Role) *Role {
+	return &Role{
+		ID:             m.GetID(),
+		OrganizationID: m.GetOrganizationID(),
+		Name:           m.GetName(),
+		Capabilities:   m.GetCapabilities(),
+		Permission:     model.GetPermissionFromCapabilities(m.GetCapabilities()),
+		CreateTime:     m.GetCreateTime(),
+		UpdateTime:     m.GetUpdateTime(),
+	}
+}
@@ -12,6 +12,7 @@ package service
 
 import (
 	"path/filepath"
+	"slices"
 	"sort"
 	"time"
 
@@ -40,6 +41,7 @@ type SnapshotService struct {
 	fileMapper     *fileMapper
 	taskRepo       *repo.TaskRepo
 	taskCache      *cache.TaskCache
+	storageLedger  *storageLedger
 	s3             infra.S3Manager
 	config         *config.Config
 }
@@ -56,27 +58,34 @@ func NewSnapshotService() *SnapshotService {
 		fileRepo:       repo.NewFileRepo(),
 		taskRepo:       repo.NewTaskRepo(),
 		taskCache:      cache.NewTaskCache(),
+		storageLedger:  newStorageLedger(),
 		s3:             infra.NewS3Manager(),
 		config:         config.GetConfig(),
 	}
 }
 
 type Snapshot struct {
-	ID         string            `json:"id"`
-	Version    int64             `json:"version"`
-	Original   *Download         `json:"original,omitempty"`
-	Preview    *Download         `json:"preview,omitempty"`
-	OCR        *Download         `json:"ocr,omitempty"`
-	Text       *Download         `json:"text,omitempty"`
-	Entities   *Download         `json:"entities,omitempty"`
-	Mosaic     *Download         `json:"mosaic,omitempty"`
-	Thumbnail  *Download         `json:"thumbnail,omitempty"`
-	Language   *string           `json:"language,omitempty"`
-	Status     string            `json:"status,omitempty"`
-	IsActive   bool              `json:"isActive"`
-	Task       *SnapshotTaskInfo `json:"task,omitempty"`
-	CreateTime string            `json:"createTime"`
-	UpdateTime *string           `json:"updateTime,omitempty"`
+	ID        string    `json:"id"`
+	Version   int64     `json:"version"`
+	Original  *Download `json:"original,omitempty"`
+	Preview   *Download `json:"preview,omitempty"`
+	OCR       *Download `json:"ocr,omitempty"`
+	Text      *Download `json:"text,omitempty"`
+	Entities  *Download `json:"entities,omitempty"`
+	Layout    *Download `json:"layout,omitempty"`
+	Summary   *Download `json:"summary,omitempty"`
+	Metadata  *Download `json:"metadata,omitempty"`
+	Sheets    *Download `json:"sheets,omitempty"`
+	Mosaic    *Download `json:"mosaic,omitempty"`
+	Thumbnail *Download `json:"thumbnail,omitempty"`
+	Language  *string   `json:"language,omitempty"`
+	// LanguageConfidence is only set when the language was detected automatically.
+	LanguageConfidence *float64          `json:"languageConfidence,omitempty"`
+	Status             string            `json:"status,omitempty"`
+	IsActive           bool              `json:"isActive"`
+	Task               *SnapshotTaskInfo `json:"task,omitempty"`
+	CreateTime         string            `json:"createTime"`
+	UpdateTime         *string           `json:"updateTime,omitempty"`
 }
 
 const (
@@ -95,6 +104,9 @@ type Download struct {
 	Size      *int64               `json:"size,omitempty"`
 	Image     *model.ImageProps    `json:"image,omitempty"`
 	Document  *model.DocumentProps `json:"document,omitempty"`
+	Model     *model.ModelProps    `json:"model,omitempty"`
+	Workbook  *model.WorkbookProps `json:"workbook,omitempty"`
+	Audio     *model.AudioProps    `json:"audio,omitempty"`
 }
 
 type SnapshotTaskInfo struct {
@@ -160,12 +172,16 @@ func (svc *SnapshotService) Activate(id string, userID string) (*File, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionEditor); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityManageVersions); err != nil {
 		return nil, err
 	}
 	if _, err := svc.snapshotCache.Get(id); err != nil {
 		return nil, err
 	}
+	before, err := svc.storageLedger.measure(file.GetID())
+	if err != nil {
+		return nil, err
+	}
 	file.SetSnapshotID(&id)
 	if err = svc.fileRepo.Save(file); err != nil {
 		return nil, err
@@ -173,10 +189,18 @@ func (svc *SnapshotService) Activate(id string, userID string) (*File, error) {
 	if err = svc.fileSearch.Update([]model.File{file}); err != nil {
 		return nil, err
 	}
+	if err = svc.fileSearch.IndexPages([]model.File{file}); err != nil {
+		log.GetLogger().Error(err)
+	}
 	err = svc.fileCache.Set(file)
 	if err != nil {
 		return nil, err
 	}
+	if after, err := svc.storageLedger.measure(file.GetID()); err != nil {
+		log.GetLogger().Error(err)
+	} else if err := svc.storageLedger.apply(file.GetWorkspaceID(), before, after); err != nil {
+		log.GetLogger().Error(err)
+	}
 	res, err := svc.fileMapper.mapOne(file, userID)
 	if err != nil {
 		return nil, err
@@ -193,13 +217,20 @@ func (svc *SnapshotService) Detach(id string, userID string) error {
 	if err != nil {
 		return err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionOwner); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityManageVersions); err != nil {
+		return err
+	}
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityDelete); err != nil {
 		return err
 	}
 	snapshot, err := svc.snapshotCache.Get(id)
 	if err != nil {
 		return err
 	}
+	before, err := svc.storageLedger.measure(file.GetID())
+	if err != nil {
+		return err
+	}
 	if err := svc.snapshotRepo.Detach(id, file.GetID()); err != nil {
 		return err
 	}
@@ -216,6 +247,7 @@ func (svc *SnapshotService) Detach(id string, userID string) error {
 				return err
 			}
 		}
+		svc.deleteCache(snapshot, []string{id})
 		if err := svc.snapshotRepo.Delete(id); err != nil {
 			return err
 		}
@@ -223,6 +255,11 @@ func (svc *SnapshotService) Detach(id string, userID string) error {
 			return err
 		}
 	}
+	if after, err := svc.storageLedger.measure(file.GetID()); err != nil {
+		log.GetLogger().Error(err)
+	} else if err := svc.storageLedger.apply(file.GetWorkspaceID(), before, after); err != nil {
+		log.GetLogger().Error(err)
+	}
 	return nil
 }
 
@@ -230,16 +267,41 @@ func (svc *SnapshotService) Patch(id string, opts SnapshotPatchOptions) (*Snapsh
 	if id != opts.Options.SnapshotID {
 		return nil, errorpkg.NewPathVariablesAndBodyParametersNotConsistent()
 	}
+	// Pipelines replace the original of some snapshots, like redactions and conversions,
+	// and the preview of office files references the cached conversion
+	changesUsage := slices.Contains(opts.Fields, repo.SnapshotFieldOriginal) ||
+		slices.Contains(opts.Fields, repo.SnapshotFieldPreview)
+	// The pages are indexed from the layout, which is only downloaded when it changes
+	changesPages := slices.Contains(opts.Fields, repo.SnapshotFieldLayout) ||
+		slices.Contains(opts.Fields, repo.SnapshotFieldText)
+	before := make(map[string][]*repo.StorageUsageEntity)
+	if changesUsage {
+		fileIDs, err := svc.fileRepo.FindIDsBySnapshot(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, fileID := range fileIDs {
+			if before[fileID], err = svc.storageLedger.measure(fileID); err != nil {
+				return nil, err
+			}
+		}
+	}
 	if err := svc.snapshotRepo.Update(id, repo.SnapshotUpdateOptions{
-		Original:  opts.Original,
-		Fields:    opts.Fields,
-		Preview:   opts.Preview,
-		Text:      opts.Text,
-		OCR:       opts.OCR,
-		Entities:  opts.Entities,
-		Mosaic:    opts.Mosaic,
-		Thumbnail: opts.Thumbnail,
-		Status:    opts.Status,
+		Original:           opts.Original,
+		Fields:             opts.Fields,
+		Preview:            opts.Preview,
+		Text:               opts.Text,
+		OCR:                opts.OCR,
+		Entities:           opts.Entities,
+		Layout:             opts.Layout,
+		Summary:            opts.Summary,
+		Metadata:           opts.Metadata,
+		Sheets:             opts.Sheets,
+		Mosaic:             opts.Mosaic,
+		Thumbnail:          opts.Thumbnail,
+		Status:             opts.Status,
+		Language:           opts.Language,
+		LanguageConfidence: opts.LanguageConfidence,
 	}); err != nil {
 		return nil, err
 	}
@@ -259,6 +321,18 @@ func (svc *SnapshotService) Patch(id string, opts SnapshotPatchOptions) (*Snapsh
 		if err = svc.fileSearch.Update([]model.File{file}); err != nil {
 			return nil, err
 		}
+		if changesPages {
+			if err = svc.fileSearch.IndexPages([]model.File{file}); err != nil {
+				log.GetLogger().Error(err)
+			}
+		}
+		if changesUsage {
+			if after, err := svc.storageLedger.measure(fileID); err != nil {
+				log.GetLogger().Error(err)
+			} else if err := svc.storageLedger.apply(file.GetWorkspaceID(), before[fileID], after); err != nil {
+				log.GetLogger().Error(err)
+			}
+		}
 	}
 	return svc.snapshotMapper.mapOne(snapshot), nil
 }
@@ -279,7 +353,7 @@ func (svc *SnapshotService) findAll(fileID string, opts SnapshotListOptions, use
 	if err != nil {
 		return nil, nil, err
 	}
-	if err = svc.fileGuard.Authorize(userID, file, model.PermissionEditor); err != nil {
+	if err = svc.fileGuard.AuthorizeCapability(userID, file, model.CapabilityManageVersions); err != nil {
 		return nil, nil, err
 	}
 	if file.GetType() != model.FileTypeFile || file.GetSnapshotID() == nil {
@@ -378,6 +452,26 @@ func (svc *SnapshotService) deleteForFile(fileID string) error {
 	return nil
 }
 
+// deleteCache deletes the cached conversion the preview of the snapshot was copied from,
+// unless snapshots other than the ones being deleted still reference it.
+func (svc *SnapshotService) deleteCache(snapshot model.Snapshot, deletedIDs []string) {
+	if snapshot.GetPreview() == nil || snapshot.GetPreview().Document == nil || snapshot.GetPreview().Document.Cache == nil {
+		return
+	}
+	cache := snapshot.GetPreview().Document.Cache
+	count, err := svc.snapshotRepo.CountCacheReferences(cache.Bucket, cache.Key, deletedIDs)
+	if err != nil {
+		log.GetLogger().Error(err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+	if err := svc.s3.RemoveObject(cache.Key, cache.Bucket, minio.RemoveObjectOptions{}); err != nil {
+		log.GetLogger().Error(err)
+	}
+}
+
 func (svc *SnapshotService) deleteAssociatedTasks(snapshots []model.Snapshot) {
 	for _, snapshot := range snapshots {
 		if snapshot.GetTaskID() != nil {
@@ -392,6 +486,10 @@ func (svc *SnapshotService) deleteAssociatedTasks(snapshots []model.Snapshot) {
 }
 
 func (svc *SnapshotService) deleteFromS3(snapshots []model.Snapshot) {
+	ids := make([]string, 0, len(snapshots))
+	for _, s := range snapshots {
+		ids = append(ids, s.GetID())
+	}
 	for _, s := range snapshots {
 		if s.HasOriginal() {
 			if err := svc.s3.RemoveObject(s.GetOriginal().Key, s.GetOriginal().Bucket, minio.RemoveObjectOptions{}); err != nil {
@@ -402,6 +500,31 @@ func (svc *SnapshotService) deleteFromS3(snapshots []model.Snapshot) {
 			if err := svc.s3.RemoveObject(s.GetPreview().Key, s.GetPreview().Bucket, minio.RemoveObjectOptions{}); err != nil {
 				log.GetLogger().Error(err)
 			}
+			if s.GetPreview().Document != nil && s.GetPreview().Document.Pages != nil {
+				for _, folder := range []string{"/pages/", "/page_thumbnails/"} {
+					if err := svc.s3.RemoveFolder(s.GetID()+folder, s.GetPreview().Bucket, minio.RemoveObjectOptions{}); err != nil {
+						log.GetLogger().Error(err)
+					}
+				}
+			}
+			if s.GetPreview().Document != nil && s.GetPreview().Document.Slides != nil {
+				if err := svc.s3.RemoveFolder(s.GetID()+"/slides/", s.GetPreview().Bucket, minio.RemoveObjectOptions{}); err != nil {
+					log.GetLogger().Error(err)
+				}
+			}
+			svc.deleteCache(s, ids)
+			if audio := s.GetPreview().Audio; audio != nil {
+				if audio.Waveform != nil {
+					if err := svc.s3.RemoveObject(s.GetID()+"/waveform"+audio.Waveform.Extension, s.GetPreview().Bucket, minio.RemoveObjectOptions{}); err != nil {
+						log.GetLogger().Error(err)
+					}
+				}
+				if audio.Transcript != nil {
+					if err := svc.s3.RemoveObject(s.GetID()+"/transcript"+audio.Transcript.Extension, s.GetPreview().Bucket, minio.RemoveObjectOptions{}); err != nil {
+						log.GetLogger().Error(err)
+					}
+				}
+			}
 		}
 		if s.HasText() {
 			if err := svc.s3.RemoveObject(s.GetText().Key, s.GetText().Bucket, minio.RemoveObjectOptions{}); err != nil {
@@ -423,6 +546,26 @@ func (svc *SnapshotService) deleteFromS3(snapshots []model.Snapshot) {
 				log.GetLogger().Error(err)
 			}
 		}
+		if s.HasLayout() {
+			if err := svc.s3.RemoveObject(s.GetLayout().Key, s.GetLayout().Bucket, minio.RemoveObjectOptions{}); err != nil {
+				log.GetLogger().Error(err)
+			}
+		}
+		if s.HasSummary() {
+			if err := svc.s3.RemoveObject(s.GetSummary().Key, s.GetSummary().Bucket, minio.RemoveObjectOptions{}); err != nil {
+				log.GetLogger().Error(err)
+			}
+		}
+		if s.HasMetadata() {
+			if err := svc.s3.RemoveObject(s.GetMetadata().Key, s.GetMetadata().Bucket, minio.RemoveObjectOptions{}); err != nil {
+				log.GetLogger().Error(err)
+			}
+		}
+		if s.HasSheets() {
+			if err := svc.s3.RemoveObject(s.GetSheets().Key, s.GetSheets().Bucket, minio.RemoveObjectOptions{}); err != nil {
+				log.GetLogger().Error(err)
+			}
+		}
 		if s.HasOCR() {
 			if err := svc.s3.RemoveObject(s.GetOCR().Key, s.GetOCR().Bucket, minio.RemoveObjectOptions{}); err != nil {
 				log.GetLogger().Error(err)
@@ -451,17 +594,23 @@ func (svc *SnapshotService) deleteFromRepo(snapshots []model.Snapshot) {
 }
 
 type SnapshotPatchOptions struct {
-	Options   conversion_client.PipelineRunOptions `json:"options"`
-	Fields    []string                             `json:"fields"`
-	Original  *model.S3Object                      `json:"original"`
-	Preview   *model.S3Object                      `json:"preview"`
-	Text      *model.S3Object                      `json:"text"`
-	OCR       *model.S3Object                      `json:"ocr"`
-	Entities  *model.S3Object                      `json:"entities"`
-	Mosaic    *model.S3Object                      `json:"mosaic"`
-	Thumbnail *model.S3Object                      `json:"thumbnail"`
-	Status    *string                              `json:"status"`
-	TaskID    *string                              `json:"taskId"`
+	Options            conversion_client.PipelineRunOptions `json:"options"`
+	Fields             []string                             `json:"fields"`
+	Original           *model.S3Object                      `json:"original"`
+	Preview            *model.S3Object                      `json:"preview"`
+	Text               *model.S3Object                      `json:"text"`
+	OCR                *model.S3Object                      `json:"ocr"`
+	Entities           *model.S3Object                      `json:"entities"`
+	Layout             *model.S3Object                      `json:"layout"`
+	Summary            *model.S3Object                      `json:"summary"`
+	Metadata           *model.S3Object                      `json:"metadata"`
+	Sheets             *model.S3Object                      `json:"sheets"`
+	Mosaic             *model.S3Object                      `json:"mosaic"`
+	Thumbnail          *model.S3Object                      `json:"thumbnail"`
+	Status             *string                              `json:"status"`
+	Language           *string                              `json:"language"`
+	LanguageConfidence *float64                             `json:"languageConfidence"`
+	TaskID             *string                              `json:"taskId"`
 }
 
 func (svc *SnapshotService) isTaskPending(snapshot model.Snapshot) (bool, error) {
@@ -513,12 +662,13 @@ func newSnapshotMapper() *snapshotMapper {
 
 func (mp *snapshotMapper) mapOne(m model.Snapshot) *Snapshot {
 	s := &Snapshot{
-		ID:         m.GetID(),
-		Version:    m.GetVersion(),
-		Status:     m.GetStatus(),
-		Language:   m.GetLanguage(),
-		CreateTime: m.GetCreateTime(),
-		UpdateTime: m.GetUpdateTime(),
+		ID:                 m.GetID(),
+		Version:            m.GetVersion(),
+		Status:             m.GetStatus(),
+		Language:           m.GetLanguage(),
+		LanguageConfidence: m.GetLanguageConfidence(),
+		CreateTime:         m.GetCreateTime(),
+		UpdateTime:         m.GetUpdateTime(),
 	}
 	if m.HasOriginal() {
 		s.Original = mp.mapS3Object(m.GetOriginal())
@@ -535,6 +685,18 @@ func (mp *snapshotMapper) mapOne(m model.Snapshot) *Snapshot {
 	if m.HasEntities() {
 		s.Entities = mp.mapS3Object(m.GetEntities())
 	}
+	if m.HasLayout() {
+		s.Layout = mp.mapS3Object(m.GetLayout())
+	}
+	if m.HasSummary() {
+		s.Summary = mp.mapS3Object(m.GetSummary())
+	}
+	if m.HasMetadata() {
+		s.Metadata = mp.mapS3Object(m.GetMetadata())
+	}
+	if m.HasSheets() {
+		s.Sheets = mp.mapS3Object(m.GetSheets())
+	}
 	if m.HasMosaic() {
 		s.Mosaic = mp.mapS3Object(m.GetMosaic())
 	}
@@ -577,5 +739,14 @@ func (mp *snapshotMapper) mapS3Object(o *model.S3Object) *Download {
 	if o.Document != nil {
 		download.Document = o.Document
 	}
+	if o.Model != nil {
+		download.Model = o.Model
+	}
+	if o.Workbook != nil {
+		download.Workbook = o.Workbook
+	}
+	if o.Audio != nil {
+		download.Audio = o.Audio
+	}
 	return download
 }
@@ -11,8 +11,18 @@
 package service
 
 import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
 	"github.com/kouprlabs/voltaserve/api/cache"
+	"github.com/kouprlabs/voltaserve/api/config"
+	"github.com/kouprlabs/voltaserve/api/errorpkg"
 	"github.com/kouprlabs/voltaserve/api/guard"
+	"github.com/kouprlabs/voltaserve/api/helper"
+	"github.com/kouprlabs/voltaserve/api/infra"
+	"github.com/kouprlabs/voltaserve/api/log"
 	"github.com/kouprlabs/voltaserve/api/model"
 	"github.com/kouprlabs/voltaserve/api/repo"
 )
@@ -21,10 +31,15 @@ type StorageService struct {
 	workspaceRepo  *repo.WorkspaceRepo
 	workspaceCache *cache.WorkspaceCache
 	workspaceGuard *guard.WorkspaceGuard
+	orgCache       *cache.OrganizationCache
+	orgGuard       *guard.OrganizationGuard
 	fileRepo       *repo.FileRepo
 	fileCache      *cache.FileCache
 	fileGuard      *guard.FileGuard
+	storageRepo    *repo.StorageRepo
+	storageLedger  *storageLedger
 	storageMapper  *storageMapper
+	config         *config.Config
 }
 
 func NewStorageService() *StorageService {
@@ -32,10 +47,42 @@ func NewStorageService() *StorageService {
 		workspaceRepo:  repo.NewWorkspaceRepo(),
 		workspaceCache: cache.NewWorkspaceCache(),
 		workspaceGuard: guard.NewWorkspaceGuard(),
+		orgCache:       cache.NewOrganizationCache(),
+		orgGuard:       guard.NewOrganizationGuard(),
 		fileRepo:       repo.NewFileRepo(),
 		fileCache:      cache.NewFileCache(),
 		fileGuard:      guard.NewFileGuard(),
+		storageRepo:    repo.NewStorageRepo(),
+		storageLedger:  newStorageLedger(),
 		storageMapper:  newStorageMapper(),
+		config:         config.GetConfig(),
+	}
+}
+
+// Start reconciles the ledger of every workspace, first to fill it for the workspaces created
+// before it existed, then periodically. It blocks so it should run in its own goroutine.
+func (svc *StorageService) Start() {
+	svc.reconcile()
+	if svc.config.Storage.ReconcileIntervalSeconds <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(svc.config.Storage.ReconcileIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		svc.reconcile()
+	}
+}
+
+func (svc *StorageService) reconcile() {
+	ids, err := svc.workspaceRepo.FindIDs()
+	if err != nil {
+		log.GetLogger().Error(err)
+		return
+	}
+	for _, id := range ids {
+		if err := svc.storageLedger.reconcile(id); err != nil {
+			log.GetLogger().Error(err)
+		}
 	}
 }
 
@@ -64,11 +111,7 @@ func (svc *StorageService) ComputeAccountUsage(userID string) (*StorageUsage, er
 	var maxBytes int64 = 0
 	var b int64 = 0
 	for _, w := range workspaces {
-		root, err := svc.fileCache.Get(w.GetRootID())
-		if err != nil {
-			return nil, err
-		}
-		size, err := svc.fileRepo.ComputeSize(root.GetID())
+		size, err := svc.storageRepo.SumWorkspaceUsage(w.GetID())
 		if err != nil {
 			return nil, err
 		}
@@ -93,13 +136,106 @@ func (svc *StorageService) ComputeWorkspaceUsage(workspaceID string, userID stri
 	if err = svc.fileGuard.Authorize(userID, root, model.PermissionViewer); err != nil {
 		return nil, err
 	}
-	size, err := svc.fileRepo.ComputeSize(root.GetID())
+	size, err := svc.storageRepo.SumWorkspaceUsage(workspace.GetID())
 	if err != nil {
 		return nil, err
 	}
 	return svc.storageMapper.mapStorageUsage(size, workspace.GetStorageCapacity()), nil
 }
 
+// ComputeOrganizationUsage sums the usage of the workspaces of the organization,
+// MaxBytes is zero when the organization has no quota.
+func (svc *StorageService) ComputeOrganizationUsage(orgID string, userID string) (*StorageUsage, error) {
+	org, err := svc.orgCache.Get(orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.orgGuard.Authorize(userID, org, model.PermissionViewer); err != nil {
+		return nil, err
+	}
+	size, err := svc.storageRepo.SumOrganizationUsage(org.GetID())
+	if err != nil {
+		return nil, err
+	}
+	var maxBytes int64
+	if org.GetStorageCapacity() != nil {
+		maxBytes = *org.GetStorageCapacity()
+	}
+	return svc.storageMapper.mapStorageUsage(size, maxBytes), nil
+}
+
+// ComputeMemberUsage sums the uploads of a member in the organization, members can see
+// their own usage and owners the usage of everyone. MaxBytes is zero when members have no quota.
+func (svc *StorageService) ComputeMemberUsage(orgID string, memberID string, userID string) (*StorageUsage, error) {
+	org, err := svc.orgCache.Get(orgID)
+	if err != nil {
+		return nil, err
+	}
+	permission := model.PermissionOwner
+	if memberID == userID {
+		permission = model.PermissionViewer
+	}
+	if err := svc.orgGuard.Authorize(userID, org, permission); err != nil {
+		return nil, err
+	}
+	if !slices.Contains(org.GetMembers(), memberID) {
+		return nil, errorpkg.NewUserNotMemberOfOrganizationError()
+	}
+	size, err := svc.storageRepo.SumMemberUsage(org.GetID(), memberID)
+	if err != nil {
+		return nil, err
+	}
+	var maxBytes int64
+	if org.GetMemberStorageCapacity() != nil {
+		maxBytes = *org.GetMemberStorageCapacity()
+	}
+	return svc.storageMapper.mapStorageUsage(size, maxBytes), nil
+}
+
+type StorageBreakdown struct {
+	Bytes        int64                   `json:"bytes"`
+	LatestBytes  int64                   `json:"latestBytes"`
+	HistoryBytes int64                   `json:"historyBytes"`
+	FileTypes    []*StorageFileTypeUsage `json:"fileTypes"`
+}
+
+type StorageFileTypeUsage struct {
+	FileType     string `json:"fileType"`
+	Bytes        int64  `json:"bytes"`
+	LatestBytes  int64  `json:"latestBytes"`
+	HistoryBytes int64  `json:"historyBytes"`
+}
+
+func (svc *StorageService) ComputeWorkspaceBreakdown(workspaceID string, userID string) (*StorageBreakdown, error) {
+	workspace, err := svc.workspaceCache.Get(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err = svc.workspaceGuard.Authorize(userID, workspace, model.PermissionViewer); err != nil {
+		return nil, err
+	}
+	entries, err := svc.storageRepo.FindWorkspaceUsage(workspace.GetID())
+	if err != nil {
+		return nil, err
+	}
+	return svc.storageMapper.mapStorageBreakdown(entries), nil
+}
+
+func (svc *StorageService) ComputeOrganizationBreakdown(orgID string, userID string) (*StorageBreakdown, error) {
+	org, err := svc.orgCache.Get(orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.orgGuard.Authorize(userID, org, model.PermissionViewer); err != nil {
+		return nil, err
+	}
+	entries, err := svc.storageRepo.FindOrganizationUsage(org.GetID())
+	if err != nil {
+		return nil, err
+	}
+	return svc.storageMapper.mapStorageBreakdown(entries), nil
+}
+
 func (svc *StorageService) ComputeFileUsage(fileID string, userID string) (*StorageUsage, error) {
 	file, err := svc.fileCache.Get(fileID)
 	if err != nil {
@@ -135,3 +271,276 @@ func (mp *storageMapper) mapStorageUsage(byteCount int64, maxBytes int64) *Stora
 	}
 	return &res
 }
+
+func (mp *storageMapper) mapStorageBreakdown(entries []*repo.StorageUsageEntity) *StorageBreakdown {
+	res := &StorageBreakdown{FileTypes: make([]*StorageFileTypeUsage, 0)}
+	fileTypes := make(map[string]*StorageFileTypeUsage)
+	for _, e := range entries {
+		fileType, ok := fileTypes[e.Category]
+		if !ok {
+			fileType = &StorageFileTypeUsage{FileType: e.Category}
+			fileTypes[e.Category] = fileType
+			res.FileTypes = append(res.FileTypes, fileType)
+		}
+		fileType.Bytes += e.Bytes
+		res.Bytes += e.Bytes
+		if e.History {
+			fileType.HistoryBytes += e.Bytes
+			res.HistoryBytes += e.Bytes
+		} else {
+			fileType.LatestBytes += e.Bytes
+			res.LatestBytes += e.Bytes
+		}
+	}
+	sort.SliceStable(res.FileTypes, func(i, j int) bool {
+		return res.FileTypes[i].Bytes > res.FileTypes[j].Bytes
+	})
+	return res
+}
+
+const (
+	storageCategoryOther = "other"
+	storageCategoryCache = "cache"
+)
+
+// storageLedger keeps the usage ledger up to date. Changes to originals are applied as deltas
+// of the subtrees they touch, so reading the usage doesn't need to walk the tree. The full
+// recompute of a workspace is left to the reconcile sweeper.
+type storageLedger struct {
+	storageRepo    *repo.StorageRepo
+	workspaceCache *cache.WorkspaceCache
+	orgCache       *cache.OrganizationCache
+	userRepo       *repo.UserRepo
+	fileIdent      *infra.FileIdentifier
+	mailTmpl       infra.MailTemplate
+	config         *config.Config
+}
+
+func newStorageLedger() *storageLedger {
+	return &storageLedger{
+		storageRepo:    repo.NewStorageRepo(),
+		workspaceCache: cache.NewWorkspaceCache(),
+		orgCache:       cache.NewOrganizationCache(),
+		userRepo:       repo.NewUserRepo(),
+		fileIdent:      infra.NewFileIdentifier(),
+		mailTmpl:       infra.NewMailTemplate(config.GetConfig().SMTP),
+		config:         config.GetConfig(),
+	}
+}
+
+// measure returns the entries of the originals in the subtree of the file, without workspace.
+func (svc *storageLedger) measure(fileID string) ([]*repo.StorageUsageEntity, error) {
+	sizes, err := svc.storageRepo.FindSnapshotSizes(fileID)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[repo.StorageUsageEntity]*repo.StorageUsageEntity)
+	var res []*repo.StorageUsageEntity
+	for _, s := range sizes {
+		category := svc.category(s.Key)
+		if s.Cache {
+			category = storageCategoryCache
+		}
+		key := repo.StorageUsageEntity{
+			UserID:   s.UserID,
+			Category: category,
+			History:  s.History,
+		}
+		if _, ok := entries[key]; !ok {
+			entries[key] = &repo.StorageUsageEntity{
+				UserID:   key.UserID,
+				Category: key.Category,
+				History:  key.History,
+			}
+			res = append(res, entries[key])
+		}
+		entries[key].Bytes += s.Size
+	}
+	return res, nil
+}
+
+// apply adds the difference between the entries measured after and before a change
+// to the ledger of the workspace.
+func (svc *storageLedger) apply(workspaceID string, before []*repo.StorageUsageEntity, after []*repo.StorageUsageEntity) error {
+	workspace, err := svc.workspaceCache.Get(workspaceID)
+	if err != nil {
+		return err
+	}
+	deltas := make(map[repo.StorageUsageEntity]*repo.StorageUsageEntity)
+	var values []*repo.StorageUsageEntity
+	var userIDs []string
+	add := func(e *repo.StorageUsageEntity, sign int64) {
+		key := repo.StorageUsageEntity{
+			UserID:   e.UserID,
+			Category: e.Category,
+			History:  e.History,
+		}
+		if _, ok := deltas[key]; !ok {
+			deltas[key] = &repo.StorageUsageEntity{
+				WorkspaceID: workspace.GetID(),
+				UserID:      key.UserID,
+				Category:    key.Category,
+				History:     key.History,
+			}
+			values = append(values, deltas[key])
+		}
+		deltas[key].Bytes += sign * e.Bytes
+		if e.UserID != "" && !slices.Contains(userIDs, e.UserID) {
+			userIDs = append(userIDs, e.UserID)
+		}
+	}
+	for _, e := range before {
+		add(e, -1)
+	}
+	for _, e := range after {
+		add(e, 1)
+	}
+	values = slices.DeleteFunc(values, func(e *repo.StorageUsageEntity) bool {
+		return e.Bytes == 0
+	})
+	if len(values) == 0 {
+		return nil
+	}
+	if err := svc.storageRepo.AddWorkspaceUsage(workspace.GetID(), values); err != nil {
+		return err
+	}
+	svc.warn(workspace.GetOrganizationID(), userIDs)
+	return nil
+}
+
+// reconcile recomputes the entries of the workspace from its tree, it fixes the drift
+// left by deltas that failed to apply.
+func (svc *storageLedger) reconcile(workspaceID string) error {
+	workspace, err := svc.workspaceCache.Get(workspaceID)
+	if err != nil {
+		return err
+	}
+	entries, err := svc.measure(workspace.GetRootID())
+	if err != nil {
+		return err
+	}
+	var userIDs []string
+	for _, e := range entries {
+		e.WorkspaceID = workspace.GetID()
+		if e.UserID != "" && !slices.Contains(userIDs, e.UserID) {
+			userIDs = append(userIDs, e.UserID)
+		}
+	}
+	if err := svc.storageRepo.ReplaceWorkspaceUsage(workspace.GetID(), entries); err != nil {
+		return err
+	}
+	svc.warn(workspace.GetOrganizationID(), userIDs)
+	return nil
+}
+
+func (svc *storageLedger) category(key string) string {
+	switch {
+	case svc.fileIdent.IsPDF(key):
+		return model.ProcessingFileTypePDF
+	case svc.fileIdent.IsOffice(key):
+		return model.ProcessingFileTypeOffice
+	case svc.fileIdent.IsPlainText(key):
+		return model.ProcessingFileTypeText
+	case svc.fileIdent.IsImage(key):
+		return model.ProcessingFileTypeImage
+	case svc.fileIdent.IsVideo(key):
+		return model.ProcessingFileTypeVideo
+	case svc.fileIdent.IsAudio(key):
+		return model.ProcessingFileTypeAudio
+	case svc.fileIdent.IsGLB(key):
+		return model.ProcessingFileTypeGLB
+	case svc.fileIdent.IsModel(key):
+		return model.ProcessingFileTypeModel
+	case svc.fileIdent.IsEmail(key):
+		return model.ProcessingFileTypeEmail
+	case svc.fileIdent.IsZIP(key):
+		return model.ProcessingFileTypeZIP
+	}
+	return storageCategoryOther
+}
+
+// warn doesn't stop on errors, as the ledger is already saved and warnings are best effort.
+func (svc *storageLedger) warn(orgID string, userIDs []string) {
+	if len(svc.config.Storage.WarningThresholds) == 0 {
+		return
+	}
+	org, err := svc.orgCache.Get(orgID)
+	if err != nil {
+		log.GetLogger().Error(err)
+		return
+	}
+	if org.GetStorageCapacity() != nil {
+		var owners []string
+		for _, p := range org.GetUserPermissions() {
+			if p.GetValue() == model.PermissionOwner {
+				owners = append(owners, p.GetUserID())
+			}
+		}
+		if err := svc.warnIfNeeded(org, "", *org.GetStorageCapacity(), owners); err != nil {
+			log.GetLogger().Error(err)
+		}
+	}
+	if org.GetMemberStorageCapacity() != nil {
+		for _, userID := range userIDs {
+			if err := svc.warnIfNeeded(org, userID, *org.GetMemberStorageCapacity(), []string{userID}); err != nil {
+				log.GetLogger().Error(err)
+			}
+		}
+	}
+}
+
+// warnIfNeeded sends a warning once per threshold crossed, going back under a threshold
+// lets it be sent again the next time it's crossed.
+func (svc *storageLedger) warnIfNeeded(org model.Organization, userID string, capacity int64, recipientIDs []string) error {
+	var usage int64
+	var err error
+	if userID == "" {
+		usage, err = svc.storageRepo.SumOrganizationUsage(org.GetID())
+	} else {
+		usage, err = svc.storageRepo.SumMemberUsage(org.GetID(), userID)
+	}
+	if err != nil {
+		return err
+	}
+	threshold := 0
+	for _, t := range svc.config.Storage.WarningThresholds {
+		if capacity > 0 && usage*100 >= int64(t)*capacity && t > threshold {
+			threshold = t
+		}
+	}
+	previous, err := svc.storageRepo.FindWarningThreshold(org.GetID(), userID)
+	if err != nil {
+		return err
+	}
+	if threshold == previous {
+		return nil
+	}
+	if err := svc.storageRepo.SaveWarningThreshold(org.GetID(), userID, threshold); err != nil {
+		return err
+	}
+	if threshold < previous {
+		return nil
+	}
+	quota := "storage quota"
+	if userID != "" {
+		quota = "upload quota"
+	}
+	for _, id := range recipientIDs {
+		recipient, err := svc.userRepo.Find(id)
+		if err != nil {
+			return err
+		}
+		if err := svc.mailTmpl.Send("storage-quota-warning", recipient.GetEmail(), map[string]string{
+			"QUOTA":             quota,
+			"ORGANIZATION_NAME": org.GetName(),
+			"PERCENTAGE":        fmt.Sprintf("%d", threshold),
+			"USAGE_MB":          fmt.Sprintf("%d", usage/helper.MegabyteToByte(1)),
+			"CAPACITY_MB":       fmt.Sprintf("%d", capacity/helper.MegabyteToByte(1)),
+			"UI_URL":            svc.config.PublicUIURL,
+			"ORGANIZATION_PATH": fmt.Sprintf("/organization/%s/settings", org.GetID()),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
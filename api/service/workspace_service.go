@@ -12,11 +12,13 @@ package service
 
 import (
 	"errors"
+	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
 
 	"github.com/kouprlabs/voltaserve/api/cache"
 	"github.com/kouprlabs/voltaserve/api/config"
@@ -24,6 +26,7 @@ import (
 	"github.com/kouprlabs/voltaserve/api/guard"
 	"github.com/kouprlabs/voltaserve/api/helper"
 	"github.com/kouprlabs/voltaserve/api/infra"
+	"github.com/kouprlabs/voltaserve/api/log"
 	"github.com/kouprlabs/voltaserve/api/model"
 	"github.com/kouprlabs/voltaserve/api/repo"
 	"github.com/kouprlabs/voltaserve/api/search"
@@ -41,6 +44,13 @@ type WorkspaceService struct {
 	fileCache       *cache.FileCache
 	fileGuard       *guard.FileGuard
 	fileMapper      *fileMapper
+	templateRepo    *repo.WorkspaceTemplateRepo
+	storageRepo     *repo.StorageRepo
+	snapshotRepo    *repo.SnapshotRepo
+	snapshotCache   *cache.SnapshotCache
+	workspaceTree   *workspaceTree
+	taskSvc         *TaskService
+	taskMapper      *taskMapper
 	s3              infra.S3Manager
 	config          *config.Config
 }
@@ -58,21 +68,31 @@ func NewWorkspaceService() *WorkspaceService {
 		fileCache:       cache.NewFileCache(),
 		fileGuard:       guard.NewFileGuard(),
 		fileMapper:      newFileMapper(),
+		templateRepo:    repo.NewWorkspaceTemplateRepo(),
+		storageRepo:     repo.NewStorageRepo(),
+		snapshotRepo:    repo.NewSnapshotRepo(),
+		snapshotCache:   cache.NewSnapshotCache(),
+		workspaceTree:   newWorkspaceTree(),
+		taskSvc:         NewTaskService(),
+		taskMapper:      newTaskMapper(),
 		s3:              infra.NewS3Manager(),
 		config:          config.GetConfig(),
 	}
 }
 
 type Workspace struct {
-	ID              string       `json:"id"`
-	Image           *string      `json:"image,omitempty"`
-	Name            string       `json:"name"`
-	RootID          string       `json:"rootId,omitempty"`
-	StorageCapacity int64        `json:"storageCapacity"`
-	Permission      string       `json:"permission"`
-	Organization    Organization `json:"organization"`
-	CreateTime      string       `json:"createTime"`
-	UpdateTime      *string      `json:"updateTime,omitempty"`
+	ID               string                  `json:"id"`
+	Image            *string                 `json:"image,omitempty"`
+	Name             string                  `json:"name"`
+	RootID           string                  `json:"rootId,omitempty"`
+	StorageCapacity  int64                   `json:"storageCapacity"`
+	ProcessingPolicy *model.ProcessingPolicy `json:"processingPolicy,omitempty"`
+	Permission       string                  `json:"permission"`
+	Organization     Organization            `json:"organization"`
+	ArchiveStatus    *string                 `json:"archiveStatus,omitempty"`
+	ArchiveTime      *string                 `json:"archiveTime,omitempty"`
+	CreateTime       string                  `json:"createTime"`
+	UpdateTime       *string                 `json:"updateTime,omitempty"`
 }
 
 const (
@@ -91,6 +111,7 @@ type WorkspaceCreateOptions struct {
 	Image           *string `json:"image"`
 	OrganizationID  string  `json:"organizationId"  validate:"required"`
 	StorageCapacity int64   `json:"storageCapacity"`
+	TemplateID      *string `json:"templateId,omitempty"`
 }
 
 func (svc *WorkspaceService) Create(opts WorkspaceCreateOptions, userID string) (*Workspace, error) {
@@ -101,6 +122,22 @@ func (svc *WorkspaceService) Create(opts WorkspaceCreateOptions, userID string)
 	if err := svc.orgGuard.Authorize(userID, org, model.PermissionEditor); err != nil {
 		return nil, err
 	}
+	if opts.StorageCapacity == 0 {
+		opts.StorageCapacity = helper.MegabyteToByte(svc.config.Defaults.WorkspaceStorageCapacityMB)
+	}
+	var template model.WorkspaceTemplate
+	if opts.TemplateID != nil {
+		if template, err = svc.templateRepo.Find(*opts.TemplateID); err != nil {
+			return nil, err
+		}
+		if template.GetOrganizationID() != org.GetID() {
+			return nil, errorpkg.NewWorkspaceTemplateOrganizationMismatchError(template)
+		}
+		size := svc.workspaceTree.size(template.GetNodes())
+		if err := svc.checkNewWorkspaceQuota(org, opts.StorageCapacity, userID, size); err != nil {
+			return nil, err
+		}
+	}
 	workspace, err := svc.createWorkspace(opts, userID)
 	if err != nil {
 		return nil, err
@@ -113,6 +150,18 @@ func (svc *WorkspaceService) Create(opts WorkspaceCreateOptions, userID string)
 	if err != nil {
 		return nil, err
 	}
+	// The tree of the template is copied in the background, the task shows up in the tasks of the user
+	if template != nil {
+		task, err := svc.createTask("Creating from template.", template.GetName(), userID)
+		if err != nil {
+			return nil, err
+		}
+		go func(task model.Task) {
+			err := svc.workspaceTree.materialize(template.GetNodes(), template.GetGrants(), workspace, userID,
+				svc.workspaceTree.trackProgress(task, svc.taskSvc))
+			svc.completeTask(task, err)
+		}(task)
+	}
 	res, err := svc.workspaceMapper.mapOne(workspace, userID)
 	if err != nil {
 		return nil, err
@@ -120,15 +169,84 @@ func (svc *WorkspaceService) Create(opts WorkspaceCreateOptions, userID string)
 	return res, nil
 }
 
+type WorkspaceCloneOptions struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+type WorkspaceCloneResult struct {
+	Workspace *Workspace `json:"workspace"`
+	Task      *Task      `json:"task"`
+}
+
+// Clone creates a workspace in the same organization with a copy of the files, snapshots
+// and grants of the source, in a bucket of its own. The new workspace is returned right away
+// while its tree is copied in the background, the returned task tracks the progress.
+func (svc *WorkspaceService) Clone(id string, opts WorkspaceCloneOptions, userID string) (*WorkspaceCloneResult, error) {
+	source, err := svc.workspaceCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.workspaceGuard.Authorize(userID, source, model.PermissionOwner); err != nil {
+		return nil, err
+	}
+	org, err := svc.orgCache.Get(source.GetOrganizationID())
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.orgGuard.Authorize(userID, org, model.PermissionEditor); err != nil {
+		return nil, err
+	}
+	nodes, grants, err := svc.workspaceTree.capture(source.GetRootID(), true, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.checkNewWorkspaceQuota(org, source.GetStorageCapacity(), userID, svc.workspaceTree.size(nodes)); err != nil {
+		return nil, err
+	}
+	workspace, err := svc.createWorkspace(WorkspaceCreateOptions{
+		Name:            opts.Name,
+		OrganizationID:  org.GetID(),
+		StorageCapacity: source.GetStorageCapacity(),
+	}, userID)
+	if err != nil {
+		return nil, err
+	}
+	root, err := svc.createRoot(workspace, userID)
+	if err != nil {
+		return nil, err
+	}
+	workspace, err = svc.associateWorkspaceWithRoot(workspace, root)
+	if err != nil {
+		return nil, err
+	}
+	task, err := svc.createTask("Cloning.", source.GetName(), userID)
+	if err != nil {
+		return nil, err
+	}
+	go func(task model.Task) {
+		err := svc.workspaceTree.materialize(nodes, grants, workspace, userID, svc.workspaceTree.trackProgress(task, svc.taskSvc))
+		svc.completeTask(task, err)
+	}(task)
+	mappedWorkspace, err := svc.workspaceMapper.mapOne(workspace, userID)
+	if err != nil {
+		return nil, err
+	}
+	mappedTask, err := svc.taskMapper.mapOne(task)
+	if err != nil {
+		return nil, err
+	}
+	return &WorkspaceCloneResult{
+		Workspace: mappedWorkspace,
+		Task:      mappedTask,
+	}, nil
+}
+
 func (svc *WorkspaceService) createWorkspace(opts WorkspaceCreateOptions, userID string) (model.Workspace, error) {
 	id := helper.NewID()
 	bucket := strings.ReplaceAll(uuid.NewString(), "-", "")
 	if err := svc.s3.CreateBucket(bucket); err != nil {
 		return nil, err
 	}
-	if opts.StorageCapacity == 0 {
-		opts.StorageCapacity = helper.MegabyteToByte(svc.config.Defaults.WorkspaceStorageCapacityMB)
-	}
 	res, err := svc.workspaceRepo.Insert(repo.WorkspaceInsertOptions{
 		ID:              id,
 		Name:            opts.Name,
@@ -159,7 +277,7 @@ func (svc *WorkspaceService) createRoot(workspace model.Workspace, userID string
 	if err != nil {
 		return nil, err
 	}
-	if err := svc.fileRepo.GrantUserPermission(res.GetID(), userID, model.PermissionOwner); err != nil {
+	if err := svc.fileRepo.GrantUserPermission(res.GetID(), userID, model.PermissionOwner, repo.PermissionGrantOptions{}); err != nil {
 		return nil, err
 	}
 	if _, err := svc.fileCache.Refresh(res.GetID()); err != nil {
@@ -285,7 +403,7 @@ func (svc *WorkspaceService) PatchStorageCapacity(id string, storageCapacity int
 	if err = svc.workspaceGuard.Authorize(userID, workspace, model.PermissionOwner); err != nil {
 		return nil, err
 	}
-	size, err := svc.fileRepo.ComputeSize(workspace.GetRootID())
+	size, err := svc.storageRepo.SumWorkspaceUsage(workspace.GetID())
 	if err != nil {
 		return nil, err
 	}
@@ -305,6 +423,295 @@ func (svc *WorkspaceService) PatchStorageCapacity(id string, storageCapacity int
 	return res, nil
 }
 
+func (svc *WorkspaceService) PatchProcessingPolicy(id string, policy *model.ProcessingPolicy, userID string) (*Workspace, error) {
+	workspace, err := svc.workspaceCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err = svc.workspaceGuard.Authorize(userID, workspace, model.PermissionOwner); err != nil {
+		return nil, err
+	}
+	if err = svc.validateProcessingPolicy(policy); err != nil {
+		return nil, err
+	}
+	if workspace, err = svc.workspaceRepo.UpdateProcessingPolicy(id, policy); err != nil {
+		return nil, err
+	}
+	if err = svc.sync(workspace); err != nil {
+		return nil, err
+	}
+	res, err := svc.workspaceMapper.mapOne(workspace, userID)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (svc *WorkspaceService) validateProcessingPolicy(policy *model.ProcessingPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.Insights != nil {
+		if !NewInsightsService().IsValidLanguage(policy.Insights.LanguageID) {
+			return errorpkg.NewInvalidProcessingPolicyError("languageId")
+		}
+		for _, fileType := range policy.Insights.FileTypes {
+			if fileType != model.ProcessingFileTypePDF &&
+				fileType != model.ProcessingFileTypeOffice &&
+				fileType != model.ProcessingFileTypeText &&
+				fileType != model.ProcessingFileTypeImage {
+				return errorpkg.NewInvalidProcessingPolicyError("fileTypes")
+			}
+		}
+	}
+	for _, value := range policy.SkipPreview {
+		if !strings.HasPrefix(value, ".") && !svc.isValidProcessingFileType(value) {
+			return errorpkg.NewInvalidProcessingPolicyError("skipPreview")
+		}
+	}
+	return nil
+}
+
+func (svc *WorkspaceService) isValidProcessingFileType(value string) bool {
+	return value == model.ProcessingFileTypePDF ||
+		value == model.ProcessingFileTypeOffice ||
+		value == model.ProcessingFileTypeText ||
+		value == model.ProcessingFileTypeImage ||
+		value == model.ProcessingFileTypeVideo ||
+		value == model.ProcessingFileTypeAudio ||
+		value == model.ProcessingFileTypeGLB ||
+		value == model.ProcessingFileTypeModel ||
+		value == model.ProcessingFileTypeEmail ||
+		value == model.ProcessingFileTypeZIP
+}
+
+type WorkspaceArchiveResult struct {
+	Workspace *Workspace `json:"workspace"`
+	Task      *Task      `json:"task"`
+}
+
+// Archive makes the workspace read-only and moves the objects of its snapshots to the archival
+// bucket in the background, thumbnails excepted so the workspace stays browsable. Snapshots shared
+// with other workspaces through copies stay where they are, as those workspaces still use them.
+func (svc *WorkspaceService) Archive(id string, userID string) (*WorkspaceArchiveResult, error) {
+	workspace, err := svc.workspaceCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.workspaceGuard.Authorize(userID, workspace, model.PermissionOwner); err != nil {
+		return nil, err
+	}
+	if svc.config.Archive.Bucket == "" {
+		return nil, errorpkg.NewArchivalNotConfiguredError()
+	}
+	if workspace.GetArchiveStatus() != nil {
+		return nil, errorpkg.NewWorkspaceArchivedError(workspace)
+	}
+	if err := svc.s3.CreateBucket(svc.config.Archive.Bucket); err != nil {
+		return nil, err
+	}
+	snapshots, err := svc.snapshotRepo.FindExclusiveForWorkspace(workspace.GetID())
+	if err != nil {
+		return nil, err
+	}
+	workspace, err = svc.updateArchiveStatus(workspace.GetID(), helper.ToPtr(model.WorkspaceArchiveStatusArchiving), nil)
+	if err != nil {
+		return nil, err
+	}
+	task, err := svc.createTask("Archiving.", workspace.GetName(), userID)
+	if err != nil {
+		return nil, err
+	}
+	go func(task model.Task) {
+		err := svc.moveSnapshots(snapshots, workspace.GetBucket(), svc.config.Archive.Bucket, svc.config.Archive.StorageClass, task)
+		// Moved snapshots keep track of their bucket, so a failed archival leaves an active
+		// workspace whose files are readable wherever they are
+		if err == nil {
+			_, err = svc.updateArchiveStatus(workspace.GetID(), helper.ToPtr(model.WorkspaceArchiveStatusArchived), helper.ToPtr(helper.NewTimestamp()))
+		} else if _, err := svc.updateArchiveStatus(workspace.GetID(), nil, nil); err != nil {
+			log.GetLogger().Error(err)
+		}
+		svc.completeTask(task, err)
+	}(task)
+	return svc.mapArchiveResult(workspace, task, userID)
+}
+
+// Restore moves the objects of the snapshots back to the bucket of the workspace in the
+// background, the workspace becomes writable again once all of them are back.
+func (svc *WorkspaceService) Restore(id string, userID string) (*WorkspaceArchiveResult, error) {
+	workspace, err := svc.workspaceCache.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.workspaceGuard.Authorize(userID, workspace, model.PermissionOwner); err != nil {
+		return nil, err
+	}
+	if workspace.GetArchiveStatus() == nil || *workspace.GetArchiveStatus() != model.WorkspaceArchiveStatusArchived {
+		return nil, errorpkg.NewWorkspaceNotArchivedError(workspace)
+	}
+	snapshots, err := svc.snapshotRepo.FindAllForWorkspace(workspace.GetID())
+	if err != nil {
+		return nil, err
+	}
+	archiveTime := workspace.GetArchiveTime()
+	workspace, err = svc.updateArchiveStatus(workspace.GetID(), helper.ToPtr(model.WorkspaceArchiveStatusRestoring), archiveTime)
+	if err != nil {
+		return nil, err
+	}
+	task, err := svc.createTask("Restoring.", workspace.GetName(), userID)
+	if err != nil {
+		return nil, err
+	}
+	go func(task model.Task) {
+		err := svc.moveSnapshots(snapshots, svc.config.Archive.Bucket, workspace.GetBucket(), "", task)
+		if err == nil {
+			_, err = svc.updateArchiveStatus(workspace.GetID(), nil, nil)
+		} else if _, err := svc.updateArchiveStatus(
+			workspace.GetID(),
+			helper.ToPtr(model.WorkspaceArchiveStatusArchived),
+			archiveTime,
+		); err != nil {
+			log.GetLogger().Error(err)
+		}
+		svc.completeTask(task, err)
+	}(task)
+	return svc.mapArchiveResult(workspace, task, userID)
+}
+
+func (svc *WorkspaceService) updateArchiveStatus(id string, archiveStatus *string, archiveTime *string) (model.Workspace, error) {
+	workspace, err := svc.workspaceRepo.UpdateArchiveStatus(id, archiveStatus, archiveTime)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.sync(workspace); err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
+
+func (svc *WorkspaceService) createTask(name string, object string, userID string) (model.Task, error) {
+	return svc.taskSvc.insertAndSync(repo.TaskInsertOptions{
+		ID:         helper.NewID(),
+		Name:       name,
+		UserID:     userID,
+		Percentage: helper.ToPtr(0),
+		Status:     model.TaskStatusRunning,
+		Payload:    map[string]string{repo.TaskPayloadObjectKey: object},
+	})
+}
+
+func (svc *WorkspaceService) completeTask(task model.Task, err error) {
+	if err != nil {
+		value := err.Error()
+		task.SetError(&value)
+		task.SetStatus(model.TaskStatusError)
+		if err := svc.taskSvc.saveAndSync(task); err != nil {
+			log.GetLogger().Error(err)
+		}
+	} else {
+		if err := svc.taskSvc.deleteAndSync(task.GetID()); err != nil {
+			log.GetLogger().Error(err)
+		}
+	}
+}
+
+func (svc *WorkspaceService) mapArchiveResult(workspace model.Workspace, task model.Task, userID string) (*WorkspaceArchiveResult, error) {
+	mappedWorkspace, err := svc.workspaceMapper.mapOne(workspace, userID)
+	if err != nil {
+		return nil, err
+	}
+	mappedTask, err := svc.taskMapper.mapOne(task)
+	if err != nil {
+		return nil, err
+	}
+	return &WorkspaceArchiveResult{
+		Workspace: mappedWorkspace,
+		Task:      mappedTask,
+	}, nil
+}
+
+// moveSnapshots moves the objects of the snapshots found in the source bucket, thumbnails
+// excepted, and saves each snapshot right after its objects are moved.
+func (svc *WorkspaceService) moveSnapshots(
+	snapshots []model.Snapshot,
+	sourceBucket string,
+	targetBucket string,
+	storageClass string,
+	task model.Task,
+) error {
+	for index, snapshot := range snapshots {
+		if err := svc.moveSnapshot(snapshot, sourceBucket, targetBucket, storageClass); err != nil {
+			return err
+		}
+		percentage := (index + 1) * 100 / len(snapshots)
+		if task.GetPercentage() != nil && *task.GetPercentage() == percentage {
+			continue
+		}
+		task.SetPercentage(&percentage)
+		if err := svc.taskSvc.saveAndSync(task); err != nil {
+			log.GetLogger().Error(err)
+		}
+	}
+	return nil
+}
+
+func (svc *WorkspaceService) moveSnapshot(snapshot model.Snapshot, sourceBucket string, targetBucket string, storageClass string) error {
+	keys, err := svc.s3.ListFolder(snapshot.GetID()+"/", sourceBucket)
+	if err != nil {
+		return err
+	}
+	objects := []*model.S3Object{
+		snapshot.GetOriginal(),
+		snapshot.GetPreview(),
+		snapshot.GetText(),
+		snapshot.GetOCR(),
+		snapshot.GetEntities(),
+		snapshot.GetLayout(),
+		snapshot.GetSummary(),
+		snapshot.GetMetadata(),
+		snapshot.GetSheets(),
+		snapshot.GetMosaic(),
+	}
+	// Objects referenced from outside the folder of the snapshot, like originals
+	// uploaded straight to S3, are moved as well
+	for _, o := range objects {
+		if o != nil && o.Bucket == sourceBucket && !slices.Contains(keys, o.Key) {
+			keys = append(keys, o.Key)
+		}
+	}
+	for _, key := range keys {
+		if snapshot.GetThumbnail() != nil && snapshot.GetThumbnail().Key == key {
+			continue
+		}
+		if err := svc.s3.MoveObject(key, sourceBucket, targetBucket, storageClass); err != nil {
+			return err
+		}
+	}
+	rebase := func(o *model.S3Object) *model.S3Object {
+		if o != nil && o.Bucket == sourceBucket {
+			o.Bucket = targetBucket
+		}
+		return o
+	}
+	snapshot.SetOriginal(rebase(snapshot.GetOriginal()))
+	snapshot.SetPreview(rebase(snapshot.GetPreview()))
+	snapshot.SetText(rebase(snapshot.GetText()))
+	snapshot.SetOCR(rebase(snapshot.GetOCR()))
+	snapshot.SetEntities(rebase(snapshot.GetEntities()))
+	snapshot.SetLayout(rebase(snapshot.GetLayout()))
+	snapshot.SetSummary(rebase(snapshot.GetSummary()))
+	snapshot.SetMetadata(rebase(snapshot.GetMetadata()))
+	snapshot.SetSheets(rebase(snapshot.GetSheets()))
+	snapshot.SetMosaic(rebase(snapshot.GetMosaic()))
+	if err := svc.snapshotRepo.Save(snapshot); err != nil {
+		return err
+	}
+	if _, err := svc.snapshotCache.Refresh(snapshot.GetID()); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (svc *WorkspaceService) Delete(id string, userID string) error {
 	workspace, err := svc.workspaceCache.Get(id)
 	if err != nil {
@@ -313,6 +720,11 @@ func (svc *WorkspaceService) Delete(id string, userID string) error {
 	if err = svc.workspaceGuard.Authorize(userID, workspace, model.PermissionOwner); err != nil {
 		return err
 	}
+	if workspace.GetArchiveStatus() != nil {
+		if err = svc.removeArchivedObjects(workspace); err != nil {
+			return err
+		}
+	}
 	if err = svc.workspaceRepo.Delete(id); err != nil {
 		return err
 	}
@@ -328,16 +740,29 @@ func (svc *WorkspaceService) Delete(id string, userID string) error {
 	return nil
 }
 
-func (svc *WorkspaceService) HasEnoughSpaceForByteSize(id string, byteSize int64) (*bool, error) {
-	workspace, err := svc.workspaceRepo.Find(id)
+// removeArchivedObjects removes the objects that removing the bucket of the workspace
+// leaves behind, as they were moved to the archival bucket.
+func (svc *WorkspaceService) removeArchivedObjects(workspace model.Workspace) error {
+	snapshots, err := svc.snapshotRepo.FindExclusiveForWorkspace(workspace.GetID())
 	if err != nil {
-		return nil, err
+		return err
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.GetOriginal() != nil && snapshot.GetOriginal().Bucket == svc.config.Archive.Bucket {
+			if err := svc.s3.RemoveFolder(snapshot.GetID()+"/", svc.config.Archive.Bucket, minio.RemoveObjectOptions{}); err != nil {
+				return err
+			}
+		}
 	}
-	root, err := svc.fileRepo.Find(workspace.GetRootID())
+	return nil
+}
+
+func (svc *WorkspaceService) HasEnoughSpaceForByteSize(id string, byteSize int64) (*bool, error) {
+	workspace, err := svc.workspaceRepo.Find(id)
 	if err != nil {
 		return nil, err
 	}
-	usage, err := svc.fileRepo.ComputeSize(root.GetID())
+	usage, err := svc.storageRepo.SumWorkspaceUsage(workspace.GetID())
 	if err != nil {
 		return nil, err
 	}
@@ -348,6 +773,58 @@ func (svc *WorkspaceService) HasEnoughSpaceForByteSize(id string, byteSize int64
 	return helper.ToPtr(true), nil
 }
 
+// CheckStorageQuota checks that storing byteSize more bytes fits in the workspace,
+// in its organization, and in the upload quota of the user within that organization.
+func (svc *WorkspaceService) CheckStorageQuota(id string, userID string, byteSize int64) error {
+	ok, err := svc.HasEnoughSpaceForByteSize(id, byteSize)
+	if err != nil {
+		return err
+	}
+	if !*ok {
+		return errorpkg.NewStorageLimitExceededError()
+	}
+	workspace, err := svc.workspaceCache.Get(id)
+	if err != nil {
+		return err
+	}
+	org, err := svc.orgCache.Get(workspace.GetOrganizationID())
+	if err != nil {
+		return err
+	}
+	return svc.checkOrganizationQuota(org, userID, byteSize)
+}
+
+// checkNewWorkspaceQuota checks that byteSize bytes fit in a new workspace of the given capacity,
+// in the organization, and in the upload quota of the user, before copying them there.
+func (svc *WorkspaceService) checkNewWorkspaceQuota(org model.Organization, capacity int64, userID string, byteSize int64) error {
+	if byteSize > capacity {
+		return errorpkg.NewStorageLimitExceededError()
+	}
+	return svc.checkOrganizationQuota(org, userID, byteSize)
+}
+
+func (svc *WorkspaceService) checkOrganizationQuota(org model.Organization, userID string, byteSize int64) error {
+	if org.GetStorageCapacity() != nil {
+		usage, err := svc.storageRepo.SumOrganizationUsage(org.GetID())
+		if err != nil {
+			return err
+		}
+		if usage+byteSize > *org.GetStorageCapacity() {
+			return errorpkg.NewOrganizationStorageLimitExceededError()
+		}
+	}
+	if org.GetMemberStorageCapacity() != nil {
+		usage, err := svc.storageRepo.SumMemberUsage(org.GetID(), userID)
+		if err != nil {
+			return err
+		}
+		if usage+byteSize > *org.GetMemberStorageCapacity() {
+			return errorpkg.NewMemberStorageLimitExceededError()
+		}
+	}
+	return nil
+}
+
 func (svc *WorkspaceService) findAll(userID string) ([]*Workspace, error) {
 	ids, err := svc.workspaceRepo.FindIDs()
 	if err != nil {
@@ -531,17 +1008,21 @@ func (mp *workspaceMapper) mapOne(m model.Workspace, userID string) (*Workspace,
 		return nil, err
 	}
 	res := &Workspace{
-		ID:              m.GetID(),
-		Name:            m.GetName(),
-		RootID:          m.GetRootID(),
-		StorageCapacity: m.GetStorageCapacity(),
-		Organization:    *o,
-		CreateTime:      m.GetCreateTime(),
-		UpdateTime:      m.GetUpdateTime(),
+		ID:               m.GetID(),
+		Name:             m.GetName(),
+		RootID:           m.GetRootID(),
+		StorageCapacity:  m.GetStorageCapacity(),
+		ProcessingPolicy: m.GetProcessingPolicy(),
+		Organization:     *o,
+		ArchiveStatus:    m.GetArchiveStatus(),
+		ArchiveTime:      m.GetArchiveTime(),
+		CreateTime:       m.GetCreateTime(),
+		UpdateTime:       m.GetUpdateTime(),
 	}
 	res.Permission = model.PermissionNone
 	for _, p := range m.GetUserPermissions() {
-		if p.GetUserID() == userID && model.GetPermissionWeight(p.GetValue()) > model.GetPermissionWeight(res.Permission) {
+		if p.GetUserID() == userID && model.GetPermissionWeight(p.GetValue()) > model.GetPermissionWeight(res.Permission) &&
+			model.IsActivePermission(p.GetStartsAt(), p.GetExpiresAt()) {
 			res.Permission = p.GetValue()
 		}
 	}
@@ -551,11 +1032,16 @@ func (mp *workspaceMapper) mapOne(m model.Workspace, userID string) (*Workspace,
 			return nil, err
 		}
 		for _, u := range g.GetMembers() {
-			if u == userID && model.GetPermissionWeight(p.GetValue()) > model.GetPermissionWeight(res.Permission) {
+			if u == userID && model.GetPermissionWeight(p.GetValue()) > model.GetPermissionWeight(res.Permission) &&
+				model.IsActivePermission(p.GetStartsAt(), p.GetExpiresAt()) {
 				res.Permission = p.GetValue()
 			}
 		}
 	}
+	// Organization admins own all workspaces and groups of the organization
+	if o.Role == model.OrganizationRoleAdmin {
+		res.Permission = model.PermissionOwner
+	}
 	return res, nil
 }
 
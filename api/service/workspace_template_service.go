@@ -0,0 +1,676 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package service
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kouprlabs/voltaserve/api/cache"
+	"github.com/kouprlabs/voltaserve/api/errorpkg"
+	"github.com/kouprlabs/voltaserve/api/guard"
+	"github.com/kouprlabs/voltaserve/api/helper"
+	"github.com/kouprlabs/voltaserve/api/infra"
+	"github.com/kouprlabs/voltaserve/api/log"
+	"github.com/kouprlabs/voltaserve/api/model"
+	"github.com/kouprlabs/voltaserve/api/repo"
+	"github.com/kouprlabs/voltaserve/api/search"
+)
+
+// WorkspaceTemplateService saves the folder structure of a workspace, optionally with
+// its files, together with its permission grants, so new workspaces can start from it.
+type WorkspaceTemplateService struct {
+	templateRepo   *repo.WorkspaceTemplateRepo
+	templateMapper *workspaceTemplateMapper
+	workspaceCache *cache.WorkspaceCache
+	workspaceGuard *guard.WorkspaceGuard
+	orgCache       *cache.OrganizationCache
+	orgGuard       *guard.OrganizationGuard
+	fileCache      *cache.FileCache
+	workspaceTree  *workspaceTree
+	taskSvc        *TaskService
+	taskMapper     *taskMapper
+	s3             infra.S3Manager
+}
+
+func NewWorkspaceTemplateService() *WorkspaceTemplateService {
+	return &WorkspaceTemplateService{
+		templateRepo:   repo.NewWorkspaceTemplateRepo(),
+		templateMapper: newWorkspaceTemplateMapper(),
+		workspaceCache: cache.NewWorkspaceCache(),
+		workspaceGuard: guard.NewWorkspaceGuard(),
+		orgCache:       cache.NewOrganizationCache(),
+		orgGuard:       guard.NewOrganizationGuard(),
+		fileCache:      cache.NewFileCache(),
+		workspaceTree:  newWorkspaceTree(),
+		taskSvc:        NewTaskService(),
+		taskMapper:     newTaskMapper(),
+		s3:             infra.NewS3Manager(),
+	}
+}
+
+type WorkspaceTemplate struct {
+	ID             string  `json:"id"`
+	OrganizationID string  `json:"organizationId"`
+	Name           string  `json:"name"`
+	IncludesFiles  bool    `json:"includesFiles"`
+	FolderCount    int     `json:"folderCount"`
+	FileCount      int     `json:"fileCount"`
+	GrantCount     int     `json:"grantCount"`
+	CreateTime     string  `json:"createTime"`
+	UpdateTime     *string `json:"updateTime,omitempty"`
+}
+
+type WorkspaceTemplateCreateOptions struct {
+	WorkspaceID  string `json:"workspaceId"  validate:"required"`
+	Name         string `json:"name"         validate:"required,max=255"`
+	IncludeFiles bool   `json:"includeFiles"`
+}
+
+// Create requires owning the workspace, as the template reveals who it's shared with.
+// When files are included, their snapshots are copied to a bucket of the template,
+// so the template doesn't depend on the workspace staying around. The template is saved
+// in the background once the copies are done, the returned task tracks the progress.
+func (svc *WorkspaceTemplateService) Create(opts WorkspaceTemplateCreateOptions, userID string) (*Task, error) {
+	workspace, err := svc.workspaceCache.Get(opts.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.workspaceGuard.Authorize(userID, workspace, model.PermissionOwner); err != nil {
+		return nil, err
+	}
+	org, err := svc.orgCache.Get(workspace.GetOrganizationID())
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.orgGuard.Authorize(userID, org, model.PermissionEditor); err != nil {
+		return nil, err
+	}
+	nodes, grants, err := svc.workspaceTree.capture(workspace.GetRootID(), opts.IncludeFiles, userID)
+	if err != nil {
+		return nil, err
+	}
+	// The creator's grant on the root only stands for their ownership of the workspace,
+	// whoever creates a workspace from the template owns it instead
+	grants = slices.DeleteFunc(grants, func(grant model.WorkspaceTemplateGrant) bool {
+		return grant.NodeID == workspace.GetRootID() && grant.UserID != nil && *grant.UserID == userID
+	})
+	// Schedules are dropped, as their dates wouldn't make sense for workspaces created later
+	for i := range grants {
+		grants[i].StartsAt = nil
+		grants[i].ExpiresAt = nil
+	}
+	task, err := svc.taskSvc.insertAndSync(repo.TaskInsertOptions{
+		ID:         helper.NewID(),
+		Name:       "Saving template.",
+		UserID:     userID,
+		Percentage: helper.ToPtr(0),
+		Status:     model.TaskStatusRunning,
+		Payload:    map[string]string{repo.TaskPayloadObjectKey: opts.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+	go func(task model.Task) {
+		if err := svc.save(org, opts, nodes, grants, task); err != nil {
+			value := err.Error()
+			task.SetError(&value)
+			task.SetStatus(model.TaskStatusError)
+			if err := svc.taskSvc.saveAndSync(task); err != nil {
+				log.GetLogger().Error(err)
+			}
+		} else {
+			if err := svc.taskSvc.deleteAndSync(task.GetID()); err != nil {
+				log.GetLogger().Error(err)
+			}
+		}
+	}(task)
+	return svc.taskMapper.mapOne(task)
+}
+
+func (svc *WorkspaceTemplateService) save(
+	org model.Organization,
+	opts WorkspaceTemplateCreateOptions,
+	nodes []model.WorkspaceTemplateNode,
+	grants []model.WorkspaceTemplateGrant,
+	task model.Task,
+) error {
+	var bucket *string
+	if opts.IncludeFiles {
+		bucket = helper.ToPtr(strings.ReplaceAll(uuid.NewString(), "-", ""))
+		if err := svc.s3.CreateBucket(*bucket); err != nil {
+			return err
+		}
+		progress := svc.workspaceTree.trackProgress(task, svc.taskSvc)
+		for i, node := range nodes {
+			if node.Snapshot != nil {
+				var err error
+				if nodes[i].Snapshot, err = svc.workspaceTree.copySnapshot(*node.Snapshot, *bucket); err != nil {
+					svc.removeBucket(*bucket)
+					return err
+				}
+			}
+			progress(i+1, len(nodes))
+		}
+	}
+	if _, err := svc.templateRepo.Insert(repo.WorkspaceTemplateInsertOptions{
+		ID:             helper.NewID(),
+		OrganizationID: org.GetID(),
+		Name:           opts.Name,
+		Bucket:         bucket,
+		Nodes:          nodes,
+		Grants:         grants,
+	}); err != nil {
+		if bucket != nil {
+			svc.removeBucket(*bucket)
+		}
+		return err
+	}
+	return nil
+}
+
+func (svc *WorkspaceTemplateService) Find(id string, userID string) (*WorkspaceTemplate, error) {
+	template, err := svc.templateRepo.Find(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.authorize(template, model.PermissionViewer, userID); err != nil {
+		return nil, err
+	}
+	return svc.templateMapper.mapOne(template), nil
+}
+
+func (svc *WorkspaceTemplateService) List(organizationID string, userID string) ([]*WorkspaceTemplate, error) {
+	org, err := svc.orgCache.Get(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.orgGuard.Authorize(userID, org, model.PermissionViewer); err != nil {
+		return nil, err
+	}
+	templates, err := svc.templateRepo.FindByOrganization(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*WorkspaceTemplate, 0)
+	for _, t := range templates {
+		res = append(res, svc.templateMapper.mapOne(t))
+	}
+	return res, nil
+}
+
+func (svc *WorkspaceTemplateService) PatchName(id string, name string, userID string) (*WorkspaceTemplate, error) {
+	template, err := svc.templateRepo.Find(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.authorize(template, model.PermissionOwner, userID); err != nil {
+		return nil, err
+	}
+	template.SetName(name)
+	if err := svc.templateRepo.Save(template); err != nil {
+		return nil, err
+	}
+	return svc.templateMapper.mapOne(template), nil
+}
+
+// Delete doesn't affect the workspaces created from the template, they have copies of its files.
+func (svc *WorkspaceTemplateService) Delete(id string, userID string) error {
+	template, err := svc.templateRepo.Find(id)
+	if err != nil {
+		return err
+	}
+	if err := svc.authorize(template, model.PermissionOwner, userID); err != nil {
+		return err
+	}
+	if err := svc.templateRepo.Delete(id); err != nil {
+		return err
+	}
+	if template.GetBucket() != nil {
+		if err := svc.s3.RemoveBucket(*template.GetBucket()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (svc *WorkspaceTemplateService) authorize(template model.WorkspaceTemplate, permission string, userID string) error {
+	org, err := svc.orgCache.Get(template.GetOrganizationID())
+	if err != nil {
+		return err
+	}
+	return svc.orgGuard.Authorize(userID, org, permission)
+}
+
+func (svc *WorkspaceTemplateService) removeBucket(bucket string) {
+	if err := svc.s3.RemoveBucket(bucket); err != nil {
+		log.GetLogger().Error(err)
+	}
+}
+
+type workspaceTemplateMapper struct{}
+
+func newWorkspaceTemplateMapper() *workspaceTemplateMapper {
+	return &workspaceTemplateMapper{}
+}
+
+func (mp *workspaceTemplateMapper) mapOne(m model.WorkspaceTemplate) *WorkspaceTemplate {
+	res := &WorkspaceTemplate{
+		ID:             m.GetID(),
+		OrganizationID: m.GetOrganizationID(),
+		Name:           m.GetName(),
+		IncludesFiles:  m.GetBucket() != nil,
+		GrantCount:     len(m.GetGrants()),
+		CreateTime:     m.GetCreateTime(),
+		UpdateTime:     m.GetUpdateTime(),
+	}
+	for _, node := range m.GetNodes() {
+		// The root is not counted, it stands for the workspace itself
+		if node.ParentID == nil {
+			continue
+		}
+		if node.Type == model.FileTypeFolder {
+			res.FolderCount++
+		} else {
+			res.FileCount++
+		}
+	}
+	return res
+}
+
+// workspaceTree captures the tree of a workspace as template nodes and grants, and
+// materializes them in another workspace. Templates and clones both go through it.
+type workspaceTree struct {
+	fileRepo      *repo.FileRepo
+	fileCache     *cache.FileCache
+	fileGuard     *guard.FileGuard
+	fileSearch    *search.FileSearch
+	snapshotRepo  *repo.SnapshotRepo
+	orgCache      *cache.OrganizationCache
+	groupCache    *cache.GroupCache
+	roleCache     *cache.RoleCache
+	storageLedger *storageLedger
+	s3            infra.S3Manager
+}
+
+func newWorkspaceTree() *workspaceTree {
+	return &workspaceTree{
+		fileRepo:      repo.NewFileRepo(),
+		fileCache:     cache.NewFileCache(),
+		fileGuard:     guard.NewFileGuard(),
+		fileSearch:    search.NewFileSearch(),
+		snapshotRepo:  repo.NewSnapshotRepo(),
+		orgCache:      cache.NewOrganizationCache(),
+		groupCache:    cache.NewGroupCache(),
+		roleCache:     cache.NewRoleCache(),
+		storageLedger: newStorageLedger(),
+		s3:            infra.NewS3Manager(),
+	}
+}
+
+// capture returns the nodes parents first, with snapshots pointing to the objects of
+// the workspace. Only the explicit grants are kept, the 'viewer' entries letting users
+// reach a shared file are recreated when the grants are applied. The user only captures
+// what they can list, and the files they can download.
+func (svc *workspaceTree) capture(rootID string, includeFiles bool, userID string) ([]model.WorkspaceTemplateNode, []model.WorkspaceTemplateGrant, error) {
+	tree, err := svc.fileRepo.FindTree(rootID)
+	if err != nil {
+		return nil, nil, err
+	}
+	tree = svc.fileGuard.FilterCapability(userID, tree, model.CapabilityList)
+	downloadable := make(map[string]bool)
+	for _, file := range svc.fileGuard.FilterCapability(userID, tree, model.CapabilityDownloadOriginal) {
+		downloadable[file.GetID()] = true
+	}
+	children := make(map[string][]model.File)
+	var root model.File
+	for _, file := range tree {
+		if file.GetID() == rootID {
+			root = file
+		} else if file.GetParentID() != nil {
+			children[*file.GetParentID()] = append(children[*file.GetParentID()], file)
+		}
+	}
+	if root == nil {
+		return nil, nil, errorpkg.NewFileNotFoundError(nil)
+	}
+	nodes := make([]model.WorkspaceTemplateNode, 0)
+	grants := make([]model.WorkspaceTemplateGrant, 0)
+	queue := []model.File{root}
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		if file.GetType() == model.FileTypeFile && (!includeFiles || !downloadable[file.GetID()]) {
+			continue
+		}
+		node := model.WorkspaceTemplateNode{
+			ID:                file.GetID(),
+			Name:              file.GetName(),
+			Type:              file.GetType(),
+			BreaksInheritance: file.GetBreaksInheritance(),
+		}
+		if file.GetID() != rootID {
+			node.ParentID = file.GetParentID()
+		}
+		if file.GetSnapshotID() != nil {
+			if node.Snapshot, err = svc.captureSnapshot(*file.GetSnapshotID()); err != nil {
+				return nil, nil, err
+			}
+		}
+		nodes = append(nodes, node)
+		grants = append(grants, svc.captureGrants(file)...)
+		queue = append(queue, children[file.GetID()]...)
+	}
+	return nodes, grants, nil
+}
+
+func (svc *workspaceTree) captureSnapshot(id string) (*model.WorkspaceTemplateSnapshot, error) {
+	snapshot, err := svc.snapshotRepo.Find(id)
+	if err != nil {
+		return nil, err
+	}
+	if !snapshot.HasOriginal() {
+		return nil, nil
+	}
+	return &model.WorkspaceTemplateSnapshot{
+		ID:                 snapshot.GetID(),
+		Bucket:             snapshot.GetOriginal().Bucket,
+		Original:           snapshot.GetOriginal(),
+		Preview:            snapshot.GetPreview(),
+		Text:               snapshot.GetText(),
+		OCR:                snapshot.GetOCR(),
+		Entities:           snapshot.GetEntities(),
+		Layout:             snapshot.GetLayout(),
+		Summary:            snapshot.GetSummary(),
+		Metadata:           snapshot.GetMetadata(),
+		Sheets:             snapshot.GetSheets(),
+		Mosaic:             snapshot.GetMosaic(),
+		Thumbnail:          snapshot.GetThumbnail(),
+		Language:           snapshot.GetLanguage(),
+		LanguageConfidence: snapshot.GetLanguageConfidence(),
+	}, nil
+}
+
+func (svc *workspaceTree) captureGrants(file model.File) []model.WorkspaceTemplateGrant {
+	res := make([]model.WorkspaceTemplateGrant, 0)
+	for _, p := range file.GetUserPermissions() {
+		if p.GetScope() == model.PermissionScopeTree {
+			res = append(res, model.WorkspaceTemplateGrant{
+				NodeID:     file.GetID(),
+				UserID:     helper.ToPtr(p.GetUserID()),
+				Permission: p.GetValue(),
+				Effect:     p.GetEffect(),
+				RoleID:     p.GetRoleID(),
+				StartsAt:   p.GetStartsAt(),
+				ExpiresAt:  p.GetExpiresAt(),
+			})
+		}
+	}
+	for _, p := range file.GetGroupPermissions() {
+		if p.GetScope() == model.PermissionScopeTree {
+			res = append(res, model.WorkspaceTemplateGrant{
+				NodeID:     file.GetID(),
+				GroupID:    helper.ToPtr(p.GetGroupID()),
+				Permission: p.GetValue(),
+				Effect:     p.GetEffect(),
+				RoleID:     p.GetRoleID(),
+				StartsAt:   p.GetStartsAt(),
+				ExpiresAt:  p.GetExpiresAt(),
+			})
+		}
+	}
+	return res
+}
+
+// size returns the bytes of the originals the nodes hold, which materializing them adds to a workspace.
+func (svc *workspaceTree) size(nodes []model.WorkspaceTemplateNode) int64 {
+	var res int64
+	for _, node := range nodes {
+		if node.Snapshot != nil && node.Snapshot.Original != nil && node.Snapshot.Original.Size != nil {
+			res += *node.Snapshot.Original.Size
+		}
+	}
+	return res
+}
+
+// trackProgress returns a progress callback which syncs the task only when the percentage changes,
+// as snapshots are copied one by one.
+func (svc *workspaceTree) trackProgress(task model.Task, taskSvc *TaskService) func(done int, total int) {
+	return func(done int, total int) {
+		percentage := done * 100 / total
+		if task.GetPercentage() != nil && *task.GetPercentage() == percentage {
+			return
+		}
+		task.SetPercentage(&percentage)
+		if err := taskSvc.saveAndSync(task); err != nil {
+			log.GetLogger().Error(err)
+		}
+	}
+}
+
+// copySnapshot copies the objects of the snapshot to the bucket under a new snapshot ID.
+func (svc *workspaceTree) copySnapshot(snapshot model.WorkspaceTemplateSnapshot, bucket string) (*model.WorkspaceTemplateSnapshot, error) {
+	id := helper.NewID()
+	if err := svc.s3.CopyFolder(snapshot.ID+"/", snapshot.Bucket, id+"/", bucket); err != nil {
+		return nil, err
+	}
+	rebase := func(o *model.S3Object) *model.S3Object {
+		if o == nil {
+			return nil
+		}
+		res := *o
+		res.Bucket = bucket
+		res.Key = id + strings.TrimPrefix(o.Key, snapshot.ID)
+		return &res
+	}
+	// The cached conversion stays in the source bucket, so the copy doesn't reference it
+	preview := rebase(snapshot.Preview)
+	if preview != nil && preview.Document != nil && preview.Document.Cache != nil {
+		document := *preview.Document
+		document.Cache = nil
+		preview.Document = &document
+	}
+	return &model.WorkspaceTemplateSnapshot{
+		ID:                 id,
+		Bucket:             bucket,
+		Original:           rebase(snapshot.Original),
+		Preview:            preview,
+		Text:               rebase(snapshot.Text),
+		OCR:                rebase(snapshot.OCR),
+		Entities:           rebase(snapshot.Entities),
+		Layout:             rebase(snapshot.Layout),
+		Summary:            rebase(snapshot.Summary),
+		Metadata:           rebase(snapshot.Metadata),
+		Sheets:             rebase(snapshot.Sheets),
+		Mosaic:             rebase(snapshot.Mosaic),
+		Thumbnail:          rebase(snapshot.Thumbnail),
+		Language:           snapshot.Language,
+		LanguageConfidence: snapshot.LanguageConfidence,
+	}, nil
+}
+
+// materialize recreates the nodes under the root of the workspace, and applies the grants
+// whose principals still belong to the organization. The grants of the user on the root
+// are skipped, as the user owns the root of the workspace they create.
+func (svc *workspaceTree) materialize(
+	nodes []model.WorkspaceTemplateNode,
+	grants []model.WorkspaceTemplateGrant,
+	workspace model.Workspace,
+	userID string,
+	progress func(done int, total int),
+) error {
+	ids := make(map[string]string)
+	var clones []model.File
+	var mappings []*repo.SnapshotFileEntity
+	for index, node := range nodes {
+		if node.ParentID == nil {
+			ids[node.ID] = workspace.GetRootID()
+			continue
+		}
+		parentID, ok := ids[*node.ParentID]
+		if !ok {
+			continue
+		}
+		clone := repo.NewFile()
+		clone.SetID(helper.NewID())
+		clone.SetParentID(&parentID)
+		clone.SetWorkspaceID(workspace.GetID())
+		clone.SetType(node.Type)
+		clone.SetName(node.Name)
+		clone.SetBreaksInheritance(node.BreaksInheritance)
+		clone.SetCreateTime(time.Now().UTC().Format(time.RFC3339))
+		if node.Snapshot != nil {
+			snapshot, err := svc.insertSnapshot(*node.Snapshot, workspace.GetBucket(), userID)
+			if err != nil {
+				return err
+			}
+			clone.SetSnapshotID(helper.ToPtr(snapshot.GetID()))
+			mappings = append(mappings, &repo.SnapshotFileEntity{
+				SnapshotID: snapshot.GetID(),
+				FileID:     clone.GetID(),
+			})
+		}
+		ids[node.ID] = clone.GetID()
+		clones = append(clones, clone)
+		if progress != nil {
+			progress(index+1, len(nodes))
+		}
+	}
+	const BulkInsertChunkSize = 1000
+	if err := svc.fileRepo.BulkInsert(clones, BulkInsertChunkSize); err != nil {
+		return err
+	}
+	if err := svc.snapshotRepo.BulkMapWithFile(mappings, BulkInsertChunkSize); err != nil {
+		return err
+	}
+	if err := svc.applyGrants(grants, ids, workspace, userID); err != nil {
+		return err
+	}
+	for _, id := range append([]string{workspace.GetRootID()}, svc.ids(clones)...) {
+		if _, err := svc.fileCache.Refresh(id); err != nil {
+			return err
+		}
+	}
+	if err := svc.fileSearch.Index(clones); err != nil {
+		log.GetLogger().Error(err)
+	}
+	if err := svc.fileSearch.IndexPages(clones); err != nil {
+		log.GetLogger().Error(err)
+	}
+	if usage, err := svc.storageLedger.measure(workspace.GetRootID()); err != nil {
+		log.GetLogger().Error(err)
+	} else if err := svc.storageLedger.apply(workspace.GetID(), nil, usage); err != nil {
+		log.GetLogger().Error(err)
+	}
+	return nil
+}
+
+// insertSnapshot attributes the copy to the user, as it counts towards their upload quota.
+func (svc *workspaceTree) insertSnapshot(source model.WorkspaceTemplateSnapshot, bucket string, userID string) (model.Snapshot, error) {
+	copied, err := svc.copySnapshot(source, bucket)
+	if err != nil {
+		return nil, err
+	}
+	res := repo.NewSnapshot()
+	res.SetID(copied.ID)
+	res.SetVersion(1)
+	res.SetUserID(helper.ToPtr(userID))
+	res.SetOriginal(copied.Original)
+	res.SetPreview(copied.Preview)
+	res.SetText(copied.Text)
+	res.SetOCR(copied.OCR)
+	res.SetEntities(copied.Entities)
+	res.SetLayout(copied.Layout)
+	res.SetSummary(copied.Summary)
+	res.SetMetadata(copied.Metadata)
+	res.SetSheets(copied.Sheets)
+	res.SetMosaic(copied.Mosaic)
+	res.SetThumbnail(copied.Thumbnail)
+	res.SetStatus(model.SnapshotStatusReady)
+	if copied.Language != nil {
+		res.SetLanguage(*copied.Language)
+	}
+	res.SetLanguageConfidence(copied.LanguageConfidence)
+	if err := svc.snapshotRepo.Insert(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (svc *workspaceTree) applyGrants(grants []model.WorkspaceTemplateGrant, ids map[string]string, workspace model.Workspace, userID string) error {
+	org, err := svc.orgCache.Get(workspace.GetOrganizationID())
+	if err != nil {
+		return err
+	}
+	for _, grant := range grants {
+		id, ok := ids[grant.NodeID]
+		if !ok {
+			continue
+		}
+		if err := svc.applyGrant(grant, id, workspace, org, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (svc *workspaceTree) applyGrant(grant model.WorkspaceTemplateGrant, id string, workspace model.Workspace, org model.Organization, userID string) error {
+	opts := repo.PermissionGrantOptions{
+		StartsAt:  grant.StartsAt,
+		ExpiresAt: grant.ExpiresAt,
+		GrantedBy: helper.ToPtr(userID),
+		RoleID:    svc.findRoleID(grant.RoleID, org.GetID()),
+	}
+	if grant.UserID != nil {
+		if !slices.Contains(org.GetMembers(), *grant.UserID) ||
+			(*grant.UserID == userID && id == workspace.GetRootID()) {
+			return nil
+		}
+		if grant.Effect == model.PermissionEffectDeny {
+			return svc.fileRepo.DenyUserPermission(id, *grant.UserID, grant.Permission)
+		}
+		return svc.fileRepo.GrantUserPermission(id, *grant.UserID, grant.Permission, opts)
+	}
+	if grant.GroupID != nil {
+		group, err := svc.groupCache.Get(*grant.GroupID)
+		if err != nil || group.GetOrganizationID() != org.GetID() {
+			return nil
+		}
+		if grant.Effect == model.PermissionEffectDeny {
+			return svc.fileRepo.DenyGroupPermission(id, group.GetID(), grant.Permission)
+		}
+		return svc.fileRepo.GrantGroupPermission(id, group.GetID(), grant.Permission, opts)
+	}
+	return nil
+}
+
+// findRoleID drops roles which were deleted since, the grant keeps the permission they stood for.
+func (svc *workspaceTree) findRoleID(roleID *string, orgID string) *string {
+	if roleID == nil {
+		return nil
+	}
+	role, err := svc.roleCache.Get(*roleID)
+	if err != nil || role.GetOrganizationID() != orgID {
+		return nil
+	}
+	return roleID
+}
+
+func (svc *workspaceTree) ids(files []model.File) []string {
+	res := make([]string, 0, len(files))
+	for _, f := range files {
+		res = append(res, f.GetID())
+	}
+	return res
+}
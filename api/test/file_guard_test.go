@@ -0,0 +1,354 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/kouprlabs/voltaserve/api/cache"
+	"github.com/kouprlabs/voltaserve/api/config"
+	"github.com/kouprlabs/voltaserve/api/errorpkg"
+	"github.com/kouprlabs/voltaserve/api/guard"
+	"github.com/kouprlabs/voltaserve/api/helper"
+	"github.com/kouprlabs/voltaserve/api/infra"
+	"github.com/kouprlabs/voltaserve/api/model"
+	"github.com/kouprlabs/voltaserve/api/repo"
+	"github.com/kouprlabs/voltaserve/api/service"
+)
+
+type FileGuardTestSuite struct {
+	suite.Suite
+	fileGuard *guard.FileGuard
+	fileCache *cache.FileCache
+	fileSvc   *service.FileService
+	workspace *service.Workspace
+	userIDs   []string
+}
+
+func (s *FileGuardTestSuite) SetupTest() {
+	userIDs, err := s.createUsers()
+	if err != nil {
+		s.Fail(err.Error())
+		return
+	}
+	org, err := s.createOrganization(userIDs[0])
+	if err != nil {
+		s.Fail(err.Error())
+		return
+	}
+	workspace, err := s.createWorkspace(org.ID, userIDs[0])
+	if err != nil {
+		s.Fail(err.Error())
+	}
+	s.fileGuard = guard.NewFileGuard()
+	s.fileCache = cache.NewFileCache()
+	s.fileSvc = service.NewFileService()
+	s.workspace = workspace
+	s.userIDs = userIDs
+}
+
+func TestFileGuardSuite(t *testing.T) {
+	suite.Run(t, new(FileGuardTestSuite))
+}
+
+func (s *FileGuardTestSuite) TestTreeAllow() {
+	// Create a folder and a file inside it
+	folder, file := s.createTree()
+
+	// Test the file inheriting the permission granted on the folder
+	err := s.fileSvc.GrantUserPermission([]string{folder.ID}, s.userIDs[1], model.PermissionEditor, s.userIDs[0])
+	s.Require().NoError(err)
+	s.Equal(model.PermissionEditor, s.fileGuard.GetPermission(s.userIDs[1], s.find(folder.ID)))
+	s.Equal(model.PermissionEditor, s.fileGuard.GetPermission(s.userIDs[1], s.find(file.ID)))
+	s.Require().NoError(s.fileGuard.Authorize(s.userIDs[1], s.find(file.ID), model.PermissionEditor))
+	s.True(s.fileGuard.HasCapability(s.userIDs[1], s.find(file.ID), model.CapabilityList))
+
+	// Test a higher permission on the file raising the inherited one
+	err = s.fileSvc.GrantUserPermission([]string{file.ID}, s.userIDs[1], model.PermissionOwner, s.userIDs[0])
+	s.Require().NoError(err)
+	s.Equal(model.PermissionOwner, s.fileGuard.GetPermission(s.userIDs[1], s.find(file.ID)))
+	s.Equal(model.PermissionEditor, s.fileGuard.GetPermission(s.userIDs[1], s.find(folder.ID)))
+
+	// Test a user without permissions not finding the file
+	err = s.fileGuard.Authorize(s.userIDs[2], s.find(file.ID), model.PermissionViewer)
+	s.Require().Error(err)
+	s.Equal(errorpkg.NewFileNotFoundError(err).Error(), err.Error())
+}
+
+func (s *FileGuardTestSuite) TestDeny() {
+	// Create a folder and a file inside it
+	folder, file := s.createTree()
+	err := s.fileSvc.GrantUserPermission([]string{folder.ID}, s.userIDs[1], model.PermissionOwner, s.userIDs[0])
+	s.Require().NoError(err)
+
+	// Test a deny on the file capping the permission inherited from the folder
+	err = s.fileSvc.DenyUserPermission([]string{file.ID}, s.userIDs[1], model.PermissionViewer, s.userIDs[0])
+	s.Require().NoError(err)
+	s.Equal(model.PermissionOwner, s.fileGuard.GetPermission(s.userIDs[1], s.find(folder.ID)))
+	s.Equal(model.PermissionViewer, s.fileGuard.GetPermission(s.userIDs[1], s.find(file.ID)))
+	s.False(s.fileGuard.HasCapability(s.userIDs[1], s.find(file.ID), model.CapabilityShare))
+
+	// Test the capped user being told the file exists but is not writable
+	err = s.fileGuard.Authorize(s.userIDs[1], s.find(file.ID), model.PermissionEditor)
+	s.Require().Error(err)
+	s.Equal(errorpkg.NewFilePermissionError(s.userIDs[1], s.find(file.ID), model.PermissionEditor).Error(), err.Error())
+
+	// Test an allow on the file overriding a deny on the folder
+	err = s.fileSvc.GrantUserPermission([]string{file.ID}, s.userIDs[2], model.PermissionEditor, s.userIDs[0])
+	s.Require().NoError(err)
+	err = s.fileSvc.DenyUserPermission([]string{folder.ID}, s.userIDs[2], model.PermissionViewer, s.userIDs[0])
+	s.Require().NoError(err)
+	s.Equal(model.PermissionNone, s.fileGuard.GetPermission(s.userIDs[2], s.find(folder.ID)))
+	s.Equal(model.PermissionEditor, s.fileGuard.GetPermission(s.userIDs[2], s.find(file.ID)))
+
+	// Test denying the own permission
+	err = s.fileSvc.DenyUserPermission([]string{file.ID}, s.userIDs[0], model.PermissionViewer, s.userIDs[0])
+	s.Require().Error(err)
+	s.Equal(errorpkg.NewCannotDenyOwnPermissionError().Error(), err.Error())
+}
+
+func (s *FileGuardTestSuite) TestNodeScope() {
+	// Create a folder with two files inside it
+	folder, file := s.createTree()
+	sibling, err := s.fileSvc.Create(service.FileCreateOptions{
+		WorkspaceID: s.workspace.ID,
+		Name:        "sibling.txt",
+		Type:        model.FileTypeFile,
+		ParentID:    folder.ID,
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+
+	// Test the ancestors being reachable without exposing the siblings
+	err = s.fileSvc.GrantUserPermission([]string{file.ID}, s.userIDs[1], model.PermissionEditor, s.userIDs[0])
+	s.Require().NoError(err)
+	s.Equal(model.PermissionEditor, s.fileGuard.GetPermission(s.userIDs[1], s.find(file.ID)))
+	s.Equal(model.PermissionViewer, s.fileGuard.GetPermission(s.userIDs[1], s.find(folder.ID)))
+	s.Equal(model.PermissionViewer, s.fileGuard.GetPermission(s.userIDs[1], s.find(s.workspace.RootID)))
+	s.Equal(model.PermissionNone, s.fileGuard.GetPermission(s.userIDs[1], s.find(sibling.ID)))
+	filtered := s.fileGuard.Filter(
+		s.userIDs[1],
+		[]model.File{s.find(file.ID), s.find(sibling.ID)},
+		model.PermissionViewer,
+	)
+	s.Require().Len(filtered, 1)
+	s.Equal(file.ID, filtered[0].GetID())
+
+	// Test a file breaking inheritance losing the permission of its parent
+	err = s.fileSvc.GrantUserPermission([]string{folder.ID}, s.userIDs[2], model.PermissionEditor, s.userIDs[0])
+	s.Require().NoError(err)
+	err = s.fileSvc.BreakPermissionInheritance([]string{sibling.ID}, false, s.userIDs[0])
+	s.Require().NoError(err)
+	s.Equal(model.PermissionEditor, s.fileGuard.GetPermission(s.userIDs[2], s.find(file.ID)))
+	s.Equal(model.PermissionNone, s.fileGuard.GetPermission(s.userIDs[2], s.find(sibling.ID)))
+}
+
+func (s *FileGuardTestSuite) TestOrganizationAdmin() {
+	// Create a folder and a file inside it
+	_, file := s.createTree()
+	s.False(s.fileGuard.IsOrganizationAdmin(s.userIDs[1], s.find(file.ID)))
+
+	// Test an admin of the organization getting owner access without grants
+	err := repo.NewOrganizationRepo().GrantUserPermission(
+		s.workspace.Organization.ID,
+		s.userIDs[1],
+		model.GetOrganizationRolePermission(model.OrganizationRoleAdmin),
+	)
+	s.Require().NoError(err)
+	_, err = cache.NewOrganizationCache().Refresh(s.workspace.Organization.ID)
+	s.Require().NoError(err)
+	s.True(s.fileGuard.IsOrganizationAdmin(s.userIDs[1], s.find(file.ID)))
+	permission, capabilities := s.fileGuard.Resolve(s.userIDs[1], s.find(file.ID))
+	s.Equal(model.PermissionOwner, permission)
+	s.ElementsMatch(model.GetPermissionCapabilities(model.PermissionOwner), capabilities)
+
+	// Test a deny not applying to the admin
+	err = s.fileSvc.DenyUserPermission([]string{file.ID}, s.userIDs[1], model.PermissionViewer, s.userIDs[0])
+	s.Require().NoError(err)
+	s.Equal(model.PermissionOwner, s.fileGuard.GetPermission(s.userIDs[1], s.find(file.ID)))
+}
+
+func (s *FileGuardTestSuite) TestSchedule() {
+	// Create a folder and a file inside it
+	_, file := s.createTree()
+
+	// Test a grant starting later not being active yet
+	err := s.fileSvc.GrantUserPermissionWithOptions([]string{file.ID}, s.userIDs[1], service.PermissionGrantOptions{
+		Permission: model.PermissionEditor,
+		Schedule: service.PermissionSchedule{
+			StartsAt: helper.ToPtr(time.Now().Add(time.Hour).Format(time.RFC3339)),
+		},
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+	s.Equal(model.PermissionNone, s.fileGuard.GetPermission(s.userIDs[1], s.find(file.ID)))
+
+	// Test a grant expiring later being active
+	err = s.fileSvc.GrantUserPermissionWithOptions([]string{file.ID}, s.userIDs[2], service.PermissionGrantOptions{
+		Permission: model.PermissionEditor,
+		Schedule: service.PermissionSchedule{
+			ExpiresAt: helper.ToPtr(time.Now().Add(time.Hour).Format(time.RFC3339)),
+		},
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+	s.Equal(model.PermissionEditor, s.fileGuard.GetPermission(s.userIDs[2], s.find(file.ID)))
+
+	// Test granting a permission that already expired
+	err = s.fileSvc.GrantUserPermissionWithOptions([]string{file.ID}, s.userIDs[2], service.PermissionGrantOptions{
+		Permission: model.PermissionEditor,
+		Schedule: service.PermissionSchedule{
+			ExpiresAt: helper.ToPtr(time.Now().Add(-time.Hour).Format(time.RFC3339)),
+		},
+	}, s.userIDs[0])
+	s.Require().Error(err)
+	s.Equal(errorpkg.NewInvalidPermissionScheduleError().Error(), err.Error())
+}
+
+func (s *FileGuardTestSuite) TestGrantLimits() {
+	// Create a folder and a file inside it
+	folder, file := s.createTree()
+
+	// Grant a role that can only list and share the folder
+	role, err := service.NewRoleService().Create(service.RoleCreateOptions{
+		OrganizationID: s.workspace.Organization.ID,
+		Name:           "sharer",
+		Capabilities:   []string{model.CapabilityList, model.CapabilityShare},
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+	err = s.fileSvc.GrantUserPermissionWithOptions([]string{folder.ID}, s.userIDs[1], service.PermissionGrantOptions{
+		Permission: model.PermissionViewer,
+		RoleID:     &role.ID,
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+
+	// Test granting the inherited role on the file
+	err = s.fileSvc.GrantUserPermissionWithOptions([]string{file.ID}, s.userIDs[2], service.PermissionGrantOptions{
+		Permission: model.PermissionViewer,
+		RoleID:     &role.ID,
+	}, s.userIDs[1])
+	s.Require().NoError(err)
+	s.ElementsMatch(
+		[]string{model.CapabilityList, model.CapabilityShare},
+		s.fileGuard.GetCapabilities(s.userIDs[2], s.find(file.ID)),
+	)
+
+	// Test granting a permission above the inherited one
+	err = s.fileSvc.GrantUserPermission([]string{file.ID}, s.userIDs[2], model.PermissionEditor, s.userIDs[1])
+	s.Require().Error(err)
+	s.Equal(errorpkg.NewCannotGrantAboveOwnPermissionError().Error(), err.Error())
+
+	// Test granting capabilities the inherited role doesn't have
+	err = s.fileSvc.GrantUserPermission([]string{file.ID}, s.userIDs[2], model.PermissionViewer, s.userIDs[1])
+	s.Require().Error(err)
+	s.Equal(errorpkg.NewCannotGrantAboveOwnPermissionError().Error(), err.Error())
+
+	// Test granting on the folder, reached through the file only
+	err = s.fileSvc.GrantUserPermission([]string{file.ID}, s.userIDs[2], model.PermissionOwner, s.userIDs[0])
+	s.Require().NoError(err)
+	err = s.fileSvc.GrantUserPermission([]string{folder.ID}, s.userIDs[2], model.PermissionViewer, s.userIDs[2])
+	s.Require().Error(err)
+}
+
+func (s *FileGuardTestSuite) TestArchivedWorkspace() {
+	// Create a folder and a file inside it
+	folder, file := s.createTree()
+	err := s.fileSvc.GrantUserPermission([]string{folder.ID}, s.userIDs[1], model.PermissionEditor, s.userIDs[0])
+	s.Require().NoError(err)
+
+	// Archive the workspace
+	workspace, err := repo.NewWorkspaceRepo().UpdateArchiveStatus(
+		s.workspace.ID,
+		helper.ToPtr(model.WorkspaceArchiveStatusArchived),
+		helper.ToPtr(helper.NewTimestamp()),
+	)
+	s.Require().NoError(err)
+	_, err = cache.NewWorkspaceCache().Refresh(s.workspace.ID)
+	s.Require().NoError(err)
+
+	// Test the editor being capped to a viewer
+	s.Equal(model.PermissionViewer, s.fileGuard.GetPermission(s.userIDs[1], s.find(file.ID)))
+	s.False(s.fileGuard.HasCapability(s.userIDs[1], s.find(file.ID), model.CapabilityRename))
+
+	// Test the editor being forbidden to write
+	_, err = s.fileSvc.PatchName(file.ID, "renamed.txt", s.userIDs[1])
+	s.Require().Error(err)
+	s.Equal(errorpkg.NewWorkspaceArchivedError(workspace).Error(), err.Error())
+	var errorResponse *errorpkg.ErrorResponse
+	s.Require().ErrorAs(err, &errorResponse)
+	s.Equal(http.StatusForbidden, errorResponse.Status)
+}
+
+func (s *FileGuardTestSuite) createTree() (*service.File, *service.File) {
+	folder, err := s.fileSvc.Create(service.FileCreateOptions{
+		WorkspaceID: s.workspace.ID,
+		Name:        "folder",
+		Type:        model.FileTypeFolder,
+		ParentID:    s.workspace.RootID,
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+	file, err := s.fileSvc.Create(service.FileCreateOptions{
+		WorkspaceID: s.workspace.ID,
+		Name:        "file.txt",
+		Type:        model.FileTypeFile,
+		ParentID:    folder.ID,
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+	return folder, file
+}
+
+// find reads the file with its permissions from the database, so each check
+// sees the grants made on its ancestors too.
+func (s *FileGuardTestSuite) find(id string) model.File {
+	file, err := s.fileCache.Refresh(id)
+	s.Require().NoError(err)
+	return file
+}
+
+func (s *FileGuardTestSuite) createUsers() ([]string, error) {
+	db, err := infra.NewPostgresManager().GetDB()
+	if err != nil {
+		return nil, nil
+	}
+	var ids []string
+	for i := range 3 {
+		id := helper.NewID()
+		db = db.Exec("INSERT INTO \"user\" (id, full_name, username, email, password_hash, create_time) VALUES (?, ?, ?, ?, ?, ?)",
+			id, fmt.Sprintf("user %d", i), id+"@voltaserve.com", id+"@voltaserve.com", "", helper.NewTimestamp())
+		if db.Error != nil {
+			return nil, db.Error
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (s *FileGuardTestSuite) createOrganization(userID string) (*service.Organization, error) {
+	org, err := service.NewOrganizationService().Create(service.OrganizationCreateOptions{Name: "organization"}, userID)
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func (s *FileGuardTestSuite) createWorkspace(orgID string, userID string) (*service.Workspace, error) {
+	workspace, err := service.NewWorkspaceService().Create(service.WorkspaceCreateOptions{
+		Name:            "workspace",
+		OrganizationID:  orgID,
+		StorageCapacity: int64(config.GetConfig().Defaults.WorkspaceStorageCapacityMB),
+	}, userID)
+	if err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
@@ -0,0 +1,279 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/kouprlabs/voltaserve/api/config"
+	"github.com/kouprlabs/voltaserve/api/helper"
+	"github.com/kouprlabs/voltaserve/api/infra"
+	"github.com/kouprlabs/voltaserve/api/model"
+	"github.com/kouprlabs/voltaserve/api/repo"
+	"github.com/kouprlabs/voltaserve/api/service"
+)
+
+type FileRepoTestSuite struct {
+	suite.Suite
+	fileRepo       *repo.FileRepo
+	permissionRepo *repo.PermissionRepo
+	fileSvc        *service.FileService
+	workspace      *service.Workspace
+	userIDs        []string
+}
+
+func (s *FileRepoTestSuite) SetupTest() {
+	userIDs, err := s.createUsers()
+	if err != nil {
+		s.Fail(err.Error())
+		return
+	}
+	org, err := s.createOrganization(userIDs[0])
+	if err != nil {
+		s.Fail(err.Error())
+		return
+	}
+	workspace, err := s.createWorkspace(org.ID, userIDs[0])
+	if err != nil {
+		s.Fail(err.Error())
+	}
+	s.fileRepo = repo.NewFileRepo()
+	s.permissionRepo = repo.NewPermissionRepo()
+	s.fileSvc = service.NewFileService()
+	s.workspace = workspace
+	s.userIDs = userIDs
+}
+
+func TestFileRepoSuite(t *testing.T) {
+	suite.Run(t, new(FileRepoTestSuite))
+}
+
+func (s *FileRepoTestSuite) TestGrantUserPermission() {
+	// Create a folder and a file inside it
+	folder, file := s.createTree()
+
+	// Test the file getting a tree entry and its ancestors a node entry
+	err := s.fileRepo.GrantUserPermission(file.ID, s.userIDs[1], model.PermissionEditor, repo.PermissionGrantOptions{})
+	s.Require().NoError(err)
+	p := s.findUserPermission(file.ID, s.userIDs[1])
+	s.Require().NotNil(p)
+	s.Equal(model.PermissionEditor, p.GetPermission())
+	s.Equal(model.PermissionEffectAllow, p.GetEffect())
+	s.Equal(model.PermissionScopeTree, p.GetScope())
+	for _, id := range []string{folder.ID, s.workspace.RootID} {
+		p = s.findUserPermission(id, s.userIDs[1])
+		s.Require().NotNil(p)
+		s.Equal(model.PermissionViewer, p.GetPermission())
+		s.Equal(model.PermissionScopeNode, p.GetScope())
+	}
+
+	// Test a grant on the folder replacing its node entry
+	err = s.fileRepo.GrantUserPermission(folder.ID, s.userIDs[1], model.PermissionViewer, repo.PermissionGrantOptions{})
+	s.Require().NoError(err)
+	p = s.findUserPermission(folder.ID, s.userIDs[1])
+	s.Require().NotNil(p)
+	s.Equal(model.PermissionScopeTree, p.GetScope())
+
+	// Test a grant on the file keeping the tree entry of the folder
+	err = s.fileRepo.GrantUserPermission(file.ID, s.userIDs[1], model.PermissionOwner, repo.PermissionGrantOptions{})
+	s.Require().NoError(err)
+	p = s.findUserPermission(folder.ID, s.userIDs[1])
+	s.Require().NotNil(p)
+	s.Equal(model.PermissionScopeTree, p.GetScope())
+	s.Equal(model.PermissionOwner, s.findUserPermission(file.ID, s.userIDs[1]).GetPermission())
+}
+
+func (s *FileRepoTestSuite) TestDenyUserPermission() {
+	// Create a folder and a file inside it
+	folder, file := s.createTree()
+
+	// Test the deny being stored on the file only
+	err := s.fileRepo.DenyUserPermission(file.ID, s.userIDs[1], model.PermissionViewer)
+	s.Require().NoError(err)
+	p := s.findUserPermission(file.ID, s.userIDs[1])
+	s.Require().NotNil(p)
+	s.Equal(model.PermissionViewer, p.GetPermission())
+	s.Equal(model.PermissionEffectDeny, p.GetEffect())
+	s.Equal(model.PermissionScopeTree, p.GetScope())
+	s.Nil(s.findUserPermission(folder.ID, s.userIDs[1]))
+	s.Nil(s.findUserPermission(s.workspace.RootID, s.userIDs[1]))
+
+	// Test a grant replacing the deny
+	err = s.fileRepo.GrantUserPermission(file.ID, s.userIDs[1], model.PermissionEditor, repo.PermissionGrantOptions{})
+	s.Require().NoError(err)
+	p = s.findUserPermission(file.ID, s.userIDs[1])
+	s.Require().NotNil(p)
+	s.Equal(model.PermissionEffectAllow, p.GetEffect())
+}
+
+func (s *FileRepoTestSuite) TestRevokeUserPermission() {
+	// Create a folder with two files inside it
+	folder, file := s.createTree()
+	sibling, err := s.fileSvc.Create(service.FileCreateOptions{
+		WorkspaceID: s.workspace.ID,
+		Name:        "sibling.txt",
+		Type:        model.FileTypeFile,
+		ParentID:    folder.ID,
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+	err = s.fileRepo.GrantUserPermission(file.ID, s.userIDs[1], model.PermissionEditor, repo.PermissionGrantOptions{})
+	s.Require().NoError(err)
+	err = s.fileRepo.GrantUserPermission(sibling.ID, s.userIDs[1], model.PermissionEditor, repo.PermissionGrantOptions{})
+	s.Require().NoError(err)
+
+	// Test the node entries being kept while they lead to the sibling
+	ids, err := s.fileRepo.RevokeUserPermission(file.ID, s.userIDs[1])
+	s.Require().NoError(err)
+	s.ElementsMatch([]string{file.ID}, ids)
+	s.Nil(s.findUserPermission(file.ID, s.userIDs[1]))
+	s.NotNil(s.findUserPermission(folder.ID, s.userIDs[1]))
+
+	// Test the node entries being pruned with the last grant
+	ids, err = s.fileRepo.RevokeUserPermission(sibling.ID, s.userIDs[1])
+	s.Require().NoError(err)
+	s.ElementsMatch([]string{sibling.ID, folder.ID, s.workspace.RootID}, ids)
+	s.Nil(s.findUserPermission(folder.ID, s.userIDs[1]))
+	s.Nil(s.findUserPermission(s.workspace.RootID, s.userIDs[1]))
+	s.Nil(s.findUserPermission(s.workspace.ID, s.userIDs[1]))
+}
+
+func (s *FileRepoTestSuite) TestRevokeUserPermissionOfFolder() {
+	// Create a folder and a file inside it
+	folder, file := s.createTree()
+	err := s.fileRepo.GrantUserPermission(folder.ID, s.userIDs[1], model.PermissionViewer, repo.PermissionGrantOptions{})
+	s.Require().NoError(err)
+	err = s.fileRepo.GrantUserPermission(file.ID, s.userIDs[1], model.PermissionEditor, repo.PermissionGrantOptions{})
+	s.Require().NoError(err)
+
+	// Test the entries of the descendants being revoked too
+	ids, err := s.fileRepo.RevokeUserPermission(folder.ID, s.userIDs[1])
+	s.Require().NoError(err)
+	s.ElementsMatch([]string{folder.ID, file.ID, s.workspace.RootID}, ids)
+	s.Nil(s.findUserPermission(file.ID, s.userIDs[1]))
+}
+
+func (s *FileRepoTestSuite) TestGrantUserPermissionKeepsWorkspacePermission() {
+	// Create a folder and a file inside it
+	_, file := s.createTree()
+	err := repo.NewWorkspaceRepo().GrantUserPermission(s.workspace.ID, s.userIDs[1], model.PermissionEditor)
+	s.Require().NoError(err)
+
+	// Test a time-limited grant on the file leaving the permission on the workspace as is
+	expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	err = s.fileRepo.GrantUserPermission(file.ID, s.userIDs[1], model.PermissionViewer, repo.PermissionGrantOptions{
+		ExpiresAt: &expiresAt,
+	})
+	s.Require().NoError(err)
+	p := s.findUserPermission(s.workspace.ID, s.userIDs[1])
+	s.Require().NotNil(p)
+	s.Equal(model.PermissionEditor, p.GetPermission())
+	s.Equal(model.PermissionScopeTree, p.GetScope())
+	s.Nil(p.GetExpiresAt())
+
+	// Test the revoke leaving the permission on the workspace too
+	_, err = s.fileRepo.RevokeUserPermission(file.ID, s.userIDs[1])
+	s.Require().NoError(err)
+	s.NotNil(s.findUserPermission(s.workspace.ID, s.userIDs[1]))
+}
+
+func (s *FileRepoTestSuite) TestPruneWorkspacePermission() {
+	// Create a folder and a file inside it
+	_, file := s.createTree()
+	expiresAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	err := s.fileRepo.GrantUserPermission(file.ID, s.userIDs[1], model.PermissionViewer, repo.PermissionGrantOptions{
+		ExpiresAt: &expiresAt,
+	})
+	s.Require().NoError(err)
+	p := s.findUserPermission(s.workspace.ID, s.userIDs[1])
+	s.Require().NotNil(p)
+	s.Equal(model.PermissionScopeNode, p.GetScope())
+	s.Require().NotNil(p.GetExpiresAt())
+	s.Equal(expiresAt, *p.GetExpiresAt())
+
+	// Test the workspace entry being pruned once the grant expired
+	filePermission := s.findUserPermission(file.ID, s.userIDs[1])
+	s.Require().NotNil(filePermission)
+	s.Require().NoError(s.permissionRepo.DeleteUserPermission(filePermission.GetID()))
+	_, err = s.fileRepo.PruneUserAncestorPermissions(file.ID, s.userIDs[1])
+	s.Require().NoError(err)
+	s.Nil(s.findUserPermission(s.workspace.ID, s.userIDs[1]))
+}
+
+func (s *FileRepoTestSuite) createTree() (*service.File, *service.File) {
+	folder, err := s.fileSvc.Create(service.FileCreateOptions{
+		WorkspaceID: s.workspace.ID,
+		Name:        "folder",
+		Type:        model.FileTypeFolder,
+		ParentID:    s.workspace.RootID,
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+	file, err := s.fileSvc.Create(service.FileCreateOptions{
+		WorkspaceID: s.workspace.ID,
+		Name:        "file.txt",
+		Type:        model.FileTypeFile,
+		ParentID:    folder.ID,
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+	return folder, file
+}
+
+func (s *FileRepoTestSuite) findUserPermission(id string, userID string) model.UserPermission {
+	permissions, err := s.permissionRepo.FindUserPermissions(id)
+	s.Require().NoError(err)
+	for _, p := range permissions {
+		if p.GetUserID() == userID {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *FileRepoTestSuite) createUsers() ([]string, error) {
+	db, err := infra.NewPostgresManager().GetDB()
+	if err != nil {
+		return nil, nil
+	}
+	var ids []string
+	for i := range 3 {
+		id := helper.NewID()
+		db = db.Exec("INSERT INTO \"user\" (id, full_name, username, email, password_hash, create_time) VALUES (?, ?, ?, ?, ?, ?)",
+			id, fmt.Sprintf("user %d", i), id+"@voltaserve.com", id+"@voltaserve.com", "", helper.NewTimestamp())
+		if db.Error != nil {
+			return nil, db.Error
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (s *FileRepoTestSuite) createOrganization(userID string) (*service.Organization, error) {
+	org, err := service.NewOrganizationService().Create(service.OrganizationCreateOptions{Name: "organization"}, userID)
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+func (s *FileRepoTestSuite) createWorkspace(orgID string, userID string) (*service.Workspace, error) {
+	workspace, err := service.NewWorkspaceService().Create(service.WorkspaceCreateOptions{
+		Name:            "workspace",
+		OrganizationID:  orgID,
+		StorageCapacity: int64(config.GetConfig().Defaults.WorkspaceStorageCapacityMB),
+	}, userID)
+	if err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
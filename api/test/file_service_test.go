@@ -12,19 +12,25 @@ package test
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/minio/minio-go/v7"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/kouprlabs/voltaserve/api/cache"
+	"github.com/kouprlabs/voltaserve/api/client/conversion_client"
 	"github.com/kouprlabs/voltaserve/api/config"
 	"github.com/kouprlabs/voltaserve/api/errorpkg"
 	"github.com/kouprlabs/voltaserve/api/helper"
 	"github.com/kouprlabs/voltaserve/api/infra"
 	"github.com/kouprlabs/voltaserve/api/model"
+	"github.com/kouprlabs/voltaserve/api/repo"
 	"github.com/kouprlabs/voltaserve/api/service"
 )
 
@@ -406,6 +412,57 @@ func (s *FileServiceTestSuite) TestDownloadOriginalBuffer() {
 	s.Equal(string(content), buf.String())
 }
 
+func (s *FileServiceTestSuite) TestDownloadPreviewBufferOfOriginal() {
+	// Create a file
+	file, err := s.fileSvc.Create(service.FileCreateOptions{
+		WorkspaceID: s.workspace.ID,
+		Name:        "test-file.txt",
+		Type:        model.FileTypeFile,
+		ParentID:    s.workspace.RootID,
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+
+	// Store the file and use its original as preview, like for PDFs and images
+	file, err = s.fileSvc.Store(file.ID, service.FileStoreOptions{
+		Path: helper.ToPtr(filepath.Join("fixtures", "files", "file.txt")),
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+	snapshot, err := cache.NewSnapshotCache().Get(file.Snapshot.ID)
+	s.Require().NoError(err)
+	_, err = service.NewSnapshotService().Patch(file.Snapshot.ID, service.SnapshotPatchOptions{
+		Options: conversion_client.PipelineRunOptions{SnapshotID: file.Snapshot.ID},
+		Fields:  []string{repo.SnapshotFieldPreview},
+		Preview: snapshot.GetOriginal(),
+	})
+	s.Require().NoError(err)
+
+	// Grant a role that can preview the file but not download its original
+	role, err := service.NewRoleService().Create(service.RoleCreateOptions{
+		OrganizationID: s.workspace.Organization.ID,
+		Name:           "previewer",
+		Capabilities:   []string{model.CapabilityList, model.CapabilityPreview},
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+	err = s.fileSvc.GrantUserPermissionWithOptions([]string{file.ID}, s.userIDs[1], service.PermissionGrantOptions{
+		Permission: model.PermissionViewer,
+		RoleID:     &role.ID,
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+
+	// Test the preview being refused as it is the original
+	_, err = s.fileSvc.DownloadPreviewBuffer(file.ID, "", new(bytes.Buffer), s.userIDs[1])
+	s.Require().Error(err)
+	var e *errorpkg.ErrorResponse
+	s.Require().ErrorAs(err, &e)
+	s.Equal(http.StatusForbidden, e.Status)
+
+	// Test the owner still getting the preview
+	buf := new(bytes.Buffer)
+	_, err = s.fileSvc.DownloadPreviewBuffer(file.ID, "", buf, s.userIDs[0])
+	s.Require().NoError(err)
+	s.NotEmpty(buf.String())
+}
+
 func (s *FileServiceTestSuite) TestMove() {
 	// Create two folders
 	folderA, err := s.fileSvc.Create(service.FileCreateOptions{
@@ -488,6 +545,61 @@ func (s *FileServiceTestSuite) TestRevokeUserPermission() {
 	s.Require().NoError(err)
 }
 
+func (s *FileServiceTestSuite) TestGrantAboveOwnPermission() {
+	// Create a file
+	file, err := s.fileSvc.Create(service.FileCreateOptions{
+		WorkspaceID: s.workspace.ID,
+		Name:        "test-file.txt",
+		Type:        model.FileTypeFile,
+		ParentID:    s.workspace.RootID,
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+
+	// Grant a role that can only list and share the file
+	role, err := service.NewRoleService().Create(service.RoleCreateOptions{
+		OrganizationID: s.workspace.Organization.ID,
+		Name:           "sharer",
+		Capabilities:   []string{model.CapabilityList, model.CapabilityShare},
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+	err = s.fileSvc.GrantUserPermissionWithOptions([]string{file.ID}, s.userIDs[1], service.PermissionGrantOptions{
+		Permission: model.PermissionViewer,
+		RoleID:     &role.ID,
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+
+	// Create the user the permissions are granted to
+	others, err := s.createUsers()
+	s.Require().NoError(err)
+	assigneeID := others[0]
+
+	// Test granting a permission above the granter's one
+	err = s.fileSvc.GrantUserPermission([]string{file.ID}, assigneeID, model.PermissionOwner, s.userIDs[1])
+	s.Require().Error(err)
+	s.Equal(errorpkg.NewCannotGrantAboveOwnPermissionError().Error(), err.Error())
+
+	// Test granting capabilities the granter doesn't have
+	err = s.fileSvc.GrantUserPermission([]string{file.ID}, assigneeID, model.PermissionViewer, s.userIDs[1])
+	s.Require().Error(err)
+	s.Equal(errorpkg.NewCannotGrantAboveOwnPermissionError().Error(), err.Error())
+
+	// Test granting the granter's own role
+	err = s.fileSvc.GrantUserPermissionWithOptions([]string{file.ID}, assigneeID, service.PermissionGrantOptions{
+		Permission: model.PermissionViewer,
+		RoleID:     &role.ID,
+	}, s.userIDs[1])
+	s.Require().NoError(err)
+
+	// Test revoking the permission of a user above the granter
+	err = s.fileSvc.RevokeUserPermission([]string{file.ID}, s.userIDs[0], s.userIDs[1])
+	s.Require().Error(err)
+	s.Equal(errorpkg.NewCannotGrantAboveOwnPermissionError().Error(), err.Error())
+
+	// Test breaking inheritance without being owner
+	err = s.fileSvc.BreakPermissionInheritance([]string{file.ID}, true, s.userIDs[1])
+	s.Require().Error(err)
+}
+
 func (s *FileServiceTestSuite) TestGrantGroupPermission() {
 	// Create a file
 	file, err := s.fileSvc.Create(service.FileCreateOptions{
@@ -565,6 +677,61 @@ func (s *FileServiceTestSuite) TestReprocess() {
 	s.Len(reprocessResult.Accepted, 1)
 }
 
+func (s *FileServiceTestSuite) TestSearchText() {
+	// Create a file
+	file, err := s.fileSvc.Create(service.FileCreateOptions{
+		WorkspaceID: s.workspace.ID,
+		Name:        "test-file.txt",
+		Type:        model.FileTypeFile,
+		ParentID:    s.workspace.RootID,
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+
+	// Store the file
+	file, err = s.fileSvc.Store(file.ID, service.FileStoreOptions{
+		Path: helper.ToPtr(filepath.Join("fixtures", "files", "file.txt")),
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+
+	// Attach a layout of three pages, where the query is only found inside a word of the last page
+	layout := model.Layout{Pages: []model.LayoutPage{
+		{Number: 1, Width: 100, Height: 100, Words: []model.LayoutWord{{Text: "Introduction", XMax: 10, YMax: 10}}},
+		{Number: 2, Width: 100, Height: 100, Words: []model.LayoutWord{{Text: "Methods", XMax: 10, YMax: 10}}},
+		{Number: 3, Width: 100, Height: 100, Words: []model.LayoutWord{
+			{Text: "Annual", XMax: 10, YMax: 10},
+			{Text: "report.", XMin: 10, XMax: 20, YMax: 10},
+		}},
+	}}
+	b, err := json.Marshal(layout)
+	s.Require().NoError(err)
+	workspace, err := cache.NewWorkspaceCache().Get(s.workspace.ID)
+	s.Require().NoError(err)
+	key := file.Snapshot.ID + "/layout.json"
+	err = infra.NewS3Manager().PutText(key, string(b), "application/json", workspace.GetBucket(), minio.PutObjectOptions{})
+	s.Require().NoError(err)
+	_, err = service.NewSnapshotService().Patch(file.Snapshot.ID, service.SnapshotPatchOptions{
+		Options: conversion_client.PipelineRunOptions{SnapshotID: file.Snapshot.ID},
+		Fields:  []string{repo.SnapshotFieldLayout},
+		Layout: &model.S3Object{
+			Bucket: workspace.GetBucket(),
+			Key:    key,
+			Size:   helper.ToPtr(int64(len(b))),
+		},
+	})
+	s.Require().NoError(err)
+
+	// Test the hit being found on the last page
+	res, err := s.fileSvc.SearchText(file.ID, "port", s.userIDs[0])
+	s.Require().NoError(err)
+	s.Equal(3, res.TotalPages)
+	s.Equal(1, res.TotalHits)
+	s.Equal([]int{3}, res.MatchedPages)
+	s.Require().Len(res.Hits, 1)
+	s.Equal("Annual report.", res.Hits[0].Snippet)
+	s.Require().Len(res.Hits[0].Boxes, 1)
+	s.InDelta(10.0, res.Hits[0].Boxes[0].XMin, 0)
+}
+
 func (s *FileServiceTestSuite) createUsers() ([]string, error) {
 	db, err := infra.NewPostgresManager().GetDB()
 	if err != nil {
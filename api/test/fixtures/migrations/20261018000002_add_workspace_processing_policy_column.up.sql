@@ -0,0 +1 @@
+ALTER TABLE workspace ADD COLUMN processing_policy jsonb NULL;
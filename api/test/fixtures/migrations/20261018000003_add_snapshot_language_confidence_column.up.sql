@@ -0,0 +1 @@
+ALTER TABLE "snapshot" ADD COLUMN language_confidence float8 NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE file ADD COLUMN breaks_inheritance bool DEFAULT false NOT NULL;
+
+ALTER TABLE userpermission ADD COLUMN effect text DEFAULT 'allow'::text NOT NULL;
+ALTER TABLE userpermission ADD COLUMN "scope" text DEFAULT 'tree'::text NOT NULL;
+
+ALTER TABLE grouppermission ADD COLUMN effect text DEFAULT 'allow'::text NOT NULL;
+ALTER TABLE grouppermission ADD COLUMN "scope" text DEFAULT 'tree'::text NOT NULL;
@@ -0,0 +1,11 @@
+ALTER TABLE userpermission ADD COLUMN starts_at text NULL;
+ALTER TABLE userpermission ADD COLUMN expires_at text NULL;
+ALTER TABLE userpermission ADD COLUMN granted_by text NULL;
+ALTER TABLE userpermission ADD COLUMN reminder_time text NULL;
+CREATE INDEX userpermission_expires_at_idx ON userpermission USING btree (expires_at);
+
+ALTER TABLE grouppermission ADD COLUMN starts_at text NULL;
+ALTER TABLE grouppermission ADD COLUMN expires_at text NULL;
+ALTER TABLE grouppermission ADD COLUMN granted_by text NULL;
+ALTER TABLE grouppermission ADD COLUMN reminder_time text NULL;
+CREATE INDEX grouppermission_expires_at_idx ON grouppermission USING btree (expires_at);
@@ -0,0 +1,17 @@
+CREATE TABLE "role" (
+	id text NOT NULL,
+	organization_id text NOT NULL,
+	"name" text NOT NULL,
+	capabilities jsonb NOT NULL,
+	create_time text NOT NULL,
+	update_time text NULL,
+	CONSTRAINT role_pkey PRIMARY KEY (id),
+	CONSTRAINT role_organization_id_fkey FOREIGN KEY (organization_id) REFERENCES organization(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX role_organization_id_name_idx ON "role" USING btree (organization_id, "name");
+
+ALTER TABLE userpermission ADD COLUMN role_id text NULL;
+ALTER TABLE userpermission ADD CONSTRAINT userpermission_role_id_fkey FOREIGN KEY (role_id) REFERENCES "role"(id) ON DELETE SET NULL;
+
+ALTER TABLE grouppermission ADD COLUMN role_id text NULL;
+ALTER TABLE grouppermission ADD CONSTRAINT grouppermission_role_id_fkey FOREIGN KEY (role_id) REFERENCES "role"(id) ON DELETE SET NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE invitation ADD COLUMN "role" text DEFAULT 'guest'::text NOT NULL;
+ALTER TABLE invitation ADD COLUMN group_ids jsonb NULL;
+ALTER TABLE invitation ADD COLUMN expires_at text NULL;
+CREATE INDEX invitation_expires_at_idx ON invitation USING btree (expires_at);
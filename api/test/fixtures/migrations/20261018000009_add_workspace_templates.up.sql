@@ -0,0 +1,13 @@
+CREATE TABLE workspace_template (
+	id text NOT NULL,
+	organization_id text NOT NULL,
+	"name" text NOT NULL,
+	bucket text NULL,
+	nodes jsonb NOT NULL,
+	grants jsonb NOT NULL,
+	create_time text NOT NULL,
+	update_time text NULL,
+	CONSTRAINT workspace_template_pkey PRIMARY KEY (id),
+	CONSTRAINT workspace_template_organization_id_fkey FOREIGN KEY (organization_id) REFERENCES organization(id) ON DELETE CASCADE
+);
+CREATE INDEX workspace_template_organization_id_idx ON workspace_template USING btree (organization_id);
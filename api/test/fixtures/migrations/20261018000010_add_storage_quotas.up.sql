@@ -0,0 +1,24 @@
+ALTER TABLE organization ADD COLUMN storage_capacity int8 NULL;
+ALTER TABLE organization ADD COLUMN member_storage_capacity int8 NULL;
+
+ALTER TABLE "snapshot" ADD COLUMN user_id text NULL;
+
+CREATE TABLE storage_usage (
+	workspace_id text NOT NULL,
+	user_id text DEFAULT ''::text NOT NULL,
+	category text NOT NULL,
+	history bool NOT NULL,
+	bytes int8 NOT NULL,
+	CONSTRAINT storage_usage_pkey PRIMARY KEY (workspace_id, user_id, category, history),
+	CONSTRAINT storage_usage_workspace_id_fkey FOREIGN KEY (workspace_id) REFERENCES workspace(id) ON DELETE CASCADE
+);
+CREATE INDEX storage_usage_user_id_idx ON storage_usage USING btree (user_id);
+
+CREATE TABLE storage_warning (
+	organization_id text NOT NULL,
+	user_id text DEFAULT ''::text NOT NULL,
+	threshold int4 NOT NULL,
+	update_time text NULL,
+	CONSTRAINT storage_warning_pkey PRIMARY KEY (organization_id, user_id),
+	CONSTRAINT storage_warning_organization_id_fkey FOREIGN KEY (organization_id) REFERENCES organization(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE workspace ADD COLUMN archive_status text NULL;
+ALTER TABLE workspace ADD COLUMN archive_time text NULL;
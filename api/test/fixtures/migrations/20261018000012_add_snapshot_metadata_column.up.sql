@@ -0,0 +1 @@
+ALTER TABLE "snapshot" ADD COLUMN metadata jsonb NULL;
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/kouprlabs/voltaserve/api/cache"
+	"github.com/kouprlabs/voltaserve/api/config"
 	"github.com/kouprlabs/voltaserve/api/errorpkg"
 	"github.com/kouprlabs/voltaserve/api/guard"
 	"github.com/kouprlabs/voltaserve/api/helper"
@@ -236,6 +237,45 @@ func (s *OrganizationServiceSuite) TestRemoveMember() {
 	s.Equal(errorpkg.NewCannotRemoveSoleOwnerOfOrganizationError(org).Error(), err.Error())
 }
 
+func (s *OrganizationServiceSuite) TestRemoveMemberRevokesFilePermissions() {
+	// Create a new organization with a workspace
+	createdOrg, err := s.orgSvc.Create(service.OrganizationCreateOptions{Name: "organization"}, s.userIDs[0])
+	s.Require().NoError(err)
+	workspace, err := service.NewWorkspaceService().Create(service.WorkspaceCreateOptions{
+		Name:            "workspace",
+		OrganizationID:  createdOrg.ID,
+		StorageCapacity: int64(config.GetConfig().Defaults.WorkspaceStorageCapacityMB),
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+
+	// Add another user to the organization and share a file with them
+	err = s.orgRepo.GrantUserPermission(createdOrg.ID, s.userIDs[1], model.PermissionViewer)
+	s.Require().NoError(err)
+	_, err = s.orgCache.Refresh(createdOrg.ID)
+	s.Require().NoError(err)
+	fileSvc := service.NewFileService()
+	file, err := fileSvc.Create(service.FileCreateOptions{
+		WorkspaceID: workspace.ID,
+		Name:        "file.txt",
+		Type:        model.FileTypeFile,
+		ParentID:    workspace.RootID,
+	}, s.userIDs[0])
+	s.Require().NoError(err)
+	err = fileSvc.GrantUserPermission([]string{file.ID}, s.userIDs[1], model.PermissionEditor, s.userIDs[0])
+	s.Require().NoError(err)
+	_, err = fileSvc.Find([]string{file.ID}, s.userIDs[1])
+	s.Require().NoError(err)
+
+	// Test the file being refused to the member once removed
+	err = s.orgSvc.RemoveMember(createdOrg.ID, s.userIDs[1], s.userIDs[0])
+	s.Require().NoError(err)
+	_, err = fileSvc.Find([]string{file.ID}, s.userIDs[1])
+	s.Require().Error(err)
+	var e *errorpkg.ErrorResponse
+	s.Require().ErrorAs(err, &e)
+	s.Equal(errorpkg.NewFileNotFoundError(nil).Code, e.Code)
+}
+
 func (s *OrganizationServiceSuite) createUsers() ([]string, error) {
 	db, err := infra.NewPostgresManager().GetDB()
 	if err != nil {
@@ -0,0 +1,69 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package api_client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/kouprlabs/voltaserve/conversion/config"
+	"github.com/kouprlabs/voltaserve/conversion/infra"
+)
+
+type FileClient struct {
+	config *config.Config
+}
+
+func NewFileClient() *FileClient {
+	return &FileClient{
+		config: config.GetConfig(),
+	}
+}
+
+type FileCreateFromAttachmentsOptions struct {
+	SnapshotID  string           `json:"snapshotId"`
+	Attachments []FileAttachment `json:"attachments"`
+}
+
+type FileAttachment struct {
+	Name        string `json:"name"`
+	SnapshotID  string `json:"snapshotId"`
+	Bucket      string `json:"bucket"`
+	Key         string `json:"key"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"contentType"`
+}
+
+func (cl *FileClient) CreateFromAttachments(opts FileCreateFromAttachmentsOptions) error {
+	body, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/v3/files/create_from_attachments?api_key=%s", cl.config.APIURL, cl.config.Security.APIKey), bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func(Body io.ReadCloser) {
+		if err := Body.Close(); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(resp.Body)
+	return nil
+}
@@ -32,17 +32,23 @@ func NewSnapshotClient() *SnapshotClient {
 }
 
 type SnapshotPatchOptions struct {
-	Options   PipelineRunOptions `json:"options"`
-	Fields    []string           `json:"fields"`
-	Original  *S3Object          `json:"original"`
-	Preview   *S3Object          `json:"preview"`
-	Text      *S3Object          `json:"text"`
-	OCR       *S3Object          `json:"ocr"`
-	Entities  *S3Object          `json:"entities"`
-	Mosaic    *S3Object          `json:"mosaic"`
-	Thumbnail *S3Object          `json:"thumbnail"`
-	Status    *string            `json:"status"`
-	TaskID    *string            `json:"taskId"`
+	Options            PipelineRunOptions `json:"options"`
+	Fields             []string           `json:"fields"`
+	Original           *S3Object          `json:"original"`
+	Preview            *S3Object          `json:"preview"`
+	Text               *S3Object          `json:"text"`
+	OCR                *S3Object          `json:"ocr"`
+	Entities           *S3Object          `json:"entities"`
+	Layout             *S3Object          `json:"layout"`
+	Summary            *S3Object          `json:"summary"`
+	Metadata           *S3Object          `json:"metadata"`
+	Sheets             *S3Object          `json:"sheets"`
+	Mosaic             *S3Object          `json:"mosaic"`
+	Thumbnail          *S3Object          `json:"thumbnail"`
+	Status             *string            `json:"status"`
+	Language           *string            `json:"language"`
+	LanguageConfidence *float64           `json:"languageConfidence"`
+	TaskID             *string            `json:"taskId"`
 }
 
 const (
@@ -52,15 +58,30 @@ const (
 )
 
 const (
-	SnapshotFieldOriginal  = "original"
-	SnapshotFieldPreview   = "preview"
-	SnapshotFieldText      = "text"
-	SnapshotFieldOCR       = "ocr"
-	SnapshotFieldEntities  = "entities"
-	SnapshotFieldMosaic    = "mosaic"
-	SnapshotFieldThumbnail = "thumbnail"
-	SnapshotFieldStatus    = "status"
-	SnapshotFieldTaskID    = "taskId"
+	SnapshotFieldOriginal           = "original"
+	SnapshotFieldPreview            = "preview"
+	SnapshotFieldText               = "text"
+	SnapshotFieldOCR                = "ocr"
+	SnapshotFieldEntities           = "entities"
+	SnapshotFieldLayout             = "layout"
+	SnapshotFieldSummary            = "summary"
+	SnapshotFieldMetadata           = "metadata"
+	SnapshotFieldSheets             = "sheets"
+	SnapshotFieldMosaic             = "mosaic"
+	SnapshotFieldThumbnail          = "thumbnail"
+	SnapshotFieldStatus             = "status"
+	SnapshotFieldLanguage           = "language"
+	SnapshotFieldLanguageConfidence = "languageConfidence"
+	SnapshotFieldTaskID             = "taskId"
+)
+
+const (
+	PayloadLanguage                  = "language"
+	PayloadMosaicThresholdMegapixels = "mosaicThresholdMegapixels"
+	PayloadRedactions                = "redactions"
+	PayloadFirstPage                 = "firstPage"
+	PayloadExtractAttachments        = "extractAttachments"
+	PayloadCacheKey                  = "cacheKey"
 )
 
 type PipelineRunOptions struct {
@@ -78,17 +99,23 @@ type S3Object struct {
 	Size     *int64         `json:"size,omitempty"`
 	Image    *ImageProps    `json:"image,omitempty"`
 	Document *DocumentProps `json:"document,omitempty"`
+	Model    *ModelProps    `json:"model,omitempty"`
+	Workbook *WorkbookProps `json:"workbook,omitempty"`
+	Audio    *AudioProps    `json:"audio,omitempty"`
 }
 
 type ImageProps struct {
 	Width      int         `json:"width"`
 	Height     int         `json:"height"`
+	ColorSpace *string     `json:"colorSpace,omitempty"`
 	ZoomLevels []ZoomLevel `json:"zoomLevels,omitempty"`
 }
 
 type DocumentProps struct {
 	Pages      *PagesProps      `json:"pages,omitempty"`
 	Thumbnails *ThumbnailsProps `json:"thumbnails,omitempty"`
+	Slides     *SlidesProps     `json:"slides,omitempty"`
+	Cache      *S3Object        `json:"cache,omitempty"`
 }
 
 type PagesProps struct {
@@ -100,6 +127,54 @@ type ThumbnailsProps struct {
 	Extension string `json:"extension"`
 }
 
+// SlidesProps describes the images of the slides of presentations, which are
+// the first pages of the PDF preview, so Count can be less than the pages count.
+type SlidesProps struct {
+	Count     int    `json:"count"`
+	Extension string `json:"extension"`
+}
+
+// AudioProps describes the renditions of the audio track of audio and video
+// files, which are stored next to the preview.
+type AudioProps struct {
+	Waveform   *WaveformProps   `json:"waveform,omitempty"`
+	Transcript *TranscriptProps `json:"transcript,omitempty"`
+}
+
+type WaveformProps struct {
+	Peaks     int    `json:"peaks"`
+	Extension string `json:"extension"`
+}
+
+type TranscriptProps struct {
+	Extension string `json:"extension"`
+}
+
+type ModelProps struct {
+	Vertices      int          `json:"vertices"`
+	Triangles     int          `json:"triangles"`
+	Meshes        int          `json:"meshes"`
+	Materials     int          `json:"materials"`
+	HasAnimations bool         `json:"hasAnimations"`
+	BoundingBox   *BoundingBox `json:"boundingBox,omitempty"`
+}
+
+type BoundingBox struct {
+	Min [3]float64 `json:"min"`
+	Max [3]float64 `json:"max"`
+}
+
+type WorkbookProps struct {
+	Sheets []SheetProps `json:"sheets"`
+}
+
+type SheetProps struct {
+	Name      string `json:"name"`
+	Rows      int    `json:"rows"`
+	Columns   int    `json:"columns"`
+	Truncated bool   `json:"truncated"`
+}
+
 type ZoomLevel struct {
 	Index               int     `json:"index"`
 	Width               int     `json:"width"`
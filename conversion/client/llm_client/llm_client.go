@@ -0,0 +1,150 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package llm_client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kouprlabs/voltaserve/conversion/config"
+	"github.com/kouprlabs/voltaserve/conversion/infra"
+	"github.com/kouprlabs/voltaserve/conversion/model"
+)
+
+// summaryMaxInputLength keeps the prompt within the context window of small models.
+const summaryMaxInputLength = 48000
+
+const summaryPrompt = `Summarize the following document in at most 5 sentences, and list its 10 most important keyphrases.
+The language of the document is "%s", answer in the same language.
+Respond only with JSON in the form {"sentences": ["..."], "keyphrases": ["..."]}.
+
+%s`
+
+// LLMClient talks to any provider exposing an OpenAI compatible chat completions API.
+type LLMClient struct {
+	config     *config.Config
+	httpClient *http.Client
+}
+
+func NewLLMClient() *LLMClient {
+	cfg := config.GetConfig()
+	return &LLMClient{
+		config: cfg,
+		httpClient: &http.Client{
+			Timeout: time.Duration(cfg.Summary.LLMTimeoutSeconds) * time.Second,
+		},
+	}
+}
+
+type chatCompletionRequest struct {
+	Model          string                  `json:"model"`
+	Messages       []chatCompletionMessage `json:"messages"`
+	ResponseFormat map[string]string       `json:"response_format,omitempty"`
+}
+
+type chatCompletionMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message chatCompletionMessage `json:"message"`
+	} `json:"choices"`
+}
+
+type summaryResponse struct {
+	Sentences  []string `json:"sentences"`
+	Keyphrases []string `json:"keyphrases"`
+}
+
+func (cl *LLMClient) Summarize(text string, language string) (*model.Summary, error) {
+	if cl.config.Summary.LLMURL == "" || cl.config.Summary.LLMModel == "" {
+		return nil, errors.New("LLM summary provider is not configured")
+	}
+	if len(text) > summaryMaxInputLength {
+		// Cut at the start of a rune, so that the prompt stays valid UTF-8
+		end := summaryMaxInputLength
+		for end > 0 && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		text = text[:end]
+	}
+	b, err := json.Marshal(chatCompletionRequest{
+		Model: cl.config.Summary.LLMModel,
+		Messages: []chatCompletionMessage{
+			{Role: "user", Content: fmt.Sprintf(summaryPrompt, language, text)},
+		},
+		ResponseFormat: map[string]string{"type": "json_object"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(
+		"POST",
+		fmt.Sprintf("%s/chat/completions", strings.TrimSuffix(cl.config.Summary.LLMURL, "/")),
+		bytes.NewBuffer(b),
+	)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cl.config.Summary.LLMAPIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+cl.config.Summary.LLMAPIKey)
+	}
+	resp, err := cl.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func(Body io.ReadCloser) {
+		if err := Body.Close(); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request failed with status %d", resp.StatusCode)
+	}
+	b, err = io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var completion chatCompletionResponse
+	if err := json.Unmarshal(b, &completion); err != nil {
+		return nil, err
+	}
+	if len(completion.Choices) == 0 {
+		return nil, errors.New("LLM returned no choices")
+	}
+	var summary summaryResponse
+	if err := json.Unmarshal([]byte(completion.Choices[0].Message.Content), &summary); err != nil {
+		return nil, err
+	}
+	res := &model.Summary{
+		Sentences:  summary.Sentences,
+		Keyphrases: make([]model.Keyphrase, 0, len(summary.Keyphrases)),
+		Provider:   config.SummaryProviderLLM,
+	}
+	// The model returns keyphrases by importance, we turn the rank into a score
+	for i, keyphrase := range summary.Keyphrases {
+		res.Keyphrases = append(res.Keyphrases, model.Keyphrase{
+			Text:  keyphrase,
+			Score: 1 - float64(i)/float64(len(summary.Keyphrases)),
+		})
+	}
+	return res, nil
+}
@@ -24,6 +24,9 @@ type Config struct {
 	Security        SecurityConfig
 	Limits          LimitsConfig
 	S3              S3Config
+	Summary         SummaryConfig
+	Transcript      TranscriptConfig
+	Color           ColorConfig
 }
 
 type SecurityConfig struct {
@@ -36,6 +39,57 @@ type LimitsConfig struct {
 	ImagePreviewMaxHeight             int
 	MultipartBodyLengthLimitMB        int
 	ImageMosaicTriggerThresholdPixels int
+	// PDFPagesBatchSize is how many page images are rendered at once, the first
+	// batch with the document, and the next ones on demand. Zero renders all pages.
+	PDFPagesBatchSize int
+	// SheetMaxRows and SheetMaxColumns cap each sheet of the structured rendition
+	// of spreadsheets, the cells past them are dropped. Zero keeps all of them.
+	SheetMaxRows    int
+	SheetMaxColumns int
+	// TextPreviewMaxSizeKB caps the part of text files rendered in the preview, the
+	// whole text is still extracted for search. Zero renders all of it.
+	TextPreviewMaxSizeKB int
+	// SlidesMaxCount caps how many slides of presentations are rendered as images,
+	// the remaining slides are still in the PDF preview. Zero renders all of them.
+	SlidesMaxCount int
+}
+
+type SummaryConfig struct {
+	// Provider is either "textrank" (default, runs offline) or "llm".
+	Provider  string
+	LLMURL    string
+	LLMModel  string
+	LLMAPIKey string
+	// LLMTimeoutSeconds bounds a whole request to the LLM, including reading the response.
+	LLMTimeoutSeconds int
+}
+
+const (
+	SummaryProviderTextRank = "textrank"
+	SummaryProviderLLM      = "llm"
+)
+
+// TranscriptConfig selects the local speech-to-text engine used to transcribe
+// audio and video files, no transcript is created when Engine is empty.
+type TranscriptConfig struct {
+	// Engine is either empty (default) or "whisper.cpp".
+	Engine string
+	// Command is the executable of the engine, and Model the path to its model.
+	Command string
+	Model   string
+	// Language is the spoken language as an ISO 639-1 code, or "auto" (default).
+	Language string
+}
+
+const (
+	TranscriptEngineWhisperCpp = "whisper.cpp"
+)
+
+// ColorConfig points to the ICC profiles used to convert previews to sRGB,
+// CMYKProfile is assumed for CMYK images that don't embed a profile.
+type ColorConfig struct {
+	SRGBProfile string
+	CMYKProfile string
 }
 
 type S3Config struct {
@@ -54,6 +108,9 @@ func GetConfig() *Config {
 	readSecurity(config)
 	readS3(config)
 	readLimits(config)
+	readSummary(config)
+	readTranscript(config)
+	readColor(config)
 	return config
 }
 
@@ -136,4 +193,75 @@ func readLimits(config *Config) {
 		}
 		config.Limits.ImageMosaicTriggerThresholdPixels = int(v)
 	}
+	if len(os.Getenv("LIMITS_PDF_PAGES_BATCH_SIZE")) > 0 {
+		v, err := strconv.ParseInt(os.Getenv("LIMITS_PDF_PAGES_BATCH_SIZE"), 10, 32)
+		if err != nil {
+			panic(err)
+		}
+		config.Limits.PDFPagesBatchSize = int(v)
+	}
+	if len(os.Getenv("LIMITS_SHEET_MAX_ROWS")) > 0 {
+		v, err := strconv.ParseInt(os.Getenv("LIMITS_SHEET_MAX_ROWS"), 10, 32)
+		if err != nil {
+			panic(err)
+		}
+		config.Limits.SheetMaxRows = int(v)
+	}
+	if len(os.Getenv("LIMITS_SHEET_MAX_COLUMNS")) > 0 {
+		v, err := strconv.ParseInt(os.Getenv("LIMITS_SHEET_MAX_COLUMNS"), 10, 32)
+		if err != nil {
+			panic(err)
+		}
+		config.Limits.SheetMaxColumns = int(v)
+	}
+	if len(os.Getenv("LIMITS_TEXT_PREVIEW_MAX_SIZE_KB")) > 0 {
+		v, err := strconv.ParseInt(os.Getenv("LIMITS_TEXT_PREVIEW_MAX_SIZE_KB"), 10, 32)
+		if err != nil {
+			panic(err)
+		}
+		config.Limits.TextPreviewMaxSizeKB = int(v)
+	}
+	if len(os.Getenv("LIMITS_SLIDES_MAX_COUNT")) > 0 {
+		v, err := strconv.ParseInt(os.Getenv("LIMITS_SLIDES_MAX_COUNT"), 10, 32)
+		if err != nil {
+			panic(err)
+		}
+		config.Limits.SlidesMaxCount = int(v)
+	}
+}
+
+func readSummary(config *Config) {
+	config.Summary.Provider = SummaryProviderTextRank
+	if len(os.Getenv("SUMMARY_PROVIDER")) > 0 {
+		config.Summary.Provider = os.Getenv("SUMMARY_PROVIDER")
+	}
+	config.Summary.LLMURL = os.Getenv("SUMMARY_LLM_URL")
+	config.Summary.LLMModel = os.Getenv("SUMMARY_LLM_MODEL")
+	config.Summary.LLMAPIKey = os.Getenv("SUMMARY_LLM_API_KEY")
+	config.Summary.LLMTimeoutSeconds = 120
+	if len(os.Getenv("SUMMARY_LLM_TIMEOUT_SECONDS")) > 0 {
+		v, err := strconv.ParseInt(os.Getenv("SUMMARY_LLM_TIMEOUT_SECONDS"), 10, 32)
+		if err != nil {
+			panic(err)
+		}
+		config.Summary.LLMTimeoutSeconds = int(v)
+	}
+}
+
+func readTranscript(config *Config) {
+	config.Transcript.Engine = os.Getenv("TRANSCRIPT_ENGINE")
+	config.Transcript.Command = "whisper-cli"
+	if len(os.Getenv("TRANSCRIPT_COMMAND")) > 0 {
+		config.Transcript.Command = os.Getenv("TRANSCRIPT_COMMAND")
+	}
+	config.Transcript.Model = os.Getenv("TRANSCRIPT_MODEL")
+	config.Transcript.Language = "auto"
+	if len(os.Getenv("TRANSCRIPT_LANGUAGE")) > 0 {
+		config.Transcript.Language = os.Getenv("TRANSCRIPT_LANGUAGE")
+	}
+}
+
+func readColor(config *Config) {
+	config.Color.SRGBProfile = os.Getenv("COLOR_SRGB_PROFILE")
+	config.Color.CMYKProfile = os.Getenv("COLOR_CMYK_PROFILE")
 }
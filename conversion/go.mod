@@ -13,6 +13,7 @@ require (
 	github.com/minio/minio-go/v7 v7.0.73
 	github.com/speps/go-hashids/v2 v2.0.1
 	go.uber.org/zap v1.27.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -62,12 +62,55 @@ func (fi *FileIdentifier) IsOffice(path string) bool {
 	return false
 }
 
+func (fi *FileIdentifier) IsPresentation(path string) bool {
+	extensions := []string{
+		".ppt",
+		".pptx",
+		".odp",
+		".otp",
+		".key",
+	}
+	extension := filepath.Ext(path)
+	for _, v := range extensions {
+		if strings.ToLower(extension) == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (fi *FileIdentifier) IsSpreadsheet(path string) bool {
+	extensions := []string{
+		".xls",
+		".xlsx",
+		".ods",
+		".ots",
+		".numbers",
+		".csv",
+	}
+	extension := filepath.Ext(path)
+	for _, v := range extensions {
+		if strings.ToLower(extension) == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (fi *FileIdentifier) IsCSV(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".csv"
+}
+
+func (fi *FileIdentifier) IsXLSX(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".xlsx"
+}
+
 func (fi *FileIdentifier) IsPlainText(path string) bool {
 	extensions := []string{
 		".txt",
 		".html",
 		".js",
-		"jsx",
+		".jsx",
 		".ts",
 		".tsx",
 		".css",
@@ -86,6 +129,7 @@ func (fi *FileIdentifier) IsPlainText(path string) bool {
 		".yaml",
 		".toml",
 		".md",
+		".markdown",
 		".csv",
 	}
 	extension := filepath.Ext(path)
@@ -97,6 +141,26 @@ func (fi *FileIdentifier) IsPlainText(path string) bool {
 	return false
 }
 
+// IsText tells if the plain text file is previewed as text, HTML files are
+// rendered like documents instead, and CSV files like spreadsheets.
+func (fi *FileIdentifier) IsText(path string) bool {
+	return fi.IsPlainText(path) && !fi.IsSpreadsheet(path) && strings.ToLower(filepath.Ext(path)) != ".html"
+}
+
+func (fi *FileIdentifier) IsMarkdown(path string) bool {
+	extension := strings.ToLower(filepath.Ext(path))
+	return extension == ".md" || extension == ".markdown"
+}
+
+func (fi *FileIdentifier) IsJSON(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".json"
+}
+
+func (fi *FileIdentifier) IsYAML(path string) bool {
+	extension := strings.ToLower(filepath.Ext(path))
+	return extension == ".yml" || extension == ".yaml"
+}
+
 func (fi *FileIdentifier) IsImage(path string) bool {
 	extensions := []string{
 		".xpm",
@@ -110,9 +174,18 @@ func (fi *FileIdentifier) IsImage(path string) bool {
 		".tif",
 		".bmp",
 		".ico",
+		".heic",
 		".heif",
 		".xcf",
 		".svg",
+		".cr2",
+		".cr3",
+		".nef",
+		".arw",
+		".dng",
+		".raf",
+		".psd",
+		".psb",
 	}
 	extension := filepath.Ext(path)
 	for _, v := range extensions {
@@ -206,6 +279,50 @@ func (fi *FileIdentifier) IsGLB(path string) bool {
 	return false
 }
 
+// IsModel matches the 3D formats that are converted to GLB using Blender,
+// STEP goes through FreeCAD first as Blender can't import it.
+func (fi *FileIdentifier) IsModel(path string) bool {
+	extensions := []string{
+		".obj",
+		".fbx",
+		".stl",
+		".ply",
+		".usd",
+		".usda",
+		".usdc",
+		".usdz",
+		".step",
+		".stp",
+	}
+	extension := filepath.Ext(path)
+	for _, v := range extensions {
+		if strings.ToLower(extension) == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (fi *FileIdentifier) IsSTEP(path string) bool {
+	extension := strings.ToLower(filepath.Ext(path))
+	return extension == ".step" || extension == ".stp"
+}
+
+func (fi *FileIdentifier) IsEmail(path string) bool {
+	extensions := []string{
+		".eml",
+		".msg",
+		".mbox",
+	}
+	extension := filepath.Ext(path)
+	for _, v := range extensions {
+		if strings.ToLower(extension) == v {
+			return true
+		}
+	}
+	return false
+}
+
 func (fi *FileIdentifier) IsZIP(path string) bool {
 	extensions := []string{
 		".zip",
@@ -273,3 +390,26 @@ func (fi *FileIdentifier) IsGLTF(path string) (bool, error) {
 	}
 	return hasGLTF && (!hasBin || (gltfFile != nil)), nil
 }
+
+// IsModelBundle inspects a ZIP archive to see if it contains a 3D model, like
+// an OBJ with its MTL and textures.
+func (fi *FileIdentifier) IsModelBundle(path string) (bool, error) {
+	zipFile, err := zip.OpenReader(path)
+	if err != nil {
+		return false, err
+	}
+	defer func(zipFile *zip.ReadCloser) {
+		if err := zipFile.Close(); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(zipFile)
+	for _, file := range zipFile.File {
+		if strings.HasPrefix(file.Name, "__MACOSX/") {
+			continue
+		}
+		if fi.IsModel(file.Name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -30,3 +30,31 @@ func (ii ImageIdentifier) IsTIFF(path string) bool {
 	return filepath.Ext(path) == ".tiff" ||
 		filepath.Ext(path) == ".tif"
 }
+
+func (ii ImageIdentifier) IsHEIF(path string) bool {
+	path = strings.ToLower(path)
+	return filepath.Ext(path) == ".heic" ||
+		filepath.Ext(path) == ".heif"
+}
+
+func (ii ImageIdentifier) IsRAW(path string) bool {
+	path = strings.ToLower(path)
+	return filepath.Ext(path) == ".cr2" ||
+		filepath.Ext(path) == ".cr3" ||
+		filepath.Ext(path) == ".nef" ||
+		filepath.Ext(path) == ".arw" ||
+		filepath.Ext(path) == ".dng" ||
+		filepath.Ext(path) == ".raf"
+}
+
+func (ii ImageIdentifier) IsPSD(path string) bool {
+	path = strings.ToLower(path)
+	return filepath.Ext(path) == ".psd" ||
+		filepath.Ext(path) == ".psb"
+}
+
+// RequiresDecoding tells whether the image cannot be displayed by browsers,
+// so its preview needs to be decoded to JPEG.
+func (ii ImageIdentifier) RequiresDecoding(path string) bool {
+	return ii.IsRAW(path) || ii.IsHEIF(path) || ii.IsPSD(path)
+}
@@ -31,6 +31,10 @@ func (pi *PipelineIdentifier) Identify(opts api_client.PipelineRunOptions) strin
 	} else {
 		if pi.fileIdent.IsPDF(opts.Key) {
 			return model.PipelinePDF
+		} else if pi.fileIdent.IsSpreadsheet(opts.Key) {
+			return model.PipelineSpreadsheet
+		} else if pi.fileIdent.IsText(opts.Key) {
+			return model.PipelineText
 		} else if pi.fileIdent.IsOffice(opts.Key) || pi.fileIdent.IsPlainText(opts.Key) {
 			return model.PipelineOffice
 		} else if pi.fileIdent.IsImage(opts.Key) {
@@ -39,6 +43,10 @@ func (pi *PipelineIdentifier) Identify(opts api_client.PipelineRunOptions) strin
 			return model.PipelineAudioVideo
 		} else if pi.fileIdent.IsGLB(opts.Key) {
 			return model.PipelineGLB
+		} else if pi.fileIdent.IsModel(opts.Key) {
+			return model.PipelineModel
+		} else if pi.fileIdent.IsEmail(opts.Key) {
+			return model.PipelineEmail
 		} else if pi.fileIdent.IsZIP(opts.Key) {
 			return model.PipelineZIP
 		}
@@ -37,6 +37,15 @@ func NewS3Manager() *S3Manager {
 	return mgr
 }
 
+func (mgr *S3Manager) StatObject(objectName string, bucketName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error) {
+	if mgr.client == nil {
+		if err := mgr.Connect(); err != nil {
+			return minio.ObjectInfo{}, err
+		}
+	}
+	return mgr.client.StatObject(context.Background(), bucketName, objectName, opts)
+}
+
 func (mgr *S3Manager) GetFile(objectName string, filePath string, bucketName string, opts minio.GetObjectOptions) error {
 	if mgr.client == nil {
 		if err := mgr.Connect(); err != nil {
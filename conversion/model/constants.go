@@ -11,12 +11,25 @@
 package model
 
 const (
-	PipelinePDF        = "pdf"
-	PipelineOffice     = "office"
-	PipelineImage      = "image"
-	PipelineAudioVideo = "audio_video"
-	PipelineInsights   = "insights"
-	PipelineMosaic     = "mosaic"
-	PipelineGLB        = "glb"
-	PipelineZIP        = "zip"
+	PipelinePDF         = "pdf"
+	PipelinePDFPages    = "pdf_pages"
+	PipelineOffice      = "office"
+	PipelineImage       = "image"
+	PipelineAudioVideo  = "audio_video"
+	PipelineInsights    = "insights"
+	PipelineMosaic      = "mosaic"
+	PipelineGLB         = "glb"
+	PipelineZIP         = "zip"
+	PipelineModel       = "model"
+	PipelineRedact      = "redact"
+	PipelineEmail       = "email"
+	PipelineSpreadsheet = "spreadsheet"
+	PipelineText        = "text"
+)
+
+const (
+	SheetColumnTypeString  = "string"
+	SheetColumnTypeNumber  = "number"
+	SheetColumnTypeBoolean = "boolean"
+	SheetColumnTypeDate    = "date"
 )
@@ -22,3 +22,126 @@ type Pipeline interface {
 type Builder interface {
 	Build(api_client.PipelineRunOptions) error
 }
+
+type Summarizer interface {
+	Summarize(text string, language string) (*Summary, error)
+}
+
+type Layout struct {
+	Pages []LayoutPage `json:"pages"`
+}
+
+type LayoutPage struct {
+	Number int          `json:"number"`
+	Width  float64      `json:"width"`
+	Height float64      `json:"height"`
+	Words  []LayoutWord `json:"words"`
+}
+
+type LayoutWord struct {
+	Text string  `json:"text"`
+	XMin float64 `json:"xMin"`
+	YMin float64 `json:"yMin"`
+	XMax float64 `json:"xMax"`
+	YMax float64 `json:"yMax"`
+}
+
+// Workbook is the structured rendition of a spreadsheet or a CSV file.
+type Workbook struct {
+	Sheets []Sheet `json:"sheets"`
+}
+
+// Sheet holds cells that are strings, numbers, booleans, or nil when empty,
+// dates are strings in RFC 3339. Truncated tells if the sheet has more rows or
+// columns than the rendition kept.
+type Sheet struct {
+	Name      string        `json:"name"`
+	Columns   []SheetColumn `json:"columns"`
+	Rows      [][]any       `json:"rows"`
+	Truncated bool          `json:"truncated"`
+}
+
+// SheetColumn is named after its letter like in spreadsheets, the type is the
+// one shared by all of its cells, or a string when they differ.
+type SheetColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// LanguageDetection holds a tesseract language ID, or a combination of
+// IDs joined with "+" when the text mixes several languages.
+type LanguageDetection struct {
+	Language   string  `json:"language"`
+	Confidence float64 `json:"confidence"`
+}
+
+type Summary struct {
+	Sentences  []string    `json:"sentences"`
+	Keyphrases []Keyphrase `json:"keyphrases"`
+	Provider   string      `json:"provider"`
+}
+
+type Keyphrase struct {
+	Text  string  `json:"text"`
+	Score float64 `json:"score"`
+}
+
+// Redaction is a region to burn out, in the coordinates of the page's layout.
+type Redaction struct {
+	Page       int     `json:"page"`
+	PageWidth  float64 `json:"pageWidth"`
+	PageHeight float64 `json:"pageHeight"`
+	XMin       float64 `json:"xMin"`
+	YMin       float64 `json:"yMin"`
+	XMax       float64 `json:"xMax"`
+	YMax       float64 `json:"yMax"`
+}
+
+// Waveform holds the peaks of the audio track for the player to draw, each
+// peak is the loudest sample of its slice of the track, between 0 and 1.
+type Waveform struct {
+	Duration float64   `json:"duration"`
+	Peaks    []float64 `json:"peaks"`
+}
+
+// MediaMetadata holds the EXIF, IPTC and XMP tags of a file normalized across
+// formats, together with the properties of its streams for audio and video,
+// and the headers of emails.
+type MediaMetadata struct {
+	CaptureTime *string        `json:"captureTime,omitempty"`
+	CameraMake  *string        `json:"cameraMake,omitempty"`
+	CameraModel *string        `json:"cameraModel,omitempty"`
+	Lens        *string        `json:"lens,omitempty"`
+	Location    *GeoLocation   `json:"location,omitempty"`
+	Duration    *float64       `json:"duration,omitempty"`
+	VideoCodec  *string        `json:"videoCodec,omitempty"`
+	AudioCodec  *string        `json:"audioCodec,omitempty"`
+	SampleRate  *int           `json:"sampleRate,omitempty"`
+	Channels    *int           `json:"channels,omitempty"`
+	Bitrate     *int64         `json:"bitrate,omitempty"`
+	Keywords    []string       `json:"keywords,omitempty"`
+	Caption     *string        `json:"caption,omitempty"`
+	Creator     *string        `json:"creator,omitempty"`
+	Copyright   *string        `json:"copyright,omitempty"`
+	Rating      *int           `json:"rating,omitempty"`
+	Email       *EmailMetadata `json:"email,omitempty"`
+}
+
+// EmailMetadata holds the headers of an email, for mailboxes they are the
+// ones of the first message.
+type EmailMetadata struct {
+	From        *string  `json:"from,omitempty"`
+	To          []string `json:"to,omitempty"`
+	Cc          []string `json:"cc,omitempty"`
+	Subject     *string  `json:"subject,omitempty"`
+	Date        *string  `json:"date,omitempty"`
+	MessageID   *string  `json:"messageId,omitempty"`
+	Attachments []string `json:"attachments,omitempty"`
+	Messages    int      `json:"messages"`
+}
+
+type GeoLocation struct {
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Altitude  *float64 `json:"altitude,omitempty"`
+}
@@ -11,6 +11,7 @@
 package pipeline
 
 import (
+	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
@@ -25,9 +26,20 @@ import (
 	"github.com/kouprlabs/voltaserve/conversion/processor"
 )
 
+// waveformPeaks is enough peaks for the player to draw the whole track at the
+// width of a screen.
+const (
+	waveformPeaks       = 2000
+	waveformExtension   = ".json"
+	transcriptExtension = ".vtt"
+)
+
 type audioVideoPipeline struct {
 	videoProc      *processor.VideoProcessor
+	audioProc      *processor.AudioProcessor
+	transcriptProc *processor.TranscriptProcessor
 	imageProc      *processor.ImageProcessor
+	metadataProc   *processor.MetadataProcessor
 	s3             *infra.S3Manager
 	taskClient     *api_client.TaskClient
 	snapshotClient *api_client.SnapshotClient
@@ -37,7 +49,10 @@ type audioVideoPipeline struct {
 func NewAudioVideoPipeline() model.Pipeline {
 	return &audioVideoPipeline{
 		videoProc:      processor.NewVideoProcessor(),
+		audioProc:      processor.NewAudioProcessor(),
+		transcriptProc: processor.NewTranscriptProcessor(),
 		imageProc:      processor.NewImageProcessor(),
+		metadataProc:   processor.NewMetadataProcessor(),
 		s3:             infra.NewS3Manager(),
 		taskClient:     api_client.NewTaskClient(),
 		snapshotClient: api_client.NewSnapshotClient(),
@@ -70,13 +85,27 @@ func (p *audioVideoPipeline) RunFromLocalPath(inputPath string, opts api_client.
 	// Here we intentionally ignore the error, as the media file may contain just audio
 	// Additionally, we don't consider failing to create the thumbnail an error
 	_ = p.createThumbnail(inputPath, opts)
+	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+		Fields: []string{api_client.TaskFieldName},
+		Name:   helper.ToPtr("Extracting metadata."),
+	}); err != nil {
+		return err
+	}
+	// We don't consider failing the extraction of the metadata an error
+	if err := p.createMetadata(inputPath, opts); err != nil {
+		infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+	}
+	audio, err := p.createAudioRenditions(inputPath, opts)
+	if err != nil {
+		return err
+	}
 	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
 		Fields: []string{api_client.TaskFieldName},
 		Name:   helper.ToPtr("Saving preview."),
 	}); err != nil {
 		return err
 	}
-	if err := p.saveOriginalAsPreview(inputPath, opts); err != nil {
+	if err := p.saveOriginalAsPreview(inputPath, audio, opts); err != nil {
 		return err
 	}
 	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
@@ -129,7 +158,111 @@ func (p *audioVideoPipeline) createThumbnail(inputPath string, opts api_client.P
 	return nil
 }
 
-func (p *audioVideoPipeline) saveOriginalAsPreview(inputPath string, opts api_client.PipelineRunOptions) error {
+// createAudioRenditions creates the waveform of the audio track, and its transcript
+// when an engine is configured, failing to create either is not an error.
+func (p *audioVideoPipeline) createAudioRenditions(inputPath string, opts api_client.PipelineRunOptions) (*api_client.AudioProps, error) {
+	hasAudio, err := p.audioProc.HasAudio(inputPath)
+	if err != nil {
+		infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+		return nil, nil
+	}
+	if !hasAudio {
+		return nil, nil
+	}
+	res := &api_client.AudioProps{}
+	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+		Fields: []string{api_client.TaskFieldName},
+		Name:   helper.ToPtr("Creating waveform."),
+	}); err != nil {
+		return nil, err
+	}
+	if waveform, err := p.createWaveform(inputPath, opts); err != nil {
+		infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+	} else {
+		res.Waveform = waveform
+	}
+	if p.transcriptProc.IsEnabled() {
+		if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+			Fields: []string{api_client.TaskFieldName},
+			Name:   helper.ToPtr("Transcribing audio."),
+		}); err != nil {
+			return nil, err
+		}
+		if err := p.createTranscript(inputPath, opts); err != nil {
+			infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+		} else {
+			res.Transcript = &api_client.TranscriptProps{Extension: transcriptExtension}
+		}
+	}
+	if res.Waveform == nil && res.Transcript == nil {
+		return nil, nil
+	}
+	return res, nil
+}
+
+func (p *audioVideoPipeline) createWaveform(inputPath string, opts api_client.PipelineRunOptions) (*api_client.WaveformProps, error) {
+	waveform, err := p.audioProc.Waveform(inputPath, waveformPeaks)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(waveform)
+	if err != nil {
+		return nil, err
+	}
+	key := opts.SnapshotID + "/waveform" + waveformExtension
+	if err := p.s3.PutText(key, string(b), "application/json", opts.Bucket, minio.PutObjectOptions{}); err != nil {
+		return nil, err
+	}
+	return &api_client.WaveformProps{
+		Peaks:     len(waveform.Peaks),
+		Extension: waveformExtension,
+	}, nil
+}
+
+// createTranscript stores the transcript as WebVTT for the players, and its text
+// as the text of the snapshot, so that what is said can be searched.
+func (p *audioVideoPipeline) createTranscript(inputPath string, opts api_client.PipelineRunOptions) error {
+	outputPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + transcriptExtension)
+	defer func(path string) {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(outputPath)
+	if err := p.transcriptProc.Transcribe(inputPath, outputPath); err != nil {
+		return err
+	}
+	if err := p.s3.PutFile(opts.SnapshotID+"/transcript"+transcriptExtension, outputPath, "text/vtt", opts.Bucket, minio.PutObjectOptions{}); err != nil {
+		return err
+	}
+	b, err := os.ReadFile(outputPath)
+	if err != nil {
+		return err
+	}
+	text := p.transcriptProc.Text(string(b))
+	if text == "" {
+		return nil
+	}
+	s3Object := api_client.S3Object{
+		Bucket: opts.Bucket,
+		Key:    opts.SnapshotID + "/text.txt",
+		Size:   helper.ToPtr(int64(len(text))),
+	}
+	if err := p.s3.PutText(s3Object.Key, text, "text/plain", s3Object.Bucket, minio.PutObjectOptions{}); err != nil {
+		return err
+	}
+	if err := p.snapshotClient.Patch(api_client.SnapshotPatchOptions{
+		Options: opts,
+		Fields:  []string{api_client.SnapshotFieldText},
+		Text:    &s3Object,
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *audioVideoPipeline) saveOriginalAsPreview(inputPath string, audio *api_client.AudioProps, opts api_client.PipelineRunOptions) error {
 	stat, err := os.Stat(inputPath)
 	if err != nil {
 		return err
@@ -141,9 +274,38 @@ func (p *audioVideoPipeline) saveOriginalAsPreview(inputPath string, opts api_cl
 			Bucket: opts.Bucket,
 			Key:    opts.Key,
 			Size:   helper.ToPtr(stat.Size()),
+			Audio:  audio,
 		},
 	}); err != nil {
 		return err
 	}
 	return nil
 }
+
+func (p *audioVideoPipeline) createMetadata(inputPath string, opts api_client.PipelineRunOptions) error {
+	metadata, err := p.metadataProc.FromAudioVideo(inputPath)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	content := string(b)
+	s3Object := api_client.S3Object{
+		Bucket: opts.Bucket,
+		Key:    opts.SnapshotID + "/metadata.json",
+		Size:   helper.ToPtr(int64(len(content))),
+	}
+	if err := p.s3.PutText(s3Object.Key, content, "application/json", s3Object.Bucket, minio.PutObjectOptions{}); err != nil {
+		return err
+	}
+	if err := p.snapshotClient.Patch(api_client.SnapshotPatchOptions{
+		Options:  opts,
+		Fields:   []string{api_client.SnapshotFieldMetadata},
+		Metadata: &s3Object,
+	}); err != nil {
+		return err
+	}
+	return nil
+}
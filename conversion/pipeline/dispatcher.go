@@ -11,44 +11,69 @@
 package pipeline
 
 import (
+	"github.com/minio/minio-go/v7"
+
 	"github.com/kouprlabs/voltaserve/conversion/client/api_client"
 	"github.com/kouprlabs/voltaserve/conversion/errorpkg"
 	"github.com/kouprlabs/voltaserve/conversion/helper"
 	"github.com/kouprlabs/voltaserve/conversion/identifier"
+	"github.com/kouprlabs/voltaserve/conversion/infra"
 	"github.com/kouprlabs/voltaserve/conversion/model"
 )
 
 type Dispatcher struct {
-	pipelineIdentifier *identifier.PipelineIdentifier
-	pdfPipeline        model.Pipeline
-	imagePipeline      model.Pipeline
-	officePipeline     model.Pipeline
-	audioVideoPipeline model.Pipeline
-	insightsPipeline   model.Pipeline
-	mosaicPipeline     model.Pipeline
-	glbPipeline        model.Pipeline
-	zipPipeline        model.Pipeline
-	taskClient         *api_client.TaskClient
-	snapshotClient     *api_client.SnapshotClient
+	pipelineIdentifier  *identifier.PipelineIdentifier
+	fileIdent           *identifier.FileIdentifier
+	pdfPipeline         model.Pipeline
+	pdfPagesPipeline    model.Pipeline
+	imagePipeline       model.Pipeline
+	officePipeline      model.Pipeline
+	audioVideoPipeline  model.Pipeline
+	insightsPipeline    model.Pipeline
+	mosaicPipeline      model.Pipeline
+	glbPipeline         model.Pipeline
+	zipPipeline         model.Pipeline
+	modelPipeline       model.Pipeline
+	redactPipeline      model.Pipeline
+	emailPipeline       model.Pipeline
+	spreadsheetPipeline model.Pipeline
+	textPipeline        model.Pipeline
+	s3                  *infra.S3Manager
+	taskClient          *api_client.TaskClient
+	snapshotClient      *api_client.SnapshotClient
 }
 
 func NewDispatcher() *Dispatcher {
 	return &Dispatcher{
-		pipelineIdentifier: identifier.NewPipelineIdentifier(),
-		pdfPipeline:        NewPDFPipeline(),
-		imagePipeline:      NewImagePipeline(),
-		officePipeline:     NewOfficePipeline(),
-		audioVideoPipeline: NewAudioVideoPipeline(),
-		insightsPipeline:   NewInsightsPipeline(),
-		mosaicPipeline:     NewMosaicPipeline(),
-		glbPipeline:        NewGLBPipeline(),
-		zipPipeline:        NewZIPPipeline(),
-		taskClient:         api_client.NewTaskClient(),
-		snapshotClient:     api_client.NewSnapshotClient(),
+		pipelineIdentifier:  identifier.NewPipelineIdentifier(),
+		fileIdent:           identifier.NewFileIdentifier(),
+		pdfPipeline:         NewPDFPipeline(),
+		pdfPagesPipeline:    NewPDFPagesPipeline(),
+		imagePipeline:       NewImagePipeline(),
+		officePipeline:      NewOfficePipeline(),
+		audioVideoPipeline:  NewAudioVideoPipeline(),
+		insightsPipeline:    NewInsightsPipeline(),
+		mosaicPipeline:      NewMosaicPipeline(),
+		glbPipeline:         NewGLBPipeline(),
+		zipPipeline:         NewZIPPipeline(),
+		modelPipeline:       NewModelPipeline(),
+		redactPipeline:      NewRedactPipeline(),
+		emailPipeline:       NewEmailPipeline(),
+		spreadsheetPipeline: NewSpreadsheetPipeline(),
+		textPipeline:        NewTextPipeline(),
+		s3:                  infra.NewS3Manager(),
+		taskClient:          api_client.NewTaskClient(),
+		snapshotClient:      api_client.NewSnapshotClient(),
 	}
 }
 
 func (d *Dispatcher) Dispatch(opts api_client.PipelineRunOptions) error {
+	id := d.pipelineIdentifier.Identify(opts)
+	// The batches of pages the API requests on demand run without a task, and
+	// leave the status of the snapshot as is
+	if id == model.PipelinePDFPages && opts.TaskID == "" {
+		return d.pdfPagesPipeline.Run(opts)
+	}
 	if err := d.snapshotClient.Patch(api_client.SnapshotPatchOptions{
 		Options: opts,
 		Fields:  []string{api_client.SnapshotFieldStatus},
@@ -63,10 +88,11 @@ func (d *Dispatcher) Dispatch(opts api_client.PipelineRunOptions) error {
 	}); err != nil {
 		return err
 	}
-	id := d.pipelineIdentifier.Identify(opts)
 	var err error
 	if id == model.PipelinePDF {
 		err = d.pdfPipeline.Run(opts)
+	} else if id == model.PipelinePDFPages {
+		err = d.pdfPagesPipeline.Run(opts)
 	} else if id == model.PipelineOffice {
 		err = d.officePipeline.Run(opts)
 	} else if id == model.PipelineImage {
@@ -81,6 +107,22 @@ func (d *Dispatcher) Dispatch(opts api_client.PipelineRunOptions) error {
 		err = d.glbPipeline.Run(opts)
 	} else if id == model.PipelineZIP {
 		err = d.zipPipeline.Run(opts)
+	} else if id == model.PipelineModel {
+		err = d.modelPipeline.Run(opts)
+	} else if id == model.PipelineRedact {
+		err = d.redactPipeline.Run(opts)
+	} else if id == model.PipelineEmail {
+		err = d.emailPipeline.Run(opts)
+	} else if id == model.PipelineSpreadsheet {
+		err = d.spreadsheetPipeline.Run(opts)
+	} else if id == model.PipelineText {
+		err = d.textPipeline.Run(opts)
+	}
+	if err == nil && id != model.PipelineInsights {
+		// The automatic insights don't fail the pipeline that produced the preview
+		if err := d.runAutomaticInsights(id, opts); err != nil {
+			infra.GetLogger().Error(err)
+		}
 	}
 	if err != nil {
 		if err := d.snapshotClient.Patch(api_client.SnapshotPatchOptions{
@@ -112,3 +154,24 @@ func (d *Dispatcher) Dispatch(opts api_client.PipelineRunOptions) error {
 		return nil
 	}
 }
+
+// runAutomaticInsights runs the insights pipeline right after the preview when the
+// workspace's processing policy requested it through the language payload.
+func (d *Dispatcher) runAutomaticInsights(id string, opts api_client.PipelineRunOptions) error {
+	if opts.Payload == nil || opts.Payload[api_client.PayloadLanguage] == "" {
+		return nil
+	}
+	insightsOpts := opts
+	insightsOpts.PipelineID = helper.ToPtr(model.PipelineInsights)
+	if id == model.PipelinePDF || id == model.PipelineImage || id == model.PipelineText {
+		return d.insightsPipeline.Run(insightsOpts)
+	} else if id == model.PipelineOffice || id == model.PipelineEmail || id == model.PipelineSpreadsheet {
+		insightsOpts.Key = opts.SnapshotID + "/preview.pdf"
+		if _, err := d.s3.StatObject(insightsOpts.Key, opts.Bucket, minio.StatObjectOptions{}); err != nil {
+			/* The office pipeline fell back to previewing the text */
+			insightsOpts.Key = opts.SnapshotID + "/text.txt"
+		}
+		return d.insightsPipeline.Run(insightsOpts)
+	}
+	return nil
+}
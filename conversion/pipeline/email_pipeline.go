@@ -0,0 +1,182 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/kouprlabs/voltaserve/conversion/client/api_client"
+	"github.com/kouprlabs/voltaserve/conversion/helper"
+	"github.com/kouprlabs/voltaserve/conversion/infra"
+	"github.com/kouprlabs/voltaserve/conversion/model"
+	"github.com/kouprlabs/voltaserve/conversion/processor"
+)
+
+type emailPipeline struct {
+	officePipeline model.Pipeline
+	emailProc      *processor.EmailProcessor
+	s3             *infra.S3Manager
+	taskClient     *api_client.TaskClient
+	snapshotClient *api_client.SnapshotClient
+	fileClient     *api_client.FileClient
+}
+
+func NewEmailPipeline() model.Pipeline {
+	return &emailPipeline{
+		officePipeline: NewOfficePipeline(),
+		emailProc:      processor.NewEmailProcessor(),
+		s3:             infra.NewS3Manager(),
+		taskClient:     api_client.NewTaskClient(),
+		snapshotClient: api_client.NewSnapshotClient(),
+		fileClient:     api_client.NewFileClient(),
+	}
+}
+
+func (p *emailPipeline) Run(opts api_client.PipelineRunOptions) error {
+	inputPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + filepath.Ext(opts.Key))
+	if err := p.s3.GetFile(opts.Key, inputPath, opts.Bucket, minio.GetObjectOptions{}); err != nil {
+		return err
+	}
+	defer func(path string) {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(inputPath)
+	return p.RunFromLocalPath(inputPath, opts)
+}
+
+// RunFromLocalPath renders the messages to HTML, which the office pipeline converts
+// to the PDF preview, and extracts the text from.
+func (p *emailPipeline) RunFromLocalPath(inputPath string, opts api_client.PipelineRunOptions) error {
+	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+		Fields: []string{api_client.TaskFieldName},
+		Name:   helper.ToPtr("Reading email."),
+	}); err != nil {
+		return err
+	}
+	emails, err := p.emailProc.Read(inputPath)
+	if err != nil {
+		return err
+	}
+	// We don't consider failing to save the metadata an error
+	if err := p.createMetadata(emails, opts); err != nil {
+		infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+	}
+	if opts.Payload != nil && opts.Payload[api_client.PayloadExtractAttachments] == "true" {
+		if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+			Fields: []string{api_client.TaskFieldName},
+			Name:   helper.ToPtr("Extracting attachments."),
+		}); err != nil {
+			return err
+		}
+		// Nor failing to extract the attachments, the message itself can still be viewed
+		if err := p.extractAttachments(emails, opts); err != nil {
+			infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+		}
+	}
+	htmlPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + ".html")
+	if err := os.WriteFile(htmlPath, []byte(p.emailProc.HTML(emails)), 0o600); err != nil {
+		return err
+	}
+	defer func(path string) {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(htmlPath)
+	return p.officePipeline.RunFromLocalPath(htmlPath, opts)
+}
+
+func (p *emailPipeline) createMetadata(emails []*processor.Email, opts api_client.PipelineRunOptions) error {
+	b, err := json.Marshal(model.MediaMetadata{Email: p.emailProc.Metadata(emails)})
+	if err != nil {
+		return err
+	}
+	content := string(b)
+	s3Object := api_client.S3Object{
+		Bucket: opts.Bucket,
+		Key:    opts.SnapshotID + "/metadata.json",
+		Size:   helper.ToPtr(int64(len(content))),
+	}
+	if err := p.s3.PutText(s3Object.Key, content, "application/json", s3Object.Bucket, minio.PutObjectOptions{}); err != nil {
+		return err
+	}
+	if err := p.snapshotClient.Patch(api_client.SnapshotPatchOptions{
+		Options:  opts,
+		Fields:   []string{api_client.SnapshotFieldMetadata},
+		Metadata: &s3Object,
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// extractAttachments uploads the attachments as the originals of new snapshots,
+// the API creates the files next to the message and processes them.
+func (p *emailPipeline) extractAttachments(emails []*processor.Email, opts api_client.PipelineRunOptions) error {
+	var attachments []api_client.FileAttachment
+	for _, email := range emails {
+		for _, attachment := range email.Attachments {
+			if attachment.Inline {
+				continue
+			}
+			res, err := p.uploadAttachment(attachment, opts)
+			if err != nil {
+				return err
+			}
+			attachments = append(attachments, *res)
+		}
+	}
+	if len(attachments) == 0 {
+		return nil
+	}
+	return p.fileClient.CreateFromAttachments(api_client.FileCreateFromAttachmentsOptions{
+		SnapshotID:  opts.SnapshotID,
+		Attachments: attachments,
+	})
+}
+
+func (p *emailPipeline) uploadAttachment(attachment processor.EmailAttachment, opts api_client.PipelineRunOptions) (*api_client.FileAttachment, error) {
+	extension := strings.ToLower(filepath.Ext(attachment.Name))
+	path := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + extension)
+	if err := os.WriteFile(path, attachment.Content, 0o600); err != nil {
+		return nil, err
+	}
+	defer func(path string) {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(path)
+	snapshotID := helper.NewID()
+	res := &api_client.FileAttachment{
+		Name:        attachment.Name,
+		SnapshotID:  snapshotID,
+		Bucket:      opts.Bucket,
+		Key:         snapshotID + "/original" + extension,
+		Size:        int64(len(attachment.Content)),
+		ContentType: helper.DetectMimeFromFile(path),
+	}
+	if err := p.s3.PutFile(res.Key, path, res.ContentType, res.Bucket, minio.PutObjectOptions{}); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -69,13 +69,18 @@ func (p *glbPipeline) RunFromLocalPath(inputPath string, opts api_client.Pipelin
 	}
 	// We don't consider failing to create the thumbnail an error
 	_ = p.createThumbnail(inputPath, opts)
-	if err := p.patchSnapshotPreviewField(inputPath, opts); err != nil {
+	// Nor failing to read the statistics, the preview works without them
+	props, err := p.glbProc.Statistics(inputPath)
+	if err != nil {
+		infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+	}
+	if err := p.patchSnapshotPreviewField(inputPath, props, opts); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (p *glbPipeline) patchSnapshotPreviewField(inputPath string, opts api_client.PipelineRunOptions) error {
+func (p *glbPipeline) patchSnapshotPreviewField(inputPath string, props *api_client.ModelProps, opts api_client.PipelineRunOptions) error {
 	stat, err := os.Stat(inputPath)
 	if err != nil {
 		return err
@@ -89,12 +94,13 @@ func (p *glbPipeline) patchSnapshotPreviewField(inputPath string, opts api_clien
 				Bucket: opts.Bucket,
 				Key:    opts.Key,
 				Size:   helper.ToPtr(stat.Size()),
+				Model:  props,
 			},
 		}); err != nil {
 			return err
 		}
 	} else {
-		/* The original is likely an .zip glTF file */
+		/* The original is likely an .zip glTF file, or another 3D format converted to GLB */
 		if err := p.snapshotClient.Patch(api_client.SnapshotPatchOptions{
 			Options: opts,
 			Fields:  []string{api_client.SnapshotFieldPreview},
@@ -102,6 +108,7 @@ func (p *glbPipeline) patchSnapshotPreviewField(inputPath string, opts api_clien
 				Bucket: opts.Bucket,
 				Key:    filepath.FromSlash(opts.SnapshotID + "/preview" + filepath.Ext(inputPath)),
 				Size:   helper.ToPtr(stat.Size()),
+				Model:  props,
 			},
 		}); err != nil {
 			return err
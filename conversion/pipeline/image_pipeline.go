@@ -11,9 +11,11 @@
 package pipeline
 
 import (
+	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/minio/minio-go/v7"
 
@@ -29,6 +31,7 @@ import (
 type imagePipeline struct {
 	mosaicPipeline model.Pipeline
 	imageProc      *processor.ImageProcessor
+	metadataProc   *processor.MetadataProcessor
 	s3             *infra.S3Manager
 	taskClient     *api_client.TaskClient
 	snapshotClient *api_client.SnapshotClient
@@ -41,6 +44,7 @@ func NewImagePipeline() model.Pipeline {
 	return &imagePipeline{
 		mosaicPipeline: NewMosaicPipeline(),
 		imageProc:      processor.NewImageProcessor(),
+		metadataProc:   processor.NewMetadataProcessor(),
 		s3:             infra.NewS3Manager(),
 		taskClient:     api_client.NewTaskClient(),
 		snapshotClient: api_client.NewSnapshotClient(),
@@ -66,29 +70,83 @@ func (p *imagePipeline) Run(opts api_client.PipelineRunOptions) error {
 }
 
 func (p *imagePipeline) RunFromLocalPath(inputPath string, opts api_client.PipelineRunOptions) error {
+	// Formats that browsers cannot display are decoded first, as measuring
+	// them directly is unreliable, e.g. a PSD yields one size per layer
+	var decodedPath string
+	if p.imageIdent.RequiresDecoding(inputPath) {
+		if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+			Fields: []string{api_client.TaskFieldName},
+			Name:   helper.ToPtr("Decoding image."),
+		}); err != nil {
+			return err
+		}
+		decodedPath = filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + ".jpg")
+		if err := p.imageProc.DecodeImage(inputPath, decodedPath); err != nil {
+			return err
+		}
+		defer func(path string) {
+			if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+				return
+			} else if err != nil {
+				infra.GetLogger().Error(err)
+			}
+		}(decodedPath)
+	}
+	sourcePath := inputPath
+	if decodedPath != "" {
+		sourcePath = decodedPath
+	}
+	colorInfo, err := p.imageProc.ColorInfo(sourcePath)
+	if err != nil {
+		// We can still produce a preview, without knowing how the colors are stored
+		infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+	}
+	// TIFF images are normalized regardless, as browsers cannot display them
+	var normalizedPath string
+	if decodedPath != "" || p.imageIdent.IsTIFF(inputPath) || (colorInfo != nil && colorInfo.RequiresNormalization()) {
+		if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+			Fields: []string{api_client.TaskFieldName},
+			Name:   helper.ToPtr("Normalizing image orientation and colors."),
+		}); err != nil {
+			return err
+		}
+		extension := ".jpg"
+		if colorInfo != nil && colorInfo.HasAlpha {
+			extension = ".png"
+		}
+		normalizedPath = filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + extension)
+		if err := p.imageProc.NormalizeImage(sourcePath, colorInfo, normalizedPath); err != nil {
+			return err
+		}
+		defer func(path string) {
+			if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+				return
+			} else if err != nil {
+				infra.GetLogger().Error(err)
+			}
+		}(normalizedPath)
+	}
 	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
 		Fields: []string{api_client.TaskFieldName},
 		Name:   helper.ToPtr("Measuring image dimensions."),
 	}); err != nil {
 		return err
 	}
-	imageProps, err := p.measureImageDimensions(inputPath, opts)
+	// The normalized image is measured, as auto-orienting may swap the width and height
+	measurePath := inputPath
+	if normalizedPath != "" {
+		measurePath = normalizedPath
+	}
+	imageProps, err := p.measureImageDimensions(inputPath, measurePath, colorInfo, opts)
 	if err != nil {
 		return err
 	}
 	var imagePath string
-	if p.imageIdent.IsTIFF(inputPath) {
-		if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
-			Fields: []string{api_client.TaskFieldName},
-			Name:   helper.ToPtr("Converting TIFF image to JPEG format."),
-		}); err != nil {
-			return err
-		}
-		jpegPath, err := p.convertTIFFToJPEG(inputPath, *imageProps, opts)
-		if err != nil {
+	if normalizedPath != "" {
+		imagePath = normalizedPath
+		if err := p.savePreview(imagePath, *imageProps, opts); err != nil {
 			return err
 		}
-		imagePath = *jpegPath
 	} else {
 		imagePath = inputPath
 		if err := p.saveOriginalAsPreview(imagePath, *imageProps, opts); err != nil {
@@ -102,6 +160,16 @@ func (p *imagePipeline) RunFromLocalPath(inputPath string, opts api_client.Pipel
 			infra.GetLogger().Error(err)
 		}
 	}(imagePath)
+	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+		Fields: []string{api_client.TaskFieldName},
+		Name:   helper.ToPtr("Extracting metadata."),
+	}); err != nil {
+		return err
+	}
+	// We don't consider failing the extraction of the metadata an error
+	if err := p.createMetadata(inputPath, opts); err != nil {
+		infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+	}
 	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
 		Fields: []string{api_client.TaskFieldName},
 		Name:   helper.ToPtr("Creating thumbnail."),
@@ -111,8 +179,7 @@ func (p *imagePipeline) RunFromLocalPath(inputPath string, opts api_client.Pipel
 	// We don't consider failing the creation of the thumbnail an error
 	_ = p.createThumbnail(imagePath, opts)
 	// Automatically trigger mosaic pipeline if the image exceeds the pixels threshold
-	if imageProps.Width >= p.config.Limits.ImageMosaicTriggerThresholdPixels ||
-		imageProps.Height >= p.config.Limits.ImageMosaicTriggerThresholdPixels {
+	if p.exceedsMosaicThreshold(*imageProps, opts) {
 		if err := p.mosaicPipeline.RunFromLocalPath(imagePath, opts); err != nil {
 			return err
 		}
@@ -128,11 +195,29 @@ func (p *imagePipeline) RunFromLocalPath(inputPath string, opts api_client.Pipel
 	return nil
 }
 
-func (p *imagePipeline) measureImageDimensions(inputPath string, opts api_client.PipelineRunOptions) (*api_client.ImageProps, error) {
-	imageProps, err := p.imageProc.MeasureImage(inputPath)
+// exceedsMosaicThreshold uses the workspace's megapixels threshold if provided,
+// otherwise falls back to the configured per-side pixels threshold.
+func (p *imagePipeline) exceedsMosaicThreshold(imageProps api_client.ImageProps, opts api_client.PipelineRunOptions) bool {
+	if opts.Payload != nil && opts.Payload[api_client.PayloadMosaicThresholdMegapixels] != "" {
+		megapixels, err := strconv.ParseFloat(opts.Payload[api_client.PayloadMosaicThresholdMegapixels], 64)
+		if err == nil && megapixels > 0 {
+			return float64(imageProps.Width)*float64(imageProps.Height) >= megapixels*1_000_000
+		}
+	}
+	return imageProps.Width >= p.config.Limits.ImageMosaicTriggerThresholdPixels ||
+		imageProps.Height >= p.config.Limits.ImageMosaicTriggerThresholdPixels
+}
+
+// measureImageDimensions stores the dimensions measured on measurePath as the ones
+// of the original, which differ only when the original had to be decoded or normalized.
+func (p *imagePipeline) measureImageDimensions(inputPath string, measurePath string, colorInfo *processor.ImageColorInfo, opts api_client.PipelineRunOptions) (*api_client.ImageProps, error) {
+	imageProps, err := p.imageProc.MeasureImage(measurePath)
 	if err != nil {
 		return nil, err
 	}
+	if colorInfo != nil {
+		imageProps.ColorSpace = helper.ToPtr(colorInfo.Name())
+	}
 	stat, err := os.Stat(inputPath)
 	if err != nil {
 		return nil, err
@@ -195,32 +280,28 @@ func (p *imagePipeline) createThumbnail(inputPath string, opts api_client.Pipeli
 	return nil
 }
 
-func (p *imagePipeline) convertTIFFToJPEG(inputPath string, imageProps api_client.ImageProps, opts api_client.PipelineRunOptions) (*string, error) {
-	jpegPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + ".jpg")
-	if err := p.imageProc.ConvertImage(inputPath, jpegPath); err != nil {
-		return nil, err
-	}
-	stat, err := os.Stat(jpegPath)
+func (p *imagePipeline) savePreview(inputPath string, imageProps api_client.ImageProps, opts api_client.PipelineRunOptions) error {
+	stat, err := os.Stat(inputPath)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	s3Object := &api_client.S3Object{
 		Bucket: opts.Bucket,
-		Key:    opts.SnapshotID + "/preview.jpg",
+		Key:    opts.SnapshotID + "/preview" + filepath.Ext(inputPath),
 		Size:   helper.ToPtr(stat.Size()),
 		Image:  &imageProps,
 	}
-	if err := p.s3.PutFile(s3Object.Key, jpegPath, helper.DetectMimeFromFile(jpegPath), s3Object.Bucket, minio.PutObjectOptions{}); err != nil {
-		return nil, err
+	if err := p.s3.PutFile(s3Object.Key, inputPath, helper.DetectMimeFromFile(inputPath), s3Object.Bucket, minio.PutObjectOptions{}); err != nil {
+		return err
 	}
 	if err := p.snapshotClient.Patch(api_client.SnapshotPatchOptions{
 		Options: opts,
 		Fields:  []string{api_client.SnapshotFieldPreview},
 		Preview: s3Object,
 	}); err != nil {
-		return nil, err
+		return err
 	}
-	return &jpegPath, nil
+	return nil
 }
 
 func (p *imagePipeline) saveOriginalAsPreview(inputPath string, imageProps api_client.ImageProps, opts api_client.PipelineRunOptions) error {
@@ -242,3 +323,31 @@ func (p *imagePipeline) saveOriginalAsPreview(inputPath string, imageProps api_c
 	}
 	return nil
 }
+
+func (p *imagePipeline) createMetadata(inputPath string, opts api_client.PipelineRunOptions) error {
+	metadata, err := p.metadataProc.FromImage(inputPath)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	content := string(b)
+	s3Object := api_client.S3Object{
+		Bucket: opts.Bucket,
+		Key:    opts.SnapshotID + "/metadata.json",
+		Size:   helper.ToPtr(int64(len(content))),
+	}
+	if err := p.s3.PutText(s3Object.Key, content, "application/json", s3Object.Bucket, minio.PutObjectOptions{}); err != nil {
+		return err
+	}
+	if err := p.snapshotClient.Patch(api_client.SnapshotPatchOptions{
+		Options:  opts,
+		Fields:   []string{api_client.SnapshotFieldMetadata},
+		Metadata: &s3Object,
+	}); err != nil {
+		return err
+	}
+	return nil
+}
@@ -15,11 +15,14 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/minio/minio-go/v7"
 
 	"github.com/kouprlabs/voltaserve/conversion/client/api_client"
 	"github.com/kouprlabs/voltaserve/conversion/client/language_client"
+	"github.com/kouprlabs/voltaserve/conversion/client/llm_client"
+	"github.com/kouprlabs/voltaserve/conversion/config"
 	"github.com/kouprlabs/voltaserve/conversion/helper"
 	"github.com/kouprlabs/voltaserve/conversion/identifier"
 	"github.com/kouprlabs/voltaserve/conversion/infra"
@@ -31,11 +34,17 @@ type insightsPipeline struct {
 	imageProc      *processor.ImageProcessor
 	pdfProc        *processor.PDFProcessor
 	ocrProc        *processor.OCRProcessor
+	languageProc   *processor.LanguageProcessor
+	summaryProc    *processor.SummaryProcessor
+	textProc       *processor.TextProcessor
 	fileIdent      *identifier.FileIdentifier
+	layoutStore    *layoutStore
 	s3             *infra.S3Manager
 	taskClient     *api_client.TaskClient
 	snapshotClient *api_client.SnapshotClient
 	languageClient *language_client.LanguageClient
+	llmClient      *llm_client.LLMClient
+	config         *config.Config
 }
 
 func NewInsightsPipeline() model.Pipeline {
@@ -43,18 +52,21 @@ func NewInsightsPipeline() model.Pipeline {
 		imageProc:      processor.NewImageProcessor(),
 		pdfProc:        processor.NewPDFProcessor(),
 		ocrProc:        processor.NewOCRProcessor(),
+		languageProc:   processor.NewLanguageProcessor(),
+		summaryProc:    processor.NewSummaryProcessor(),
+		textProc:       processor.NewTextProcessor(),
 		fileIdent:      identifier.NewFileIdentifier(),
+		layoutStore:    newLayoutStore(),
 		s3:             infra.NewS3Manager(),
 		taskClient:     api_client.NewTaskClient(),
 		snapshotClient: api_client.NewSnapshotClient(),
 		languageClient: language_client.NewLanguageClient(),
+		llmClient:      llm_client.NewLLMClient(),
+		config:         config.GetConfig(),
 	}
 }
 
 func (p *insightsPipeline) Run(opts api_client.PipelineRunOptions) error {
-	if opts.Payload == nil || opts.Payload["language"] == "" {
-		return errors.New("language is undefined")
-	}
 	inputPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + filepath.Ext(opts.Key))
 	if err := p.s3.GetFile(opts.Key, inputPath, opts.Bucket, minio.GetObjectOptions{}); err != nil {
 		return err
@@ -70,6 +82,25 @@ func (p *insightsPipeline) Run(opts api_client.PipelineRunOptions) error {
 }
 
 func (p *insightsPipeline) RunFromLocalPath(inputPath string, opts api_client.PipelineRunOptions) error {
+	if opts.Payload == nil || opts.Payload[api_client.PayloadLanguage] == "" {
+		if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+			Fields: []string{api_client.TaskFieldName},
+			Name:   helper.ToPtr("Detecting language."),
+		}); err != nil {
+			return err
+		}
+		language, err := p.detectLanguage(inputPath, opts)
+		if err != nil {
+			return err
+		}
+		payload := map[string]string{api_client.PayloadLanguage: *language}
+		for k, v := range opts.Payload {
+			if k != api_client.PayloadLanguage {
+				payload[k] = v
+			}
+		}
+		opts.Payload = payload
+	}
 	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
 		Fields: []string{api_client.TaskFieldName},
 		Name:   helper.ToPtr("Extracting text."),
@@ -89,6 +120,16 @@ func (p *insightsPipeline) RunFromLocalPath(inputPath string, opts api_client.Pi
 	if err := p.createEntities(*text, opts); err != nil {
 		return err
 	}
+	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+		Fields: []string{api_client.TaskFieldName},
+		Name:   helper.ToPtr("Summarizing."),
+	}); err != nil {
+		return err
+	}
+	// We don't consider failing the creation of the summary an error
+	if err := p.createSummary(*text, opts); err != nil {
+		infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+	}
 	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
 		Fields: []string{api_client.TaskFieldName, api_client.TaskFieldStatus},
 		Name:   helper.ToPtr("Done."),
@@ -99,9 +140,62 @@ func (p *insightsPipeline) RunFromLocalPath(inputPath string, opts api_client.Pi
 	return nil
 }
 
+// detectLanguage records the detected language and its confidence on the snapshot.
+func (p *insightsPipeline) detectLanguage(inputPath string, opts api_client.PipelineRunOptions) (*string, error) {
+	var detection *model.LanguageDetection
+	if p.fileIdent.IsImage(opts.Key) {
+		var err error
+		detection, err = p.languageProc.DetectFromImage(inputPath)
+		if err != nil {
+			return nil, err
+		}
+	} else if p.fileIdent.IsText(opts.Key) {
+		text, err := p.textProc.Read(inputPath)
+		if err != nil {
+			return nil, err
+		}
+		detection, err = p.languageProc.DetectFromText(text)
+		if err != nil {
+			return nil, err
+		}
+	} else if p.fileIdent.IsPDF(opts.Key) || p.fileIdent.IsOffice(opts.Key) || p.fileIdent.IsPlainText(opts.Key) {
+		text, err := p.pdfProc.TextFromPDF(inputPath)
+		if err != nil {
+			return nil, err
+		}
+		detection, err = p.languageProc.DetectFromText(*text)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, errors.New("unsupported file type")
+	}
+	if err := p.snapshotClient.Patch(api_client.SnapshotPatchOptions{
+		Options:            opts,
+		Fields:             []string{api_client.SnapshotFieldLanguage, api_client.SnapshotFieldLanguageConfidence},
+		Language:           &detection.Language,
+		LanguageConfidence: &detection.Confidence,
+	}); err != nil {
+		return nil, err
+	}
+	return &detection.Language, nil
+}
+
 func (p *insightsPipeline) createText(inputPath string, opts api_client.PipelineRunOptions) (*string, error) {
+	if p.fileIdent.IsText(opts.Key) {
+		/* Text files are previewed as HTML, so they have neither OCR nor layout */
+		text, err := p.textProc.Read(inputPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.putText(text, opts); err != nil {
+			return nil, err
+		}
+		return &text, nil
+	}
 	/* Generate PDF/A */
 	var pdfPath string
+	var layout *model.Layout
 	if p.fileIdent.IsImage(opts.Key) {
 		/* Get DPI */
 		dpi, err := p.imageProc.DPIFromImage(inputPath)
@@ -122,7 +216,7 @@ func (p *insightsPipeline) createText(inputPath string, opts api_client.Pipeline
 		}(noAlphaImagePath)
 		/* Convert to PDF/A */
 		pdfPath = filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + ".pdf")
-		if err := p.ocrProc.SearchablePDFFromFile(noAlphaImagePath, opts.Payload["language"], *dpi, pdfPath); err != nil {
+		if err := p.ocrProc.SearchablePDFFromFile(noAlphaImagePath, opts.Payload[api_client.PayloadLanguage], *dpi, pdfPath); err != nil {
 			return nil, err
 		}
 		defer func(path string) {
@@ -152,6 +246,11 @@ func (p *insightsPipeline) createText(inputPath string, opts api_client.Pipeline
 		}); err != nil {
 			return nil, err
 		}
+		/* Get the layout from tesseract's hOCR, as it reads the image the way it's previewed */
+		layout, err = p.ocrProc.LayoutFromImage(noAlphaImagePath, opts.Payload[api_client.PayloadLanguage], *dpi)
+		if err != nil {
+			infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+		}
 	} else if p.fileIdent.IsPDF(opts.Key) || p.fileIdent.IsOffice(opts.Key) || p.fileIdent.IsPlainText(opts.Key) {
 		pdfPath = inputPath
 	} else {
@@ -162,23 +261,46 @@ func (p *insightsPipeline) createText(inputPath string, opts api_client.Pipeline
 	if text == nil || err != nil {
 		return nil, err
 	}
-	/* Set text S3 object */
+	if err := p.putText(*text, opts); err != nil {
+		return nil, err
+	}
+	/* Extract layout, we don't consider failing this an error */
+	if err := p.createLayout(pdfPath, layout, opts); err != nil {
+		infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+	}
+	return text, nil
+}
+
+func (p *insightsPipeline) putText(text string, opts api_client.PipelineRunOptions) error {
 	s3Object := api_client.S3Object{
 		Bucket: opts.Bucket,
 		Key:    opts.SnapshotID + "/text.txt",
-		Size:   helper.ToPtr(int64(len(*text))),
+		Size:   helper.ToPtr(int64(len(text))),
 	}
-	if err := p.s3.PutText(s3Object.Key, *text, "text/plain", s3Object.Bucket, minio.PutObjectOptions{}); err != nil {
-		return nil, err
+	if err := p.s3.PutText(s3Object.Key, text, "text/plain", s3Object.Bucket, minio.PutObjectOptions{}); err != nil {
+		return err
 	}
 	if err := p.snapshotClient.Patch(api_client.SnapshotPatchOptions{
 		Options: opts,
 		Fields:  []string{api_client.SnapshotFieldText},
 		Text:    &s3Object,
 	}); err != nil {
-		return nil, err
+		return err
 	}
-	return text, nil
+	return nil
+}
+
+// createLayout saves the layout read by the OCR engine, or the one of the text
+// layer of the PDF when there is none.
+func (p *insightsPipeline) createLayout(pdfPath string, layout *model.Layout, opts api_client.PipelineRunOptions) error {
+	if layout == nil {
+		var err error
+		layout, err = p.pdfProc.LayoutFromPDF(pdfPath)
+		if err != nil {
+			return err
+		}
+	}
+	return p.layoutStore.save(layout, opts)
 }
 
 func (p *insightsPipeline) createEntities(text string, opts api_client.PipelineRunOptions) error {
@@ -189,8 +311,9 @@ func (p *insightsPipeline) createEntities(text string, opts api_client.PipelineR
 		return errors.New("text exceeds supported limit of 1000000 characters")
 	}
 	res, err := p.languageClient.GetEntities(language_client.GetEntitiesOptions{
-		Text:     text,
-		Language: opts.Payload["language"],
+		Text: text,
+		// Entities are extracted with the primary language of a combination like "eng+deu"
+		Language: strings.Split(opts.Payload[api_client.PayloadLanguage], "+")[0],
 	})
 	if err != nil {
 		return err
@@ -217,3 +340,43 @@ func (p *insightsPipeline) createEntities(text string, opts api_client.PipelineR
 	}
 	return nil
 }
+
+func (p *insightsPipeline) createSummary(text string, opts api_client.PipelineRunOptions) error {
+	summary, err := p.summarize(text, opts.Payload[api_client.PayloadLanguage])
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	content := string(b)
+	s3Object := api_client.S3Object{
+		Bucket: opts.Bucket,
+		Key:    opts.SnapshotID + "/summary.json",
+		Size:   helper.ToPtr(int64(len(content))),
+	}
+	if err := p.s3.PutText(s3Object.Key, content, "application/json", s3Object.Bucket, minio.PutObjectOptions{}); err != nil {
+		return err
+	}
+	if err := p.snapshotClient.Patch(api_client.SnapshotPatchOptions{
+		Options: opts,
+		Fields:  []string{api_client.SnapshotFieldSummary},
+		Summary: &s3Object,
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// summarize uses the configured provider, and falls back to TextRank if the LLM fails.
+func (p *insightsPipeline) summarize(text string, language string) (*model.Summary, error) {
+	if p.config.Summary.Provider == config.SummaryProviderLLM {
+		summary, err := p.llmClient.Summarize(text, language)
+		if err == nil {
+			return summary, nil
+		}
+		infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+	}
+	return p.summaryProc.Summarize(text, language)
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package pipeline
+
+import (
+	"encoding/json"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/kouprlabs/voltaserve/conversion/client/api_client"
+	"github.com/kouprlabs/voltaserve/conversion/helper"
+	"github.com/kouprlabs/voltaserve/conversion/infra"
+	"github.com/kouprlabs/voltaserve/conversion/model"
+)
+
+// layoutStore uploads the layout of a snapshot and references it from the snapshot,
+// it's shared by the pipelines that extract layouts.
+type layoutStore struct {
+	s3             *infra.S3Manager
+	snapshotClient *api_client.SnapshotClient
+}
+
+func newLayoutStore() *layoutStore {
+	return &layoutStore{
+		s3:             infra.NewS3Manager(),
+		snapshotClient: api_client.NewSnapshotClient(),
+	}
+}
+
+func (s *layoutStore) save(layout *model.Layout, opts api_client.PipelineRunOptions) error {
+	b, err := json.Marshal(layout)
+	if err != nil {
+		return err
+	}
+	content := string(b)
+	s3Object := api_client.S3Object{
+		Bucket: opts.Bucket,
+		Key:    opts.SnapshotID + "/layout.json",
+		Size:   helper.ToPtr(int64(len(content))),
+	}
+	if err := s.s3.PutText(s3Object.Key, content, "application/json", s3Object.Bucket, minio.PutObjectOptions{}); err != nil {
+		return err
+	}
+	if err := s.snapshotClient.Patch(api_client.SnapshotPatchOptions{
+		Options: opts,
+		Fields:  []string{api_client.SnapshotFieldLayout},
+		Layout:  &s3Object,
+	}); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package pipeline
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/kouprlabs/voltaserve/conversion/client/api_client"
+	"github.com/kouprlabs/voltaserve/conversion/helper"
+	"github.com/kouprlabs/voltaserve/conversion/identifier"
+	"github.com/kouprlabs/voltaserve/conversion/infra"
+	"github.com/kouprlabs/voltaserve/conversion/model"
+	"github.com/kouprlabs/voltaserve/conversion/processor"
+)
+
+// modelBundlePriority decides which file is converted when a ZIP bundle contains
+// several models, formats that reference materials and textures come first.
+var modelBundlePriority = []string{".usdz", ".usd", ".usdc", ".usda", ".fbx", ".obj", ".ply", ".step", ".stp", ".stl"}
+
+type modelPipeline struct {
+	glbPipeline model.Pipeline
+	glbProc     *processor.GLBProcessor
+	zipProc     *processor.ZIPProcessor
+	s3          *infra.S3Manager
+	fi          *identifier.FileIdentifier
+	taskClient  *api_client.TaskClient
+}
+
+func NewModelPipeline() model.Pipeline {
+	return &modelPipeline{
+		glbPipeline: NewGLBPipeline(),
+		glbProc:     processor.NewGLBProcessor(),
+		zipProc:     processor.NewZIPProcessor(),
+		s3:          infra.NewS3Manager(),
+		fi:          identifier.NewFileIdentifier(),
+		taskClient:  api_client.NewTaskClient(),
+	}
+}
+
+func (p *modelPipeline) Run(opts api_client.PipelineRunOptions) error {
+	inputPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + filepath.Ext(opts.Key))
+	if err := p.s3.GetFile(opts.Key, inputPath, opts.Bucket, minio.GetObjectOptions{}); err != nil {
+		return err
+	}
+	defer func(path string) {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(inputPath)
+	return p.RunFromLocalPath(inputPath, opts)
+}
+
+// RunFromLocalPath accepts either a model, or a ZIP bundle containing a model
+// together with its materials and textures.
+func (p *modelPipeline) RunFromLocalPath(inputPath string, opts api_client.PipelineRunOptions) error {
+	modelPath := inputPath
+	if p.fi.IsZIP(inputPath) {
+		if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+			Fields: []string{api_client.TaskFieldName},
+			Name:   helper.ToPtr("Extracting ZIP."),
+		}); err != nil {
+			return err
+		}
+		tmpDir := filepath.FromSlash(os.TempDir() + "/" + helper.NewID())
+		defer func(path string) {
+			if err := os.RemoveAll(path); err != nil {
+				infra.GetLogger().Error(err)
+			}
+		}(tmpDir)
+		if err := p.zipProc.Extract(inputPath, tmpDir); err != nil {
+			return err
+		}
+		path, err := p.findModel(tmpDir)
+		if err != nil {
+			return err
+		}
+		if path == nil {
+			// Do nothing, treat it as a ZIP file
+			return nil
+		}
+		modelPath = *path
+	}
+	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+		Fields: []string{api_client.TaskFieldName},
+		Name:   helper.ToPtr("Converting to GLB."),
+	}); err != nil {
+		return err
+	}
+	glbPath, err := p.convertToGLB(modelPath, opts)
+	if err != nil {
+		return err
+	}
+	defer func(path string) {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(*glbPath)
+	// Creates the thumbnail, and patches the preview with the statistics of the model
+	if err := p.glbPipeline.RunFromLocalPath(*glbPath, opts); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *modelPipeline) convertToGLB(inputPath string, opts api_client.PipelineRunOptions) (*string, error) {
+	outputPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + ".glb")
+	if err := p.glbProc.FromModel(inputPath, outputPath); err != nil {
+		return nil, err
+	}
+	if err := p.s3.PutFile(opts.SnapshotID+"/preview.glb", outputPath, helper.DetectMimeFromFile(outputPath), opts.Bucket, minio.PutObjectOptions{}); err != nil {
+		return nil, err
+	}
+	return &outputPath, nil
+}
+
+func (p *modelPipeline) findModel(dir string) (*string, error) {
+	found := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == "__MACOSX" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		extension := strings.ToLower(filepath.Ext(path))
+		if _, ok := found[extension]; !ok && p.fi.IsModel(path) {
+			found[extension] = path
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, extension := range modelBundlePriority {
+		if path, ok := found[extension]; ok {
+			return &path, nil
+		}
+	}
+	return nil, nil
+}
@@ -75,9 +75,28 @@ func (p *mosaicPipeline) RunFromLocalPath(inputPath string, opts api_client.Pipe
 	}); err != nil {
 		return err
 	}
-	if !p.imageProc.IsSupportedByBild(inputPath) {
+	if p.imageIdent.RequiresDecoding(inputPath) {
 		outputPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + ".jpg")
-		if err := p.imageProc.ConvertImage(inputPath, outputPath); err != nil {
+		if err := p.imageProc.DecodeImage(inputPath, outputPath); err != nil {
+			return err
+		}
+		defer func(path string) {
+			if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+				return
+			} else if err != nil {
+				infra.GetLogger().Error(err)
+			}
+		}(outputPath)
+		inputPath = outputPath
+	}
+	// The mosaic service only auto-orients, so the colors are converted to sRGB here
+	colorInfo, err := p.imageProc.ColorInfo(inputPath)
+	if err != nil {
+		infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+	}
+	if !p.imageProc.IsSupportedByBild(inputPath) || colorInfo == nil || colorInfo.RequiresNormalization() {
+		outputPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + ".jpg")
+		if err := p.imageProc.NormalizeImage(inputPath, colorInfo, outputPath); err != nil {
 			return err
 		}
 		defer func(path string) {
@@ -25,8 +25,15 @@ import (
 	"github.com/kouprlabs/voltaserve/conversion/processor"
 )
 
+// officeCacheFolder holds the PDF conversions of office files in the bucket of
+// the workspace, named after the SHA-256 of their content, so that uploading
+// the same document again skips LibreOffice. The previews reference the entry
+// they were converted from, the API deletes it with the last of them.
+const officeCacheFolder = "cache/office"
+
 type officePipeline struct {
 	pdfPipeline    model.Pipeline
+	textPipeline   model.Pipeline
 	officeProc     *processor.OfficeProcessor
 	pdfProc        *processor.PDFProcessor
 	s3             *infra.S3Manager
@@ -38,6 +45,7 @@ type officePipeline struct {
 func NewOfficePipeline() model.Pipeline {
 	return &officePipeline{
 		pdfPipeline:    NewPDFPipeline(),
+		textPipeline:   NewTextPipeline(),
 		officeProc:     processor.NewOfficeProcessor(),
 		pdfProc:        processor.NewPDFProcessor(),
 		s3:             infra.NewS3Manager(),
@@ -70,9 +78,10 @@ func (p *officePipeline) RunFromLocalPath(inputPath string, opts api_client.Pipe
 	}); err != nil {
 		return err
 	}
-	pdfPath, err := p.convertToPDF(inputPath, opts)
+	pdfPath, cacheKey, err := p.convertToPDF(inputPath, opts)
 	if err != nil {
-		return err
+		infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+		return p.runTextFallback(inputPath, err, opts)
 	}
 	defer func(path string) {
 		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
@@ -81,31 +90,74 @@ func (p *officePipeline) RunFromLocalPath(inputPath string, opts api_client.Pipe
 			infra.GetLogger().Error(err)
 		}
 	}(*pdfPath)
+	if cacheKey != nil {
+		payload := make(map[string]string, len(opts.Payload)+1)
+		for k, v := range opts.Payload {
+			payload[k] = v
+		}
+		payload[api_client.PayloadCacheKey] = *cacheKey
+		opts.Payload = payload
+	}
 	return p.pdfPipeline.RunFromLocalPath(*pdfPath, opts)
 }
 
-func (p *officePipeline) convertToPDF(inputPath string, opts api_client.PipelineRunOptions) (*string, error) {
-	outputDir := filepath.FromSlash(os.TempDir() + "/" + helper.NewID())
-	outputPath, err := p.officeProc.PDF(inputPath, outputDir)
+// runTextFallback previews the text of the document when LibreOffice can't convert
+// it, the conversion error is returned if the text can't be extracted either.
+func (p *officePipeline) runTextFallback(inputPath string, conversionErr error, opts api_client.PipelineRunOptions) error {
+	text, err := p.officeProc.Text(inputPath)
 	if err != nil {
-		return nil, err
+		infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+		return conversionErr
+	}
+	textPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + ".txt")
+	if err := os.WriteFile(textPath, []byte(text), 0o600); err != nil {
+		return err
 	}
 	defer func(path string) {
-		if err := os.RemoveAll(path); err != nil {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
 			infra.GetLogger().Error(err)
 		}
-	}(outputDir)
+	}(textPath)
+	return p.textPipeline.RunFromLocalPath(textPath, opts)
+}
+
+// convertToPDF returns the path of the PDF, and the key of the cache entry if
+// the conversion is cached.
+func (p *officePipeline) convertToPDF(inputPath string, opts api_client.PipelineRunOptions) (*string, *string, error) {
+	hash, err := helper.HashFile(inputPath)
+	if err != nil {
+		return nil, nil, err
+	}
 	pdfPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + ".pdf")
-	if err := os.Rename(*outputPath, pdfPath); err != nil {
-		return nil, err
+	cacheKey := officeCacheFolder + "/" + hash + ".pdf"
+	isCached := false
+	if _, err := p.s3.StatObject(cacheKey, opts.Bucket, minio.StatObjectOptions{}); err == nil {
+		if err := p.s3.GetFile(cacheKey, pdfPath, opts.Bucket, minio.GetObjectOptions{}); err != nil {
+			infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+		} else {
+			isCached = true
+		}
+	}
+	if !isCached {
+		if err := p.convert(inputPath, pdfPath); err != nil {
+			return nil, nil, err
+		}
+		// We don't consider failing to cache the conversion an error
+		if err := p.s3.PutFile(cacheKey, pdfPath, helper.DetectMimeFromFile(pdfPath), opts.Bucket, minio.PutObjectOptions{}); err != nil {
+			infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+		} else {
+			isCached = true
+		}
 	}
 	stat, err := os.Stat(pdfPath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	pdfKey := opts.SnapshotID + "/preview.pdf"
 	if err := p.s3.PutFile(pdfKey, pdfPath, helper.DetectMimeFromFile(pdfPath), opts.Bucket, minio.PutObjectOptions{}); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if err := p.snapshotClient.Patch(api_client.SnapshotPatchOptions{
 		Options: opts,
@@ -116,7 +168,24 @@ func (p *officePipeline) convertToPDF(inputPath string, opts api_client.Pipeline
 			Size:   helper.ToPtr(stat.Size()),
 		},
 	}); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if !isCached {
+		return &pdfPath, nil, nil
+	}
+	return &pdfPath, &cacheKey, nil
+}
+
+func (p *officePipeline) convert(inputPath string, pdfPath string) error {
+	outputDir := filepath.FromSlash(os.TempDir() + "/" + helper.NewID())
+	defer func(path string) {
+		if err := os.RemoveAll(path); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(outputDir)
+	outputPath, err := p.officeProc.PDF(inputPath, outputDir)
+	if err != nil {
+		return err
 	}
-	return &pdfPath, nil
+	return os.Rename(*outputPath, pdfPath)
 }
@@ -0,0 +1,159 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/kouprlabs/voltaserve/conversion/client/api_client"
+	"github.com/kouprlabs/voltaserve/conversion/config"
+	"github.com/kouprlabs/voltaserve/conversion/helper"
+	"github.com/kouprlabs/voltaserve/conversion/infra"
+	"github.com/kouprlabs/voltaserve/conversion/model"
+	"github.com/kouprlabs/voltaserve/conversion/processor"
+)
+
+// pdfPageResolutions are the sizes of the longest side of the page images, the
+// high resolution is meant for zooming and high density displays.
+var pdfPageResolutions = []struct {
+	Name string
+	Size int
+}{
+	{Name: "standard", Size: 1600},
+	{Name: "high", Size: 3200},
+}
+
+const (
+	pdfPageThumbnailSize = 256
+	pdfPageExtension     = ".webp"
+)
+
+type pdfPagesPipeline struct {
+	pdfProc    *processor.PDFProcessor
+	imageProc  *processor.ImageProcessor
+	s3         *infra.S3Manager
+	taskClient *api_client.TaskClient
+	config     *config.Config
+}
+
+func NewPDFPagesPipeline() model.Pipeline {
+	return &pdfPagesPipeline{
+		pdfProc:    processor.NewPDFProcessor(),
+		imageProc:  processor.NewImageProcessor(),
+		s3:         infra.NewS3Manager(),
+		taskClient: api_client.NewTaskClient(),
+		config:     config.GetConfig(),
+	}
+}
+
+func (p *pdfPagesPipeline) Run(opts api_client.PipelineRunOptions) error {
+	inputPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + filepath.Ext(opts.Key))
+	if err := p.s3.GetFile(opts.Key, inputPath, opts.Bucket, minio.GetObjectOptions{}); err != nil {
+		return err
+	}
+	defer func(path string) {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(inputPath)
+	return p.RunFromLocalPath(inputPath, opts)
+}
+
+// RunFromLocalPath renders a batch of pages, starting at the page given in the
+// payload, or at the first page.
+func (p *pdfPagesPipeline) RunFromLocalPath(inputPath string, opts api_client.PipelineRunOptions) error {
+	first := 1
+	if opts.Payload != nil && opts.Payload[api_client.PayloadFirstPage] != "" {
+		v, err := strconv.Atoi(opts.Payload[api_client.PayloadFirstPage])
+		if err != nil {
+			return err
+		}
+		first = v
+	}
+	count, err := p.pdfProc.CountPages(inputPath)
+	if err != nil {
+		return err
+	}
+	if first < 1 || first > *count {
+		return fmt.Errorf("page %d is out of range", first)
+	}
+	last := *count
+	if p.config.Limits.PDFPagesBatchSize > 0 {
+		last = min(first+p.config.Limits.PDFPagesBatchSize-1, *count)
+	}
+	if opts.TaskID != "" {
+		if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+			Fields: []string{api_client.TaskFieldName},
+			Name:   helper.ToPtr("Rendering pages."),
+		}); err != nil {
+			return err
+		}
+	}
+	dir, err := os.MkdirTemp(os.TempDir(), helper.NewID())
+	if err != nil {
+		return err
+	}
+	defer func(path string) {
+		if err := os.RemoveAll(path); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(dir)
+	for page := first; page <= last; page++ {
+		if err := p.renderPage(inputPath, page, dir, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderPage uploads the images of the page in every resolution, and its
+// thumbnail, which is downscaled from the standard resolution.
+func (p *pdfPagesPipeline) renderPage(inputPath string, page int, dir string, opts api_client.PipelineRunOptions) error {
+	var paths []string
+	defer func() {
+		for _, path := range paths {
+			if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+				continue
+			} else if err != nil {
+				infra.GetLogger().Error(err)
+			}
+		}
+	}()
+	for _, resolution := range pdfPageResolutions {
+		outputPath := filepath.Join(dir, fmt.Sprintf("%s-%d%s", resolution.Name, page, pdfPageExtension))
+		paths = append(paths, outputPath)
+		if err := p.pdfProc.RenderPage(inputPath, page, resolution.Size, outputPath); err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s/pages/%s/%d%s", opts.SnapshotID, resolution.Name, page, pdfPageExtension)
+		if err := p.s3.PutFile(key, outputPath, helper.DetectMimeFromFile(outputPath), opts.Bucket, minio.PutObjectOptions{}); err != nil {
+			return err
+		}
+	}
+	thumbnailPath := filepath.Join(dir, fmt.Sprintf("thumbnail-%d%s", page, pdfPageExtension))
+	paths = append(paths, thumbnailPath)
+	if err := p.imageProc.ResizeImage(paths[0], pdfPageThumbnailSize, pdfPageThumbnailSize, thumbnailPath); err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%s/page_thumbnails/%d%s", opts.SnapshotID, page, pdfPageExtension)
+	if err := p.s3.PutFile(key, thumbnailPath, helper.DetectMimeFromFile(thumbnailPath), opts.Bucket, minio.PutObjectOptions{}); err != nil {
+		return err
+	}
+	return nil
+}
@@ -12,6 +12,7 @@ package pipeline
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -26,9 +27,18 @@ import (
 	"github.com/kouprlabs/voltaserve/conversion/processor"
 )
 
+// Slides are rendered losslessly, their text and flat colors suffer from lossy
+// compression more than the pages of documents.
+const (
+	pdfSlideSize      = 1920
+	pdfSlideExtension = ".png"
+)
+
 type pdfPipeline struct {
+	pagesPipeline  model.Pipeline
 	pdfProc        *processor.PDFProcessor
 	imageProc      *processor.ImageProcessor
+	layoutStore    *layoutStore
 	s3             *infra.S3Manager
 	taskClient     *api_client.TaskClient
 	snapshotClient *api_client.SnapshotClient
@@ -38,8 +48,10 @@ type pdfPipeline struct {
 
 func NewPDFPipeline() model.Pipeline {
 	return &pdfPipeline{
+		pagesPipeline:  NewPDFPagesPipeline(),
 		pdfProc:        processor.NewPDFProcessor(),
 		imageProc:      processor.NewImageProcessor(),
+		layoutStore:    newLayoutStore(),
 		s3:             infra.NewS3Manager(),
 		taskClient:     api_client.NewTaskClient(),
 		snapshotClient: api_client.NewSnapshotClient(),
@@ -71,9 +83,23 @@ func (p *pdfPipeline) RunFromLocalPath(inputPath string, opts api_client.Pipelin
 	document := api_client.DocumentProps{
 		Pages: &api_client.PagesProps{
 			Count:     *count,
-			Extension: ".pdf",
+			Extension: pdfPageExtension,
+		},
+		Thumbnails: &api_client.ThumbnailsProps{
+			Extension: pdfPageExtension,
 		},
 	}
+	if cacheKey, ok := opts.Payload[api_client.PayloadCacheKey]; ok {
+		stat, err := os.Stat(inputPath)
+		if err != nil {
+			return err
+		}
+		document.Cache = &api_client.S3Object{
+			Bucket: opts.Bucket,
+			Key:    cacheKey,
+			Size:   helper.ToPtr(stat.Size()),
+		}
+	}
 	if err := p.patchSnapshotPreviewField(inputPath, &document, opts); err != nil {
 		return err
 	}
@@ -85,6 +111,22 @@ func (p *pdfPipeline) RunFromLocalPath(inputPath string, opts api_client.Pipelin
 	}
 	// We don't consider failing the creation of the thumbnail an error
 	_ = p.createThumbnail(inputPath, opts)
+	if p.fileIdent.IsPresentation(opts.Key) {
+		if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+			Fields: []string{api_client.TaskFieldName},
+			Name:   helper.ToPtr("Rendering slides."),
+		}); err != nil {
+			return err
+		}
+		// Nor failing to render the slides
+		if err := p.createSlides(inputPath, &document, opts); err != nil {
+			infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+		}
+	}
+	// Nor failing to render the pages, the PDF itself can still be viewed
+	if err := p.pagesPipeline.RunFromLocalPath(inputPath, opts); err != nil {
+		infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+	}
 	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
 		Fields: []string{api_client.TaskFieldName},
 		Name:   helper.ToPtr("Saving preview."),
@@ -100,6 +142,10 @@ func (p *pdfPipeline) RunFromLocalPath(inputPath string, opts api_client.Pipelin
 	if err := p.extractText(inputPath, opts); err != nil {
 		return err
 	}
+	// We don't consider failing the extraction of the layout an error
+	if err := p.extractLayout(inputPath, opts); err != nil {
+		infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+	}
 	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
 		Fields: []string{api_client.TaskFieldName, api_client.TaskFieldStatus},
 		Name:   helper.ToPtr("Done."),
@@ -149,6 +195,42 @@ func (p *pdfPipeline) createThumbnail(inputPath string, opts api_client.Pipeline
 	return nil
 }
 
+// createSlides renders the pages of presentations as PNG images, one per slide,
+// and adds them to the document of the preview.
+func (p *pdfPipeline) createSlides(inputPath string, document *api_client.DocumentProps, opts api_client.PipelineRunOptions) error {
+	count := document.Pages.Count
+	if p.config.Limits.SlidesMaxCount > 0 {
+		count = min(count, p.config.Limits.SlidesMaxCount)
+	}
+	dir, err := os.MkdirTemp(os.TempDir(), helper.NewID())
+	if err != nil {
+		return err
+	}
+	defer func(path string) {
+		if err := os.RemoveAll(path); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(dir)
+	for slide := 1; slide <= count; slide++ {
+		outputPath := filepath.Join(dir, fmt.Sprintf("%d%s", slide, pdfSlideExtension))
+		if err := p.pdfProc.RenderPage(inputPath, slide, pdfSlideSize, outputPath); err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s/slides/%d%s", opts.SnapshotID, slide, pdfSlideExtension)
+		if err := p.s3.PutFile(key, outputPath, helper.DetectMimeFromFile(outputPath), opts.Bucket, minio.PutObjectOptions{}); err != nil {
+			return err
+		}
+		if err := os.Remove(outputPath); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}
+	document.Slides = &api_client.SlidesProps{
+		Count:     count,
+		Extension: pdfSlideExtension,
+	}
+	return p.patchSnapshotPreviewField(inputPath, document, opts)
+}
+
 func (p *pdfPipeline) extractText(inputPath string, opts api_client.PipelineRunOptions) error {
 	text, err := p.pdfProc.TextFromPDF(inputPath)
 	if err != nil {
@@ -175,6 +257,14 @@ func (p *pdfPipeline) extractText(inputPath string, opts api_client.PipelineRunO
 	return nil
 }
 
+func (p *pdfPipeline) extractLayout(inputPath string, opts api_client.PipelineRunOptions) error {
+	layout, err := p.pdfProc.LayoutFromPDF(inputPath)
+	if err != nil {
+		return err
+	}
+	return p.layoutStore.save(layout, opts)
+}
+
 func (p *pdfPipeline) patchSnapshotPreviewField(inputPath string, document *api_client.DocumentProps, opts api_client.PipelineRunOptions) error {
 	stat, err := os.Stat(inputPath)
 	if err != nil {
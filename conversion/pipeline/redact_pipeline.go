@@ -0,0 +1,145 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/kouprlabs/voltaserve/conversion/client/api_client"
+	"github.com/kouprlabs/voltaserve/conversion/helper"
+	"github.com/kouprlabs/voltaserve/conversion/infra"
+	"github.com/kouprlabs/voltaserve/conversion/model"
+	"github.com/kouprlabs/voltaserve/conversion/processor"
+)
+
+const redactDPI = 150
+
+// redactPipeline burns redactions out of the PDF given by opts.Key, and stores the
+// result as the original of the snapshot given by opts.SnapshotID.
+type redactPipeline struct {
+	pdfPipeline    model.Pipeline
+	pdfProc        *processor.PDFProcessor
+	ocrProc        *processor.OCRProcessor
+	s3             *infra.S3Manager
+	taskClient     *api_client.TaskClient
+	snapshotClient *api_client.SnapshotClient
+}
+
+func NewRedactPipeline() model.Pipeline {
+	return &redactPipeline{
+		pdfPipeline:    NewPDFPipeline(),
+		pdfProc:        processor.NewPDFProcessor(),
+		ocrProc:        processor.NewOCRProcessor(),
+		s3:             infra.NewS3Manager(),
+		taskClient:     api_client.NewTaskClient(),
+		snapshotClient: api_client.NewSnapshotClient(),
+	}
+}
+
+func (p *redactPipeline) Run(opts api_client.PipelineRunOptions) error {
+	inputPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + filepath.Ext(opts.Key))
+	if err := p.s3.GetFile(opts.Key, inputPath, opts.Bucket, minio.GetObjectOptions{}); err != nil {
+		return err
+	}
+	defer func(path string) {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(inputPath)
+	return p.RunFromLocalPath(inputPath, opts)
+}
+
+func (p *redactPipeline) RunFromLocalPath(inputPath string, opts api_client.PipelineRunOptions) error {
+	if opts.Payload == nil || opts.Payload[api_client.PayloadRedactions] == "" {
+		return errors.New("redactions are undefined")
+	}
+	var redactions []model.Redaction
+	if err := json.Unmarshal([]byte(opts.Payload[api_client.PayloadRedactions]), &redactions); err != nil {
+		return err
+	}
+	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+		Fields: []string{api_client.TaskFieldName},
+		Name:   helper.ToPtr("Redacting."),
+	}); err != nil {
+		return err
+	}
+	redactedPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + ".pdf")
+	if err := p.pdfProc.RedactPDF(inputPath, redactions, redactDPI, redactedPath); err != nil {
+		return err
+	}
+	defer func(path string) {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(redactedPath)
+	outputPath := redactedPath
+	if opts.Payload[api_client.PayloadLanguage] != "" {
+		if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+			Fields: []string{api_client.TaskFieldName},
+			Name:   helper.ToPtr("Restoring text layer."),
+		}); err != nil {
+			return err
+		}
+		ocrPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + ".pdf")
+		// We don't consider failing to restore the text layer an error, the redacted PDF is still valid
+		if err := p.ocrProc.SearchablePDFFromFile(redactedPath, opts.Payload[api_client.PayloadLanguage], redactDPI, ocrPath); err != nil {
+			infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+		} else {
+			defer func(path string) {
+				if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+					return
+				} else if err != nil {
+					infra.GetLogger().Error(err)
+				}
+			}(ocrPath)
+			outputPath = ocrPath
+		}
+	}
+	original, err := p.saveOriginal(outputPath, opts)
+	if err != nil {
+		return err
+	}
+	pdfOpts := opts
+	pdfOpts.Key = original.Key
+	return p.pdfPipeline.RunFromLocalPath(outputPath, pdfOpts)
+}
+
+func (p *redactPipeline) saveOriginal(inputPath string, opts api_client.PipelineRunOptions) (*api_client.S3Object, error) {
+	stat, err := os.Stat(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	s3Object := &api_client.S3Object{
+		Bucket: opts.Bucket,
+		Key:    opts.SnapshotID + "/original.pdf",
+		Size:   helper.ToPtr(stat.Size()),
+	}
+	if err := p.s3.PutFile(s3Object.Key, inputPath, helper.DetectMimeFromFile(inputPath), s3Object.Bucket, minio.PutObjectOptions{}); err != nil {
+		return nil, err
+	}
+	if err := p.snapshotClient.Patch(api_client.SnapshotPatchOptions{
+		Options:  opts,
+		Fields:   []string{api_client.SnapshotFieldOriginal},
+		Original: s3Object,
+	}); err != nil {
+		return nil, err
+	}
+	return s3Object, nil
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/kouprlabs/voltaserve/conversion/client/api_client"
+	"github.com/kouprlabs/voltaserve/conversion/helper"
+	"github.com/kouprlabs/voltaserve/conversion/identifier"
+	"github.com/kouprlabs/voltaserve/conversion/infra"
+	"github.com/kouprlabs/voltaserve/conversion/model"
+	"github.com/kouprlabs/voltaserve/conversion/processor"
+)
+
+type spreadsheetPipeline struct {
+	officePipeline model.Pipeline
+	officeProc     *processor.OfficeProcessor
+	sheetProc      *processor.SheetProcessor
+	fileIdent      *identifier.FileIdentifier
+	s3             *infra.S3Manager
+	taskClient     *api_client.TaskClient
+	snapshotClient *api_client.SnapshotClient
+}
+
+func NewSpreadsheetPipeline() model.Pipeline {
+	return &spreadsheetPipeline{
+		officePipeline: NewOfficePipeline(),
+		officeProc:     processor.NewOfficeProcessor(),
+		sheetProc:      processor.NewSheetProcessor(),
+		fileIdent:      identifier.NewFileIdentifier(),
+		s3:             infra.NewS3Manager(),
+		taskClient:     api_client.NewTaskClient(),
+		snapshotClient: api_client.NewSnapshotClient(),
+	}
+}
+
+func (p *spreadsheetPipeline) Run(opts api_client.PipelineRunOptions) error {
+	inputPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + filepath.Ext(opts.Key))
+	if err := p.s3.GetFile(opts.Key, inputPath, opts.Bucket, minio.GetObjectOptions{}); err != nil {
+		return err
+	}
+	defer func(path string) {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(inputPath)
+	return p.RunFromLocalPath(inputPath, opts)
+}
+
+// RunFromLocalPath keeps the PDF preview and thumbnail of the office pipeline, then
+// adds the structured rendition of the sheets, whose cells replace the text of the PDF.
+func (p *spreadsheetPipeline) RunFromLocalPath(inputPath string, opts api_client.PipelineRunOptions) error {
+	if err := p.officePipeline.RunFromLocalPath(inputPath, opts); err != nil {
+		return err
+	}
+	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+		Fields: []string{api_client.TaskFieldName},
+		Name:   helper.ToPtr("Reading sheets."),
+	}); err != nil {
+		return err
+	}
+	workbook, err := p.readWorkbook(inputPath)
+	if err != nil {
+		// We don't consider failing to read the sheets an error, the PDF preview can still be viewed
+		infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+		return nil
+	}
+	if err := p.createSheets(workbook, opts); err != nil {
+		return err
+	}
+	if err := p.createText(workbook, opts); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *spreadsheetPipeline) readWorkbook(inputPath string) (*model.Workbook, error) {
+	if p.fileIdent.IsCSV(inputPath) {
+		return p.sheetProc.ReadCSV(inputPath)
+	} else if p.fileIdent.IsXLSX(inputPath) {
+		return p.sheetProc.ReadXLSX(inputPath)
+	}
+	outputDir := filepath.FromSlash(os.TempDir() + "/" + helper.NewID())
+	defer func(path string) {
+		if err := os.RemoveAll(path); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(outputDir)
+	xlsxPath, err := p.officeProc.XLSX(inputPath, outputDir)
+	if err != nil {
+		return nil, err
+	}
+	return p.sheetProc.ReadXLSX(*xlsxPath)
+}
+
+func (p *spreadsheetPipeline) createSheets(workbook *model.Workbook, opts api_client.PipelineRunOptions) error {
+	b, err := json.Marshal(workbook)
+	if err != nil {
+		return err
+	}
+	content := string(b)
+	s3Object := api_client.S3Object{
+		Bucket:   opts.Bucket,
+		Key:      opts.SnapshotID + "/sheets.json",
+		Size:     helper.ToPtr(int64(len(content))),
+		Workbook: p.sheetProc.Props(workbook),
+	}
+	if err := p.s3.PutText(s3Object.Key, content, "application/json", s3Object.Bucket, minio.PutObjectOptions{}); err != nil {
+		return err
+	}
+	if err := p.snapshotClient.Patch(api_client.SnapshotPatchOptions{
+		Options: opts,
+		Fields:  []string{api_client.SnapshotFieldSheets},
+		Sheets:  &s3Object,
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *spreadsheetPipeline) createText(workbook *model.Workbook, opts api_client.PipelineRunOptions) error {
+	text := p.sheetProc.Text(workbook)
+	s3Object := api_client.S3Object{
+		Bucket: opts.Bucket,
+		Key:    opts.SnapshotID + "/text.txt",
+		Size:   helper.ToPtr(int64(len(text))),
+	}
+	if err := p.s3.PutText(s3Object.Key, text, "text/plain", s3Object.Bucket, minio.PutObjectOptions{}); err != nil {
+		return err
+	}
+	if err := p.snapshotClient.Patch(api_client.SnapshotPatchOptions{
+		Options: opts,
+		Fields:  []string{api_client.SnapshotFieldText},
+		Text:    &s3Object,
+	}); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package pipeline
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/minio/minio-go/v7"
+
+	"github.com/kouprlabs/voltaserve/conversion/client/api_client"
+	"github.com/kouprlabs/voltaserve/conversion/helper"
+	"github.com/kouprlabs/voltaserve/conversion/infra"
+	"github.com/kouprlabs/voltaserve/conversion/model"
+	"github.com/kouprlabs/voltaserve/conversion/processor"
+)
+
+type textPipeline struct {
+	textProc       *processor.TextProcessor
+	s3             *infra.S3Manager
+	taskClient     *api_client.TaskClient
+	snapshotClient *api_client.SnapshotClient
+}
+
+func NewTextPipeline() model.Pipeline {
+	return &textPipeline{
+		textProc:       processor.NewTextProcessor(),
+		s3:             infra.NewS3Manager(),
+		taskClient:     api_client.NewTaskClient(),
+		snapshotClient: api_client.NewSnapshotClient(),
+	}
+}
+
+func (p *textPipeline) Run(opts api_client.PipelineRunOptions) error {
+	inputPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + filepath.Ext(opts.Key))
+	if err := p.s3.GetFile(opts.Key, inputPath, opts.Bucket, minio.GetObjectOptions{}); err != nil {
+		return err
+	}
+	defer func(path string) {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(inputPath)
+	return p.RunFromLocalPath(inputPath, opts)
+}
+
+// RunFromLocalPath renders source code, Markdown, JSON and YAML to an HTML preview,
+// instead of converting them to PDF.
+func (p *textPipeline) RunFromLocalPath(inputPath string, opts api_client.PipelineRunOptions) error {
+	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+		Fields: []string{api_client.TaskFieldName},
+		Name:   helper.ToPtr("Rendering preview."),
+	}); err != nil {
+		return err
+	}
+	text, err := p.textProc.Read(inputPath)
+	if err != nil {
+		return err
+	}
+	if err := p.createPreview(text, opts); err != nil {
+		return err
+	}
+	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+		Fields: []string{api_client.TaskFieldName},
+		Name:   helper.ToPtr("Extracting text."),
+	}); err != nil {
+		return err
+	}
+	if err := p.createText(text, opts); err != nil {
+		return err
+	}
+	if err := p.taskClient.Patch(opts.TaskID, api_client.TaskPatchOptions{
+		Fields: []string{api_client.TaskFieldName, api_client.TaskFieldStatus},
+		Name:   helper.ToPtr("Done."),
+		Status: helper.ToPtr(api_client.TaskStatusSuccess),
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *textPipeline) createPreview(text string, opts api_client.PipelineRunOptions) error {
+	content := p.textProc.HTML(text, filepath.Ext(opts.Key))
+	s3Object := api_client.S3Object{
+		Bucket: opts.Bucket,
+		Key:    opts.SnapshotID + "/preview.html",
+		Size:   helper.ToPtr(int64(len(content))),
+	}
+	if err := p.s3.PutText(s3Object.Key, content, "text/html; charset=utf-8", s3Object.Bucket, minio.PutObjectOptions{}); err != nil {
+		return err
+	}
+	if err := p.snapshotClient.Patch(api_client.SnapshotPatchOptions{
+		Options: opts,
+		Fields:  []string{api_client.SnapshotFieldPreview},
+		Preview: &s3Object,
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *textPipeline) createText(text string, opts api_client.PipelineRunOptions) error {
+	s3Object := api_client.S3Object{
+		Bucket: opts.Bucket,
+		Key:    opts.SnapshotID + "/text.txt",
+		Size:   helper.ToPtr(int64(len(text))),
+	}
+	if err := p.s3.PutText(s3Object.Key, text, "text/plain", s3Object.Bucket, minio.PutObjectOptions{}); err != nil {
+		return err
+	}
+	if err := p.snapshotClient.Patch(api_client.SnapshotPatchOptions{
+		Options: opts,
+		Fields:  []string{api_client.SnapshotFieldText},
+		Text:    &s3Object,
+	}); err != nil {
+		return err
+	}
+	return nil
+}
@@ -27,6 +27,7 @@ import (
 
 type zipPipeline struct {
 	glbPipeline    model.Pipeline
+	modelPipeline  model.Pipeline
 	zipProc        *processor.ZIPProcessor
 	gltfProc       *processor.GLTFProcessor
 	s3             *infra.S3Manager
@@ -38,6 +39,7 @@ type zipPipeline struct {
 func NewZIPPipeline() model.Pipeline {
 	return &zipPipeline{
 		glbPipeline:    NewGLBPipeline(),
+		modelPipeline:  NewModelPipeline(),
 		zipProc:        processor.NewZIPProcessor(),
 		gltfProc:       processor.NewGLTFProcessor(),
 		s3:             infra.NewS3Manager(),
@@ -111,6 +113,14 @@ func (p *zipPipeline) RunFromLocalPath(inputPath string, opts api_client.Pipelin
 		if err := p.glbPipeline.RunFromLocalPath(*glbPath, opts); err != nil {
 			return err
 		}
+		return nil
+	}
+	isModelBundle, err := p.fi.IsModelBundle(inputPath)
+	if err != nil {
+		return err
+	}
+	if isModelBundle {
+		return p.modelPipeline.RunFromLocalPath(inputPath, opts)
 	}
 	// Do nothing, treat it as a ZIP file
 	return nil
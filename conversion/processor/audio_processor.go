@@ -0,0 +1,99 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kouprlabs/voltaserve/conversion/config"
+	"github.com/kouprlabs/voltaserve/conversion/helper"
+	"github.com/kouprlabs/voltaserve/conversion/infra"
+	"github.com/kouprlabs/voltaserve/conversion/model"
+)
+
+// waveformSampleRate is the rate the audio is decoded at to compute the peaks,
+// it is low to keep the decoded samples small, and high enough for drawing.
+const waveformSampleRate = 2000
+
+type AudioProcessor struct {
+	cmd    *infra.Command
+	config *config.Config
+}
+
+func NewAudioProcessor() *AudioProcessor {
+	return &AudioProcessor{
+		cmd:    infra.NewCommand(),
+		config: config.GetConfig(),
+	}
+}
+
+// HasAudio tells if the file has an audio track, which videos can lack.
+func (p *AudioProcessor) HasAudio(inputPath string) (bool, error) {
+	output, err := infra.NewCommand().ReadOutput(
+		"ffprobe", "-v", "error",
+		"-select_streams", "a",
+		"-show_entries", "stream=index",
+		"-of", "csv=p=0", inputPath,
+	)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(*output) != "", nil
+}
+
+// Waveform computes up to count peaks of the audio track downmixed to mono.
+func (p *AudioProcessor) Waveform(inputPath string, count int) (*model.Waveform, error) {
+	rawPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + ".raw")
+	defer func(path string) {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(rawPath)
+	if err := infra.NewCommand().Exec(
+		"ffmpeg", "-v", "error", "-i", inputPath,
+		"-vn", "-ac", "1", "-ar", strconv.Itoa(waveformSampleRate),
+		"-f", "s16le", "-acodec", "pcm_s16le", rawPath,
+	); err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(rawPath)
+	if err != nil {
+		return nil, err
+	}
+	samples := len(b) / 2
+	if samples == 0 {
+		return nil, errors.New("audio track has no samples")
+	}
+	count = min(count, samples)
+	peaks := make([]float64, count)
+	for i := range count {
+		start, end := i*samples/count, (i+1)*samples/count
+		var peak int
+		for j := start; j < end; j++ {
+			v := int(int16(binary.LittleEndian.Uint16(b[j*2:])))
+			peak = max(peak, v, -v)
+		}
+		/* Three decimals are plenty for drawing, and keep the JSON small */
+		peaks[i] = math.Round(float64(peak)/math.MaxInt16*1000) / 1000
+	}
+	return &model.Waveform{
+		Duration: float64(samples) / waveformSampleRate,
+		Peaks:    peaks,
+	}, nil
+}
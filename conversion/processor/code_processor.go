@@ -0,0 +1,315 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// codeLanguage describes the tokens the highlighter tells apart, the code isn't
+// parsed, which is enough to color keywords, strings, numbers and comments.
+type codeLanguage struct {
+	keywords      []string
+	lineComments  []string
+	blockComments [][2]string
+	quotes        []codeQuote
+	// hashComments are only comments after a space, like in shell scripts, so
+	// that they don't catch fragments of URLs.
+	hashComments bool
+	// stringKeys colors the strings followed by a colon as keys, like in JSON.
+	stringKeys bool
+}
+
+type codeQuote struct {
+	delimiter string
+	multiline bool
+	escapes   bool
+}
+
+var (
+	codeQuotesC      = []codeQuote{{`"`, false, true}, {`'`, false, true}}
+	codeQuotesJS     = []codeQuote{{"`", true, true}, {`"`, false, true}, {`'`, false, true}}
+	codeQuotesPython = []codeQuote{{`"""`, true, true}, {`'''`, true, true}, {`"`, false, true}, {`'`, false, true}}
+	codeKeywordsC    = []string{
+		"auto", "break", "case", "char", "const", "continue", "default", "do", "double", "else", "enum",
+		"extern", "float", "for", "goto", "if", "int", "long", "register", "return", "short", "signed",
+		"sizeof", "static", "struct", "switch", "typedef", "union", "unsigned", "void", "volatile",
+		"while", "NULL", "true", "false",
+	}
+	codeKeywordsJS = []string{
+		"async", "await", "break", "case", "catch", "class", "const", "continue", "debugger",
+		"default", "delete", "do", "else", "export", "extends", "false", "finally", "for", "from",
+		"function", "if", "import", "in", "instanceof", "let", "new", "null", "of", "return", "static",
+		"super", "switch", "this", "throw", "true", "try", "typeof", "undefined", "var", "void",
+		"while", "with", "yield",
+	}
+	codeLanguageC = &codeLanguage{
+		keywords:      codeKeywordsC,
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        codeQuotesC,
+	}
+	codeLanguageCPP = &codeLanguage{
+		keywords: append(slices.Clone(codeKeywordsC),
+			"bool", "catch", "class", "constexpr", "delete", "explicit", "friend", "inline",
+			"namespace", "new", "noexcept", "nullptr", "operator", "override", "private",
+			"protected", "public", "template", "this", "throw", "try", "typename", "using", "virtual",
+		),
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        codeQuotesC,
+	}
+	codeLanguageGo = &codeLanguage{
+		keywords: []string{
+			"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough",
+			"for", "func", "go", "goto", "if", "import", "interface", "map", "package", "range",
+			"return", "select", "struct", "switch", "type", "var", "true", "false", "nil", "iota",
+		},
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []codeQuote{{"`", true, false}, {`"`, false, true}, {`'`, false, true}},
+	}
+	codeLanguageJS = &codeLanguage{
+		keywords:      codeKeywordsJS,
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        codeQuotesJS,
+	}
+	codeLanguageTS = &codeLanguage{
+		keywords: append(slices.Clone(codeKeywordsJS),
+			"abstract", "any", "as", "boolean", "declare", "enum", "implements", "interface", "keyof",
+			"namespace", "never", "number", "private", "protected", "public", "readonly", "string",
+			"type", "unknown",
+		),
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        codeQuotesJS,
+	}
+	codeLanguageJava = &codeLanguage{
+		keywords: []string{
+			"abstract", "boolean", "break", "byte", "case", "catch", "char", "class", "continue",
+			"default", "do", "double", "else", "enum", "extends", "false", "final", "finally", "float",
+			"for", "if", "implements", "import", "instanceof", "int", "interface", "long", "new", "null",
+			"package", "private", "protected", "public", "record", "return", "short", "static", "super",
+			"switch", "synchronized", "this", "throw", "throws", "true", "try", "var", "void",
+			"volatile", "while",
+		},
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        []codeQuote{{`"""`, true, true}, {`"`, false, true}, {`'`, false, true}},
+	}
+	codeLanguagePython = &codeLanguage{
+		keywords: []string{
+			"False", "None", "True", "and", "as", "assert", "async", "await", "break", "class",
+			"continue", "def", "del", "elif", "else", "except", "finally", "for", "from", "global",
+			"if", "import", "in", "is", "lambda", "nonlocal", "not", "or", "pass", "raise", "return",
+			"self", "try", "while", "with", "yield",
+		},
+		lineComments: []string{"#"},
+		quotes:       codeQuotesPython,
+	}
+	codeLanguageRuby = &codeLanguage{
+		keywords: []string{
+			"alias", "and", "begin", "break", "case", "class", "def", "do", "else", "elsif", "end",
+			"ensure", "false", "for", "if", "in", "module", "next", "nil", "not", "or", "redo",
+			"require", "rescue", "retry", "return", "self", "super", "then", "true", "undef",
+			"unless", "until", "when", "while", "yield",
+		},
+		lineComments:  []string{"#"},
+		blockComments: [][2]string{{"=begin", "=end"}},
+		quotes:        codeQuotesC,
+	}
+	codeLanguageCSS = &codeLanguage{
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        codeQuotesC,
+	}
+	codeLanguageSCSS = &codeLanguage{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        codeQuotesC,
+	}
+	codeLanguageJSON = &codeLanguage{
+		keywords:   []string{"true", "false", "null"},
+		quotes:     []codeQuote{{`"`, false, true}},
+		stringKeys: true,
+	}
+	codeLanguageYAML = &codeLanguage{
+		keywords:     []string{"true", "false", "null", "yes", "no", "on", "off"},
+		lineComments: []string{"#"},
+		quotes:       []codeQuote{{`"`, false, true}, {`'`, false, false}},
+		hashComments: true,
+	}
+	codeLanguageTOML = &codeLanguage{
+		keywords:     []string{"true", "false"},
+		lineComments: []string{"#"},
+		quotes:       []codeQuote{{`"""`, true, true}, {`'''`, true, false}, {`"`, false, true}, {`'`, false, false}},
+		hashComments: true,
+	}
+)
+
+var codeLanguages = map[string]*codeLanguage{
+	".c":    codeLanguageC,
+	".h":    codeLanguageC,
+	".cpp":  codeLanguageCPP,
+	".hpp":  codeLanguageCPP,
+	".go":   codeLanguageGo,
+	".js":   codeLanguageJS,
+	".jsx":  codeLanguageJS,
+	".ts":   codeLanguageTS,
+	".tsx":  codeLanguageTS,
+	".java": codeLanguageJava,
+	".py":   codeLanguagePython,
+	".rb":   codeLanguageRuby,
+	".css":  codeLanguageCSS,
+	".scss": codeLanguageSCSS,
+	".sass": codeLanguageSCSS,
+	".json": codeLanguageJSON,
+	".yml":  codeLanguageYAML,
+	".yaml": codeLanguageYAML,
+	".toml": codeLanguageTOML,
+}
+
+type CodeProcessor struct{}
+
+func NewCodeProcessor() *CodeProcessor {
+	return &CodeProcessor{}
+}
+
+// Highlight escapes the source and wraps its tokens in spans whose classes are
+// "k" for keywords, "s" for strings, "a" for keys, "n" for numbers and "c" for
+// comments. Languages that aren't known are escaped only.
+func (p *CodeProcessor) Highlight(source string, extension string) string {
+	lang, ok := codeLanguages[strings.ToLower(extension)]
+	if !ok {
+		return html.EscapeString(source)
+	}
+	var b strings.Builder
+	for i := 0; i < len(source); {
+		if end, ok := p.matchComment(source, i, lang); ok {
+			p.writeToken(&b, "c", source[i:end])
+			i = end
+		} else if end, ok := p.matchString(source, i, lang); ok {
+			if lang.stringKeys && strings.HasPrefix(strings.TrimLeft(source[end:], " \t"), ":") {
+				p.writeToken(&b, "a", source[i:end])
+			} else {
+				p.writeToken(&b, "s", source[i:end])
+			}
+			i = end
+		} else if p.isDigit(source[i]) && (i == 0 || !p.isIdentifier(source[i-1])) {
+			end := i
+			for end < len(source) && (p.isIdentifier(source[end]) || source[end] == '.') {
+				end++
+			}
+			p.writeToken(&b, "n", source[i:end])
+			i = end
+		} else if p.isIdentifier(source[i]) {
+			end := i
+			for end < len(source) && p.isIdentifier(source[end]) {
+				end++
+			}
+			word := source[i:end]
+			if p.isKeyword(word, lang) {
+				p.writeToken(&b, "k", word)
+			} else {
+				b.WriteString(html.EscapeString(word))
+			}
+			i = end
+		} else {
+			_, size := utf8.DecodeRuneInString(source[i:])
+			b.WriteString(html.EscapeString(source[i : i+size]))
+			i += size
+		}
+	}
+	return b.String()
+}
+
+// Listing lays out the highlighted source next to its line numbers.
+func (p *CodeProcessor) Listing(source string, extension string) string {
+	source = strings.TrimSuffix(source, "\n")
+	var gutter strings.Builder
+	for i := 1; i <= strings.Count(source, "\n")+1; i++ {
+		gutter.WriteString(strconv.Itoa(i) + "\n")
+	}
+	return `<table class="listing"><tr><td class="gutter"><pre>` + gutter.String() +
+		`</pre></td><td class="code"><pre><code>` + p.Highlight(source, extension) +
+		`</code></pre></td></tr></table>`
+}
+
+func (p *CodeProcessor) matchComment(source string, i int, lang *codeLanguage) (int, bool) {
+	rest := source[i:]
+	for _, comment := range lang.blockComments {
+		if strings.HasPrefix(rest, comment[0]) {
+			end := strings.Index(rest[len(comment[0]):], comment[1])
+			if end < 0 {
+				return len(source), true
+			}
+			return i + len(comment[0]) + end + len(comment[1]), true
+		}
+	}
+	for _, comment := range lang.lineComments {
+		if !strings.HasPrefix(rest, comment) {
+			continue
+		}
+		if lang.hashComments && i > 0 && source[i-1] != ' ' && source[i-1] != '\t' && source[i-1] != '\n' {
+			continue
+		}
+		end := strings.IndexByte(rest, '\n')
+		if end < 0 {
+			return len(source), true
+		}
+		return i + end, true
+	}
+	return 0, false
+}
+
+// matchString ends unterminated strings at the end of the line, unless they
+// can span several lines.
+func (p *CodeProcessor) matchString(source string, i int, lang *codeLanguage) (int, bool) {
+	for _, quote := range lang.quotes {
+		if !strings.HasPrefix(source[i:], quote.delimiter) {
+			continue
+		}
+		j := i + len(quote.delimiter)
+		for j < len(source) {
+			if quote.escapes && source[j] == '\\' {
+				j += 2
+			} else if strings.HasPrefix(source[j:], quote.delimiter) {
+				return j + len(quote.delimiter), true
+			} else if source[j] == '\n' && !quote.multiline {
+				return j, true
+			} else {
+				j++
+			}
+		}
+		return len(source), true
+	}
+	return 0, false
+}
+
+func (p *CodeProcessor) writeToken(b *strings.Builder, class string, value string) {
+	b.WriteString(`<span class="` + class + `">` + html.EscapeString(value) + `</span>`)
+}
+
+func (p *CodeProcessor) isKeyword(word string, lang *codeLanguage) bool {
+	return slices.Contains(lang.keywords, word)
+}
+
+func (p *CodeProcessor) isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (p *CodeProcessor) isIdentifier(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || p.isDigit(c) || c == '_' || c == '$'
+}
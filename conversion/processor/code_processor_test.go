@@ -0,0 +1,47 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"testing"
+)
+
+func TestCodeHighlight(t *testing.T) {
+	p := NewCodeProcessor()
+	for _, tc := range []struct {
+		name      string
+		source    string
+		extension string
+		want      string
+	}{
+		{"keyword", "func main() {}", ".go", `<span class="k">func</span> main() {}`},
+		{"comment", "x // a <b>", ".go", `x <span class="c">// a &lt;b&gt;</span>`},
+		{"string", `s := "a\"b"`, ".go", `s := <span class="s">&#34;a\&#34;b&#34;</span>`},
+		{"number", "x = 42", ".go", `x = <span class="n">42</span>`},
+		{"hash comment", "x = 1 # c", ".py", `x = <span class="n">1</span> <span class="c"># c</span>`},
+		{"unknown language", "func <b>", ".unknown", "func &lt;b&gt;"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := p.Highlight(tc.source, tc.extension); got != tc.want {
+				t.Errorf("Highlight(%q, %q) = %q, want %q", tc.source, tc.extension, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCodeListing(t *testing.T) {
+	got := NewCodeProcessor().Listing("a\nb", ".txt")
+	want := `<table class="listing"><tr><td class="gutter"><pre>1` + "\n2\n" +
+		`</pre></td><td class="code"><pre><code>a` + "\nb" + `</code></pre></td></tr></table>`
+	if got != want {
+		t.Errorf("Listing() = %q, want %q", got, want)
+	}
+}
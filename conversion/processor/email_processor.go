@@ -0,0 +1,663 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kouprlabs/voltaserve/conversion/helper"
+	"github.com/kouprlabs/voltaserve/conversion/infra"
+	"github.com/kouprlabs/voltaserve/conversion/model"
+)
+
+// emailMaxDepth limits the nesting of multipart bodies.
+const emailMaxDepth = 16
+
+// emailAllowedElements are kept when sanitizing the HTML body of an email, other
+// elements are removed but their content is kept, except for emailDroppedElements
+// that are removed together with their content.
+var (
+	emailAllowedElements = []string{
+		"a", "abbr", "address", "b", "big", "blockquote", "br", "caption", "center", "cite",
+		"code", "col", "colgroup", "dd", "del", "div", "dl", "dt", "em", "font", "h1", "h2",
+		"h3", "h4", "h5", "h6", "hr", "i", "img", "ins", "kbd", "li", "ol", "p", "pre", "q",
+		"s", "small", "span", "strike", "strong", "sub", "sup", "table", "tbody", "td",
+		"tfoot", "th", "thead", "tr", "tt", "u", "ul",
+	}
+	emailDroppedElements = []string{
+		"applet", "audio", "button", "embed", "form", "frame", "frameset", "head", "iframe",
+		"math", "noscript", "object", "script", "select", "style", "svg", "template",
+		"textarea", "title", "video",
+	}
+	emailAllowedImageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp", "image/bmp"}
+	emailAllowedAttributes = []string{
+		"align", "alt", "bgcolor", "border", "cellpadding", "cellspacing", "color", "colspan",
+		"dir", "face", "height", "href", "lang", "rowspan", "size", "src", "style", "title",
+		"valign", "width",
+	}
+)
+
+// windows1252 maps the bytes 0x80 to 0x9F, the other bytes match ISO-8859-1.
+var windows1252 = [32]rune{
+	'€', '�', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '�', 'Ž', '�',
+	'�', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '�', 'ž', 'Ÿ',
+}
+
+const emailStylesheet = `
+body { font-family: sans-serif; font-size: 11pt; }
+table.headers th { text-align: left; padding-right: 12px; vertical-align: top; color: #555555; }
+pre.body { white-space: pre-wrap; font-family: sans-serif; }
+`
+
+type Email struct {
+	From        *string
+	To          []string
+	Cc          []string
+	Subject     *string
+	Date        *time.Time
+	MessageID   *string
+	HTML        *string
+	Text        *string
+	Attachments []EmailAttachment
+}
+
+type EmailAttachment struct {
+	Name        string
+	ContentType string
+	ContentID   string
+	Content     []byte
+	// Inline attachments are the images that the HTML body references by content ID.
+	Inline bool
+}
+
+type EmailProcessor struct {
+	cmd *infra.Command
+}
+
+func NewEmailProcessor() *EmailProcessor {
+	return &EmailProcessor{
+		cmd: infra.NewCommand(),
+	}
+}
+
+// Read parses the messages of an .eml, .msg or .mbox file, Outlook messages are
+// converted to MIME first.
+func (p *EmailProcessor) Read(inputPath string) ([]*Email, error) {
+	extension := strings.ToLower(filepath.Ext(inputPath))
+	if extension == ".msg" {
+		emlPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + ".eml")
+		if err := p.cmd.Exec("msgconvert", "--outfile", emlPath, inputPath); err != nil {
+			return nil, err
+		}
+		defer func(path string) {
+			if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+				return
+			} else if err != nil {
+				infra.GetLogger().Error(err)
+			}
+		}(emlPath)
+		inputPath = emlPath
+	}
+	b, err := os.ReadFile(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	var raws [][]byte
+	if extension == ".mbox" {
+		raws = p.splitMailbox(b)
+	} else {
+		raws = [][]byte{b}
+	}
+	var res []*Email
+	for _, raw := range raws {
+		email, err := p.parse(raw)
+		if err != nil {
+			if extension != ".mbox" {
+				return nil, err
+			}
+			// A malformed message doesn't prevent reading the rest of the mailbox
+			infra.GetLogger().Named(infra.StrPipeline).Errorw(err.Error())
+			continue
+		}
+		res = append(res, email)
+	}
+	if len(res) == 0 {
+		return nil, errors.New("no messages found")
+	}
+	return res, nil
+}
+
+// HTML renders the messages as a single document, each message starts on a new page.
+func (p *EmailProcessor) HTML(emails []*Email) string {
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE html><html><head><meta charset="utf-8"><style>` + emailStylesheet + `</style></head><body>`)
+	for i, email := range emails {
+		if i > 0 {
+			b.WriteString(`<div style="page-break-before: always"></div>`)
+		}
+		b.WriteString(`<table class="headers">`)
+		p.writeHeader(&b, "From", email.From)
+		p.writeHeader(&b, "To", p.join(email.To))
+		p.writeHeader(&b, "Cc", p.join(email.Cc))
+		if email.Date != nil {
+			p.writeHeader(&b, "Date", helper.ToPtr(email.Date.Format(time.RFC1123Z)))
+		}
+		p.writeHeader(&b, "Subject", email.Subject)
+		p.writeHeader(&b, "Attachments", p.join(p.attachmentNames(email)))
+		b.WriteString(`</table><hr>`)
+		if email.HTML != nil {
+			b.WriteString(p.Sanitize(*email.HTML, email.Attachments))
+		} else if email.Text != nil {
+			b.WriteString(`<pre class="body">` + html.EscapeString(*email.Text) + `</pre>`)
+		}
+	}
+	b.WriteString(`</body></html>`)
+	return b.String()
+}
+
+// Metadata returns the headers of the first message, and the attachments of all messages.
+func (p *EmailProcessor) Metadata(emails []*Email) *model.EmailMetadata {
+	email := emails[0]
+	res := &model.EmailMetadata{
+		From:      email.From,
+		To:        email.To,
+		Cc:        email.Cc,
+		Subject:   email.Subject,
+		MessageID: email.MessageID,
+		Messages:  len(emails),
+	}
+	if email.Date != nil {
+		res.Date = helper.ToPtr(email.Date.Format(time.RFC3339))
+	}
+	for _, email := range emails {
+		res.Attachments = append(res.Attachments, p.attachmentNames(email)...)
+	}
+	return res
+}
+
+// Sanitize keeps the elements and attributes that are safe to render, and drops
+// anything that runs scripts or loads remote content. Images are kept only when
+// they are embedded in the message, images referenced by content ID are inlined
+// as data URIs.
+func (p *EmailProcessor) Sanitize(value string, attachments []EmailAttachment) string {
+	var b strings.Builder
+	for i := 0; i < len(value); {
+		if value[i] != '<' {
+			end := strings.IndexByte(value[i:], '<')
+			if end < 0 {
+				end = len(value) - i
+			}
+			b.WriteString(value[i : i+end])
+			i += end
+			continue
+		}
+		rest := value[i:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				return b.String()
+			}
+			i += 4 + end + 3
+		case len(rest) > 1 && (rest[1] == '!' || rest[1] == '?'):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return b.String()
+			}
+			i += end + 1
+		case len(rest) > 1 && (rest[1] == '/' || p.isLetter(rest[1])):
+			tag, n := p.parseTag(rest)
+			i += n
+			if !tag.closing && !tag.selfClosing && slices.Contains(emailDroppedElements, tag.name) {
+				end := p.indexFold(value[i:], "</"+tag.name)
+				if end < 0 {
+					return b.String()
+				}
+				i += end
+				continue
+			}
+			if slices.Contains(emailAllowedElements, tag.name) {
+				p.writeTag(&b, tag, attachments)
+			}
+		default:
+			b.WriteString("&lt;")
+			i++
+		}
+	}
+	return b.String()
+}
+
+type emailTag struct {
+	name        string
+	closing     bool
+	selfClosing bool
+	attributes  [][2]string
+}
+
+// parseTag reads a tag starting with "<", and returns the number of bytes it spans.
+func (p *EmailProcessor) parseTag(s string) (emailTag, int) {
+	res := emailTag{}
+	i := 1
+	if s[i] == '/' {
+		res.closing = true
+		i++
+	}
+	start := i
+	for i < len(s) && !p.isSpace(s[i]) && s[i] != '>' && s[i] != '/' {
+		i++
+	}
+	res.name = strings.ToLower(s[start:i])
+	for i < len(s) {
+		for i < len(s) && (p.isSpace(s[i]) || s[i] == '/') {
+			res.selfClosing = s[i] == '/'
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+		if s[i] == '>' {
+			return res, i + 1
+		}
+		res.selfClosing = false
+		start := i
+		for i < len(s) && !p.isSpace(s[i]) && s[i] != '>' && s[i] != '/' && (s[i] != '=' || i == start) {
+			i++
+		}
+		name := strings.ToLower(s[start:i])
+		for i < len(s) && p.isSpace(s[i]) {
+			i++
+		}
+		var value string
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && p.isSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				quote := s[i]
+				end := strings.IndexByte(s[i+1:], quote)
+				if end < 0 {
+					return res, len(s)
+				}
+				value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start := i
+				for i < len(s) && !p.isSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				value = s[start:i]
+			}
+		}
+		res.attributes = append(res.attributes, [2]string{name, html.UnescapeString(value)})
+	}
+	return res, len(s)
+}
+
+func (p *EmailProcessor) writeTag(b *strings.Builder, tag emailTag, attachments []EmailAttachment) {
+	if tag.closing {
+		b.WriteString("</" + tag.name + ">")
+		return
+	}
+	var attributes strings.Builder
+	hasSource := false
+	alt := ""
+	for _, attribute := range tag.attributes {
+		name, value := attribute[0], attribute[1]
+		if name == "alt" {
+			alt = value
+		}
+		if !slices.Contains(emailAllowedAttributes, name) {
+			continue
+		}
+		switch name {
+		case "href":
+			if tag.name != "a" || !p.isSafeLink(value) {
+				continue
+			}
+		case "src":
+			if tag.name != "img" {
+				continue
+			}
+			source := p.imageSource(value, attachments)
+			if source == nil {
+				continue
+			}
+			value = *source
+			hasSource = true
+		case "style":
+			if !p.isSafeStyle(value) {
+				continue
+			}
+		}
+		attributes.WriteString(" " + name + `="` + html.EscapeString(value) + `"`)
+	}
+	if tag.name == "img" && !hasSource {
+		// Remote images are not loaded, their alternative text is shown instead
+		b.WriteString(html.EscapeString(alt))
+		return
+	}
+	b.WriteString("<" + tag.name + attributes.String() + ">")
+}
+
+// imageSource accepts raster images only, as SVG can reference remote content.
+func (p *EmailProcessor) imageSource(value string, attachments []EmailAttachment) *string {
+	value = strings.TrimSpace(value)
+	lower := strings.ToLower(value)
+	if strings.HasPrefix(lower, "data:") {
+		mediaType, _, _ := strings.Cut(lower[5:], ";")
+		if !slices.Contains(emailAllowedImageTypes, mediaType) {
+			return nil
+		}
+		return &value
+	}
+	if !strings.HasPrefix(lower, "cid:") {
+		return nil
+	}
+	for _, attachment := range attachments {
+		if attachment.ContentID != "" &&
+			strings.EqualFold(attachment.ContentID, value[4:]) &&
+			slices.Contains(emailAllowedImageTypes, attachment.ContentType) {
+			return helper.ToPtr("data:" + attachment.ContentType + ";base64," + base64.StdEncoding.EncodeToString(attachment.Content))
+		}
+	}
+	return nil
+}
+
+func (p *EmailProcessor) isSafeLink(value string) bool {
+	lower := strings.ToLower(strings.TrimSpace(value))
+	return strings.HasPrefix(lower, "http://") ||
+		strings.HasPrefix(lower, "https://") ||
+		strings.HasPrefix(lower, "mailto:")
+}
+
+// isSafeStyle rejects declarations that load resources, backslashes are rejected
+// too as CSS escapes could hide them.
+func (p *EmailProcessor) isSafeStyle(value string) bool {
+	lower := strings.ToLower(value)
+	return !strings.Contains(lower, "url(") &&
+		!strings.Contains(lower, "image-set(") &&
+		!strings.Contains(lower, "expression(") &&
+		!strings.Contains(lower, "@import") &&
+		!strings.Contains(lower, "\\")
+}
+
+func (p *EmailProcessor) parse(raw []byte) (*Email, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	decoder := p.wordDecoder()
+	res := &Email{
+		Subject:   p.decodeHeader(decoder, msg.Header.Get("Subject")),
+		MessageID: p.decodeHeader(decoder, strings.Trim(msg.Header.Get("Message-Id"), "<> ")),
+		To:        p.addresses(decoder, msg.Header.Get("To")),
+		Cc:        p.addresses(decoder, msg.Header.Get("Cc")),
+	}
+	if from := p.addresses(decoder, msg.Header.Get("From")); len(from) > 0 {
+		res.From = &from[0]
+	}
+	if date, err := msg.Header.Date(); err == nil {
+		res.Date = &date
+	}
+	if err := p.readPart(textproto.MIMEHeader(msg.Header), msg.Body, "", 0, decoder, res); err != nil {
+		return nil, err
+	}
+	if res.HTML != nil {
+		lower := strings.ToLower(*res.HTML)
+		for i, attachment := range res.Attachments {
+			res.Attachments[i].Inline = attachment.ContentID != "" &&
+				strings.Contains(lower, "cid:"+strings.ToLower(attachment.ContentID))
+		}
+	}
+	return res, nil
+}
+
+// readPart walks the MIME tree, the first text parts that aren't attachments make
+// the body, and the other parts the attachments. Only the first version of each
+// kind of a multipart/alternative is kept.
+func (p *EmailProcessor) readPart(header textproto.MIMEHeader, body io.Reader, parentType string, depth int, decoder *mime.WordDecoder, email *Email) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= emailMaxDepth {
+			return nil
+		}
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err != nil {
+				// Truncated bodies keep the parts read so far
+				return nil
+			}
+			if err := p.readPart(part.Header, part, mediaType, depth+1, decoder, email); err != nil {
+				return err
+			}
+		}
+	}
+	content, err := io.ReadAll(p.decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	name := dispositionParams["filename"]
+	if name == "" {
+		name = params["name"]
+	}
+	if value := p.decodeHeader(decoder, name); value != nil {
+		name = *value
+	}
+	if disposition != "attachment" && name == "" && (mediaType == "text/html" || mediaType == "text/plain") {
+		text := p.decodeCharset(params["charset"], content)
+		target := &email.Text
+		if mediaType == "text/html" {
+			target = &email.HTML
+		}
+		if *target == nil {
+			*target = &text
+		} else if parentType != "multipart/alternative" {
+			*target = helper.ToPtr(**target + "\n" + text)
+		}
+		return nil
+	}
+	email.Attachments = append(email.Attachments, EmailAttachment{
+		Name:        p.attachmentName(name, mediaType, len(email.Attachments)+1),
+		ContentType: mediaType,
+		ContentID:   strings.Trim(header.Get("Content-Id"), "<> "),
+		Content:     content,
+	})
+	return nil
+}
+
+// attachmentName strips the directories that some clients leave in file names,
+// and names the attachments that have no name.
+func (p *EmailProcessor) attachmentName(name string, mediaType string, index int) string {
+	name = strings.TrimSpace(name[strings.LastIndexAny(name, `/\`)+1:])
+	if name == "" || name == "." || name == ".." {
+		extension := ".bin"
+		if mediaType == "message/rfc822" {
+			extension = ".eml"
+		} else if extensions, err := mime.ExtensionsByType(mediaType); err == nil && len(extensions) > 0 {
+			extension = extensions[0]
+		}
+		name = fmt.Sprintf("attachment-%d%s", index, extension)
+	}
+	if len(name) > 255 {
+		extension := filepath.Ext(name)
+		if len(extension) > 16 {
+			extension = ""
+		}
+		name = strings.ToValidUTF8(name[:255-len(extension)], "") + extension
+	}
+	return name
+}
+
+func (p *EmailProcessor) attachmentNames(email *Email) []string {
+	var res []string
+	for _, attachment := range email.Attachments {
+		if !attachment.Inline {
+			res = append(res, attachment.Name)
+		}
+	}
+	return res
+}
+
+func (p *EmailProcessor) decodeTransferEncoding(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// decodeCharset handles UTF-8 and the Western European charsets, other charsets
+// are read as UTF-8 and invalid sequences are replaced.
+func (p *EmailProcessor) decodeCharset(charset string, b []byte) string {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "iso-8859-1", "iso8859-1", "latin1", "windows-1252", "cp1252":
+		var res strings.Builder
+		for _, c := range b {
+			if c >= 0x80 && c <= 0x9F {
+				res.WriteRune(windows1252[c-0x80])
+			} else {
+				res.WriteRune(rune(c))
+			}
+		}
+		return res.String()
+	}
+	return strings.ToValidUTF8(string(b), "�")
+}
+
+func (p *EmailProcessor) wordDecoder() *mime.WordDecoder {
+	return &mime.WordDecoder{
+		CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+			b, err := io.ReadAll(input)
+			if err != nil {
+				return nil, err
+			}
+			return strings.NewReader(p.decodeCharset(charset, b)), nil
+		},
+	}
+}
+
+func (p *EmailProcessor) decodeHeader(decoder *mime.WordDecoder, value string) *string {
+	if decoded, err := decoder.DecodeHeader(value); err == nil {
+		value = decoded
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// addresses formats the addresses of a header, and falls back to the decoded
+// header when it doesn't follow RFC 5322.
+func (p *EmailProcessor) addresses(decoder *mime.WordDecoder, value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	parser := mail.AddressParser{WordDecoder: decoder}
+	list, err := parser.ParseList(value)
+	if err != nil {
+		if decoded := p.decodeHeader(decoder, value); decoded != nil {
+			return []string{*decoded}
+		}
+		return nil
+	}
+	var res []string
+	for _, address := range list {
+		if address.Name != "" {
+			res = append(res, address.Name+" <"+address.Address+">")
+		} else {
+			res = append(res, address.Address)
+		}
+	}
+	return res
+}
+
+// splitMailbox splits an mbox file on its "From " lines, and unescapes the
+// ">From " lines of the messages.
+func (p *EmailProcessor) splitMailbox(b []byte) [][]byte {
+	var res [][]byte
+	var current *bytes.Buffer
+	for _, line := range bytes.SplitAfter(b, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("From ")) {
+			if current != nil && current.Len() > 0 {
+				res = append(res, current.Bytes())
+			}
+			current = &bytes.Buffer{}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if trimmed := bytes.TrimLeft(line, ">"); len(trimmed) < len(line) && bytes.HasPrefix(trimmed, []byte("From ")) {
+			line = line[1:]
+		}
+		current.Write(line)
+	}
+	if current != nil && current.Len() > 0 {
+		res = append(res, current.Bytes())
+	}
+	return res
+}
+
+func (p *EmailProcessor) writeHeader(b *strings.Builder, name string, value *string) {
+	if value == nil {
+		return
+	}
+	b.WriteString("<tr><th>" + name + "</th><td>" + html.EscapeString(*value) + "</td></tr>")
+}
+
+func (p *EmailProcessor) join(values []string) *string {
+	if len(values) == 0 {
+		return nil
+	}
+	return helper.ToPtr(strings.Join(values, ", "))
+}
+
+// indexFold finds an ASCII substring ignoring case.
+func (p *EmailProcessor) indexFold(s string, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *EmailProcessor) isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func (p *EmailProcessor) isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
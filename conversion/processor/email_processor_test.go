@@ -0,0 +1,145 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestEmailSanitize(t *testing.T) {
+	p := NewEmailProcessor()
+	attachments := []EmailAttachment{
+		{Name: "logo.png", ContentType: "image/png", ContentID: "logo", Content: []byte{1, 2}, Inline: true},
+	}
+	for _, tc := range []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"event handler", `<p onclick="x()">Hi <b>there</b></p>`, "<p>Hi <b>there</b></p>"},
+		{"script", `a<script>alert(1)</script>b`, "ab"},
+		{"iframe", `a<iframe src="https://example.com">b</iframe>c`, "ac"},
+		{"comment", `a<!-- b -->c`, "ac"},
+		{"unknown element", `<custom>kept</custom>`, "kept"},
+		{"stray bracket", `a < b`, "a &lt; b"},
+		{"unsafe link", `<a href="javascript:alert(1)">x</a>`, "<a>x</a>"},
+		{"link", `<a href="https://example.com">x</a>`, `<a href="https://example.com">x</a>`},
+		{"safe style", `<div style="color: red">a</div>`, `<div style="color: red">a</div>`},
+		{"remote style", `<div style="background: url(x)">a</div>`, "<div>a</div>"},
+		{"inline image", `<img src="cid:logo" alt="logo">`, `<img src="data:image/png;base64,AQI=" alt="logo">`},
+		{"remote image", `<img src="https://example.com/a.png" alt="remote">`, "remote"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := p.Sanitize(tc.value, attachments); got != tc.want {
+				t.Errorf("Sanitize(%q) = %q, want %q", tc.value, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEmailReadMessage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "message.eml")
+	raw := "From: =?UTF-8?B?SsO2cmc=?= <jorg@example.com>\r\n" +
+		"To: a@example.com, \"B\" <b@example.com>\r\n" +
+		"Subject: =?UTF-8?Q?Caf=C3=A9?=\r\n" +
+		"Date: Mon, 02 Jan 2006 15:04:05 +0000\r\n" +
+		"Message-ID: <1@example.com>\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"b\"\r\n" +
+		"\r\n" +
+		"--b\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"Content-Transfer-Encoding: quoted-printable\r\n" +
+		"\r\n" +
+		"Hello =E2=82=AC\r\n" +
+		"--b\r\n" +
+		"Content-Type: application/pdf; name=\"a.pdf\"\r\n" +
+		"Content-Disposition: attachment; filename=\"a.pdf\"\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"JVBERg==\r\n" +
+		"--b--\r\n"
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p := NewEmailProcessor()
+	emails, err := p.Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 1 {
+		t.Fatalf("len(emails) = %d, want 1", len(emails))
+	}
+	email := emails[0]
+	if *email.From != "Jörg <jorg@example.com>" {
+		t.Errorf("From = %q", *email.From)
+	}
+	if !slices.Equal(email.To, []string{"a@example.com", "B <b@example.com>"}) {
+		t.Errorf("To = %q", email.To)
+	}
+	if *email.Subject != "Café" {
+		t.Errorf("Subject = %q", *email.Subject)
+	}
+	if !email.Date.Equal(time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("Date = %v", email.Date)
+	}
+	if *email.Text != "Hello €" {
+		t.Errorf("Text = %q", *email.Text)
+	}
+	if len(email.Attachments) != 1 || email.Attachments[0].Name != "a.pdf" || string(email.Attachments[0].Content) != "%PDF" {
+		t.Errorf("Attachments = %+v", email.Attachments)
+	}
+	metadata := p.Metadata(emails)
+	if *metadata.MessageID != "1@example.com" || *metadata.Date != "2006-01-02T15:04:05Z" || metadata.Messages != 1 {
+		t.Errorf("Metadata = %+v", metadata)
+	}
+}
+
+func TestEmailReadMailbox(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mailbox.mbox")
+	raw := "From a@example.com Mon Jan  2 15:04:05 2006\n" +
+		"From: a@example.com\n" +
+		"Subject: one\n" +
+		"\n" +
+		"body one\n" +
+		">From here\n" +
+		"\n" +
+		"From b@example.com Mon Jan  2 15:04:05 2006\n" +
+		"From: b@example.com\n" +
+		"Subject: two\n" +
+		"\n" +
+		"body two\n"
+	if err := os.WriteFile(path, []byte(raw), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	emails, err := NewEmailProcessor().Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 2 {
+		t.Fatalf("len(emails) = %d, want 2", len(emails))
+	}
+	for i, want := range []struct {
+		subject string
+		text    string
+	}{
+		{"one", "body one\nFrom here\n\n"},
+		{"two", "body two\n"},
+	} {
+		if *emails[i].Subject != want.subject || *emails[i].Text != want.text {
+			t.Errorf("emails[%d] = %q, %q, want %q, %q", i, *emails[i].Subject, *emails[i].Text, want.subject, want.text)
+		}
+	}
+}
@@ -13,17 +13,24 @@ package processor
 import (
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"path/filepath"
 
+	"github.com/kouprlabs/voltaserve/conversion/client/api_client"
 	"github.com/kouprlabs/voltaserve/conversion/config"
+	"github.com/kouprlabs/voltaserve/conversion/helper"
+	"github.com/kouprlabs/voltaserve/conversion/identifier"
 	"github.com/kouprlabs/voltaserve/conversion/infra"
 )
 
 type GLBProcessor struct {
 	cmd       *infra.Command
 	imageProc *ImageProcessor
+	fi        *identifier.FileIdentifier
 	config    *config.Config
 }
 
@@ -31,6 +38,7 @@ func NewGLBProcessor() *GLBProcessor {
 	return &GLBProcessor{
 		cmd:       infra.NewCommand(),
 		imageProc: NewImageProcessor(),
+		fi:        identifier.NewFileIdentifier(),
 		config:    config.GetConfig(),
 	}
 }
@@ -223,12 +231,207 @@ bpy.ops.render.render(write_still=True)
 	return nil
 }
 
+// FromModel converts OBJ, FBX, STL, PLY, USD and STEP to GLB. The MTL files and
+// textures referenced by the model are resolved relative to its directory,
+// and embedded in the GLB.
+func (p *GLBProcessor) FromModel(inputPath string, outputPath string) error {
+	if p.fi.IsSTEP(inputPath) {
+		stlPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + ".stl")
+		defer func(path string) {
+			if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+				return
+			} else if err != nil {
+				infra.GetLogger().Error(err)
+			}
+		}(stlPath)
+		if err := p.stepToSTL(inputPath, stlPath); err != nil {
+			return err
+		}
+		inputPath = stlPath
+	}
+	err := infra.NewCommand().Exec("blender", "--background", "--python-expr", `
+import bpy
+import os
+import sys
+
+# Read command-line arguments
+argv = sys.argv
+argv = argv[argv.index("--") + 1 :]
+input_file = argv[argv.index("--input") + 1]
+output_file = argv[argv.index("--output") + 1]
+
+# Start from an empty scene, without the default cube, camera and light
+bpy.ops.wm.read_factory_settings(use_empty=True)
+
+# The importers moved to bpy.ops.wm as they were rewritten in C++, and the
+# legacy ones were removed afterwards
+extension = os.path.splitext(input_file)[1].lower()
+if extension == ".obj":
+    if bpy.app.version >= (3, 2, 0):
+        bpy.ops.wm.obj_import(filepath=input_file)
+    else:
+        bpy.ops.import_scene.obj(filepath=input_file)
+elif extension == ".fbx":
+    bpy.ops.import_scene.fbx(filepath=input_file)
+elif extension == ".stl":
+    if bpy.app.version >= (4, 2, 0):
+        bpy.ops.wm.stl_import(filepath=input_file)
+    else:
+        bpy.ops.import_mesh.stl(filepath=input_file)
+elif extension == ".ply":
+    if bpy.app.version >= (4, 0, 0):
+        bpy.ops.wm.ply_import(filepath=input_file)
+    else:
+        bpy.ops.import_mesh.ply(filepath=input_file)
+elif extension in (".usd", ".usda", ".usdc", ".usdz"):
+    bpy.ops.wm.usd_import(filepath=input_file)
+else:
+    raise RuntimeError("Unsupported format: " + extension)
+
+if not bpy.context.scene.objects:
+    raise RuntimeError("No objects imported")
+
+# Export GLB with the textures embedded
+bpy.ops.export_scene.gltf(
+    filepath=output_file,
+    export_format="GLB",
+    export_apply=True,
+    export_animations=True,
+)
+`,
+		"--",
+		"--input", inputPath,
+		"--output", outputPath,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// stepToSTL tessellates a STEP file with FreeCAD. The deflection is relative to
+// the size of the shape, so that small and large parts keep the same level of detail.
+func (p *GLBProcessor) stepToSTL(inputPath string, outputPath string) error {
+	if err := p.cmd.Exec("freecadcmd", "-c", fmt.Sprintf(`
+import MeshPart
+import Part
+
+shape = Part.read(%q)
+deflection = max(shape.BoundBox.DiagonalLength / 1000, 0.001)
+mesh = MeshPart.meshFromShape(Shape=shape, LinearDeflection=deflection, AngularDeflection=0.5)
+mesh.write(%q)
+`, inputPath, outputPath)); err != nil {
+		return err
+	}
+	if _, err := os.Stat(outputPath); err != nil {
+		return errors.New("freecad did not produce a mesh")
+	}
+	return nil
+}
+
+// Statistics counts the vertices and triangles of the scene as it is rendered,
+// so a mesh instanced by several nodes is counted once per node. The bounding
+// box is computed from the bounds of the positions of each primitive, which
+// glTF requires, transformed by the nodes.
+func (p *GLBProcessor) Statistics(inputPath string) (*api_client.ModelProps, error) {
+	doc, err := p.readGLTF(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	res := &api_client.ModelProps{
+		Meshes:        len(doc.Meshes),
+		Materials:     len(doc.Materials),
+		HasAnimations: len(doc.Animations) > 0,
+	}
+	var bbox *api_client.BoundingBox
+	var visit func(index int, parent gltfMatrix, depth int)
+	visit = func(index int, parent gltfMatrix, depth int) {
+		// The depth guards against cycles in malformed files
+		if index < 0 || index >= len(doc.Nodes) || depth > len(doc.Nodes) {
+			return
+		}
+		node := doc.Nodes[index]
+		world := parent.mul(node.matrix())
+		if node.Mesh != nil && *node.Mesh >= 0 && *node.Mesh < len(doc.Meshes) {
+			for _, primitive := range doc.Meshes[*node.Mesh].Primitives {
+				bbox = p.addPrimitive(doc, primitive, world, res, bbox)
+			}
+		}
+		for _, child := range node.Children {
+			visit(child, world, depth+1)
+		}
+	}
+	for _, root := range doc.rootNodes() {
+		visit(root, gltfIdentity, 0)
+	}
+	res.BoundingBox = bbox
+	return res, nil
+}
+
+func (p *GLBProcessor) addPrimitive(
+	doc *gltfDocument,
+	primitive gltfPrimitive,
+	world gltfMatrix,
+	props *api_client.ModelProps,
+	bbox *api_client.BoundingBox,
+) *api_client.BoundingBox {
+	position, ok := primitive.Attributes["POSITION"]
+	if !ok || position < 0 || position >= len(doc.Accessors) {
+		return bbox
+	}
+	accessor := doc.Accessors[position]
+	props.Vertices += accessor.Count
+	count := accessor.Count
+	if primitive.Indices != nil && *primitive.Indices >= 0 && *primitive.Indices < len(doc.Accessors) {
+		count = doc.Accessors[*primitive.Indices].Count
+	}
+	// Modes are points (0), lines (1 to 3), triangles (4), strip (5) and fan (6)
+	mode := 4
+	if primitive.Mode != nil {
+		mode = *primitive.Mode
+	}
+	if mode == 4 {
+		props.Triangles += count / 3
+	} else if (mode == 5 || mode == 6) && count > 2 {
+		props.Triangles += count - 2
+	}
+	if len(accessor.Min) != 3 || len(accessor.Max) != 3 {
+		return bbox
+	}
+	for i := 0; i < 8; i++ {
+		corner := [3]float64{accessor.Min[0], accessor.Min[1], accessor.Min[2]}
+		for axis := 0; axis < 3; axis++ {
+			if i&(1<<axis) != 0 {
+				corner[axis] = accessor.Max[axis]
+			}
+		}
+		point := world.apply(corner)
+		if bbox == nil {
+			bbox = &api_client.BoundingBox{Min: point, Max: point}
+			continue
+		}
+		for axis := 0; axis < 3; axis++ {
+			bbox.Min[axis] = math.Min(bbox.Min[axis], point[axis])
+			bbox.Max[axis] = math.Max(bbox.Max[axis], point[axis])
+		}
+	}
+	return bbox
+}
+
 func (p *GLBProcessor) hasAnimations(filePath string) bool {
-	file, err := os.Open(filePath) //nolint:gosec // Known path
+	doc, err := p.readGLTF(filePath)
 	if err != nil {
 		infra.GetLogger().Error(err)
 		return false
 	}
+	return len(doc.Animations) > 0
+}
+
+func (p *GLBProcessor) readGLTF(filePath string) (*gltfDocument, error) {
+	file, err := os.Open(filePath) //nolint:gosec // Known path
+	if err != nil {
+		return nil, err
+	}
 	defer func(file *os.File) {
 		if err := file.Close(); err != nil {
 			infra.GetLogger().Error(err)
@@ -236,12 +439,11 @@ func (p *GLBProcessor) hasAnimations(filePath string) bool {
 	}(file)
 	data, err := io.ReadAll(file)
 	if err != nil {
-		infra.GetLogger().Error(err)
-		return false
+		return nil, err
 	}
 	// GLB Header is 12 bytes: magic (4 bytes) + version (4 bytes) + length (4 bytes)
 	if len(data) < 12 || string(data[:4]) != "glTF" {
-		return false
+		return nil, errors.New("invalid GLB header")
 	}
 	const headerSize = 12
 	const chunkHeaderSize = 8
@@ -249,15 +451,13 @@ func (p *GLBProcessor) hasAnimations(filePath string) bool {
 	var jsonChunk []byte
 	for offset < len(data) {
 		if offset+chunkHeaderSize > len(data) {
-			// Invalid chunk header in GLB file
-			return false
+			return nil, errors.New("invalid chunk header in GLB file")
 		}
 		chunkLength := binary.LittleEndian.Uint32(data[offset : offset+4])
 		chunkType := string(data[offset+4 : offset+8])
 		offset += chunkHeaderSize
 		if offset+int(chunkLength) > len(data) {
-			// Chunk length exceeds file size in GLB file
-			return false
+			return nil, errors.New("chunk length exceeds file size in GLB file")
 		}
 		if chunkType == "JSON" {
 			jsonChunk = data[offset : offset+int(chunkLength)]
@@ -266,15 +466,122 @@ func (p *GLBProcessor) hasAnimations(filePath string) bool {
 		offset += int(chunkLength)
 	}
 	if jsonChunk == nil {
-		return false
+		return nil, errors.New("missing JSON chunk in GLB file")
 	}
-	type GLTF struct {
-		Animations []interface{} `json:"animations"`
+	var res gltfDocument
+	if err := json.Unmarshal(jsonChunk, &res); err != nil {
+		return nil, err
 	}
-	var gltf GLTF
-	if err := json.Unmarshal(jsonChunk, &gltf); err != nil {
-		infra.GetLogger().Error(err)
-		return false
+	return &res, nil
+}
+
+type gltfDocument struct {
+	Scene  *int `json:"scene"`
+	Scenes []struct {
+		Nodes []int `json:"nodes"`
+	} `json:"scenes"`
+	Nodes  []gltfNode `json:"nodes"`
+	Meshes []struct {
+		Primitives []gltfPrimitive `json:"primitives"`
+	} `json:"meshes"`
+	Accessors []struct {
+		Count int       `json:"count"`
+		Min   []float64 `json:"min"`
+		Max   []float64 `json:"max"`
+	} `json:"accessors"`
+	Materials  []interface{} `json:"materials"`
+	Animations []interface{} `json:"animations"`
+}
+
+// rootNodes returns the nodes of the default scene, or when there is no scene,
+// the nodes that aren't children of other nodes.
+func (d *gltfDocument) rootNodes() []int {
+	if len(d.Scenes) > 0 {
+		if d.Scene != nil && *d.Scene >= 0 && *d.Scene < len(d.Scenes) {
+			return d.Scenes[*d.Scene].Nodes
+		}
+		return d.Scenes[0].Nodes
+	}
+	isChild := make(map[int]bool)
+	for _, node := range d.Nodes {
+		for _, child := range node.Children {
+			isChild[child] = true
+		}
+	}
+	var res []int
+	for i := range d.Nodes {
+		if !isChild[i] {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+type gltfNode struct {
+	Mesh        *int      `json:"mesh"`
+	Children    []int     `json:"children"`
+	Matrix      []float64 `json:"matrix"`
+	Translation []float64 `json:"translation"`
+	Rotation    []float64 `json:"rotation"`
+	Scale       []float64 `json:"scale"`
+}
+
+// matrix returns the local transform of the node, which is either given as a
+// matrix, or as translation, rotation (quaternion) and scale.
+func (n gltfNode) matrix() gltfMatrix {
+	if len(n.Matrix) == 16 {
+		var res gltfMatrix
+		copy(res[:], n.Matrix)
+		return res
+	}
+	t := [3]float64{0, 0, 0}
+	if len(n.Translation) == 3 {
+		copy(t[:], n.Translation)
+	}
+	q := [4]float64{0, 0, 0, 1}
+	if len(n.Rotation) == 4 {
+		copy(q[:], n.Rotation)
+	}
+	s := [3]float64{1, 1, 1}
+	if len(n.Scale) == 3 {
+		copy(s[:], n.Scale)
+	}
+	x, y, z, w := q[0], q[1], q[2], q[3]
+	return gltfMatrix{
+		(1 - 2*(y*y+z*z)) * s[0], 2 * (x*y + z*w) * s[0], 2 * (x*z - y*w) * s[0], 0,
+		2 * (x*y - z*w) * s[1], (1 - 2*(x*x+z*z)) * s[1], 2 * (y*z + x*w) * s[1], 0,
+		2 * (x*z + y*w) * s[2], 2 * (y*z - x*w) * s[2], (1 - 2*(x*x+y*y)) * s[2], 0,
+		t[0], t[1], t[2], 1,
+	}
+}
+
+type gltfPrimitive struct {
+	Attributes map[string]int `json:"attributes"`
+	Indices    *int           `json:"indices"`
+	Mode       *int           `json:"mode"`
+}
+
+// gltfMatrix is a 4x4 matrix in column-major order, as in glTF.
+type gltfMatrix [16]float64
+
+var gltfIdentity = gltfMatrix{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+
+func (m gltfMatrix) mul(o gltfMatrix) gltfMatrix {
+	var res gltfMatrix
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			for k := 0; k < 4; k++ {
+				res[col*4+row] += m[k*4+row] * o[col*4+k]
+			}
+		}
+	}
+	return res
+}
+
+func (m gltfMatrix) apply(v [3]float64) [3]float64 {
+	return [3]float64{
+		m[0]*v[0] + m[4]*v[1] + m[8]*v[2] + m[12],
+		m[1]*v[0] + m[5]*v[1] + m[9]*v[2] + m[13],
+		m[2]*v[0] + m[6]*v[1] + m[10]*v[2] + m[14],
 	}
-	return len(gltf.Animations) > 0
 }
@@ -11,6 +11,9 @@
 package processor
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -24,6 +27,10 @@ import (
 	"github.com/kouprlabs/voltaserve/conversion/infra"
 )
 
+// rawPreviewMinSize is the longest side, in pixels, below which the preview
+// embedded in a RAW image is too small to be used, so the RAW gets developed.
+const rawPreviewMinSize = 1024
+
 type ImageProcessor struct {
 	fileIdent  *identifier.FileIdentifier
 	imageIdent *identifier.ImageIdentifier
@@ -153,6 +160,163 @@ func (p *ImageProcessor) ConvertImage(inputPath string, outputPath string) error
 	}
 }
 
+// DecodeImage converts camera RAW, HEIF and layered PSD images, which browsers
+// cannot display, to the format given by the extension of outputPath.
+func (p *ImageProcessor) DecodeImage(inputPath string, outputPath string) error {
+	if p.imageIdent.IsRAW(inputPath) {
+		if p.imageIdent.IsJPEG(outputPath) {
+			if err := p.ExtractRAWPreview(inputPath, outputPath); err == nil {
+				return nil
+			}
+		}
+		return p.DevelopRAW(inputPath, outputPath)
+	} else if p.imageIdent.IsHEIF(inputPath) {
+		if err := infra.NewCommand().Exec("heif-convert", "-q", "90", inputPath, outputPath); err != nil {
+			return err
+		}
+		return nil
+	} else if p.imageIdent.IsPSD(inputPath) {
+		// The first image of a PSD is the composite of all its layers
+		if err := infra.NewCommand().Exec("convert", inputPath+"[0]", "-flatten", outputPath); err != nil {
+			return err
+		}
+		return nil
+	} else {
+		return p.ConvertImage(inputPath, outputPath)
+	}
+}
+
+// ExtractRAWPreview writes the JPEG preview embedded by the camera, which is much
+// faster than developing the RAW, provided it is large enough.
+func (p *ImageProcessor) ExtractRAWPreview(inputPath string, outputPath string) error {
+	for _, tag := range []string{"-JpgFromRaw", "-PreviewImage"} {
+		preview, err := infra.NewCommand().ReadOutput("exiftool", "-b", tag, inputPath)
+		if err != nil || len(*preview) == 0 {
+			continue
+		}
+		if err := os.WriteFile(outputPath, []byte(*preview), 0o600); err != nil {
+			return err
+		}
+		props, err := p.MeasureImage(outputPath)
+		if err != nil || max(props.Width, props.Height) < rawPreviewMinSize {
+			continue
+		}
+		// The preview doesn't carry the orientation of the RAW, and not all cameras
+		// write one, so we don't consider failing to copy it an error
+		_ = infra.NewCommand().Exec("exiftool", "-overwrite_original", "-TagsFromFile", inputPath, "-Orientation", outputPath)
+		return nil
+	}
+	return errors.New("RAW image has no usable embedded preview")
+}
+
+// DevelopRAW demosaics the sensor data with the white balance of the camera.
+func (p *ImageProcessor) DevelopRAW(inputPath string, outputPath string) error {
+	tiffPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + ".tiff")
+	if err := infra.NewCommand().Exec("dcraw_emu", "-w", "-T", "-Z", tiffPath, inputPath); err != nil {
+		return err
+	}
+	defer func(path string) {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(tiffPath)
+	return p.ConvertImage(tiffPath, outputPath)
+}
+
+// ImageColorInfo describes how the pixels of an image are stored, as reported by ImageMagick.
+type ImageColorInfo struct {
+	ColorSpace  string
+	Profile     string
+	Depth       int
+	Orientation string
+	HasAlpha    bool
+}
+
+// Name is the description of the embedded ICC profile, like "Adobe RGB (1998)",
+// or the color space when the image has no profile.
+func (i ImageColorInfo) Name() string {
+	if i.Profile != "" {
+		return i.Profile
+	}
+	return i.ColorSpace
+}
+
+// RequiresNormalization tells whether browsers would display the image rotated,
+// or with wrong colors, unless it gets normalized first.
+func (i ImageColorInfo) RequiresNormalization() bool {
+	if i.Orientation != "" && i.Orientation != "TopLeft" && i.Orientation != "Undefined" {
+		return true
+	}
+	if i.Depth > 8 {
+		return true
+	}
+	if i.ColorSpace != "sRGB" && i.ColorSpace != "Gray" {
+		return true
+	}
+	return i.Profile != "" && !strings.Contains(strings.ToLower(i.Profile), "srgb")
+}
+
+func (p *ImageProcessor) ColorInfo(inputPath string) (*ImageColorInfo, error) {
+	output, err := infra.NewCommand().ReadOutput(
+		"identify", "-format", "%[orientation]|%[colorspace]|%[depth]|%[channels]|%[profile:icc]", inputPath+"[0]",
+	)
+	if err != nil {
+		return nil, err
+	}
+	values := strings.SplitN(strings.TrimSpace(*output), "|", 5)
+	if len(values) < 5 {
+		return nil, errors.New("unexpected output of identify: " + *output)
+	}
+	depth, err := strconv.Atoi(values[2])
+	if err != nil {
+		return nil, err
+	}
+	// Channels are given like "srgba" or "cmyka  5.0" depending on the version
+	channels := strings.Fields(values[3])
+	return &ImageColorInfo{
+		Orientation: values[0],
+		ColorSpace:  values[1],
+		Depth:       depth,
+		HasAlpha:    len(channels) > 0 && strings.HasSuffix(channels[0], "a"),
+		Profile:     strings.TrimSpace(values[4]),
+	}, nil
+}
+
+// NormalizeImage auto-orients the image and converts it to 8-bit sRGB, using the ICC
+// profiles of the configuration when available. The alpha channel is flattened on
+// white when outputPath is a JPEG. If info is nil, the colors are converted naively.
+func (p *ImageProcessor) NormalizeImage(inputPath string, info *ImageColorInfo, outputPath string) error {
+	args := []string{inputPath + "[0]", "-auto-orient"}
+	sRGBProfile, cmykProfile := p.config.Color.SRGBProfile, p.config.Color.CMYKProfile
+	switch {
+	case info != nil && info.ColorSpace == "CMYK" && info.Profile == "" && p.isFile(cmykProfile) && p.isFile(sRGBProfile):
+		// The first profile is assigned, as the image has none, the second one converts
+		args = append(args, "-profile", cmykProfile, "-profile", sRGBProfile)
+	case info != nil && info.Profile != "" && p.isFile(sRGBProfile):
+		args = append(args, "-profile", sRGBProfile)
+	case info == nil || info.ColorSpace != "Gray":
+		args = append(args, "-colorspace", "sRGB")
+	}
+	args = append(args, "-depth", "8")
+	if p.imageIdent.IsJPEG(outputPath) {
+		args = append(args, "-background", "white", "-alpha", "remove", "-alpha", "off", "-quality", "90")
+	}
+	if err := infra.NewCommand().Exec("convert", append(args, outputPath)...); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p *ImageProcessor) isFile(path string) bool {
+	if path == "" {
+		return false
+	}
+	stat, err := os.Stat(path)
+	return err == nil && !stat.IsDir()
+}
+
 func (p *ImageProcessor) RemoveAlphaChannel(inputPath string, outputPath string) error {
 	bildImage, err := imgio.Open(inputPath)
 	if err == nil && p.IsSupportedByBild(outputPath) {
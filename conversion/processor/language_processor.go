@@ -0,0 +1,237 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/kouprlabs/voltaserve/conversion/model"
+)
+
+const (
+	// languageChunkMinLetters is the amount of letters a chunk of text needs
+	// to be classified on its own, shorter paragraphs are merged together.
+	languageChunkMinLetters = 300
+	// languageMinLetters is the amount of letters below which we don't attempt
+	// to detect the language at all.
+	languageMinLetters = 20
+	// languageSecondaryMinShare is the share of the text a language needs to
+	// be included in a multi-language combination.
+	languageSecondaryMinShare = 0.2
+	languageMaxCombination    = 3
+)
+
+var languageStopWords = map[string][]string{
+	"eng": {"the", "and", "of", "to", "in", "is", "that", "for", "it", "with", "as", "was", "on", "are", "be", "this", "by", "have", "from", "not", "or", "which", "an", "at", "were"},
+	"deu": {"der", "die", "und", "das", "ist", "nicht", "ein", "eine", "zu", "den", "von", "mit", "sich", "des", "auf", "für", "im", "dem", "auch", "es", "wird", "sind", "oder", "aus"},
+	"fra": {"le", "la", "les", "et", "des", "est", "un", "une", "du", "dans", "que", "pour", "qui", "pas", "sur", "au", "avec", "ce", "il", "sont", "par", "plus", "ne", "se"},
+	"ita": {"il", "di", "che", "la", "per", "un", "una", "del", "della", "sono", "non", "con", "gli", "le", "da", "nel", "alla", "questo", "anche", "è", "ma", "si", "dei"},
+	"nld": {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "te", "zijn", "met", "voor", "die", "er", "aan", "ook", "als", "bij", "naar", "wordt", "maar", "om", "dit"},
+	"por": {"de", "o", "que", "do", "da", "em", "um", "uma", "para", "com", "não", "os", "no", "na", "se", "por", "mais", "as", "dos", "como", "mas", "ao", "é"},
+	"spa": {"de", "la", "que", "el", "en", "y", "los", "del", "se", "las", "por", "un", "para", "con", "no", "una", "su", "al", "es", "lo", "como", "más", "pero", "sus"},
+	"swe": {"och", "att", "det", "som", "en", "på", "är", "av", "för", "med", "till", "den", "har", "de", "inte", "om", "ett", "var", "jag", "men", "sig", "från", "vi", "så"},
+	"nor": {"og", "i", "det", "er", "som", "en", "på", "til", "av", "for", "med", "at", "har", "ikke", "den", "de", "et", "var", "fra", "om", "seg", "men", "også", "jeg", "vi"},
+	"dan": {"og", "i", "at", "det", "er", "en", "til", "på", "som", "de", "med", "af", "for", "ikke", "den", "har", "et", "der", "var", "fra", "om", "men", "også", "jeg", "vi"},
+	"fin": {"ja", "on", "ei", "se", "että", "oli", "hän", "ovat", "mutta", "kun", "tai", "myös", "joka", "sen", "niin", "kuin", "ole", "mitä", "jos", "vain", "tämä", "siitä"},
+}
+
+// languageFromScript maps the scripts reported by tesseract's OSD to the
+// language used for a quick OCR pass.
+var languageFromScript = map[string]string{
+	"Latin":      "eng",
+	"Cyrillic":   "rus",
+	"Arabic":     "ara",
+	"Devanagari": "hin",
+	"Han":        "chi_sim",
+	"HanS":       "chi_sim",
+	"HanT":       "chi_tra",
+	"Japanese":   "jpn",
+	"Hiragana":   "jpn",
+	"Katakana":   "jpn",
+}
+
+type LanguageProcessor struct {
+	ocrProc *OCRProcessor
+}
+
+func NewLanguageProcessor() *LanguageProcessor {
+	return &LanguageProcessor{
+		ocrProc: NewOCRProcessor(),
+	}
+}
+
+// DetectFromText classifies the text chunk by chunk, so documents mixing
+// languages get a combination like "eng+deu" usable by tesseract.
+func (p *LanguageProcessor) DetectFromText(text string) (*model.LanguageDetection, error) {
+	weights := make(map[string]int)
+	total := 0
+	for _, chunk := range p.chunks(text) {
+		language, letters := p.classify(chunk)
+		if language == "" {
+			continue
+		}
+		weights[language] += letters
+		total += letters
+	}
+	if total < languageMinLetters {
+		return nil, errors.New("not enough text to detect the language")
+	}
+	languages := make([]string, 0, len(weights))
+	for language := range weights {
+		languages = append(languages, language)
+	}
+	sort.Slice(languages, func(i, j int) bool {
+		if weights[languages[i]] == weights[languages[j]] {
+			return languages[i] < languages[j]
+		}
+		return weights[languages[i]] > weights[languages[j]]
+	})
+	combination := []string{languages[0]}
+	for _, language := range languages[1:] {
+		if len(combination) == languageMaxCombination {
+			break
+		}
+		if float64(weights[language])/float64(total) >= languageSecondaryMinShare {
+			combination = append(combination, language)
+		}
+	}
+	return &model.LanguageDetection{
+		Language:   strings.Join(combination, "+"),
+		Confidence: float64(weights[languages[0]]) / float64(total),
+	}, nil
+}
+
+// DetectFromImage finds the script with tesseract's OSD, then runs a quick OCR
+// pass with a language of that script to classify the resulting text.
+func (p *LanguageProcessor) DetectFromImage(inputPath string) (*model.LanguageDetection, error) {
+	language, ok := "", false
+	// OSD fails on images with little text, we assume Latin in this case
+	if script, err := p.ocrProc.ScriptFromImage(inputPath); err == nil {
+		language, ok = languageFromScript[*script]
+	}
+	if !ok {
+		language = languageFromScript["Latin"]
+	}
+	text, err := p.ocrProc.TextFromImage(inputPath, language)
+	if err != nil {
+		return nil, err
+	}
+	res, err := p.DetectFromText(*text)
+	if err != nil {
+		return nil, err
+	}
+	// Simplified and Traditional Chinese cannot be told apart from text alone
+	if language == "chi_tra" {
+		res.Language = strings.ReplaceAll(res.Language, "chi_sim", "chi_tra")
+	}
+	return res, nil
+}
+
+// chunks splits the text into paragraphs, merging short ones together.
+func (p *LanguageProcessor) chunks(text string) []string {
+	var res []string
+	var current strings.Builder
+	letters := 0
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\f", "\n\n"), "\n\n") {
+		current.WriteString(paragraph)
+		current.WriteString("\n")
+		letters += p.countLetters(paragraph)
+		if letters >= languageChunkMinLetters {
+			res = append(res, current.String())
+			current.Reset()
+			letters = 0
+		}
+	}
+	if letters > 0 {
+		if len(res) > 0 && letters < languageChunkMinLetters/2 {
+			res[len(res)-1] += current.String()
+		} else {
+			res = append(res, current.String())
+		}
+	}
+	return res
+}
+
+// classify returns the language of a chunk together with its amount of letters.
+func (p *LanguageProcessor) classify(chunk string) (string, int) {
+	var latin, cyrillic, arabic, devanagari, han, kana, letters int
+	for _, r := range chunk {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Devanagari, r):
+			devanagari++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			kana++
+		}
+	}
+	if letters == 0 {
+		return "", 0
+	}
+	switch max(latin, cyrillic, arabic, devanagari, han+kana) {
+	case latin:
+		return p.classifyLatin(chunk), letters
+	case cyrillic:
+		return "rus", letters
+	case arabic:
+		return "ara", letters
+	case devanagari:
+		return "hin", letters
+	}
+	if kana > 0 {
+		return "jpn", letters
+	}
+	return "chi_sim", letters
+}
+
+// classifyLatin counts the stop words of each Latin-script language.
+func (p *LanguageProcessor) classifyLatin(chunk string) string {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(chunk), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		counts[word]++
+	}
+	best, bestScore := "", 0
+	for language, stopWords := range languageStopWords {
+		score := 0
+		for _, stopWord := range stopWords {
+			score += counts[stopWord]
+		}
+		if score > bestScore || (score == bestScore && score > 0 && language < best) {
+			best, bestScore = language, score
+		}
+	}
+	return best
+}
+
+func (p *LanguageProcessor) countLetters(text string) int {
+	res := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			res++
+		}
+	}
+	return res
+}
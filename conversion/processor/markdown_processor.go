@@ -0,0 +1,707 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// markdownMaxDepth limits the nesting of block quotes, lists and emphasis.
+const markdownMaxDepth = 16
+
+// markdownMaxSpan is how far the closing delimiter of emphasis, code spans and
+// links is looked for, which keeps unbalanced delimiters from being quadratic.
+const markdownMaxSpan = 4096
+
+// markdownHardBreak marks the line breaks to keep within a paragraph.
+const markdownHardBreak = "\x00"
+
+const markdownEscapable = "\\`*_{}[]()#+-.!|~<>\""
+
+var (
+	markdownReferencePattern = regexp.MustCompile(`^ {0,3}\[([^\]]+)\]:\s*<?(\S+?)>?(?:\s+["'(](.*)["')])?\s*$`)
+	markdownHeadingPattern   = regexp.MustCompile(`^(#{1,6})(?:\s+|$)`)
+	markdownOrderedPattern   = regexp.MustCompile(`^([0-9]{1,9})[.)](?:\s|$)`)
+	markdownHTMLPattern      = regexp.MustCompile(`^<(?:[a-zA-Z][a-zA-Z0-9-]*(?:[\s/>]|$)|/[a-zA-Z]|!--)`)
+	// markdownLanguages maps the info strings of fenced code blocks to extensions.
+	markdownLanguages = map[string]string{
+		"c": ".c", "cpp": ".cpp", "c++": ".cpp", "go": ".go", "golang": ".go", "java": ".java",
+		"js": ".js", "javascript": ".js", "jsx": ".jsx", "ts": ".ts", "typescript": ".ts",
+		"tsx": ".tsx", "py": ".py", "python": ".py", "rb": ".rb", "ruby": ".rb", "css": ".css",
+		"scss": ".scss", "sass": ".sass", "json": ".json", "yml": ".yaml", "yaml": ".yaml",
+		"toml": ".toml",
+	}
+)
+
+type MarkdownProcessor struct {
+	codeProc  *CodeProcessor
+	emailProc *EmailProcessor
+}
+
+func NewMarkdownProcessor() *MarkdownProcessor {
+	return &MarkdownProcessor{
+		codeProc:  NewCodeProcessor(),
+		emailProc: NewEmailProcessor(),
+	}
+}
+
+// HTML renders CommonMark with the tables, strikethroughs and task lists of GFM.
+// The HTML embedded in the document goes through the same allowlist as emails,
+// so that nothing runs scripts or loads remote content.
+func (p *MarkdownProcessor) HTML(source string) string {
+	source = strings.ReplaceAll(source, markdownHardBreak, "")
+	source = strings.ReplaceAll(strings.ReplaceAll(source, "\r\n", "\n"), "\r", "\n")
+	d := &markdownDocument{
+		proc: p,
+		refs: make(map[string]markdownReference),
+	}
+	var lines []string
+	for _, line := range strings.Split(source, "\n") {
+		line = d.expandTabs(line)
+		if m := markdownReferencePattern.FindStringSubmatch(line); m != nil {
+			d.refs[strings.ToLower(m[1])] = markdownReference{url: m[2], title: m[3]}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	var b strings.Builder
+	d.writeBlocks(&b, lines, 0)
+	return b.String()
+}
+
+type markdownReference struct {
+	url   string
+	title string
+}
+
+// markdownDocument holds the state of a rendering, that is the link references.
+type markdownDocument struct {
+	proc *MarkdownProcessor
+	refs map[string]markdownReference
+}
+
+func (d *markdownDocument) writeBlocks(b *strings.Builder, lines []string, depth int) {
+	if depth > markdownMaxDepth {
+		b.WriteString("<p>" + html.EscapeString(strings.Join(lines, "\n")) + "</p>\n")
+		return
+	}
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			i++
+		} else if d.indent(line) >= 4 {
+			i = d.writeIndentedCode(b, lines, i)
+		} else if d.fence(trimmed) != "" {
+			i = d.writeFencedCode(b, lines, i)
+		} else if m := markdownHeadingPattern.FindStringSubmatch(trimmed); m != nil {
+			text := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(trimmed[len(m[1]):]), "#"))
+			d.writeHeading(b, len(m[1]), text, depth)
+			i++
+		} else if d.isRule(trimmed) {
+			b.WriteString("<hr>\n")
+			i++
+		} else if strings.HasPrefix(trimmed, ">") {
+			var quoted []string
+			for i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">") {
+				quoted = append(quoted, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+				i++
+			}
+			b.WriteString("<blockquote>\n")
+			d.writeBlocks(b, quoted, depth+1)
+			b.WriteString("</blockquote>\n")
+		} else if _, _, _, ok := d.listMarker(line); ok {
+			i = d.writeList(b, lines, i, depth)
+		} else if i+1 < len(lines) && strings.Contains(trimmed, "|") && d.isTableDelimiter(lines[i+1]) {
+			i = d.writeTable(b, lines, i, depth)
+		} else if markdownHTMLPattern.MatchString(trimmed) {
+			var block []string
+			for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+				block = append(block, lines[i])
+				i++
+			}
+			b.WriteString(d.proc.emailProc.Sanitize(strings.Join(block, "\n"), nil) + "\n")
+		} else {
+			i = d.writeParagraph(b, lines, i, depth)
+		}
+	}
+}
+
+func (d *markdownDocument) writeParagraph(b *strings.Builder, lines []string, i int, depth int) int {
+	var paragraph []string
+	for i < len(lines) {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" || (len(paragraph) > 0 && d.interrupts(line)) {
+			break
+		}
+		paragraph = append(paragraph, line)
+		i++
+		if i < len(lines) {
+			/* Setext headings underline the paragraph */
+			underline := strings.TrimSpace(lines[i])
+			if underline != "" && strings.Trim(underline, "=") == "" {
+				d.writeHeading(b, 1, strings.TrimSpace(strings.Join(paragraph, " ")), depth)
+				return i + 1
+			} else if len(underline) >= 2 && strings.Trim(underline, "-") == "" {
+				d.writeHeading(b, 2, strings.TrimSpace(strings.Join(paragraph, " ")), depth)
+				return i + 1
+			}
+		}
+	}
+	var text strings.Builder
+	for j, line := range paragraph {
+		if j > 0 {
+			text.WriteString("\n")
+		}
+		if j < len(paragraph)-1 && (strings.HasSuffix(line, "  ") || strings.HasSuffix(line, "\\")) {
+			text.WriteString(strings.TrimSpace(strings.TrimSuffix(line, "\\")) + markdownHardBreak)
+		} else {
+			text.WriteString(strings.TrimSpace(line))
+		}
+	}
+	b.WriteString("<p>" + d.inline(text.String(), depth) + "</p>\n")
+	return i
+}
+
+func (d *markdownDocument) writeHeading(b *strings.Builder, level int, text string, depth int) {
+	tag := "h" + strconv.Itoa(level)
+	b.WriteString("<" + tag + ` id="` + html.EscapeString(d.slug(text)) + `">` + d.inline(text, depth) + "</" + tag + ">\n")
+}
+
+func (d *markdownDocument) writeIndentedCode(b *strings.Builder, lines []string, i int) int {
+	var code []string
+	for i < len(lines) && (d.indent(lines[i]) >= 4 || strings.TrimSpace(lines[i]) == "") {
+		code = append(code, d.dedent(lines[i], 4))
+		i++
+	}
+	for len(code) > 0 && strings.TrimSpace(code[len(code)-1]) == "" {
+		code = code[:len(code)-1]
+	}
+	b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+	return i
+}
+
+func (d *markdownDocument) writeFencedCode(b *strings.Builder, lines []string, i int) int {
+	indent := d.indent(lines[i])
+	trimmed := strings.TrimSpace(lines[i])
+	fence := d.fence(trimmed)
+	var language string
+	if fields := strings.Fields(trimmed[len(fence):]); len(fields) > 0 {
+		language = strings.ToLower(fields[0])
+	}
+	var code []string
+	for i++; i < len(lines); i++ {
+		if t := strings.TrimSpace(lines[i]); strings.HasPrefix(t, fence) && strings.Trim(t, fence[:1]) == "" {
+			i++
+			break
+		}
+		code = append(code, d.dedent(lines[i], indent))
+	}
+	b.WriteString("<pre><code>" + d.proc.codeProc.Highlight(strings.Join(code, "\n"), markdownLanguages[language]) + "</code></pre>\n")
+	return i
+}
+
+// writeList renders the items that follow with the same kind of marker, the
+// content of an item is what is indented past its marker.
+func (d *markdownDocument) writeList(b *strings.Builder, lines []string, i int, depth int) int {
+	_, ordered, start, _ := d.listMarker(lines[i])
+	if !ordered {
+		b.WriteString("<ul>\n")
+	} else if start != 1 {
+		b.WriteString(`<ol start="` + strconv.Itoa(start) + `">` + "\n")
+	} else {
+		b.WriteString("<ol>\n")
+	}
+	for i < len(lines) {
+		if strings.TrimSpace(lines[i]) == "" {
+			j := d.skipBlank(lines, i)
+			if j < len(lines) {
+				if _, o, _, ok := d.listMarker(lines[j]); ok && o == ordered {
+					i = j
+					continue
+				}
+			}
+			break
+		}
+		width, o, _, ok := d.listMarker(lines[i])
+		if !ok || o != ordered {
+			break
+		}
+		item := []string{""}
+		if width < len(lines[i]) {
+			item[0] = lines[i][width:]
+		}
+		for i++; i < len(lines); {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				/* Blank lines are part of the item when its content goes on after them */
+				j := d.skipBlank(lines, i)
+				if j == len(lines) || d.indent(lines[j]) < width {
+					break
+				}
+				for ; i < j; i++ {
+					item = append(item, "")
+				}
+			} else if d.indent(line) >= width {
+				item = append(item, d.dedent(line, width))
+				i++
+			} else if _, _, _, isItem := d.listMarker(line); isItem || d.interrupts(line) {
+				break
+			} else {
+				/* Lazy continuation of the paragraph */
+				item = append(item, line)
+				i++
+			}
+		}
+		b.WriteString("<li>")
+		d.writeTask(b, item)
+		d.writeBlocks(b, item, depth+1)
+		b.WriteString("</li>\n")
+	}
+	if ordered {
+		b.WriteString("</ol>\n")
+	} else {
+		b.WriteString("</ul>\n")
+	}
+	return i
+}
+
+// writeTask renders the check box of task list items, and removes it from the item.
+func (d *markdownDocument) writeTask(b *strings.Builder, item []string) {
+	if strings.HasPrefix(item[0], "[ ] ") {
+		b.WriteString(`<input type="checkbox" disabled> `)
+		item[0] = item[0][4:]
+	} else if strings.HasPrefix(item[0], "[x] ") || strings.HasPrefix(item[0], "[X] ") {
+		b.WriteString(`<input type="checkbox" checked disabled> `)
+		item[0] = item[0][4:]
+	}
+}
+
+func (d *markdownDocument) writeTable(b *strings.Builder, lines []string, i int, depth int) int {
+	header := d.tableCells(lines[i])
+	var aligns []string
+	for _, cell := range d.tableCells(lines[i+1]) {
+		cell = strings.TrimSpace(cell)
+		if strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":") {
+			aligns = append(aligns, "center")
+		} else if strings.HasSuffix(cell, ":") {
+			aligns = append(aligns, "right")
+		} else if strings.HasPrefix(cell, ":") {
+			aligns = append(aligns, "left")
+		} else {
+			aligns = append(aligns, "")
+		}
+	}
+	writeRow := func(cells []string, tag string) {
+		b.WriteString("<tr>")
+		for j := range header {
+			var cell string
+			if j < len(cells) {
+				cell = cells[j]
+			}
+			if j < len(aligns) && aligns[j] != "" {
+				b.WriteString("<" + tag + ` style="text-align: ` + aligns[j] + `">`)
+			} else {
+				b.WriteString("<" + tag + ">")
+			}
+			b.WriteString(d.inline(strings.TrimSpace(cell), depth) + "</" + tag + ">")
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("<table>\n<thead>\n")
+	writeRow(header, "th")
+	b.WriteString("</thead>\n<tbody>\n")
+	for i += 2; i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|"); i++ {
+		writeRow(d.tableCells(lines[i]), "td")
+	}
+	b.WriteString("</tbody>\n</table>\n")
+	return i
+}
+
+func (d *markdownDocument) tableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if !strings.HasSuffix(line, "\\|") {
+		line = strings.TrimSuffix(line, "|")
+	}
+	var res []string
+	start := 0
+	for j := 0; j < len(line); j++ {
+		if line[j] == '\\' {
+			j++
+		} else if line[j] == '|' {
+			res = append(res, line[start:j])
+			start = j + 1
+		}
+	}
+	return append(res, line[start:])
+}
+
+func (d *markdownDocument) isTableDelimiter(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.Contains(trimmed, "-") && strings.Contains(trimmed, "|") && strings.Trim(trimmed, "|:- ") == ""
+}
+
+// inline renders code spans, emphasis, links and images, the rest is escaped.
+func (d *markdownDocument) inline(text string, depth int) string {
+	if depth > markdownMaxDepth {
+		return html.EscapeString(strings.ReplaceAll(text, markdownHardBreak, " "))
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		c := text[i]
+		if strings.HasPrefix(text[i:], markdownHardBreak) {
+			b.WriteString("<br>")
+			i += len(markdownHardBreak)
+		} else if c == '\\' && i+1 < len(text) && strings.IndexByte(markdownEscapable, text[i+1]) >= 0 {
+			b.WriteString(html.EscapeString(text[i+1 : i+2]))
+			i += 2
+		} else if c == '`' {
+			i = d.writeCodeSpan(&b, text, i)
+		} else if c == '!' && i+1 < len(text) && text[i+1] == '[' {
+			if label, ref, end, ok := d.link(text, i+1); ok {
+				d.writeImage(&b, label, ref)
+				i = end
+			} else {
+				b.WriteString("!")
+				i++
+			}
+		} else if c == '[' {
+			if label, ref, end, ok := d.link(text, i); ok {
+				d.writeLink(&b, label, ref, depth)
+				i = end
+			} else {
+				b.WriteString("[")
+				i++
+			}
+		} else if c == '<' {
+			i = d.writeAngleBracket(&b, text, i)
+		} else if c == '*' || c == '_' || c == '~' {
+			i = d.writeEmphasis(&b, text, i, depth)
+		} else {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			b.WriteString(html.EscapeString(text[i : i+size]))
+			i += size
+		}
+	}
+	return b.String()
+}
+
+func (d *markdownDocument) writeCodeSpan(b *strings.Builder, text string, i int) int {
+	n := 0
+	for i+n < len(text) && text[i+n] == '`' {
+		n++
+	}
+	delimiter := text[i : i+n]
+	window := text[i+n : min(len(text), i+n+markdownMaxSpan)]
+	end := strings.Index(window, delimiter)
+	if end < 0 {
+		b.WriteString(delimiter)
+		return i + n
+	}
+	code := strings.ReplaceAll(window[:end], markdownHardBreak, " ")
+	code = strings.ReplaceAll(code, "\n", " ")
+	if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' {
+		code = code[1 : len(code)-1]
+	}
+	b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+	return i + n + end + n
+}
+
+// writeEmphasis matches a run of up to three delimiters with the same run further
+// in the text, underscores within words are left as they are.
+func (d *markdownDocument) writeEmphasis(b *strings.Builder, text string, i int, depth int) int {
+	c := text[i]
+	n := 0
+	for i+n < len(text) && text[i+n] == c && n < 3 {
+		n++
+	}
+	delimiter := text[i : i+n]
+	if (c == '~' && n != 2) ||
+		(c == '_' && i > 0 && d.isAlphanumeric(text[i-1])) ||
+		i+n >= len(text) || text[i+n] == ' ' || text[i+n] == '\n' {
+		b.WriteString(html.EscapeString(delimiter))
+		return i + n
+	}
+	window := text[i+n : min(len(text), i+n+markdownMaxSpan)]
+	for offset := 0; offset < len(window); {
+		end := strings.Index(window[offset:], delimiter)
+		if end < 0 {
+			break
+		}
+		end += offset
+		after := i + n + end + n
+		if end > 0 && window[end-1] != ' ' && window[end-1] != '\n' &&
+			(after >= len(text) || text[after] != c) &&
+			(c != '_' || after >= len(text) || !d.isAlphanumeric(text[after])) {
+			inner := d.inline(window[:end], depth+1)
+			switch {
+			case c == '~':
+				b.WriteString("<del>" + inner + "</del>")
+			case n == 1:
+				b.WriteString("<em>" + inner + "</em>")
+			case n == 2:
+				b.WriteString("<strong>" + inner + "</strong>")
+			default:
+				b.WriteString("<strong><em>" + inner + "</em></strong>")
+			}
+			return after
+		}
+		offset = end + 1
+	}
+	b.WriteString(html.EscapeString(delimiter))
+	return i + n
+}
+
+// writeAngleBracket renders autolinks and inline HTML, or escapes the bracket.
+func (d *markdownDocument) writeAngleBracket(b *strings.Builder, text string, i int) int {
+	end := strings.IndexByte(text[i:min(len(text), i+markdownMaxSpan)], '>')
+	if end > 1 {
+		value := text[i+1 : i+end]
+		if !strings.ContainsAny(value, " \n<") && (d.proc.emailProc.isSafeLink(value) || d.isEmail(value)) {
+			href := value
+			if d.isEmail(value) {
+				href = "mailto:" + value
+			}
+			b.WriteString(`<a href="` + html.EscapeString(href) + `">` + html.EscapeString(value) + "</a>")
+			return i + end + 1
+		}
+		if markdownHTMLPattern.MatchString(text[i:]) {
+			b.WriteString(d.proc.emailProc.Sanitize(text[i:i+end+1], nil))
+			return i + end + 1
+		}
+	}
+	b.WriteString("&lt;")
+	return i + 1
+}
+
+func (d *markdownDocument) writeLink(b *strings.Builder, label string, ref markdownReference, depth int) {
+	content := d.inline(label, depth+1)
+	if !d.isSafeLink(ref.url) {
+		b.WriteString(content)
+		return
+	}
+	b.WriteString(`<a href="` + html.EscapeString(ref.url) + `"`)
+	if ref.title != "" {
+		b.WriteString(` title="` + html.EscapeString(ref.title) + `"`)
+	}
+	b.WriteString(">" + content + "</a>")
+}
+
+// writeImage keeps the images embedded as data URIs, like for emails remote
+// images are not loaded, so their alternative text is shown instead.
+func (d *markdownDocument) writeImage(b *strings.Builder, alt string, ref markdownReference) {
+	source := d.proc.emailProc.imageSource(ref.url, nil)
+	if source == nil {
+		b.WriteString(html.EscapeString(alt))
+		return
+	}
+	b.WriteString(`<img src="` + html.EscapeString(*source) + `" alt="` + html.EscapeString(alt) + `">`)
+}
+
+// link reads an inline link like [label](url "title"), or a reference link like
+// [label][ref] or [label], and returns the index after it.
+func (d *markdownDocument) link(text string, i int) (string, markdownReference, int, bool) {
+	nesting := 0
+	end := -1
+	for j := i; j < len(text) && j < i+markdownMaxSpan; j++ {
+		if text[j] == '\\' {
+			j++
+		} else if text[j] == '[' {
+			nesting++
+		} else if text[j] == ']' {
+			nesting--
+			if nesting == 0 {
+				end = j
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return "", markdownReference{}, 0, false
+	}
+	label := text[i+1 : end]
+	rest := text[end+1:]
+	if strings.HasPrefix(rest, "(") {
+		nesting := 0
+		for j := 0; j < len(rest) && j < markdownMaxSpan; j++ {
+			if rest[j] == '(' {
+				nesting++
+			} else if rest[j] == ')' {
+				nesting--
+				if nesting == 0 {
+					ref := d.destination(rest[1:j])
+					return label, ref, end + 1 + j + 1, true
+				}
+			}
+		}
+		return "", markdownReference{}, 0, false
+	}
+	if strings.HasPrefix(rest, "[") {
+		if closing := strings.IndexByte(rest, ']'); closing > 0 {
+			name := rest[1:closing]
+			if name == "" {
+				name = label
+			}
+			if ref, ok := d.refs[strings.ToLower(name)]; ok {
+				return label, ref, end + 1 + closing + 1, true
+			}
+		}
+	}
+	if ref, ok := d.refs[strings.ToLower(label)]; ok {
+		return label, ref, end + 1, true
+	}
+	return "", markdownReference{}, 0, false
+}
+
+func (d *markdownDocument) destination(value string) markdownReference {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "<") {
+		if end := strings.IndexByte(value, '>'); end > 0 {
+			return markdownReference{url: value[1:end], title: d.title(value[end+1:])}
+		}
+	}
+	url, title, _ := strings.Cut(value, " ")
+	return markdownReference{url: url, title: d.title(title)}
+}
+
+func (d *markdownDocument) title(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 {
+		return value[1 : len(value)-1]
+	}
+	return ""
+}
+
+// isSafeLink accepts the links of emails and anchors within the document,
+// relative links are dropped as there is nothing to resolve them against.
+func (d *markdownDocument) isSafeLink(value string) bool {
+	if strings.HasPrefix(value, "#") {
+		return true
+	}
+	u, err := url.Parse(value)
+	return err == nil && u.Scheme != "" && d.proc.emailProc.isSafeLink(value)
+}
+
+func (d *markdownDocument) isEmail(value string) bool {
+	local, domain, ok := strings.Cut(value, "@")
+	return ok && local != "" && strings.Contains(domain, ".") && !strings.ContainsAny(value, ":/\"'")
+}
+
+// interrupts tells if the line starts a block that ends a paragraph.
+func (d *markdownDocument) interrupts(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if d.fence(trimmed) != "" || markdownHeadingPattern.MatchString(trimmed) ||
+		d.isRule(trimmed) || strings.HasPrefix(trimmed, ">") {
+		return true
+	}
+	_, ordered, start, ok := d.listMarker(line)
+	return ok && (!ordered || start == 1)
+}
+
+// listMarker returns the column where the content of the item starts.
+func (d *markdownDocument) listMarker(line string) (int, bool, int, bool) {
+	indent := d.indent(line)
+	if indent >= 4 {
+		return 0, false, 0, false
+	}
+	rest := line[indent:]
+	if len(rest) > 0 && strings.IndexByte("-*+", rest[0]) >= 0 && (len(rest) == 1 || rest[1] == ' ') {
+		if d.isRule(strings.TrimSpace(rest)) {
+			return 0, false, 0, false
+		}
+		return indent + 2, false, 0, true
+	}
+	if m := markdownOrderedPattern.FindStringSubmatch(rest); m != nil {
+		start, _ := strconv.Atoi(m[1])
+		return indent + len(m[1]) + 2, true, start, true
+	}
+	return 0, false, 0, false
+}
+
+func (d *markdownDocument) fence(trimmed string) string {
+	for _, c := range []string{"`", "~"} {
+		n := 0
+		for n < len(trimmed) && trimmed[n] == c[0] {
+			n++
+		}
+		if n >= 3 && (c == "~" || !strings.Contains(trimmed[n:], "`")) {
+			return trimmed[:n]
+		}
+	}
+	return ""
+}
+
+func (d *markdownDocument) isRule(trimmed string) bool {
+	if len(trimmed) < 3 {
+		return false
+	}
+	c := trimmed[0]
+	if c != '-' && c != '*' && c != '_' {
+		return false
+	}
+	count := 0
+	for j := 0; j < len(trimmed); j++ {
+		if trimmed[j] == c {
+			count++
+		} else if trimmed[j] != ' ' {
+			return false
+		}
+	}
+	return count >= 3
+}
+
+func (d *markdownDocument) skipBlank(lines []string, i int) int {
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	return i
+}
+
+func (d *markdownDocument) indent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func (d *markdownDocument) dedent(line string, n int) string {
+	return line[min(n, d.indent(line)):]
+}
+
+func (d *markdownDocument) expandTabs(line string) string {
+	indent := 0
+	for indent < len(line) && (line[indent] == ' ' || line[indent] == '\t') {
+		indent++
+	}
+	return strings.ReplaceAll(line[:indent], "\t", "    ") + line[indent:]
+}
+
+// slug is the ID of a heading, made of its lowercase letters and digits, with
+// dashes in place of spaces.
+func (d *markdownDocument) slug(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			b.WriteRune(r)
+		} else if r == ' ' {
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+func (d *markdownDocument) isAlphanumeric(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"testing"
+)
+
+func TestMarkdownEscaping(t *testing.T) {
+	p := NewMarkdownProcessor()
+	for _, tc := range []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"text", `a < b & "c"`, "<p>a &lt; b &amp; &#34;c&#34;</p>\n"},
+		{"backslash escapes", `\*not emphasis\*`, "<p>*not emphasis*</p>\n"},
+		{"code span", "`<b>`", "<p><code>&lt;b&gt;</code></p>\n"},
+		{"fenced code", "```\n<div>\n```", "<pre><code>&lt;div&gt;</code></pre>\n"},
+		{"script", "<script>alert(1)</script>", "\n"},
+		{"event handler", `<a href="javascript:alert(1)" onclick="x()">y</a>`, "<a>y</a>\n"},
+		{"link title", `[x](https://example.com "t<")`, "<p><a href=\"https://example.com\" title=\"t&lt;\">x</a></p>\n"},
+		{
+			"reference with quotes",
+			"[r]\n\n[r]: https://example.com/\"onmouseover=\"x",
+			"<p><a href=\"https://example.com/&#34;onmouseover=&#34;x\">r</a></p>\n",
+		},
+		{"unsafe link", "[x](javascript:alert(1))", "<p>x</p>\n"},
+		{"autolink", "<https://example.com>", "<p><a href=\"https://example.com\">https://example.com</a></p>\n"},
+		{"unsafe autolink", "<javascript:alert(1)>", "<p>&lt;javascript:alert(1)&gt;</p>\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := p.HTML(tc.source); got != tc.want {
+				t.Errorf("HTML(%q) = %q, want %q", tc.source, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownImages(t *testing.T) {
+	p := NewMarkdownProcessor()
+	for _, tc := range []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"remote", "![alt <b>](https://example.com/a.png)", "<p>alt &lt;b&gt;</p>\n"},
+		{
+			"data",
+			"![dot](data:image/png;base64,iVBORw0KGgo=)",
+			"<p><img src=\"data:image/png;base64,iVBORw0KGgo=\" alt=\"dot\"></p>\n",
+		},
+		{"svg", "![svg](data:image/svg+xml;base64,PHN2Zz4=)", "<p>svg</p>\n"},
+		{"embedded remote", `<img src="https://example.com/a.png" alt="remote">`, "remote\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := p.HTML(tc.source); got != tc.want {
+				t.Errorf("HTML(%q) = %q, want %q", tc.source, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownIsSafeLink(t *testing.T) {
+	d := &markdownDocument{proc: NewMarkdownProcessor()}
+	for _, tc := range []struct {
+		value string
+		want  bool
+	}{
+		{"#section", true},
+		{"https://example.com", true},
+		{"http://example.com", true},
+		{"mailto:someone@example.com", true},
+		{"HTTPS://EXAMPLE.COM", true},
+		{"javascript:alert(1)", false},
+		{"JavaScript:alert(1)", false},
+		{"data:text/html,<script>alert(1)</script>", false},
+		{"vbscript:msgbox(1)", false},
+		{"ftp://example.com", false},
+		{"//example.com", false},
+		{"relative/path", false},
+		{"", false},
+	} {
+		if got := d.isSafeLink(tc.value); got != tc.want {
+			t.Errorf("isSafeLink(%q) = %v, want %v", tc.value, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kouprlabs/voltaserve/conversion/helper"
+	"github.com/kouprlabs/voltaserve/conversion/infra"
+	"github.com/kouprlabs/voltaserve/conversion/model"
+)
+
+// metadataTags are read by exiftool, the first tag found wins when several
+// tags carry the same information in EXIF, IPTC and XMP.
+var (
+	metadataTagsCaptureTime = []string{"DateTimeOriginal", "CreateDate", "MediaCreateDate"}
+	metadataTagsLens        = []string{"LensModel", "Lens"}
+	metadataTagsKeywords    = []string{"Keywords", "Subject"}
+	metadataTagsCaption     = []string{"Caption-Abstract", "Description", "ImageDescription"}
+	metadataTagsCreator     = []string{"Creator", "By-line", "Artist"}
+	metadataTagsCopyright   = []string{"Rights", "CopyrightNotice", "Copyright"}
+)
+
+type MetadataProcessor struct{}
+
+func NewMetadataProcessor() *MetadataProcessor {
+	return &MetadataProcessor{}
+}
+
+// FromImage reads the EXIF, IPTC and XMP tags of the image.
+func (p *MetadataProcessor) FromImage(inputPath string) (*model.MediaMetadata, error) {
+	tags, err := p.readTags(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	return p.mapTags(tags), nil
+}
+
+// FromAudioVideo reads the tags like FromImage, and complements them with
+// the duration, bitrate, codecs, sample rate and channels reported by ffprobe.
+func (p *MetadataProcessor) FromAudioVideo(inputPath string) (*model.MediaMetadata, error) {
+	tags, err := p.readTags(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	res := p.mapTags(tags)
+	output, err := infra.NewCommand().ReadOutput(
+		"ffprobe", "-v", "error",
+		"-show_entries", "format=duration,bit_rate:stream=codec_type,codec_name,sample_rate,channels",
+		"-of", "json", inputPath,
+	)
+	if err != nil {
+		return nil, err
+	}
+	probe := struct {
+		Streams []struct {
+			CodecType  string `json:"codec_type"`
+			CodecName  string `json:"codec_name"`
+			SampleRate string `json:"sample_rate"`
+			Channels   int    `json:"channels"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+			BitRate  string `json:"bit_rate"`
+		} `json:"format"`
+	}{}
+	if err := json.Unmarshal([]byte(*output), &probe); err != nil {
+		return nil, err
+	}
+	for _, stream := range probe.Streams {
+		if stream.CodecType == "video" && res.VideoCodec == nil {
+			res.VideoCodec = helper.ToPtr(stream.CodecName)
+		} else if stream.CodecType == "audio" && res.AudioCodec == nil {
+			res.AudioCodec = helper.ToPtr(stream.CodecName)
+			if sampleRate, err := strconv.Atoi(stream.SampleRate); err == nil {
+				res.SampleRate = helper.ToPtr(sampleRate)
+			}
+			if stream.Channels > 0 {
+				res.Channels = helper.ToPtr(stream.Channels)
+			}
+		}
+	}
+	if duration, err := strconv.ParseFloat(probe.Format.Duration, 64); err == nil {
+		res.Duration = helper.ToPtr(duration)
+	}
+	if bitrate, err := strconv.ParseInt(probe.Format.BitRate, 10, 64); err == nil {
+		res.Bitrate = helper.ToPtr(bitrate)
+	}
+	return res, nil
+}
+
+func (p *MetadataProcessor) readTags(inputPath string) (map[string]any, error) {
+	args := []string{"-j", "-n"}
+	for _, tags := range [][]string{
+		metadataTagsCaptureTime,
+		metadataTagsLens,
+		metadataTagsKeywords,
+		metadataTagsCaption,
+		metadataTagsCreator,
+		metadataTagsCopyright,
+		{"OffsetTimeOriginal", "Make", "Model", "GPSLatitude", "GPSLongitude", "GPSAltitude", "Duration", "Rating"},
+	} {
+		for _, tag := range tags {
+			args = append(args, "-"+tag)
+		}
+	}
+	output, err := infra.NewCommand().ReadOutput("exiftool", append(args, inputPath)...)
+	if err != nil {
+		return nil, err
+	}
+	var res []map[string]any
+	if err := json.Unmarshal([]byte(*output), &res); err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, errors.New("exiftool returned no tags")
+	}
+	return res[0], nil
+}
+
+func (p *MetadataProcessor) mapTags(tags map[string]any) *model.MediaMetadata {
+	res := &model.MediaMetadata{
+		CameraMake:  p.stringTag(tags, "Make"),
+		CameraModel: p.stringTag(tags, "Model"),
+		Lens:        p.firstStringTag(tags, metadataTagsLens),
+		Caption:     p.firstStringTag(tags, metadataTagsCaption),
+		Creator:     p.firstStringTag(tags, metadataTagsCreator),
+		Copyright:   p.firstStringTag(tags, metadataTagsCopyright),
+		Duration:    p.numberTag(tags, "Duration"),
+		Keywords:    p.keywords(tags),
+	}
+	for _, tag := range metadataTagsCaptureTime {
+		if value := p.stringTag(tags, tag); value != nil {
+			if captureTime := p.captureTime(*value, p.stringTag(tags, "OffsetTimeOriginal")); captureTime != nil {
+				res.CaptureTime = captureTime
+				break
+			}
+		}
+	}
+	latitude, longitude := p.numberTag(tags, "GPSLatitude"), p.numberTag(tags, "GPSLongitude")
+	// Zero coordinates are what some devices write when they have no fix
+	if latitude != nil && longitude != nil && (*latitude != 0 || *longitude != 0) {
+		res.Location = &model.GeoLocation{
+			Latitude:  *latitude,
+			Longitude: *longitude,
+			Altitude:  p.numberTag(tags, "GPSAltitude"),
+		}
+	}
+	if rating := p.numberTag(tags, "Rating"); rating != nil && *rating >= 0 && *rating <= 5 {
+		res.Rating = helper.ToPtr(int(math.Round(*rating)))
+	}
+	return res
+}
+
+// captureTime turns an EXIF date into RFC 3339. EXIF dates have no time zone,
+// so unless the camera writes an offset we read them as UTC.
+func (p *MetadataProcessor) captureTime(value string, offset *string) *string {
+	layout := "2006:01:02 15:04:05"
+	if len(value) > len(layout) {
+		value = value[:len(layout)]
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil || t.Year() <= 1 {
+		return nil
+	}
+	if offset != nil {
+		if o, err := time.Parse("-07:00", *offset); err == nil {
+			_, seconds := o.Zone()
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.FixedZone("", seconds))
+		}
+	}
+	return helper.ToPtr(t.Format(time.RFC3339))
+}
+
+// keywords merges IPTC keywords and XMP subjects, which are often duplicates.
+func (p *MetadataProcessor) keywords(tags map[string]any) []string {
+	var res []string
+	for _, tag := range metadataTagsKeywords {
+		var values []any
+		switch v := tags[tag].(type) {
+		case []any:
+			values = v
+		case nil:
+			continue
+		default:
+			values = []any{v}
+		}
+		for _, value := range values {
+			keyword := strings.TrimSpace(fmt.Sprint(value))
+			if keyword != "" && !slices.Contains(res, keyword) {
+				res = append(res, keyword)
+			}
+		}
+	}
+	return res
+}
+
+func (p *MetadataProcessor) firstStringTag(tags map[string]any, names []string) *string {
+	for _, name := range names {
+		if value := p.stringTag(tags, name); value != nil {
+			return value
+		}
+	}
+	return nil
+}
+
+// stringTag formats numbers as well, as exiftool outputs numeric
+// looking values like camera models as JSON numbers.
+func (p *MetadataProcessor) stringTag(tags map[string]any, name string) *string {
+	value, ok := tags[name]
+	if !ok || value == nil {
+		return nil
+	}
+	if _, ok := value.([]any); ok {
+		return nil
+	}
+	res := strings.TrimSpace(fmt.Sprint(value))
+	if res == "" {
+		return nil
+	}
+	return &res
+}
+
+func (p *MetadataProcessor) numberTag(tags map[string]any, name string) *float64 {
+	switch v := tags[name].(type) {
+	case float64:
+		return &v
+	case string:
+		if res, err := strconv.ParseFloat(v, 64); err == nil {
+			return &res
+		}
+	}
+	return nil
+}
@@ -11,10 +11,17 @@
 package processor
 
 import (
+	"bufio"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
 
 	"github.com/kouprlabs/voltaserve/conversion/config"
 	"github.com/kouprlabs/voltaserve/conversion/infra"
+	"github.com/kouprlabs/voltaserve/conversion/model"
 )
 
 type OCRProcessor struct {
@@ -46,3 +53,148 @@ func (p *OCRProcessor) SearchablePDFFromFile(inputPath string, language string,
 	}
 	return nil
 }
+
+// ScriptFromImage runs tesseract's orientation and script detection,
+// and returns the detected script name (e.g. "Latin", "Cyrillic").
+func (p *OCRProcessor) ScriptFromImage(inputPath string) (*string, error) {
+	output, err := infra.NewCommand().ReadOutput("tesseract", inputPath, "stdout", "--psm", "0")
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(strings.NewReader(*output))
+	for scanner.Scan() {
+		name, value, found := strings.Cut(scanner.Text(), ":")
+		if found && strings.TrimSpace(name) == "Script" {
+			script := strings.TrimSpace(value)
+			return &script, nil
+		}
+	}
+	return nil, errors.New("script not found in OSD output")
+}
+
+func (p *OCRProcessor) TextFromImage(inputPath string, language string) (*string, error) {
+	output, err := infra.NewCommand().ReadOutput("tesseract", inputPath, "stdout", "-l", language)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSpace(*output)
+	return &text, nil
+}
+
+// LayoutFromImage reads the words of the image together with their bounding boxes
+// from tesseract's hOCR output, converted from pixels to points with the DPI.
+func (p *OCRProcessor) LayoutFromImage(inputPath string, language string, dpi int) (*model.Layout, error) {
+	output, err := infra.NewCommand().ReadOutput("tesseract", inputPath, "stdout", "-l", language, "hocr")
+	if err != nil {
+		return nil, err
+	}
+	return p.parseHOCR(strings.NewReader(*output), dpi)
+}
+
+func (p *OCRProcessor) parseHOCR(r io.Reader, dpi int) (*model.Layout, error) {
+	if dpi <= 0 {
+		return nil, fmt.Errorf("invalid DPI %d", dpi)
+	}
+	scale := 72 / float64(dpi)
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+	res := &model.Layout{Pages: make([]model.LayoutPage, 0)}
+	var page *model.LayoutPage
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		class := p.attr(start, "class")
+		if class == "ocr_page" {
+			if page != nil {
+				res.Pages = append(res.Pages, *page)
+			}
+			bbox := p.bbox(start)
+			page = &model.LayoutPage{
+				Number: len(res.Pages) + 1,
+				Width:  bbox[2] * scale,
+				Height: bbox[3] * scale,
+				Words:  make([]model.LayoutWord, 0),
+			}
+		} else if class == "ocrx_word" && page != nil {
+			text, err := p.innerText(decoder)
+			if err != nil {
+				return nil, err
+			}
+			if text == "" {
+				continue
+			}
+			bbox := p.bbox(start)
+			page.Words = append(page.Words, model.LayoutWord{
+				Text: text,
+				XMin: bbox[0] * scale,
+				YMin: bbox[1] * scale,
+				XMax: bbox[2] * scale,
+				YMax: bbox[3] * scale,
+			})
+		}
+	}
+	if page != nil {
+		res.Pages = append(res.Pages, *page)
+	}
+	return res, nil
+}
+
+// innerText reads the text up to the end of the current element, words can be
+// wrapped in elements like <strong> or <em>.
+func (p *OCRProcessor) innerText(decoder *xml.Decoder) (string, error) {
+	var sb strings.Builder
+	depth := 1
+	for depth > 0 {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			sb.Write(t)
+		}
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+func (p *OCRProcessor) attr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// bbox parses the "bbox x0 y0 x1 y1" property of the title of an hOCR element.
+func (p *OCRProcessor) bbox(element xml.StartElement) [4]float64 {
+	var res [4]float64
+	for _, property := range strings.Split(p.attr(element, "title"), ";") {
+		fields := strings.Fields(property)
+		if len(fields) != 5 || fields[0] != "bbox" {
+			continue
+		}
+		for i := range res {
+			value, err := strconv.ParseFloat(fields[i+1], 64)
+			if err != nil {
+				return [4]float64{}
+			}
+			res[i] = value
+		}
+	}
+	return res
+}
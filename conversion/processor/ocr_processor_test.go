@@ -0,0 +1,47 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kouprlabs/voltaserve/conversion/model"
+)
+
+func TestOCRParseHOCR(t *testing.T) {
+	hocr := `<!DOCTYPE html><html><body>` +
+		`<div class="ocr_page" title="image &quot;input.png&quot;; bbox 0 0 300 150; ppageno 0">` +
+		`<span class="ocrx_word" title="bbox 30 15 90 45; x_wconf 95"><strong>Hello</strong></span>` +
+		`<span class="ocrx_word" title="bbox 100 15 160 45"> </span><br>` +
+		`</div>` +
+		`<div class="ocr_page" title="bbox 0 0 600 300">` +
+		`<span class='ocrx_word' title='bbox 0 0 60 30'>A&amp;B</span>` +
+		`</div></body></html>`
+	layout, err := NewOCRProcessor().parseHOCR(strings.NewReader(hocr), 144)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &model.Layout{Pages: []model.LayoutPage{
+		{Number: 1, Width: 150, Height: 75, Words: []model.LayoutWord{{Text: "Hello", XMin: 15, YMin: 7.5, XMax: 45, YMax: 22.5}}},
+		{Number: 2, Width: 300, Height: 150, Words: []model.LayoutWord{{Text: "A&B", XMin: 0, YMin: 0, XMax: 30, YMax: 15}}},
+	}}
+	if !reflect.DeepEqual(layout, want) {
+		t.Errorf("parseHOCR() = %+v, want %+v", layout, want)
+	}
+}
+
+func TestOCRParseHOCRInvalidDPI(t *testing.T) {
+	if _, err := NewOCRProcessor().parseHOCR(strings.NewReader(""), 0); err == nil {
+		t.Error("parseHOCR() error = nil, want an error")
+	}
+}
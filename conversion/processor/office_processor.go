@@ -11,9 +11,16 @@
 package processor
 
 import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/kouprlabs/voltaserve/conversion/config"
@@ -33,6 +40,20 @@ func NewOfficeProcessor() *OfficeProcessor {
 	}
 }
 
+// XLSX converts spreadsheets that are not Office Open XML, like .xls, .ods and
+// .numbers, so that they are read like any other .xlsx file.
+func (p *OfficeProcessor) XLSX(inputPath string, outputDir string) (*string, error) {
+	if err := infra.NewCommand().Exec("soffice", "--headless", "--convert-to", "xlsx", "--outdir", outputDir, inputPath); err != nil {
+		return nil, err
+	}
+	base := filepath.Base(inputPath)
+	outputPath := filepath.FromSlash(outputDir + "/" + strings.TrimSuffix(base, path.Ext(base)) + ".xlsx")
+	if _, err := os.Stat(outputPath); err != nil {
+		return nil, err
+	}
+	return &outputPath, nil
+}
+
 func (p *OfficeProcessor) PDF(inputPath string, outputDir string) (*string, error) {
 	if err := infra.NewCommand().Exec("soffice", "--headless", "--convert-to", "pdf", "--outdir", outputDir, inputPath); err != nil {
 		return nil, err
@@ -43,3 +64,127 @@ func (p *OfficeProcessor) PDF(inputPath string, outputDir string) (*string, erro
 	base := filepath.Base(inputPath)
 	return helper.ToPtr(filepath.FromSlash(outputDir + "/" + strings.TrimSuffix(base, path.Ext(base)) + ".pdf")), nil
 }
+
+// odfTextNamespace is the namespace of the paragraphs, spaces and tabs of the
+// OpenDocument formats.
+const odfTextNamespace = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+
+var officeSlidePattern = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+
+// Text extracts the text of Office Open XML and OpenDocument files without
+// LibreOffice, it is the fallback when the conversion to PDF fails, so the
+// layout, the images and the formatting are lost.
+func (p *OfficeProcessor) Text(inputPath string) (string, error) {
+	r, err := zip.OpenReader(inputPath)
+	if err != nil {
+		return "", err
+	}
+	defer func(r *zip.ReadCloser) {
+		if err := r.Close(); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(r)
+	var parts []*zip.File
+	slides := make(map[*zip.File]int)
+	for _, f := range r.File {
+		switch f.Name {
+		case "content.xml", "word/document.xml", "xl/sharedStrings.xml":
+			parts = append(parts, f)
+		default:
+			if m := officeSlidePattern.FindStringSubmatch(f.Name); m != nil {
+				slides[f], _ = strconv.Atoi(m[1])
+				parts = append(parts, f)
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return "", errors.New("no text found in " + filepath.Base(inputPath))
+	}
+	/* Slides are named after their number, not in the order of the zip */
+	slices.SortStableFunc(parts, func(a, b *zip.File) int {
+		return slides[a] - slides[b]
+	})
+	var texts []string
+	for _, f := range parts {
+		text, err := p.textFromXML(f)
+		if err != nil {
+			return "", err
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// textFromXML keeps the text of the runs of the paragraphs, which are <w:t>, <a:t>
+// and <t> in Office Open XML, and the content of <text:p> and <text:h> in
+// OpenDocument, the rest of the markup is dropped.
+func (p *OfficeProcessor) textFromXML(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer func(rc io.ReadCloser) {
+		if err := rc.Close(); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(rc)
+	var b strings.Builder
+	var runs, texts, paragraphs int
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			isODF := t.Name.Space == odfTextNamespace
+			switch {
+			case t.Name.Local == "r" && !isODF:
+				runs++
+			case t.Name.Local == "t" && !isODF:
+				texts++
+			case isODF && (t.Name.Local == "p" || t.Name.Local == "h"):
+				paragraphs++
+			case t.Name.Local == "tab" && (runs > 0 || paragraphs > 0):
+				/* Not the tab stops of the paragraph properties */
+				b.WriteString("\t")
+			case t.Name.Local == "br" || (isODF && t.Name.Local == "line-break"):
+				b.WriteString("\n")
+			case isODF && t.Name.Local == "s":
+				count := 1
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "c" {
+						if v, err := strconv.Atoi(attr.Value); err == nil && v > 0 {
+							count = v
+						}
+					}
+				}
+				b.WriteString(strings.Repeat(" ", count))
+			}
+		case xml.EndElement:
+			isODF := t.Name.Space == odfTextNamespace
+			switch {
+			case t.Name.Local == "r" && !isODF:
+				runs--
+			case t.Name.Local == "t" && !isODF:
+				texts--
+			case isODF && (t.Name.Local == "p" || t.Name.Local == "h"):
+				paragraphs--
+				b.WriteString("\n")
+			case t.Name.Local == "p" || t.Name.Local == "si":
+				/* Paragraphs of Office Open XML, and shared strings of spreadsheets */
+				b.WriteString("\n")
+			}
+		case xml.CharData:
+			if texts > 0 || paragraphs > 0 {
+				b.Write(t)
+			}
+		}
+	}
+	return b.String(), nil
+}
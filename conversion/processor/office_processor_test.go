@@ -0,0 +1,84 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestOfficeText(t *testing.T) {
+	p := NewOfficeProcessor()
+	for _, tc := range []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			"docx",
+			map[string]string{
+				"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+					`<w:p><w:pPr><w:tabs><w:tab w:val="left"/></w:tabs></w:pPr><w:r><w:t>Hello</w:t></w:r>` +
+					`<w:r><w:tab/><w:t xml:space="preserve">world &amp; more</w:t></w:r></w:p>` +
+					`<w:p><w:r><w:t>Second</w:t><w:br/><w:t>line</w:t></w:r></w:p>` +
+					`<w:p><w:r><w:instrText>PAGE</w:instrText></w:r></w:p>` +
+					`</w:body></w:document>`,
+			},
+			"Hello\tworld & more\nSecond\nline",
+		},
+		{
+			"pptx",
+			map[string]string{
+				"ppt/slides/slide10.xml": `<p:sld xmlns:p="p" xmlns:a="a"><a:p><a:r><a:t>Ten</a:t></a:r></a:p></p:sld>`,
+				"ppt/slides/slide2.xml":  `<p:sld xmlns:p="p" xmlns:a="a"><a:p><a:r><a:t>Two</a:t></a:r></a:p></p:sld>`,
+				"ppt/slides/slide1.xml":  `<p:sld xmlns:p="p" xmlns:a="a"><a:p><a:r><a:t>One</a:t></a:r></a:p></p:sld>`,
+			},
+			"One\n\nTwo\n\nTen",
+		},
+		{
+			"xlsx",
+			map[string]string{
+				"xl/sharedStrings.xml": `<sst xmlns="x"><si><t>Name</t></si><si><r><t>Rich</t></r><r><t> text</t></r></si></sst>`,
+			},
+			"Name\nRich text",
+		},
+		{
+			"odt",
+			map[string]string{
+				"content.xml": `<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" ` +
+					`xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0"><office:body><office:text>` +
+					`<text:h>Title</text:h><text:p>a<text:s text:c="3"/>b<text:tab/>c<text:line-break/>d</text:p>` +
+					`</office:text></office:body></office:document-content>`,
+			},
+			"Title\na   b\tc\nd",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "input."+tc.name)
+			writeZip(t, path, tc.files)
+			got, err := p.Text(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("Text() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOfficeTextNotFound(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.docx")
+	writeZip(t, path, map[string]string{"docProps/app.xml": "<Properties/>"})
+	if _, err := NewOfficeProcessor().Text(path); err == nil {
+		t.Error("Text() error = nil, want an error")
+	}
+}
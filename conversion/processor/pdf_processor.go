@@ -11,8 +11,11 @@
 package processor
 
 import (
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -21,6 +24,7 @@ import (
 	"github.com/kouprlabs/voltaserve/conversion/config"
 	"github.com/kouprlabs/voltaserve/conversion/helper"
 	"github.com/kouprlabs/voltaserve/conversion/infra"
+	"github.com/kouprlabs/voltaserve/conversion/model"
 )
 
 type PDFProcessor struct {
@@ -60,6 +64,101 @@ func (p *PDFProcessor) TextFromPDF(inputPath string) (*string, error) {
 	return helper.ToPtr(strings.TrimSpace(string(b))), nil
 }
 
+// LayoutFromPDF extracts the words of each page together with their bounding
+// boxes, expressed in PDF points relative to the top-left corner of the page.
+func (p *PDFProcessor) LayoutFromPDF(inputPath string) (*model.Layout, error) {
+	tmpPath := filepath.Join(os.TempDir(), helper.NewID()+".html")
+
+	if err := infra.NewCommand().Exec("pdftotext", "-bbox", inputPath, tmpPath); err != nil {
+		return nil, err
+	}
+
+	defer func(path string) {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(tmpPath)
+
+	f, err := os.Open(tmpPath) //nolint:gosec // Known path
+	if err != nil {
+		return nil, err
+	}
+	defer func(f *os.File) {
+		if err := f.Close(); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(f)
+
+	return p.parseLayout(f)
+}
+
+func (p *PDFProcessor) parseLayout(r io.Reader) (*model.Layout, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+	res := &model.Layout{Pages: make([]model.LayoutPage, 0)}
+	var page *model.LayoutPage
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local == "page" {
+			if page != nil {
+				res.Pages = append(res.Pages, *page)
+			}
+			page = &model.LayoutPage{
+				Number: len(res.Pages) + 1,
+				Width:  p.floatAttr(start, "width"),
+				Height: p.floatAttr(start, "height"),
+				Words:  make([]model.LayoutWord, 0),
+			}
+		} else if start.Name.Local == "word" && page != nil {
+			var text string
+			if err := decoder.DecodeElement(&text, &start); err != nil {
+				return nil, err
+			}
+			text = strings.TrimSpace(text)
+			if text == "" {
+				continue
+			}
+			page.Words = append(page.Words, model.LayoutWord{
+				Text: text,
+				XMin: p.floatAttr(start, "xMin"),
+				YMin: p.floatAttr(start, "yMin"),
+				XMax: p.floatAttr(start, "xMax"),
+				YMax: p.floatAttr(start, "yMax"),
+			})
+		}
+	}
+	if page != nil {
+		res.Pages = append(res.Pages, *page)
+	}
+	return res, nil
+}
+
+func (p *PDFProcessor) floatAttr(element xml.StartElement, name string) float64 {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			value, err := strconv.ParseFloat(attr.Value, 64)
+			if err != nil {
+				return 0
+			}
+			return value
+		}
+	}
+	return 0
+}
+
 func (p *PDFProcessor) Thumbnail(inputPath string, width int, height int, outputPath string) error {
 	var widthStr string
 	if width == 0 {
@@ -90,3 +189,90 @@ func (p *PDFProcessor) CountPages(inputPath string) (*int, error) {
 	}
 	return &count, nil
 }
+
+// RenderPage rasterizes the page so that its longest side is size pixels, the
+// format is given by the extension of outputPath.
+func (p *PDFProcessor) RenderPage(inputPath string, page int, size int, outputPath string) error {
+	prefix := filepath.FromSlash(os.TempDir() + "/" + helper.NewID())
+	defer func(path string) {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(prefix + ".png")
+	if err := infra.NewCommand().Exec(
+		"pdftoppm", "-scale-to", strconv.Itoa(size), "-png",
+		"-f", strconv.Itoa(page), "-l", strconv.Itoa(page), "-singlefile",
+		inputPath, prefix,
+	); err != nil {
+		return err
+	}
+	if err := infra.NewCommand().Exec("convert", prefix+".png", "-quality", "85", outputPath); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RedactPDF rasterizes every page and burns out the redactions, so that no text,
+// vector or metadata of the redacted areas survives in the output.
+func (p *PDFProcessor) RedactPDF(inputPath string, redactions []model.Redaction, dpi int, outputPath string) error {
+	count, err := p.CountPages(inputPath)
+	if err != nil {
+		return err
+	}
+	dir, err := os.MkdirTemp(os.TempDir(), helper.NewID())
+	if err != nil {
+		return err
+	}
+	defer func(path string) {
+		if err := os.RemoveAll(path); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(dir)
+	pages := make([]string, 0, *count)
+	for page := 1; page <= *count; page++ {
+		prefix := filepath.Join(dir, fmt.Sprintf("page-%d", page))
+		if err := infra.NewCommand().Exec(
+			"pdftoppm", "-r", strconv.Itoa(dpi), "-png",
+			"-f", strconv.Itoa(page), "-l", strconv.Itoa(page), "-singlefile",
+			inputPath, prefix,
+		); err != nil {
+			return err
+		}
+		pagePath := prefix + ".png"
+		if err := p.burnRedactions(pagePath, page, redactions, dpi); err != nil {
+			return err
+		}
+		pages = append(pages, pagePath)
+	}
+	args := append(pages, "-o", outputPath)
+	if err := infra.NewCommand().Exec("img2pdf", args...); err != nil {
+		return err
+	}
+	return nil
+}
+
+// burnRedactions draws the page's redactions in black, and stores the DPI in
+// the image so that img2pdf restores the original page size.
+func (p *PDFProcessor) burnRedactions(pagePath string, page int, redactions []model.Redaction, dpi int) error {
+	args := []string{pagePath, "-alpha", "off", "-units", "PixelsPerInch", "-density", strconv.Itoa(dpi)}
+	scale := float64(dpi) / 72
+	for _, r := range redactions {
+		if r.Page != page {
+			continue
+		}
+		args = append(args, "-fill", "black", "-draw", fmt.Sprintf(
+			"rectangle %d,%d %d,%d",
+			int(math.Floor(r.XMin*scale)),
+			int(math.Floor(r.YMin*scale)),
+			int(math.Ceil(r.XMax*scale)),
+			int(math.Ceil(r.YMax*scale)),
+		))
+	}
+	args = append(args, pagePath)
+	if err := infra.NewCommand().Exec("convert", args...); err != nil {
+		return err
+	}
+	return nil
+}
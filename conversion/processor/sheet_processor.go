@@ -0,0 +1,670 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kouprlabs/voltaserve/conversion/client/api_client"
+	"github.com/kouprlabs/voltaserve/conversion/config"
+	"github.com/kouprlabs/voltaserve/conversion/infra"
+	"github.com/kouprlabs/voltaserve/conversion/model"
+)
+
+// sheetDateFormatIDs are the built-in number formats of dates and times, custom
+// formats are recognized by their date and time tokens.
+var sheetDateFormatIDs = []int{
+	14, 15, 16, 17, 18, 19, 20, 21, 22, 27, 28, 29, 30, 31, 32, 33, 34, 35, 36, 45, 46, 47,
+	50, 51, 52, 53, 54, 55, 56, 57, 58,
+}
+
+var (
+	// sheetNumberPattern leaves out numbers with leading zeros, like zip codes,
+	// which are kept as strings.
+	sheetNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+	sheetDatePattern   = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`)
+)
+
+// sheetMaxSignificantDigits is the precision of a float64, longer numbers like
+// IDs are kept as strings.
+const sheetMaxSignificantDigits = 15
+
+// csvSampleSize is how much of a CSV file is looked at to detect its delimiter
+// and its encoding.
+const csvSampleSize = 64 * 1024
+
+type SheetProcessor struct {
+	config *config.Config
+}
+
+func NewSheetProcessor() *SheetProcessor {
+	return &SheetProcessor{
+		config: config.GetConfig(),
+	}
+}
+
+type xlsxWorkbook struct {
+	Properties struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []xlsxSheetRef `xml:"sheets>sheet"`
+}
+
+type xlsxSheetRef struct {
+	Name  string     `xml:"name,attr"`
+	Attrs []xml.Attr `xml:",any,attr"`
+}
+
+type xlsxRelationships struct {
+	Relationships []xlsxRelationship `xml:"Relationship"`
+}
+
+type xlsxRelationship struct {
+	ID     string `xml:"Id,attr"`
+	Type   string `xml:"Type,attr"`
+	Target string `xml:"Target,attr"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxRichText struct {
+	Text *string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxCell struct {
+	Ref          string        `xml:"r,attr"`
+	Type         string        `xml:"t,attr"`
+	Style        int           `xml:"s,attr"`
+	Value        *string       `xml:"v"`
+	InlineString *xlsxRichText `xml:"is"`
+}
+
+// ReadXLSX reads the values cached in the cells, formulas are not evaluated.
+func (p *SheetProcessor) ReadXLSX(inputPath string) (*model.Workbook, error) {
+	r, err := zip.OpenReader(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func(r *zip.ReadCloser) {
+		if err := r.Close(); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(r)
+	files := make(map[string]*zip.File)
+	for _, f := range r.File {
+		files[f.Name] = f
+	}
+	var workbook xlsxWorkbook
+	if err := p.decodeXML(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	var rels xlsxRelationships
+	if err := p.decodeXML(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	var sharedStrings []string
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst xlsxSharedStrings
+		if err := p.decodeXML(files, "xl/sharedStrings.xml", &sst); err != nil {
+			return nil, err
+		}
+		for _, item := range sst.Items {
+			sharedStrings = append(sharedStrings, item.String())
+		}
+	}
+	var dateStyles []bool
+	if _, ok := files["xl/styles.xml"]; ok {
+		var styles xlsxStyles
+		if err := p.decodeXML(files, "xl/styles.xml", &styles); err != nil {
+			return nil, err
+		}
+		dateStyles = p.dateStyles(styles)
+	}
+	res := &model.Workbook{Sheets: []model.Sheet{}}
+	for _, ref := range workbook.Sheets {
+		f, ok := files[p.sheetPath(ref, rels)]
+		if !ok {
+			/* Chart sheets and other sheets without cells */
+			continue
+		}
+		sheet, err := p.readXLSXSheet(f, ref.Name, sharedStrings, dateStyles, workbook.Properties.Date1904)
+		if err != nil {
+			return nil, err
+		}
+		res.Sheets = append(res.Sheets, *sheet)
+	}
+	return res, nil
+}
+
+// ReadCSV detects the delimiter among commas, semicolons and tabs, files that
+// are not UTF-8 are read as Windows-1252.
+func (p *SheetProcessor) ReadCSV(inputPath string) (*model.Workbook, error) {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func(f *os.File) {
+		if err := f.Close(); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(f)
+	br := bufio.NewReaderSize(f, csvSampleSize)
+	if bom, err := br.Peek(3); err == nil && bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		if _, err := br.Discard(3); err != nil {
+			return nil, err
+		}
+	}
+	sample, _ := br.Peek(csvSampleSize)
+	if i := bytes.LastIndexByte(sample, '\n'); i >= 0 && len(sample) == csvSampleSize {
+		/* Don't cut the last character in half */
+		sample = sample[:i]
+	}
+	reader := csv.NewReader(br)
+	reader.Comma = p.delimiter(sample)
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+	isUTF8 := utf8.Valid(sample)
+	builder := newSheetBuilder("Sheet1", p.config.Limits.SheetMaxRows, p.config.Limits.SheetMaxColumns)
+	for row := 0; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if !builder.hasRoom(row) {
+			break
+		}
+		for col, field := range record {
+			if !isUTF8 {
+				field = p.decodeWindows1252(field)
+			}
+			builder.set(row, col, p.csvValue(field))
+		}
+	}
+	return &model.Workbook{Sheets: []model.Sheet{builder.sheet()}}, nil
+}
+
+func (p *SheetProcessor) Props(workbook *model.Workbook) *api_client.WorkbookProps {
+	res := &api_client.WorkbookProps{Sheets: []api_client.SheetProps{}}
+	for _, sheet := range workbook.Sheets {
+		res.Sheets = append(res.Sheets, api_client.SheetProps{
+			Name:      sheet.Name,
+			Rows:      len(sheet.Rows),
+			Columns:   len(sheet.Columns),
+			Truncated: sheet.Truncated,
+		})
+	}
+	return res
+}
+
+// Text lists the cells of each sheet under its name, separated by tabs.
+func (p *SheetProcessor) Text(workbook *model.Workbook) string {
+	var b strings.Builder
+	for _, sheet := range workbook.Sheets {
+		b.WriteString(sheet.Name + "\n")
+		for _, row := range sheet.Rows {
+			var cells []string
+			for _, v := range row {
+				cells = append(cells, p.format(v))
+			}
+			line := strings.TrimRight(strings.Join(cells, "\t"), "\t")
+			if line != "" {
+				b.WriteString(line + "\n")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (p *SheetProcessor) decodeXML(files map[string]*zip.File, name string, v any) error {
+	f, ok := files[name]
+	if !ok {
+		return errors.New("missing " + name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func(rc io.ReadCloser) {
+		if err := rc.Close(); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(rc)
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+func (p *SheetProcessor) sheetPath(ref xlsxSheetRef, rels xlsxRelationships) string {
+	var id string
+	for _, attr := range ref.Attrs {
+		if attr.Name.Local == "id" && attr.Name.Space != "" {
+			id = attr.Value
+		}
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID == id && strings.HasSuffix(rel.Type, "/worksheet") {
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/")
+			}
+			return path.Join("xl", rel.Target)
+		}
+	}
+	return ""
+}
+
+func (p *SheetProcessor) readXLSXSheet(f *zip.File, name string, sharedStrings []string, dateStyles []bool, date1904 bool) (*model.Sheet, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func(rc io.ReadCloser) {
+		if err := rc.Close(); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(rc)
+	builder := newSheetBuilder(name, p.config.Limits.SheetMaxRows, p.config.Limits.SheetMaxColumns)
+	decoder := xml.NewDecoder(rc)
+	row, col := -1, -1
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local == "row" {
+			row, col = row+1, -1
+			for _, attr := range start.Attr {
+				/* Rows without cells are left out of the file */
+				if n, err := strconv.Atoi(attr.Value); attr.Name.Local == "r" && err == nil && n-1 > row {
+					row = n - 1
+				}
+			}
+		} else if start.Name.Local == "c" {
+			var cell xlsxCell
+			if err := decoder.DecodeElement(&cell, &start); err != nil {
+				return nil, err
+			}
+			col++
+			if index, ok := p.columnIndex(cell.Ref); ok {
+				col = index
+			}
+			value := p.cellValue(cell, sharedStrings, dateStyles, date1904)
+			if value == nil {
+				continue
+			}
+			if !builder.hasRoom(max(row, 0)) {
+				break
+			}
+			builder.set(max(row, 0), col, value)
+		}
+	}
+	sheet := builder.sheet()
+	return &sheet, nil
+}
+
+func (p *SheetProcessor) cellValue(cell xlsxCell, sharedStrings []string, dateStyles []bool, date1904 bool) any {
+	if cell.Type == "inlineStr" {
+		if cell.InlineString == nil {
+			return nil
+		}
+		return p.text(cell.InlineString.String())
+	}
+	if cell.Value == nil {
+		return nil
+	}
+	switch cell.Type {
+	case "s":
+		index, err := strconv.Atoi(*cell.Value)
+		if err != nil || index < 0 || index >= len(sharedStrings) {
+			return nil
+		}
+		return p.text(sharedStrings[index])
+	case "b":
+		return *cell.Value == "1"
+	case "str", "e":
+		return p.text(*cell.Value)
+	case "d":
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, *cell.Value); err == nil {
+				return t
+			}
+		}
+		return p.text(*cell.Value)
+	}
+	v, err := strconv.ParseFloat(*cell.Value, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return p.text(*cell.Value)
+	}
+	if cell.Style >= 0 && cell.Style < len(dateStyles) && dateStyles[cell.Style] {
+		return p.serialToTime(v, date1904)
+	}
+	return v
+}
+
+// serialToTime converts a number of days since the epoch of the workbook, the
+// fraction being the time of the day.
+func (p *SheetProcessor) serialToTime(v float64, date1904 bool) time.Time {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return epoch.Add(time.Duration(math.Round(v*86400)) * time.Second)
+}
+
+func (p *SheetProcessor) dateStyles(styles xlsxStyles) []bool {
+	formats := make(map[int]string)
+	for _, numFmt := range styles.NumFmts {
+		formats[numFmt.ID] = numFmt.Code
+	}
+	var res []bool
+	for _, xf := range styles.CellXfs {
+		if code, ok := formats[xf.NumFmtID]; ok {
+			res = append(res, p.isDateFormat(code))
+		} else {
+			res = append(res, slices.Contains(sheetDateFormatIDs, xf.NumFmtID))
+		}
+	}
+	return res
+}
+
+// isDateFormat looks for date and time tokens, leaving out literal text, colors
+// and conditions.
+func (p *SheetProcessor) isDateFormat(code string) bool {
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		if c == '"' {
+			quoted = !quoted
+		} else if quoted {
+			continue
+		} else if c == '\\' || c == '_' || c == '*' {
+			i++
+		} else if c == '[' {
+			if j := strings.IndexByte(code[i:], ']'); j >= 0 {
+				i += j
+			}
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return strings.ContainsAny(strings.ToLower(b.String()), "dmyhs")
+}
+
+func (p *SheetProcessor) columnIndex(ref string) (int, bool) {
+	n := 0
+	for _, c := range strings.ToUpper(ref) {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		n = n*26 + int(c-'A'+1)
+	}
+	return n - 1, n > 0
+}
+
+func (p *SheetProcessor) csvValue(field string) any {
+	v := strings.TrimSpace(field)
+	if v == "" {
+		return nil
+	}
+	if sheetNumberPattern.MatchString(v) && p.significantDigits(v) <= sheetMaxSignificantDigits {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && !math.IsInf(f, 0) {
+			return f
+		}
+	}
+	if strings.EqualFold(v, "true") {
+		return true
+	} else if strings.EqualFold(v, "false") {
+		return false
+	}
+	if sheetDatePattern.MatchString(v) {
+		if t, err := time.Parse("2006-01-02", v); err == nil {
+			return t
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t
+	}
+	return field
+}
+
+func (p *SheetProcessor) significantDigits(v string) int {
+	mantissa, _, _ := strings.Cut(strings.ToLower(v), "e")
+	digits := strings.TrimLeft(strings.NewReplacer("-", "", ".", "").Replace(mantissa), "0")
+	return len(digits)
+}
+
+func (p *SheetProcessor) delimiter(sample []byte) rune {
+	line, _, _ := bytes.Cut(sample, []byte("\n"))
+	res, count := ',', bytes.Count(line, []byte(","))
+	for _, c := range []rune{';', '\t'} {
+		if n := bytes.Count(line, []byte(string(c))); n > count {
+			res, count = c, n
+		}
+	}
+	return res
+}
+
+func (p *SheetProcessor) decodeWindows1252(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c >= 0x80 && c <= 0x9F {
+			b.WriteRune(windows1252[c-0x80])
+		} else {
+			b.WriteRune(rune(c))
+		}
+	}
+	return b.String()
+}
+
+// text returns nil for empty strings, so that the cell is considered empty.
+func (p *SheetProcessor) text(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+func (p *SheetProcessor) format(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ").Replace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+func (t xlsxRichText) String() string {
+	if t.Text != nil {
+		return *t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// sheetBuilder collects the cells of a sheet up to the limits, the cells are
+// strings, float64, bool or time.Time.
+type sheetBuilder struct {
+	name       string
+	maxRows    int
+	maxColumns int
+	rows       [][]any
+	truncated  bool
+}
+
+func newSheetBuilder(name string, maxRows int, maxColumns int) *sheetBuilder {
+	return &sheetBuilder{
+		name:       name,
+		maxRows:    maxRows,
+		maxColumns: maxColumns,
+	}
+}
+
+// hasRoom tells if the row is within the limit, otherwise marks the sheet as
+// truncated.
+func (b *sheetBuilder) hasRoom(row int) bool {
+	if b.maxRows > 0 && row >= b.maxRows {
+		b.truncated = true
+		return false
+	}
+	return true
+}
+
+func (b *sheetBuilder) set(row int, col int, value any) {
+	if value == nil {
+		return
+	}
+	if b.maxColumns > 0 && col >= b.maxColumns {
+		b.truncated = true
+		return
+	}
+	for len(b.rows) <= row {
+		b.rows = append(b.rows, nil)
+	}
+	for len(b.rows[row]) <= col {
+		b.rows[row] = append(b.rows[row], nil)
+	}
+	b.rows[row][col] = value
+}
+
+func (b *sheetBuilder) sheet() model.Sheet {
+	width := 0
+	for _, row := range b.rows {
+		width = max(width, len(row))
+	}
+	res := model.Sheet{
+		Name:      b.name,
+		Columns:   []model.SheetColumn{},
+		Rows:      [][]any{},
+		Truncated: b.truncated,
+	}
+	for col := 0; col < width; col++ {
+		res.Columns = append(res.Columns, model.SheetColumn{
+			Name: b.columnName(col),
+			Type: b.columnType(col),
+		})
+	}
+	for _, row := range b.rows {
+		cells := make([]any, width)
+		for col, v := range row {
+			if t, ok := v.(time.Time); ok {
+				cells[col] = t.Format(time.RFC3339)
+			} else {
+				cells[col] = v
+			}
+		}
+		res.Rows = append(res.Rows, cells)
+	}
+	return res
+}
+
+// columnType is the type shared by the cells of the column, the first row is
+// left out when it looks like a header, that is when it only holds strings.
+func (b *sheetBuilder) columnType(col int) string {
+	rows := b.rows
+	if len(rows) > 1 && b.isHeader(rows[0]) {
+		rows = rows[1:]
+	}
+	var res string
+	for _, row := range rows {
+		if col >= len(row) || row[col] == nil {
+			continue
+		}
+		var t string
+		switch row[col].(type) {
+		case float64:
+			t = model.SheetColumnTypeNumber
+		case bool:
+			t = model.SheetColumnTypeBoolean
+		case time.Time:
+			t = model.SheetColumnTypeDate
+		default:
+			t = model.SheetColumnTypeString
+		}
+		if res == "" {
+			res = t
+		} else if res != t {
+			return model.SheetColumnTypeString
+		}
+	}
+	if res == "" {
+		return model.SheetColumnTypeString
+	}
+	return res
+}
+
+func (b *sheetBuilder) isHeader(row []any) bool {
+	found := false
+	for _, v := range row {
+		if v == nil {
+			continue
+		}
+		if _, ok := v.(string); !ok {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// columnName returns the letters of the column, like A, B, …, Z, AA, AB.
+func (b *sheetBuilder) columnName(col int) string {
+	var res string
+	for n := col + 1; n > 0; n = (n - 1) / 26 {
+		res = string(rune('A'+(n-1)%26)) + res
+	}
+	return res
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kouprlabs/voltaserve/conversion/config"
+	"github.com/kouprlabs/voltaserve/conversion/model"
+)
+
+func TestSheetReadCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.csv")
+	if err := os.WriteFile(path, []byte("name;age;zip;ok;date\nAnn;30;01234;true;2024-01-02\nBob;4.5;12345;FALSE;x\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p := NewSheetProcessor()
+	workbook, err := p.ReadCSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := model.Sheet{
+		Name: "Sheet1",
+		Columns: []model.SheetColumn{
+			{Name: "A", Type: model.SheetColumnTypeString},
+			{Name: "B", Type: model.SheetColumnTypeNumber},
+			{Name: "C", Type: model.SheetColumnTypeString},
+			{Name: "D", Type: model.SheetColumnTypeBoolean},
+			{Name: "E", Type: model.SheetColumnTypeString},
+		},
+		Rows: [][]any{
+			{"name", "age", "zip", "ok", "date"},
+			{"Ann", float64(30), "01234", true, "2024-01-02T00:00:00Z"},
+			{"Bob", 4.5, float64(12345), false, "x"},
+		},
+	}
+	if len(workbook.Sheets) != 1 || !reflect.DeepEqual(workbook.Sheets[0], want) {
+		t.Errorf("ReadCSV() = %+v, want %+v", workbook.Sheets, want)
+	}
+	if got := p.Text(workbook); got != "Sheet1\nname\tage\tzip\tok\tdate\nAnn\t30\t01234\ttrue\t2024-01-02T00:00:00Z\nBob\t4.5\t12345\tfalse\tx\n\n" {
+		t.Errorf("Text() = %q", got)
+	}
+}
+
+func TestSheetReadCSVWindows1252(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.csv")
+	if err := os.WriteFile(path, []byte("a,b\ncaf\xe9,1234567890123456\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	workbook, err := NewSheetProcessor().ReadCSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	/* The number has more digits than a float64 holds, so it's kept as a string */
+	want := [][]any{{"a", "b"}, {"café", "1234567890123456"}}
+	if !reflect.DeepEqual(workbook.Sheets[0].Rows, want) {
+		t.Errorf("ReadCSV() rows = %q, want %q", workbook.Sheets[0].Rows, want)
+	}
+}
+
+func TestSheetReadCSVTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.csv")
+	if err := os.WriteFile(path, []byte("a,b,c\n1,2,3\n4,5,6\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p := NewSheetProcessor()
+	p.config = &config.Config{Limits: config.LimitsConfig{SheetMaxRows: 2, SheetMaxColumns: 2}}
+	workbook, err := p.ReadCSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheet := workbook.Sheets[0]
+	if !sheet.Truncated || len(sheet.Rows) != 2 || len(sheet.Columns) != 2 {
+		t.Errorf("ReadCSV() = %+v, want 2 rows and 2 columns, truncated", sheet)
+	}
+}
+
+func TestSheetReadXLSX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.xlsx")
+	writeZip(t, path, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<si><t>Name</t></si><si><r><t>Rich</t></r><r><t> text</t></r></si></sst>`,
+		"xl/styles.xml": `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+			`<cellXfs><xf numFmtId="0"/><xf numFmtId="14"/></cellXfs></styleSheet>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="inlineStr"><is><t>Inline</t></is></c></row>` +
+			`<row r="2"><c r="A2" t="s"><v>1</v></c><c r="B2"><v>1.5</v></c><c r="C2" s="1"><v>45000.5</v></c><c r="D2" t="b"><v>1</v></c></row>` +
+			`</sheetData></worksheet>`,
+	})
+	workbook, err := NewSheetProcessor().ReadXLSX(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(workbook.Sheets) != 1 {
+		t.Fatalf("len(Sheets) = %d, want 1", len(workbook.Sheets))
+	}
+	sheet := workbook.Sheets[0]
+	want := [][]any{
+		{"Name", nil, "Inline", nil},
+		{"Rich text", 1.5, "2023-03-15T12:00:00Z", true},
+	}
+	if sheet.Name != "Data" || !reflect.DeepEqual(sheet.Rows, want) {
+		t.Errorf("ReadXLSX() = %+v, want the rows %+v", sheet, want)
+	}
+}
+
+func TestSheetIsDateFormat(t *testing.T) {
+	p := NewSheetProcessor()
+	for _, tc := range []struct {
+		code string
+		want bool
+	}{
+		{"yyyy-mm-dd", true},
+		{"h:mm", true},
+		{"[$-409]d-mmm", true},
+		{"0.00", false},
+		{`"day"0`, false},
+		{"[Red]0.00", false},
+		{`0\d`, false},
+	} {
+		if got := p.isDateFormat(tc.code); got != tc.want {
+			t.Errorf("isDateFormat(%q) = %v, want %v", tc.code, got, tc.want)
+		}
+	}
+}
+
+func TestSheetSerialToTime(t *testing.T) {
+	p := NewSheetProcessor()
+	if got := p.serialToTime(45000.5, false); !got.Equal(time.Date(2023, 3, 15, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("serialToTime(45000.5, false) = %v", got)
+	}
+	if got := p.serialToTime(1, true); !got.Equal(time.Date(1904, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("serialToTime(1, true) = %v", got)
+	}
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(f *os.File) {
+		if err := f.Close(); err != nil {
+			t.Error(err)
+		}
+	}(f)
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
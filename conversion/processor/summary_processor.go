@@ -0,0 +1,300 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/kouprlabs/voltaserve/conversion/config"
+	"github.com/kouprlabs/voltaserve/conversion/model"
+)
+
+const (
+	summaryMaxSentences      = 5
+	summaryMaxCandidates     = 500
+	summaryMinSentenceWords  = 5
+	summaryMaxSentenceWords  = 80
+	summaryMaxKeyphrases     = 10
+	summaryKeyphraseMaxWords = 4
+	summaryCoOccurrenceSize  = 2
+	textRankDamping          = 0.85
+	textRankIterations       = 30
+	textRankTolerance        = 1e-4
+)
+
+// summaryStopWords complement the stop words used for language detection,
+// which are too few to keep filler words out of the keyphrases.
+var summaryStopWords = []string{
+	"also", "been", "but", "can", "could", "did", "does", "had", "has", "her", "his", "how", "into", "its", "may",
+	"more", "most", "must", "our", "out", "over", "such", "than", "their", "them", "then", "there", "these", "they",
+	"those", "through", "under", "upon", "very", "what", "when", "where", "while", "who", "will", "would", "you",
+	"your", "all", "any", "each", "other", "some", "only", "about", "after", "before", "between", "both", "should",
+}
+
+// SummaryProcessor computes an extractive summary and keyphrases with TextRank,
+// it doesn't depend on any external service so it runs offline.
+type SummaryProcessor struct {
+	stopWords map[string]bool
+}
+
+func NewSummaryProcessor() *SummaryProcessor {
+	stopWords := make(map[string]bool)
+	for _, words := range languageStopWords {
+		for _, word := range words {
+			stopWords[word] = true
+		}
+	}
+	for _, word := range summaryStopWords {
+		stopWords[word] = true
+	}
+	return &SummaryProcessor{stopWords: stopWords}
+}
+
+func (p *SummaryProcessor) Summarize(text string, _ string) (*model.Summary, error) {
+	sentences := p.sentences(text)
+	if len(sentences) == 0 {
+		return nil, errors.New("text has no sentences to summarize")
+	}
+	return &model.Summary{
+		Sentences:  p.rankSentences(sentences),
+		Keyphrases: p.rankKeyphrases(sentences),
+		Provider:   config.SummaryProviderTextRank,
+	}, nil
+}
+
+// rankSentences returns the best sentences in their original order.
+func (p *SummaryProcessor) rankSentences(sentences []string) []string {
+	candidates := sentences
+	if len(candidates) > summaryMaxCandidates {
+		candidates = candidates[:summaryMaxCandidates]
+	}
+	words := make([]map[string]bool, len(candidates))
+	for i, sentence := range candidates {
+		words[i] = make(map[string]bool)
+		for _, word := range p.words(sentence) {
+			if !p.stopWords[word] {
+				words[i][word] = true
+			}
+		}
+	}
+	graph := make([]map[int]float64, len(candidates))
+	for i := range candidates {
+		graph[i] = make(map[int]float64)
+		for j := range candidates {
+			if i != j {
+				if similarity := p.similarity(words[i], words[j]); similarity > 0 {
+					graph[i][j] = similarity
+				}
+			}
+		}
+	}
+	scores := p.textRank(graph)
+	indexes := make([]int, len(candidates))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool {
+		return scores[indexes[a]] > scores[indexes[b]]
+	})
+	if len(indexes) > summaryMaxSentences {
+		indexes = indexes[:summaryMaxSentences]
+	}
+	sort.Ints(indexes)
+	res := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		res = append(res, candidates[i])
+	}
+	return res
+}
+
+// rankKeyphrases ranks words on their co-occurrence graph, then merges
+// adjacent top ranked words into phrases.
+func (p *SummaryProcessor) rankKeyphrases(sentences []string) []model.Keyphrase {
+	vocabulary := make(map[string]int)
+	var terms []string
+	tokens := make([][]string, len(sentences))
+	for i, sentence := range sentences {
+		tokens[i] = p.words(sentence)
+		for _, word := range tokens[i] {
+			if p.isCandidate(word) {
+				if _, ok := vocabulary[word]; !ok {
+					vocabulary[word] = len(terms)
+					terms = append(terms, word)
+				}
+			}
+		}
+	}
+	if len(terms) == 0 {
+		return []model.Keyphrase{}
+	}
+	graph := make([]map[int]float64, len(terms))
+	for i := range graph {
+		graph[i] = make(map[int]float64)
+	}
+	for _, words := range tokens {
+		for i, word := range words {
+			if !p.isCandidate(word) {
+				continue
+			}
+			for j := i + 1; j < len(words) && j <= i+summaryCoOccurrenceSize; j++ {
+				if !p.isCandidate(words[j]) || words[j] == word {
+					continue
+				}
+				a, b := vocabulary[word], vocabulary[words[j]]
+				graph[a][b] = 1
+				graph[b][a] = 1
+			}
+		}
+	}
+	scores := p.textRank(graph)
+	/* Keep the top third of the words as keywords */
+	ranked := make([]int, len(terms))
+	for i := range ranked {
+		ranked[i] = i
+	}
+	sort.SliceStable(ranked, func(a, b int) bool {
+		return scores[ranked[a]] > scores[ranked[b]]
+	})
+	keywords := make(map[string]float64)
+	for _, i := range ranked[:max(1, len(ranked)/3)] {
+		keywords[terms[i]] = scores[i]
+	}
+	/* Collapse adjacent keywords into phrases */
+	phrases := make(map[string]float64)
+	for _, words := range tokens {
+		var phrase []string
+		score := 0.0
+		flush := func() {
+			if len(phrase) > 0 && len(phrase) <= summaryKeyphraseMaxWords {
+				text := strings.Join(phrase, " ")
+				phrases[text] = math.Max(phrases[text], score)
+			}
+			phrase, score = nil, 0
+		}
+		for _, word := range words {
+			if s, ok := keywords[word]; ok {
+				phrase = append(phrase, word)
+				score += s
+			} else {
+				flush()
+			}
+		}
+		flush()
+	}
+	res := make([]model.Keyphrase, 0, len(phrases))
+	for text, score := range phrases {
+		res = append(res, model.Keyphrase{Text: text, Score: score})
+	}
+	sort.Slice(res, func(a, b int) bool {
+		if res[a].Score == res[b].Score {
+			return res[a].Text < res[b].Text
+		}
+		return res[a].Score > res[b].Score
+	})
+	if len(res) > summaryMaxKeyphrases {
+		res = res[:summaryMaxKeyphrases]
+	}
+	return res
+}
+
+// textRank runs weighted PageRank on a symmetric graph given as adjacency lists.
+func (p *SummaryProcessor) textRank(graph []map[int]float64) []float64 {
+	n := len(graph)
+	scores := make([]float64, n)
+	weights := make([]float64, n)
+	for i := range graph {
+		scores[i] = 1
+		for _, w := range graph[i] {
+			weights[i] += w
+		}
+	}
+	for iteration := 0; iteration < textRankIterations; iteration++ {
+		delta := 0.0
+		next := make([]float64, n)
+		for i := range graph {
+			sum := 0.0
+			// The graph is symmetric, so the neighbours of i are also the nodes pointing to it
+			for j, w := range graph[i] {
+				if weights[j] > 0 {
+					sum += w / weights[j] * scores[j]
+				}
+			}
+			next[i] = (1 - textRankDamping) + textRankDamping*sum
+			delta += math.Abs(next[i] - scores[i])
+		}
+		scores = next
+		if delta < textRankTolerance {
+			break
+		}
+	}
+	return scores
+}
+
+// similarity is the normalized word overlap from the original TextRank paper.
+func (p *SummaryProcessor) similarity(a map[string]bool, b map[string]bool) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return 0
+	}
+	common := 0
+	for word := range a {
+		if b[word] {
+			common++
+		}
+	}
+	if common == 0 {
+		return 0
+	}
+	return float64(common) / (math.Log(float64(len(a))) + math.Log(float64(len(b))))
+}
+
+func (p *SummaryProcessor) sentences(text string) []string {
+	var res []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\f", "\n\n"), "\n\n") {
+		paragraph = strings.Join(strings.Fields(paragraph), " ")
+		var current strings.Builder
+		runes := []rune(paragraph)
+		for i, r := range runes {
+			current.WriteRune(r)
+			isLast := i == len(runes)-1
+			if isLast || (strings.ContainsRune(".!?。！？", r) && (unicode.IsSpace(runes[i+1]) || r > unicode.MaxLatin1)) {
+				sentence := strings.TrimSpace(current.String())
+				current.Reset()
+				count := len(strings.Fields(sentence))
+				if count >= summaryMinSentenceWords && count <= summaryMaxSentenceWords {
+					res = append(res, sentence)
+				}
+			}
+		}
+	}
+	return res
+}
+
+func (p *SummaryProcessor) words(sentence string) []string {
+	return strings.FieldsFunc(strings.ToLower(sentence), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+}
+
+func (p *SummaryProcessor) isCandidate(word string) bool {
+	if p.stopWords[word] || len([]rune(word)) < 3 {
+		return false
+	}
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
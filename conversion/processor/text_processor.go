@@ -0,0 +1,223 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kouprlabs/voltaserve/conversion/config"
+	"github.com/kouprlabs/voltaserve/conversion/identifier"
+)
+
+const textStylesheet = `
+body { margin: 0; font-family: sans-serif; font-size: 14px; line-height: 1.5; color: #1a202c; background: #ffffff; }
+.notice { margin: 0; padding: 8px 16px; background: #fefcbf; border-bottom: 1px solid #ecc94b; }
+.notice.error { background: #fed7d7; border-color: #f56565; }
+table.listing { border-collapse: collapse; width: 100%; }
+table.listing td { padding: 0; vertical-align: top; }
+table.listing pre { margin: 0; padding: 12px 16px; font-family: monospace; font-size: 13px; line-height: 1.5; }
+td.gutter pre { color: #a0aec0; text-align: right; user-select: none; border-right: 1px solid #e2e8f0; }
+td.code pre { white-space: pre; }
+.k { color: #805ad5; font-weight: bold; }
+.s { color: #2f855a; }
+.a { color: #2b6cb0; }
+.n { color: #c05621; }
+.c { color: #718096; font-style: italic; }
+article.markdown { max-width: 860px; margin: 0 auto; padding: 24px 32px; }
+article.markdown pre { padding: 12px 16px; background: #f7fafc; border-radius: 6px; overflow: auto; }
+article.markdown code { font-family: monospace; font-size: 13px; }
+article.markdown :not(pre) > code { padding: 2px 4px; background: #edf2f7; border-radius: 4px; }
+article.markdown blockquote { margin: 0; padding: 0 16px; color: #4a5568; border-left: 4px solid #e2e8f0; }
+article.markdown table { border-collapse: collapse; }
+article.markdown th, article.markdown td { padding: 6px 12px; border: 1px solid #e2e8f0; }
+article.markdown img { max-width: 100%; }
+article.markdown li > p { margin: 0; }
+`
+
+type TextProcessor struct {
+	codeProc     *CodeProcessor
+	markdownProc *MarkdownProcessor
+	fileIdent    *identifier.FileIdentifier
+	config       *config.Config
+}
+
+func NewTextProcessor() *TextProcessor {
+	return &TextProcessor{
+		codeProc:     NewCodeProcessor(),
+		markdownProc: NewMarkdownProcessor(),
+		fileIdent:    identifier.NewFileIdentifier(),
+		config:       config.GetConfig(),
+	}
+}
+
+// Read decodes the text from the encoding given by its byte order mark, or from
+// UTF-16 detected from its zero bytes, or UTF-8, and falls back to Windows-1252.
+func (p *TextProcessor) Read(inputPath string) (string, error) {
+	b, err := os.ReadFile(inputPath)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case bytes.HasPrefix(b, []byte("\xef\xbb\xbf")):
+		return strings.ToValidUTF8(string(b[3:]), "�"), nil
+	case bytes.HasPrefix(b, []byte("\xff\xfe")):
+		return p.decodeUTF16(b[2:], false), nil
+	case bytes.HasPrefix(b, []byte("\xfe\xff")):
+		return p.decodeUTF16(b[2:], true), nil
+	}
+	/* UTF-16 text in ASCII is also valid UTF-8, so its zero bytes are looked for first */
+	if bigEndian, ok := p.detectUTF16(b); ok {
+		return p.decodeUTF16(b, bigEndian), nil
+	}
+	if utf8.Valid(b) {
+		return string(b), nil
+	}
+	var res strings.Builder
+	for _, c := range b {
+		if c >= 0x80 && c <= 0x9F {
+			res.WriteRune(windows1252[c-0x80])
+		} else {
+			res.WriteRune(rune(c))
+		}
+	}
+	return res.String(), nil
+}
+
+// HTML renders a standalone document, Markdown is rendered, JSON and YAML are
+// validated and pretty-printed, and other text is highlighted according to the
+// extension. Text past the size limit is left out.
+func (p *TextProcessor) HTML(text string, extension string) string {
+	var notices []string
+	maxSize := p.config.Limits.TextPreviewMaxSizeKB * 1024
+	isTruncated := maxSize > 0 && len(text) > maxSize
+	if isTruncated {
+		text = p.truncate(text, maxSize)
+		notices = append(notices, fmt.Sprintf(`<p class="notice">The file is too large to be shown entirely, only the first %d KB are shown.</p>`, p.config.Limits.TextPreviewMaxSizeKB))
+	}
+	var body string
+	if p.fileIdent.IsMarkdown(extension) {
+		body = `<article class="markdown">` + p.markdownProc.HTML(text) + `</article>`
+	} else {
+		/* A truncated document is not valid, so it's shown as it is */
+		if p.fileIdent.IsJSON(extension) && !isTruncated {
+			if formatted, err := p.formatJSON(text); err != nil {
+				notices = append(notices, `<p class="notice error">Invalid JSON: `+html.EscapeString(err.Error())+`</p>`)
+			} else {
+				text = formatted
+			}
+		} else if p.fileIdent.IsYAML(extension) && !isTruncated {
+			if formatted, err := p.formatYAML(text); err != nil {
+				notices = append(notices, `<p class="notice error">Invalid YAML: `+html.EscapeString(err.Error())+`</p>`)
+			} else {
+				text = formatted
+			}
+		}
+		body = p.codeProc.Listing(text, extension)
+	}
+	return "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n" +
+		"<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n" +
+		"<style>" + textStylesheet + "</style>\n</head>\n<body>\n" +
+		strings.Join(notices, "\n") + body + "\n</body>\n</html>\n"
+}
+
+func (p *TextProcessor) formatJSON(text string) (string, error) {
+	var b bytes.Buffer
+	if err := json.Indent(&b, []byte(text), "", "  "); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line := strings.Count(text[:min(int(syntaxErr.Offset), len(text))], "\n") + 1
+			return "", fmt.Errorf("line %d: %w", line, err)
+		}
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// formatYAML re-indents every document of the stream, the comments are kept.
+func (p *TextProcessor) formatYAML(text string) (string, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(text))
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	for {
+		var node yaml.Node
+		if err := decoder.Decode(&node); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", errors.New(strings.TrimPrefix(err.Error(), "yaml: "))
+		}
+		if err := encoder.Encode(&node); err != nil {
+			return "", err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// truncate cuts the text at the last line break within the size, or at the last
+// character that fits when there is none.
+func (p *TextProcessor) truncate(text string, size int) string {
+	if i := strings.LastIndexByte(text[:size], '\n'); i > 0 {
+		return text[:i+1]
+	}
+	for size > 0 && !utf8.RuneStart(text[size]) {
+		size--
+	}
+	return text[:size]
+}
+
+// detectUTF16 looks for the zero bytes that ASCII characters have in UTF-16.
+func (p *TextProcessor) detectUTF16(b []byte) (bool, bool) {
+	if len(b) < 2 || len(b)%2 != 0 {
+		return false, false
+	}
+	var even, odd int
+	for i := 0; i < min(len(b), 4096); i += 2 {
+		if b[i] == 0 {
+			even++
+		}
+		if b[i+1] == 0 {
+			odd++
+		}
+	}
+	pairs := min(len(b), 4096) / 2
+	if odd > pairs/2 && even == 0 {
+		return false, true
+	} else if even > pairs/2 && odd == 0 {
+		return true, true
+	}
+	return false, false
+}
+
+func (p *TextProcessor) decodeUTF16(b []byte, bigEndian bool) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		} else {
+			units = append(units, uint16(b[i+1])<<8|uint16(b[i]))
+		}
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kouprlabs/voltaserve/conversion/config"
+)
+
+func TestTextRead(t *testing.T) {
+	p := NewTextProcessor()
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"utf-8", "héllo", "héllo"},
+		{"utf-8 with byte order mark", "\xef\xbb\xbfhé", "hé"},
+		{"utf-16le with byte order mark", "\xff\xfeh\x00i\x00", "hi"},
+		{"utf-16be with byte order mark", "\xfe\xff\x00h\x00i", "hi"},
+		{"utf-16le", "h\x00i\x00", "hi"},
+		{"utf-16be", "\x00h\x00i", "hi"},
+		{"windows-1252", "caf\xe9 \x80", "café €"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "input.txt")
+			if err := os.WriteFile(path, []byte(tc.input), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.Read(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("Read(%q) = %q, want %q", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTextFormat(t *testing.T) {
+	p := NewTextProcessor()
+	if got, err := p.formatJSON(`{"a":1,"b":[1]}`); err != nil || got != "{\n  \"a\": 1,\n  \"b\": [\n    1\n  ]\n}" {
+		t.Errorf("formatJSON() = %q, %v", got, err)
+	}
+	if _, err := p.formatJSON("{\n\"a\":}"); err == nil || !strings.HasPrefix(err.Error(), "line 2: ") {
+		t.Errorf("formatJSON() error = %v, want the line", err)
+	}
+	if got, err := p.formatYAML("a:    1\n# c\nb:\n    - x\n---\nc: 2\n"); err != nil || got != "a: 1\n# c\nb:\n  - x\n---\nc: 2\n" {
+		t.Errorf("formatYAML() = %q, %v", got, err)
+	}
+	if _, err := p.formatYAML("a: [1"); err == nil || strings.HasPrefix(err.Error(), "yaml: ") {
+		t.Errorf("formatYAML() error = %v, want it without prefix", err)
+	}
+}
+
+func TestTextTruncate(t *testing.T) {
+	p := NewTextProcessor()
+	for _, tc := range []struct {
+		text string
+		size int
+		want string
+	}{
+		{"ab\ncd\nef", 7, "ab\ncd\n"},
+		{"abcdef", 4, "abcd"},
+		{"héllo", 2, "h"},
+	} {
+		if got := p.truncate(tc.text, tc.size); got != tc.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tc.text, tc.size, got, tc.want)
+		}
+	}
+}
+
+func TestTextHTML(t *testing.T) {
+	p := NewTextProcessor()
+	p.config = &config.Config{Limits: config.LimitsConfig{TextPreviewMaxSizeKB: 1}}
+	for _, tc := range []struct {
+		name      string
+		text      string
+		extension string
+		contains  string
+	}{
+		{"markdown", "# Title", ".md", `<article class="markdown"><h1 id="title">Title</h1>`},
+		{"json", `{"a":1}`, ".json", "{\n  <span class=\"a\">&#34;a&#34;</span>: <span class=\"n\">1</span>\n}"},
+		{"invalid json", `{"a":}`, ".json", `<p class="notice error">Invalid JSON: line 1: `},
+		{"invalid yaml", "a: [1", ".yaml", `<p class="notice error">Invalid YAML: `},
+		{"escaped", "<script>", ".txt", "&lt;script&gt;"},
+		{"truncated", strings.Repeat("a\n", 1024), ".txt", "only the first 1 KB are shown"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := p.HTML(tc.text, tc.extension); !strings.Contains(got, tc.contains) {
+				t.Errorf("HTML(%q, %q) = %q, want it to contain %q", tc.text, tc.extension, got, tc.contains)
+			}
+		})
+	}
+}
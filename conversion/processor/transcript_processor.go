@@ -0,0 +1,105 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kouprlabs/voltaserve/conversion/config"
+	"github.com/kouprlabs/voltaserve/conversion/helper"
+	"github.com/kouprlabs/voltaserve/conversion/infra"
+)
+
+var transcriptTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// TranscriptProcessor transcribes speech with the local engine of the config,
+// the transcripts are WebVTT files, so that players show them as subtitles.
+type TranscriptProcessor struct {
+	cmd    *infra.Command
+	config *config.Config
+}
+
+func NewTranscriptProcessor() *TranscriptProcessor {
+	return &TranscriptProcessor{
+		cmd:    infra.NewCommand(),
+		config: config.GetConfig(),
+	}
+}
+
+func (p *TranscriptProcessor) IsEnabled() bool {
+	return p.config.Transcript.Engine != ""
+}
+
+// Transcribe writes the transcript of the audio track of the file to outputPath,
+// which is decoded first to the 16 kHz mono WAV expected by speech-to-text models.
+func (p *TranscriptProcessor) Transcribe(inputPath string, outputPath string) error {
+	wavPath := filepath.FromSlash(os.TempDir() + "/" + helper.NewID() + ".wav")
+	defer func(path string) {
+		if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(wavPath)
+	if err := infra.NewCommand().Exec(
+		"ffmpeg", "-v", "error", "-i", inputPath,
+		"-vn", "-ac", "1", "-ar", "16000", "-acodec", "pcm_s16le", wavPath,
+	); err != nil {
+		return err
+	}
+	switch p.config.Transcript.Engine {
+	case config.TranscriptEngineWhisperCpp:
+		return p.transcribeWithWhisperCpp(wavPath, outputPath)
+	default:
+		return fmt.Errorf("unknown transcript engine %q", p.config.Transcript.Engine)
+	}
+}
+
+// Text joins the cues of the transcript without their timings and voice tags.
+func (p *TranscriptProcessor) Text(vtt string) string {
+	var lines []string
+	for _, block := range strings.Split(strings.ReplaceAll(vtt, "\r\n", "\n"), "\n\n") {
+		blockLines := strings.Split(strings.TrimSpace(block), "\n")
+		for i, line := range blockLines {
+			if !strings.Contains(line, "-->") {
+				continue
+			}
+			/* The header, notes and styles have no timings, so they are skipped */
+			for _, cue := range blockLines[i+1:] {
+				if cue = strings.TrimSpace(transcriptTagPattern.ReplaceAllString(cue, "")); cue != "" {
+					lines = append(lines, cue)
+				}
+			}
+			break
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (p *TranscriptProcessor) transcribeWithWhisperCpp(wavPath string, outputPath string) error {
+	/* whisper.cpp names the output after the prefix, adding the extension */
+	prefix := filepath.FromSlash(os.TempDir() + "/" + helper.NewID())
+	if err := infra.NewCommand().Exec(
+		p.config.Transcript.Command,
+		"-m", p.config.Transcript.Model,
+		"-l", p.config.Transcript.Language,
+		"-f", wavPath,
+		"-ovtt", "-of", prefix, "-np",
+	); err != nil {
+		return err
+	}
+	return os.Rename(prefix+".vtt", outputPath)
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package processor
+
+import (
+	"testing"
+)
+
+func TestTranscriptText(t *testing.T) {
+	p := NewTranscriptProcessor()
+	for _, tc := range []struct {
+		name string
+		vtt  string
+		want string
+	}{
+		{"empty", "WEBVTT\n", ""},
+		{"cues", "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nHello\n\n00:00:01.000 --> 00:00:02.000\nWorld\n", "Hello\nWorld"},
+		{"identifiers", "WEBVTT\n\n1\n00:00:00.000 --> 00:00:01.000\nHello\nthere\n", "Hello\nthere"},
+		{"notes", "WEBVTT\n\nNOTE a comment\n\n00:00:00.000 --> 00:00:01.000\nHello\n", "Hello"},
+		{"tags", "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\n<v Bob>Hello</v> <b>there</b>\n", "Hello there"},
+		{"empty cue", "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\n\n00:00:01.000 --> 00:00:02.000\nHello\n", "Hello"},
+		{"crlf", "WEBVTT\r\n\r\n00:00:00.000 --> 00:00:01.000\r\nHello\r\n", "Hello"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := p.Text(tc.vtt); got != tc.want {
+				t.Errorf("Text(%q) = %q, want %q", tc.vtt, got, tc.want)
+			}
+		})
+	}
+}
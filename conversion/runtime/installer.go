@@ -30,6 +30,7 @@ func (d *Installer) Start() {
 			d.installCoreTools()
 			d.installGltfPipeline()
 			d.installBlender()
+			d.installFreeCAD()
 			d.installLibreOffice()
 			d.installTesseract()
 			d.installFonts()
@@ -58,7 +59,12 @@ func (d *Installer) installCoreTools() {
 		"imagemagick",
 		"poppler-utils",
 		"libimage-exiftool-perl",
+		"libemail-outlook-message-perl",
+		"libraw-bin",
+		"libheif-examples",
+		"colord-data",
 		"ocrmypdf",
+		"img2pdf",
 		"qpdf",
 		"unzip",
 		"nodejs",
@@ -98,6 +104,16 @@ func (d *Installer) installBlender() {
 	infra.GetLogger().Named(infra.StrInstaller).Infow("✅️  completed", "package", "blender")
 }
 
+func (d *Installer) installFreeCAD() {
+	infra.GetLogger().Named(infra.StrInstaller).Infow("⬇️  installing", "package", "freecad")
+	if err := d.cmd.Exec("apt-get", "install", "-y", "freecad"); err != nil {
+		infra.GetLogger().Error(err)
+		infra.GetLogger().Named(infra.StrInstaller).Infow("❌️  failed", "package", "freecad")
+		return
+	}
+	infra.GetLogger().Named(infra.StrInstaller).Infow("✅️  completed", "package", "freecad")
+}
+
 func (d *Installer) installLibreOffice() {
 	infra.GetLogger().Named(infra.StrInstaller).Infow("⬇️  installing", "package", "libreoffice")
 	packages := []string{
@@ -139,6 +155,7 @@ func (d *Installer) installTesseract() {
 		"tesseract-ocr-fin",
 		"tesseract-ocr-dan",
 		"tesseract-ocr-rus",
+		"tesseract-ocr-hin",
 	}
 	args := append([]string{"install", "-y"}, packages...)
 	if err := d.cmd.Exec("apt-get", args...); err != nil {
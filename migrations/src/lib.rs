@@ -31,6 +31,19 @@ mod m20240913_000001_drop_segmentation_column;
 mod m20241114_000001_drop_user_force_change_password_column;
 mod m20241209_000001_add_user_failed_attempts_column;
 mod m20241209_000001_add_user_locked_until_column;
+mod m20261018_000001_add_snapshot_layout_column;
+mod m20261018_000002_add_workspace_processing_policy_column;
+mod m20261018_000003_add_snapshot_language_confidence_column;
+mod m20261018_000004_add_snapshot_summary_column;
+mod m20261018_000005_add_permission_inheritance;
+mod m20261018_000006_add_permission_schedule_columns;
+mod m20261018_000007_add_roles;
+mod m20261018_000008_add_invitation_options;
+mod m20261018_000009_add_workspace_templates;
+mod m20261018_000010_add_storage_quotas;
+mod m20261018_000011_add_workspace_archival;
+mod m20261018_000012_add_snapshot_metadata_column;
+mod m20261018_000013_add_snapshot_sheets_column;
 
 #[async_trait::async_trait]
 impl MigratorTrait for Migrator {
@@ -54,6 +67,19 @@ impl MigratorTrait for Migrator {
             Box::new(m20241114_000001_drop_user_force_change_password_column::Migration),
             Box::new(m20241209_000001_add_user_failed_attempts_column::Migration),
             Box::new(m20241209_000001_add_user_locked_until_column::Migration),
+            Box::new(m20261018_000001_add_snapshot_layout_column::Migration),
+            Box::new(m20261018_000002_add_workspace_processing_policy_column::Migration),
+            Box::new(m20261018_000003_add_snapshot_language_confidence_column::Migration),
+            Box::new(m20261018_000004_add_snapshot_summary_column::Migration),
+            Box::new(m20261018_000005_add_permission_inheritance::Migration),
+            Box::new(m20261018_000006_add_permission_schedule_columns::Migration),
+            Box::new(m20261018_000007_add_roles::Migration),
+            Box::new(m20261018_000008_add_invitation_options::Migration),
+            Box::new(m20261018_000009_add_workspace_templates::Migration),
+            Box::new(m20261018_000010_add_storage_quotas::Migration),
+            Box::new(m20261018_000011_add_workspace_archival::Migration),
+            Box::new(m20261018_000012_add_snapshot_metadata_column::Migration),
+            Box::new(m20261018_000013_add_snapshot_sheets_column::Migration),
         ]
     }
 }
@@ -0,0 +1,169 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+use sea_orm_migration::prelude::*;
+
+use crate::models::v1::{File, Grouppermission, Userpermission};
+
+#[derive(DeriveMigrationName)]
+pub struct Migration;
+
+#[async_trait::async_trait]
+impl MigrationTrait for Migration {
+    async fn up(
+        &self,
+        manager: &SchemaManager,
+    ) -> Result<(), DbErr> {
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(File::Table)
+                    .add_column(
+                        ColumnDef::new(File::BreaksInheritance)
+                            .boolean()
+                            .not_null()
+                            .default(false),
+                    )
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Userpermission::Table)
+                    .add_column(
+                        ColumnDef::new(Userpermission::Effect)
+                            .text()
+                            .not_null()
+                            .default("allow"),
+                    )
+                    .add_column(
+                        ColumnDef::new(Userpermission::Scope)
+                            .text()
+                            .not_null()
+                            .default("tree"),
+                    )
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Grouppermission::Table)
+                    .add_column(
+                        ColumnDef::new(Grouppermission::Effect)
+                            .text()
+                            .not_null()
+                            .default("allow"),
+                    )
+                    .add_column(
+                        ColumnDef::new(Grouppermission::Scope)
+                            .text()
+                            .not_null()
+                            .default("tree"),
+                    )
+                    .to_owned(),
+            )
+            .await?;
+
+        // Permissions used to be materialized on every file of the tree, and on
+        // every ancestor as 'viewer' so the shared file can be reached. A row only
+        // keeps applying to the subtree if all the children carry the same row,
+        // otherwise it is an ancestor row and must not be inherited.
+        for (table, principal) in [("userpermission", "user_id"), ("grouppermission", "group_id")] {
+            manager
+                .get_connection()
+                .execute_unprepared(&format!(
+                    "UPDATE {table} p SET scope = 'node' FROM file f
+                     WHERE f.id = p.resource_id AND EXISTS (
+                         SELECT 1 FROM file c WHERE c.parent_id = f.id AND NOT EXISTS (
+                             SELECT 1 FROM {table} cp WHERE cp.resource_id = c.id
+                             AND cp.{principal} = p.{principal} AND cp.permission = p.permission))"
+                ))
+                .await?;
+
+            // Rows equal to the one of the parent are now inherited
+            manager
+                .get_connection()
+                .execute_unprepared(&format!(
+                    "DELETE FROM {table} p USING file f, {table} pp
+                     WHERE f.id = p.resource_id AND pp.resource_id = f.parent_id
+                     AND pp.{principal} = p.{principal} AND pp.permission = p.permission
+                     AND pp.scope = 'tree' AND p.scope = 'tree'"
+                ))
+                .await?;
+        }
+
+        Ok(())
+    }
+
+    async fn down(
+        &self,
+        manager: &SchemaManager,
+    ) -> Result<(), DbErr> {
+        // Inherited permissions are materialized back on every file of the tree, deny
+        // entries cannot be represented in the previous model so they are dropped
+        for (table, principal) in [("userpermission", "user_id"), ("grouppermission", "group_id")] {
+            manager
+                .get_connection()
+                .execute_unprepared(&format!(
+                    "WITH RECURSIVE rec (id, {principal}, permission) AS
+                     (SELECT p.resource_id, p.{principal}, p.permission FROM {table} p
+                     JOIN file f ON f.id = p.resource_id WHERE p.scope = 'tree' AND p.effect = 'allow'
+                     UNION SELECT f.id, rec.{principal}, rec.permission FROM rec
+                     JOIN file f ON f.parent_id = rec.id WHERE NOT f.breaks_inheritance)
+                     INSERT INTO {table} (id, {principal}, resource_id, permission, create_time)
+                     SELECT DISTINCT ON (rec.{principal}, rec.id)
+                     md5(random()::text || rec.id), rec.{principal}, rec.id, rec.permission, now()::text FROM rec
+                     ORDER BY rec.{principal}, rec.id,
+                     CASE rec.permission WHEN 'owner' THEN 3 WHEN 'editor' THEN 2 ELSE 1 END DESC
+                     ON CONFLICT DO NOTHING"
+                ))
+                .await?;
+
+            manager
+                .get_connection()
+                .execute_unprepared(&format!("DELETE FROM {table} WHERE effect = 'deny'"))
+                .await?;
+        }
+
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Grouppermission::Table)
+                    .drop_column(Grouppermission::Effect)
+                    .drop_column(Grouppermission::Scope)
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Userpermission::Table)
+                    .drop_column(Userpermission::Effect)
+                    .drop_column(Userpermission::Scope)
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(File::Table)
+                    .drop_column(File::BreaksInheritance)
+                    .to_owned(),
+            )
+            .await?;
+
+        Ok(())
+    }
+}
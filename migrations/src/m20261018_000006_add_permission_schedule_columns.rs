@@ -0,0 +1,121 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+use sea_orm_migration::prelude::*;
+
+use crate::models::v1::{Grouppermission, Userpermission};
+
+#[derive(DeriveMigrationName)]
+pub struct Migration;
+
+#[async_trait::async_trait]
+impl MigrationTrait for Migration {
+    async fn up(
+        &self,
+        manager: &SchemaManager,
+    ) -> Result<(), DbErr> {
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Userpermission::Table)
+                    .add_column(ColumnDef::new(Userpermission::StartsAt).text())
+                    .add_column(ColumnDef::new(Userpermission::ExpiresAt).text())
+                    .add_column(ColumnDef::new(Userpermission::GrantedBy).text())
+                    .add_column(ColumnDef::new(Userpermission::ReminderTime).text())
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Grouppermission::Table)
+                    .add_column(ColumnDef::new(Grouppermission::StartsAt).text())
+                    .add_column(ColumnDef::new(Grouppermission::ExpiresAt).text())
+                    .add_column(ColumnDef::new(Grouppermission::GrantedBy).text())
+                    .add_column(ColumnDef::new(Grouppermission::ReminderTime).text())
+                    .to_owned(),
+            )
+            .await?;
+
+        // The sweeper looks up expired grants periodically
+        manager
+            .create_index(
+                Index::create()
+                    .name("userpermission_expires_at_idx")
+                    .if_not_exists()
+                    .table(Userpermission::Table)
+                    .col(Userpermission::ExpiresAt)
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .create_index(
+                Index::create()
+                    .name("grouppermission_expires_at_idx")
+                    .if_not_exists()
+                    .table(Grouppermission::Table)
+                    .col(Grouppermission::ExpiresAt)
+                    .to_owned(),
+            )
+            .await?;
+
+        Ok(())
+    }
+
+    async fn down(
+        &self,
+        manager: &SchemaManager,
+    ) -> Result<(), DbErr> {
+        manager
+            .drop_index(
+                Index::drop()
+                    .name("grouppermission_expires_at_idx")
+                    .table(Grouppermission::Table)
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .drop_index(
+                Index::drop()
+                    .name("userpermission_expires_at_idx")
+                    .table(Userpermission::Table)
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Grouppermission::Table)
+                    .drop_column(Grouppermission::StartsAt)
+                    .drop_column(Grouppermission::ExpiresAt)
+                    .drop_column(Grouppermission::GrantedBy)
+                    .drop_column(Grouppermission::ReminderTime)
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Userpermission::Table)
+                    .drop_column(Userpermission::StartsAt)
+                    .drop_column(Userpermission::ExpiresAt)
+                    .drop_column(Userpermission::GrantedBy)
+                    .drop_column(Userpermission::ReminderTime)
+                    .to_owned(),
+            )
+            .await?;
+
+        Ok(())
+    }
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+use sea_orm_migration::prelude::*;
+
+use crate::models::v1::{Grouppermission, Organization, Role, Userpermission};
+
+#[derive(DeriveMigrationName)]
+pub struct Migration;
+
+#[async_trait::async_trait]
+impl MigrationTrait for Migration {
+    async fn up(
+        &self,
+        manager: &SchemaManager,
+    ) -> Result<(), DbErr> {
+        manager
+            .create_table(
+                Table::create()
+                    .table(Role::Table)
+                    .if_not_exists()
+                    .col(
+                        ColumnDef::new(Role::Id)
+                            .text()
+                            .primary_key(),
+                    )
+                    .col(
+                        ColumnDef::new(Role::OrganizationId)
+                            .text()
+                            .not_null(),
+                    )
+                    .foreign_key(
+                        ForeignKey::create()
+                            .from(Role::Table, Role::OrganizationId)
+                            .to(Organization::Table, Organization::Id)
+                            .on_delete(ForeignKeyAction::Cascade),
+                    )
+                    .col(
+                        ColumnDef::new(Role::Name)
+                            .text()
+                            .not_null(),
+                    )
+                    .col(
+                        ColumnDef::new(Role::Capabilities)
+                            .json_binary()
+                            .not_null(),
+                    )
+                    .col(
+                        ColumnDef::new(Role::CreateTime)
+                            .text()
+                            .not_null(),
+                    )
+                    .col(ColumnDef::new(Role::UpdateTime).text())
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .create_index(
+                Index::create()
+                    .name("role_organization_id_name_idx")
+                    .if_not_exists()
+                    .unique()
+                    .table(Role::Table)
+                    .col(Role::OrganizationId)
+                    .col(Role::Name)
+                    .to_owned(),
+            )
+            .await?;
+
+        // Roles in use cannot be deleted, the grants have to be changed first
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Userpermission::Table)
+                    .add_column(ColumnDef::new(Userpermission::RoleId).text())
+                    .add_foreign_key(
+                        TableForeignKey::new()
+                            .name("userpermission_role_id_fkey")
+                            .from_tbl(Userpermission::Table)
+                            .from_col(Userpermission::RoleId)
+                            .to_tbl(Role::Table)
+                            .to_col(Role::Id)
+                            .on_delete(ForeignKeyAction::SetNull),
+                    )
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Grouppermission::Table)
+                    .add_column(ColumnDef::new(Grouppermission::RoleId).text())
+                    .add_foreign_key(
+                        TableForeignKey::new()
+                            .name("grouppermission_role_id_fkey")
+                            .from_tbl(Grouppermission::Table)
+                            .from_col(Grouppermission::RoleId)
+                            .to_tbl(Role::Table)
+                            .to_col(Role::Id)
+                            .on_delete(ForeignKeyAction::SetNull),
+                    )
+                    .to_owned(),
+            )
+            .await?;
+
+        Ok(())
+    }
+
+    async fn down(
+        &self,
+        manager: &SchemaManager,
+    ) -> Result<(), DbErr> {
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Grouppermission::Table)
+                    .drop_foreign_key(Alias::new("grouppermission_role_id_fkey"))
+                    .drop_column(Grouppermission::RoleId)
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Userpermission::Table)
+                    .drop_foreign_key(Alias::new("userpermission_role_id_fkey"))
+                    .drop_column(Userpermission::RoleId)
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .drop_table(
+                Table::drop()
+                    .table(Role::Table)
+                    .to_owned(),
+            )
+            .await?;
+
+        Ok(())
+    }
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+use sea_orm_migration::prelude::*;
+
+use crate::models::v1::Invitation;
+
+#[derive(DeriveMigrationName)]
+pub struct Migration;
+
+#[async_trait::async_trait]
+impl MigrationTrait for Migration {
+    async fn up(
+        &self,
+        manager: &SchemaManager,
+    ) -> Result<(), DbErr> {
+        // Existing invitations keep yielding the same membership when accepted
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Invitation::Table)
+                    .add_column(
+                        ColumnDef::new(Invitation::Role)
+                            .text()
+                            .not_null()
+                            .default("guest"),
+                    )
+                    .add_column(ColumnDef::new(Invitation::GroupIds).json_binary())
+                    .add_column(ColumnDef::new(Invitation::ExpiresAt).text())
+                    .to_owned(),
+            )
+            .await?;
+
+        // The sweeper looks up expired invitations periodically
+        manager
+            .create_index(
+                Index::create()
+                    .name("invitation_expires_at_idx")
+                    .if_not_exists()
+                    .table(Invitation::Table)
+                    .col(Invitation::ExpiresAt)
+                    .to_owned(),
+            )
+            .await?;
+
+        Ok(())
+    }
+
+    async fn down(
+        &self,
+        manager: &SchemaManager,
+    ) -> Result<(), DbErr> {
+        manager
+            .drop_index(
+                Index::drop()
+                    .name("invitation_expires_at_idx")
+                    .table(Invitation::Table)
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Invitation::Table)
+                    .drop_column(Invitation::Role)
+                    .drop_column(Invitation::GroupIds)
+                    .drop_column(Invitation::ExpiresAt)
+                    .to_owned(),
+            )
+            .await?;
+
+        Ok(())
+    }
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+use sea_orm_migration::prelude::*;
+
+use crate::models::v1::{Organization, WorkspaceTemplate};
+
+#[derive(DeriveMigrationName)]
+pub struct Migration;
+
+#[async_trait::async_trait]
+impl MigrationTrait for Migration {
+    async fn up(
+        &self,
+        manager: &SchemaManager,
+    ) -> Result<(), DbErr> {
+        // The bucket holds copies of the files, it's null for templates made of folders only
+        manager
+            .create_table(
+                Table::create()
+                    .table(WorkspaceTemplate::Table)
+                    .if_not_exists()
+                    .col(
+                        ColumnDef::new(WorkspaceTemplate::Id)
+                            .text()
+                            .primary_key(),
+                    )
+                    .col(
+                        ColumnDef::new(WorkspaceTemplate::OrganizationId)
+                            .text()
+                            .not_null(),
+                    )
+                    .foreign_key(
+                        ForeignKey::create()
+                            .from(WorkspaceTemplate::Table, WorkspaceTemplate::OrganizationId)
+                            .to(Organization::Table, Organization::Id)
+                            .on_delete(ForeignKeyAction::Cascade),
+                    )
+                    .col(
+                        ColumnDef::new(WorkspaceTemplate::Name)
+                            .text()
+                            .not_null(),
+                    )
+                    .col(ColumnDef::new(WorkspaceTemplate::Bucket).text())
+                    .col(
+                        ColumnDef::new(WorkspaceTemplate::Nodes)
+                            .json_binary()
+                            .not_null(),
+                    )
+                    .col(
+                        ColumnDef::new(WorkspaceTemplate::Grants)
+                            .json_binary()
+                            .not_null(),
+                    )
+                    .col(
+                        ColumnDef::new(WorkspaceTemplate::CreateTime)
+                            .text()
+                            .not_null(),
+                    )
+                    .col(ColumnDef::new(WorkspaceTemplate::UpdateTime).text())
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .create_index(
+                Index::create()
+                    .name("workspace_template_organization_id_idx")
+                    .if_not_exists()
+                    .table(WorkspaceTemplate::Table)
+                    .col(WorkspaceTemplate::OrganizationId)
+                    .to_owned(),
+            )
+            .await?;
+
+        Ok(())
+    }
+
+    async fn down(
+        &self,
+        manager: &SchemaManager,
+    ) -> Result<(), DbErr> {
+        manager
+            .drop_table(
+                Table::drop()
+                    .table(WorkspaceTemplate::Table)
+                    .to_owned(),
+            )
+            .await?;
+
+        Ok(())
+    }
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+use sea_orm_migration::prelude::*;
+
+use crate::models::v1::{Organization, Snapshot, StorageUsage, StorageWarning, Workspace};
+
+#[derive(DeriveMigrationName)]
+pub struct Migration;
+
+#[async_trait::async_trait]
+impl MigrationTrait for Migration {
+    async fn up(
+        &self,
+        manager: &SchemaManager,
+    ) -> Result<(), DbErr> {
+        // Null capacities mean the organization and its members are not capped
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Organization::Table)
+                    .add_column(ColumnDef::new(Organization::StorageCapacity).big_integer())
+                    .add_column(ColumnDef::new(Organization::MemberStorageCapacity).big_integer())
+                    .to_owned(),
+            )
+            .await?;
+
+        // The uploader of a snapshot, existing snapshots are not attributed to anyone
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Snapshot::Table)
+                    .add_column(ColumnDef::new(Snapshot::UserId).text())
+                    .to_owned(),
+            )
+            .await?;
+
+        // The usage ledger holds the bytes of the originals per workspace, uploader,
+        // file type, and whether they belong to the latest version or to the history
+        manager
+            .create_table(
+                Table::create()
+                    .table(StorageUsage::Table)
+                    .if_not_exists()
+                    .col(
+                        ColumnDef::new(StorageUsage::WorkspaceId)
+                            .text()
+                            .not_null(),
+                    )
+                    .foreign_key(
+                        ForeignKey::create()
+                            .from(StorageUsage::Table, StorageUsage::WorkspaceId)
+                            .to(Workspace::Table, Workspace::Id)
+                            .on_delete(ForeignKeyAction::Cascade),
+                    )
+                    .col(
+                        ColumnDef::new(StorageUsage::UserId)
+                            .text()
+                            .not_null()
+                            .default(""),
+                    )
+                    .col(
+                        ColumnDef::new(StorageUsage::Category)
+                            .text()
+                            .not_null(),
+                    )
+                    .col(
+                        ColumnDef::new(StorageUsage::History)
+                            .boolean()
+                            .not_null(),
+                    )
+                    .col(
+                        ColumnDef::new(StorageUsage::Bytes)
+                            .big_integer()
+                            .not_null(),
+                    )
+                    .primary_key(
+                        Index::create()
+                            .col(StorageUsage::WorkspaceId)
+                            .col(StorageUsage::UserId)
+                            .col(StorageUsage::Category)
+                            .col(StorageUsage::History),
+                    )
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .create_index(
+                Index::create()
+                    .name("storage_usage_user_id_idx")
+                    .if_not_exists()
+                    .table(StorageUsage::Table)
+                    .col(StorageUsage::UserId)
+                    .to_owned(),
+            )
+            .await?;
+
+        // The highest threshold a warning was sent for, the user is empty for the organization itself
+        manager
+            .create_table(
+                Table::create()
+                    .table(StorageWarning::Table)
+                    .if_not_exists()
+                    .col(
+                        ColumnDef::new(StorageWarning::OrganizationId)
+                            .text()
+                            .not_null(),
+                    )
+                    .foreign_key(
+                        ForeignKey::create()
+                            .from(StorageWarning::Table, StorageWarning::OrganizationId)
+                            .to(Organization::Table, Organization::Id)
+                            .on_delete(ForeignKeyAction::Cascade),
+                    )
+                    .col(
+                        ColumnDef::new(StorageWarning::UserId)
+                            .text()
+                            .not_null()
+                            .default(""),
+                    )
+                    .col(
+                        ColumnDef::new(StorageWarning::Threshold)
+                            .integer()
+                            .not_null(),
+                    )
+                    .col(ColumnDef::new(StorageWarning::UpdateTime).text())
+                    .primary_key(
+                        Index::create()
+                            .col(StorageWarning::OrganizationId)
+                            .col(StorageWarning::UserId),
+                    )
+                    .to_owned(),
+            )
+            .await?;
+
+        Ok(())
+    }
+
+    async fn down(
+        &self,
+        manager: &SchemaManager,
+    ) -> Result<(), DbErr> {
+        manager
+            .drop_table(
+                Table::drop()
+                    .table(StorageWarning::Table)
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .drop_table(
+                Table::drop()
+                    .table(StorageUsage::Table)
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Snapshot::Table)
+                    .drop_column(Snapshot::UserId)
+                    .to_owned(),
+            )
+            .await?;
+
+        manager
+            .alter_table(
+                Table::alter()
+                    .table(Organization::Table)
+                    .drop_column(Organization::StorageCapacity)
+                    .drop_column(Organization::MemberStorageCapacity)
+                    .to_owned(),
+            )
+            .await?;
+
+        Ok(())
+    }
+}
@@ -11,11 +11,15 @@ mod file;
 mod group;
 mod invitation;
 mod organization;
+mod role;
 mod snapshot;
+mod storage;
 mod task;
 mod user;
 mod workspace;
+mod workspace_template;
 
 pub use {
-    file::*, group::*, invitation::*, organization::*, snapshot::*, task::*, user::*, workspace::*,
+    file::*, group::*, invitation::*, organization::*, role::*, snapshot::*, storage::*, task::*, user::*, workspace::*,
+    workspace_template::*,
 };
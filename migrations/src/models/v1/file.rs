@@ -18,6 +18,7 @@ pub enum File {
     ParentId,
     WorkspaceId,
     SnapshotId,
+    BreaksInheritance,
     CreateTime,
     UpdateTime,
 }
@@ -37,6 +38,13 @@ pub enum Userpermission {
     UserId,
     ResourceId,
     Permission,
+    Effect,
+    Scope,
+    StartsAt,
+    ExpiresAt,
+    GrantedBy,
+    ReminderTime,
+    RoleId,
     CreateTime,
 }
 
@@ -47,5 +55,12 @@ pub enum Grouppermission {
     GroupId,
     ResourceId,
     Permission,
+    Effect,
+    Scope,
+    StartsAt,
+    ExpiresAt,
+    GrantedBy,
+    ReminderTime,
+    RoleId,
     CreateTime,
 }
@@ -17,6 +17,9 @@ pub enum Invitation {
     OwnerId,
     Email,
     Status,
+    Role,
+    GroupIds,
+    ExpiresAt,
     CreateTime,
     UpdateTime,
 }
@@ -14,6 +14,8 @@ pub enum Organization {
     Table,
     Id,
     Name,
+    StorageCapacity,
+    MemberStorageCapacity,
     CreateTime,
     UpdateTime,
 }
@@ -19,12 +19,18 @@ pub enum Snapshot {
     Text,
     Ocr,
     Entities,
+    Layout,
+    Summary,
+    Metadata,
+    Sheets,
     Mosaic,
     Segmentation,
     Thumbnail,
     Language,
+    LanguageConfidence,
     Status,
     TaskId,
+    UserId,
     CreateTime,
     UpdateTime,
 }
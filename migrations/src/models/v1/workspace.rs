@@ -18,6 +18,9 @@ pub enum Workspace {
     StorageCapacity,
     RootId,
     Bucket,
+    ProcessingPolicy,
+    ArchiveStatus,
+    ArchiveTime,
     CreateTime,
     UpdateTime,
 }
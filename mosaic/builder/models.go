@@ -182,13 +182,15 @@ type Image struct {
 	file string
 }
 
+// NewImage applies the EXIF orientation, color management is left to the caller
+// which is expected to provide 8-bit sRGB images.
 func NewImage(file string) (*Image, error) {
 	img, err := imgio.Open(file)
 	if err != nil {
 		return nil, err
 	}
 	return &Image{
-		img:  img,
+		img:  applyOrientation(img, readOrientation(file)),
 		file: file,
 	}, nil
 }
@@ -0,0 +1,142 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+
+package builder
+
+import (
+	"bufio"
+	"encoding/binary"
+	"image"
+	"io"
+	"os"
+
+	"github.com/kouprlabs/voltaserve/mosaic/infra"
+)
+
+const exifTagOrientation = 0x0112
+
+// readOrientation returns the EXIF orientation of a JPEG, from 1 to 8,
+// or 1 when the file is not a JPEG or carries no orientation.
+func readOrientation(file string) int {
+	f, err := os.Open(file)
+	if err != nil {
+		return 1
+	}
+	defer func(f *os.File) {
+		if err := f.Close(); err != nil {
+			infra.GetLogger().Error(err)
+		}
+	}(f)
+	r := bufio.NewReader(f)
+	var marker [2]byte
+	if _, err := io.ReadFull(r, marker[:]); err != nil || marker != [2]byte{0xFF, 0xD8} {
+		return 1
+	}
+	for {
+		if _, err := io.ReadFull(r, marker[:]); err != nil || marker[0] != 0xFF {
+			return 1
+		}
+		// Metadata segments come before the start of scan
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			return 1
+		}
+		var size [2]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(size[:])) - 2
+		if length < 0 {
+			return 1
+		}
+		if marker[1] != 0xE1 {
+			if _, err := r.Discard(length); err != nil {
+				return 1
+			}
+			continue
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return 1
+		}
+		if orientation, ok := parseOrientation(data); ok {
+			return orientation
+		}
+	}
+}
+
+// parseOrientation looks for the orientation in the first IFD of an APP1 segment.
+func parseOrientation(data []byte) (int, bool) {
+	if len(data) < 14 || string(data[:6]) != "Exif\x00\x00" {
+		return 0, false
+	}
+	tiff := data[6:]
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, false
+	}
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset < 0 || offset+2 > len(tiff) {
+		return 0, false
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0, false
+		}
+		if order.Uint16(tiff[entry:]) == exifTagOrientation {
+			value := int(order.Uint16(tiff[entry+8:]))
+			return value, value >= 1 && value <= 8
+		}
+	}
+	return 0, false
+}
+
+// applyOrientation transforms the pixels so the image displays upright, the
+// orientations from 5 to 8 swap the width and the height.
+func applyOrientation(src image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if orientation >= 5 {
+		dst = image.NewRGBA(image.Rect(0, 0, height, width))
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = width-1-x, y
+			case 3:
+				dx, dy = width-1-x, height-1-y
+			case 4:
+				dx, dy = x, height-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = height-1-y, x
+			case 7:
+				dx, dy = height-1-y, width-1-x
+			case 8:
+				dx, dy = y, width-1-x
+			}
+			dst.Set(dx, dy, src.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -14,7 +14,8 @@ import { getAccessTokenOrRedirect } from '@/client/token'
 import { getConfig } from '@/config/config'
 import { encodeQuery } from '@/lib/helpers/query'
 import { Group } from './group'
-import { PermissionType } from './permission'
+import { PermissionEffect, PermissionType } from './permission'
+import { Capability } from './role'
 import { Snapshot } from './snapshot'
 
 export enum FileType {
@@ -42,7 +43,9 @@ export type File = {
   type: FileType
   parentId: string
   permission: PermissionType
+  capabilities: Capability[]
   isShared?: boolean
+  breaksInheritance: boolean
   snapshot?: Snapshot
   createTime: string
   updateTime?: string
@@ -61,12 +64,144 @@ export type FileUserPermission = {
   id: string
   user: AuthUser
   permission: PermissionType
+  effect: PermissionEffect
+  startsAt?: string
+  expiresAt?: string
+  roleId?: string
+  inheritedFrom?: string
+}
+
+export enum FileAccessOrigin {
+  File = 'file',
+  Folder = 'folder',
+  Workspace = 'workspace',
+}
+
+export enum FileAccessReason {
+  NotStarted = 'not_started',
+  Expired = 'expired',
+  NotInherited = 'not_inherited',
+}
+
+export type FileAccessGrant = {
+  permission: PermissionType
+  effect: PermissionEffect
+  roleId?: string
+  group?: Group
+  fileId: string
+  fileName: string
+  origin: FileAccessOrigin
+  startsAt?: string
+  expiresAt?: string
+  isActive: boolean
+  reason?: FileAccessReason
+}
+
+export type FileAccess = {
+  user: AuthUser
+  permission: PermissionType
+  capabilities: Capability[]
+  grants: FileAccessGrant[]
+  inheritanceBrokenAt?: string
+  isOrganizationAdmin: boolean
 }
 
 export type FileGroupPermission = {
   id: string
   group: Group
   permission: PermissionType
+  effect: PermissionEffect
+  startsAt?: string
+  expiresAt?: string
+  roleId?: string
+  inheritedFrom?: string
+}
+
+export type FileTextSearchResult = {
+  query: string
+  hits: FileTextSearchHit[]
+  totalHits: number
+  isTruncated: boolean
+  totalPages: number
+  matchedPages: number[]
+}
+
+export type FileTextSearchHit = {
+  page: number
+  pageWidth: number
+  pageHeight: number
+  snippet: string
+  boxes: FileTextSearchBox[]
+}
+
+export type FileTextSearchBox = {
+  xMin: number
+  yMin: number
+  xMax: number
+  yMax: number
+}
+
+export type FilePageResolution = 'standard' | 'high'
+
+export type FileWaveform = {
+  duration: number
+  peaks: number[]
+}
+
+export type FileMediaMetadata = {
+  captureTime?: string
+  cameraMake?: string
+  cameraModel?: string
+  lens?: string
+  location?: FileGeoLocation
+  duration?: number
+  videoCodec?: string
+  audioCodec?: string
+  sampleRate?: number
+  channels?: number
+  bitrate?: number
+  keywords?: string[]
+  caption?: string
+  creator?: string
+  copyright?: string
+  rating?: number
+  email?: FileEmailMetadata
+}
+
+export type FileEmailMetadata = {
+  from?: string
+  to?: string[]
+  cc?: string[]
+  subject?: string
+  date?: string
+  messageId?: string
+  attachments?: string[]
+  messages: number
+}
+
+export type FileSheet = {
+  name: string
+  columns: FileSheetColumn[]
+  rows: FileSheetCell[][]
+  offset: number
+  limit: number
+  totalRows: number
+  truncated: boolean
+}
+
+export type FileSheetColumn = {
+  name: string
+  type: FileSheetColumnType
+}
+
+export type FileSheetColumnType = 'string' | 'number' | 'boolean' | 'date'
+
+export type FileSheetCell = string | number | boolean | null
+
+export type FileGeoLocation = {
+  latitude: number
+  longitude: number
+  altitude?: number
 }
 
 export type FileQuery = {
@@ -76,6 +211,11 @@ export type FileQuery = {
   createTimeBefore?: number
   updateTimeAfter?: number
   updateTimeBefore?: number
+  camera?: string
+  captureTimeAfter?: number
+  captureTimeBefore?: number
+  captureMonth?: number
+  minRating?: number
 }
 
 export type FileListOptions = {
@@ -123,7 +263,10 @@ export type FileFilePatchNameOptions = {
 export type FileGrantUserPermissionOptions = {
   ids: string[]
   userId: string
-  permission: string
+  permission?: string
+  roleId?: string
+  startsAt?: string
+  expiresAt?: string
 }
 
 export type FileRevokeUserPermissionOptions = {
@@ -134,7 +277,10 @@ export type FileRevokeUserPermissionOptions = {
 export type FileGrantGroupPermissionOptions = {
   ids: string[]
   groupId: string
-  permission: string
+  permission?: string
+  roleId?: string
+  startsAt?: string
+  expiresAt?: string
 }
 
 export type FileRevokeGroupPermissionOptions = {
@@ -142,6 +288,27 @@ export type FileRevokeGroupPermissionOptions = {
   groupId: string
 }
 
+export type FileDenyUserPermissionOptions = {
+  ids: string[]
+  userId: string
+  permission: string
+}
+
+export type FileDenyGroupPermissionOptions = {
+  ids: string[]
+  groupId: string
+  permission: string
+}
+
+export type FileBreakPermissionInheritanceOptions = {
+  ids: string[]
+  copyPermissions: boolean
+}
+
+export type FileRestorePermissionInheritanceOptions = {
+  ids: string[]
+}
+
 export type FileCreateOptions = {
   type: FileType
   workspaceId: string
@@ -391,6 +558,99 @@ export class FileAPI {
     )
   }
 
+  static useSearchText(
+    id: string | null | undefined,
+    query: string | null | undefined,
+    swrOptions?: SWRConfiguration,
+  ) {
+    const url = `/files/${id}/search?${new URLSearchParams({ q: query || '' })}`
+    return useSWR<FileTextSearchResult>(
+      id && query ? url : null,
+      () =>
+        apiFetcher({ url, method: 'GET' }) as Promise<FileTextSearchResult>,
+      swrOptions,
+    )
+  }
+
+  static useGetMetadata(
+    id: string | null | undefined,
+    swrOptions?: SWRConfiguration,
+  ) {
+    const url = `/files/${id}/metadata`
+    return useSWR<FileMediaMetadata>(
+      id ? url : null,
+      () => apiFetcher({ url, method: 'GET' }) as Promise<FileMediaMetadata>,
+      swrOptions,
+    )
+  }
+
+  static useGetSheet(
+    id: string | null | undefined,
+    sheet: number,
+    offset: number,
+    limit: number,
+    swrOptions?: SWRConfiguration,
+  ) {
+    const params = new URLSearchParams({
+      offset: offset.toString(),
+      limit: limit.toString(),
+    })
+    const url = `/files/${id}/sheets/${sheet}?${params}`
+    return useSWR<FileSheet>(
+      id ? url : null,
+      () => apiFetcher({ url, method: 'GET' }) as Promise<FileSheet>,
+      swrOptions,
+    )
+  }
+
+  static useGetWaveform(
+    id: string | null | undefined,
+    swrOptions?: SWRConfiguration,
+  ) {
+    const params = new URLSearchParams({
+      access_token: getAccessTokenOrRedirect(),
+    })
+    const url = `/files/${id}/waveform.json?${params}`
+    return useSWR<FileWaveform>(
+      id ? url : null,
+      () => apiFetcher({ url, method: 'GET' }) as Promise<FileWaveform>,
+      swrOptions,
+    )
+  }
+
+  static getTranscriptURL(id: string, accessToken: string) {
+    const params = new URLSearchParams({ access_token: accessToken })
+    return `${getConfig().apiURL}/files/${id}/transcript.vtt?${params}`
+  }
+
+  static getPageURL(
+    id: string,
+    page: number,
+    accessToken: string,
+    resolution: FilePageResolution = 'standard',
+  ) {
+    const params = new URLSearchParams({
+      resolution,
+      access_token: accessToken,
+    })
+    return `${getConfig().apiURL}/files/${id}/pages/${page}.webp?${params}`
+  }
+
+  static getPageThumbnailURL(id: string, page: number, accessToken: string) {
+    const params = new URLSearchParams({ access_token: accessToken })
+    return `${getConfig().apiURL}/files/${id}/page_thumbnails/${page}.webp?${params}`
+  }
+
+  static getSlideURL(
+    id: string,
+    slide: number,
+    extension: string,
+    accessToken: string,
+  ) {
+    const params = new URLSearchParams({ access_token: accessToken })
+    return `${getConfig().apiURL}/files/${id}/slides/${slide}${extension}?${params}`
+  }
+
   static async grantUserPermission(options: FileGrantUserPermissionOptions) {
     return apiFetcher({
       url: `/files/grant_user_permission`,
@@ -425,6 +685,42 @@ export class FileAPI {
     })
   }
 
+  static async denyUserPermission(options: FileDenyUserPermissionOptions) {
+    return apiFetcher({
+      url: `/files/deny_user_permission`,
+      method: 'POST',
+      body: JSON.stringify(options),
+    })
+  }
+
+  static async denyGroupPermission(options: FileDenyGroupPermissionOptions) {
+    return apiFetcher({
+      url: `/files/deny_group_permission`,
+      method: 'POST',
+      body: JSON.stringify(options),
+    })
+  }
+
+  static async breakPermissionInheritance(
+    options: FileBreakPermissionInheritanceOptions,
+  ) {
+    return apiFetcher({
+      url: `/files/break_permission_inheritance`,
+      method: 'POST',
+      body: JSON.stringify(options),
+    })
+  }
+
+  static async restorePermissionInheritance(
+    options: FileRestorePermissionInheritanceOptions,
+  ) {
+    return apiFetcher({
+      url: `/files/restore_permission_inheritance`,
+      method: 'POST',
+      body: JSON.stringify(options),
+    })
+  }
+
   static useGetUserPermissions(
     id: string | null | undefined,
     swrOptions?: SWRConfiguration,
@@ -449,4 +745,17 @@ export class FileAPI {
       swrOptions,
     )
   }
+
+  static useGetAccess(
+    id: string | null | undefined,
+    userId: string | null | undefined,
+    swrOptions?: SWRConfiguration,
+  ) {
+    const url = `/files/${id}/access?${new URLSearchParams({ user_id: userId || '' })}`
+    return useSWR<FileAccess>(
+      id && userId ? url : null,
+      () => apiFetcher({ url, method: 'GET' }) as Promise<FileAccess>,
+      swrOptions,
+    )
+  }
 }
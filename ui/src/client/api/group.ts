@@ -28,6 +28,7 @@ export type Group = {
   name: string
   organization: Organization
   permission: PermissionType
+  subgroups?: string[]
   createTime: string
   updateTime?: string
 }
@@ -61,12 +62,22 @@ export type GroupPatchNameOptions = {
 
 export type GroupAddMemberOptions = {
   userId: string
+  startsAt?: string
+  expiresAt?: string
 }
 
 export type GroupRemoveMemberOptions = {
   userId: string
 }
 
+export type GroupAddSubgroupOptions = {
+  groupId: string
+}
+
+export type GroupRemoveSubgroupOptions = {
+  groupId: string
+}
+
 type GroupListQueryParams = {
   page?: string
   size?: string
@@ -156,4 +167,32 @@ export class GroupAPI {
       body: JSON.stringify(options),
     })
   }
+
+  static useListSubgroups(
+    id: string | null | undefined,
+    swrOptions?: SWRConfiguration,
+  ) {
+    const url = `/groups/${id}/subgroups`
+    return useSWR<Group[]>(
+      id ? url : null,
+      () => apiFetcher({ url, method: 'GET' }) as Promise<Group[]>,
+      swrOptions,
+    )
+  }
+
+  static addSubgroup(id: string, options: GroupAddSubgroupOptions) {
+    return apiFetcher({
+      url: `/groups/${id}/subgroups`,
+      method: 'POST',
+      body: JSON.stringify(options),
+    })
+  }
+
+  static removeSubgroup(id: string, options: GroupRemoveSubgroupOptions) {
+    return apiFetcher({
+      url: `/groups/${id}/subgroups`,
+      method: 'DELETE',
+      body: JSON.stringify(options),
+    })
+  }
 }
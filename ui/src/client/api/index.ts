@@ -15,8 +15,10 @@ export * from './invitation'
 export * from './mosaic'
 export * from './organization'
 export * from './permission'
+export * from './role'
 export * from './snapshot'
 export * from './storage'
 export * from './task'
 export * from './user'
 export * from './workspace'
+export * from './workspace-template'
@@ -9,10 +9,12 @@
 // AGPL-3.0-only in the root of this repository.
 import useSWR, { SWRConfiguration } from 'swr'
 import { apiFetcher } from '@/client/fetcher'
+import { File, FileTextSearchBox } from './file'
 import { Snapshot } from './snapshot'
 
 export type InsightsCreateOptions = {
-  languageId: string
+  // Detected automatically when omitted
+  languageId?: string
 }
 
 export type InsightsLanguage = {
@@ -21,6 +23,46 @@ export type InsightsLanguage = {
   name: string
 }
 
+export type InsightsKeyphrase = {
+  text: string
+  score: number
+}
+
+export type InsightsSummary = {
+  sentences: string[]
+  keyphrases: InsightsKeyphrase[]
+  provider: string
+}
+
+export type InsightsPIIType =
+  | 'email'
+  | 'phone'
+  | 'iban'
+  | 'national_id'
+  | 'person'
+  | 'organization'
+  | 'location'
+
+export type InsightsPIIFinding = {
+  id: string
+  type: InsightsPIIType
+  text: string
+  page: number
+  pageWidth: number
+  pageHeight: number
+  boxes: FileTextSearchBox[]
+}
+
+export type InsightsPIIList = {
+  data: InsightsPIIFinding[]
+  totalElements: number
+}
+
+export type InsightsRedactOptions = {
+  findingIds?: string[]
+  types?: InsightsPIIType[]
+}
+
 export type InsightsInfo = {
   isAvailable: boolean
   isOutdated: boolean
@@ -115,6 +157,43 @@ export class InsightsAPI {
     }) as Promise<InsightsInfo>
   }
 
+  static useGetSummary(
+    id: string | null | undefined,
+    swrOptions?: SWRConfiguration,
+  ) {
+    const url = `/insights/${id}/summary`
+    return useSWR<InsightsSummary>(
+      id ? url : null,
+      () =>
+        apiFetcher({
+          url,
+          method: 'GET',
+          showError: false,
+        }) as Promise<InsightsSummary>,
+      swrOptions,
+    )
+  }
+
+  static useListPII(
+    id: string | null | undefined,
+    swrOptions?: SWRConfiguration,
+  ) {
+    const url = `/insights/${id}/pii`
+    return useSWR<InsightsPIIList>(
+      id ? url : null,
+      () => apiFetcher({ url, method: 'GET' }) as Promise<InsightsPIIList>,
+      swrOptions,
+    )
+  }
+
+  static redact(id: string, options: InsightsRedactOptions) {
+    return apiFetcher({
+      url: `/insights/${id}/redact`,
+      method: 'POST',
+      body: JSON.stringify(options),
+    }) as Promise<File>
+  }
+
   static useGetLanguages(swrOptions?: SWRConfiguration) {
     const url = `/insights/languages`
     return useSWR<InsightsLanguage[]>(
@@ -10,7 +10,7 @@
 import useSWR, { SWRConfiguration } from 'swr'
 import { apiFetcher } from '@/client/fetcher'
 import { AuthUser } from '@/client/idp/user'
-import { Organization } from './organization'
+import { Organization, OrganizationRole } from './organization'
 
 export enum InvitationSortBy {
   Email = 'email',
@@ -23,7 +23,7 @@ export enum InvitationSortOrder {
   Desc = 'desc',
 }
 
-export type InvitationStatus = 'pending' | 'accepted' | 'declined'
+export type InvitationStatus = 'pending' | 'accepted' | 'declined' | 'expired'
 
 export type Invitation = {
   id: string
@@ -31,6 +31,9 @@ export type Invitation = {
   email: string
   organization?: Organization
   status: InvitationStatus
+  role: OrganizationRole
+  groupIds?: string[]
+  expiresAt?: string
   createTime: string
   updateTime?: string
 }
@@ -43,11 +46,33 @@ export type InvitationList = {
   size: number
 }
 
-export type InvitationCreateOptions = {
+export type InvitationOptions = {
+  role?: OrganizationRole
+  groupIds?: string[]
+  expiresAt?: string
+}
+
+export type InvitationCreateOptions = InvitationOptions & {
   organizationId: string
   emails: string[]
 }
 
+export type InvitationImportStatus = 'invited' | 'invalid' | 'existing'
+
+export type InvitationImportResult = {
+  row: number
+  email: string
+  status: InvitationImportStatus
+  invitationId?: string
+}
+
+type InvitationImportQueryParams = {
+  organization_id: string
+  role?: string
+  group_ids?: string
+  expires_at?: string
+}
+
 export type InvitationListOptions = {
   organizationId?: string
   size?: number
@@ -156,4 +181,39 @@ export class InvitationAPI {
       method: 'POST',
     })
   }
+
+  static async import(
+    organizationId: string,
+    file: Blob,
+    options?: InvitationOptions,
+  ) {
+    const params: InvitationImportQueryParams = {
+      organization_id: organizationId,
+    }
+    if (options?.role) {
+      params.role = options.role
+    }
+    if (options?.groupIds?.length) {
+      params.group_ids = options.groupIds.join(',')
+    }
+    if (options?.expiresAt) {
+      params.expires_at = options.expiresAt
+    }
+    const body = new FormData()
+    body.append('file', file)
+    return apiFetcher({
+      url: `/invitations/import?${new URLSearchParams(params)}`,
+      method: 'POST',
+      body,
+      contentType: 'multipart/form-data',
+    }) as Promise<InvitationImportResult[]>
+  }
+
+  static async revokePending(organizationId: string) {
+    const params = new URLSearchParams({ organization_id: organizationId })
+    return apiFetcher({
+      url: `/invitations/outgoing/revoke?${params}`,
+      method: 'POST',
+    })
+  }
 }
@@ -9,7 +9,10 @@
 // AGPL-3.0-only in the root of this repository.
 import useSWR, { SWRConfiguration } from 'swr'
 import { apiFetcher } from '@/client/fetcher'
+import { AuthUser } from '@/client/idp/user'
+import { FileAccessGrant } from './file'
 import { PermissionType } from './permission'
+import { Task } from './task'
 
 export enum OrganizationSortBy {
   Name = 'name',
@@ -22,14 +25,57 @@ export enum OrganizationSortOrder {
   Desc = 'desc',
 }
 
+export enum OrganizationRole {
+  Admin = 'admin',
+  Member = 'member',
+  Guest = 'guest',
+}
+
 export type Organization = {
   id: string
   name: string
   permission: PermissionType
+  role?: OrganizationRole
+  storageCapacity?: number
+  memberStorageCapacity?: number
   createTime: string
   updateTime?: string
 }
 
+export type WorkspaceAccessUser = {
+  user: AuthUser
+  permission: PermissionType
+  isPartial: boolean
+  grants: FileAccessGrant[]
+}
+
+export type WorkspaceAccess = {
+  workspaceId: string
+  workspaceName: string
+  users: WorkspaceAccessUser[]
+}
+
+export type OwnedResource = {
+  id: string
+  name: string
+  isSoleOwner: boolean
+}
+
+export type OrganizationOwnership = {
+  workspaces: OwnedResource[]
+  groups: OwnedResource[]
+  files: OwnedResource[]
+  invitations: number
+  tasks: number
+  soleOwnerCount: number
+}
+
+export type OrganizationTransferOwnershipOptions = {
+  userId: string
+  targetUserId: string
+  demoteMember?: boolean
+}
+
 export type OrganizationList = {
   data: Organization[]
   totalPages: number
@@ -55,10 +101,20 @@ export type OrganizationPatchNameOptions = {
   name: string
 }
 
+export type OrganizationPatchStorageQuotaOptions = {
+  storageCapacity?: number | null
+  memberStorageCapacity?: number | null
+}
+
 export type OrganizationRemoveMemberOptions = {
   userId: string
 }
 
+export type OrganizationPatchMemberRoleOptions = {
+  userId: string
+  role: OrganizationRole
+}
+
 type OrganizationListQueryParams = {
   page?: string
   size?: string
@@ -134,6 +190,17 @@ export class OrganizationAPI {
     }) as Promise<Organization>
   }
 
+  static patchStorageQuota(
+    id: string,
+    options: OrganizationPatchStorageQuotaOptions,
+  ) {
+    return apiFetcher({
+      url: `/organizations/${id}/storage_quota`,
+      method: 'PATCH',
+      body: JSON.stringify(options),
+    }) as Promise<Organization>
+  }
+
   static async delete(id: string) {
     return apiFetcher({
       url: `/organizations/${id}`,
@@ -158,4 +225,57 @@ export class OrganizationAPI {
       body: JSON.stringify(options),
     })
   }
+
+  static async patchMemberRole(
+    id: string,
+    options: OrganizationPatchMemberRoleOptions,
+  ) {
+    return apiFetcher({
+      url: `/organizations/${id}/members/role`,
+      method: 'PATCH',
+      body: JSON.stringify(options),
+    })
+  }
+
+  static useGetOwnership(
+    id: string | null | undefined,
+    userId: string | null | undefined,
+    swrOptions?: SWRConfiguration,
+  ) {
+    const params = new URLSearchParams({ user_id: userId ?? '' })
+    const url = `/organizations/${id}/members/ownership?${params}`
+    return useSWR<OrganizationOwnership>(
+      id && userId ? url : null,
+      () =>
+        apiFetcher({ url, method: 'GET' }) as Promise<OrganizationOwnership>,
+      swrOptions,
+    )
+  }
+
+  static async transferOwnership(
+    id: string,
+    options: OrganizationTransferOwnershipOptions,
+  ) {
+    return apiFetcher({
+      url: `/organizations/${id}/members/transfer`,
+      method: 'POST',
+      body: JSON.stringify(options),
+    }) as Promise<Task>
+  }
+
+  static useGetWorkspaceAccess(
+    id: string | null | undefined,
+    workspaceId?: string,
+    swrOptions?: SWRConfiguration,
+  ) {
+    const query = workspaceId
+      ? `?${new URLSearchParams({ workspace_id: workspaceId })}`
+      : ''
+    const url = `/organizations/${id}/access${query}`
+    return useSWR<WorkspaceAccess[]>(
+      id ? url : null,
+      () => apiFetcher({ url, method: 'GET' }) as Promise<WorkspaceAccess[]>,
+      swrOptions,
+    )
+  }
 }
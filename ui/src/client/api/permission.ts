@@ -15,6 +15,8 @@ export const OWNER_PERMISSION = 'owner'
 
 export type PermissionType = 'viewer' | 'editor' | 'owner' | 'none'
 
+export type PermissionEffect = 'allow' | 'deny'
+
 export function gtViewerPermission(permission: PermissionType): boolean {
   return (
     getPermissionWeight(permission) > getPermissionWeight(VIEWER_PERMISSION)
@@ -0,0 +1,114 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+import useSWR, { SWRConfiguration } from 'swr'
+import { apiFetcher } from '@/client/fetcher'
+import { PermissionType } from './permission'
+
+export enum Capability {
+  List = 'list',
+  Preview = 'preview',
+  DownloadOriginal = 'download_original',
+  Upload = 'upload',
+  Rename = 'rename',
+  Move = 'move',
+  Delete = 'delete',
+  Share = 'share',
+  ManageVersions = 'manage_versions',
+  RunInsights = 'run_insights',
+  CreateMosaic = 'create_mosaic',
+}
+
+export type Role = {
+  id: string
+  organizationId: string
+  name: string
+  capabilities: Capability[]
+  permission: PermissionType
+  createTime: string
+  updateTime?: string
+}
+
+export type RoleCreateOptions = {
+  organizationId: string
+  name: string
+  capabilities: Capability[]
+}
+
+export type RolePatchNameOptions = {
+  name: string
+}
+
+export type RolePatchCapabilitiesOptions = {
+  capabilities: Capability[]
+}
+
+export class RoleAPI {
+  static create(options: RoleCreateOptions) {
+    return apiFetcher({
+      url: `/roles`,
+      method: 'POST',
+      body: JSON.stringify(options),
+    }) as Promise<Role>
+  }
+
+  static patchName(id: string, options: RolePatchNameOptions) {
+    return apiFetcher({
+      url: `/roles/${id}/name`,
+      method: 'PATCH',
+      body: JSON.stringify(options),
+    }) as Promise<Role>
+  }
+
+  static patchCapabilities(id: string, options: RolePatchCapabilitiesOptions) {
+    return apiFetcher({
+      url: `/roles/${id}/capabilities`,
+      method: 'PATCH',
+      body: JSON.stringify(options),
+    }) as Promise<Role>
+  }
+
+  static useGet(id: string | null | undefined, swrOptions?: SWRConfiguration) {
+    const url = `/roles/${id}`
+    return useSWR<Role>(
+      id ? url : null,
+      () => apiFetcher({ url, method: 'GET' }) as Promise<Role>,
+      swrOptions,
+    )
+  }
+
+  static useList(
+    organizationId: string | null | undefined,
+    swrOptions?: SWRConfiguration,
+  ) {
+    const params = new URLSearchParams({
+      organization_id: organizationId ?? '',
+    })
+    const url = `/roles?${params}`
+    return useSWR<Role[]>(
+      organizationId ? url : null,
+      () => apiFetcher({ url, method: 'GET' }) as Promise<Role[]>,
+      swrOptions,
+    )
+  }
+
+  static delete(id: string) {
+    return apiFetcher({
+      url: `/roles/${id}`,
+      method: 'DELETE',
+    })
+  }
+}
+
+export function hasCapability(
+  capabilities: Capability[] | undefined,
+  capability: Capability,
+): boolean {
+  return capabilities?.includes(capability) ?? false
+}
@@ -20,9 +20,14 @@ export type Snapshot = {
   ocr?: SnapshotDownload
   text?: SnapshotDownload
   entities?: SnapshotDownload
+  layout?: SnapshotDownload
+  summary?: SnapshotDownload
+  metadata?: SnapshotDownload
+  sheets?: SnapshotDownload
   mosaic?: SnapshotDownload
   thumbnail?: SnapshotDownload
   language?: string
+  languageConfidence?: number
   isActive: boolean
   task?: SnapshotTaskInfo
   createTime: string
@@ -75,17 +80,36 @@ export type SnapshotDownload = {
   size?: number
   image?: SnapshotImageProps
   document?: SnapshotDocumentProps
+  model?: SnapshotModelProps
+  workbook?: SnapshotWorkbookProps
+  audio?: SnapshotAudioProps
+}
+
+export type SnapshotAudioProps = {
+  waveform?: SnapshotWaveformProps
+  transcript?: SnapshotTranscriptProps
+}
+
+export type SnapshotWaveformProps = {
+  peaks: number
+  extension: string
+}
+
+export type SnapshotTranscriptProps = {
+  extension: string
 }
 
 export type SnapshotImageProps = {
   width: number
   height: number
+  colorSpace?: string
   zoomLevels?: SnapshotZoomLevel[]
 }
 
 export type SnapshotDocumentProps = {
   pages?: SnapshotPagesProps
   thumbnails?: SnapshotThumbnailsProps
+  slides?: SnapshotSlidesProps
 }
 
 export type SnapshotPagesProps = {
@@ -97,6 +121,36 @@ export type SnapshotThumbnailsProps = {
   extension: string
 }
 
+export type SnapshotSlidesProps = {
+  count: number
+  extension: string
+}
+
+export type SnapshotModelProps = {
+  vertices: number
+  triangles: number
+  meshes: number
+  materials: number
+  hasAnimations: boolean
+  boundingBox?: SnapshotBoundingBox
+}
+
+export type SnapshotWorkbookProps = {
+  sheets: SnapshotSheetProps[]
+}
+
+export type SnapshotSheetProps = {
+  name: string
+  rows: number
+  columns: number
+  truncated: boolean
+}
+
+export type SnapshotBoundingBox = {
+  min: [number, number, number]
+  max: [number, number, number]
+}
+
 export type SnapshotTile = {
   width: number
   height: number
@@ -16,6 +16,20 @@ export type StorageUsage = {
   percentage: number
 }
 
+export type StorageBreakdown = {
+  bytes: number
+  latestBytes: number
+  historyBytes: number
+  fileTypes: StorageFileTypeUsage[]
+}
+
+export type StorageFileTypeUsage = {
+  fileType: string
+  bytes: number
+  latestBytes: number
+  historyBytes: number
+}
+
 export class StorageAPI {
   static useGetAccountUsage(swrOptions?: SWRConfiguration) {
     const url = `/storage/account_usage`
@@ -42,6 +56,78 @@ export class StorageAPI {
     )
   }
 
+  static useGetWorkspaceUsageBreakdown(
+    id: string | null | undefined,
+    swrOptions?: SWRConfiguration,
+  ) {
+    const url = id
+      ? `/storage/workspace_usage_breakdown?${new URLSearchParams({
+          id,
+        })}`
+      : null
+    return useSWR<StorageBreakdown>(
+      url,
+      () =>
+        apiFetcher({ url: url!, method: 'GET' }) as Promise<StorageBreakdown>,
+      swrOptions,
+    )
+  }
+
+  static useGetOrganizationUsage(
+    id: string | null | undefined,
+    swrOptions?: SWRConfiguration,
+  ) {
+    const url = id
+      ? `/storage/organization_usage?${new URLSearchParams({
+          id,
+        })}`
+      : null
+    return useSWR<StorageUsage>(
+      url,
+      () => apiFetcher({ url: url!, method: 'GET' }) as Promise<StorageUsage>,
+      swrOptions,
+    )
+  }
+
+  static useGetOrganizationUsageBreakdown(
+    id: string | null | undefined,
+    swrOptions?: SWRConfiguration,
+  ) {
+    const url = id
+      ? `/storage/organization_usage_breakdown?${new URLSearchParams({
+          id,
+        })}`
+      : null
+    return useSWR<StorageBreakdown>(
+      url,
+      () =>
+        apiFetcher({ url: url!, method: 'GET' }) as Promise<StorageBreakdown>,
+      swrOptions,
+    )
+  }
+
+  static useGetMemberUsage(
+    organizationId: string | null | undefined,
+    userId?: string,
+    swrOptions?: SWRConfiguration,
+  ) {
+    const params: Record<string, string> = {}
+    if (organizationId) {
+      params.organization_id = organizationId
+    }
+    if (userId) {
+      params.user_id = userId
+    }
+    const url = organizationId
+      ? `/storage/member_usage?${new URLSearchParams(params)}`
+      : null
+    return useSWR<StorageUsage>(
+      url,
+      () => apiFetcher({ url: url!, method: 'GET' }) as Promise<StorageUsage>,
+      swrOptions,
+    )
+  }
+
   static useGetFileUsage(
     id: string | null | undefined,
     swrOptions?: SWRConfiguration,
@@ -0,0 +1,85 @@
+// Copyright (c) 2023 Anass Bouassaba.
+//
+// Use of this software is governed by the Business Source License
+// included in the file LICENSE in the root of this repository.
+//
+// As of the Change Date specified in that file, in accordance with
+// the Business Source License, use of this software will be governed
+// by the GNU Affero General Public License v3.0 only, included in the file
+// AGPL-3.0-only in the root of this repository.
+import useSWR, { SWRConfiguration } from 'swr'
+import { apiFetcher } from '@/client/fetcher'
+import { Task } from './task'
+
+export type WorkspaceTemplate = {
+  id: string
+  organizationId: string
+  name: string
+  includesFiles: boolean
+  folderCount: number
+  fileCount: number
+  grantCount: number
+  createTime: string
+  updateTime?: string
+}
+
+export type WorkspaceTemplateCreateOptions = {
+  workspaceId: string
+  name: string
+  includeFiles: boolean
+}
+
+export type WorkspaceTemplatePatchNameOptions = {
+  name: string
+}
+
+export class WorkspaceTemplateAPI {
+  static create(options: WorkspaceTemplateCreateOptions) {
+    return apiFetcher({
+      url: `/workspace_templates`,
+      method: 'POST',
+      body: JSON.stringify(options),
+    }) as Promise<Task>
+  }
+
+  static patchName(id: string, options: WorkspaceTemplatePatchNameOptions) {
+    return apiFetcher({
+      url: `/workspace_templates/${id}/name`,
+      method: 'PATCH',
+      body: JSON.stringify(options),
+    }) as Promise<WorkspaceTemplate>
+  }
+
+  static useGet(id: string | null | undefined, swrOptions?: SWRConfiguration) {
+    const url = `/workspace_templates/${id}`
+    return useSWR<WorkspaceTemplate>(
+      id ? url : null,
+      () =>
+        apiFetcher({ url, method: 'GET' }) as Promise<WorkspaceTemplate>,
+      swrOptions,
+    )
+  }
+
+  static useList(
+    organizationId: string | null | undefined,
+    swrOptions?: SWRConfiguration,
+  ) {
+    const params = new URLSearchParams({
+      organization_id: organizationId ?? '',
+    })
+    const url = `/workspace_templates?${params}`
+    return useSWR<WorkspaceTemplate[]>(
+      organizationId ? url : null,
+      () =>
+        apiFetcher({ url, method: 'GET' }) as Promise<WorkspaceTemplate[]>,
+      swrOptions,
+    )
+  }
+
+  static delete(id: string) {
+    return apiFetcher({
+      url: `/workspace_templates/${id}`,
+      method: 'DELETE',
+    })
+  }
+}
@@ -11,6 +11,7 @@ import useSWR, { SWRConfiguration } from 'swr'
 import { apiFetcher } from '@/client/fetcher'
 import { Organization } from './organization'
 import { PermissionType } from './permission'
+import { Task } from './task'
 
 export enum WorkspaceSortBy {
   Name = 'name',
@@ -30,10 +31,39 @@ export type Workspace = {
   storageCapacity: number
   rootId: string
   organization: Organization
+  processingPolicy?: WorkspaceProcessingPolicy
+  archiveStatus?: WorkspaceArchiveStatus
+  archiveTime?: string
   createTime: string
   updateTime?: string
 }
 
+export type WorkspaceArchiveStatus = 'archiving' | 'archived' | 'restoring'
+
+export type WorkspaceProcessingFileType =
+  | 'pdf'
+  | 'office'
+  | 'text'
+  | 'image'
+  | 'video'
+  | 'audio'
+  | 'glb'
+  | 'model'
+  | 'email'
+  | 'zip'
+
+export type WorkspaceProcessingPolicy = {
+  insights?: {
+    languageId: string
+    fileTypes?: WorkspaceProcessingFileType[]
+  }
+  mosaic?: {
+    thresholdMegapixels: number
+  }
+  skipPreview?: string[]
+  extractEmailAttachments?: boolean
+}
+
 export type WorkspaceList = {
   data: Workspace[]
   totalPages: number
@@ -47,6 +77,21 @@ export interface WorkspaceCreateOptions {
   image?: string
   organizationId: string
   storageCapacity: number
+  templateId?: string
+}
+
+export type WorkspaceCloneOptions = {
+  name: string
+}
+
+export type WorkspaceCloneResult = {
+  workspace: Workspace
+  task: Task
+}
+
+export type WorkspaceArchiveResult = {
+  workspace: Workspace
+  task: Task
 }
 
 export type WorkspaceListOptions = {
@@ -65,6 +110,10 @@ export interface WorkspacePatchStorageCapacityOptions {
   storageCapacity: number
 }
 
+export interface WorkspacePatchProcessingPolicyOptions {
+  policy: WorkspaceProcessingPolicy | null
+}
+
 type WorkspaceListQueryParams = {
   page?: string
   size?: string
@@ -151,6 +200,39 @@ export class WorkspaceAPI {
     }) as Promise<Workspace>
   }
 
+  static patchProcessingPolicy(
+    id: string,
+    options: WorkspacePatchProcessingPolicyOptions,
+  ) {
+    return apiFetcher({
+      url: `/workspaces/${id}/processing_policy`,
+      method: 'PATCH',
+      body: JSON.stringify(options),
+    }) as Promise<Workspace>
+  }
+
+  static clone(id: string, options: WorkspaceCloneOptions) {
+    return apiFetcher({
+      url: `/workspaces/${id}/clone`,
+      method: 'POST',
+      body: JSON.stringify(options),
+    }) as Promise<WorkspaceCloneResult>
+  }
+
+  static archive(id: string) {
+    return apiFetcher({
+      url: `/workspaces/${id}/archive`,
+      method: 'POST',
+    }) as Promise<WorkspaceArchiveResult>
+  }
+
+  static restore(id: string) {
+    return apiFetcher({
+      url: `/workspaces/${id}/restore`,
+      method: 'POST',
+    }) as Promise<WorkspaceArchiveResult>
+  }
+
   static async delete(id: string) {
     return apiFetcher({
       url: `/workspaces/${id}`,
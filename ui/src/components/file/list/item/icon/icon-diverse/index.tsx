@@ -64,7 +64,10 @@ const IconDiverse = ({ file, scale }: IconFontProps) => {
     image = isDark ? DarkPdfSvg : PdfSvg
   } else if (fe.isText(original?.extension)) {
     image = isDark ? DarkTextSvg : TextSvg
-  } else if (fe.isRichText(original?.extension)) {
+  } else if (
+    fe.isRichText(original?.extension) ||
+    fe.isEmail(original?.extension)
+  ) {
     image = isDark ? DarkRichTextSvg : RichTextSvg
   } else if (fe.isWord(original?.extension)) {
     image = isDark ? DarkWordSvg : WordSvg
@@ -90,7 +93,10 @@ const IconDiverse = ({ file, scale }: IconFontProps) => {
     image = isDark ? DarkCodeSvg : CodeSvg
   } else if (fe.isCSV(original?.extension)) {
     image = isDark ? DarkCsvSvg : CsvSvg
-  } else if (fe.isGLB(original?.extension)) {
+  } else if (
+    fe.isGLB(original?.extension) ||
+    fe.isModel(original?.extension)
+  ) {
     image = isDark ? DarkModelSvg : ModelSvg
   } else {
     image = isDark ? DarkFileSvg : FileSvg
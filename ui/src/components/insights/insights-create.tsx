@@ -25,6 +25,8 @@ interface LanguageOption extends OptionBase {
   value: string
 }
 
+const AUTO_DETECT = 'auto'
+
 const InsightsCreate = () => {
   const dispatch = useAppDispatch()
   const id = useAppSelector((state) =>
@@ -36,6 +38,7 @@ const InsightsCreate = () => {
   const mutateTasks = useAppSelector((state) => state.ui.tasks.mutateList)
   const mutateInfo = useAppSelector((state) => state.ui.insights.mutateInfo)
   const [language, setLanguage] = useState<InsightsLanguage>()
+  const [isAutoDetect, setIsAutoDetect] = useState(false)
   const {
     data: languages,
     error: languagesError,
@@ -61,18 +64,34 @@ const InsightsCreate = () => {
   const languagesIsReady = languages && !languagesError
 
   const handleCreate = useCallback(async () => {
-    if (id && language) {
-      await InsightsAPI.create(id, { languageId: language.id }, false)
+    if (id && (language || isAutoDetect)) {
+      await InsightsAPI.create(
+        id,
+        { languageId: isAutoDetect ? undefined : language?.id },
+        false,
+      )
       await mutateInfo?.(await InsightsAPI.getInfo(id))
       await mutateFiles?.()
       await mutateTasks?.(await TaskAPI.list())
       dispatch(modalDidClose())
     }
-  }, [language, id, mutateFiles, mutateTasks, mutateInfo, dispatch])
+  }, [
+    language,
+    isAutoDetect,
+    id,
+    mutateFiles,
+    mutateTasks,
+    mutateInfo,
+    dispatch,
+  ])
 
   const handleLanguageChange = useCallback(
     (newValue: SingleValue<LanguageOption>) => {
-      if (newValue?.value && languages) {
+      if (newValue?.value === AUTO_DETECT) {
+        setIsAutoDetect(true)
+        setLanguage(undefined)
+      } else if (newValue?.value && languages) {
+        setIsAutoDetect(false)
         setLanguage(languages.filter((e) => e.id === newValue.value)[0])
       }
     },
@@ -109,10 +128,13 @@ const InsightsCreate = () => {
                 <Select<LanguageOption, false>
                   className={cx('w-full')}
                   defaultValue={existingLanguage}
-                  options={languages.map((language) => ({
-                    value: language.id,
-                    label: language.name,
-                  }))}
+                  options={[
+                    { value: AUTO_DETECT, label: 'Detect Automatically' },
+                    ...languages.map((language) => ({
+                      value: language.id,
+                      label: language.name,
+                    })),
+                  ]}
                   placeholder="Select Language"
                   selectedOptionStyle="check"
                   onChange={handleLanguageChange}
@@ -136,7 +158,7 @@ const InsightsCreate = () => {
             type="button"
             variant="solid"
             colorScheme="blue"
-            isDisabled={!language}
+            isDisabled={!language && !isAutoDetect}
             onClick={handleCreate}
           >
             Collect Insights
@@ -8,7 +8,8 @@
 // by the GNU Affero General Public License v3.0 only, included in the file
 // AGPL-3.0-only in the root of this repository.
 import { useMemo } from 'react'
-import { File } from '@/client/api/file'
+import cx from 'classnames'
+import { File, FileAPI } from '@/client/api/file'
 import { getAccessTokenOrRedirect } from '@/client/token'
 
 export type ViewerAudioProps = {
@@ -27,15 +28,43 @@ const ViewerAudio = ({ file }: ViewerAudioProps) => {
       access_token: getAccessTokenOrRedirect(),
     })}`
   }, [file, download])
+  const { data: waveform } = FileAPI.useGetWaveform(
+    file.snapshot?.preview?.audio?.waveform ? file.id : null,
+  )
+  const path = useMemo(() => {
+    if (!waveform || waveform.peaks.length === 0) {
+      return undefined
+    }
+    return waveform.peaks
+      .map(
+        (peak, index) => `M${index} ${50 - peak * 50}V${50 + peak * 50}`,
+      )
+      .join('')
+  }, [waveform])
 
   if (!download) {
     return null
   }
 
   return (
-    <audio controls>
-      <source src={url} />
-    </audio>
+    <div className={cx('flex', 'flex-col', 'items-center', 'gap-2')}>
+      {waveform && path ? (
+        <svg
+          className={cx('w-[600px]', 'max-w-full', 'h-[100px]')}
+          viewBox={`0 0 ${waveform.peaks.length} 100`}
+          preserveAspectRatio="none"
+        >
+          <path
+            d={path}
+            stroke="currentColor"
+            vectorEffect="non-scaling-stroke"
+          />
+        </svg>
+      ) : null}
+      <audio controls>
+        <source src={url} />
+      </audio>
+    </div>
   )
 }
 
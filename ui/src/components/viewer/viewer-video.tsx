@@ -9,7 +9,7 @@
 // AGPL-3.0-only in the root of this repository.
 import { useMemo } from 'react'
 import { variables } from '@koupr/ui'
-import { File } from '@/client/api/file'
+import { File, FileAPI } from '@/client/api/file'
 import { getAccessTokenOrRedirect } from '@/client/token'
 
 export type ViewerVideoProps = {
@@ -28,6 +28,12 @@ const ViewerVideo = ({ file }: ViewerVideoProps) => {
       access_token: getAccessTokenOrRedirect(),
     })}`
   }, [file, download])
+  const transcriptURL = useMemo(() => {
+    if (!file.snapshot?.preview?.audio?.transcript) {
+      return undefined
+    }
+    return FileAPI.getTranscriptURL(file.id, getAccessTokenOrRedirect())
+  }, [file])
 
   if (!download) {
     return null
@@ -44,6 +50,14 @@ const ViewerVideo = ({ file }: ViewerVideoProps) => {
       }}
     >
       <source src={url} />
+      {transcriptURL ? (
+        <track
+          kind="subtitles"
+          src={transcriptURL}
+          label="Transcript"
+          default
+        />
+      ) : null}
     </video>
   )
 }
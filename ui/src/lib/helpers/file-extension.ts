@@ -15,6 +15,13 @@ export function isPDF(ext?: string | null) {
   return ext === '.pdf'
 }
 
+export function isHTML(ext?: string | null) {
+  if (!ext) {
+    return false
+  }
+  return ext === '.html'
+}
+
 export function isImage(ext?: string | null) {
   if (!ext) {
     return false
@@ -32,9 +39,18 @@ export function isImage(ext?: string | null) {
       '.tif',
       '.bmp',
       '.ico',
+      '.heic',
       '.heif',
       '.xcf',
       '.svg',
+      '.cr2',
+      '.cr3',
+      '.nef',
+      '.arw',
+      '.dng',
+      '.raf',
+      '.psd',
+      '.psb',
     ].findIndex((e) => e === ext) !== -1
   )
 }
@@ -175,7 +191,7 @@ export function isCode(ext?: string | null) {
     [
       '.html',
       '.js',
-      'jsx',
+      '.jsx',
       '.ts',
       '.tsx',
       '.css',
@@ -194,6 +210,7 @@ export function isCode(ext?: string | null) {
       '.yaml',
       '.toml',
       '.md',
+      '.markdown',
     ].findIndex((e) => e === ext) !== -1
   )
 }
@@ -211,3 +228,30 @@ export function isGLB(ext?: string | null) {
   }
   return ext === '.glb'
 }
+
+export function isModel(ext?: string | null) {
+  if (!ext) {
+    return false
+  }
+  return (
+    [
+      '.obj',
+      '.fbx',
+      '.stl',
+      '.ply',
+      '.usd',
+      '.usda',
+      '.usdc',
+      '.usdz',
+      '.step',
+      '.stp',
+    ].findIndex((e) => e === ext) !== -1
+  )
+}
+
+export function isEmail(ext?: string | null) {
+  if (!ext) {
+    return false
+  }
+  return ['.eml', '.msg', '.mbox'].findIndex((e) => e === ext) !== -1
+}
@@ -16,6 +16,7 @@ import { Helmet } from 'react-helmet-async'
 import { File, FileAPI } from '@/client/api/file'
 import DrawerContent from '@/components/viewer/drawer/drawer-content'
 import ViewerAudio from '@/components/viewer/viewer-audio'
+import ViewerHTML from '@/components/viewer/viewer-html'
 import ViewerImage from '@/components/viewer/viewer-image'
 import ViewerModel from '@/components/viewer/viewer-model'
 import ViewerMosaic from '@/components/viewer/viewer-mosaic'
@@ -25,6 +26,7 @@ import downloadFile from '@/lib/helpers/download-file'
 import {
   isGLB,
   isAudio,
+  isHTML,
   isImage,
   isPDF,
   isVideo,
@@ -45,6 +47,15 @@ const ViewerPage = () => {
           (file.snapshot.preview && isPDF(file.snapshot.preview.extension))),
     )
   }, [file, location])
+  const hasHTML = useMemo(
+    () =>
+      Boolean(
+        file?.snapshot &&
+          file.snapshot?.preview &&
+          isHTML(file.snapshot?.preview.extension),
+      ),
+    [file],
+  )
   const hasImage = useMemo(
     () =>
       Boolean(
@@ -87,6 +98,8 @@ const ViewerPage = () => {
       } else {
         if (hasPDF) {
           return <ViewerPDF file={file} />
+        } else if (hasHTML) {
+          return <ViewerHTML file={file} />
         } else if (hasImage) {
           if (hasMosaicImage) {
             return <ViewerMosaic file={file} />
@@ -121,6 +134,7 @@ const ViewerPage = () => {
       hasMosaicPath,
       hasMosaicImage,
       hasPDF,
+      hasHTML,
       hasImage,
       hasVideo,
       hasAudio,